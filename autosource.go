@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/matdmb/organize-media/pkg/i18n"
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// autoSource is the -source value selecting mounted memory cards
+const autoSource = "auto"
+
+// For testing purposes
+var findCardVolumes = utils.FindCardVolumes
+
+// resolveSources returns the source directories to import. With -source auto,
+// these are the DCIM folders of mounted memory cards: a single card is used
+// directly, otherwise the user picks one or all of them (all with -yes).
+func resolveSources(source string, yes bool, in io.Reader, out io.Writer) ([]string, error) {
+	if source != autoSource {
+		return []string{source}, nil
+	}
+
+	cards, err := findCardVolumes()
+	if err != nil {
+		return nil, i18n.Errorf("failed to list mounted volumes: %v", err)
+	}
+
+	switch {
+	case len(cards) == 0:
+		return nil, i18n.Errorf("no memory card with a DCIM folder found")
+	case len(cards) == 1:
+		fmt.Fprint(out, i18n.Sprintf("Using memory card: %s\n", cards[0]))
+		return cards, nil
+	case yes:
+		fmt.Fprint(out, i18n.Sprintf("Importing all %d memory cards\n", len(cards)))
+		return cards, nil
+	}
+
+	return selectCards(cards, in, out)
+}
+
+// selectCards asks which of several memory cards to import
+func selectCards(cards []string, in io.Reader, out io.Writer) ([]string, error) {
+	fmt.Fprint(out, i18n.T("Memory cards found:\n"))
+	for i, card := range cards {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, card)
+	}
+	fmt.Fprint(out, i18n.Sprintf("Select a card (1-%d) or a for all: ", len(cards)))
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return nil, i18n.Errorf("error reading input: %v", err)
+	}
+	answer = strings.TrimSpace(answer)
+
+	if strings.EqualFold(answer, "a") {
+		return cards, nil
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(cards) {
+		return cards[n-1 : n], nil
+	}
+	return nil, i18n.Errorf("invalid selection: %s", answer)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// mockCards overrides memory card detection for testing
+func mockCards(cards []string, err error) func() {
+	original := findCardVolumes
+	findCardVolumes = func() ([]string, error) { return cards, err }
+	return func() { findCardVolumes = original }
+}
+
+func TestResolveSources(t *testing.T) {
+	two := []string{"/media/user/CARD1/DCIM", "/media/user/CARD2/DCIM"}
+
+	testCases := []struct {
+		name    string
+		source  string
+		cards   []string
+		findErr error
+		yes     bool
+		input   string
+		want    []string
+		wantErr string
+	}{
+		{name: "explicit source", source: "/photos", want: []string{"/photos"}},
+		{name: "single card", source: "auto", cards: two[:1], want: two[:1]},
+		{name: "no card", source: "auto", wantErr: "no memory card"},
+		{name: "detection error", source: "auto", findErr: errors.New("boom"), wantErr: "failed to list mounted volumes"},
+		{name: "several cards with yes", source: "auto", cards: two, yes: true, want: two},
+		{name: "pick one", source: "auto", cards: two, input: "2\n", want: two[1:]},
+		{name: "pick all", source: "auto", cards: two, input: "a\n", want: two},
+		{name: "answer without newline", source: "auto", cards: two, input: "1", want: two[:1]},
+		{name: "out of range", source: "auto", cards: two, input: "3\n", wantErr: "invalid selection"},
+		{name: "no answer", source: "auto", cards: two, input: "", wantErr: "error reading input"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer mockCards(tc.cards, tc.findErr)()
+
+			var out bytes.Buffer
+			got, err := resolveSources(tc.source, tc.yes, strings.NewReader(tc.input), &out)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("resolveSources() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSources() unexpected error: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("resolveSources() = %v, want %v", got, tc.want)
+			}
+			if len(tc.cards) > 1 && !tc.yes && !strings.Contains(out.String(), "2) "+tc.cards[1]) {
+				t.Errorf("Expected the cards to be listed, got %q", out.String())
+			}
+		})
+	}
+}
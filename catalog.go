@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runCatalog implements the catalog subcommand, which maintains the catalog
+// of imported files
+func runCatalog(args []string, stdout io.Writer) error {
+	if len(args) > 0 && args[0] == "import" {
+		return runCatalogImport(args[1:], stdout)
+	}
+	if len(args) == 0 || args[0] != "repair" {
+		return fmt.Errorf("unknown catalog command (expected repair or import)")
+	}
+
+	fs := flag.NewFlagSet("catalog repair", flag.ContinueOnError)
+	catalogFile := fs.String("catalog", "", "Path to the catalog to repair")
+	dest := fs.String("dest", "", "Destination directory the catalog describes")
+	hashAlgo := fs.String("hash", "sha256", "Hash algorithm of the records added for files missing from the catalog: sha256 or blake3")
+	dryRun := fs.Bool("dry-run", false, "Only report the differences, leaving the catalog untouched")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *catalogFile == "" || *dest == "" {
+		return fmt.Errorf("catalog file and destination directory are required")
+	}
+	if !models.HashAlgorithms[*hashAlgo] {
+		return fmt.Errorf("unsupported hash algorithm: %s (expected sha256 or blake3)", *hashAlgo)
+	}
+
+	repair, err := utils.RepairCatalog(*catalogFile, *dest, *hashAlgo, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Kept: %d, removed (missing from the destination): %d, added (missing from the catalog): %d, moved: %d\n", repair.Kept, repair.Removed, repair.Added, repair.Moved)
+	if *dryRun {
+		fmt.Fprintln(stdout, "Dry run, the catalog was not changed")
+	}
+	return nil
+}
+
+// runCatalogImport implements the catalog import subcommand, which records the
+// files of an archive listed in its checksum manifests without hashing them
+func runCatalogImport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("catalog import", flag.ContinueOnError)
+	catalogFile := fs.String("catalog", "", "Path to the catalog to record the files in")
+	dest := fs.String("dest", "", "Destination directory holding the checksum manifests")
+	hashAlgo := fs.String("hash", "sha256", "Hash algorithm of the catalog, only manifests of this algorithm are imported: sha256 or blake3")
+	dryRun := fs.Bool("dry-run", false, "Only count the files that would be recorded, leaving the catalog untouched")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *catalogFile == "" || *dest == "" {
+		return fmt.Errorf("catalog file and destination directory are required")
+	}
+	if !models.HashAlgorithms[*hashAlgo] {
+		return fmt.Errorf("unsupported hash algorithm: %s (expected sha256 or blake3)", *hashAlgo)
+	}
+
+	catalog, err := utils.OpenCatalog(*catalogFile)
+	if err != nil {
+		return err
+	}
+	defer catalog.Close()
+
+	imported, err := utils.ImportManifests(catalog, *dest, *hashAlgo, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Manifests: %d, recorded: %d, already in the catalog: %d, stale or missing: %d\n", imported.Manifests, imported.Imported, imported.Recorded, imported.Stale)
+	if imported.Invalid > 0 {
+		fmt.Fprintf(stdout, "Manifests that could not be read, skipped: %d\n", imported.Invalid)
+	}
+	if *dryRun {
+		fmt.Fprintln(stdout, "Dry run, the catalog was not changed")
+	}
+	return nil
+}
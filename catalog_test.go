@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+func TestRunCatalogRepair(t *testing.T) {
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, "photo.jpg"), []byte("photo"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	catalogFile := filepath.Join(t.TempDir(), "catalog.jsonl")
+
+	var out bytes.Buffer
+	if err := runCatalog([]string{"repair", "-catalog", catalogFile, "-dest", dest}, &out); err != nil {
+		t.Fatalf("runCatalog() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "added (missing from the catalog): 1") {
+		t.Errorf("runCatalog() output = %q", out.String())
+	}
+}
+
+func TestRunCatalogImport(t *testing.T) {
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dest, "photo.jpg"), []byte("photo"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	manifest := utils.HashBuffer([]byte("photo"), utils.HashSHA256) + "  photo.jpg\n"
+	if err := os.WriteFile(filepath.Join(dest, "SHA256SUMS"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to create manifest: %v", err)
+	}
+	catalogFile := filepath.Join(t.TempDir(), "catalog.jsonl")
+
+	var out bytes.Buffer
+	if err := runCatalog([]string{"import", "-catalog", catalogFile, "-dest", dest}, &out); err != nil {
+		t.Fatalf("runCatalog() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Manifests: 1, recorded: 1") {
+		t.Errorf("runCatalog() output = %q", out.String())
+	}
+}
+
+func TestRunCatalogErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{"no command", nil},
+		{"unknown command", []string{"compact"}},
+		{"missing catalog", []string{"repair", "-dest", t.TempDir()}},
+		{"import without destination", []string{"import", "-catalog", "catalog.jsonl"}},
+		{"unsupported hash", []string{"repair", "-catalog", "catalog.jsonl", "-dest", t.TempDir(), "-hash", "md5"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runCatalog(tc.args, &bytes.Buffer{}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
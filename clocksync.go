@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runClockSync implements the clock-sync subcommand, which measures how far
+// apart the clocks of camera bodies are from photos of the same clock or
+// slate, and stores the offsets used by -clock-offsets on later imports
+func runClockSync(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("clock-sync", flag.ContinueOnError)
+	reference := fs.String("reference", "", "Photo of the clock or slate taken by the reference body")
+	var photos stringList
+	fs.Var(&photos, "photo", "Photo of the same clock or slate taken at the same moment by another body, may be repeated")
+	offsetsFile := fs.String("clock-offsets", "", "JSON file of clock offsets, created or updated")
+	atFlag := fs.String("time", "", "Real time shown by the clock in the reference photo, \"YYYY-MM-DD HH:MM:SS\" (optional)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	photos = append(photos, fs.Args()...)
+	if *reference == "" || len(photos) == 0 || *offsetsFile == "" {
+		return fmt.Errorf("reference photo, photos of other bodies and clock offsets file are required")
+	}
+
+	var at time.Time
+	if *atFlag != "" {
+		var err error
+		if at, err = utils.ParseManualDate(*atFlag); err != nil {
+			return err
+		}
+	}
+
+	// Offsets of bodies synchronized earlier are kept
+	offsets, err := utils.LoadClockOffsets(*offsetsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		offsets, err = utils.ClockOffsets{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	serials, err := offsets.Sync(*reference, photos, at)
+	if err != nil {
+		return err
+	}
+	if err := offsets.Save(*offsetsFile); err != nil {
+		return err
+	}
+	for i, serial := range serials {
+		role := ""
+		if i == 0 {
+			role = " (reference)"
+		}
+		fmt.Fprintf(stdout, "%s%s: %s\n", serial, role, offsets[serial])
+	}
+	fmt.Fprintf(stdout, "Clock offsets written to %s\n", *offsetsFile)
+	return nil
+}
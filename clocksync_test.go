@@ -0,0 +1,33 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunClockSyncErrors(t *testing.T) {
+	offsetsFile := filepath.Join(t.TempDir(), "clocks.json")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "no reference", args: []string{"-photo", "b.jpg", "-clock-offsets", offsetsFile}, wantErr: "required"},
+		{name: "no photo", args: []string{"-reference", "a.jpg", "-clock-offsets", offsetsFile}, wantErr: "required"},
+		{name: "no offsets file", args: []string{"-reference", "a.jpg", "b.jpg"}, wantErr: "required"},
+		{name: "invalid time", args: []string{"-reference", "a.jpg", "-photo", "b.jpg", "-clock-offsets", offsetsFile, "-time", "noon"}, wantErr: "invalid date"},
+		{name: "missing photo", args: []string{"-reference", filepath.Join(t.TempDir(), "a.jpg"), "-photo", "b.jpg", "-clock-offsets", offsetsFile}, wantErr: "a.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runClockSync(tt.args, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runClockSync() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runCompressionAdvice implements the compression-advice subcommand, which
+// recompresses sample JPEG files at several qualities and recommends the
+// lowest -compression level that keeps them visually close to the originals
+func runCompressionAdvice(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("compression-advice", flag.ContinueOnError)
+	var samples stringList
+	fs.Var(&samples, "sample", "Representative JPEG file, may be repeated")
+	target := fs.Float64("target", utils.DefaultSSIMTarget, "Lowest structural similarity (SSIM) to the originals accepted, between 0 and 1")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	samples = append(samples, fs.Args()...)
+	if len(samples) == 0 {
+		return fmt.Errorf("sample JPEG files are required")
+	}
+	if *target <= 0 || *target > 1 {
+		return fmt.Errorf("invalid SSIM target: %v (expected a number between 0 and 1)", *target)
+	}
+
+	scores := make([][]utils.QualityScore, 0, len(samples))
+	for _, sample := range samples {
+		data, err := os.ReadFile(sample)
+		if err != nil {
+			return err
+		}
+		sampleScores, err := utils.ScoreQualities(data, utils.AdvisedQualities)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(sample), err)
+		}
+		scores = append(scores, sampleScores)
+	}
+
+	// Every sample counts: the worst similarity is the one to look at
+	fmt.Fprintf(stdout, "%-8s %10s %10s %10s\n", "Quality", "Size", "Min SSIM", "Mean SSIM")
+	for i, quality := range utils.AdvisedQualities {
+		var ratio, sum float64
+		worst := 1.0
+		for _, sample := range scores {
+			ratio += sample[i].Ratio
+			sum += sample[i].SSIM
+			worst = min(worst, sample[i].SSIM)
+		}
+		n := float64(len(scores))
+		fmt.Fprintf(stdout, "%-8d %9.0f%% %10.4f %10.4f\n", quality, 100*ratio/n, worst, sum/n)
+	}
+
+	quality, smaller := utils.RecommendQuality(scores, *target)
+	switch {
+	case quality == 0:
+		fmt.Fprintf(stdout, "No quality keeps every sample above SSIM %.3f, import without -compression\n", *target)
+	case !smaller:
+		fmt.Fprintf(stdout, "Quality %d keeps every sample above SSIM %.3f but does not make them smaller, import without -compression\n", quality, *target)
+	default:
+		fmt.Fprintf(stdout, "Recommended: -compression %d, the lowest quality keeping every sample above SSIM %.3f\n", quality, *target)
+	}
+	return nil
+}
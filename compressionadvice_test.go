@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCompressionAdvice(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8((x + y) * 2), A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	sample := filepath.Join(t.TempDir(), "IMG_0001.JPG")
+	if err := os.WriteFile(sample, encoded.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runCompressionAdvice([]string{"-sample", sample}, &out); err != nil {
+		t.Fatalf("runCompressionAdvice() error = %v", err)
+	}
+	for _, want := range []string{"Quality", "Min SSIM", "\n95 ", "Recommended: -compression "} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("runCompressionAdvice() output = %q, want %q", out.String(), want)
+		}
+	}
+}
+
+func TestRunCompressionAdviceErrors(t *testing.T) {
+	notJPEG := filepath.Join(t.TempDir(), "notes.jpg")
+	if err := os.WriteFile(notJPEG, []byte("not a picture"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{"no samples", nil},
+		{"invalid target", []string{"-target", "1.5", notJPEG}},
+		{"missing sample", []string{filepath.Join(t.TempDir(), "missing.jpg")}},
+		{"not a JPEG file", []string{notJPEG}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runCompressionAdvice(tc.args, &bytes.Buffer{}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runKeygen implements the keygen subcommand, which creates a key file for -encrypt-key
+func runKeygen(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	outFile := fs.String("o", "", "Path of the key file to create")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *outFile == "" {
+		return fmt.Errorf("key file path is required")
+	}
+
+	if err := utils.GenerateKeyFile(*outFile); err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	fmt.Fprintf(stdout, "Key written to %s, keep a copy in a safe place: encrypted files cannot be recovered without it\n", *outFile)
+	return nil
+}
+
+// runDecrypt implements the decrypt subcommand, which restores the files of an encrypted destination
+func runDecrypt(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	source := fs.String("source", "", "Encrypted destination directory, or part of it")
+	dest := fs.String("dest", "", "Directory receiving the decrypted files")
+	keyFile := fs.String("key", "", "Key file used to encrypt the files")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *source == "" || *dest == "" || *keyFile == "" {
+		return fmt.Errorf("source, destination and key file are required")
+	}
+
+	enc, err := utils.LoadEncryptor(*keyFile, false)
+	if err != nil {
+		return err
+	}
+	count, err := utils.DecryptTree(enc, *source, *dest)
+	fmt.Fprintf(stdout, "Decrypted files: %d\n", count)
+	return err
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunKeygenAndDecrypt(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+
+	var out bytes.Buffer
+	if err := runKeygen([]string{"-o", keyFile}, &out); err != nil {
+		t.Fatalf("runKeygen() error = %v", err)
+	}
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private key file: %v", err)
+	}
+
+	out.Reset()
+	if err := runDecrypt([]string{"-source", t.TempDir(), "-dest", t.TempDir(), "-key", keyFile}, &out); err != nil {
+		t.Fatalf("runDecrypt() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Decrypted files: 0") {
+		t.Errorf("runDecrypt() output = %q", out.String())
+	}
+}
+
+func TestRunDecryptErrors(t *testing.T) {
+	badKey := filepath.Join(t.TempDir(), "bad")
+	if err := os.WriteFile(badKey, []byte("not a key"), 0600); err != nil {
+		t.Fatalf("Failed to create key file: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "missing key", args: []string{"-source", t.TempDir(), "-dest", t.TempDir()}},
+		{name: "invalid key", args: []string{"-source", t.TempDir(), "-dest", t.TempDir(), "-key", badKey}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runDecrypt(tc.args, &bytes.Buffer{}); err == nil {
+				t.Errorf("runDecrypt(%v) expected error, got nil", tc.args)
+			}
+		})
+	}
+
+	if err := runKeygen(nil, &bytes.Buffer{}); err == nil {
+		t.Error("runKeygen() without path expected error")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runDateSet implements the date-set subcommand, which imports files that
+// carry no usable date with a capture date given by the user
+func runDateSet(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("date-set", flag.ContinueOnError)
+	dateFlag := fs.String("date", "", "Capture date of the files: YYYY-MM-DD or \"YYYY-MM-DD HH:MM\"")
+	dest := fs.String("dest", "", "Path to the destination directory for organized pictures")
+	compression := fs.Int("compression", -1, "Compression level for JPG files (0-100, optional)")
+	reportFile := fs.String("report", "", "JSON report file listing the outcome of every file (optional)")
+	deleteSource := fs.Bool("delete", false, "Delete the files after they are organized")
+	writeDates := fs.Bool("write-dates", false, "Write the date into the EXIF metadata of JPEG files, and XMP sidecars of other files")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dateFlag == "" || *dest == "" {
+		return fmt.Errorf("date and destination directory are required")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no files to date")
+	}
+
+	date, err := utils.ParseManualDate(*dateFlag)
+	if err != nil {
+		return err
+	}
+
+	files := make([]string, 0, fs.NArg())
+	overrides := make(map[string]time.Time, fs.NArg())
+	for _, arg := range fs.Args() {
+		path, err := filepath.Abs(arg)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("not a file: %s", arg)
+		}
+		if !utils.SupportedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return fmt.Errorf("%w: %s", utils.ErrUnsupportedFormat, arg)
+		}
+		files = append(files, path)
+		overrides[path] = date
+	}
+
+	// The files go through a normal run over their common directory, restricted to them
+	params := &models.Params{
+		Source:        commonDir(files),
+		Destination:   *dest,
+		Compression:   *compression,
+		ReportFile:    *reportFile,
+		DeleteSource:  *deleteSource,
+		WriteDates:    *writeDates,
+		SkipUserInput: true,
+		Files:         files,
+		DateOverrides: overrides,
+	}
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	summary, err := utils.ProcessMediaFiles(params)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Dated %d files as %s: copied %d, compressed %d, skipped %d, failed %d\n",
+		len(files), *dateFlag, summary.Copied, summary.Compressed, summary.Skipped, summary.Failed)
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d files could not be organized", summary.Failed)
+	}
+	return nil
+}
+
+// commonDir returns the deepest directory holding every file
+func commonDir(files []string) string {
+	dir := filepath.Dir(files[0])
+	for _, f := range files[1:] {
+		for {
+			rel, err := filepath.Rel(dir, f)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return dir
+}
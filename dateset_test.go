@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDateSet(t *testing.T) {
+	source := t.TempDir()
+	dest := t.TempDir()
+	scan := filepath.Join(source, "scans", "scan.jpg")
+	other := filepath.Join(source, "other.jpg")
+	for _, path := range []string{scan, other} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte{0xFF, 0xD8, 0xFF, 0xD9}, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+
+	var out bytes.Buffer
+	if err := runDateSet([]string{"-date", "2023-08-14", "-dest", dest, "-report", reportFile, scan}, &out); err != nil {
+		t.Fatalf("runDateSet() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "copied 1") {
+		t.Errorf("runDateSet() output = %q", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(dest, "2023", "08-14", "scan.jpg")); err != nil {
+		t.Errorf("Expected the dated file: %v", err)
+	}
+	// Only the given files are imported
+	if _, err := os.Stat(filepath.Join(dest, "2023", "08-14", "other.jpg")); !os.IsNotExist(err) {
+		t.Error("Files not given should be left alone")
+	}
+
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	var report struct {
+		Files []struct {
+			ManualDate bool `json:"manual_date"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(report.Files) != 1 || !report.Files[0].ManualDate {
+		t.Errorf("Expected one manually dated report entry, got %+v", report.Files)
+	}
+}
+
+func TestRunDateSetErrors(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(text, []byte("notes"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	photo := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(photo, []byte{0xFF, 0xD8, 0xFF, 0xD9}, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	dest := t.TempDir()
+
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "missing date", args: []string{"-dest", dest, photo}},
+		{name: "invalid date", args: []string{"-date", "14/08/2023", "-dest", dest, photo}},
+		{name: "no files", args: []string{"-date", "2023-08-14", "-dest", dest}},
+		{name: "missing file", args: []string{"-date", "2023-08-14", "-dest", dest, filepath.Join(dir, "missing.jpg")}},
+		{name: "unsupported file", args: []string{"-date", "2023-08-14", "-dest", dest, text}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runDateSet(tc.args, &bytes.Buffer{}); err == nil {
+				t.Errorf("runDateSet(%v) expected error, got nil", tc.args)
+			}
+		})
+	}
+}
+
+func TestCommonDir(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "photos")
+	files := []string{
+		filepath.Join(root, "2023", "a.jpg"),
+		filepath.Join(root, "2023", "trip", "b.jpg"),
+		filepath.Join(root, "scans", "c.jpg"),
+	}
+	if got := commonDir(files); got != root {
+		t.Errorf("commonDir() = %q, want %q", got, root)
+	}
+	if got := commonDir(files[:1]); got != filepath.Join(root, "2023") {
+		t.Errorf("commonDir() of one file = %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runDedupeReport implements the dedupe-report subcommand, which lists the
+// groups of identical files of the destination and the space they waste, and
+// optionally removes the extra copies, recording them in a journal
+func runDedupeReport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("dedupe-report", flag.ContinueOnError)
+	dest := fs.String("dest", "", "Destination directory to search for duplicates")
+	catalogFile := fs.String("catalog", "", "Catalog of the destination, whose hashes are used instead of reading every file (optional)")
+	hashAlgo := fs.String("hash", "sha256", "Hash algorithm of the files compared: sha256 or blake3")
+	keep := fs.String("keep", "", "Remove the extra copies of every group, keeping the first, shortest or oldest one (optional)")
+	interactive := fs.Bool("interactive", false, "Ask which copy of every group to keep, removing the others")
+	journal := fs.String("journal", "", "Journal of removed duplicates (default: "+utils.DedupeJournalName+" in the destination)")
+	restore := fs.Bool("restore", false, "Copy back the duplicates recorded in the journal from the copies kept")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dest == "" {
+		return fmt.Errorf("destination directory is required")
+	}
+	if !models.HashAlgorithms[*hashAlgo] {
+		return fmt.Errorf("unsupported hash algorithm: %s (expected sha256 or blake3)", *hashAlgo)
+	}
+	if *keep != "" && !utils.KeepRules[*keep] {
+		return fmt.Errorf("unsupported keep rule: %s (expected first, shortest or oldest)", *keep)
+	}
+	if *keep != "" && *interactive {
+		return fmt.Errorf("-keep and -interactive cannot be used together")
+	}
+	if *journal == "" {
+		*journal = filepath.Join(*dest, utils.DedupeJournalName)
+	}
+
+	if *restore {
+		restored, err := utils.RestoreDuplicates(*journal)
+		fmt.Fprintf(stdout, "Restored %d files from %s\n", restored, *journal)
+		return err
+	}
+
+	groups, err := utils.FindDuplicates(*dest, *catalogFile, *hashAlgo)
+	if err != nil {
+		return err
+	}
+
+	var copies int
+	var wasted int64
+	for i, group := range groups {
+		copies += len(group.Files) - 1
+		wasted += group.Wasted()
+		fmt.Fprintf(stdout, "Group %d: %d copies of %s, %s wasted\n", i+1, len(group.Files), utils.FormatSize(group.Size), utils.FormatSize(group.Wasted()))
+		for j, file := range group.Files {
+			fmt.Fprintf(stdout, "  %d) %s\n", j+1, relativeTo(*dest, file))
+		}
+	}
+	fmt.Fprintf(stdout, "Duplicate groups: %d, extra copies: %d, wasted space: %s\n", len(groups), copies, utils.FormatSize(wasted))
+
+	if *keep == "" && !*interactive {
+		return nil
+	}
+
+	// Extra copies are removed one group at a time, each removal journaled first
+	var removed int
+	var freed int64
+	in := bufio.NewReader(stdin)
+	for i, group := range groups {
+		var keeper int
+		if *interactive {
+			fmt.Fprintf(stdout, "Group %d: keep which copy (1-%d), or s to skip? ", i+1, len(group.Files))
+			answer, err := in.ReadString('\n')
+			if err != nil && (err != io.EOF || answer == "") {
+				return fmt.Errorf("error reading input: %v", err)
+			}
+			answer = strings.TrimSpace(answer)
+			if strings.EqualFold(answer, "s") {
+				continue
+			}
+			n, err := strconv.Atoi(answer)
+			if err != nil || n < 1 || n > len(group.Files) {
+				return fmt.Errorf("invalid selection: %s", answer)
+			}
+			keeper = n - 1
+		} else if keeper, err = group.Keeper(*keep); err != nil {
+			return err
+		}
+
+		for j, file := range group.Files {
+			if j == keeper {
+				continue
+			}
+			if err := utils.RemoveDuplicate(*journal, group.Files[keeper], file, group, *hashAlgo); err != nil {
+				fmt.Fprintf(stdout, "Not removed %s: %v\n", relativeTo(*dest, file), err)
+				continue
+			}
+			fmt.Fprintf(stdout, "Removed %s, kept %s\n", relativeTo(*dest, file), relativeTo(*dest, group.Files[keeper]))
+			removed++
+			freed += group.Size
+		}
+	}
+	fmt.Fprintf(stdout, "Removed %d files, freed %s, journal: %s\n", removed, utils.FormatSize(freed), *journal)
+	if removed > 0 && *catalogFile != "" {
+		fmt.Fprintln(stdout, "Run catalog repair to drop the records of removed files from the catalog")
+	}
+	return nil
+}
+
+// relativeTo returns path relative to dir when it is inside it
+func relativeTo(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createDuplicates creates a destination holding a picture and its copy
+func createDuplicates(t *testing.T) (dest, original, copy string) {
+	t.Helper()
+	dest = t.TempDir()
+	original = filepath.Join(dest, "2024", "06-11", "DSC00001.JPG")
+	copy = filepath.Join(dest, "2024", "06-12", "DSC00001 (1).JPG")
+	for _, path := range []string{original, copy} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("picture"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return dest, original, copy
+}
+
+func TestRunDedupeReport(t *testing.T) {
+	dest, original, copy := createDuplicates(t)
+
+	var out bytes.Buffer
+	if err := runDedupeReport([]string{"-dest", dest}, &out); err != nil {
+		t.Fatalf("runDedupeReport() error = %v", err)
+	}
+	for _, want := range []string{"Group 1: 2 copies of 7 bytes, 7 bytes wasted", filepath.Join("2024", "06-12", "DSC00001 (1).JPG"), "Duplicate groups: 1, extra copies: 1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("runDedupeReport() output = %q, want %q", out.String(), want)
+		}
+	}
+	for _, path := range []string{original, copy} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected the report to leave files untouched: %v", err)
+		}
+	}
+}
+
+func TestRunDedupeReportCleanup(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		input   string
+		removed bool
+	}{
+		{name: "keep rule", args: []string{"-keep", "shortest"}, removed: true},
+		{name: "interactive", args: []string{"-interactive"}, input: "1\n", removed: true},
+		{name: "interactive skip", args: []string{"-interactive"}, input: "s\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			originalStdin := stdin
+			defer func() { stdin = originalStdin }()
+			stdin = strings.NewReader(tc.input)
+
+			dest, original, copy := createDuplicates(t)
+			var out bytes.Buffer
+			if err := runDedupeReport(append([]string{"-dest", dest}, tc.args...), &out); err != nil {
+				t.Fatalf("runDedupeReport() error = %v", err)
+			}
+			if _, err := os.Stat(original); err != nil {
+				t.Errorf("Expected the original kept: %v", err)
+			}
+			if _, err := os.Stat(copy); os.IsNotExist(err) != tc.removed {
+				t.Errorf("copy removed = %v, want %v", os.IsNotExist(err), tc.removed)
+			}
+			if !tc.removed {
+				return
+			}
+
+			// The journal brings the copy back
+			out.Reset()
+			if err := runDedupeReport([]string{"-dest", dest, "-restore"}, &out); err != nil {
+				t.Fatalf("runDedupeReport() error = %v", err)
+			}
+			if _, err := os.Stat(copy); err != nil {
+				t.Errorf("Expected the copy restored: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunDedupeReportErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{"missing destination", nil},
+		{"unsupported hash", []string{"-dest", t.TempDir(), "-hash", "md5"}},
+		{"unsupported keep rule", []string{"-dest", t.TempDir(), "-keep", "largest"}},
+		{"keep rule and interactive", []string{"-dest", t.TempDir(), "-keep", "first", "-interactive"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runDedupeReport(tc.args, &bytes.Buffer{}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runEmit implements the emit subcommand, which writes the planned copies as
+// an rsync or rclone script instead of copying anything
+func runEmit(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("emit", flag.ContinueOnError)
+	source := fs.String("source", "", "Path to the source directory containing pictures")
+	dest := fs.String("dest", "", "Path to the destination directory for organized pictures")
+	format := fs.String("format", utils.EmitRsync, "Output format: rsync, rclone or tsv")
+	outFile := fs.String("o", "", "File receiving the output (default: standard output)")
+	layout := fs.String("layout", "", "Folder layout below the destination, such as {year}/{month_name}/{day}, or flat for date-prefixed names without folders (default: {year}/{month}-{day})")
+	holidays := fs.String("holidays", "", "Holiday calendar of the {weekday} and {holiday} layout tokens: us, gb, fr or de (default: from the language)")
+	rename := fs.String("rename", "", "Name template of organized files, such as {year}{month}{day}_{seq}, with the layout tokens, {name} and {seq} (optional)")
+	layoutCmd := fs.String("layout-cmd", "", "Command printing the folder of every file from its JSON description on standard input (optional)")
+	brackets := fs.String("brackets", "", "Layout of bracketed sequences: folder or stem (optional)")
+	route := fs.String("route", "", "Comma-separated kinds routed to their own day subfolder: timelapse, pano (optional)")
+	clockFile := fs.String("clock-offsets", "", "JSON file of clock offsets per camera serial number, such as {\"4012345\": \"3m12s\"} (optional)")
+	trustFolders := fs.Bool("trust-folders", false, "Date files of a source already organized in YYYY/MM-DD folders by their folder instead of their metadata")
+	noGPS := fs.Bool("no-gps", false, "Never read GPS locations, videos recorded in UTC are dated in UTC")
+	copyUnknown := fs.Bool("copy-unknown", false, "Copy files of unsupported formats to other/, dated by their modification time")
+	destFS := fs.String("dest-fs", "", "File system of the destination: fat or native (default: detected)")
+	maxFilesPerDir := fs.Int("max-files-per-dir", 0, "Move files beyond this number per destination folder to part-2/, part-3/... subfolders (optional)")
+	shardThreshold := fs.Int("shard-threshold", 0, "Split day folders holding more files than this into hour subfolders (optional)")
+	screenshots := fs.String("screenshots", "", "Policy for screenshots: route, keep or skip (default: route for phone exports, keep otherwise)")
+	phoneEdits := fs.String("phone-edits", "", "Policy for edited copies exported next to their originals: keep, edited or original (optional)")
+	profile := fs.String("profile", "", "Ingestion profile setting the flags left out for a kind of source: apple-photos (optional)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := applyProfile(fs, *profile); err != nil {
+		return err
+	}
+	if *source == "" || *dest == "" {
+		return fmt.Errorf("source and destination directories are required")
+	}
+	if !utils.EmitFormats[*format] {
+		return fmt.Errorf("unsupported emit format: %s (expected rsync, rclone or tsv)", *format)
+	}
+
+	params := &models.Params{
+		Source:         *source,
+		Destination:    *dest,
+		Compression:    -1,
+		Layout:         *layout,
+		LayoutCommand:  *layoutCmd,
+		Rename:         *rename,
+		Holidays:       *holidays,
+		Brackets:       *brackets,
+		Route:          *route,
+		ShardThreshold: *shardThreshold,
+		MaxFilesPerDir: *maxFilesPerDir,
+		DestFS:         *destFS,
+		CopyUnknown:    *copyUnknown,
+		ClockFile:      *clockFile,
+		TrustFolders:   *trustFolders,
+		Screenshots:    *screenshots,
+		PhoneEdits:     *phoneEdits,
+		Profile:        *profile,
+		NoGPS:          *noGPS,
+	}
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	plan, err := utils.PlanMediaFiles(params)
+	if err != nil {
+		return err
+	}
+
+	w := stdout
+	if *outFile != "" {
+		file, err := os.Create(*outFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return utils.WriteTransferPlan(w, plan, *format)
+}
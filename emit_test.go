@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunEmitErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "missing destination", args: []string{"-source", t.TempDir()}},
+		{name: "invalid format", args: []string{"-source", t.TempDir(), "-dest", t.TempDir(), "-format", "robocopy"}},
+		{name: "non-existent source", args: []string{"-source", filepath.Join(t.TempDir(), "missing"), "-dest", t.TempDir()}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runEmit(tc.args, &bytes.Buffer{}); err == nil {
+				t.Errorf("runEmit(%v) expected error, got nil", tc.args)
+			}
+		})
+	}
+}
+
+func TestRunEmit(t *testing.T) {
+	source := t.TempDir()
+	dest := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "photo.nef"), []byte("no date"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runEmit([]string{"-source", source, "-dest", dest}, &out); err != nil {
+		t.Fatalf("runEmit() error = %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "#!/bin/sh\n") || !strings.Contains(out.String(), "# skipped "+filepath.Join(source, "photo.nef")) {
+		t.Errorf("runEmit() output = %q", out.String())
+	}
+
+	// Nothing is copied
+	if entries, err := os.ReadDir(dest); err != nil || len(entries) != 0 {
+		t.Errorf("Expected an untouched destination, got %v (%v)", entries, err)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// For testing purposes
+var listenAndServe = http.ListenAndServe
+
+// runGallery implements the gallery subcommand, which serves a read-only web
+// gallery of an organized archive to check an import visually
+func runGallery(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("gallery", flag.ContinueOnError)
+	dest := fs.String("dest", "", "Destination directory of the organized archive")
+	catalogFile := fs.String("catalog", "", "Catalog of the archive, listing its imported files instead of walking the destination (optional)")
+	listen := fs.String("listen", "localhost:8081", "Address the gallery listens on, such as :8081 for every network interface")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dest == "" {
+		return fmt.Errorf("destination directory is required")
+	}
+
+	gallery, err := utils.NewGallery(*dest, *catalogFile)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Serving %d files of %s read-only on http://%s/\n", gallery.Len(), *dest, *listen)
+	return listenAndServe(*listen, gallery)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRunGallery(t *testing.T) {
+	dest := t.TempDir()
+
+	var addr string
+	original := listenAndServe
+	listenAndServe = func(a string, handler http.Handler) error {
+		addr = a
+		return http.ErrServerClosed
+	}
+	defer func() { listenAndServe = original }()
+
+	var out bytes.Buffer
+	if err := runGallery([]string{"-dest", dest, "-listen", ":8081"}, &out); err != http.ErrServerClosed {
+		t.Fatalf("runGallery() error = %v, want the error of the server", err)
+	}
+	if addr != ":8081" {
+		t.Errorf("listened on %q, want :8081", addr)
+	}
+	if !strings.Contains(out.String(), "Serving 0 files of "+dest) {
+		t.Errorf("runGallery() output = %q", out.String())
+	}
+
+	if err := runGallery(nil, &out); err == nil {
+		t.Error("Expected error without destination, got nil")
+	}
+}
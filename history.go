@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runHistory implements the history subcommand, which lists the import runs
+// recorded in a catalog, or the files imported by one of them
+func runHistory(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	catalogFile := fs.String("catalog", "", "Path to the catalog recording the imports")
+	runID := fs.String("run", "", "Identifier of a run whose imported files are listed (optional)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *catalogFile == "" {
+		return fmt.Errorf("catalog file is required")
+	}
+
+	catalog, err := utils.OpenCatalog(*catalogFile)
+	if err != nil {
+		return err
+	}
+	defer catalog.Close()
+
+	if *runID != "" {
+		run, ok := catalog.Run(*runID)
+		if !ok {
+			return fmt.Errorf("no run %s in %s", *runID, *catalogFile)
+		}
+		printRun(stdout, run, catalog.RunFiles(run.ID))
+		return nil
+	}
+
+	runs := catalog.Runs()
+	if len(runs) == 0 {
+		fmt.Fprintln(stdout, "No import recorded in this catalog")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN\tSTARTED\tDURATION\tHOST\tCOPIED\tSKIPPED\tFAILED\tSOURCE")
+	for _, run := range runs {
+		s := run.Summary
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n", run.ID, run.StartedAt.Local().Format("2006-01-02 15:04"), runDuration(run), run.Host, s.Copied+s.Compressed, s.Skipped, s.Failed, runSource(run))
+	}
+	return tw.Flush()
+}
+
+// printRun prints the details of a run and the files it imported
+func printRun(w io.Writer, run utils.RunRecord, files []utils.CatalogRecord) {
+	fmt.Fprintf(w, "Run: %s\n", run.ID)
+	fmt.Fprintf(w, "Started: %s (%s)\n", run.StartedAt.Local().Format("2006-01-02 15:04:05"), runDuration(run))
+	fmt.Fprintf(w, "Host: %s, version: %s\n", run.Host, utils.BuildInfo{Version: run.Version, Commit: run.Commit, BuildDate: run.BuildDate})
+	if run.Params != nil {
+		fmt.Fprintf(w, "Source: %s\n", run.Params.Source)
+		fmt.Fprintf(w, "Destination: %s\n", run.Params.Destination)
+		keys := make([]string, 0, len(run.Params.RunTags))
+		for key := range run.Params.RunTags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "Tag: %s=%s\n", key, run.Params.RunTags[key])
+		}
+	}
+	if run.Cancelled {
+		fmt.Fprintln(w, "Cancelled before the end")
+	}
+	s := run.Summary
+	fmt.Fprintf(w, "Copied: %d, compressed: %d, skipped: %d, failed: %d, deleted: %d\n", s.Copied, s.Compressed, s.Skipped, s.Failed, s.Deleted)
+
+	fmt.Fprintf(w, "\nFiles still recorded for this run: %d\n", len(files))
+	for _, file := range files {
+		fmt.Fprintf(w, "  %s <- %s\n", file.Destination, file.Source)
+	}
+}
+
+// runDuration returns the duration of a run rounded to the second
+func runDuration(run utils.RunRecord) time.Duration {
+	return run.FinishedAt.Sub(run.StartedAt).Round(time.Second)
+}
+
+// runSource returns the source directory of a run
+func runSource(run utils.RunRecord) string {
+	if run.Params == nil {
+		return ""
+	}
+	return run.Params.Source
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+func TestRunHistory(t *testing.T) {
+	source := t.TempDir()
+	// A file without date, the run skips it
+	if err := os.WriteFile(filepath.Join(source, "IMG_0001.jpg"), []byte{0xFF, 0xD8, 0xFF, 0xD9}, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	catalogFile := filepath.Join(t.TempDir(), "catalog.jsonl")
+	params := &models.Params{Source: source, Destination: t.TempDir(), Compression: -1, CatalogFile: catalogFile}
+	if _, err := utils.ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runHistory([]string{"-catalog", catalogFile}, &out); err != nil {
+		t.Fatalf("runHistory() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "RUN") || !strings.Contains(lines[1], source) {
+		t.Fatalf("runHistory() output = %q", out.String())
+	}
+
+	id := strings.Fields(lines[1])[0]
+	out.Reset()
+	if err := runHistory([]string{"-catalog", catalogFile, "-run", id}, &out); err != nil {
+		t.Fatalf("runHistory() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Run: "+id) || !strings.Contains(out.String(), "skipped: 1") {
+		t.Errorf("runHistory() run output = %q", out.String())
+	}
+
+	if err := runHistory([]string{"-catalog", catalogFile, "-run", "unknown"}, &out); err == nil {
+		t.Error("Expected an error for an unknown run")
+	}
+	if err := runHistory(nil, &out); err == nil {
+		t.Error("Expected an error without catalog")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// Example file resolved when layout-test is given no sample
+var (
+	exampleName = "DSC00001.ARW"
+	exampleDate = time.Date(2024, 6, 11, 15, 30, 10, 0, time.UTC)
+)
+
+// runLayoutTest implements the layout-test subcommand, which validates a
+// folder layout and prints where sample files would be organized with it
+func runLayoutTest(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("layout-test", flag.ContinueOnError)
+	layoutFlag := fs.String("layout", utils.DefaultLayout, "Folder layout to test, such as {year}/{month_name}/{day}")
+	layoutCmd := fs.String("layout-cmd", "", "Command printing the folder of every file from its JSON description on standard input (optional)")
+	var samples stringList
+	fs.Var(&samples, "sample", "File whose destination is printed, may be repeated (optional)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	samples = append(samples, fs.Args()...)
+
+	layout, err := utils.ParseLayout(*layoutFlag)
+	if err != nil {
+		return err
+	}
+	layout = layout.WithCommand(*layoutCmd)
+	fmt.Fprintf(stdout, "Layout: %s\n", layout)
+
+	if len(samples) == 0 {
+		fmt.Fprintf(stdout, "%s (%s) -> %s\n", exampleName, exampleDate.Format("2006-01-02 15:04:05"), layout.Resolve(exampleName, exampleDate))
+		return nil
+	}
+
+	failed := 0
+	for _, sample := range samples {
+		dest, date, err := layout.Preview(sample)
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: %v\n", filepath.Base(sample), err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "%s (%s) -> %s\n", filepath.Base(sample), date.Format("2006-01-02 15:04:05"), dest)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d samples could not be resolved", failed, len(samples))
+	}
+	return nil
+}
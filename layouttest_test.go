@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLayoutTest(t *testing.T) {
+	dir := t.TempDir()
+	dated := filepath.Join(dir, "IMG_0001.jpg")
+	if err := os.WriteFile(dated, append([]byte{0xFF, 0xD8}, "2024:06:11 15:30:10\x00\xFF\xD9"...), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	undated := filepath.Join(dir, "IMG_0002.jpg")
+	if err := os.WriteFile(undated, []byte{0xFF, 0xD8, 0xFF, 0xD9}, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "default layout example",
+			want: "DSC00001.ARW (2024-06-11 15:30:10) -> " + filepath.Join("2024", "06-11", "DSC00001.ARW"),
+		},
+		{
+			name: "sample",
+			args: []string{"-layout", "{year}/{month_name}/{day}", "-sample", dated},
+			want: "IMG_0001.jpg (2024-06-11 15:30:10) -> " + filepath.Join("2024", "June", "11", "IMG_0001.jpg"),
+		},
+		{
+			name:    "sample without date",
+			args:    []string{"-sample", undated},
+			want:    "IMG_0002.jpg: ",
+			wantErr: true,
+		},
+		{name: "unknown token", args: []string{"-layout", "{year}/{month_nam}"}, wantErr: true},
+		{name: "path traversal", args: []string{"-layout", "../{year}"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := runLayoutTest(tt.args, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runLayoutTest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("runLayoutTest() output = %q, want it to contain %q", out.String(), tt.want)
+			}
+		})
+	}
+}
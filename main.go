@@ -3,68 +3,548 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/matdmb/organize-media/pkg/i18n"
 	"github.com/matdmb/organize-media/pkg/models"
 	"github.com/matdmb/organize-media/pkg/organizemedia"
+	"github.com/matdmb/organize-media/pkg/output"
+	"github.com/matdmb/organize-media/pkg/utils"
 )
 
 // For testing purposes
-var osExit = os.Exit
+var (
+	osExit           = os.Exit
+	stdin  io.Reader = os.Stdin
+)
 
 func main() {
+	// Dispatch subcommands before parsing the run flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "scan":
+			if err := runScan(os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "emit":
+			if err := runEmit(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "date-set":
+			if err := runDateSet(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "sync":
+			if err := runSync(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "catalog":
+			if err := runCatalog(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "history":
+			if err := runHistory(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "gallery":
+			if err := runGallery(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "compression-advice":
+			if err := runCompressionAdvice(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "dedupe-report":
+			if err := runDedupeReport(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "layout-test":
+			if err := runLayoutTest(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "clock-sync":
+			if err := runClockSync(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "keygen":
+			if err := runKeygen(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		case "decrypt":
+			if err := runDecrypt(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		}
+	}
+
 	// Define flags
-	source := flag.String("source", "", "Path to the source directory containing pictures")
+	source := flag.String("source", "", "Path to the source directory containing pictures, or auto to import mounted memory cards")
 	dest := flag.String("dest", "", "Path to the destination directory for organized pictures")
+	var mirrors stringList
+	flag.Var(&mirrors, "dest-mirror", "Additional destination receiving a copy of every organized file, may be repeated (optional)")
+	staging := flag.String("staging", "", "Local folder staging the files of an rclone destination before their upload (default: system temporary folder)")
+	var tiers stringList
+	flag.Var(&tiers, "tier", "Tiering rule <age>=<folder>, such as 2y=/cold-archive, sending older files to another folder, may be repeated (optional)")
 	compression := flag.Int("compression", -1, "Compression level for JPG files (0-100, optional)")
+	keepEdits := flag.Bool("keep-edits", false, "Copy JPG files edited in Photoshop, Lightroom or other editors without recompressing them")
 	delete := flag.Bool("delete", false, "Delete source files after processing")
 	logFile := flag.Bool("enable-log", false, "Enable logging to a file")
+	cacheFile := flag.String("cache", "", "Path to a metadata cache file to speed up repeated runs (optional)")
+	catalogFile := flag.String("catalog", "", "Path to the catalog recording imported files (optional)")
+	hashAlgo := flag.String("hash", "sha256", "Hash algorithm of catalog records: sha256 or blake3")
+	incremental := flag.Bool("incremental", false, "Skip files whose content is already recorded in the catalog")
+	workers := flag.String("workers", "1", "Number of files processed concurrently, or auto to tune it during the run")
+	lowPower := flag.Bool("low-power", false, "Spare the battery: at most 2 workers, and on battery copies checked by size only and pauses between files")
+	sinceLast := flag.Bool("since-last", false, "Only import files added or modified since the last import from this source")
+	strict := flag.Bool("strict", false, "Import nothing if any file has no date or conflicts at the destination")
+	precheck := flag.Bool("precheck", false, "Read every source file before importing and stop if any is unreadable")
+	salvage := flag.Bool("salvage", false, "Keep the readable part of files failing mid-read in a damaged folder")
+	isolateCorrupt := flag.Bool("isolate-corrupt", false, "Copy empty and truncated files to a corrupt folder of the destination")
+	reportFile := flag.String("report", "", "Path to a JSON report of the outcome of every file (optional)")
+	fromReport := flag.String("from-report", "", "Import again the files of the JSON report of a previous run instead of scanning the source (optional)")
+	onlyErrors := flag.Bool("only-errors", false, "With -from-report, only import again the files that failed or were skipped")
+	timelineFile := flag.String("timeline", "", "Path to a JSON timeline of imported files ordered by capture time across cameras (optional)")
+	cull := flag.String("cull", "", "Format reviewed during culling, jpeg or raw: companions of deleted files are not imported (optional)")
+	cullDelete := flag.Bool("cull-delete", false, "Delete orphaned companions from the source in cull mode")
+	layout := flag.String("layout", "", "Folder layout below the destination, such as {year}/{month_name}/{day}, or flat for date-prefixed names without folders (default: {year}/{month}-{day})")
+	holidays := flag.String("holidays", "", "Holiday calendar of the {weekday} and {holiday} layout tokens: us, gb, fr or de (default: from the language)")
+	rename := flag.String("rename", "", "Name template of organized files, such as {year}{month}{day}_{seq}, with the layout tokens, {name} and {seq} (optional)")
+	layoutCmd := flag.String("layout-cmd", "", "Command printing the folder of every file from its JSON description on standard input, such as \"python3 route.py\" (optional)")
+	brackets := flag.String("brackets", "", "Layout of bracketed sequences: folder or stem (optional)")
+	route := flag.String("route", "", "Comma-separated kinds routed to their own day subfolder: timelapse, pano (optional)")
+	shardThreshold := flag.Int("shard-threshold", 0, "Split day folders holding more files than this into hour subfolders (optional)")
+	settle := flag.Duration("settle", 0, "Leave files written less than this long ago, such as 5s, or still open for writing, for the next run (optional)")
+	yearRoots := flag.String("year-roots", "", "JSON file mapping years to destination roots, such as {\"2010-2019\": \"/mnt/a\", \"2020-\": \"/mnt/b\"} (optional)")
+	clockFile := flag.String("clock-offsets", "", "JSON file of clock offsets per camera serial number, such as {\"4012345\": \"3m12s\"} (optional)")
+	writeDates := flag.Bool("write-dates", false, "Write corrected capture dates back into the EXIF metadata of JPEG files, and XMP sidecars of other files")
+	trustFolders := flag.Bool("trust-folders", false, "Date files of a source already organized in YYYY/MM-DD folders by their folder instead of their metadata")
+	noGPS := flag.Bool("no-gps", false, "Never read GPS locations, videos recorded in UTC are dated in UTC")
+	copyUnknown := flag.Bool("copy-unknown", false, "Copy files of unsupported formats to other/, dated by their modification time")
+	destFS := flag.String("dest-fs", "", "File system of the destination: fat or native (default: detected)")
+	foldCase := flag.Bool("fold-case", false, "Treat destination names differing only in case as the same file (default: detected)")
+	maxFilesPerDir := flag.Int("max-files-per-dir", 0, "Move files beyond this number per destination folder to part-2/, part-3/... subfolders (optional)")
+	proxies := flag.String("proxies", "", "Policy for .LRV proxies and .THM thumbnails of videos: skip, keep or route (optional)")
+	screenshots := flag.String("screenshots", "", "Policy for screenshots: route, keep or skip (default: route for phone exports, keep otherwise)")
+	phoneEdits := flag.String("phone-edits", "", "Policy for edited copies exported next to their originals, such as IMG_E1234.HEIC: keep, edited or original (optional)")
+	profile := flag.String("profile", "", "Ingestion profile setting the flags left out for a kind of source: apple-photos (optional)")
+	lightroom := flag.String("lightroom", "", "Lightroom catalog, or its previews, whose files are not imported again (optional)")
+	lightroomFlag := flag.Bool("lightroom-flag", false, "Import the files of the Lightroom catalog with a warning instead of skipping them")
+	dngCmd := flag.String("dng-cmd", "", "Command converting RAW files to DNG, such as \"dnglab convert {} {out}\": failures fall back to a plain copy (optional)")
+	dngFormats := flag.String("dng-formats", "", "Comma-separated RAW formats converted by -dng-cmd, such as cr2,nef (default: every RAW format)")
+	dngOriginals := flag.String("dng-originals", "", "Folder keeping the originals of RAW files converted to DNG, laid out like the destination (default: raw-originals in the destination)")
+	albums := flag.String("albums", "", "Mirror Google Takeout albums: links or tags (optional)")
+	provenance := flag.String("provenance", "", "Record the source, hash and settings of every imported file in XMP: embed or sidecar (optional)")
+	checkCmd := flag.String("check-cmd", "", "Command run on every file before it is written, such as \"clamscan --no-summary {}\": a non-zero exit rejects the file (optional)")
+	quarantine := flag.String("quarantine", "", "Folder receiving a copy of files rejected by -check-cmd (optional)")
+	readOnly := flag.Bool("read-only", false, "Make destination files read-only once written")
+	encryptKey := flag.String("encrypt-key", "", "Key file encrypting destination files, created with the keygen command (optional)")
+	hashNames := flag.Bool("hash-names", false, "Hash the names of encrypted destination files")
+	var tags stringList
+	flag.Var(&tags, "tag", "key=value pair recorded with the run in the catalog and report, may be repeated (optional)")
+	eject := flag.Bool("eject", false, "Eject the source volume after a run without errors")
+	notifyMQTT := flag.String("notify-mqtt", "", "JSON file of MQTT settings used to publish the status of every run, for home-automation dashboards (optional)")
+	notifySMTP := flag.String("notify-smtp", "", "JSON file of SMTP settings used to email a summary of every run, with the report attached (optional)")
+	// Test mode, not listed in the usage
+	chaos := flag.String("chaos", "", "Rates of injected IO faults, such as 0.05 or read=0.1,write=0.02 (testing only)")
+	chaosSeed := flag.Int64("chaos-seed", 0, "Seed of the faults injected by -chaos (default: random)")
+	yes := flag.Bool("yes", false, "Process files without asking for confirmation")
+	flag.BoolVar(yes, "y", false, "Shorthand for -yes")
+
+	lang := flag.String("lang", "", "Language of messages: en, fr or de (defaults to the system locale)")
+	quiet := flag.Bool("quiet", false, "Only print the summary and errors")
+	verbose := flag.Bool("verbose", false, "Print per-file progress details")
+	noColor := flag.Bool("no-color", false, "Disable colored status tags")
+	version := flag.Bool("version", false, "Print the version, commit and build date of the tool and exit")
 
-	// Parse the flags
+	// Parse the flags, fill the others from OM_ environment variables, then from the profile
 	flag.Parse()
+	if err := applyEnv(flag.CommandLine, os.LookupEnv); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if err := applyProfile(flag.CommandLine, *profile); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	if *version {
+		fmt.Println("organize-media", utils.CurrentBuild())
+		return
+	}
+
+	// Select the message language, from -lang or the locale environment
+	if *lang == "" {
+		*lang = i18n.DetectLanguage()
+	}
+	if err := i18n.SetLanguage(*lang); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
 
 	// Validate required flags
-	if err := validateFlags(*source, *dest); err != nil {
+	if err := validateFlags(*source, *dest, *fromReport); err != nil {
 		handleValidationError()
 	}
 
+	// Configure output, colors are only used on terminals
+	verbosity, err := outputVerbosity(*quiet, *verbose)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	output.Configure(verbosity, !*noColor && output.ColorSupported(os.Stdout))
+
+	workerCount, err := parseWorkers(*workers)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	runTags, err := parseRunTags(tags)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Expand -source auto into the mounted memory cards
+	sources, err := resolveSources(*source, *yes, stdin, os.Stdout)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
 	// Run with validated params
-	runOrganize(*source, *dest, *compression, *delete, *logFile)
+	for _, source := range sources {
+		runOrganize(&models.Params{
+			Source:           source,
+			Destination:      *dest,
+			Mirrors:          mirrors,
+			StagingDir:       *staging,
+			Tiers:            tiers,
+			Compression:      *compression,
+			KeepEdits:        *keepEdits,
+			SkipUserInput:    *yes,
+			DeleteSource:     *delete,
+			EnableLog:        *logFile,
+			CacheFile:        *cacheFile,
+			CatalogFile:      *catalogFile,
+			Incremental:      *incremental,
+			HashAlgo:         *hashAlgo,
+			Workers:          workerCount,
+			LowPower:         *lowPower,
+			Precheck:         *precheck,
+			Strict:           *strict,
+			SinceLast:        *sinceLast,
+			SalvageDamaged:   *salvage,
+			IsolateCorrupt:   *isolateCorrupt,
+			ReportFile:       *reportFile,
+			FromReport:       *fromReport,
+			OnlyErrors:       *onlyErrors,
+			TimelineFile:     *timelineFile,
+			Cull:             *cull,
+			CullDelete:       *cullDelete,
+			Layout:           *layout,
+			LayoutCommand:    *layoutCmd,
+			Rename:           *rename,
+			Holidays:         *holidays,
+			Brackets:         *brackets,
+			Route:            *route,
+			ShardThreshold:   *shardThreshold,
+			MaxFilesPerDir:   *maxFilesPerDir,
+			DestFS:           *destFS,
+			FoldCase:         *foldCase,
+			CopyUnknown:      *copyUnknown,
+			ClockFile:        *clockFile,
+			WriteDates:       *writeDates,
+			YearRootsFile:    *yearRoots,
+			Settle:           *settle,
+			TrustFolders:     *trustFolders,
+			NoGPS:            *noGPS,
+			Proxies:          *proxies,
+			Screenshots:      *screenshots,
+			PhoneEdits:       *phoneEdits,
+			Profile:          *profile,
+			LightroomCatalog: *lightroom,
+			LightroomFlag:    *lightroomFlag,
+			DNGCommand:       *dngCmd,
+			DNGFormats:       *dngFormats,
+			DNGOriginals:     *dngOriginals,
+			Albums:           *albums,
+			Provenance:       *provenance,
+			CheckCommand:     *checkCmd,
+			QuarantineDir:    *quarantine,
+			ReadOnly:         *readOnly,
+			EncryptKeyFile:   *encryptKey,
+			HashNames:        *hashNames,
+			RunTags:          runTags,
+			Eject:            *eject,
+			NotifySMTP:       *notifySMTP,
+			NotifyMQTT:       *notifyMQTT,
+			Chaos:            *chaos,
+			ChaosSeed:        *chaosSeed,
+		})
+	}
 }
 
-// validateFlags checks if required flags are provided
-func validateFlags(source, dest string) error {
+// validateFlags checks if required flags are provided. The source and
+// destination of a run from a report default to those of the report.
+func validateFlags(source, dest, fromReport string) error {
+	if fromReport != "" {
+		return nil
+	}
 	if source == "" || dest == "" {
 		return fmt.Errorf("source and destination directories are required")
 	}
 	return nil
 }
 
+// envPrefix starts the environment variables setting flags, such as OM_DEST
+const envPrefix = "OM_"
+
+// envExcluded are the flags not read from the environment: -version would
+// replace every run, and -y is a shorthand of -yes
+var envExcluded = map[string]bool{"version": true, "y": true}
+
+// envPathLists are the repeated flags taking paths, whose environment
+// variable lists them like PATH (colon-separated, semicolon-separated on
+// Windows) since paths may hold commas
+var envPathLists = map[string]bool{"dest-mirror": true, "tier": true}
+
+// envName returns the environment variable of a flag, such as OM_DEST_MIRROR
+// for -dest-mirror
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnv sets the flags left out of the command line from their environment
+// variable: flags given on the command line take precedence over the
+// environment, which takes precedence over the defaults of the flags. There
+// is no configuration file. Repeated flags take comma-separated values, or
+// a path list for the flags of envPathLists.
+func applyEnv(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] || envExcluded[f.Name] {
+			return
+		}
+		name := envName(f.Name)
+		value, ok := lookup(name)
+		if !ok {
+			return
+		}
+		values := []string{value}
+		if _, repeated := f.Value.(*stringList); repeated {
+			values = strings.Split(value, ",")
+			if envPathLists[f.Name] {
+				values = filepath.SplitList(value)
+			}
+		}
+		for _, v := range values {
+			if v = strings.TrimSpace(v); v == "" && len(values) > 1 {
+				continue
+			}
+			if setErr := fs.Set(f.Name, v); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %v", value, name, setErr)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// profiles are the flags set by the ingestion profiles of -profile, unless
+// they are given on the command line or in the environment
+var profiles = map[string]map[string]string{
+	utils.ProfileApplePhotos: {"phone-edits": utils.PhoneEditsKeep},
+}
+
+// applyProfile sets the flags of a profile left out of the command line and
+// the environment, leaving out those fs does not define. Unknown profiles set
+// nothing, they are reported by the validation of the run.
+func applyProfile(fs *flag.FlagSet, name string) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	for flagName, value := range profiles[name] {
+		if given[flagName] || fs.Lookup(flagName) == nil {
+			continue
+		}
+		if err := fs.Set(flagName, value); err != nil {
+			return fmt.Errorf("invalid value %q for -%s in profile %s: %v", value, flagName, name, err)
+		}
+	}
+	return nil
+}
+
+// outputVerbosity returns the verbosity selected by the -quiet and -verbose flags
+func outputVerbosity(quiet, verbose bool) (output.Verbosity, error) {
+	switch {
+	case quiet && verbose:
+		return output.Normal, fmt.Errorf("-quiet and -verbose cannot be used together")
+	case quiet:
+		return output.Quiet, nil
+	case verbose:
+		return output.Verbose, nil
+	default:
+		return output.Normal, nil
+	}
+}
+
+// parseWorkers parses the -workers flag, a positive number or "auto"
+func parseWorkers(value string) (int, error) {
+	if value == "auto" {
+		return models.AutoWorkers, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid number of workers: %s (expected a positive number or auto)", value)
+	}
+	return n, nil
+}
+
+// stringList collects the values of a repeated flag
+type stringList []string
+
+func (t *stringList) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *stringList) Set(value string) error {
+	*t = append(*t, value)
+	return nil
+}
+
+// parseRunTags parses the -tag flags, key=value pairs such as client=smith
+func parseRunTags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	tags := make(map[string]string, len(values))
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid tag: %s (expected key=value)", value)
+		}
+		if _, exists := tags[key]; exists {
+			return nil, fmt.Errorf("tag %s given more than once", key)
+		}
+		tags[key] = strings.TrimSpace(val)
+	}
+	return tags, nil
+}
+
 // handleValidationError prints usage info and exits
 func handleValidationError() {
 	fmt.Println("Usage:")
-	fmt.Println("  -source    Source directory containing media files")
-	fmt.Println("  -dest      Destination directory for organized files")
+	fmt.Println("  -source    Source directory containing media files, or auto for mounted memory cards (DCIM folder)")
+	fmt.Println("  -dest      Destination directory for organized files, or rclone:<remote>:<path> to upload with rclone")
+	fmt.Println("  -dest-mirror  Backup directory receiving a copy of every organized file, may be repeated")
+	fmt.Println("  -staging   Local folder staging files before their upload to an rclone destination (default: system temporary folder)")
+	fmt.Println("  -tier      Send files older than an age to another folder, such as 2y=/cold-archive (y, m, w or d), may be repeated")
+	fmt.Println("  -year-roots  Spread the archive over several drives, from a JSON file mapping years or ranges of years to destination roots")
 	fmt.Println("  -compression  JPEG compression level (0-100, default: 90, -1 to disable)")
+	fmt.Println("  -keep-edits  Never recompress JPEG files marked as edited by Photoshop, Lightroom or other editors")
 	fmt.Println("  -delete    Delete source files after successful processing (default: false)")
+	fmt.Println("  -y, -yes   Skip the confirmation prompt, required when stdin is not a terminal")
 	fmt.Println("  -enable-log  Enable logging to file (default: false)")
+	fmt.Println("  -lang      Language of messages: en, fr or de (default: system locale)")
+	fmt.Println("  -quiet     Only print the summary and errors")
+	fmt.Println("  -verbose   Print per-file progress details")
+	fmt.Println("  -no-color  Disable colored status tags (also disabled when output is not a terminal)")
+	fmt.Println("  -cache     Metadata cache file used to skip EXIF extraction of unchanged files")
+	fmt.Println("  -catalog   Catalog file recording every imported file by content hash")
+	fmt.Println("  -incremental  Skip files already recorded in the catalog (requires -catalog)")
+	fmt.Println("  -hash      Hash algorithm of catalog records: sha256 (default) or blake3 (faster on multi-core machines)")
+	fmt.Println("  -workers   Number of files processed concurrently (default: 1), or auto to adapt it to the disks and CPUs")
+	fmt.Println("  -low-power    Spare the battery of a laptop: at most 2 workers, and while on battery copies checked by size instead of read back before deleting the source and short pauses between files")
+	fmt.Println("  -precheck  Read every source file first and stop before importing if any is unreadable")
+	fmt.Println("  -since-last  Only import files added or modified since the last import from this source")
+	fmt.Println("  -strict    Import nothing if any file has no date or conflicts at the destination")
+	fmt.Println("  -salvage   Copy the readable part of files failing mid-read to <dest>/damaged")
+	fmt.Println("  -isolate-corrupt  Copy empty and truncated files to <dest>/corrupt")
+	fmt.Println("  -report    JSON report file listing the outcome of every file")
+	fmt.Println("  -from-report  Import again the files of a previous report, without scanning the source (source and destination default to the report's)")
+	fmt.Println("  -only-errors  With -from-report, only import again the files that failed or were skipped")
+	fmt.Println("  -timeline  JSON file listing imported files of every camera in capture order, to sync multi-camera edits")
+	fmt.Println("  -cull      Format reviewed during culling (jpeg or raw), files of the other format left without a companion are not imported")
+	fmt.Println("  -cull-delete  Delete those orphaned files from the source instead of only skipping them")
+	fmt.Println("  -layout    Folder layout below the destination from tokens such as {year}, {month}, {month_name}, {day}, {week}, {weekday}, {holiday}, {camera} or {tag:<key>} (value of a -tag), or flat for date-prefixed names in the destination itself (default: {year}/{month}-{day})")
+	fmt.Println("  -rename    Rename organized files from a template of layout tokens, {name} (original name) and {seq} (0001, 0002... per destination day, in shooting order), keeping their extension")
+	fmt.Println("  -layout-cmd  Command choosing the folder of every file: it reads a JSON description of the file and prints a folder relative to the destination, the layout folder when it prints nothing")
+	fmt.Println("  -holidays  Holiday calendar of the {weekday} and {holiday} tokens: us, gb, fr or de (default: from -lang)")
+	fmt.Println("  -brackets  Group bracketed sequences: folder (own subfolder) or stem (named after the first frame)")
+	fmt.Println("  -route     Route timelapse frames and panoramas to timelapse/ and pano/ day subfolders, such as timelapse,pano")
+	fmt.Println("  -shard-threshold  Split day folders holding more files than this into hour subfolders, such as 2024/06-11/14h/")
+	fmt.Println("  -settle    Leave files written less than this long ago (such as 5s) or still open for writing for the next run, for tethered-capture hot folders")
+	fmt.Println("  -clock-offsets  Correct the dates of camera bodies whose clock is off, from a JSON file mapping serial numbers to offsets")
+	fmt.Println("  -write-dates  Write dates corrected by -clock-offsets, date-set or GPS time zones back into JPEG files, and .xmp sidecars of other files")
+	fmt.Println("  -trust-folders  Date files of a source already organized in YYYY/MM-DD folders by their folder, without reading their metadata")
+	fmt.Println("  -no-gps    Privacy mode: never read GPS locations, so none is logged or stored; videos recorded in UTC are dated in UTC")
+	fmt.Println("  -copy-unknown  Copy files of unsupported formats to other/YYYY/MM-DD, dated by their modification time, instead of ignoring them")
+	fmt.Println("  -dest-fs   Force FAT-safe file names (fat) or never sanitize them (native), detected from the destination by default")
+	fmt.Println("  -fold-case  Treat destination names differing only in case (DSC00001.JPG, dsc00001.jpg) as the same file, for destinations shared over SMB with macOS or Windows; detected on case-insensitive volumes")
+	fmt.Println("  -max-files-per-dir  Cap the files per destination folder for FAT32 drives and old NAS, extra files going to part-2/, part-3/...")
+	fmt.Println("  -proxies   Handle GoPro and DJI .LRV proxies and .THM thumbnails: skip, keep (next to the day's files) or route (to a proxies/ tree)")
+	fmt.Println("  -screenshots  Handle screenshots of phone exports: route (to a Screenshots/YYYY/MM tree), keep (in day folders) or skip (default: route for DCIM folders and the apple-photos profile, keep otherwise)")
+	fmt.Println("  -phone-edits  Handle edited copies of phone exports (IMG_E1234.HEIC, *-edited.jpg): keep both next to each other, edited or original to import only one")
+	fmt.Println("  -lightroom    Skip files already managed in a Lightroom catalog (.lrcat, or its Previews.lrdata), found by path or by name and capture time; -lightroom-flag imports them with a warning")
+	fmt.Println("  -dng-cmd      Convert RAW files to DNG with a command such as \"dnglab convert {} {out}\", keeping the originals in raw-originals/ or -dng-originals; -dng-formats limits the converted formats")
+	fmt.Println("  -profile      Ingestion profile of a kind of source: apple-photos for Photos.app and iCloud Photos exports (Live Photos, AAE sidecars, Originals and Edited folders)")
+	fmt.Println("  -albums    Mirror Google Takeout albums as symlinks under Albums/ (links) or in the catalog and report (tags)")
+	fmt.Println("  -provenance  Record the tool version, source path, source hash and compression of every imported file in its XMP metadata (embed, sidecars for formats other than JPEG) or in .xmp sidecars (sidecar)")
+	fmt.Println("  -check-cmd Validate every file with a command before writing it, {} being the file path, such as \"clamscan --no-summary {}\"")
+	fmt.Println("  -quarantine  Copy files rejected by -check-cmd to this folder")
+	fmt.Println("  -read-only Make destination and mirror files read-only once written, to protect the archive")
+	fmt.Println("  -encrypt-key  Encrypt destination files with AES-256-GCM using a key file created by keygen")
+	fmt.Println("  -hash-names  Also hide the names of encrypted files (requires -encrypt-key)")
+	fmt.Println("  -tag       Record a key=value pair with the run in the catalog and report, such as -tag client=smith -tag job=wedding2024")
+	fmt.Println("  -eject     Eject the source memory card after a run without errors")
+	fmt.Println("  -notify-mqtt  Publish the state of every run (running, idle or errored) to MQTT, using the broker settings of a JSON file")
+	fmt.Println("  -notify-smtp  Email a summary of every run, with the report attached, using the SMTP settings of a JSON file")
+	fmt.Println("  -version   Print the version, commit and build date of the tool")
+	fmt.Println("\nEvery flag can also be set with an environment variable, such as OM_SOURCE, OM_DEST or OM_DEST_MIRROR")
+	fmt.Println("(OM_DEST_MIRROR and OM_TIER take paths separated like PATH, OM_TAG comma-separated pairs). Flags given on the command line take precedence.")
+	fmt.Println("\nCommands:")
+	fmt.Println("  scan       Inspect a source without writing anything (-l for a per-file table)")
+	fmt.Println("  date-set   Organize files that carry no date with a date given by hand (-date YYYY-MM-DD -dest <dir> <files...>)")
+	fmt.Println("  sync       Copy files of an organized archive missing or changed in a backup (-from, -to, -verify, -catalog)")
+	fmt.Println("  history    List the imports recorded in a catalog, or the files of one of them (-catalog, -run <id>)")
+	fmt.Println("  gallery    Browse the archive in a read-only web gallery of its folders and thumbnails (-dest, -catalog, -listen)")
+	fmt.Println("  catalog repair  Reconcile a catalog with its destination tree after a crash or manual changes (-catalog, -dest, -dry-run)")
+	fmt.Println("  catalog import  Record the files listed in the sha256sum, b3sum and ExifTool CSV manifests of a destination without hashing them (-catalog, -dest, -dry-run)")
+	fmt.Println("  compression-advice  Recommend a -compression level from sample JPEG files recompressed at several qualities (-sample <file>, -target <ssim>)")
+	fmt.Println("  dedupe-report  List groups of identical files of the destination and the space they waste, optionally removing extra copies (-dest, -catalog, -keep first|shortest|oldest, -interactive, -restore)")
+	fmt.Println("  clock-sync  Store the clock offsets of camera bodies from photos of the same clock or slate (-reference, -photo, -clock-offsets)")
+	fmt.Println("  keygen     Create an encryption key file (-o <file>)")
+	fmt.Println("  decrypt    Restore encrypted files with their original names (-source, -dest, -key)")
+	fmt.Println("  layout-test  Validate a folder layout and print where sample files would be organized (-layout, -layout-cmd, -sample <file>)")
+	fmt.Println("  emit       Write the planned copies as an rsync or rclone script (-format rsync|rclone|tsv) instead of copying")
 	fmt.Println("\nExample:")
 	fmt.Println("  ./organize-media -source /path/to/photos -dest /path/to/organized")
+	fmt.Println("  ./organize-media -source auto -dest /path/to/organized")
+	fmt.Println("  ./organize-media scan -source /path/to/card -dest /path/to/organized -l -sort size")
+	fmt.Println("  ./organize-media emit -source /path/to/card -dest /path/to/organized -o import.sh")
 	osExit(1)
 }
 
 // runOrganize runs the organize logic with the given parameters
-func runOrganize(source, dest string, compression int, delete, logFile bool) {
-	// Initialize Params struct
-	params := &models.Params{
-		Source:       source,
-		Destination:  dest,
-		Compression:  compression,
-		DeleteSource: delete,
-		EnableLog:    logFile,
-	}
-
+func runOrganize(params *models.Params) {
 	// Run the main logic
 	if err := organizemedia.Organize(params); err != nil {
 		log.Fatalf("Error: %v", err)
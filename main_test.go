@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -9,6 +10,10 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+	"github.com/matdmb/organize-media/pkg/utils"
 )
 
 func TestMainFunction(t *testing.T) {
@@ -41,7 +46,7 @@ func TestMainFunction(t *testing.T) {
 	defer mockInput("y")()
 
 	// Mock command-line arguments
-	os.Args = []string{"main", "-source", srcDir, "-dest", destDir, "-compression", "50"}
+	os.Args = []string{"main", "-source", srcDir, "-dest", destDir, "-compression", "50", "-yes"}
 
 	// Run the main function
 	defer func() {
@@ -217,7 +222,8 @@ func TestCompressionRange(t *testing.T) {
 			cmdTest := exec.Command(testBinary,
 				"-source", srcDir,
 				"-dest", destDir,
-				"-compression", fmt.Sprintf("%d", tt.compression))
+				"-compression", fmt.Sprintf("%d", tt.compression),
+				"-yes")
 
 			cmdTest.Stdin = pr
 			var stdout, stderr bytes.Buffer
@@ -249,10 +255,11 @@ func TestCompressionRange(t *testing.T) {
 // TestValidateFlags tests the flag validation logic directly
 func TestValidateFlags(t *testing.T) {
 	testCases := []struct {
-		name    string
-		source  string
-		dest    string
-		wantErr bool
+		name       string
+		source     string
+		dest       string
+		fromReport string
+		wantErr    bool
 	}{
 		{
 			name:    "both valid",
@@ -278,11 +285,16 @@ func TestValidateFlags(t *testing.T) {
 			dest:    "",
 			wantErr: true,
 		},
+		{
+			name:       "both empty from report",
+			fromReport: "/tmp/report.json",
+			wantErr:    false,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := validateFlags(tc.source, tc.dest)
+			err := validateFlags(tc.source, tc.dest, tc.fromReport)
 
 			if tc.wantErr && err == nil {
 				t.Errorf("validateFlags() expected error, got nil")
@@ -342,6 +354,7 @@ func TestHandleValidationError(t *testing.T) {
 		"-dest",
 		"-compression",
 		"-delete",
+		"-yes",
 		"-enable-log",
 		"Example:",
 		"./organize-media -source /path/to/photos -dest /path/to/organized",
@@ -353,3 +366,181 @@ func TestHandleValidationError(t *testing.T) {
 		}
 	}
 }
+
+// TestOutputVerbosity tests the selection of the output verbosity from flags
+func TestOutputVerbosity(t *testing.T) {
+	testCases := []struct {
+		name    string
+		quiet   bool
+		verbose bool
+		want    output.Verbosity
+		wantErr bool
+	}{
+		{name: "default", want: output.Normal},
+		{name: "quiet", quiet: true, want: output.Quiet},
+		{name: "verbose", verbose: true, want: output.Verbose},
+		{name: "conflicting flags", quiet: true, verbose: true, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := outputVerbosity(tc.quiet, tc.verbose)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("outputVerbosity() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("outputVerbosity() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseWorkers(t *testing.T) {
+	testCases := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "1", want: 1},
+		{value: "8", want: 8},
+		{value: "auto", want: models.AutoWorkers},
+		{value: "0", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "many", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := parseWorkers(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseWorkers() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("parseWorkers() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRunTags(t *testing.T) {
+	testCases := []struct {
+		name    string
+		values  []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "pairs", values: []string{"client=smith", "job = wedding2024"}, want: map[string]string{"client": "smith", "job": "wedding2024"}},
+		{name: "empty value", values: []string{"note="}, want: map[string]string{"note": ""}},
+		{name: "value with equal sign", values: []string{"query=a=b"}, want: map[string]string{"query": "a=b"}},
+		{name: "missing value", values: []string{"client"}, wantErr: true},
+		{name: "missing key", values: []string{"=smith"}, wantErr: true},
+		{name: "duplicate key", values: []string{"client=smith", "client=jones"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseRunTags(tc.values)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseRunTags() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("parseRunTags() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	list := string(filepath.ListSeparator)
+	testCases := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		wantDest    string
+		wantLevel   int
+		wantYes     bool
+		wantMirrors []string
+		wantTags    []string
+		wantErr     bool
+	}{
+		{name: "defaults", wantLevel: -1},
+		{name: "environment", env: map[string]string{"OM_DEST": "/archive", "OM_COMPRESSION": "80", "OM_YES": "true"}, wantDest: "/archive", wantLevel: 80, wantYes: true},
+		{name: "flags take precedence", args: []string{"-dest", "/photos", "-compression", "50"}, env: map[string]string{"OM_DEST": "/archive", "OM_COMPRESSION": "80"}, wantDest: "/photos", wantLevel: 50},
+		{name: "repeated flag", env: map[string]string{"OM_DEST_MIRROR": "/backup1" + list + "/backup2"}, wantLevel: -1, wantMirrors: []string{"/backup1", "/backup2"}},
+		{name: "paths with commas", env: map[string]string{"OM_DEST_MIRROR": "/photos, 2024" + list + "/backup"}, wantLevel: -1, wantMirrors: []string{"/photos, 2024", "/backup"}},
+		{name: "repeated flag on the command line", args: []string{"-dest-mirror", "/usb"}, env: map[string]string{"OM_DEST_MIRROR": "/backup1" + list + "/backup2"}, wantLevel: -1, wantMirrors: []string{"/usb"}},
+		{name: "comma-separated flag", env: map[string]string{"OM_TAG": "host=nas, job=inbox"}, wantLevel: -1, wantTags: []string{"host=nas", "job=inbox"}},
+		{name: "shorthand not read", env: map[string]string{"OM_Y": "true"}, wantLevel: -1},
+		{name: "invalid value", env: map[string]string{"OM_COMPRESSION": "high"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			dest := fs.String("dest", "", "")
+			level := fs.Int("compression", -1, "")
+			yes := fs.Bool("yes", false, "")
+			fs.BoolVar(yes, "y", false, "")
+			var mirrors, tags stringList
+			fs.Var(&mirrors, "dest-mirror", "")
+			fs.Var(&tags, "tag", "")
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			err := applyEnv(fs, func(name string) (string, bool) {
+				value, ok := tc.env[name]
+				return value, ok
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("applyEnv() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if *dest != tc.wantDest || *level != tc.wantLevel || *yes != tc.wantYes || fmt.Sprint(mirrors) != fmt.Sprint(tc.wantMirrors) || fmt.Sprint(tags) != fmt.Sprint(tc.wantTags) {
+				t.Errorf("applyEnv() = dest %q, compression %d, yes %v, mirrors %q, tags %q", *dest, *level, *yes, mirrors, tags)
+			}
+		})
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		profile string
+		want    string
+	}{
+		{name: "no profile"},
+		{name: "profile", profile: utils.ProfileApplePhotos, want: utils.PhoneEditsKeep},
+		{name: "flags take precedence", args: []string{"-phone-edits", "edited"}, profile: utils.ProfileApplePhotos, want: utils.PhoneEditsEdited},
+		{name: "environment takes precedence", env: map[string]string{"OM_PHONE_EDITS": "original"}, profile: utils.ProfileApplePhotos, want: utils.PhoneEditsOriginal},
+		{name: "unknown profile", profile: "android"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			phoneEdits := fs.String("phone-edits", "", "")
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if err := applyEnv(fs, func(name string) (string, bool) {
+				value, ok := tc.env[name]
+				return value, ok
+			}); err != nil {
+				t.Fatalf("applyEnv() error = %v", err)
+			}
+
+			if err := applyProfile(fs, tc.profile); err != nil {
+				t.Fatalf("applyProfile() error = %v", err)
+			}
+			if *phoneEdits != tc.want {
+				t.Errorf("applyProfile() = phone edits %q, want %q", *phoneEdits, tc.want)
+			}
+		})
+	}
+}
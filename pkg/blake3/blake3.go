@@ -0,0 +1,277 @@
+// Package blake3 implements the BLAKE3 hash function with a 256-bit output.
+//
+// Inputs larger than a few chunks are split along the BLAKE3 tree and their
+// subtrees are hashed concurrently, so hashing large media files uses every core.
+package blake3
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// Size is the size of a BLAKE3 digest in bytes
+const Size = 32
+
+const (
+	blockLen = 64
+	chunkLen = 1024
+)
+
+// Domain separation flags
+const (
+	flagChunkStart = 1 << 0
+	flagChunkEnd   = 1 << 1
+	flagParent     = 1 << 2
+	flagRoot       = 1 << 3
+)
+
+// parallelMinSize is the smallest subtree worth hashing on another goroutine
+const parallelMinSize = 128 * chunkLen
+
+var iv = [8]uint32{
+	0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A,
+	0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19,
+}
+
+// schedule holds the message word order of each round, the message permutation
+// being applied once per round
+var schedule = [7][16]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{2, 6, 3, 10, 7, 0, 4, 13, 1, 11, 12, 5, 9, 14, 15, 8},
+	{3, 4, 10, 12, 13, 2, 7, 14, 6, 5, 9, 0, 11, 15, 8, 1},
+	{10, 7, 12, 9, 14, 3, 13, 15, 4, 0, 11, 2, 5, 8, 1, 6},
+	{12, 13, 9, 11, 15, 10, 14, 8, 7, 2, 5, 3, 0, 1, 6, 4},
+	{9, 14, 11, 5, 8, 12, 15, 1, 13, 3, 0, 10, 2, 6, 4, 7},
+	{11, 15, 5, 0, 1, 9, 8, 6, 14, 10, 2, 12, 3, 4, 7, 13},
+}
+
+// Sum256 returns the BLAKE3 digest of data
+func Sum256(data []byte) [Size]byte {
+	// Allow about two concurrent subtrees per CPU
+	depth := bits.Len(uint(runtime.GOMAXPROCS(0)))
+	out := subtreeOutput(data, 0, depth)
+
+	words := compress(out.cv, out.block, 0, out.blockLen, out.flags|flagRoot)
+	var sum [Size]byte
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint32(sum[i*4:], words[i])
+	}
+	return sum
+}
+
+// segmentLen is the size of the subtrees hashed at once by a Hasher, a power
+// of two number of chunks large enough to be hashed concurrently
+const segmentLen = 1 << 20
+
+// Hasher computes the BLAKE3 digest of data written in pieces, such as files
+// too large to be held in memory. It implements hash.Hash.
+type Hasher struct {
+	buf      []byte      // Pending input, at most one segment
+	segments uint64      // Number of segments hashed
+	stack    [][8]uint32 // Chaining values of the complete subtrees of segments
+}
+
+// New returns a Hasher computing a 256-bit BLAKE3 digest
+func New() *Hasher {
+	return &Hasher{buf: make([]byte, 0, segmentLen)}
+}
+
+// Write adds p to the hashed data. It never returns an error.
+func (h *Hasher) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// A full segment is only hashed once more input follows, the last one
+		// being part of the root
+		if len(h.buf) == segmentLen {
+			h.pushSegment()
+		}
+		take := min(segmentLen-len(h.buf), len(p))
+		h.buf = append(h.buf, p[:take]...)
+		p = p[take:]
+	}
+	return n, nil
+}
+
+// pushSegment hashes the pending segment and merges the complete subtrees
+// it finishes, as the BLAKE3 tree of a longer input holds them
+func (h *Hasher) pushSegment() {
+	depth := bits.Len(uint(runtime.GOMAXPROCS(0)))
+	cv := subtreeOutput(h.buf, h.segments*segmentLen/chunkLen, depth).chainingValue()
+	h.segments++
+	for total := h.segments; total&1 == 0; total >>= 1 {
+		cv = parentOutput(h.stack[len(h.stack)-1], cv).chainingValue()
+		h.stack = h.stack[:len(h.stack)-1]
+	}
+	h.stack = append(h.stack, cv)
+	h.buf = h.buf[:0]
+}
+
+// Sum appends the digest of the data written so far to b, without changing
+// the state of the Hasher
+func (h *Hasher) Sum(b []byte) []byte {
+	depth := bits.Len(uint(runtime.GOMAXPROCS(0)))
+	out := subtreeOutput(h.buf, h.segments*segmentLen/chunkLen, depth)
+	for i := len(h.stack) - 1; i >= 0; i-- {
+		out = parentOutput(h.stack[i], out.chainingValue())
+	}
+
+	words := compress(out.cv, out.block, 0, out.blockLen, out.flags|flagRoot)
+	var sum [Size]byte
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint32(sum[i*4:], words[i])
+	}
+	return append(b, sum[:]...)
+}
+
+// Reset clears the written data
+func (h *Hasher) Reset() {
+	h.buf, h.segments, h.stack = h.buf[:0], 0, nil
+}
+
+// Size returns the size of the digest in bytes
+func (h *Hasher) Size() int { return Size }
+
+// BlockSize returns the size of the blocks of the compression function
+func (h *Hasher) BlockSize() int { return blockLen }
+
+// output is a compression left pending, so the root flag can still be applied
+type output struct {
+	cv       [8]uint32
+	block    [16]uint32
+	counter  uint64
+	blockLen uint32
+	flags    uint32
+}
+
+// chainingValue finalizes a non-root output
+func (o output) chainingValue() [8]uint32 {
+	words := compress(o.cv, o.block, o.counter, o.blockLen, o.flags)
+	var cv [8]uint32
+	copy(cv[:], words[:8])
+	return cv
+}
+
+// subtreeOutput hashes input, whose first chunk has index counter. The left
+// subtree is hashed on another goroutine while depth allows it.
+func subtreeOutput(input []byte, counter uint64, depth int) output {
+	if len(input) <= chunkLen {
+		return chunkOutput(input, counter)
+	}
+
+	left := leftLen(len(input))
+	rightCounter := counter + uint64(left/chunkLen)
+
+	var leftCV, rightCV [8]uint32
+	if depth > 0 && len(input) >= parallelMinSize {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			leftCV = subtreeOutput(input[:left], counter, depth-1).chainingValue()
+		}()
+		rightCV = subtreeOutput(input[left:], rightCounter, depth-1).chainingValue()
+		wg.Wait()
+	} else {
+		leftCV = subtreeOutput(input[:left], counter, 0).chainingValue()
+		rightCV = subtreeOutput(input[left:], rightCounter, 0).chainingValue()
+	}
+
+	return parentOutput(leftCV, rightCV)
+}
+
+// leftLen returns the size of the left subtree: the largest power of two
+// number of chunks that leaves at least one byte for the right subtree
+func leftLen(n int) int {
+	fullChunks := uint((n - 1) / chunkLen)
+	return (1 << (bits.Len(fullChunks) - 1)) * chunkLen
+}
+
+// chunkOutput hashes a chunk of at most chunkLen bytes, leaving its last block pending
+func chunkOutput(chunk []byte, counter uint64) output {
+	cv := iv
+	flags := uint32(flagChunkStart)
+
+	for len(chunk) > blockLen {
+		cv = firstEight(compress(cv, blockWords(chunk[:blockLen]), counter, blockLen, flags))
+		chunk = chunk[blockLen:]
+		flags = 0
+	}
+
+	return output{
+		cv:       cv,
+		block:    blockWords(chunk),
+		counter:  counter,
+		blockLen: uint32(len(chunk)),
+		flags:    flags | flagChunkEnd,
+	}
+}
+
+// parentOutput combines the chaining values of two subtrees
+func parentOutput(left, right [8]uint32) output {
+	var block [16]uint32
+	copy(block[:8], left[:])
+	copy(block[8:], right[:])
+	return output{cv: iv, block: block, blockLen: blockLen, flags: flagParent}
+}
+
+// blockWords reads a block of at most blockLen bytes, zero padded, as little-endian words
+func blockWords(b []byte) [16]uint32 {
+	var padded [blockLen]byte
+	copy(padded[:], b)
+
+	var words [16]uint32
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(padded[i*4:])
+	}
+	return words
+}
+
+func firstEight(words [16]uint32) [8]uint32 {
+	var cv [8]uint32
+	copy(cv[:], words[:8])
+	return cv
+}
+
+// compress is the BLAKE3 compression function
+func compress(cv [8]uint32, m [16]uint32, counter uint64, blockLen, flags uint32) [16]uint32 {
+	v0, v1, v2, v3 := cv[0], cv[1], cv[2], cv[3]
+	v4, v5, v6, v7 := cv[4], cv[5], cv[6], cv[7]
+	v8, v9, v10, v11 := iv[0], iv[1], iv[2], iv[3]
+	v12, v13, v14, v15 := uint32(counter), uint32(counter>>32), blockLen, flags
+
+	for r := range schedule {
+		s := &schedule[r]
+		// Columns
+		v0, v4, v8, v12 = g(v0, v4, v8, v12, m[s[0]], m[s[1]])
+		v1, v5, v9, v13 = g(v1, v5, v9, v13, m[s[2]], m[s[3]])
+		v2, v6, v10, v14 = g(v2, v6, v10, v14, m[s[4]], m[s[5]])
+		v3, v7, v11, v15 = g(v3, v7, v11, v15, m[s[6]], m[s[7]])
+		// Diagonals
+		v0, v5, v10, v15 = g(v0, v5, v10, v15, m[s[8]], m[s[9]])
+		v1, v6, v11, v12 = g(v1, v6, v11, v12, m[s[10]], m[s[11]])
+		v2, v7, v8, v13 = g(v2, v7, v8, v13, m[s[12]], m[s[13]])
+		v3, v4, v9, v14 = g(v3, v4, v9, v14, m[s[14]], m[s[15]])
+	}
+
+	return [16]uint32{
+		v0 ^ v8, v1 ^ v9, v2 ^ v10, v3 ^ v11,
+		v4 ^ v12, v5 ^ v13, v6 ^ v14, v7 ^ v15,
+		v8 ^ cv[0], v9 ^ cv[1], v10 ^ cv[2], v11 ^ cv[3],
+		v12 ^ cv[4], v13 ^ cv[5], v14 ^ cv[6], v15 ^ cv[7],
+	}
+}
+
+// g is the BLAKE3 quarter-round function
+func g(a, b, c, d, mx, my uint32) (uint32, uint32, uint32, uint32) {
+	a += b + mx
+	d = bits.RotateLeft32(d^a, -16)
+	c += d
+	b = bits.RotateLeft32(b^c, -12)
+	a += b + my
+	d = bits.RotateLeft32(d^a, -8)
+	c += d
+	b = bits.RotateLeft32(b^c, -7)
+	return a, b, c, d
+}
@@ -0,0 +1,94 @@
+package blake3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// testInput returns the input used by the official BLAKE3 test vectors
+func testInput(n int) []byte {
+	input := make([]byte, n)
+	for i := range input {
+		input[i] = byte(i % 251)
+	}
+	return input
+}
+
+func TestSum256(t *testing.T) {
+	tests := []struct {
+		length int
+		want   string
+	}{
+		{0, "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{1, "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+		{64, "4eed7141ea4a5cd4b788606bd23f46e212af9cacebacdc7d1f4c6dc7f2511b98"},
+		{65, "de1e5fa0be70df6d2be8fffd0e99ceaa8eb6e8c93a63f2d8d1c30ecb6b263dee"},
+		{1023, "10108970eeda3eb932baac1428c7a2163b0e924c9a9e25b35bba72b28f70bd11"},
+		{1024, "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+		{1025, "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+		{2048, "e776b6028c7cd22a4d0ba182a8bf62205d2ef576467e838ed6f2529b85fba24a"},
+		{3073, "7124b49501012f81cc7f11ca069ec9226cecb8a2c850cfe644e327d22d3e1cd3"},
+		{8193, "bab6c09cb8ce8cf459261398d2e7aef35700bf488116ceb94a36d0f5f1b7bc3b"},
+		{31744, "62b6960e1a44bcc1eb1a611a8d6235b6b4b78f32e7abc4fb4c6cdcce94895c47"},
+		{102400, "bc3e3d41a1146b069abffad3c0d44860cf664390afce4d9661f7902e7943e085"},
+		{1 << 20, "74cb441fd087764ca9c3694da742ebe30cbeb3060a17009ca81825c7a8d10343"},
+	}
+
+	for _, tt := range tests {
+		sum := Sum256(testInput(tt.length))
+		if got := hex.EncodeToString(sum[:]); got != tt.want {
+			t.Errorf("Sum256(%d bytes) = %s, want %s", tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestParallelMatchesSerial(t *testing.T) {
+	for _, n := range []int{parallelMinSize, parallelMinSize + 1, 5<<20 + 13} {
+		input := testInput(n)
+		serial := subtreeOutput(input, 0, 0)
+		parallel := subtreeOutput(input, 0, 4)
+		if serial != parallel {
+			t.Errorf("parallel hashing of %d bytes differs from serial hashing", n)
+		}
+	}
+}
+
+func TestHasherMatchesSum256(t *testing.T) {
+	for _, n := range []int{0, 1, 1024, 1025, segmentLen - 1, segmentLen, segmentLen + 1, 2 * segmentLen, 3*segmentLen + 5, 4 * segmentLen} {
+		input := testInput(n)
+		want := Sum256(input)
+
+		// Written in pieces that do not line up with chunks or segments
+		h := New()
+		for rest := input; len(rest) > 0; {
+			piece := min(len(rest), 100_003)
+			h.Write(rest[:piece])
+			rest = rest[piece:]
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Errorf("Hasher of %d bytes = %x, want %x", n, got, want)
+		}
+		// Sum leaves the state unchanged
+		if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+			t.Errorf("second Sum of %d bytes = %x, want %x", n, got, want)
+		}
+	}
+
+	h := New()
+	h.Write(testInput(3 * segmentLen))
+	h.Reset()
+	empty := Sum256(nil)
+	if got := h.Sum(nil); !bytes.Equal(got, empty[:]) {
+		t.Errorf("Hasher after Reset = %x, want %x", got, empty)
+	}
+}
+
+func BenchmarkSum256(b *testing.B) {
+	input := testInput(24 << 20) // Typical RAW file size
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Sum256(input)
+	}
+}
@@ -0,0 +1,209 @@
+package i18n
+
+// german holds the German translations of user-facing messages
+var german = map[string]string{
+	// Prompts
+	"Number of files to process: %d [%s]\n":                      "Anzahl zu verarbeitender Dateien: %d [%s]\n",
+	"Planned destinations:\n":                                    "Geplante Ziele:\n",
+	"  ... and %d more\n":                                        "  ... und %d weitere\n",
+	"New destination folders (%d): ":                             "Neue Zielordner (%d): ",
+	"%d files have no date and will be skipped\n":                "%d Dateien haben kein Datum und werden übersprungen\n",
+	"%d files are empty or truncated and will not be imported\n": "%d Dateien sind leer oder abgeschnitten und werden nicht importiert\n",
+	"Do you want to proceed with processing %d files? (y/n): ":   "Möchten Sie %d Dateien verarbeiten? (j/n): ",
+	"Operation cancelled.":                                       "Vorgang abgebrochen.",
+
+	// Run information and summary
+	"Application started.":      "Anwendung gestartet.",
+	"Source directory: %s":      "Quellordner: %s",
+	"Destination directory: %s": "Zielordner: %s",
+	"Destination files are encrypted with the key in %s (hashed names: %t)":                    "Zieldateien werden mit dem Schlüssel in %s verschlüsselt (gehashte Namen: %t)",
+	"hashing file names requires an encryption key":                                            "das Hashen von Dateinamen erfordert einen Verschlüsselungsschlüssel",
+	"encryption key file not found: %s":                                                        "Schlüsseldatei nicht gefunden: %s",
+	"deleting source files is not supported with rclone destinations":                          "das Löschen der Quelldateien wird bei rclone-Zielen nicht unterstützt",
+	"importing only new files since the last import is not supported with rclone destinations": "der Import nur neuer Dateien seit dem letzten Import wird bei rclone-Zielen nicht unterstützt",
+	"Listing files already on %s...":                                                           "Auflisten der bereits auf %s vorhandenen Dateien...",
+	"failed to create staging folder: %v":                                                      "Fehler beim Erstellen des Zwischenordners: %v",
+	"not enough space to stage %s in %s: %s available, choose another folder with -staging":    "nicht genug Speicherplatz, um %s in %s vorzubereiten: %s verfügbar, wählen Sie mit -staging einen anderen Ordner",
+	"Staging files in %s before uploading to %s":                                               "Dateien werden in %s vorbereitet, bevor sie nach %s hochgeladen werden",
+	"Uploading to %s...":                                              "Hochladen nach %s...",
+	"Files uploaded to %s":                                            "Dateien nach %s hochgeladen",
+	"Mirror directory: %s":                                            "Spiegelverzeichnis: %s",
+	"Number of files mirrored: %d":                                    "Anzahl gespiegelter Dateien: %d",
+	"Number of files rejected by the check command: %d":               "Anzahl vom Prüfbefehl abgelehnter Dateien: %d",
+	"Check command: %s":                                               "Prüfbefehl: %s",
+	"quarantine requires a check command":                             "die Quarantäne erfordert einen Prüfbefehl",
+	"Number of files write-protected: %d":                             "Anzahl schreibgeschützter Dateien: %d",
+	"Number of files failed to mirror: %d":                            "Anzahl nicht gespiegelter Dateien: %d",
+	"mirror directory does not exist: %s":                             "Spiegelverzeichnis existiert nicht: %s",
+	"mirror directory must not overlap the destination directory: %s": "Spiegelverzeichnis darf sich nicht mit dem Zielverzeichnis überschneiden: %s",
+	"mirror directory must not overlap the source directory: %s":      "Spiegelverzeichnis darf sich nicht mit dem Quellverzeichnis überschneiden: %s",
+	"Compression level: %d":                                           "Komprimierungsstufe: %d",
+	"Compression: not applied":                                        "Komprimierung: nicht angewendet",
+	"Delete source files: %t":                                         "Quelldateien löschen: %t",
+	"Workers: auto":                                                   "Worker: automatisch",
+	"Workers: %d":                                                     "Worker: %d",
+	"Low-power mode: at most 2 workers, copies checked by size and pauses between files on battery": "Energiesparmodus: höchstens 2 Worker, Kopien anhand der Größe geprüft und Pausen zwischen Dateien im Akkubetrieb",
+	"Catalog: %s (incremental: %t)": "Katalog: %s (inkrementell: %t)",
+	"Only importing files added or modified since the last import from this source": "Nur seit dem letzten Import aus dieser Quelle hinzugefügte oder geänderte Dateien werden importiert",
+	"Bracketed sequences are placed in their own subfolder":                         "Belichtungsreihen werden in einem eigenen Unterordner abgelegt",
+	"Bracketed sequences are named after their first frame":                         "Belichtungsreihen werden nach ihrem ersten Bild benannt",
+	"Routed to their own day subfolder: %s":                                         "In einen eigenen Unterordner des Tages verschoben: %s",
+	"invalid chaos rates: %v":                                                       "ungültige Chaos-Raten: %v",
+	"Chaos mode: IO faults are injected at rates %s (seed %d)":                      "Chaos-Modus: E/A-Fehler werden mit den Raten %s injiziert (Seed %d)",
+	"Number of injected IO faults: %d":                                              "Anzahl injizierter E/A-Fehler: %d",
+	"Folder layout: %s":                                                             "Ordnerstruktur: %s",
+	"invalid layout: %v":                                                            "ungültige Ordnerstruktur: %v",
+	"File names: %s":                                                                "Dateinamen: %s",
+	"invalid name template: %v":                                                     "ungültige Namensvorlage: %v",
+	"Files written less than %v ago or still open for writing are left for the next run":                                  "Dateien, die vor weniger als %v geschrieben wurden oder noch zum Schreiben geöffnet sind, bleiben für den nächsten Lauf liegen",
+	"Camera clocks are corrected with the offsets in %s":                                                                  "Kamerauhren werden mit den Abweichungen aus %s korrigiert",
+	"Screenshots are placed in the %s/YYYY/MM tree":                                                                       "Bildschirmfotos werden im Baum %s/JJJJ/MM abgelegt",
+	"Screenshots are skipped":                                                                                             "Bildschirmfotos werden übersprungen",
+	"No date found for %s, enter its date (YYYY-MM-DD, - to skip): ":                                                      "Kein Datum für %s gefunden, geben Sie sein Datum ein (JJJJ-MM-TT, - zum Überspringen): ",
+	"The source is already organized by date, use -trust-folders to date files by their folder instead of their metadata": "Die Quelle ist bereits nach Datum organisiert, verwenden Sie -trust-folders, um Dateien nach ihrem Ordner statt nach ihren Metadaten zu datieren",
+	"The source is already organized by date. Date files by their folder instead of reading their metadata? (y/n): ":      "Die Quelle ist bereits nach Datum organisiert. Dateien nach ihrem Ordner datieren, statt ihre Metadaten zu lesen? (j/n): ",
+	"Files in YYYY/MM-DD folders are dated by their folder":                                                               "Dateien in JJJJ/MM-TT-Ordnern werden nach ihrem Ordner datiert",
+	"Privacy mode: GPS locations are never read":                                                                          "Datenschutzmodus: GPS-Positionen werden nie gelesen",
+	"Files of unsupported formats are copied to %s/, dated by their modification time":                                    "Dateien nicht unterstützter Formate werden nach %s/ kopiert, datiert nach ihrem Änderungsdatum",
+	"Destination file names are made valid on FAT and exFAT":                                                              "Zieldateinamen werden für FAT und exFAT gültig gemacht",
+	"Destination names differing only in case are treated as the same file":                                               "Zielnamen, die sich nur in der Groß- und Kleinschreibung unterscheiden, werden als dieselbe Datei behandelt",
+	"At most %d files per destination folder, extra files go to part-2/, part-3/...":                                      "Höchstens %d Dateien pro Zielordner, weitere Dateien kommen in part-2/, part-3/...",
+	"Days with more than %d files are split into hour subfolders":                                                         "Tage mit mehr als %d Dateien werden in Stundenunterordner aufgeteilt",
+	"Run tags: %s": "Tags des Imports: %s",
+	"Video proxies and thumbnails (.LRV, .THM) are skipped":                               "Video-Proxys und Miniaturen (.LRV, .THM) werden übersprungen",
+	"Video proxies and thumbnails (.LRV, .THM) are kept in the day folder of their video": "Video-Proxys und Miniaturen (.LRV, .THM) werden im Tagesordner ihres Videos abgelegt",
+	"Video proxies and thumbnails (.LRV, .THM) are placed in the %s tree":                 "Video-Proxys und Miniaturen (.LRV, .THM) werden im Verzeichnisbaum %s abgelegt",
+	"Cull mode: files without a matching %s file are not imported":                        "Aussortiermodus: Dateien ohne passende %s-Datei werden nicht importiert",
+	"Cull mode: files without a matching %s file are deleted from the source":             "Aussortiermodus: Dateien ohne passende %s-Datei werden aus der Quelle gelöscht",
+	"Number of files culled: %d":                                                          "Anzahl aussortierter Dateien: %d",
+	"Number of files unchanged since the last import: %d":                                 "Anzahl seit dem letzten Import unveränderter Dateien: %d",
+	"Skipping user input confirmation (test mode).":                                       "Benutzerbestätigung übersprungen (Testmodus).",
+	"Processing Summary:":                                     "Zusammenfassung der Verarbeitung:",
+	"%d files have been successfully processed":               "%d Dateien wurden erfolgreich verarbeitet",
+	"Number of files copied: %d":                              "Anzahl kopierter Dateien: %d",
+	"Number of files compressed: %d":                          "Anzahl komprimierter Dateien: %d",
+	"Number of files deleted: %d":                             "Anzahl gelöschter Dateien: %d",
+	"Number of files verified before deleting the source: %d": "Anzahl vor dem Löschen der Quelle geprüfter Dateien: %d",
+	"Number of files skipped: %d":                             "Anzahl übersprungener Dateien: %d",
+	"  already in the destination: %d":                        "  bereits im Ziel: %d",
+	"  left out on purpose: %d":                               "  absichtlich ausgelassen: %d",
+	"  destination taken by another file: %d":                 "  Ziel von einer anderen Datei belegt: %d",
+	"  no capture date: %d":                                   "  ohne Aufnahmedatum: %d",
+	"  unreadable: %d":                                        "  unlesbar: %d",
+	"  unsupported format: %d":                                "  nicht unterstütztes Format: %d",
+	"Some skipped files may be missing from the archive, check them before wiping the source": "Einige übersprungene Dateien fehlen möglicherweise im Archiv, prüfen Sie sie, bevor Sie die Quelle löschen",
+	"Number of empty or truncated files: %d":                                                  "Anzahl leerer oder abgeschnittener Dateien: %d",
+	"Number of damaged files partially salvaged: %d":                                          "Anzahl teilweise geretteter beschädigter Dateien: %d",
+	"Report written to: %s":                                                                   "Bericht geschrieben nach: %s",
+	"Edited copies exported by phones are placed next to their originals":                     "Von Telefonen exportierte bearbeitete Kopien werden neben ihren Originalen abgelegt",
+	"Originals of edited copies exported by phones are skipped":                               "Originale von Telefonen exportierter bearbeiteter Kopien werden übersprungen",
+	"Edited copies exported by phones are skipped":                                            "Von Telefonen exportierte bearbeitete Kopien werden übersprungen",
+	"Apple Photos profile: Live Photo videos and AAE sidecars are placed with their photo":    "Apple-Fotos-Profil: Videos von Live Photos und AAE-Begleitdateien werden bei ihrem Foto abgelegt",
+	"Files managed in the Lightroom catalog %s are imported with a warning":                   "Im Lightroom-Katalog %s verwaltete Dateien werden mit einer Warnung importiert",
+	"Files managed in the Lightroom catalog %s are skipped":                                   "Im Lightroom-Katalog %s verwaltete Dateien werden übersprungen",
+	"every RAW format": "alle RAW-Formate",
+	"RAW files (%s) are converted to DNG, originals are kept in %s":             "RAW-Dateien (%s) werden in DNG umgewandelt, die Originale bleiben in %s",
+	"Number of files already managed in Lightroom: %d":                          "Anzahl bereits in Lightroom verwalteter Dateien: %d",
+	"unsupported phone edits policy: %s (expected keep, edited or original)":    "nicht unterstützte Richtlinie für bearbeitete Kopien: %s (erwartet keep, edited oder original)",
+	"unsupported profile: %s (expected apple-photos)":                           "nicht unterstütztes Profil: %s (erwartet apple-photos)",
+	"flagging Lightroom files requires a Lightroom catalog":                     "das Markieren von Lightroom-Dateien erfordert einen Lightroom-Katalog",
+	"Lightroom catalog not found: %s":                                           "Lightroom-Katalog nicht gefunden: %s",
+	"DNG formats and originals require a DNG command":                           "DNG-Formate und -Originale erfordern einen DNG-Befehl",
+	"DNG command must write to {out} or {dir}: %s":                              "der DNG-Befehl muss nach {out} oder {dir} schreiben: %s",
+	"unsupported RAW format: %s (expected nef, cr2, cr3, arw, raf, rw2 or raw)": "nicht unterstütztes RAW-Format: %s (erwartet nef, cr2, cr3, arw, raf, rw2 oder raw)",
+	"Number of edited files copied without recompression: %d":                   "Anzahl bearbeiteter Dateien ohne Neukomprimierung kopiert: %d",
+	"unsupported holiday calendar: %s (expected us, gb, fr or de)":              "nicht unterstützter Feiertagskalender: %s (erwartet us, gb, fr oder de)",
+	"year roots file not found: %s":                                             "Datei der Jahreswurzeln nicht gefunden: %s",
+	"invalid year roots: %v":                                                    "ungültige Jahreswurzeln: %v",
+	"year root directory does not exist: %s":                                    "Jahreswurzel-Verzeichnis existiert nicht: %s",
+	"Files of %s go to: %s":                                                     "Dateien von %s kommen nach: %s",
+	"tier directory does not exist: %s":                                         "Speicherstufen-Verzeichnis existiert nicht: %s",
+	"tier directory must not overlap the source directory: %s":                  "Speicherstufen-Verzeichnis darf sich nicht mit dem Quellverzeichnis überschneiden: %s",
+	"invalid tiering rule: %v":                                                  "ungültige Speicherstufen-Regel: %v",
+	"Files older than %s go to: %s":                                             "Dateien älter als %s kommen nach: %s",
+	"report file not found: %s":                                                 "Berichtsdatei nicht gefunden: %s",
+	"importing only the errors of a run requires its report file":               "Nur die Fehler eines Laufs zu importieren erfordert dessen Berichtsdatei",
+	"failed to read report: %v":                                                 "Bericht konnte nicht gelesen werden: %v",
+	"No file to import again in report %s":                                      "Keine erneut zu importierende Datei im Bericht %s",
+	"Failed to publish the run status: %v":                                      "Veröffentlichen des Importstatus fehlgeschlagen: %v",
+	"Run status %s published to %s":                                             "Importstatus %s veröffentlicht auf %s",
+	"Failed to send the run summary: %v":                                        "Senden der Zusammenfassung fehlgeschlagen: %v",
+	"Run summary sent to %s":                                                    "Zusammenfassung gesendet an %s",
+	"[organize-media] %s: %d files imported, %d failed":                         "[organize-media] %s: %d Dateien importiert, %d fehlgeschlagen",
+	"[organize-media] %s: import failed":                                        "[organize-media] %s: Import fehlgeschlagen",
+	"Bytes written: %s":                                                         "Geschriebene Bytes: %s",
+	"Errors:":                                                                   "Fehler:",
+	"... and %d more, see the attached report":                                  "... und %d weitere, siehe angehängten Bericht",
+	"Timeline written to: %s":                                                   "Zeitleiste geschrieben nach: %s",
+	"Number of files failed: %d":                                                "Anzahl fehlgeschlagener Dateien: %d",
+	"Source volume not ejected: %d files had errors":                            "Quellvolume nicht ausgeworfen: %d Dateien hatten Fehler",
+	"Failed to eject source volume: %v":                                         "Auswerfen des Quellvolumes fehlgeschlagen: %v",
+	"Source volume ejected, the card can be removed safely.":                    "Quellvolume ausgeworfen, die Karte kann sicher entfernt werden.",
+	"Number of metadata cache hits: %d":                                         "Anzahl Metadaten aus dem Cache: %d",
+	"Processing completed in %v":                                                "Verarbeitung abgeschlossen in %v",
+	"Average time per file: %.2f seconds":                                       "Durchschnittliche Zeit pro Datei: %.2f Sekunden",
+	"Read: %s at %s average, %s peak":                                           "Gelesen: %s mit %s im Mittel, %s Spitze",
+	"Written: %s at %s average, %s peak":                                        "Geschrieben: %s mit %s im Mittel, %s Spitze",
+	"Time per phase: scan %v, read %v, extract %v, compress %v, write %v":       "Zeit pro Phase: Durchlauf %v, Lesen %v, Extraktion %v, Komprimierung %v, Schreiben %v",
+	"Worker utilization: %.0f%% (%d workers)":                                   "Worker-Auslastung: %.0f%% (%d Worker)",
+	"Process completed.":                                                        "Vorgang abgeschlossen.",
+
+	// Errors
+	"source directory is required":                                                            "Quellordner ist erforderlich",
+	"destination directory is required":                                                       "Zielordner ist erforderlich",
+	"source directory does not exist: %s":                                                     "Quellordner existiert nicht: %s",
+	"destination directory does not exist: %s":                                                "Zielordner existiert nicht: %s",
+	"compression level must be an integer between 0 and 100":                                  "Komprimierungsstufe muss eine ganze Zahl zwischen 0 und 100 sein",
+	"unsupported hash algorithm: %s (expected sha256 or blake3)":                              "nicht unterstützter Hash-Algorithmus: %s (sha256 oder blake3 erwartet)",
+	"unsupported cull format: %s (expected jpeg or raw)":                                      "nicht unterstütztes Aussortierformat: %s (jpeg oder raw erwartet)",
+	"deleting culled files requires a cull format":                                            "das Löschen aussortierter Dateien erfordert ein Aussortierformat",
+	"unsupported bracket layout: %s (expected folder or stem)":                                "nicht unterstütztes Layout für Belichtungsreihen: %s (folder oder stem erwartet)",
+	"unsupported route: %s (expected timelapse or pano)":                                      "nicht unterstützte Weiterleitung: %s (timelapse oder pano erwartet)",
+	"unsupported album mode: %s (expected links or tags)":                                     "nicht unterstützter Albummodus: %s (links oder tags erwartet)",
+	"unsupported provenance mode: %s (expected embed or sidecar)":                             "nicht unterstützter Herkunftsmodus: %s (embed oder sidecar erwartet)",
+	"unsupported proxy policy: %s (expected skip, keep or route)":                             "nicht unterstützte Proxy-Richtlinie: %s (skip, keep oder route erwartet)",
+	"album tags require a catalog or report file":                                             "Album-Tags erfordern eine Katalog- oder Berichtsdatei",
+	"invalid number of workers: %d":                                                           "ungültige Anzahl an Workern: %d",
+	"invalid settle time: %v":                                                                 "ungültige Wartezeit: %v",
+	"MQTT settings file not found: %s":                                                        "Datei der MQTT-Einstellungen nicht gefunden: %s",
+	"SMTP settings file not found: %s":                                                        "Datei der SMTP-Einstellungen nicht gefunden: %s",
+	"clock offsets file not found: %s":                                                        "Datei der Uhrabweichungen nicht gefunden: %s",
+	"unsupported screenshot policy: %s (expected route, keep or skip)":                        "nicht unterstützte Richtlinie für Bildschirmfotos: %s (erwartet route, keep oder skip)",
+	"unsupported destination file system: %s (expected fat or native)":                        "nicht unterstütztes Zieldateisystem: %s (erwartet: fat oder native)",
+	"invalid maximum number of files per directory: %d":                                       "ungültige Höchstzahl an Dateien pro Ordner: %d",
+	"invalid shard threshold: %d":                                                             "ungültiger Schwellenwert für die Aufteilung: %d",
+	"incremental mode requires a catalog file":                                                "Inkrementeller Modus erfordert eine Katalogdatei",
+	"error counting files: %v":                                                                "Fehler beim Zählen der Dateien: %v",
+	"no files to process in source directory":                                                 "keine zu verarbeitenden Dateien im Quellordner",
+	"confirmation required but standard input is not a terminal, use -yes to skip the prompt": "Bestätigung erforderlich, aber die Standardeingabe ist kein Terminal, verwenden Sie -yes, um die Abfrage zu überspringen",
+	"error planning files: %v":                                                                "Fehler bei der Planung der Dateien: %v",
+	"error reading input: %v":                                                                 "Fehler beim Lesen der Eingabe: %v",
+	"operation cancelled by user":                                                             "Vorgang vom Benutzer abgebrochen",
+	"destination directory is not writable: %v":                                               "Zielordner ist nicht beschreibbar: %v",
+	"error moving files: %v":                                                                  "Fehler beim Verschieben der Dateien: %v",
+	"failed to resolve source path: %v":                                                       "Quellpfad konnte nicht aufgelöst werden: %v",
+	"failed to resolve destination path: %v":                                                  "Zielpfad konnte nicht aufgelöst werden: %v",
+	"source and destination must be different directories: %s":                                "Quelle und Ziel müssen verschiedene Ordner sein: %s",
+	"destination directory must not be inside the source directory: %s is inside %s":          "Zielordner darf nicht im Quellordner liegen: %s liegt in %s",
+	"source directory must not be inside the destination directory: %s is inside %s":          "Quellordner darf nicht im Zielordner liegen: %s liegt in %s",
+
+	// Memory card detection
+	"failed to list mounted volumes: %v":      "eingehängte Laufwerke konnten nicht aufgelistet werden: %v",
+	"no memory card with a DCIM folder found": "keine Speicherkarte mit DCIM-Ordner gefunden",
+	"Using memory card: %s\n":                 "Verwende Speicherkarte: %s\n",
+	"Importing all %d memory cards\n":         "Importiere alle %d Speicherkarten\n",
+	"Memory cards found:\n":                   "Gefundene Speicherkarten:\n",
+	"Select a card (1-%d) or a for all: ":     "Karte wählen (1-%d) oder a für alle: ",
+	"invalid selection: %s":                   "ungültige Auswahl: %s",
+
+	// Strict mode
+	"Would be skipped %s: %v":                                      "Würde übersprungen %s: %v",
+	"strict mode: %d files would be skipped, nothing was imported": "strikter Modus: %d Dateien würden übersprungen, nichts wurde importiert",
+
+	// Precheck
+	"Checking that every source file can be read...":           "Prüfe, ob jede Quelldatei gelesen werden kann...",
+	"error checking source files: %v":                          "Fehler beim Prüfen der Quelldateien: %v",
+	"Unreadable file %s: %v":                                   "Nicht lesbare Datei %s: %v",
+	"precheck found %d unreadable files, nothing was imported": "Vorabprüfung hat %d nicht lesbare Dateien gefunden, nichts wurde importiert",
+	"Precheck passed: all source files are readable.":          "Vorabprüfung bestanden: alle Quelldateien sind lesbar.",
+}
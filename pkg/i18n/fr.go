@@ -0,0 +1,209 @@
+package i18n
+
+// french holds the French translations of user-facing messages
+var french = map[string]string{
+	// Prompts
+	"Number of files to process: %d [%s]\n":                      "Nombre de fichiers à traiter : %d [%s]\n",
+	"Planned destinations:\n":                                    "Destinations prévues :\n",
+	"  ... and %d more\n":                                        "  ... et %d de plus\n",
+	"New destination folders (%d): ":                             "Nouveaux dossiers de destination (%d) : ",
+	"%d files have no date and will be skipped\n":                "%d fichiers n'ont pas de date et seront ignorés\n",
+	"%d files are empty or truncated and will not be imported\n": "%d fichiers sont vides ou tronqués et ne seront pas importés\n",
+	"Do you want to proceed with processing %d files? (y/n): ":   "Voulez-vous traiter %d fichiers ? (o/n) : ",
+	"Operation cancelled.":                                       "Opération annulée.",
+
+	// Run information and summary
+	"Application started.":      "Application démarrée.",
+	"Source directory: %s":      "Dossier source : %s",
+	"Destination directory: %s": "Dossier de destination : %s",
+	"Destination files are encrypted with the key in %s (hashed names: %t)":                    "Les fichiers de destination sont chiffrés avec la clé de %s (noms hachés : %t)",
+	"hashing file names requires an encryption key":                                            "le hachage des noms de fichiers nécessite une clé de chiffrement",
+	"encryption key file not found: %s":                                                        "fichier de clé de chiffrement introuvable : %s",
+	"deleting source files is not supported with rclone destinations":                          "la suppression des fichiers source n'est pas prise en charge avec les destinations rclone",
+	"importing only new files since the last import is not supported with rclone destinations": "l'import des seuls nouveaux fichiers depuis le dernier import n'est pas pris en charge avec les destinations rclone",
+	"Listing files already on %s...":                                                           "Liste des fichiers déjà présents sur %s...",
+	"failed to create staging folder: %v":                                                      "échec de la création du dossier de préparation : %v",
+	"not enough space to stage %s in %s: %s available, choose another folder with -staging":    "pas assez d'espace pour préparer %s dans %s : %s disponibles, choisissez un autre dossier avec -staging",
+	"Staging files in %s before uploading to %s":                                               "Préparation des fichiers dans %s avant l'envoi vers %s",
+	"Uploading to %s...":                                              "Envoi vers %s...",
+	"Files uploaded to %s":                                            "Fichiers envoyés vers %s",
+	"Mirror directory: %s":                                            "Répertoire miroir : %s",
+	"Number of files mirrored: %d":                                    "Nombre de fichiers copiés vers les miroirs : %d",
+	"Number of files rejected by the check command: %d":               "Nombre de fichiers rejetés par la commande de vérification : %d",
+	"Check command: %s":                                               "Commande de vérification : %s",
+	"quarantine requires a check command":                             "la quarantaine nécessite une commande de vérification",
+	"Number of files write-protected: %d":                             "Nombre de fichiers protégés en écriture : %d",
+	"Number of files failed to mirror: %d":                            "Nombre de fichiers non copiés vers les miroirs : %d",
+	"mirror directory does not exist: %s":                             "le répertoire miroir n'existe pas : %s",
+	"mirror directory must not overlap the destination directory: %s": "le répertoire miroir ne doit pas chevaucher le répertoire de destination : %s",
+	"mirror directory must not overlap the source directory: %s":      "le répertoire miroir ne doit pas chevaucher le répertoire source : %s",
+	"Compression level: %d":                                           "Niveau de compression : %d",
+	"Compression: not applied":                                        "Compression : non appliquée",
+	"Delete source files: %t":                                         "Suppression des fichiers source : %t",
+	"Workers: auto":                                                   "Workers : automatique",
+	"Workers: %d":                                                     "Workers : %d",
+	"Low-power mode: at most 2 workers, copies checked by size and pauses between files on battery": "Mode économie d'énergie : 2 workers au plus, copies vérifiées par leur taille et pauses entre les fichiers sur batterie",
+	"Catalog: %s (incremental: %t)": "Catalogue : %s (incrémental : %t)",
+	"Only importing files added or modified since the last import from this source": "Import des seuls fichiers ajoutés ou modifiés depuis le dernier import de cette source",
+	"Bracketed sequences are placed in their own subfolder":                         "Les séquences de bracketing sont placées dans leur propre sous-dossier",
+	"Bracketed sequences are named after their first frame":                         "Les séquences de bracketing sont nommées d'après leur première image",
+	"Routed to their own day subfolder: %s":                                         "Placés dans leur propre sous-dossier du jour : %s",
+	"invalid chaos rates: %v":                                                       "taux de chaos invalides : %v",
+	"Chaos mode: IO faults are injected at rates %s (seed %d)":                      "Mode chaos : des erreurs d'E/S sont injectées aux taux %s (graine %d)",
+	"Number of injected IO faults: %d":                                              "Nombre d'erreurs d'E/S injectées : %d",
+	"Folder layout: %s":                                                             "Organisation des dossiers : %s",
+	"invalid layout: %v":                                                            "organisation des dossiers invalide : %v",
+	"File names: %s":                                                                "Noms des fichiers : %s",
+	"invalid name template: %v":                                                     "modèle de nom invalide : %v",
+	"Files written less than %v ago or still open for writing are left for the next run":                                  "Les fichiers écrits il y a moins de %v ou encore ouverts en écriture sont laissés pour le prochain import",
+	"Camera clocks are corrected with the offsets in %s":                                                                  "Les horloges des appareils sont corrigées avec les décalages de %s",
+	"Screenshots are placed in the %s/YYYY/MM tree":                                                                       "Les captures d'écran sont placées dans l'arborescence %s/AAAA/MM",
+	"Screenshots are skipped":                                                                                             "Les captures d'écran sont ignorées",
+	"No date found for %s, enter its date (YYYY-MM-DD, - to skip): ":                                                      "Aucune date trouvée pour %s, saisissez sa date (AAAA-MM-JJ, - pour l'ignorer) : ",
+	"The source is already organized by date, use -trust-folders to date files by their folder instead of their metadata": "La source est déjà organisée par date, utilisez -trust-folders pour dater les fichiers par leur dossier plutôt que par leurs métadonnées",
+	"The source is already organized by date. Date files by their folder instead of reading their metadata? (y/n): ":      "La source est déjà organisée par date. Dater les fichiers par leur dossier plutôt que de lire leurs métadonnées ? (o/n) : ",
+	"Files in YYYY/MM-DD folders are dated by their folder":                                                               "Les fichiers des dossiers AAAA/MM-JJ sont datés par leur dossier",
+	"Privacy mode: GPS locations are never read":                                                                          "Mode confidentialité : les positions GPS ne sont jamais lues",
+	"Files of unsupported formats are copied to %s/, dated by their modification time":                                    "Les fichiers de formats non pris en charge sont copiés dans %s/, datés par leur date de modification",
+	"Destination file names are made valid on FAT and exFAT":                                                              "Les noms de fichiers de destination sont rendus valides sur FAT et exFAT",
+	"Destination names differing only in case are treated as the same file":                                               "Les noms de destination ne différant que par la casse sont traités comme le même fichier",
+	"At most %d files per destination folder, extra files go to part-2/, part-3/...":                                      "Au plus %d fichiers par dossier de destination, les suivants vont dans part-2/, part-3/...",
+	"Days with more than %d files are split into hour subfolders":                                                         "Les jours de plus de %d fichiers sont répartis en sous-dossiers par heure",
+	"Run tags: %s": "Tags de l'import : %s",
+	"Video proxies and thumbnails (.LRV, .THM) are skipped":                               "Les proxies et vignettes vidéo (.LRV, .THM) sont ignorés",
+	"Video proxies and thumbnails (.LRV, .THM) are kept in the day folder of their video": "Les proxies et vignettes vidéo (.LRV, .THM) sont placés dans le dossier du jour de leur vidéo",
+	"Video proxies and thumbnails (.LRV, .THM) are placed in the %s tree":                 "Les proxies et vignettes vidéo (.LRV, .THM) sont placés dans l'arborescence %s",
+	"Cull mode: files without a matching %s file are not imported":                        "Mode tri : les fichiers sans fichier %s correspondant ne sont pas importés",
+	"Cull mode: files without a matching %s file are deleted from the source":             "Mode tri : les fichiers sans fichier %s correspondant sont supprimés de la source",
+	"Number of files culled: %d":                                                          "Nombre de fichiers écartés par le tri : %d",
+	"Number of files unchanged since the last import: %d":                                 "Nombre de fichiers inchangés depuis le dernier import : %d",
+	"Skipping user input confirmation (test mode).":                                       "Confirmation utilisateur ignorée (mode test).",
+	"Processing Summary:":                                     "Résumé du traitement :",
+	"%d files have been successfully processed":               "%d fichiers ont été traités avec succès",
+	"Number of files copied: %d":                              "Nombre de fichiers copiés : %d",
+	"Number of files compressed: %d":                          "Nombre de fichiers compressés : %d",
+	"Number of files deleted: %d":                             "Nombre de fichiers supprimés : %d",
+	"Number of files verified before deleting the source: %d": "Nombre de fichiers vérifiés avant suppression de la source : %d",
+	"Number of files skipped: %d":                             "Nombre de fichiers ignorés : %d",
+	"  already in the destination: %d":                        "  déjà dans la destination : %d",
+	"  left out on purpose: %d":                               "  écartés volontairement : %d",
+	"  destination taken by another file: %d":                 "  destination occupée par un autre fichier : %d",
+	"  no capture date: %d":                                   "  sans date de prise de vue : %d",
+	"  unreadable: %d":                                        "  illisibles : %d",
+	"  unsupported format: %d":                                "  format non pris en charge : %d",
+	"Some skipped files may be missing from the archive, check them before wiping the source": "Certains fichiers ignorés peuvent manquer dans l'archive, vérifiez-les avant d'effacer la source",
+	"Number of empty or truncated files: %d":                                                  "Nombre de fichiers vides ou tronqués : %d",
+	"Number of damaged files partially salvaged: %d":                                          "Nombre de fichiers endommagés partiellement récupérés : %d",
+	"Report written to: %s":                                                                   "Rapport écrit dans : %s",
+	"Edited copies exported by phones are placed next to their originals":                     "Les copies retouchées exportées par les téléphones sont placées à côté de leurs originaux",
+	"Originals of edited copies exported by phones are skipped":                               "Les originaux des copies retouchées exportées par les téléphones sont ignorés",
+	"Edited copies exported by phones are skipped":                                            "Les copies retouchées exportées par les téléphones sont ignorées",
+	"Apple Photos profile: Live Photo videos and AAE sidecars are placed with their photo":    "Profil Apple Photos : les vidéos des Live Photos et les fichiers annexes AAE sont placés avec leur photo",
+	"Files managed in the Lightroom catalog %s are imported with a warning":                   "Les fichiers gérés dans le catalogue Lightroom %s sont importés avec un avertissement",
+	"Files managed in the Lightroom catalog %s are skipped":                                   "Les fichiers gérés dans le catalogue Lightroom %s sont ignorés",
+	"every RAW format": "tous les formats RAW",
+	"RAW files (%s) are converted to DNG, originals are kept in %s":             "Les fichiers RAW (%s) sont convertis en DNG, les originaux sont conservés dans %s",
+	"Number of files already managed in Lightroom: %d":                          "Nombre de fichiers déjà gérés dans Lightroom : %d",
+	"unsupported phone edits policy: %s (expected keep, edited or original)":    "politique de copies retouchées non prise en charge : %s (attendu keep, edited ou original)",
+	"unsupported profile: %s (expected apple-photos)":                           "profil non pris en charge : %s (attendu apple-photos)",
+	"flagging Lightroom files requires a Lightroom catalog":                     "le signalement des fichiers Lightroom nécessite un catalogue Lightroom",
+	"Lightroom catalog not found: %s":                                           "catalogue Lightroom introuvable : %s",
+	"DNG formats and originals require a DNG command":                           "les formats et les originaux DNG nécessitent une commande DNG",
+	"DNG command must write to {out} or {dir}: %s":                              "la commande DNG doit écrire dans {out} ou {dir} : %s",
+	"unsupported RAW format: %s (expected nef, cr2, cr3, arw, raf, rw2 or raw)": "format RAW non pris en charge : %s (attendu nef, cr2, cr3, arw, raf, rw2 ou raw)",
+	"Number of edited files copied without recompression: %d":                   "Nombre de fichiers retouchés copiés sans recompression : %d",
+	"unsupported holiday calendar: %s (expected us, gb, fr or de)":              "calendrier des jours fériés non pris en charge : %s (attendu us, gb, fr ou de)",
+	"year roots file not found: %s":                                             "fichier des racines par année introuvable : %s",
+	"invalid year roots: %v":                                                    "racines par année invalides : %v",
+	"year root directory does not exist: %s":                                    "le répertoire racine d'année n'existe pas : %s",
+	"Files of %s go to: %s":                                                     "Les fichiers de %s vont dans : %s",
+	"tier directory does not exist: %s":                                         "le répertoire de niveau de stockage n'existe pas : %s",
+	"tier directory must not overlap the source directory: %s":                  "le répertoire de niveau de stockage ne doit pas chevaucher le répertoire source : %s",
+	"invalid tiering rule: %v":                                                  "règle de niveau de stockage invalide : %v",
+	"Files older than %s go to: %s":                                             "Les fichiers de plus de %s vont dans : %s",
+	"report file not found: %s":                                                 "fichier de rapport introuvable : %s",
+	"importing only the errors of a run requires its report file":               "importer uniquement les erreurs d'une exécution nécessite son fichier de rapport",
+	"failed to read report: %v":                                                 "échec de la lecture du rapport : %v",
+	"No file to import again in report %s":                                      "Aucun fichier à réimporter dans le rapport %s",
+	"Failed to publish the run status: %v":                                      "Échec de la publication de l'état de l'import : %v",
+	"Run status %s published to %s":                                             "État de l'import %s publié sur %s",
+	"Failed to send the run summary: %v":                                        "Échec de l'envoi du résumé de l'import : %v",
+	"Run summary sent to %s":                                                    "Résumé de l'import envoyé à %s",
+	"[organize-media] %s: %d files imported, %d failed":                         "[organize-media] %s : %d fichiers importés, %d en échec",
+	"[organize-media] %s: import failed":                                        "[organize-media] %s : échec de l'import",
+	"Bytes written: %s":                                                         "Octets écrits : %s",
+	"Errors:":                                                                   "Erreurs :",
+	"... and %d more, see the attached report":                                  "... et %d de plus, voir le rapport joint",
+	"Timeline written to: %s":                                                   "Chronologie écrite dans : %s",
+	"Number of files failed: %d":                                                "Nombre de fichiers en échec : %d",
+	"Source volume not ejected: %d files had errors":                            "Volume source non éjecté : %d fichiers ont rencontré des erreurs",
+	"Failed to eject source volume: %v":                                         "Échec de l'éjection du volume source : %v",
+	"Source volume ejected, the card can be removed safely.":                    "Volume source éjecté, la carte peut être retirée en toute sécurité.",
+	"Number of metadata cache hits: %d":                                         "Nombre de métadonnées lues depuis le cache : %d",
+	"Processing completed in %v":                                                "Traitement terminé en %v",
+	"Average time per file: %.2f seconds":                                       "Temps moyen par fichier : %.2f secondes",
+	"Read: %s at %s average, %s peak":                                           "Lu : %s à %s en moyenne, %s en pointe",
+	"Written: %s at %s average, %s peak":                                        "Écrit : %s à %s en moyenne, %s en pointe",
+	"Time per phase: scan %v, read %v, extract %v, compress %v, write %v":       "Temps par phase : parcours %v, lecture %v, extraction %v, compression %v, écriture %v",
+	"Worker utilization: %.0f%% (%d workers)":                                   "Utilisation des workers : %.0f%% (%d workers)",
+	"Process completed.":                                                        "Processus terminé.",
+
+	// Errors
+	"source directory is required":                                                            "le dossier source est requis",
+	"destination directory is required":                                                       "le dossier de destination est requis",
+	"source directory does not exist: %s":                                                     "le dossier source n'existe pas : %s",
+	"destination directory does not exist: %s":                                                "le dossier de destination n'existe pas : %s",
+	"compression level must be an integer between 0 and 100":                                  "le niveau de compression doit être un entier entre 0 et 100",
+	"unsupported hash algorithm: %s (expected sha256 or blake3)":                              "algorithme de hachage non pris en charge : %s (sha256 ou blake3 attendu)",
+	"unsupported cull format: %s (expected jpeg or raw)":                                      "format de tri non pris en charge : %s (jpeg ou raw attendu)",
+	"deleting culled files requires a cull format":                                            "la suppression des fichiers écartés nécessite un format de tri",
+	"unsupported bracket layout: %s (expected folder or stem)":                                "disposition des séquences de bracketing non prise en charge : %s (folder ou stem attendu)",
+	"unsupported route: %s (expected timelapse or pano)":                                      "routage non pris en charge : %s (timelapse ou pano attendu)",
+	"unsupported album mode: %s (expected links or tags)":                                     "mode d'albums non pris en charge : %s (links ou tags attendu)",
+	"unsupported provenance mode: %s (expected embed or sidecar)":                             "mode de provenance non pris en charge : %s (embed ou sidecar attendu)",
+	"unsupported proxy policy: %s (expected skip, keep or route)":                             "politique de proxies non prise en charge : %s (skip, keep ou route attendu)",
+	"album tags require a catalog or report file":                                             "les tags d'albums nécessitent un fichier de catalogue ou de rapport",
+	"invalid number of workers: %d":                                                           "nombre de workers invalide : %d",
+	"invalid settle time: %v":                                                                 "délai de stabilisation invalide : %v",
+	"MQTT settings file not found: %s":                                                        "fichier des paramètres MQTT introuvable : %s",
+	"SMTP settings file not found: %s":                                                        "fichier des paramètres SMTP introuvable : %s",
+	"clock offsets file not found: %s":                                                        "fichier des décalages d'horloge introuvable : %s",
+	"unsupported screenshot policy: %s (expected route, keep or skip)":                        "politique de captures d'écran non prise en charge : %s (attendu route, keep ou skip)",
+	"unsupported destination file system: %s (expected fat or native)":                        "système de fichiers de destination non pris en charge : %s (attendu : fat ou native)",
+	"invalid maximum number of files per directory: %d":                                       "nombre maximal de fichiers par dossier invalide : %d",
+	"invalid shard threshold: %d":                                                             "seuil de répartition invalide : %d",
+	"incremental mode requires a catalog file":                                                "le mode incrémental nécessite un fichier catalogue",
+	"error counting files: %v":                                                                "erreur lors du comptage des fichiers : %v",
+	"no files to process in source directory":                                                 "aucun fichier à traiter dans le dossier source",
+	"confirmation required but standard input is not a terminal, use -yes to skip the prompt": "confirmation requise mais l'entrée standard n'est pas un terminal, utilisez -yes pour ignorer la question",
+	"error planning files: %v":                                                                "erreur lors de la planification des fichiers : %v",
+	"error reading input: %v":                                                                 "erreur de lecture de la saisie : %v",
+	"operation cancelled by user":                                                             "opération annulée par l'utilisateur",
+	"destination directory is not writable: %v":                                               "le dossier de destination n'est pas accessible en écriture : %v",
+	"error moving files: %v":                                                                  "erreur lors du déplacement des fichiers : %v",
+	"failed to resolve source path: %v":                                                       "impossible de résoudre le chemin source : %v",
+	"failed to resolve destination path: %v":                                                  "impossible de résoudre le chemin de destination : %v",
+	"source and destination must be different directories: %s":                                "la source et la destination doivent être des dossiers différents : %s",
+	"destination directory must not be inside the source directory: %s is inside %s":          "le dossier de destination ne doit pas être dans le dossier source : %s est dans %s",
+	"source directory must not be inside the destination directory: %s is inside %s":          "le dossier source ne doit pas être dans le dossier de destination : %s est dans %s",
+
+	// Memory card detection
+	"failed to list mounted volumes: %v":      "impossible de lister les volumes montés : %v",
+	"no memory card with a DCIM folder found": "aucune carte mémoire avec un dossier DCIM trouvée",
+	"Using memory card: %s\n":                 "Utilisation de la carte mémoire : %s\n",
+	"Importing all %d memory cards\n":         "Import des %d cartes mémoire\n",
+	"Memory cards found:\n":                   "Cartes mémoire trouvées :\n",
+	"Select a card (1-%d) or a for all: ":     "Choisissez une carte (1-%d) ou a pour toutes : ",
+	"invalid selection: %s":                   "sélection invalide : %s",
+
+	// Strict mode
+	"Would be skipped %s: %v":                                      "Serait ignoré %s : %v",
+	"strict mode: %d files would be skipped, nothing was imported": "mode strict : %d fichiers seraient ignorés, rien n'a été importé",
+
+	// Precheck
+	"Checking that every source file can be read...":           "Vérification de la lecture de chaque fichier source...",
+	"error checking source files: %v":                          "erreur lors de la vérification des fichiers source : %v",
+	"Unreadable file %s: %v":                                   "Fichier illisible %s : %v",
+	"precheck found %d unreadable files, nothing was imported": "la vérification a trouvé %d fichiers illisibles, rien n'a été importé",
+	"Precheck passed: all source files are readable.":          "Vérification réussie : tous les fichiers source sont lisibles.",
+}
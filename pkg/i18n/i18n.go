@@ -0,0 +1,145 @@
+// Package i18n translates user-facing messages. Messages are keyed by their
+// English format string, so untranslated messages fall back to English.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is used when no supported language is requested
+const DefaultLanguage = "en"
+
+// bundles maps a language code to its translations, keyed by English format string
+var bundles = map[string]map[string]string{
+	"fr": french,
+	"de": german,
+}
+
+// yesAnswers lists the accepted confirmations per language, in addition to "y"
+var yesAnswers = map[string][]string{
+	"en": {"y", "yes"},
+	"fr": {"o", "oui"},
+	"de": {"j", "ja"},
+}
+
+var (
+	mu      sync.RWMutex
+	current = DefaultLanguage
+)
+
+// SetLanguage selects the language of translated messages
+func SetLanguage(lang string) error {
+	code := normalize(lang)
+	if code != DefaultLanguage && bundles[code] == nil {
+		return fmt.Errorf("unsupported language: %s (supported: %s)", lang, strings.Join(Supported(), ", "))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	current = code
+	return nil
+}
+
+// Language returns the selected language code
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Supported returns the supported language codes
+func Supported() []string {
+	return []string{"en", "fr", "de"}
+}
+
+// DetectLanguage returns the supported language of the user's locale
+// (LC_ALL, LC_MESSAGES, LANG), or the default language
+func DetectLanguage() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		code := normalize(value)
+		if code == DefaultLanguage || bundles[code] != nil {
+			return code
+		}
+		// The first variable set takes precedence, even if unsupported
+		return DefaultLanguage
+	}
+	return DefaultLanguage
+}
+
+// T translates a message
+func T(message string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if translated, ok := bundles[current][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Sprintf translates a format string and formats it
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Errorf translates a format string and returns it as an error
+func Errorf(format string, args ...any) error {
+	if len(args) == 0 {
+		return errors.New(T(format))
+	}
+	return fmt.Errorf(T(format), args...)
+}
+
+// Wrapf is like Errorf, the error also matching kind with errors.Is so
+// callers can tell errors apart whatever the language
+func Wrapf(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, err: Errorf(format, args...)}
+}
+
+// kindError is a translated error classified by a sentinel error
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// IsYes reports whether answer confirms a prompt in the selected language
+func IsYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "y" {
+		return true
+	}
+	for _, yes := range yesAnswers[Language()] {
+		if answer == yes {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize turns locale names such as "fr_FR.UTF-8" or "de-DE" into a language code
+func normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "_-.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if lang == "" || lang == "c" || lang == "posix" {
+		return DefaultLanguage
+	}
+	return lang
+}
@@ -0,0 +1,128 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetLanguage(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	tests := []struct {
+		name    string
+		lang    string
+		want    string
+		wantErr bool
+	}{
+		{name: "English", lang: "en", want: "en"},
+		{name: "French locale", lang: "fr_FR.UTF-8", want: "fr"},
+		{name: "German tag", lang: "de-DE", want: "de"},
+		{name: "POSIX locale", lang: "C", want: "en"},
+		{name: "Unsupported language", lang: "xx", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetLanguage(tt.lang)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetLanguage(%q) error = %v, wantErr %v", tt.lang, err, tt.wantErr)
+			}
+			if !tt.wantErr && Language() != tt.want {
+				t.Errorf("Language() = %s, want %s", Language(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{name: "No locale", env: map[string]string{}, want: "en"},
+		{name: "LANG", env: map[string]string{"LANG": "de_DE.UTF-8"}, want: "de"},
+		{name: "LC_ALL overrides LANG", env: map[string]string{"LC_ALL": "fr_FR.UTF-8", "LANG": "de_DE.UTF-8"}, want: "fr"},
+		{name: "Unsupported locale", env: map[string]string{"LANG": "ja_JP.UTF-8"}, want: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+				t.Setenv(env, tt.env[env])
+			}
+			if got := DetectLanguage(); got != tt.want {
+				t.Errorf("DetectLanguage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	if got := T("Operation cancelled."); got != "Operation cancelled." {
+		t.Errorf("T() in English = %q, want the original message", got)
+	}
+
+	if err := SetLanguage("fr"); err != nil {
+		t.Fatalf("SetLanguage() unexpected error: %v", err)
+	}
+	if got := Sprintf("Number of files copied: %d", 3); got != "Nombre de fichiers copiés : 3" {
+		t.Errorf("Sprintf() in French = %q", got)
+	}
+	if got := Errorf("operation cancelled by user").Error(); got != "opération annulée par l'utilisateur" {
+		t.Errorf("Errorf() in French = %q", got)
+	}
+	if got := T("untranslated message"); got != "untranslated message" {
+		t.Errorf("T() should fall back to English, got %q", got)
+	}
+}
+
+func TestIsYes(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	tests := []struct {
+		lang   string
+		answer string
+		want   bool
+	}{
+		{lang: "en", answer: "y", want: true},
+		{lang: "en", answer: "Yes", want: true},
+		{lang: "en", answer: "n", want: false},
+		{lang: "fr", answer: "o", want: true},
+		{lang: "fr", answer: "oui", want: true},
+		{lang: "fr", answer: "y", want: true},
+		{lang: "de", answer: "J", want: true},
+		{lang: "de", answer: "nein", want: false},
+		{lang: "de", answer: "oui", want: false},
+	}
+
+	for _, tt := range tests {
+		if err := SetLanguage(tt.lang); err != nil {
+			t.Fatalf("SetLanguage() unexpected error: %v", err)
+		}
+		if got := IsYes(tt.answer); got != tt.want {
+			t.Errorf("IsYes(%q) in %s = %v, want %v", tt.answer, tt.lang, got, tt.want)
+		}
+	}
+}
+
+// TestBundlesComplete ensures every bundle translates the same messages with matching verbs
+func TestBundlesComplete(t *testing.T) {
+	for lang, bundle := range bundles {
+		for other, otherBundle := range bundles {
+			for key := range otherBundle {
+				if _, ok := bundle[key]; !ok {
+					t.Errorf("%s bundle is missing %q translated in %s", lang, key, other)
+				}
+			}
+		}
+
+		for key, translated := range bundle {
+			if strings.Count(key, "%") != strings.Count(translated, "%") {
+				t.Errorf("%s translation of %q does not use the same format verbs: %q", lang, key, translated)
+			}
+		}
+	}
+}
@@ -1,10 +1,79 @@
 package models
 
+import "time"
+
+// AutoWorkers selects a number of workers tuned during the run
+const AutoWorkers = -1
+
 type Params struct {
-	Source        string
-	Destination   string
-	Compression   int
-	SkipUserInput bool // Flag to bypass user input
-	DeleteSource  bool // Flag to delete source files after processing
-	EnableLog     bool // Flag to enable logging
+	Source           string
+	Destination      string
+	Mirrors          []string // Additional destinations receiving a copy of every organized file (optional)
+	StagingDir       string   // Local folder staging the files of rclone destinations before their upload, the system temporary folder when empty (optional)
+	Tiers            []string // Tiering rules such as 2y=/cold-archive, sending files older than an age to another folder (optional)
+	YearRootsFile    string   // JSON file mapping years or ranges of years to destination roots (optional)
+	Compression      int
+	KeepEdits        bool              // Flag to copy JPEG files edited in Photoshop, Lightroom or other editors as is instead of recompressing them
+	SkipUserInput    bool              // Flag to bypass user input
+	DeleteSource     bool              // Flag to delete source files after processing
+	EnableLog        bool              // Flag to enable logging
+	CacheFile        string            // Path to the metadata cache file (optional)
+	CatalogFile      string            // Path to the catalog of imported files (optional)
+	Incremental      bool              // Flag to skip files already recorded in the catalog
+	HashAlgo         string            // Hash algorithm of catalog records: sha256 (default) or blake3
+	Workers          int               // Number of files processed concurrently, or AutoWorkers
+	LowPower         bool              // Spare the battery: fewer workers, and on battery copies checked by size only and pauses between files
+	SinceLast        bool              // Flag to only import files added or modified since the last import from the source
+	Strict           bool              // Flag to import nothing if any file would be skipped
+	Precheck         bool              // Flag to read every source file before importing
+	SalvageDamaged   bool              // Flag to keep the readable part of files failing mid-read
+	IsolateCorrupt   bool              // Flag to copy empty and truncated files to a corrupt folder
+	ReportFile       string            // Path to the JSON report of the run (optional)
+	TimelineFile     string            // Path to the JSON timeline of imported files ordered by capture time, for multi-camera editing (optional)
+	Cull             string            // Format reviewed during culling, jpeg or raw: files of the other format without a companion are not imported (optional)
+	CullDelete       bool              // Flag to delete orphaned files from the source in cull mode
+	Layout           string            // Folder layout below the destination, such as {year}/{month_name}/{day}, {year}/{month}-{day} when empty (optional)
+	Rename           string            // Name template of organized files, such as {year}{month}{day}_{seq}, with the tokens of layouts, {name} and {seq}, the original name when empty (optional)
+	LayoutCommand    string            // Command choosing the folder of every file from its JSON description, the layout folder when it prints nothing (optional)
+	Holidays         string            // Holiday calendar of the {weekday} and {holiday} layout tokens: us, gb, fr or de, that of the language when empty (optional)
+	Brackets         string            // Layout of bracketed sequences: folder or stem (optional)
+	Route            string            // Comma-separated kinds routed to their own day subfolder: timelapse, pano (optional)
+	DestFS           string            // File system of the destination: fat to force FAT-safe names, native to never sanitize them, detected when empty (optional)
+	FoldCase         bool              // Flag to treat destination names differing only in case as the same file, for destinations shared with macOS or Windows
+	MaxFilesPerDir   int               // Number of files above which a destination directory overflows into part-2/, part-3/... subfolders, 0 for no limit (optional)
+	ShardThreshold   int               // Number of files above which a day folder is split into hour subfolders, 0 to disable (optional)
+	ClockFile        string            // JSON file of clock offsets per camera serial number (optional)
+	WriteDates       bool              // Flag to write corrected capture dates back into JPEG files, and XMP sidecars of other files
+	Settle           time.Duration     // Time since their last write after which files are considered complete, for tethered-capture hot folders, 0 to disable (optional)
+	TrustFolders     bool              // Flag to date files of an already organized source by their YYYY/MM-DD folder instead of their metadata
+	NoGPS            bool              // Flag to never read GPS locations, for privacy
+	CopyUnknown      bool              // Flag to copy files of unsupported formats to an other folder, dated by their modification time
+	Proxies          string            // Policy for .LRV and .THM companions of videos: skip, keep or route (optional)
+	Screenshots      string            // Policy for screenshots: route to a Screenshots/YYYY/MM tree, keep in day folders or skip, routed for device exports and kept otherwise when empty (optional)
+	PhoneEdits       string            // Policy for edited copies exported by phones next to their originals: keep both, edited or original, unrelated files when empty (optional)
+	Profile          string            // Ingestion profile of a kind of source: apple-photos for Photos.app and iCloud Photos exports (optional)
+	LightroomCatalog string            // Lightroom catalog whose files are not imported, or its previews (optional)
+	LightroomFlag    bool              // Flag to import the files of the Lightroom catalog with a warning instead of skipping them
+	DNGCommand       string            // Command converting RAW files to DNG, such as "dnglab convert {} {out}", failures falling back to a copy (optional)
+	DNGFormats       string            // Comma-separated RAW formats converted to DNG, such as cr2,nef, every RAW format when empty (optional)
+	DNGOriginals     string            // Folder keeping the originals of converted RAW files, laid out like the destination, raw-originals in the destination when empty (optional)
+	CheckCommand     string            // Command run on every file before it is written, a non-zero exit rejects it (optional)
+	QuarantineDir    string            // Folder receiving a copy of rejected files (optional)
+	ReadOnly         bool              // Flag to make destination files read-only after writing
+	EncryptKeyFile   string            // Key file encrypting destination files (optional)
+	HashNames        bool              // Flag to hash the names of encrypted destination files
+	RunTags          map[string]string // Free-form key=value pairs recorded with the run (optional)
+	Albums           string            // Mirroring of Google Takeout albums: links or tags (optional)
+	Provenance       string            // Recording of the source, hash and settings of every imported file: embed or sidecar (optional)
+	Eject            bool              // Flag to eject the source volume after a run without errors
+	NotifySMTP       string            // JSON file of SMTP settings used to email a summary of the run (optional)
+	NotifyMQTT       string            // JSON file of MQTT settings used to publish the status of the run (optional)
+	Chaos            string            // Rates of IO faults injected in the run, a test mode checking that failures never lose files (optional)
+	ChaosSeed        int64             // Seed of the injected faults, random when 0 (optional)
+
+	// Manual dating of files that carry no usable date
+	DateOverrides map[string]time.Time // Capture dates assigned manually, keyed by source file path as found in the source (optional)
+	Files         []string             // Source files to import instead of every file of the source directory (optional)
+	FromReport    string               // JSON report of a previous run whose files are imported again instead of scanning the source (optional)
+	OnlyErrors    bool                 // Flag to only import again the files of the report that failed or were skipped
 }
@@ -0,0 +1,382 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/matdmb/organize-media/pkg/i18n"
+)
+
+// Errors of missing directories, to be matched with errors.Is
+var (
+	ErrSourceMissing      = errors.New("source directory is missing")
+	ErrDestinationMissing = errors.New("destination directory is missing")
+)
+
+// HashAlgorithms lists the supported content hash algorithms. An empty
+// algorithm selects the default, SHA-256.
+var HashAlgorithms = map[string]bool{
+	"":       true,
+	"sha256": true,
+	"blake3": true,
+}
+
+// CullFormats lists the formats that can be reviewed in cull mode. An empty
+// format disables culling.
+var CullFormats = map[string]bool{
+	"":     true,
+	"jpeg": true,
+	"raw":  true,
+}
+
+// BracketLayouts lists the layouts of bracketed sequences. An empty layout
+// organizes bracketed frames like any other file.
+var BracketLayouts = map[string]bool{
+	"":       true,
+	"folder": true,
+	"stem":   true,
+}
+
+// RouteKinds lists the kinds of pictures that can be routed to their own folder
+var RouteKinds = map[string]bool{
+	"timelapse": true,
+	"pano":      true,
+}
+
+// AlbumModes lists the ways of mirroring Google Takeout albums. An empty mode
+// ignores albums.
+var AlbumModes = map[string]bool{
+	"":      true,
+	"links": true,
+	"tags":  true,
+}
+
+// ProvenanceModes lists the ways of recording the provenance of imported
+// files. An empty mode records none.
+var ProvenanceModes = map[string]bool{
+	"":        true,
+	"embed":   true,
+	"sidecar": true,
+}
+
+// ProxyPolicies lists the policies for the low-resolution companions of
+// videos. An empty policy ignores them like other unsupported files.
+var ProxyPolicies = map[string]bool{
+	"":      true,
+	"skip":  true,
+	"keep":  true,
+	"route": true,
+}
+
+// RawFormats lists the RAW formats that can be converted to DNG
+var RawFormats = map[string]bool{
+	"nef": true,
+	"cr2": true,
+	"cr3": true,
+	"arw": true,
+	"raf": true,
+	"rw2": true,
+	"raw": true,
+}
+
+// Profiles lists the ingestion profiles tuned for a kind of source. An empty
+// profile handles every source alike.
+var Profiles = map[string]bool{
+	"":             true,
+	"apple-photos": true,
+}
+
+// PhoneEditPolicies lists the policies for the edited copies phones export
+// next to their originals. An empty policy treats them as unrelated files.
+var PhoneEditPolicies = map[string]bool{
+	"":         true,
+	"keep":     true,
+	"edited":   true,
+	"original": true,
+}
+
+// HolidayCalendars lists the holiday calendars of the {weekday} and {holiday}
+// layout tokens. An empty calendar is that of the language of messages.
+var HolidayCalendars = map[string]bool{
+	"":   true,
+	"us": true,
+	"gb": true,
+	"fr": true,
+	"de": true,
+}
+
+// ScreenshotPolicies lists the policies for screenshots. An empty policy
+// routes them for phone and tablet exports, and organizes them like other
+// pictures otherwise.
+var ScreenshotPolicies = map[string]bool{
+	"":      true,
+	"route": true,
+	"keep":  true,
+	"skip":  true,
+}
+
+// DestFileSystems lists the destination file systems that can be forced. An
+// empty value detects the file system of the destination.
+var DestFileSystems = map[string]bool{
+	"":       true,
+	"fat":    true,
+	"native": true,
+}
+
+// Validate checks the parameters of a run before anything is read or written.
+// It returns every problem found at once, joined with errors.Join, so callers
+// such as GUIs can report them together.
+func (p *Params) Validate() error {
+	var errs []error
+
+	// Paths must be given, exist and not overlap
+	sourceOK, destinationOK := true, true
+	if p.Source == "" {
+		errs = append(errs, i18n.Wrapf(ErrSourceMissing, "source directory is required"))
+		sourceOK = false
+	} else if _, err := os.Stat(p.Source); os.IsNotExist(err) {
+		errs = append(errs, i18n.Wrapf(ErrSourceMissing, "source directory does not exist: %s", p.Source))
+		sourceOK = false
+	}
+	if p.Destination == "" {
+		errs = append(errs, i18n.Wrapf(ErrDestinationMissing, "destination directory is required"))
+		destinationOK = false
+	} else if _, err := os.Stat(p.Destination); os.IsNotExist(err) {
+		errs = append(errs, i18n.Wrapf(ErrDestinationMissing, "destination directory does not exist: %s", p.Destination))
+		destinationOK = false
+	}
+	if sourceOK && destinationOK {
+		// Overlapping source and destination would re-process our own output
+		if err := checkPathOverlap(p.Source, p.Destination); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, rule := range p.Tiers {
+		_, tier, _ := strings.Cut(rule, "=")
+		tier = strings.TrimSpace(tier)
+		if tier == "" {
+			continue // Reported when the rule is parsed
+		}
+		if _, err := os.Stat(tier); os.IsNotExist(err) {
+			errs = append(errs, i18n.Errorf("tier directory does not exist: %s", tier))
+			continue
+		}
+		if sourceOK && overlaps(p.Source, tier) {
+			errs = append(errs, i18n.Errorf("tier directory must not overlap the source directory: %s", tier))
+		}
+	}
+	for _, mirror := range p.Mirrors {
+		if _, err := os.Stat(mirror); os.IsNotExist(err) {
+			errs = append(errs, i18n.Errorf("mirror directory does not exist: %s", mirror))
+			continue
+		}
+		// A mirror inside the primary destination or the source would be organized again
+		if destinationOK && overlaps(p.Destination, mirror) {
+			errs = append(errs, i18n.Errorf("mirror directory must not overlap the destination directory: %s", mirror))
+		}
+		if sourceOK && overlaps(p.Source, mirror) {
+			errs = append(errs, i18n.Errorf("mirror directory must not overlap the source directory: %s", mirror))
+		}
+	}
+
+	if p.Compression < -1 || p.Compression > 100 {
+		errs = append(errs, i18n.Errorf("compression level must be an integer between 0 and 100"))
+	}
+
+	if p.Workers < AutoWorkers {
+		errs = append(errs, i18n.Errorf("invalid number of workers: %d", p.Workers))
+	}
+
+	// Incremental mode relies on the catalog to know what was already imported
+	if p.Incremental && p.CatalogFile == "" {
+		errs = append(errs, i18n.Errorf("incremental mode requires a catalog file"))
+	}
+
+	if !HashAlgorithms[p.HashAlgo] {
+		errs = append(errs, i18n.Errorf("unsupported hash algorithm: %s (expected sha256 or blake3)", p.HashAlgo))
+	}
+
+	if !CullFormats[p.Cull] {
+		errs = append(errs, i18n.Errorf("unsupported cull format: %s (expected jpeg or raw)", p.Cull))
+	}
+	if p.CullDelete && p.Cull == "" {
+		errs = append(errs, i18n.Errorf("deleting culled files requires a cull format"))
+	}
+
+	if !BracketLayouts[p.Brackets] {
+		errs = append(errs, i18n.Errorf("unsupported bracket layout: %s (expected folder or stem)", p.Brackets))
+	}
+
+	if p.ShardThreshold < 0 {
+		errs = append(errs, i18n.Errorf("invalid shard threshold: %d", p.ShardThreshold))
+	}
+
+	if !DestFileSystems[p.DestFS] {
+		errs = append(errs, i18n.Errorf("unsupported destination file system: %s (expected fat or native)", p.DestFS))
+	}
+
+	if p.MaxFilesPerDir < 0 {
+		errs = append(errs, i18n.Errorf("invalid maximum number of files per directory: %d", p.MaxFilesPerDir))
+	}
+
+	if p.Settle < 0 {
+		errs = append(errs, i18n.Errorf("invalid settle time: %v", p.Settle))
+	}
+
+	if p.YearRootsFile != "" {
+		if _, err := os.Stat(p.YearRootsFile); err != nil {
+			errs = append(errs, i18n.Errorf("year roots file not found: %s", p.YearRootsFile))
+		}
+	}
+
+	if p.ClockFile != "" {
+		if _, err := os.Stat(p.ClockFile); err != nil {
+			errs = append(errs, i18n.Errorf("clock offsets file not found: %s", p.ClockFile))
+		}
+	}
+
+	if p.LightroomFlag && p.LightroomCatalog == "" {
+		errs = append(errs, i18n.Errorf("flagging Lightroom files requires a Lightroom catalog"))
+	} else if p.LightroomCatalog != "" {
+		if _, err := os.Stat(p.LightroomCatalog); err != nil {
+			errs = append(errs, i18n.Errorf("Lightroom catalog not found: %s", p.LightroomCatalog))
+		}
+	}
+
+	if (p.DNGFormats != "" || p.DNGOriginals != "") && p.DNGCommand == "" {
+		errs = append(errs, i18n.Errorf("DNG formats and originals require a DNG command"))
+	} else if p.DNGCommand != "" && !strings.Contains(p.DNGCommand, "{out}") && !strings.Contains(p.DNGCommand, "{dir}") {
+		errs = append(errs, i18n.Errorf("DNG command must write to {out} or {dir}: %s", p.DNGCommand))
+	}
+	for _, format := range strings.Split(p.DNGFormats, ",") {
+		if format = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), ".")); format != "" && !RawFormats[format] {
+			errs = append(errs, i18n.Errorf("unsupported RAW format: %s (expected nef, cr2, cr3, arw, raf, rw2 or raw)", format))
+		}
+	}
+
+	if p.NotifySMTP != "" {
+		if _, err := os.Stat(p.NotifySMTP); err != nil {
+			errs = append(errs, i18n.Errorf("SMTP settings file not found: %s", p.NotifySMTP))
+		}
+	}
+
+	if p.NotifyMQTT != "" {
+		if _, err := os.Stat(p.NotifyMQTT); err != nil {
+			errs = append(errs, i18n.Errorf("MQTT settings file not found: %s", p.NotifyMQTT))
+		}
+	}
+
+	if p.FromReport != "" {
+		if _, err := os.Stat(p.FromReport); err != nil {
+			errs = append(errs, i18n.Errorf("report file not found: %s", p.FromReport))
+		}
+	} else if p.OnlyErrors {
+		errs = append(errs, i18n.Errorf("importing only the errors of a run requires its report file"))
+	}
+
+	if p.QuarantineDir != "" && p.CheckCommand == "" {
+		errs = append(errs, i18n.Errorf("quarantine requires a check command"))
+	}
+
+	if p.HashNames && p.EncryptKeyFile == "" {
+		errs = append(errs, i18n.Errorf("hashing file names requires an encryption key"))
+	} else if p.EncryptKeyFile != "" {
+		if _, err := os.Stat(p.EncryptKeyFile); err != nil {
+			errs = append(errs, i18n.Errorf("encryption key file not found: %s", p.EncryptKeyFile))
+		}
+	}
+
+	if !ProxyPolicies[p.Proxies] {
+		errs = append(errs, i18n.Errorf("unsupported proxy policy: %s (expected skip, keep or route)", p.Proxies))
+	}
+
+	if !PhoneEditPolicies[p.PhoneEdits] {
+		errs = append(errs, i18n.Errorf("unsupported phone edits policy: %s (expected keep, edited or original)", p.PhoneEdits))
+	}
+
+	if !Profiles[p.Profile] {
+		errs = append(errs, i18n.Errorf("unsupported profile: %s (expected apple-photos)", p.Profile))
+	}
+
+	if !HolidayCalendars[p.Holidays] {
+		errs = append(errs, i18n.Errorf("unsupported holiday calendar: %s (expected us, gb, fr or de)", p.Holidays))
+	}
+
+	if !ScreenshotPolicies[p.Screenshots] {
+		errs = append(errs, i18n.Errorf("unsupported screenshot policy: %s (expected route, keep or skip)", p.Screenshots))
+	}
+
+	if !ProvenanceModes[p.Provenance] {
+		errs = append(errs, i18n.Errorf("unsupported provenance mode: %s (expected embed or sidecar)", p.Provenance))
+	}
+
+	if !AlbumModes[p.Albums] {
+		errs = append(errs, i18n.Errorf("unsupported album mode: %s (expected links or tags)", p.Albums))
+	}
+	// Album tags are only recorded in the catalog and report
+	if p.Albums == "tags" && p.CatalogFile == "" && p.ReportFile == "" {
+		errs = append(errs, i18n.Errorf("album tags require a catalog or report file"))
+	}
+
+	for _, kind := range strings.Split(p.Route, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" && !RouteKinds[kind] {
+			errs = append(errs, i18n.Errorf("unsupported route: %s (expected timelapse or pano)", kind))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkPathOverlap returns an error if the source and destination are the same
+// directory or one is nested inside the other, after resolving symlinks
+func checkPathOverlap(source, destination string) error {
+	src, err := resolvePath(source)
+	if err != nil {
+		return i18n.Errorf("failed to resolve source path: %v", err)
+	}
+	dst, err := resolvePath(destination)
+	if err != nil {
+		return i18n.Errorf("failed to resolve destination path: %v", err)
+	}
+
+	switch {
+	case src == dst:
+		return i18n.Errorf("source and destination must be different directories: %s", src)
+	case isWithin(src, dst):
+		return i18n.Errorf("destination directory must not be inside the source directory: %s is inside %s", dst, src)
+	case isWithin(dst, src):
+		return i18n.Errorf("source directory must not be inside the destination directory: %s is inside %s", src, dst)
+	}
+	return nil
+}
+
+// overlaps reports whether two directories are the same or one is nested in
+// the other, after resolving symlinks
+func overlaps(a, b string) bool {
+	ra, errA := resolvePath(a)
+	rb, errB := resolvePath(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return ra == rb || isWithin(ra, rb) || isWithin(rb, ra)
+}
+
+// resolvePath returns the absolute, symlink-free form of path
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// isWithin reports whether child is located below parent
+func isWithin(parent, child string) bool {
+	rel, err := filepath.Rel(parent, child)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,288 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/i18n"
+)
+
+func TestValidate(t *testing.T) {
+	source := t.TempDir()
+	destination := t.TempDir()
+	missing := filepath.Join(source, "missing")
+
+	tests := []struct {
+		name   string
+		params Params
+		want   []string // Expected error messages, none for valid params
+	}{
+		{
+			name:   "valid",
+			params: Params{Source: source, Destination: destination, Compression: -1},
+		},
+		{
+			name:   "valid with every option",
+			params: Params{Source: source, Destination: destination, Compression: 80, Workers: AutoWorkers, CatalogFile: "catalog.jsonl", Incremental: true, HashAlgo: "blake3", Cull: "jpeg", CullDelete: true, Brackets: "folder", Route: "timelapse, pano"},
+		},
+		{
+			name:   "missing paths",
+			params: Params{Compression: -1},
+			want:   []string{"source directory is required", "destination directory is required"},
+		},
+		{
+			name:   "nonexistent paths",
+			params: Params{Source: missing, Destination: missing, Compression: -1},
+			want:   []string{"source directory does not exist", "destination directory does not exist"},
+		},
+		{
+			name:   "overlapping paths",
+			params: Params{Source: source, Destination: source, Compression: -1},
+			want:   []string{"must be different directories"},
+		},
+		{
+			name:   "missing mirror",
+			params: Params{Source: source, Destination: destination, Mirrors: []string{missing}, Compression: -1},
+			want:   []string{"mirror directory does not exist"},
+		},
+		{
+			name:   "mirror overlapping the destination and source",
+			params: Params{Source: source, Destination: destination, Mirrors: []string{destination, source}, Compression: -1},
+			want: []string{
+				"mirror directory must not overlap the destination directory",
+				"mirror directory must not overlap the source directory",
+			},
+		},
+		{
+			name:   "missing tier",
+			params: Params{Source: source, Destination: destination, Tiers: []string{"2y=" + missing}, Compression: -1},
+			want:   []string{"tier directory does not exist"},
+		},
+		{
+			name:   "tier overlapping the source",
+			params: Params{Source: source, Destination: destination, Tiers: []string{"2y=" + source}, Compression: -1},
+			want:   []string{"tier directory must not overlap the source directory"},
+		},
+		{
+			name:   "missing year roots file",
+			params: Params{Source: source, Destination: destination, YearRootsFile: missing, Compression: -1},
+			want:   []string{"year roots file not found"},
+		},
+		{
+			name:   "unsupported holiday calendar",
+			params: Params{Source: source, Destination: destination, Holidays: "jp", Compression: -1},
+			want:   []string{"unsupported holiday calendar: jp"},
+		},
+		{
+			name:   "unsupported phone edits policy",
+			params: Params{Source: source, Destination: destination, PhoneEdits: "both", Compression: -1},
+			want:   []string{"unsupported phone edits policy: both"},
+		},
+		{
+			name:   "unsupported provenance mode",
+			params: Params{Source: source, Destination: destination, Provenance: "exif", Compression: -1},
+			want:   []string{"unsupported provenance mode: exif"},
+		},
+		{
+			name: "every problem at once",
+			params: Params{
+				Source:         source,
+				Destination:    missing,
+				Compression:    101,
+				Workers:        -2,
+				Incremental:    true,
+				HashAlgo:       "md5",
+				Cull:           "png",
+				Brackets:       "hdr",
+				Route:          "pano,video",
+				Proxies:        "delete",
+				Screenshots:    "delete",
+				Albums:         "folders",
+				ShardThreshold: -1,
+				MaxFilesPerDir: -1,
+				DestFS:         "ntfs",
+				Settle:         -time.Second,
+			},
+			want: []string{
+				"destination directory does not exist",
+				"compression level must be an integer between 0 and 100",
+				"invalid number of workers: -2",
+				"invalid shard threshold: -1",
+				"unsupported destination file system: ntfs",
+				"invalid maximum number of files per directory: -1",
+				"invalid settle time: -1s",
+				"incremental mode requires a catalog file",
+				"unsupported hash algorithm: md5",
+				"unsupported cull format: png",
+				"unsupported bracket layout: hdr",
+				"unsupported route: video",
+				"unsupported proxy policy: delete",
+				"unsupported screenshot policy: delete",
+				"unsupported album mode: folders",
+			},
+		},
+		{
+			name:   "cull deletion without format",
+			params: Params{Source: source, Destination: destination, Compression: -1, CullDelete: true},
+			want:   []string{"deleting culled files requires a cull format"},
+		},
+		{
+			name:   "quarantine without check command",
+			params: Params{Source: source, Destination: destination, Compression: -1, QuarantineDir: missing},
+			want:   []string{"quarantine requires a check command"},
+		},
+		{
+			name:   "hashed names without key",
+			params: Params{Source: source, Destination: destination, Compression: -1, HashNames: true},
+			want:   []string{"hashing file names requires an encryption key"},
+		},
+		{
+			name:   "Lightroom flag without catalog",
+			params: Params{Source: source, Destination: destination, Compression: -1, LightroomFlag: true},
+			want:   []string{"flagging Lightroom files requires a Lightroom catalog"},
+		},
+		{
+			name:   "missing Lightroom catalog",
+			params: Params{Source: source, Destination: destination, Compression: -1, LightroomCatalog: missing},
+			want:   []string{"Lightroom catalog not found"},
+		},
+		{
+			name:   "DNG formats without command",
+			params: Params{Source: source, Destination: destination, Compression: -1, DNGFormats: "arw"},
+			want:   []string{"DNG formats and originals require a DNG command"},
+		},
+		{
+			name:   "DNG command without output",
+			params: Params{Source: source, Destination: destination, Compression: -1, DNGCommand: "dnglab convert {}"},
+			want:   []string{"DNG command must write to {out} or {dir}"},
+		},
+		{
+			name:   "unsupported RAW format",
+			params: Params{Source: source, Destination: destination, Compression: -1, DNGCommand: "dnglab convert {} {out}", DNGFormats: "arw,jpg"},
+			want:   []string{"unsupported RAW format: jpg"},
+		},
+		{
+			name:   "missing key file",
+			params: Params{Source: source, Destination: destination, Compression: -1, EncryptKeyFile: missing},
+			want:   []string{"encryption key file not found"},
+		},
+		{
+			name:   "album tags without catalog or report",
+			params: Params{Source: source, Destination: destination, Compression: -1, Albums: "tags"},
+			want:   []string{"album tags require a catalog or report file"},
+		},
+		{
+			name:   "missing SMTP settings",
+			params: Params{Source: source, Destination: destination, Compression: -1, NotifySMTP: missing},
+			want:   []string{"SMTP settings file not found"},
+		},
+		{
+			name:   "missing MQTT settings",
+			params: Params{Source: source, Destination: destination, Compression: -1, NotifyMQTT: missing},
+			want:   []string{"MQTT settings file not found"},
+		},
+		{
+			name:   "missing report of a previous run",
+			params: Params{Source: source, Destination: destination, Compression: -1, FromReport: missing},
+			want:   []string{"report file not found"},
+		},
+		{
+			name:   "only errors without report",
+			params: Params{Source: source, Destination: destination, Compression: -1, OnlyErrors: true},
+			want:   []string{"importing only the errors of a run requires its report file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() expected errors %v, got nil", tt.want)
+			}
+
+			// Each problem is reported as a separate joined error
+			joined, ok := err.(interface{ Unwrap() []error })
+			if !ok || len(joined.Unwrap()) != len(tt.want) {
+				t.Fatalf("Validate() = %q, want %d errors", err, len(tt.want))
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateErrorKinds(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	// Error kinds do not depend on the language of the messages
+	for _, lang := range []string{"en", "fr"} {
+		if err := i18n.SetLanguage(lang); err != nil {
+			t.Fatalf("SetLanguage(%q) error = %v", lang, err)
+		}
+		err := (&Params{Source: missing, Compression: -1}).Validate()
+		if !errors.Is(err, ErrSourceMissing) || !errors.Is(err, ErrDestinationMissing) {
+			t.Errorf("Validate() in %s = %v, want ErrSourceMissing and ErrDestinationMissing", lang, err)
+		}
+	}
+	i18n.SetLanguage(i18n.DefaultLanguage)
+
+	if err := (&Params{Source: missing, Destination: t.TempDir(), Compression: -1}).Validate(); errors.Is(err, ErrDestinationMissing) {
+		t.Errorf("Validate() = %v, destination is not missing", err)
+	}
+}
+
+func TestCheckPathOverlap(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "photos")
+	sibling := filepath.Join(root, "photos2")
+	nested := filepath.Join(source, "organized")
+	for _, dir := range []string{source, sibling, nested} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	// Symlinks may require extra privileges on Windows
+	link := filepath.Join(root, "link")
+	hasSymlink := os.Symlink(source, link) == nil
+
+	tests := []struct {
+		name        string
+		source      string
+		destination string
+		wantErr     bool
+	}{
+		{name: "separate directories", source: source, destination: sibling, wantErr: false},
+		{name: "same directory", source: source, destination: source, wantErr: true},
+		{name: "destination inside source", source: source, destination: nested, wantErr: true},
+		{name: "source inside destination", source: nested, destination: source, wantErr: true},
+		{name: "destination symlinked to source", source: source, destination: link, wantErr: true},
+		{name: "relative path to same directory", source: source, destination: filepath.Join(nested, ".."), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.destination == link && !hasSymlink {
+				t.Skip("Skipping symlink test, symlinks are not supported")
+			}
+
+			err := checkPathOverlap(tt.source, tt.destination)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkPathOverlap(%q, %q) error = %v, wantErr %v", tt.source, tt.destination, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,132 +1,771 @@
 package organizemedia
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/matdmb/organize-media/pkg/i18n"
 	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
 	"github.com/matdmb/organize-media/pkg/utils"
 )
 
+// For testing purposes
+var (
+	stdinIsTerminal = isTerminal
+	ejectVolume     = utils.EjectVolume
+	listRemote      = utils.ListRemoteFiles
+	uploadToRemote  = utils.UploadToRemote
+	freeSpace       = utils.FreeSpace
+	sendEmail       = utils.SendEmail
+	publishStatus   = utils.PublishStatus
+)
+
 func Organize(params *models.Params) error {
-	// Validate source directory existence
-	if _, err := os.Stat(params.Source); os.IsNotExist(err) {
-		return fmt.Errorf("source directory does not exist: %s", params.Source)
+	// Files of a previous report are imported again without scanning the source
+	if params.FromReport != "" {
+		if err := loadReportFiles(params); err != nil {
+			return err
+		}
+		if len(params.Files) == 0 {
+			output.Info(i18n.Sprintf("No file to import again in report %s", params.FromReport))
+			return nil
+		}
 	}
 
-	// Validate destination directory existence
-	if _, err := os.Stat(params.Destination); os.IsNotExist(err) {
-		return fmt.Errorf("destination directory does not exist: %s", params.Destination)
+	// Cloud destinations are organized locally first, rclone only moves the bytes
+	if utils.IsRcloneDestination(params.Destination) {
+		return organizeToRemote(params)
 	}
 
-	// Validate compression range
-	if params.Compression < -1 || params.Compression > 100 {
-		return fmt.Errorf("compression level must be an integer between 0 and 100")
+	// Normalize paths so separators, drive-relative and UNC paths are handled consistently
+	for _, path := range []*string{&params.Source, &params.Destination} {
+		normalized, err := utils.NormalizePath(*path)
+		if err != nil {
+			return err
+		}
+		*path = normalized
+	}
+
+	// Report every invalid parameter at once
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	if params.Layout != "" {
+		if _, err := utils.ParseLayout(params.Layout); err != nil {
+			return i18n.Errorf("invalid layout: %v", err)
+		}
+	}
+	if params.Rename != "" {
+		if _, err := utils.ParseNameTemplate(params.Rename); err != nil {
+			return i18n.Errorf("invalid name template: %v", err)
+		}
+	}
+	tiers, err := utils.ParseTiers(params.Tiers)
+	if err != nil {
+		return i18n.Errorf("invalid tiering rule: %v", err)
+	}
+	var yearRoots utils.YearRoots
+	if params.YearRootsFile != "" {
+		if yearRoots, err = utils.LoadYearRoots(params.YearRootsFile); err != nil {
+			return i18n.Errorf("invalid year roots: %v", err)
+		}
+		for _, root := range yearRoots {
+			if _, err := os.Stat(root.Dir); err != nil {
+				return i18n.Errorf("year root directory does not exist: %s", root.Dir)
+			}
+		}
+	}
+	var chaosRates utils.ChaosRates
+	if params.Chaos != "" {
+		if chaosRates, err = utils.ParseChaosRates(params.Chaos); err != nil {
+			return i18n.Errorf("invalid chaos rates: %v", err)
+		}
 	}
 
 	var logOutput io.Writer
 	// Setup logger
-	logOutput, err := setupLogger(params.EnableLog)
+	logOutput, err = setupLogger(params.EnableLog)
 	if err != nil {
 		return err
 	}
 	log.SetOutput(logOutput)
 
-	log.Println("Application started.")
+	output.Info(i18n.T("Application started."))
+
+	// Privacy mode, no GPS location is read from the files of the run
+	if params.NoGPS {
+		output.Info(i18n.T("Privacy mode: GPS locations are never read"))
+	}
 
-	log.Printf("Source directory: %s", params.Source)
-	log.Printf("Destination directory: %s", params.Destination)
+	output.Info(i18n.Sprintf("Source directory: %s", params.Source))
+	output.Info(i18n.Sprintf("Destination directory: %s", params.Destination))
+	for _, mirror := range params.Mirrors {
+		output.Info(i18n.Sprintf("Mirror directory: %s", mirror))
+	}
+	for _, tier := range tiers {
+		output.Info(i18n.Sprintf("Files older than %s go to: %s", tier.Age(), tier.Dir))
+	}
+	for _, root := range yearRoots {
+		output.Info(i18n.Sprintf("Files of %s go to: %s", root.Years(), root.Dir))
+	}
+	if params.CheckCommand != "" {
+		output.Info(i18n.Sprintf("Check command: %s", params.CheckCommand))
+	}
+	if params.EncryptKeyFile != "" {
+		output.Info(i18n.Sprintf("Destination files are encrypted with the key in %s (hashed names: %t)", params.EncryptKeyFile, params.HashNames))
+	}
 
 	if params.Compression >= 0 {
-		log.Printf("Compression level: %d", params.Compression)
+		output.Info(i18n.Sprintf("Compression level: %d", params.Compression))
+	} else {
+		output.Info(i18n.T("Compression: not applied"))
+	}
+
+	output.Info(i18n.Sprintf("Delete source files: %t", params.DeleteSource))
+
+	if params.Workers == models.AutoWorkers {
+		output.Info(i18n.T("Workers: auto"))
 	} else {
-		log.Printf("Compression: not applied")
+		output.Info(i18n.Sprintf("Workers: %d", max(1, params.Workers)))
+	}
+	if params.LowPower {
+		output.Info(i18n.T("Low-power mode: at most 2 workers, copies checked by size and pauses between files on battery"))
+	}
+
+	if params.CatalogFile != "" {
+		output.Info(i18n.Sprintf("Catalog: %s (incremental: %t)", params.CatalogFile, params.Incremental))
+	}
+
+	if params.SinceLast {
+		output.Info(i18n.T("Only importing files added or modified since the last import from this source"))
+	}
+
+	switch params.Brackets {
+	case utils.BracketsFolder:
+		output.Info(i18n.T("Bracketed sequences are placed in their own subfolder"))
+	case utils.BracketsStem:
+		output.Info(i18n.T("Bracketed sequences are named after their first frame"))
+	}
+	if params.Route != "" {
+		output.Info(i18n.Sprintf("Routed to their own day subfolder: %s", params.Route))
+	}
+	if params.ShardThreshold > 0 {
+		output.Info(i18n.Sprintf("Days with more than %d files are split into hour subfolders", params.ShardThreshold))
+	}
+	if params.Layout != "" {
+		output.Info(i18n.Sprintf("Folder layout: %s", params.Layout))
+	}
+	if params.Rename != "" {
+		output.Info(i18n.Sprintf("File names: %s", params.Rename))
+	}
+	if params.Settle > 0 {
+		output.Info(i18n.Sprintf("Files written less than %v ago or still open for writing are left for the next run", params.Settle))
+	}
+	if params.ClockFile != "" {
+		output.Info(i18n.Sprintf("Camera clocks are corrected with the offsets in %s", params.ClockFile))
+	}
+	if params.CopyUnknown {
+		output.Info(i18n.Sprintf("Files of unsupported formats are copied to %s/, dated by their modification time", utils.OtherDir))
+	}
+	if utils.DestinationIsFAT(params) {
+		output.Info(i18n.T("Destination file names are made valid on FAT and exFAT"))
+	}
+	if params.FoldCase {
+		output.Info(i18n.T("Destination names differing only in case are treated as the same file"))
+	}
+	if params.MaxFilesPerDir > 0 {
+		output.Info(i18n.Sprintf("At most %d files per destination folder, extra files go to part-2/, part-3/...", params.MaxFilesPerDir))
+	}
+
+	if len(params.RunTags) > 0 {
+		output.Info(i18n.Sprintf("Run tags: %s", formatRunTags(params.RunTags)))
+	}
+
+	switch params.Proxies {
+	case utils.ProxiesSkip:
+		output.Info(i18n.T("Video proxies and thumbnails (.LRV, .THM) are skipped"))
+	case utils.ProxiesKeep:
+		output.Info(i18n.T("Video proxies and thumbnails (.LRV, .THM) are kept in the day folder of their video"))
+	case utils.ProxiesRoute:
+		output.Info(i18n.Sprintf("Video proxies and thumbnails (.LRV, .THM) are placed in the %s tree", utils.ProxiesDir))
+	}
+
+	switch utils.ScreenshotsPolicy(params) {
+	case utils.ScreenshotsRoute:
+		output.Info(i18n.Sprintf("Screenshots are placed in the %s/YYYY/MM tree", utils.ScreenshotsDir))
+	case utils.ScreenshotsSkip:
+		output.Info(i18n.T("Screenshots are skipped"))
+	}
+
+	if params.DNGCommand != "" {
+		formats, originals := params.DNGFormats, params.DNGOriginals
+		if formats == "" {
+			formats = i18n.T("every RAW format")
+		}
+		if originals == "" {
+			originals = filepath.Join(params.Destination, utils.DNGOriginalsDir)
+		}
+		output.Info(i18n.Sprintf("RAW files (%s) are converted to DNG, originals are kept in %s", formats, originals))
 	}
 
-	log.Printf("Delete source files: %t", params.DeleteSource)
+	if params.LightroomCatalog != "" {
+		if params.LightroomFlag {
+			output.Info(i18n.Sprintf("Files managed in the Lightroom catalog %s are imported with a warning", params.LightroomCatalog))
+		} else {
+			output.Info(i18n.Sprintf("Files managed in the Lightroom catalog %s are skipped", params.LightroomCatalog))
+		}
+	}
+
+	if params.Profile == utils.ProfileApplePhotos {
+		output.Info(i18n.T("Apple Photos profile: Live Photo videos and AAE sidecars are placed with their photo"))
+	}
+
+	switch params.PhoneEdits {
+	case utils.PhoneEditsKeep:
+		output.Info(i18n.T("Edited copies exported by phones are placed next to their originals"))
+	case utils.PhoneEditsEdited:
+		output.Info(i18n.T("Originals of edited copies exported by phones are skipped"))
+	case utils.PhoneEditsOriginal:
+		output.Info(i18n.T("Edited copies exported by phones are skipped"))
+	}
+
+	if params.Cull != "" {
+		// Files of the other format whose reviewed companion was deleted are orphans
+		reviewed := strings.ToUpper(params.Cull)
+		if params.CullDelete {
+			output.Info(i18n.Sprintf("Cull mode: files without a matching %s file are deleted from the source", reviewed))
+		} else {
+			output.Info(i18n.Sprintf("Cull mode: files without a matching %s file are not imported", reviewed))
+		}
+	}
 
 	// Count files in the source directory
-	totalFiles, size, err := utils.CountFiles(params.Source)
+	totalFiles, size, err := utils.CountImportableFiles(params)
 	if err != nil {
-		return fmt.Errorf("error counting files: %v", err)
+		return i18n.Errorf("error counting files: %v", err)
 	}
 
 	if totalFiles == 0 {
-		return fmt.Errorf("no files to process in source directory")
+		return i18n.Errorf("no files to process in source directory")
 	}
 
-	fmt.Printf("Number of files to process: %d [%s]\n", totalFiles, formatSize(size))
+	fmt.Print(i18n.Sprintf("Number of files to process: %d [%s]\n", totalFiles, utils.FormatSize(size)))
+
+	// Read every file up front so a failing card is noticed before anything is copied
+	if params.Precheck {
+		fmt.Println(i18n.T("Checking that every source file can be read..."))
+		unreadable, err := utils.PrecheckFiles(params.Source)
+		if err != nil {
+			return i18n.Errorf("error checking source files: %v", err)
+		}
+		if len(unreadable) > 0 {
+			for _, f := range unreadable {
+				output.Status("ERROR", i18n.Sprintf("Unreadable file %s: %v", f.Path, f.Err))
+			}
+			return i18n.Errorf("precheck found %d unreadable files, nothing was imported", len(unreadable))
+		}
+		output.Info(i18n.T("Precheck passed: all source files are readable."))
+	}
+
+	// Never block on a prompt nobody can answer (cron, pipes)
+	if !params.SkipUserInput && !stdinIsTerminal() {
+		return i18n.Errorf("confirmation required but standard input is not a terminal, use -yes to skip the prompt")
+	}
+
+	// Sources already in the YYYY/MM-DD layout, such as archives migrated from
+	// another machine, can be dated by their folders without reading metadata
+	if !params.TrustFolders {
+		if organized, err := utils.IsOrganizedSource(params.Source); err == nil && organized {
+			if params.SkipUserInput {
+				output.Info(i18n.T("The source is already organized by date, use -trust-folders to date files by their folder instead of their metadata"))
+			} else {
+				fmt.Print(i18n.T("The source is already organized by date. Date files by their folder instead of reading their metadata? (y/n): "))
+				var response string
+				if _, err := fmt.Fscanln(os.Stdin, &response); err != nil {
+					return i18n.Errorf("error reading input: %v", err)
+				}
+				params.TrustFolders = i18n.IsYes(response)
+			}
+		}
+	}
+	if params.TrustFolders {
+		output.Info(i18n.T("Files in YYYY/MM-DD folders are dated by their folder"))
+	}
+
+	// Resolve destinations up front for strict mode and the confirmation preview
+	var plan []utils.PlannedFile
+	if params.Strict || !params.SkipUserInput {
+		if plan, err = utils.PlanMediaFiles(params); err != nil {
+			return i18n.Errorf("error planning files: %v", err)
+		}
+	}
+
+	// In strict mode, import everything or nothing
+	if params.Strict {
+		// Files without a date can be dated by hand rather than failing the run
+		if !params.SkipUserInput {
+			assigned, err := askDates(params, utils.PlanConflicts(plan))
+			if err != nil {
+				return err
+			}
+			if assigned > 0 {
+				if plan, err = utils.PlanMediaFiles(params); err != nil {
+					return i18n.Errorf("error planning files: %v", err)
+				}
+			}
+		}
+		if conflicts := utils.PlanConflicts(plan); len(conflicts) > 0 {
+			for _, f := range conflicts {
+				output.Status("ERROR", i18n.Sprintf("Would be skipped %s: %v", f.Source, f.Err))
+			}
+			return i18n.Errorf("strict mode: %d files would be skipped, nothing was imported", len(conflicts))
+		}
+	}
 
 	if !params.SkipUserInput {
+		// Show where files will land so a wrong -dest or camera clock is caught before confirming
+		fmt.Print(formatPlanPreview(params.Destination, plan))
+
 		// Ask for user confirmation
-		fmt.Printf("Do you want to proceed with processing %d files? (y/n): ", totalFiles)
+		fmt.Print(i18n.Sprintf("Do you want to proceed with processing %d files? (y/n): ", len(plan)))
 		var response string
 		if _, err := fmt.Fscanln(os.Stdin, &response); err != nil {
-			return fmt.Errorf("error reading input: %v", err)
+			return i18n.Errorf("error reading input: %v", err)
 		}
-		if strings.ToLower(response) != "y" {
-			fmt.Println("Operation cancelled.")
-			return fmt.Errorf("operation cancelled by user")
+		if !i18n.IsYes(response) {
+			fmt.Println(i18n.T("Operation cancelled."))
+			return i18n.Errorf("operation cancelled by user")
 		}
 	} else {
-		log.Println("Skipping user input confirmation (test mode).")
+		output.Info(i18n.T("Skipping user input confirmation (test mode)."))
 	}
 
 	// Ensure destination directory is writable
 	testFile := filepath.Join(params.Destination, "test_write.tmp")
 	if err := os.WriteFile(testFile, []byte("test"), 0644); err != nil {
-		return fmt.Errorf("destination directory is not writable: %v", err)
+		return i18n.Errorf("destination directory is not writable: %v", err)
 	}
 	// Remove the test file after the check
 	defer os.Remove(testFile)
 
-	summary, err := utils.ProcessMediaFiles(params)
+	// Test mode injecting IO faults, to check that failures never lose files
+	var fsys utils.FileSystem = utils.OSFileSystem{}
+	var chaos *utils.ChaosFS
+	if chaosRates != nil {
+		seed := params.ChaosSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		chaos = utils.NewChaosFS(fsys, chaosRates, seed)
+		fsys = chaos
+		output.Status("WARNING", i18n.Sprintf("Chaos mode: IO faults are injected at rates %s (seed %d)", params.Chaos, seed))
+	}
+
+	started := time.Now()
+	if params.NotifyMQTT != "" {
+		publishRunStatus(params, started, nil, nil)
+	}
+	summary, err := utils.ProcessMediaFilesFS(context.Background(), fsys, params, nil)
+	if params.NotifyMQTT != "" {
+		publishRunStatus(params, started, &summary, err)
+	}
+	if params.NotifySMTP != "" {
+		notifyRun(params, started, summary, err)
+	}
 	if err != nil {
-		return fmt.Errorf("error moving files: %v", err)
+		return i18n.Errorf("error moving files: %v", err)
 	}
 
 	// Print processing summary
-	log.Printf("Processing Summary:")
-	log.Printf("%d files have been successfully processed", summary.Processed)
-	log.Printf("Number of files copied: %d", summary.Copied)
-	log.Printf("Number of files compressed: %d", summary.Compressed)
-	log.Printf("Number of files deleted: %d", summary.Deleted)
-	log.Printf("Number of files skipped: %d", summary.Skipped)
-
-	log.Printf("Processing completed in %v", summary.Duration)
+	output.Summary(i18n.T("Processing Summary:"))
+	output.Summary(i18n.Sprintf("%d files have been successfully processed", summary.Processed))
+	output.Summary(i18n.Sprintf("Number of files copied: %d", summary.Copied))
+	output.Summary(i18n.Sprintf("Number of files compressed: %d", summary.Compressed))
+	output.Summary(i18n.Sprintf("Number of files deleted: %d", summary.Deleted))
+	if params.DeleteSource {
+		output.Summary(i18n.Sprintf("Number of files verified before deleting the source: %d", summary.Verified))
+	}
+	output.Summary(i18n.Sprintf("Number of files skipped: %d", summary.Skipped))
+	for _, line := range formatSkips(summary.Skips) {
+		output.Summary(line)
+	}
+	if summary.Corrupt > 0 {
+		output.Summary(i18n.Sprintf("Number of empty or truncated files: %d", summary.Corrupt))
+	}
+	if summary.Failed > 0 {
+		output.Summary(i18n.Sprintf("Number of files failed: %d", summary.Failed))
+	}
+	if chaos != nil {
+		output.Summary(i18n.Sprintf("Number of injected IO faults: %d", chaos.Injected()))
+	}
+	if params.CheckCommand != "" {
+		output.Summary(i18n.Sprintf("Number of files rejected by the check command: %d", summary.Rejected))
+	}
+	if params.LightroomCatalog != "" {
+		output.Summary(i18n.Sprintf("Number of files already managed in Lightroom: %d", summary.Lightroom))
+	}
+	if params.KeepEdits && params.Compression >= 0 {
+		output.Summary(i18n.Sprintf("Number of edited files copied without recompression: %d", summary.EditsKept))
+	}
+	if params.ReadOnly {
+		output.Summary(i18n.Sprintf("Number of files write-protected: %d", summary.Protected))
+	}
+	if len(params.Mirrors) > 0 {
+		output.Summary(i18n.Sprintf("Number of files mirrored: %d", summary.Mirrored))
+		if summary.MirrorFailed > 0 {
+			output.Summary(i18n.Sprintf("Number of files failed to mirror: %d", summary.MirrorFailed))
+		}
+	}
+	if params.SinceLast {
+		output.Summary(i18n.Sprintf("Number of files unchanged since the last import: %d", summary.Unchanged))
+	}
+	if params.Cull != "" {
+		output.Summary(i18n.Sprintf("Number of files culled: %d", summary.Culled))
+	}
+	if params.SalvageDamaged {
+		output.Summary(i18n.Sprintf("Number of damaged files partially salvaged: %d", summary.Salvaged))
+	}
+	if params.CacheFile != "" {
+		output.Summary(i18n.Sprintf("Number of metadata cache hits: %d", summary.CacheHits))
+	}
+
+	output.Summary(i18n.Sprintf("Processing completed in %v", summary.Duration))
 	if summary.Processed > 0 {
 		avgTime := summary.Duration.Seconds() / float64(summary.Processed)
-		log.Printf("Average time per file: %.2f seconds", avgTime)
+		output.Summary(i18n.Sprintf("Average time per file: %.2f seconds", avgTime))
+	}
+	printIOStats(summary)
+
+	if params.ReportFile != "" {
+		output.Summary(i18n.Sprintf("Report written to: %s", params.ReportFile))
+	}
+	if params.TimelineFile != "" {
+		output.Summary(i18n.Sprintf("Timeline written to: %s", params.TimelineFile))
+	}
+
+	if params.Eject {
+		ejectSource(params.Source, summary)
 	}
 
-	log.Println("Process completed.")
+	output.Summary(i18n.T("Process completed."))
 
 	return nil
 }
 
-// formatSize formats the size in bytes to a human-readable string in GB, MB, or KB.
-func formatSize(size int64) string {
-	const (
-		KB = 1 << 10
-		MB = 1 << 20
-		GB = 1 << 30
-	)
+// askDates prompts for the capture date of the planned files without one and
+// records the answers as manual dates of the run. It returns the number of
+// files dated.
+func askDates(params *models.Params, conflicts []utils.PlannedFile) (int, error) {
+	assigned := 0
+	for _, f := range conflicts {
+		if !errors.Is(f.Err, utils.ErrNoDate) {
+			continue
+		}
+		for {
+			fmt.Print(i18n.Sprintf("No date found for %s, enter its date (YYYY-MM-DD, - to skip): ", f.Source))
+			var response string
+			if _, err := fmt.Fscanln(os.Stdin, &response); err != nil {
+				return assigned, i18n.Errorf("error reading input: %v", err)
+			}
+			if response == "-" {
+				break
+			}
+			date, err := utils.ParseManualDate(response)
+			if err != nil {
+				output.Status("ERROR", err.Error())
+				continue
+			}
+			if params.DateOverrides == nil {
+				params.DateOverrides = make(map[string]time.Time)
+			}
+			params.DateOverrides[f.Source] = date
+			assigned++
+			break
+		}
+	}
+	return assigned, nil
+}
+
+// ejectSource ejects the source volume after a run without errors, so the
+// card is only reported safe to remove when everything on it was handled
+func ejectSource(source string, summary utils.ProcessingSummary) {
+	if failed := summary.Failed + summary.Salvaged + summary.MirrorFailed; failed > 0 {
+		output.Status("WARNING", i18n.Sprintf("Source volume not ejected: %d files had errors", failed))
+		return
+	}
+	if err := ejectVolume(source); err != nil {
+		output.Status("WARNING", i18n.Sprintf("Failed to eject source volume: %v", err))
+		return
+	}
+	output.Summary(i18n.T("Source volume ejected, the card can be removed safely."))
+}
+
+// Number of failed files listed in the run summary email
+const notifiedErrors = 20
+
+// notifyRun emails a summary of the run started at started to the recipients
+// of the SMTP settings, with the report attached. A report started earlier is
+// that of a previous run, left in place by a run that failed before writing
+// its own, and is not attached. Failing to send it does not fail the run.
+func notifyRun(params *models.Params, started time.Time, summary utils.ProcessingSummary, runErr error) {
+	settings, err := utils.LoadSMTPSettings(params.NotifySMTP)
+	if err != nil {
+		output.Status("WARNING", i18n.Sprintf("Failed to send the run summary: %v", err))
+		return
+	}
+
+	var report *utils.Report
+	attachments := map[string][]byte{}
+	if params.ReportFile != "" {
+		data, err := os.ReadFile(params.ReportFile)
+		if err == nil {
+			report, _ = utils.LoadReport(params.ReportFile)
+		}
+		if report != nil && !report.StartedAt.Before(started) {
+			attachments[filepath.Base(params.ReportFile)] = data
+		} else {
+			report = nil
+		}
+	}
+
+	subject, body := formatNotification(params, summary, report, runErr)
+	if err := sendEmail(settings, subject, body, attachments); err != nil {
+		output.Status("WARNING", i18n.Sprintf("Failed to send the run summary: %v", err))
+		return
+	}
+	output.Info(i18n.Sprintf("Run summary sent to %s", strings.Join(settings.To, ", ")))
+}
+
+// formatNotification returns the subject and body of the run summary email,
+// listing the files that failed when the report is available
+func formatNotification(params *models.Params, summary utils.ProcessingSummary, report *utils.Report, runErr error) (string, string) {
+	host, _ := os.Hostname()
+	subject := i18n.Sprintf("[organize-media] %s: %d files imported, %d failed", host, summary.Copied+summary.Compressed, summary.Failed)
+	if runErr != nil {
+		subject = i18n.Sprintf("[organize-media] %s: import failed", host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, i18n.Sprintf("Source directory: %s", params.Source))
+	fmt.Fprintln(&b, i18n.Sprintf("Destination directory: %s", params.Destination))
+	if len(params.RunTags) > 0 {
+		fmt.Fprintln(&b, i18n.Sprintf("Run tags: %s", formatRunTags(params.RunTags)))
+	}
+	if runErr != nil {
+		fmt.Fprintln(&b, i18n.Sprintf("error moving files: %v", runErr))
+	}
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, i18n.Sprintf("%d files have been successfully processed", summary.Processed))
+	fmt.Fprintln(&b, i18n.Sprintf("Number of files copied: %d", summary.Copied))
+	fmt.Fprintln(&b, i18n.Sprintf("Number of files compressed: %d", summary.Compressed))
+	fmt.Fprintln(&b, i18n.Sprintf("Number of files skipped: %d", summary.Skipped))
+	for _, line := range formatSkips(summary.Skips) {
+		fmt.Fprintln(&b, line)
+	}
+	fmt.Fprintln(&b, i18n.Sprintf("Number of files failed: %d", summary.Failed))
+	fmt.Fprintln(&b, i18n.Sprintf("Bytes written: %s", utils.FormatSize(summary.Stats.BytesWritten)))
+	fmt.Fprintln(&b, i18n.Sprintf("Processing completed in %v", round(summary.Duration)))
+
+	if report != nil {
+		var failed []utils.ReportEntry
+		for _, entry := range report.Files {
+			if entry.Status == utils.ReportFailed || entry.Status == utils.ReportCorrupt {
+				failed = append(failed, entry)
+			}
+		}
+		if len(failed) > 0 {
+			fmt.Fprintln(&b)
+			fmt.Fprintln(&b, i18n.T("Errors:"))
+			for i, entry := range failed {
+				if i == notifiedErrors {
+					fmt.Fprintln(&b, i18n.Sprintf("... and %d more, see the attached report", len(failed)-notifiedErrors))
+					break
+				}
+				fmt.Fprintf(&b, "- %s: %s\n", entry.Source, entry.Reason)
+			}
+		}
+	}
+	return subject, b.String()
+}
+
+// publishRunStatus publishes the state of the run to the MQTT broker of the
+// settings: running until there is a summary, then idle, or errored when the
+// run or any file failed. Failing to publish does not fail the run.
+func publishRunStatus(params *models.Params, started time.Time, summary *utils.ProcessingSummary, runErr error) {
+	settings, err := utils.LoadMQTTSettings(params.NotifyMQTT)
+	if err != nil {
+		output.Status("WARNING", i18n.Sprintf("Failed to publish the run status: %v", err))
+		return
+	}
+
+	status := utils.RunStatus{
+		State:       utils.StatusRunning,
+		Source:      params.Source,
+		Destination: params.Destination,
+		StartedAt:   started,
+	}
+	if summary != nil {
+		finished := started.Add(summary.Duration)
+		status.State = utils.StatusIdle
+		status.FinishedAt = &finished
+		status.Processed = summary.Processed
+		status.Copied = summary.Copied
+		status.Compressed = summary.Compressed
+		status.Skipped = summary.Skipped
+		status.Failed = summary.Failed
+		status.BytesWritten = summary.Stats.BytesWritten
+		if runErr != nil || summary.Failed > 0 {
+			status.State = utils.StatusErrored
+		}
+		if runErr != nil {
+			status.Error = runErr.Error()
+		}
+	}
 
-	switch {
-	case size >= GB:
-		return fmt.Sprintf("%.2f GB", float64(size)/GB)
-	case size >= MB:
-		return fmt.Sprintf("%.2f MB", float64(size)/MB)
-	case size >= KB:
-		return fmt.Sprintf("%.2f KB", float64(size)/KB)
-	default:
-		return fmt.Sprintf("%d bytes", size)
+	if err := publishStatus(settings, status); err != nil {
+		output.Status("WARNING", i18n.Sprintf("Failed to publish the run status: %v", err))
+		return
 	}
+	output.Info(i18n.Sprintf("Run status %s published to %s", status.State, settings.Topic))
+}
+
+// skipMessages describe the reasons files are skipped in run summaries
+var skipMessages = map[string]string{
+	utils.SkipExistsIdentical: "  already in the destination: %d",
+	utils.SkipFiltered:        "  left out on purpose: %d",
+	utils.SkipExistsConflict:  "  destination taken by another file: %d",
+	utils.SkipNoDate:          "  no capture date: %d",
+	utils.SkipUnreadable:      "  unreadable: %d",
+	utils.SkipUnsupported:     "  unsupported format: %d",
+}
+
+// formatSkips breaks down the skipped files by reason, with a warning when
+// some of them may be missing from the archive
+func formatSkips(skips utils.SkipCounts) []string {
+	var lines []string
+	atRisk := false
+	for _, reason := range utils.SkipReasons {
+		if n := skips.Count(reason); n > 0 {
+			lines = append(lines, i18n.Sprintf(skipMessages[reason], n))
+			atRisk = atRisk || !utils.IsBenignSkip(reason)
+		}
+	}
+	if atRisk {
+		lines = append(lines, i18n.T("Some skipped files may be missing from the archive, check them before wiping the source"))
+	}
+	return lines
+}
+
+// printIOStats prints throughput, time per phase and worker utilization
+func printIOStats(summary utils.ProcessingSummary) {
+	stats := summary.Stats
+	output.Summary(i18n.Sprintf("Read: %s at %s average, %s peak",
+		utils.FormatSize(stats.BytesRead), utils.FormatRate(stats.ReadRate()), utils.FormatRate(stats.PeakReadRate)))
+	output.Summary(i18n.Sprintf("Written: %s at %s average, %s peak",
+		utils.FormatSize(stats.BytesWritten), utils.FormatRate(stats.WriteRate()), utils.FormatRate(stats.PeakWriteRate)))
+	output.Summary(i18n.Sprintf("Time per phase: scan %v, read %v, extract %v, compress %v, write %v",
+		round(stats.Scan), round(stats.Read), round(stats.Extract), round(stats.Compress), round(stats.Write)))
+	output.Summary(i18n.Sprintf("Worker utilization: %.0f%% (%d workers)", stats.Utilization(summary.Duration)*100, stats.Workers))
+}
+
+// round shortens durations to a readable precision
+func round(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
+
+// Number of planned mappings and new folders shown before confirmation
+const (
+	previewSampleSize = 5
+	previewFolderSize = 10
+)
+
+// formatPlanPreview describes a sample of planned mappings and the destination
+// day folders that will be created
+func formatPlanPreview(destination string, plan []utils.PlannedFile) string {
+	var b strings.Builder
+	var samples []string
+	var undated, corrupt int
+	newFolders := make(map[string]bool)
+
+	for _, f := range plan {
+		if errors.Is(f.Err, utils.ErrCorruptFile) {
+			corrupt++
+			continue
+		}
+		if f.Err != nil {
+			undated++
+			continue
+		}
+
+		dir := filepath.Dir(f.Destination)
+		rel, err := filepath.Rel(destination, dir)
+		if err != nil {
+			rel = dir
+		}
+		switch {
+		case len(samples) >= previewSampleSize:
+		case filepath.Base(f.Destination) != filepath.Base(f.Source):
+			// Renamed files, such as those of the flat layout, show their new name
+			samples = append(samples, fmt.Sprintf("  %s → %s\n", filepath.Base(f.Source), filepath.Join(rel, filepath.Base(f.Destination))))
+		default:
+			samples = append(samples, fmt.Sprintf("  %s → %s%c\n", filepath.Base(f.Source), rel, filepath.Separator))
+		}
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			newFolders[rel] = true
+		}
+	}
+
+	if len(samples) > 0 {
+		b.WriteString(i18n.T("Planned destinations:\n"))
+		for _, sample := range samples {
+			b.WriteString(sample)
+		}
+		if more := len(plan) - undated - corrupt - len(samples); more > 0 {
+			b.WriteString(i18n.Sprintf("  ... and %d more\n", more))
+		}
+	}
+
+	if len(newFolders) > 0 {
+		folders := make([]string, 0, len(newFolders))
+		for folder := range newFolders {
+			folders = append(folders, folder)
+		}
+		sort.Strings(folders)
+
+		b.WriteString(i18n.Sprintf("New destination folders (%d): ", len(folders)))
+		if len(folders) > previewFolderSize {
+			fmt.Fprintf(&b, "%s, ...\n", strings.Join(folders[:previewFolderSize], ", "))
+		} else {
+			fmt.Fprintf(&b, "%s\n", strings.Join(folders, ", "))
+		}
+	}
+
+	if undated > 0 {
+		b.WriteString(i18n.Sprintf("%d files have no date and will be skipped\n", undated))
+	}
+	if corrupt > 0 {
+		b.WriteString(i18n.Sprintf("%d files are empty or truncated and will not be imported\n", corrupt))
+	}
+
+	return b.String()
+}
+
+// isTerminal reports whether standard input is attached to a terminal
+func isTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
 func setupLogger(enableLog bool) (io.Writer, error) {
@@ -136,6 +775,7 @@ func setupLogger(enableLog bool) (io.Writer, error) {
 		if err := os.MkdirAll(destinationFolder, 0755); err != nil {
 			return nil, fmt.Errorf("failed to create logs directory: %v", err)
 		}
+		utils.RegisterSelfPath(destinationFolder)
 
 		// Create log file with timestamped name
 		logFileName := time.Now().Format("2006-01-02_15-04-05") + ".log"
@@ -151,11 +791,104 @@ func setupLogger(enableLog bool) (io.Writer, error) {
 		log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 		log.SetOutput(logFile)
 		log.Println("Log initialized at", time.Now().Format(time.RFC1123))
+		log.Println("organize-media", utils.CurrentBuild())
 
 		// Return multi-writer to log to both terminal and log file
-		return io.MultiWriter(os.Stdout, logFile), nil
+		return io.MultiWriter(os.Stdout, output.StripColor(logFile)), nil
 	}
 
 	// Default to logging only to the terminal
 	return os.Stdout, nil
 }
+
+// formatRunTags formats the tags of a run as sorted key=value pairs
+func formatRunTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// organizeToRemote organizes files into a local staging folder, in the system
+// temporary folder unless -staging sets it, and uploads it to an rclone
+// remote. Files already on the remote are skipped like files already at a
+// local destination, through empty placeholders in the staging folder.
+func organizeToRemote(params *models.Params) error {
+	remote := utils.RcloneRemote(params.Destination)
+
+	// Sources must stay until the upload is done, and the state of previous
+	// imports lives in the destination
+	if params.DeleteSource {
+		return i18n.Errorf("deleting source files is not supported with rclone destinations")
+	}
+	if params.SinceLast {
+		return i18n.Errorf("importing only new files since the last import is not supported with rclone destinations")
+	}
+
+	output.Info(i18n.Sprintf("Listing files already on %s...", remote))
+	existing, err := listRemote(remote)
+	if err != nil {
+		return err
+	}
+
+	staging, err := os.MkdirTemp(params.StagingDir, "organize-media-")
+	if err != nil {
+		return i18n.Errorf("failed to create staging folder: %v", err)
+	}
+	defer os.RemoveAll(staging)
+
+	// Every file is staged before the upload starts, a staging volume filling
+	// up midway would fail the rest of the run. Sources that cannot be read
+	// are reported by the run itself.
+	if _, size, err := utils.CountImportableFiles(params); err == nil {
+		if free, ok := freeSpace(staging); ok && free < size {
+			return i18n.Errorf("not enough space to stage %s in %s: %s available, choose another folder with -staging", utils.FormatSize(size), staging, utils.FormatSize(free))
+		}
+	}
+
+	placeholders, err := utils.CreatePlaceholders(staging, existing)
+	if err != nil {
+		return i18n.Errorf("failed to create staging folder: %v", err)
+	}
+	output.Info(i18n.Sprintf("Staging files in %s before uploading to %s", staging, remote))
+
+	local := *params
+	local.Destination = staging
+	if err := Organize(&local); err != nil {
+		return err
+	}
+
+	for _, path := range placeholders {
+		os.Remove(path)
+	}
+
+	output.Info(i18n.Sprintf("Uploading to %s...", remote))
+	if err := uploadToRemote(staging, remote); err != nil {
+		return err
+	}
+	output.Summary(i18n.Sprintf("Files uploaded to %s", remote))
+	return nil
+}
+
+// loadReportFiles selects the files of the report of a previous run, and
+// defaults the source and destination to those of that run
+func loadReportFiles(params *models.Params) error {
+	report, err := utils.LoadReport(params.FromReport)
+	if os.IsNotExist(err) {
+		return i18n.Errorf("report file not found: %s", params.FromReport)
+	}
+	if err != nil {
+		return i18n.Errorf("failed to read report: %v", err)
+	}
+
+	if params.Source == "" {
+		params.Source = report.Source
+	}
+	if params.Destination == "" {
+		params.Destination = report.Destination
+	}
+	params.Files = report.SourceFiles(params.OnlyErrors)
+	return nil
+}
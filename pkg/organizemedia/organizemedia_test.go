@@ -1,58 +1,19 @@
 package organizemedia
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/utils"
 )
 
-func TestFormatSize(t *testing.T) {
-	tests := []struct {
-		name     string
-		size     int64
-		expected string
-	}{
-		{
-			name:     "Bytes",
-			size:     500,
-			expected: "500 bytes",
-		},
-		{
-			name:     "Kilobytes",
-			size:     1500,
-			expected: "1.46 KB",
-		},
-		{
-			name:     "Megabytes",
-			size:     1500000,
-			expected: "1.43 MB",
-		},
-		{
-			name:     "Gigabytes",
-			size:     1500000000,
-			expected: "1.40 GB",
-		},
-		{
-			name:     "Zero",
-			size:     0,
-			expected: "0 bytes",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := formatSize(tt.size)
-			if result != tt.expected {
-				t.Errorf("formatSize(%d) = %s; want %s", tt.size, result, tt.expected)
-			}
-		})
-	}
-}
-
 // TestOrganizeErrorHandling tests error cases in the Organize function
 func TestOrganizeErrorHandling(t *testing.T) {
 	// Create test directories
@@ -106,6 +67,67 @@ func TestOrganizeErrorHandling(t *testing.T) {
 		}
 	})
 
+	t.Run("Invalid layout", func(t *testing.T) {
+		params := &models.Params{
+			Source:        sourceDir,
+			Destination:   destDir,
+			Compression:   -1,
+			Layout:        "{year}/../{day}",
+			SkipUserInput: true,
+		}
+
+		err := Organize(params)
+		if err == nil || !strings.Contains(err.Error(), "invalid layout") {
+			t.Errorf("Expected invalid layout error, got %v", err)
+		}
+	})
+
+	t.Run("Invalid chaos rates", func(t *testing.T) {
+		params := &models.Params{
+			Source:        sourceDir,
+			Destination:   destDir,
+			Compression:   -1,
+			Chaos:         "write=2",
+			SkipUserInput: true,
+		}
+
+		err := Organize(params)
+		if err == nil || !strings.Contains(err.Error(), "invalid chaos rates") {
+			t.Errorf("Expected invalid chaos rates error, got %v", err)
+		}
+	})
+
+	t.Run("Incremental without catalog", func(t *testing.T) {
+		params := &models.Params{
+			Source:        sourceDir,
+			Destination:   destDir,
+			Compression:   -1,
+			Incremental:   true,
+			SkipUserInput: true,
+		}
+
+		err := Organize(params)
+		if err == nil || !strings.Contains(err.Error(), "requires a catalog") {
+			t.Errorf("Expected catalog requirement error, got %v", err)
+		}
+	})
+
+	t.Run("Unsupported hash algorithm", func(t *testing.T) {
+		params := &models.Params{
+			Source:        sourceDir,
+			Destination:   destDir,
+			Compression:   -1,
+			CatalogFile:   filepath.Join(t.TempDir(), "catalog.jsonl"),
+			HashAlgo:      "md5",
+			SkipUserInput: true,
+		}
+
+		err := Organize(params)
+		if err == nil || !strings.Contains(err.Error(), "unsupported hash algorithm") {
+			t.Errorf("Expected hash algorithm error, got %v", err)
+		}
+	})
+
 	t.Run("Permission denied for destination", func(t *testing.T) {
 		// Skip on Windows as permission tests behave differently
 		if os.Getenv("GOOS") == "windows" {
@@ -462,6 +484,9 @@ func TestUserConfirmation(t *testing.T) {
 	oldStdin := os.Stdin
 	defer func() { os.Stdin = oldStdin }()
 
+	// The pipe stands in for an interactive terminal
+	defer mockTerminal(true)()
+
 	testCases := []struct {
 		name          string
 		userInput     string
@@ -550,6 +575,7 @@ func TestUserConfirmationError(t *testing.T) {
 	// Back up standard input
 	oldStdin := os.Stdin
 	defer func() { os.Stdin = oldStdin }()
+	defer mockTerminal(true)()
 
 	// Create a read-only pipe to simulate an input error
 	r, _, _ := os.Pipe()
@@ -577,3 +603,570 @@ func TestUserConfirmationError(t *testing.T) {
 		t.Errorf("Expected error to contain 'error reading input', got: %v", err)
 	}
 }
+
+// TestCheckPathOverlap tests detection of nested or identical source and destination paths
+func TestOrganizePathOverlap(t *testing.T) {
+	source := t.TempDir()
+	nested := filepath.Join(source, "organized")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	params := &models.Params{
+		Source:        source,
+		Destination:   nested,
+		Compression:   -1,
+		SkipUserInput: true,
+	}
+	if err := Organize(params); err == nil || !strings.Contains(err.Error(), "must not be inside") {
+		t.Errorf("Expected overlap error, got %v", err)
+	}
+}
+
+// mockTerminal overrides terminal detection of standard input for testing
+func mockTerminal(interactive bool) func() {
+	original := stdinIsTerminal
+	stdinIsTerminal = func() bool { return interactive }
+	return func() { stdinIsTerminal = original }
+}
+
+// TestNonInteractiveInput tests that a run needing confirmation fails fast without a terminal
+func TestNonInteractiveInput(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	sampleFile := filepath.Join(sourceDir, "test.jpg")
+	if err := os.WriteFile(sampleFile, []byte("test data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	defer mockTerminal(false)()
+
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: destDir,
+		Compression: -1,
+	}
+
+	err := Organize(params)
+	if err == nil || !strings.Contains(err.Error(), "not a terminal") {
+		t.Errorf("Expected non-terminal error, got %v", err)
+	}
+
+	// Skipping the prompt works without a terminal
+	params.SkipUserInput = true
+	if err := Organize(params); err != nil {
+		t.Errorf("Unexpected error with skip user input: %v", err)
+	}
+}
+
+// TestFormatPlanPreview tests the planned destination preview shown before confirmation
+func TestFormatPlanPreview(t *testing.T) {
+	destDir := t.TempDir()
+
+	// An existing day folder must not be listed as new
+	if err := os.MkdirAll(filepath.Join(destDir, "2024", "06-10"), 0755); err != nil {
+		t.Fatalf("Failed to create existing folder: %v", err)
+	}
+
+	var plan []utils.PlannedFile
+	for i := 0; i < 7; i++ {
+		day := fmt.Sprintf("06-%02d", 10+i%3)
+		name := fmt.Sprintf("DSC%05d.ARW", i)
+		plan = append(plan, utils.PlannedFile{
+			Source:      filepath.Join("/card", name),
+			Destination: filepath.Join(destDir, "2024", day, name),
+		})
+	}
+	plan = append(plan, utils.PlannedFile{Source: "/card/broken.jpg", Err: fmt.Errorf("no date")})
+
+	preview := formatPlanPreview(destDir, plan)
+
+	expected := []string{
+		"DSC00000.ARW → " + filepath.Join("2024", "06-10") + string(filepath.Separator),
+		"... and 2 more",
+		"New destination folders (2): " + filepath.Join("2024", "06-11") + ", " + filepath.Join("2024", "06-12"),
+		"1 files have no date and will be skipped",
+	}
+	for _, want := range expected {
+		if !strings.Contains(preview, want) {
+			t.Errorf("Expected preview to contain %q, got:\n%s", want, preview)
+		}
+	}
+	if strings.Contains(preview, "DSC00005.ARW") {
+		t.Errorf("Expected preview to be limited to %d samples, got:\n%s", previewSampleSize, preview)
+	}
+
+	// Files renamed by the flat layout show their new name
+	flat := []utils.PlannedFile{{Source: "/card/DSC00001.ARW", Destination: filepath.Join(destDir, "2024-06-11_153010_DSC00001.ARW")}}
+	if preview := formatPlanPreview(destDir, flat); !strings.Contains(preview, "DSC00001.ARW → 2024-06-11_153010_DSC00001.ARW\n") {
+		t.Errorf("Expected preview to show the flat name, got:\n%s", preview)
+	}
+}
+
+// TestOrganizePrecheck tests that a precheck of readable files lets the import proceed
+func TestOrganizePrecheck(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	sampleFile := filepath.Join(sourceDir, "test.jpg")
+	if err := os.WriteFile(sampleFile, []byte("test data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:        sourceDir,
+		Destination:   destDir,
+		Compression:   -1,
+		Precheck:      true,
+		SkipUserInput: true,
+	}
+
+	if err := Organize(params); err != nil {
+		t.Errorf("Unexpected error with precheck: %v", err)
+	}
+}
+
+func TestOrganizeStrict(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	// A file without EXIF date would be skipped
+	if err := os.WriteFile(filepath.Join(sourceDir, "test.jpg"), []byte("test data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:        sourceDir,
+		Destination:   destDir,
+		Compression:   -1,
+		Strict:        true,
+		SkipUserInput: true,
+	}
+
+	err := Organize(params)
+	if err == nil || !strings.Contains(err.Error(), "strict mode: 1 files would be skipped") {
+		t.Errorf("Expected strict mode error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(destDir); len(entries) != 0 {
+		t.Errorf("Expected nothing written in strict mode, found %d entries", len(entries))
+	}
+}
+
+func TestOrganizeStrictAskDates(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "scan.jpg"), []byte{0xFF, 0xD8, 0xFF, 0xD9}, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+	defer mockTerminal(true)()
+
+	// An invalid date is asked again, then the run is confirmed
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	w.WriteString("14/08/2023\n2023-08-14\ny\n")
+	w.Close()
+	os.Stdin = r
+
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: destDir,
+		Compression: -1,
+		Strict:      true,
+	}
+	if err := Organize(params); err != nil {
+		t.Fatalf("Organize() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "2023", "08-14", "scan.jpg")); err != nil {
+		t.Errorf("Expected the file at its manual date: %v", err)
+	}
+}
+
+func TestEjectSource(t *testing.T) {
+	originalEject := ejectVolume
+	defer func() { ejectVolume = originalEject }()
+
+	tests := []struct {
+		name      string
+		summary   utils.ProcessingSummary
+		ejectErr  error
+		wantEject bool
+	}{
+		{"Run without errors", utils.ProcessingSummary{Processed: 3, Copied: 3}, nil, true},
+		{"Eject failure", utils.ProcessingSummary{Processed: 1}, errors.New("device busy"), true},
+		{"Failed files", utils.ProcessingSummary{Processed: 2, Failed: 1}, nil, false},
+		{"Salvaged files", utils.ProcessingSummary{Processed: 2, Salvaged: 1}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ejected []string
+			ejectVolume = func(path string) error {
+				ejected = append(ejected, path)
+				return tt.ejectErr
+			}
+
+			ejectSource("/media/user/CARD/DCIM", tt.summary)
+
+			if tt.wantEject && (len(ejected) != 1 || ejected[0] != "/media/user/CARD/DCIM") {
+				t.Errorf("Expected source to be ejected once, got %v", ejected)
+			}
+			if !tt.wantEject && len(ejected) != 0 {
+				t.Errorf("Expected source not to be ejected, got %v", ejected)
+			}
+		})
+	}
+}
+
+func TestOrganizeToRemote(t *testing.T) {
+	originalList, originalUpload, originalFree := listRemote, uploadToRemote, freeSpace
+	defer func() { listRemote, uploadToRemote, freeSpace = originalList, originalUpload, originalFree }()
+
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "test.jpg"), []byte("test data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	listRemote = func(remote string) ([]string, error) {
+		return []string{"2025/01-11/old.jpg"}, nil
+	}
+	var uploadedTo, stagedIn string
+	var staged []string
+	uploadToRemote = func(dir, remote string) error {
+		uploadedTo, stagedIn = remote, filepath.Dir(dir)
+		return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				staged = append(staged, path)
+			}
+			return err
+		})
+	}
+
+	params := &models.Params{
+		Source:        sourceDir,
+		Destination:   "rclone:gdrive:Photos",
+		StagingDir:    t.TempDir(),
+		Compression:   -1,
+		SkipUserInput: true,
+	}
+	if err := Organize(params); err != nil {
+		t.Fatalf("Organize() error = %v", err)
+	}
+	if uploadedTo != "gdrive:Photos" {
+		t.Errorf("Uploaded to %q, want gdrive:Photos", uploadedTo)
+	}
+	if stagedIn != params.StagingDir {
+		t.Errorf("Staged in %s, want %s", stagedIn, params.StagingDir)
+	}
+	// Placeholders of remote files are never uploaded
+	if len(staged) != 0 {
+		t.Errorf("Expected nothing to upload, staged %v", staged)
+	}
+
+	// Nothing is staged when the staging volume cannot hold the source
+	uploadedTo = ""
+	freeSpace = func(string) (int64, bool) { return 4, true }
+	if err := Organize(params); err == nil || !strings.Contains(err.Error(), "not enough space") {
+		t.Errorf("Expected an error staging on a full volume, got %v", err)
+	}
+	if uploadedTo != "" {
+		t.Errorf("Uploaded to %q from a full staging volume", uploadedTo)
+	}
+
+	params.DeleteSource = true
+	if err := Organize(params); err == nil || !strings.Contains(err.Error(), "not supported with rclone destinations") {
+		t.Errorf("Expected an error deleting sources with an rclone destination, got %v", err)
+	}
+}
+
+func TestOrganizeNotify(t *testing.T) {
+	originalSend := sendEmail
+	defer func() { sendEmail = originalSend }()
+
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "test.jpg"), []byte("test data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	work := t.TempDir()
+	settingsFile := filepath.Join(work, "smtp.json")
+	settings := `{"server": "smtp.example.com:587", "from": "nas@example.com", "to": ["me@example.com"]}`
+	if err := os.WriteFile(settingsFile, []byte(settings), 0644); err != nil {
+		t.Fatalf("Failed to write SMTP settings: %v", err)
+	}
+
+	var subject, body string
+	var attachments map[string][]byte
+	sendEmail = func(s utils.SMTPSettings, subj, b string, attached map[string][]byte) error {
+		subject, body, attachments = subj, b, attached
+		return errors.New("connection refused")
+	}
+
+	params := &models.Params{
+		Source:        sourceDir,
+		Destination:   t.TempDir(),
+		Compression:   -1,
+		SkipUserInput: true,
+		ReportFile:    filepath.Join(work, "report.json"),
+		NotifySMTP:    settingsFile,
+	}
+	// Failing to send the summary does not fail the run
+	if err := Organize(params); err != nil {
+		t.Fatalf("Organize() error = %v", err)
+	}
+
+	if !strings.Contains(subject, "0 files imported") {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	for _, want := range []string{sourceDir, "Number of files skipped: 1", "Bytes written:"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected body to contain %q, got:\n%s", want, body)
+		}
+	}
+	if len(attachments["report.json"]) == 0 {
+		t.Errorf("Expected the report to be attached, got %v", attachments)
+	}
+
+	// A run failing before it writes its report does not send the previous one
+	stale := &utils.Report{StartedAt: time.Now().Add(-time.Hour)}
+	stale.Add(utils.ReportEntry{Source: "IMG_0001.jpg", Status: utils.ReportFailed, Reason: "read error"})
+	if err := stale.Write(params.ReportFile); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+	notifyRun(params, time.Now(), utils.ProcessingSummary{}, errors.New("disk full"))
+	if len(attachments) != 0 || strings.Contains(body, "IMG_0001.jpg") {
+		t.Errorf("Expected the previous report left out, got %v:\n%s", attachments, body)
+	}
+}
+
+func TestFormatNotificationErrors(t *testing.T) {
+	report := &utils.Report{}
+	for i := range notifiedErrors + 2 {
+		report.Add(utils.ReportEntry{Source: fmt.Sprintf("IMG_%04d.jpg", i), Status: utils.ReportFailed, Reason: "read error"})
+	}
+	report.Add(utils.ReportEntry{Source: "IMG_9999.jpg", Status: utils.ReportCopied})
+
+	params := &models.Params{Source: "/media/card", Destination: "/photos"}
+	summary := utils.ProcessingSummary{Processed: notifiedErrors + 3, Copied: 1, Failed: notifiedErrors + 2}
+	subject, body := formatNotification(params, summary, report, nil)
+
+	if !strings.Contains(subject, fmt.Sprintf("1 files imported, %d failed", notifiedErrors+2)) {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if !strings.Contains(body, "- IMG_0000.jpg: read error") || strings.Contains(body, "IMG_9999.jpg") {
+		t.Errorf("Expected only failed files to be listed, got:\n%s", body)
+	}
+	if !strings.Contains(body, "... and 2 more") {
+		t.Errorf("Expected the list of failed files to be truncated, got:\n%s", body)
+	}
+
+	subject, body = formatNotification(params, summary, nil, errors.New("disk full"))
+	if !strings.Contains(subject, "import failed") || !strings.Contains(body, "disk full") {
+		t.Errorf("Expected the run error to be reported, got %q:\n%s", subject, body)
+	}
+}
+
+func TestFormatSkips(t *testing.T) {
+	tests := []struct {
+		name      string
+		skips     utils.SkipCounts
+		want      []string
+		wantCheck bool
+	}{
+		{name: "no skipped files"},
+		{
+			name:  "benign skips",
+			skips: utils.SkipCounts{ExistsIdentical: 3, Filtered: 1},
+			want:  []string{"  already in the destination: 3", "  left out on purpose: 1"},
+		},
+		{
+			name:      "files at risk",
+			skips:     utils.SkipCounts{ExistsIdentical: 1, NoDate: 2},
+			want:      []string{"  already in the destination: 1", "  no capture date: 2"},
+			wantCheck: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatSkips(tt.skips)
+			if tt.wantCheck {
+				if len(got) == 0 || !strings.Contains(got[len(got)-1], "check them") {
+					t.Fatalf("formatSkips() = %q, want a warning last", got)
+				}
+				got = got[:len(got)-1]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("formatSkips() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrganizePublishStatus(t *testing.T) {
+	originalPublish := publishStatus
+	defer func() { publishStatus = originalPublish }()
+
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "test.jpg"), []byte("test data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	settingsFile := filepath.Join(t.TempDir(), "mqtt.json")
+	if err := os.WriteFile(settingsFile, []byte(`{"broker": "homeassistant.local:1883"}`), 0644); err != nil {
+		t.Fatalf("Failed to write MQTT settings: %v", err)
+	}
+
+	var published []utils.RunStatus
+	publishStatus = func(settings utils.MQTTSettings, status utils.RunStatus) error {
+		if settings.Topic != utils.DefaultMQTTTopic {
+			t.Errorf("Published to %q, want %q", settings.Topic, utils.DefaultMQTTTopic)
+		}
+		published = append(published, status)
+		return nil
+	}
+
+	params := &models.Params{
+		Source:        sourceDir,
+		Destination:   t.TempDir(),
+		Compression:   -1,
+		SkipUserInput: true,
+		NotifyMQTT:    settingsFile,
+	}
+	if err := Organize(params); err != nil {
+		t.Fatalf("Organize() error = %v", err)
+	}
+
+	if len(published) != 2 {
+		t.Fatalf("Expected 2 published states, got %+v", published)
+	}
+	if published[0].State != utils.StatusRunning || published[0].FinishedAt != nil {
+		t.Errorf("Expected the run to be published as running first, got %+v", published[0])
+	}
+	if published[1].State != utils.StatusIdle || published[1].Skipped != 1 || published[1].FinishedAt == nil {
+		t.Errorf("Expected the finished run to be published as idle, got %+v", published[1])
+	}
+}
+
+func TestPublishRunStatusErrored(t *testing.T) {
+	originalPublish := publishStatus
+	defer func() { publishStatus = originalPublish }()
+
+	settingsFile := filepath.Join(t.TempDir(), "mqtt.json")
+	if err := os.WriteFile(settingsFile, []byte(`{"broker": "homeassistant.local:1883"}`), 0644); err != nil {
+		t.Fatalf("Failed to write MQTT settings: %v", err)
+	}
+	var published utils.RunStatus
+	publishStatus = func(settings utils.MQTTSettings, status utils.RunStatus) error {
+		published = status
+		return nil
+	}
+	params := &models.Params{Source: "/media/card", Destination: "/photos", NotifyMQTT: settingsFile}
+
+	tests := []struct {
+		name    string
+		summary utils.ProcessingSummary
+		runErr  error
+		want    string
+	}{
+		{"Run without errors", utils.ProcessingSummary{Processed: 2, Copied: 2}, nil, utils.StatusIdle},
+		{"Failed files", utils.ProcessingSummary{Processed: 2, Copied: 1, Failed: 1}, nil, utils.StatusErrored},
+		{"Run error", utils.ProcessingSummary{}, errors.New("disk full"), utils.StatusErrored},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publishRunStatus(params, time.Now(), &tt.summary, tt.runErr)
+			if published.State != tt.want {
+				t.Errorf("Published state %q, want %q", published.State, tt.want)
+			}
+			if tt.runErr != nil && published.Error != tt.runErr.Error() {
+				t.Errorf("Published error %q, want %q", published.Error, tt.runErr)
+			}
+		})
+	}
+}
+
+func TestOrganizeChaos(t *testing.T) {
+	sourceDir := t.TempDir()
+	source := filepath.Join(sourceDir, "test.jpg")
+	if err := os.WriteFile(source, []byte("2024:06:11 15:30:10"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:        sourceDir,
+		Destination:   t.TempDir(),
+		Compression:   -1,
+		DeleteSource:  true,
+		HashAlgo:      utils.HashSHA256,
+		Chaos:         "write=1",
+		ChaosSeed:     1,
+		SkipUserInput: true,
+	}
+	if err := Organize(params); err != nil {
+		t.Fatalf("Organize() error = %v", err)
+	}
+
+	// Every write fails, the source is kept
+	if _, err := os.Stat(source); err != nil {
+		t.Errorf("Expected the source to be kept: %v", err)
+	}
+}
+
+func TestOrganizeFromReport(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	for _, name := range []string{"copied.jpg", "failed.jpg"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte("test data"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	previous := &utils.Report{Source: sourceDir, Destination: destDir, Files: []utils.ReportEntry{
+		{Source: filepath.Join(sourceDir, "copied.jpg"), Status: utils.ReportCopied},
+		{Source: filepath.Join(sourceDir, "failed.jpg"), Status: utils.ReportFailed},
+	}}
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	if err := previous.Write(reportPath); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+
+	// Source and destination come from the report, only the failed file is imported again
+	params := &models.Params{
+		Compression:   -1,
+		FromReport:    reportPath,
+		OnlyErrors:    true,
+		HashAlgo:      utils.HashSHA256,
+		SkipUserInput: true,
+		DateOverrides: map[string]time.Time{
+			filepath.Join(sourceDir, "copied.jpg"): time.Date(2024, 6, 11, 15, 30, 10, 0, time.UTC),
+			filepath.Join(sourceDir, "failed.jpg"): time.Date(2024, 6, 11, 15, 30, 10, 0, time.UTC),
+		},
+	}
+	if err := Organize(params); err != nil {
+		t.Fatalf("Organize() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "2024", "06-11", "failed.jpg")); err != nil {
+		t.Errorf("Expected the failed file to be imported: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "2024", "06-11", "copied.jpg")); !os.IsNotExist(err) {
+		t.Errorf("Expected the copied file not to be imported again, stat error = %v", err)
+	}
+
+	// A report without errors leaves nothing to do
+	params = &models.Params{Compression: -1, FromReport: reportPath, OnlyErrors: true, SkipUserInput: true}
+	previous.Files = previous.Files[:1]
+	if err := previous.Write(reportPath); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+	if err := Organize(params); err != nil {
+		t.Errorf("Organize() of a report without errors error = %v", err)
+	}
+
+	params = &models.Params{Compression: -1, FromReport: filepath.Join(t.TempDir(), "missing.json")}
+	if err := Organize(params); err == nil || !strings.Contains(err.Error(), "report file not found") {
+		t.Errorf("Organize() with a missing report error = %v", err)
+	}
+}
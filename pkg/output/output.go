@@ -0,0 +1,136 @@
+// Package output prints run information, per-file statuses and summaries
+// according to the selected verbosity, with colored status tags on terminals.
+package output
+
+import (
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// Verbosity controls which messages are printed
+type Verbosity int
+
+const (
+	Quiet   Verbosity = iota // Summary and errors only
+	Normal                   // Run information and per-file results
+	Verbose                  // Everything, including per-file progress details
+)
+
+// ANSI color codes of status tags
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+// tagColors maps status tags to their color
+var tagColors = map[string]string{
+	"COPIED":     colorGreen,
+	"COMPRESSED": colorGreen,
+	"CORRUPT":    colorYellow,
+	"CULLED":     colorCyan,
+	"DELETED":    colorCyan,
+	"REJECTED":   colorYellow,
+	"SALVAGED":   colorYellow,
+	"SKIPPED":    colorYellow,
+	"WARNING":    colorYellow,
+	"ERROR":      colorRed,
+}
+
+var (
+	mu        sync.RWMutex
+	verbosity = Normal
+	colored   = false
+)
+
+// Configure sets the verbosity and whether status tags are colored
+func Configure(v Verbosity, color bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	verbosity = v
+	colored = color
+}
+
+// Level returns the configured verbosity
+func Level() Verbosity {
+	mu.RLock()
+	defer mu.RUnlock()
+	return verbosity
+}
+
+// ColorSupported reports whether f is a terminal that should receive colors.
+// Colors are disabled when NO_COLOR is set or TERM is "dumb".
+func ColorSupported(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Info prints run information, hidden in quiet mode
+func Info(msg string) {
+	if Level() >= Normal {
+		log.Print(msg)
+	}
+}
+
+// Debug prints per-file progress details, shown in verbose mode only
+func Debug(msg string) {
+	if Level() >= Verbose {
+		log.Print(msg)
+	}
+}
+
+// Summary prints end-of-run results, always shown
+func Summary(msg string) {
+	log.Print(msg)
+}
+
+// Status prints the outcome of a file with a tag such as SKIPPED or ERROR.
+// Errors are always shown, other statuses are hidden in quiet mode.
+func Status(tag, msg string) {
+	if tag != "ERROR" && Level() < Normal {
+		return
+	}
+	log.Print(Tag(tag) + " " + msg)
+}
+
+// Tag returns the bracketed status tag, colored when colors are enabled
+func Tag(tag string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if color, ok := tagColors[tag]; ok && colored {
+		return color + "[" + tag + "]" + colorReset
+	}
+	return "[" + tag + "]"
+}
+
+// ansiPattern matches ANSI escape sequences
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// stripWriter removes ANSI escape sequences before writing
+type stripWriter struct {
+	w io.Writer
+}
+
+// StripColor wraps w so colors never end up in files such as logs
+func StripColor(w io.Writer) io.Writer {
+	return &stripWriter{w: w}
+}
+
+func (s *stripWriter) Write(p []byte) (int, error) {
+	if _, err := s.w.Write(ansiPattern.ReplaceAll(p, nil)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
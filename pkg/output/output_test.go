@@ -0,0 +1,130 @@
+package output
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureLog redirects the standard logger to a buffer for testing
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	flags := log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+		Configure(Normal, false)
+	})
+	return &buf
+}
+
+func TestVerbosity(t *testing.T) {
+	tests := []struct {
+		name      string
+		verbosity Verbosity
+		want      []string
+		notWant   []string
+	}{
+		{
+			name:      "quiet",
+			verbosity: Quiet,
+			want:      []string{"summary", "[ERROR] failed"},
+			notWant:   []string{"info", "debug", "[SKIPPED] skipped"},
+		},
+		{
+			name:      "normal",
+			verbosity: Normal,
+			want:      []string{"summary", "[ERROR] failed", "info", "[SKIPPED] skipped"},
+			notWant:   []string{"debug"},
+		},
+		{
+			name:      "verbose",
+			verbosity: Verbose,
+			want:      []string{"summary", "[ERROR] failed", "info", "[SKIPPED] skipped", "debug"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := captureLog(t)
+			Configure(tt.verbosity, false)
+
+			Info("info")
+			Debug("debug")
+			Summary("summary")
+			Status("SKIPPED", "skipped")
+			Status("ERROR", "failed")
+
+			got := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTag(t *testing.T) {
+	defer Configure(Normal, false)
+
+	Configure(Normal, false)
+	if got := Tag("SKIPPED"); got != "[SKIPPED]" {
+		t.Errorf("Tag() without colors = %q, want [SKIPPED]", got)
+	}
+
+	Configure(Normal, true)
+	if got := Tag("SKIPPED"); got != colorYellow+"[SKIPPED]"+colorReset {
+		t.Errorf("Tag() with colors = %q, want yellow [SKIPPED]", got)
+	}
+	if got := Tag("ERROR"); got != colorRed+"[ERROR]"+colorReset {
+		t.Errorf("Tag() with colors = %q, want red [ERROR]", got)
+	}
+	if got := Tag("UNKNOWN"); got != "[UNKNOWN]" {
+		t.Errorf("Tag() of unknown tag = %q, want [UNKNOWN]", got)
+	}
+}
+
+func TestColorSupported(t *testing.T) {
+	// Regular files are never terminals
+	f, err := os.CreateTemp(t.TempDir(), "output")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer f.Close()
+
+	if ColorSupported(f) {
+		t.Error("Expected no color support for a regular file")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if ColorSupported(os.Stdout) {
+		t.Error("Expected no color support when NO_COLOR is set")
+	}
+}
+
+func TestStripColor(t *testing.T) {
+	var buf bytes.Buffer
+	w := StripColor(&buf)
+
+	input := []byte(colorYellow + "[SKIPPED]" + colorReset + " file.jpg\n")
+	n, err := w.Write(input)
+	if err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	if n != len(input) {
+		t.Errorf("Write() = %d, want %d", n, len(input))
+	}
+	if buf.String() != "[SKIPPED] file.jpg\n" {
+		t.Errorf("StripColor() wrote %q, want plain text", buf.String())
+	}
+}
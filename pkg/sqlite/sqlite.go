@@ -0,0 +1,415 @@
+// Package sqlite reads the rows of the tables of SQLite 3 database files,
+// such as Lightroom catalogs, without a database driver.
+//
+// Only what reading tables needs is implemented: table b-trees, overflow
+// pages and records of UTF-8 databases. Changes still in a write-ahead log
+// are not seen, databases should be closed by the application writing them.
+package sqlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// headerMagic starts every SQLite 3 database file
+const headerMagic = "SQLite format 3\x00"
+
+// Types of b-tree pages
+const (
+	pageTableInterior = 0x05
+	pageTableLeaf     = 0x0d
+)
+
+// ErrNotDatabase is returned when a file is not an SQLite 3 database
+var ErrNotDatabase = errors.New("not an SQLite database")
+
+// DB is an SQLite database file opened for reading
+type DB struct {
+	file     *os.File
+	pageSize int
+	usable   int // Page size without the bytes reserved at the end of every page
+	pages    int
+	tables   map[string]table // Keyed by lower case name
+}
+
+// table is a table of the schema
+type table struct {
+	root    int
+	columns []string // Lower case names
+	rowid   int      // Index of the column aliasing the rowid, -1 when none
+}
+
+// Open opens a database file and reads its schema
+func Open(path string) (*DB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 100)
+	if _, err := file.ReadAt(header, 0); err != nil || string(header[:16]) != headerMagic {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s", ErrNotDatabase, path)
+	}
+	if encoding := binary.BigEndian.Uint32(header[56:]); encoding > 1 {
+		file.Close()
+		return nil, fmt.Errorf("unsupported text encoding of %s: only UTF-8 is read", path)
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(header[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	db := &DB{
+		file:     file,
+		pageSize: pageSize,
+		usable:   pageSize - int(header[20]),
+		pages:    int(info.Size() / int64(pageSize)),
+		tables:   make(map[string]table),
+	}
+	if pageSize < 512 || db.usable < 480 {
+		file.Close()
+		return nil, fmt.Errorf("%w: invalid page size in %s", ErrNotDatabase, path)
+	}
+
+	// The schema is the table of page 1: type, name, tbl_name, rootpage, sql
+	err = db.walk(1, func(_ int64, values []any) error {
+		if len(values) < 5 || values[0] != "table" {
+			return nil
+		}
+		name, _ := values[1].(string)
+		root, _ := values[3].(int64)
+		sql, _ := values[4].(string)
+		// Tables without rowid are stored as indexes
+		if strings.Contains(strings.ToUpper(sql), "WITHOUT ROWID") {
+			return nil
+		}
+		columns, rowid := parseColumns(sql)
+		db.tables[strings.ToLower(name)] = table{root: int(root), columns: columns, rowid: rowid}
+		return nil
+	})
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read the schema of %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Close closes the database file
+func (db *DB) Close() error {
+	return db.file.Close()
+}
+
+// HasTable reports whether the database holds a table
+func (db *DB) HasTable(name string) bool {
+	_, ok := db.tables[strings.ToLower(name)]
+	return ok
+}
+
+// Rows calls fn with the values of columns for every row of a table, in rowid
+// order. Values are int64, float64, string, []byte or nil for NULL, and the
+// columns added to a table after a row was written are nil.
+func (db *DB) Rows(tableName string, columns []string, fn func(values []any) error) error {
+	t, ok := db.tables[strings.ToLower(tableName)]
+	if !ok {
+		return fmt.Errorf("no such table: %s", tableName)
+	}
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		indexes[i] = -1
+		for j, name := range t.columns {
+			if name == strings.ToLower(column) {
+				indexes[i] = j
+			}
+		}
+		if indexes[i] < 0 {
+			return fmt.Errorf("no such column: %s.%s", tableName, column)
+		}
+	}
+
+	selected := make([]any, len(columns))
+	return db.walk(t.root, func(rowid int64, values []any) error {
+		for i, index := range indexes {
+			selected[i] = nil
+			if index < len(values) {
+				selected[i] = values[index]
+			}
+			if index == t.rowid {
+				selected[i] = rowid
+			}
+		}
+		return fn(selected)
+	})
+}
+
+// page reads a page, numbered from 1
+func (db *DB) page(n int) ([]byte, error) {
+	if n < 1 || n > db.pages {
+		return nil, fmt.Errorf("page %d out of range", n)
+	}
+	data := make([]byte, db.pageSize)
+	if _, err := db.file.ReadAt(data, int64(n-1)*int64(db.pageSize)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// walk calls fn with the rowid and values of every row of the table b-tree
+// rooted at page root
+func (db *DB) walk(root int, fn func(rowid int64, values []any) error) error {
+	return db.walkPage(root, 0, fn)
+}
+
+// walkPage walks the b-tree page n, depth pages below the root
+func (db *DB) walkPage(n, depth int, fn func(rowid int64, values []any) error) error {
+	// A b-tree deeper than this is a loop in a damaged file
+	if depth > 64 {
+		return fmt.Errorf("b-tree too deep at page %d", n)
+	}
+	data, err := db.page(n)
+	if err != nil {
+		return err
+	}
+	header := data
+	if n == 1 {
+		header = data[100:]
+	}
+
+	cells := int(binary.BigEndian.Uint16(header[3:]))
+	if len(header) < 12+2*cells {
+		return fmt.Errorf("too many cells in page %d", n)
+	}
+	switch header[0] {
+	case pageTableInterior:
+		pointers := header[12:]
+		for i := 0; i < cells; i++ {
+			offset := int(binary.BigEndian.Uint16(pointers[2*i:]))
+			if offset+4 > len(data) {
+				return fmt.Errorf("cell out of page %d", n)
+			}
+			if err := db.walkPage(int(binary.BigEndian.Uint32(data[offset:])), depth+1, fn); err != nil {
+				return err
+			}
+		}
+		return db.walkPage(int(binary.BigEndian.Uint32(header[8:])), depth+1, fn)
+
+	case pageTableLeaf:
+		pointers := header[8:]
+		for i := 0; i < cells; i++ {
+			offset := int(binary.BigEndian.Uint16(pointers[2*i:]))
+			rowid, payload, err := db.leafCell(data, offset)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", n, err)
+			}
+			values, err := parseRecord(payload)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", n, err)
+			}
+			if err := fn(rowid, values); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("page %d is not a table b-tree page", n)
+	}
+}
+
+// leafCell returns the rowid and payload of the table leaf cell at offset,
+// gathering the part of the payload spilled to overflow pages
+func (db *DB) leafCell(data []byte, offset int) (int64, []byte, error) {
+	if offset >= len(data) {
+		return 0, nil, errors.New("cell out of page")
+	}
+	size, n := varint(data[offset:])
+	offset += n
+	rowid, n := varint(data[offset:])
+	offset += n
+	if size < 0 || size > int64(db.pages)*int64(db.pageSize) {
+		return 0, nil, errors.New("invalid payload size")
+	}
+
+	local := db.localPayload(int(size))
+	if offset+local > len(data) {
+		return 0, nil, errors.New("payload out of page")
+	}
+	payload := make([]byte, 0, size)
+	payload = append(payload, data[offset:offset+local]...)
+	if local == int(size) {
+		return rowid, payload, nil
+	}
+
+	if offset+local+4 > len(data) {
+		return 0, nil, errors.New("overflow pointer out of page")
+	}
+	next := int(binary.BigEndian.Uint32(data[offset+local:]))
+	for len(payload) < int(size) {
+		if next == 0 {
+			return 0, nil, errors.New("truncated overflow chain")
+		}
+		overflow, err := db.page(next)
+		if err != nil {
+			return 0, nil, err
+		}
+		chunk := min(int(size)-len(payload), db.usable-4)
+		payload = append(payload, overflow[4:4+chunk]...)
+		next = int(binary.BigEndian.Uint32(overflow))
+	}
+	return rowid, payload, nil
+}
+
+// localPayload returns how many bytes of a payload of size bytes are stored
+// in a table leaf cell, the rest going to overflow pages
+func (db *DB) localPayload(size int) int {
+	maxLocal := db.usable - 35
+	if size <= maxLocal {
+		return size
+	}
+	minLocal := (db.usable-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(db.usable-4)
+	if local > maxLocal {
+		return minLocal
+	}
+	return local
+}
+
+// parseRecord decodes the values of a record
+func parseRecord(payload []byte) ([]any, error) {
+	headerSize, n := varint(payload)
+	if headerSize < int64(n) || headerSize > int64(len(payload)) {
+		return nil, errors.New("invalid record header")
+	}
+
+	var values []any
+	body := payload[headerSize:]
+	for pos := n; pos < int(headerSize); {
+		serial, n := varint(payload[pos:int(headerSize)])
+		pos += n
+
+		size := serialSize(serial)
+		if size > len(body) {
+			return nil, errors.New("record value out of payload")
+		}
+		value := body[:size]
+		body = body[size:]
+
+		switch {
+		case serial == 0:
+			values = append(values, nil)
+		case serial <= 6:
+			v := int64(int8(value[0])) // Sign of the first byte, then the others
+			for _, b := range value[1:] {
+				v = v<<8 | int64(b)
+			}
+			values = append(values, v)
+		case serial == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(value)))
+		case serial == 8, serial == 9:
+			values = append(values, serial-8)
+		case serial >= 12 && serial%2 == 0:
+			values = append(values, append([]byte(nil), value...))
+		case serial >= 13:
+			values = append(values, string(value))
+		default:
+			return nil, fmt.Errorf("invalid serial type %d", serial)
+		}
+	}
+	return values, nil
+}
+
+// serialSize returns the size of the value of a serial type
+func serialSize(serial int64) int {
+	switch {
+	case serial >= 12:
+		return int((serial - 12) / 2)
+	case serial == 5:
+		return 6
+	case serial == 6, serial == 7:
+		return 8
+	case serial >= 1 && serial <= 4:
+		return int(serial)
+	default:
+		return 0
+	}
+}
+
+// varint decodes a big-endian variable-length integer of 1 to 9 bytes,
+// returning its value and size, 0 bytes when b is too short
+func varint(b []byte) (int64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return int64(v<<8 | uint64(b[i])), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return int64(v), i + 1
+		}
+	}
+	return 0, len(b)
+}
+
+// parseColumns returns the lower case names of the columns of a CREATE TABLE
+// statement, and the index of the column aliasing the rowid, -1 when none
+func parseColumns(sql string) ([]string, int) {
+	start, end := strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if start < 0 || end < start {
+		return nil, -1
+	}
+
+	var columns []string
+	rowid := -1
+	for _, def := range splitDefinitions(sql[start+1 : end]) {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			continue
+		}
+		if len(fields) > 1 && strings.EqualFold(fields[1], "INTEGER") && strings.Contains(strings.ToUpper(def), "PRIMARY KEY") {
+			rowid = len(columns)
+		}
+		columns = append(columns, strings.ToLower(strings.Trim(fields[0], "\"`[]")))
+	}
+	return columns, rowid
+}
+
+// splitDefinitions splits the definitions of a CREATE TABLE statement on the
+// commas outside parentheses and quotes
+func splitDefinitions(s string) []string {
+	var defs []string
+	depth, quote, last := 0, byte(0), 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			defs = append(defs, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(defs, s[last:])
+}
@@ -0,0 +1,364 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTable is a table written by writeTestDB, its rows numbered from 1
+type testTable struct {
+	name string
+	sql  string
+	rows [][]any
+}
+
+// testWriter lays out the pages of a database written by writeTestDB
+type testWriter struct {
+	pageSize int
+	pages    [][]byte
+}
+
+// alloc adds a page and returns its number
+func (w *testWriter) alloc() int {
+	w.pages = append(w.pages, make([]byte, w.pageSize))
+	return len(w.pages)
+}
+
+// cell returns the table leaf cell of a row, spilling its payload to
+// overflow pages like SQLite does
+func (w *testWriter) cell(rowid int64, values []any) []byte {
+	payload := encodeRecord(values)
+	cell := appendVarint(nil, int64(len(payload)))
+	cell = appendVarint(cell, rowid)
+
+	db := &DB{usable: w.pageSize}
+	local := db.localPayload(len(payload))
+	cell = append(cell, payload[:local]...)
+	if local == len(payload) {
+		return cell
+	}
+
+	rest := payload[local:]
+	first := w.alloc()
+	cell = binary.BigEndian.AppendUint32(cell, uint32(first))
+	for page := first; len(rest) > 0; {
+		chunk := min(len(rest), w.pageSize-4)
+		copy(w.pages[page-1][4:], rest[:chunk])
+		rest = rest[chunk:]
+		if len(rest) > 0 {
+			next := w.alloc()
+			binary.BigEndian.PutUint32(w.pages[page-1], uint32(next))
+			page = next
+		}
+	}
+	return cell
+}
+
+// fill writes a b-tree page of type kind holding cells, its header at offset
+func (w *testWriter) fill(n, offset int, kind byte, cells [][]byte, right int) {
+	page := w.pages[n-1]
+	header := 8
+	if kind == pageTableInterior {
+		header = 12
+		binary.BigEndian.PutUint32(page[offset+8:], uint32(right))
+	}
+	page[offset] = kind
+	binary.BigEndian.PutUint16(page[offset+3:], uint16(len(cells)))
+
+	content := w.pageSize
+	for i, cell := range cells {
+		content -= len(cell)
+		copy(page[content:], cell)
+		binary.BigEndian.PutUint16(page[offset+header+2*i:], uint16(content))
+	}
+	binary.BigEndian.PutUint16(page[offset+5:], uint16(content))
+}
+
+// writeTestDB writes a database holding tables, splitting the rows of a table
+// over several leaves below an interior page when they do not fit in one
+func writeTestDB(t *testing.T, path string, pageSize int, tables []testTable) {
+	t.Helper()
+	w := &testWriter{pageSize: pageSize}
+	w.alloc() // Schema
+
+	var schema [][]byte
+	for _, table := range tables {
+		var leaves [][][]byte
+		var lastRowids []int64
+		var leaf [][]byte
+		used := 8
+		for i, row := range table.rows {
+			cell := w.cell(int64(i+1), row)
+			if used+len(cell)+2 > pageSize && len(leaf) > 0 {
+				leaves, lastRowids = append(leaves, leaf), append(lastRowids, int64(i))
+				leaf, used = nil, 8
+			}
+			leaf = append(leaf, cell)
+			used += len(cell) + 2
+		}
+		leaves, lastRowids = append(leaves, leaf), append(lastRowids, int64(len(table.rows)))
+
+		pages := make([]int, len(leaves))
+		for i, cells := range leaves {
+			pages[i] = w.alloc()
+			w.fill(pages[i], 0, pageTableLeaf, cells, 0)
+		}
+		root := pages[0]
+		if len(pages) > 1 {
+			var cells [][]byte
+			for i, page := range pages[:len(pages)-1] {
+				cells = append(cells, appendVarint(binary.BigEndian.AppendUint32(nil, uint32(page)), lastRowids[i]))
+			}
+			root = w.alloc()
+			w.fill(root, 0, pageTableInterior, cells, pages[len(pages)-1])
+		}
+		schema = append(schema, w.cell(int64(len(schema)+1), []any{"table", table.name, table.name, int64(root), table.sql}))
+	}
+	w.fill(1, 100, pageTableLeaf, schema, 0)
+
+	header := w.pages[0]
+	copy(header, headerMagic)
+	binary.BigEndian.PutUint16(header[16:], uint16(pageSize))
+	header[18], header[19] = 1, 1
+	header[21], header[22], header[23] = 64, 32, 32
+	binary.BigEndian.PutUint32(header[28:], uint32(len(w.pages)))
+	binary.BigEndian.PutUint32(header[56:], 1)
+
+	var data []byte
+	for _, page := range w.pages {
+		data = append(data, page...)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write test database: %v", err)
+	}
+}
+
+// encodeRecord encodes values in the record format
+func encodeRecord(values []any) []byte {
+	var types, body []byte
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			types = appendVarint(types, 0)
+		case int64:
+			types = appendVarint(types, 6)
+			body = binary.BigEndian.AppendUint64(body, uint64(v))
+		case float64:
+			types = appendVarint(types, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			types = appendVarint(types, int64(13+2*len(v)))
+			body = append(body, v...)
+		case []byte:
+			types = appendVarint(types, int64(12+2*len(v)))
+			body = append(body, v...)
+		}
+	}
+	// The header size counts itself, one byte for the records of these tests
+	return append(append(appendVarint(nil, int64(len(types)+1)), types...), body...)
+}
+
+// appendVarint appends the variable-length encoding of v, below 2^56
+func appendVarint(b []byte, v int64) []byte {
+	var groups []byte
+	for {
+		groups = append([]byte{byte(v & 0x7f)}, groups...)
+		v >>= 7
+		if v == 0 {
+			break
+		}
+	}
+	for i := range groups[:len(groups)-1] {
+		groups[i] |= 0x80
+	}
+	return append(b, groups...)
+}
+
+func TestVarint(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want int64
+		size int
+	}{
+		{[]byte{0x00}, 0, 1},
+		{[]byte{0x7f}, 127, 1},
+		{[]byte{0x81, 0x00}, 128, 2},
+		{[]byte{0x82, 0x80, 0x01}, 32769, 3},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, -1, 9},
+		{[]byte{0x81}, 0, 1}, // Truncated
+	}
+	for _, tt := range tests {
+		got, size := varint(tt.data)
+		if got != tt.want || size != tt.size {
+			t.Errorf("varint(%x) = %d, %d, want %d, %d", tt.data, got, size, tt.want, tt.size)
+		}
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	// Header of 9 bytes: its size, NULL, int8, int24, zero, one, float, text of 2 bytes, blob of 1 byte
+	payload := []byte{9, 0, 1, 3, 8, 9, 7, 17, 14}
+	payload = append(payload, 0xfe)             // -2
+	payload = append(payload, 0x01, 0x00, 0x00) // 65536
+	payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(1.5))
+	payload = append(payload, "hi"...)
+	payload = append(payload, 0x2a)
+
+	got, err := parseRecord(payload)
+	if err != nil {
+		t.Fatalf("parseRecord() error = %v", err)
+	}
+	want := []any{nil, int64(-2), int64(65536), int64(0), int64(1), 1.5, "hi", []byte{0x2a}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRecord() = %#v, want %#v", got, want)
+	}
+
+	if _, err := parseRecord([]byte{3, 15, 0}); err == nil {
+		t.Error("parseRecord() of a value out of the payload, expected an error")
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	tests := []struct {
+		sql       string
+		want      []string
+		wantRowid int
+	}{
+		{"CREATE TABLE AgLibraryFile (\n    id_local INTEGER PRIMARY KEY,\n    id_global UNIQUE NOT NULL,\n    baseName NOT NULL DEFAULT '',\n    folder INTEGER)", []string{"id_local", "id_global", "basename", "folder"}, 0},
+		{`CREATE TABLE "t" (a TEXT DEFAULT 'x,y', "B" NUMERIC(10, 2), [c], PRIMARY KEY (a))`, []string{"a", "b", "c"}, -1},
+		{"CREATE TABLE t (a TEXT PRIMARY KEY, id INTEGER)", []string{"a", "id"}, -1},
+	}
+	for _, tt := range tests {
+		got, rowid := parseColumns(tt.sql)
+		if !reflect.DeepEqual(got, tt.want) || rowid != tt.wantRowid {
+			t.Errorf("parseColumns(%q) = %v, %d, want %v, %d", tt.sql, got, rowid, tt.want, tt.wantRowid)
+		}
+	}
+}
+
+func TestRows(t *testing.T) {
+	// Enough rows for several leaves, and a name spilling to overflow pages
+	long := strings.Repeat("long name ", 300)
+	var files [][]any
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("IMG_%04d", i)
+		if i == 50 {
+			name = long
+		}
+		files = append(files, []any{nil, name, int64(i % 3)})
+	}
+	// A row written before the last column was added
+	files = append(files, []any{nil, "old"})
+
+	path := filepath.Join(t.TempDir(), "test.lrcat")
+	writeTestDB(t, path, 1024, []testTable{
+		{name: "Folder", sql: "CREATE TABLE Folder (id_local INTEGER PRIMARY KEY, path)", rows: [][]any{{nil, "a/"}, {nil, "b/"}}},
+		{name: "File", sql: "CREATE TABLE File (id_local INTEGER PRIMARY KEY, baseName, folder INTEGER)", rows: files},
+	})
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	if !db.HasTable("file") || db.HasTable("missing") {
+		t.Errorf("HasTable() does not match the schema")
+	}
+
+	var got [][]any
+	err = db.Rows("File", []string{"folder", "baseName", "id_local"}, func(values []any) error {
+		got = append(got, append([]any(nil), values...))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Rows() error = %v", err)
+	}
+	if len(got) != len(files) {
+		t.Fatalf("Rows() returned %d rows, want %d", len(got), len(files))
+	}
+	for i, row := range got {
+		want := []any{nil, files[i][1], int64(i + 1)}
+		if len(files[i]) > 2 {
+			want[0] = files[i][2]
+		}
+		if !reflect.DeepEqual(row, want) {
+			t.Errorf("row %d = %v, want %v", i+1, row, want)
+		}
+	}
+
+	if err := db.Rows("File", []string{"size"}, func([]any) error { return nil }); err == nil {
+		t.Error("Rows() of a missing column, expected an error")
+	}
+	if err := db.Rows("Image", nil, func([]any) error { return nil }); err == nil {
+		t.Error("Rows() of a missing table, expected an error")
+	}
+}
+
+func TestOpenNotDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.lrcat")
+	if err := os.WriteFile(path, []byte(strings.Repeat("not a database ", 10)), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if _, err := Open(path); !errors.Is(err, ErrNotDatabase) {
+		t.Errorf("Open() error = %v, want ErrNotDatabase", err)
+	}
+}
+
+func TestRowsSQLite(t *testing.T) {
+	// Databases written by SQLite itself, when its shell is installed
+	shell, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	script := `PRAGMA page_size = 1024;
+CREATE TABLE File (id_local INTEGER PRIMARY KEY, baseName NOT NULL DEFAULT '', size INTEGER, ratio REAL);
+WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 500)
+INSERT INTO File (baseName, size, ratio) SELECT printf('IMG_%04d', i), i * 100000, i / 4.0 FROM n;
+UPDATE File SET baseName = baseName || hex(zeroblob(1000)) WHERE id_local = 7;
+ALTER TABLE File ADD COLUMN extension;
+INSERT INTO File (baseName, extension) VALUES ('last', 'JPG');
+DELETE FROM File WHERE id_local = 3;`
+	cmd := exec.Command(shell, path)
+	cmd.Stdin = strings.NewReader(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3 error = %v: %s", err, out)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	var rows [][]any
+	err = db.Rows("File", []string{"id_local", "baseName", "size", "ratio", "extension"}, func(values []any) error {
+		rows = append(rows, append([]any(nil), values...))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Rows() error = %v", err)
+	}
+	if len(rows) != 500 {
+		t.Fatalf("Rows() returned %d rows, want 500", len(rows))
+	}
+	if want := []any{int64(2), "IMG_0002", int64(200000), 0.5, nil}; !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("row 2 = %v, want %v", rows[1], want)
+	}
+	if want := "IMG_0007" + strings.Repeat("00", 1000); rows[5][1] != want {
+		t.Errorf("row 7 name of %d bytes, want %d", len(rows[5][1].(string)), len(want))
+	}
+	if want := []any{int64(501), "last", nil, nil, "JPG"}; !reflect.DeepEqual(rows[499], want) {
+		t.Errorf("last row = %v, want %v", rows[499], want)
+	}
+}
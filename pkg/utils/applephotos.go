@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ProfileApplePhotos is the ingestion profile of Photos.app and iCloud Photos
+// exports: Live Photo videos and AAE sidecars are organized with their photo,
+// and the edited versions of an Edited folder are paired with the originals of
+// the Originals folder next to it
+const ProfileApplePhotos = "apple-photos"
+
+// Folders Photos.app exports unmodified originals and edited versions to
+const (
+	appleOriginalsDir = "Originals"
+	appleEditedDir    = "Edited"
+)
+
+// appleSidecarExt is the extension of the adjustments Photos.app exports next
+// to photos, IMG_1234.AAE for IMG_1234.HEIC
+const appleSidecarExt = ".aae"
+
+// appleOriginalSidecarStem matches the names of the adjustments of edited
+// photos, IMG_O1234.AAE for IMG_1234.HEIC and its edited copy IMG_E1234.HEIC
+var appleOriginalSidecarStem = regexp.MustCompile(`(?i)^(IMG_)O(\d+)$`)
+
+// appleNumberedStem matches the names iOS gives to photos, IMG_1234
+var appleNumberedStem = regexp.MustCompile(`(?i)^(IMG_)(\d+)$`)
+
+// applePhotosCompanion returns the photo a Live Photo video or an AAE sidecar
+// goes with, if it is next to it: IMG_1234.HEIC for IMG_1234.MOV and
+// IMG_1234.AAE, and for IMG_O1234.AAE
+func applePhotosCompanion(path string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != appleSidecarExt && !videoExtensions[ext] {
+		return "", false
+	}
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	stems := []string{stem}
+	if m := appleOriginalSidecarStem.FindStringSubmatch(stem); m != nil && ext == appleSidecarExt {
+		stems = append(stems, m[1]+m[2])
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return "", false
+	}
+	for _, stem := range stems {
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			if entry.IsDir() || videoExtensions[strings.ToLower(ext)] || !isAllowedExtension(ext) {
+				continue
+			}
+			if strings.EqualFold(strings.TrimSuffix(name, ext), stem) {
+				return filepath.Join(filepath.Dir(path), name), true
+			}
+		}
+	}
+	return "", false
+}
+
+// FindExportEdits returns the edited versions of a Photos.app export, in an
+// Edited folder, whose original is in the Originals folder next to it, mapped
+// to their original. The original may be of another format, such as
+// Edited/IMG_1234.JPG for Originals/IMG_1234.HEIC.
+func FindExportEdits(source string) (map[string]string, error) {
+	return findExportEdits(OSFileSystem{}, source)
+}
+
+// findExportEdits is FindExportEdits for a source on fsys
+func findExportEdits(fsys FileSystem, source string) (map[string]string, error) {
+	originals := make(map[string]string) // Lower-case export folder and name without extension to file
+	var edits []string
+
+	err := walkFiles(fsys, source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+		if info.IsDir() || !isAllowedExtension(filepath.Ext(path)) {
+			return nil
+		}
+		switch dir := filepath.Base(filepath.Dir(path)); {
+		case strings.EqualFold(dir, appleEditedDir):
+			edits = append(edits, path)
+		case strings.EqualFold(dir, appleOriginalsDir):
+			originals[exportKey(path)] = path
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	pairs := make(map[string]string)
+	for _, edit := range edits {
+		if original, ok := originals[exportKey(edit)]; ok {
+			pairs[edit] = original
+		}
+	}
+	return pairs, nil
+}
+
+// exportKey returns the key pairing the files of the Originals and Edited
+// folders of an export: the export folder and the name without extension
+func exportKey(path string) string {
+	export := filepath.Dir(filepath.Dir(path))
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.ToLower(filepath.Join(export, name))
+}
+
+// exportEditName returns the name of an edited version of an export, named
+// like its original, once organized next to it: the name iOS gives to edited
+// copies, IMG_E1234.JPG for IMG_1234.JPG, or the -edited suffix of Google
+// Photos for other names
+func exportEditName(name string) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if m := appleNumberedStem.FindStringSubmatch(stem); m != nil {
+		return m[1] + "E" + m[2] + ext
+	}
+	return stem + "-edited" + ext
+}
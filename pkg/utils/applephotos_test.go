@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestApplePhotosCompanion(t *testing.T) {
+	source := t.TempDir()
+	createCullSource(t, source, []string{
+		"IMG_1234.HEIC",
+		"IMG_1234.MOV",
+		"IMG_1234.AAE",
+		"IMG_O1234.AAE",
+		"IMG_E1234.JPG",
+		"IMG_E1234.MOV",
+		"IMG_1235.MOV", // Plain video, no photo
+		"IMG_1236.AAE", // Photo deleted
+	})
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"IMG_1234.MOV", "IMG_1234.HEIC", true},
+		{"IMG_1234.AAE", "IMG_1234.HEIC", true},
+		{"IMG_O1234.AAE", "IMG_1234.HEIC", true},
+		{"IMG_E1234.MOV", "IMG_E1234.JPG", true},
+		{"IMG_1235.MOV", "", false},
+		{"IMG_1236.AAE", "", false},
+		{"IMG_1234.HEIC", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := applePhotosCompanion(filepath.Join(source, tt.name))
+		if ok != tt.wantOK || (ok && got != filepath.Join(source, tt.want)) {
+			t.Errorf("applePhotosCompanion(%s) = %s, %v, want %s, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFindExportEdits(t *testing.T) {
+	source := t.TempDir()
+	createCullSource(t, source, []string{
+		"Trip/Originals/IMG_1234.HEIC",
+		"Trip/Edited/IMG_1234.JPG", // Edited version in another format
+		"Trip/Edited/IMG_1235.JPG", // Original not exported
+		"Home/Originals/IMG_1235.HEIC",
+		"Home/Edited/Beach.jpg",
+		"Home/Originals/Beach.jpg",
+		"Edited/IMG_1236.JPG", // No Originals folder
+	})
+
+	pairs, err := FindExportEdits(source)
+	if err != nil {
+		t.Fatalf("FindExportEdits() error = %v", err)
+	}
+	path := func(name string) string { return filepath.Join(source, filepath.FromSlash(name)) }
+	want := map[string]string{
+		path("Trip/Edited/IMG_1234.JPG"): path("Trip/Originals/IMG_1234.HEIC"),
+		path("Home/Edited/Beach.jpg"):    path("Home/Originals/Beach.jpg"),
+	}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("FindExportEdits() = %v, want %v", pairs, want)
+	}
+}
+
+func TestExportEditName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"IMG_1234.JPG", "IMG_E1234.JPG"},
+		{"img_0001.heic", "img_E0001.heic"},
+		{"Beach.jpg", "Beach-edited.jpg"},
+		{"IMG_1234", "IMG_E1234"},
+	}
+	for _, tt := range tests {
+		if got := exportEditName(tt.name); got != tt.want {
+			t.Errorf("exportEditName(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProcessMediaFilesApplePhotos(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    []string // Imported files, relative to the destination
+	}{
+		{profile: "", want: []string{
+			filepath.Join("2024", "06-11", "IMG_1234.JPG"),
+			filepath.Join("2024", "08-02", "IMG_1234.JPG"),
+		}},
+		{profile: ProfileApplePhotos, want: []string{
+			filepath.Join("2024", "06-11", "IMG_1234.AAE"),
+			filepath.Join("2024", "06-11", "IMG_1234.JPG"),
+			filepath.Join("2024", "06-11", "IMG_1234.MOV"),
+			filepath.Join("2024", "06-11", "IMG_E1234.JPG"),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run("profile "+tt.profile, func(t *testing.T) {
+			// The edited version carries the date it was exported on
+			source := t.TempDir()
+			files := map[string][]byte{
+				"Originals/IMG_1234.JPG": createSerialJPEG("2024:06:11 15:30:10", "1"),
+				"Originals/IMG_1234.MOV": []byte("video"),
+				"Originals/IMG_1234.AAE": []byte("<plist/>"),
+				"Edited/IMG_1234.JPG":    createSerialJPEG("2024:08:02 09:00:00", "1"),
+			}
+			for name, data := range files {
+				path := filepath.Join(source, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+					t.Fatalf("Failed to create directory: %v", err)
+				}
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatalf("Failed to create test file: %v", err)
+				}
+			}
+
+			// The profile keeps both versions, as -phone-edits keep
+			dest := t.TempDir()
+			params := &models.Params{Source: source, Destination: dest, Compression: -1, Profile: tt.profile}
+			if tt.profile != "" {
+				params.PhoneEdits = PhoneEditsKeep
+			}
+			plan, err := PlanMediaFiles(params)
+			if err != nil {
+				t.Fatalf("PlanMediaFiles() error = %v", err)
+			}
+			if len(plan) != len(tt.want) {
+				t.Errorf("planned %d files, want %d: %+v", len(plan), len(tt.want), plan)
+			}
+
+			if _, err := ProcessMediaFiles(params); err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if got := organizedFiles(t, dest); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Organized files = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
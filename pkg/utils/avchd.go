@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// avchdExtensions are the MPEG transport streams recorded by AVCHD camcorders
+var avchdExtensions = map[string]bool{
+	".mts":  true,
+	".m2ts": true,
+}
+
+// avchdScanSize is how much of a stream is searched for its recording date,
+// repeated with every key frame from the start of the stream
+const avchdScanSize = 4 << 20
+
+// mdpmMarker starts the MDPM (Modified Digital Video Pack Metadata) of AVCHD
+// camcorders: the GUID of their H.264 user data, then MDPM
+var mdpmMarker = append([]byte{
+	0x17, 0xee, 0x8c, 0x60, 0xf8, 0x4d, 0x11, 0xd9,
+	0x8c, 0xd6, 0x08, 0x00, 0x20, 0x0c, 0x9a, 0x66,
+}, "MDPM"...)
+
+// MDPM entries holding the recording date: time zone, century, year and
+// month, then day, hour, minute and second
+const (
+	mdpmTagDate  = 0x18
+	mdpmTagClock = 0x19
+)
+
+// avchdCreationTime returns the recording date of an AVCHD stream, the local
+// time of the camcorder written in the MDPM of its video
+func avchdCreationTime(r io.ReadSeeker) (time.Time, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return time.Time{}, err
+	}
+	data := make([]byte, avchdScanSize)
+	n, err := io.ReadFull(r, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return time.Time{}, err
+	}
+
+	streams := transportStreams(data[:n])
+	pids := make([]int, 0, len(streams))
+	for pid := range streams {
+		pids = append(pids, int(pid))
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		if date, ok := mdpmDate(unescapeNAL(streams[uint16(pid)])); ok {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w in AVCHD stream", ErrNoDate)
+}
+
+// transportStreams returns the payloads of the packets of an MPEG transport
+// stream by PID. AVCHD streams prefix their 188-byte packets with a 4-byte
+// timecode.
+func transportStreams(data []byte) map[uint16][]byte {
+	var packetSize, start int
+	switch {
+	case len(data) > 196 && data[4] == 0x47 && data[196] == 0x47:
+		packetSize, start = 192, 4
+	case len(data) > 188 && data[0] == 0x47 && data[188] == 0x47:
+		packetSize, start = 188, 0
+	default:
+		return nil
+	}
+
+	streams := make(map[uint16][]byte)
+	for offset := 0; offset+packetSize <= len(data); offset += packetSize {
+		packet := data[offset+start : offset+packetSize]
+		if packet[0] != 0x47 {
+			continue // Lost synchronization
+		}
+		pid := uint16(packet[1]&0x1f)<<8 | uint16(packet[2])
+
+		payload := packet[4:]
+		switch packet[3] >> 4 & 0x3 {
+		case 1: // Payload only
+		case 3: // Adaptation field, then payload
+			length := int(packet[4])
+			if 5+length > len(packet) {
+				continue
+			}
+			payload = packet[5+length:]
+		default:
+			continue
+		}
+		streams[pid] = append(streams[pid], payload...)
+	}
+	return streams
+}
+
+// unescapeNAL removes the emulation prevention bytes of H.264 data, the 3
+// inserted after two zero bytes
+func unescapeNAL(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+// mdpmDate returns the first valid recording date of the MDPM of a video
+// stream. The MDPM holds a count of entries of a tag and 4 bytes.
+func mdpmDate(stream []byte) (time.Time, bool) {
+	for {
+		i := bytes.Index(stream, mdpmMarker)
+		if i < 0 || i+len(mdpmMarker) >= len(stream) {
+			return time.Time{}, false
+		}
+		stream = stream[i+len(mdpmMarker):]
+
+		count, entries := int(stream[0]), stream[1:]
+		var date, clock []byte
+		for j := 0; j < count && len(entries) >= 5; j++ {
+			switch entries[0] {
+			case mdpmTagDate:
+				date = entries[1:5]
+			case mdpmTagClock:
+				clock = entries[1:5]
+			}
+			entries = entries[5:]
+		}
+		if date != nil && clock != nil {
+			if t, ok := mdpmTime(date, clock); ok {
+				return t, true
+			}
+		}
+	}
+}
+
+// mdpmTime decodes the BCD values of the date and clock entries of an MDPM,
+// ignoring the time zone so the date is the wall clock time like EXIF dates
+func mdpmTime(date, clock []byte) (time.Time, bool) {
+	var values [7]int
+	for i, b := range append(append([]byte{}, date[1:]...), clock...) {
+		hi, lo := int(b>>4), int(b&0x0f)
+		if hi > 9 || lo > 9 {
+			return time.Time{}, false
+		}
+		values[i] = hi*10 + lo
+	}
+
+	year := values[0]*100 + values[1]
+	month, day, hour, minute, second := values[2], values[3], values[4], values[5], values[6]
+	if year < 1990 || month < 1 || month > 12 || day < 1 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC)
+	if t.Day() != day {
+		return time.Time{}, false // Such as February 30
+	}
+	return t, true
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createAVCHD returns a minimal AVCHD stream of 192-byte packets whose video,
+// split across packets after another stream, carries an MDPM with the
+// recording date of a camcorder. Its minute and second are zero so the
+// H.264 data holds an emulation prevention byte.
+func createAVCHD(date time.Time) []byte {
+	bcd := func(v int) byte { return byte(v/10<<4 | v%10) }
+	mdpm := append([]byte{0x00, 0x00, 0x01, 0x06, 0x05}, mdpmMarker...)
+	mdpm = append(mdpm, 3,
+		0x13, 0x00, 0x00, 0x00, 0x00, // Unrelated entry
+		mdpmTagDate, 0x09, bcd(date.Year()/100), bcd(date.Year()%100), bcd(int(date.Month())),
+		mdpmTagClock, bcd(date.Day()), bcd(date.Hour()), bcd(date.Minute()), bcd(date.Second()),
+	)
+	mdpm = bytes.ReplaceAll(mdpm, []byte{0x00, 0x00, 0x00}, []byte{0x00, 0x00, 0x03, 0x00})
+
+	packet := func(pid uint16, adaptation int, payload []byte) []byte {
+		p := []byte{0x00, 0x00, 0x00, 0x00, 0x47, byte(pid >> 8), byte(pid), 0x10}
+		if adaptation > 0 {
+			p[7] = 0x30
+			p = append(p, byte(adaptation-1))
+			p = append(p, make([]byte, adaptation-1)...)
+		}
+		p = append(p, payload...)
+		return append(p, bytes.Repeat([]byte{0xff}, 192-len(p))...)
+	}
+	var stream []byte
+	stream = append(stream, packet(0x0000, 0, []byte{0x00, 0x00, 0x01})...)
+	stream = append(stream, packet(0x1011, 184-20, mdpm[:20])...)
+	stream = append(stream, packet(0x1100, 0, []byte("audio"))...)
+	stream = append(stream, packet(0x1011, 184-len(mdpm[20:]), mdpm[20:])...)
+	return stream
+}
+
+func TestAVCHDCreationTime(t *testing.T) {
+	date := time.Date(2011, time.July, 14, 10, 0, 0, 0, time.UTC)
+	// March 2 turned into February 30
+	bad := createAVCHD(time.Date(2011, time.March, 2, 10, 0, 0, 0, time.UTC))
+	bad = bytes.Replace(bad, []byte{0x03, mdpmTagClock, 0x02}, []byte{0x02, mdpmTagClock, 0x30}, 1)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    time.Time
+		wantErr bool
+	}{
+		{"camcorder stream", createAVCHD(date), date, false},
+		{"invalid date", bad, time.Time{}, true},
+		{"no metadata", bytes.Repeat([]byte{0x00, 0x00, 0x00, 0x00, 0x47, 0x10, 0x11, 0x10}, 100), time.Time{}, true},
+		{"not a stream", []byte("not a transport stream"), time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetImageDateTime(tt.data, ".MTS")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetImageDateTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("GetImageDateTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeNAL(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want []byte
+	}{
+		{[]byte{0x00, 0x00, 0x03, 0x01}, []byte{0x00, 0x00, 0x01}},
+		{[]byte{0x00, 0x00, 0x03, 0x00, 0x00, 0x03}, []byte{0x00, 0x00, 0x00, 0x00}},
+		{[]byte{0x00, 0x03, 0x00}, []byte{0x00, 0x03, 0x00}},
+	}
+	for _, tt := range tests {
+		if got := unescapeNAL(tt.data); !bytes.Equal(got, tt.want) {
+			t.Errorf("unescapeNAL(%x) = %x, want %x", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestProcessMediaFilesAVCHDAndMPO(t *testing.T) {
+	source := t.TempDir()
+	destination := t.TempDir()
+	// An MPO file holds the JPEG images of both eyes
+	mpo := append(createSerialJPEG("2012:03:04 05:06:07", "1"), createSerialJPEG("2012:03:04 05:06:07", "1")...)
+	files := map[string][]byte{
+		"DSCF0001.MPO":            mpo,
+		"PRIVATE/AVCHD/00001.MTS": createAVCHD(time.Date(2011, time.July, 14, 10, 0, 0, 0, time.UTC)),
+	}
+	for name, data := range files {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test folder: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	params := &models.Params{
+		Source:      source,
+		Destination: destination,
+		Compression: 50,
+	}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 2 {
+		t.Errorf("Expected 2 copied files, got %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(destination, "2011", "07-14", "00001.MTS")); err != nil {
+		t.Errorf("Expected imported stream: %v", err)
+	}
+	// MPO files are copied as they are, recompression would keep one image
+	got, err := os.ReadFile(filepath.Join(destination, "2012", "03-04", "DSCF0001.MPO"))
+	if err != nil {
+		t.Fatalf("Expected imported MPO file: %v", err)
+	}
+	if !bytes.Equal(got, mpo) {
+		t.Errorf("MPO file was modified on import")
+	}
+}
@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/matdmb/organize-media/pkg/models"
@@ -63,3 +66,45 @@ func copyFile(src, dst string) error {
 	}
 	return os.WriteFile(dst, data, 0644)
 }
+
+// Reading a batch of 24MB RAW files, as processFile does for every file.
+// Compare allocations and garbage collections, reported per 1000 files.
+const benchmarkFileSize = 24 << 20
+
+// reportGC reports the garbage collections run since before, per 1000 operations
+func reportGC(b *testing.B, before runtime.MemStats) {
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)*1000/float64(b.N), "gc/1000op")
+	b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+}
+
+func BenchmarkReadAll(b *testing.B) {
+	data := make([]byte, benchmarkFileSize)
+	b.SetBytes(benchmarkFileSize)
+	b.ReportAllocs()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	defer reportGC(b, before)
+	for i := 0; i < b.N; i++ {
+		if _, err := io.ReadAll(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadAllBuffer(b *testing.B) {
+	data := make([]byte, benchmarkFileSize)
+	b.SetBytes(benchmarkFileSize)
+	b.ReportAllocs()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	defer reportGC(b, before)
+	for i := 0; i < b.N; i++ {
+		buf, err := readAllBuffer(bytes.NewReader(data), benchmarkFileSize)
+		if err != nil {
+			b.Fatal(err)
+		}
+		putBuffer(buf)
+	}
+}
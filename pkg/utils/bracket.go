@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Layouts of bracketed sequences at the destination
+const (
+	BracketsFolder = "folder" // Each sequence in its own subfolder of the day folder
+	BracketsStem   = "stem"   // Frames renamed after the first frame of their sequence
+)
+
+// BracketDirPrefix starts the name of the subfolders holding bracketed sequences
+const BracketDirPrefix = "bracket_"
+
+// bracketMaxGap is the longest time between two frames of the same sequence
+const bracketMaxGap = 2 * time.Second
+
+// FindBracketSequences returns the bracketed sequences of the source, such as
+// exposure brackets for HDR or focus brackets for stacking. A sequence is made
+// of consecutive frames of one camera flagged as auto bracket in their EXIF
+// exposure mode and shot at most two seconds apart. Each sequence lists its
+// frames in shooting order, each frame listing its companion files.
+func FindBracketSequences(source string, cache *MetadataCache) ([][][]string, error) {
+	shots, err := readShots(OSFileSystem{}, source, cache, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var sequences [][][]string
+	for _, dirShots := range shots {
+		sequences = append(sequences, bracketSequences(dirShots)...)
+	}
+	sort.Slice(sequences, func(i, j int) bool { return sequences[i][0][0] < sequences[j][0][0] })
+	return sequences, nil
+}
+
+// bracketSequences groups the ordered shots of a directory into bracketed sequences
+func bracketSequences(shots []*shot) [][][]string {
+	var sequences [][][]string
+	var current [][]string
+	var previous *shot
+	flush := func() {
+		if len(current) > 1 {
+			sequences = append(sequences, current)
+		}
+		current, previous = nil, nil
+	}
+
+	for _, s := range shots {
+		if !s.known || s.meta.ExposureMode != ExposureAutoBracket {
+			flush()
+			continue
+		}
+		if previous != nil && (s.meta.Camera() != previous.meta.Camera() || s.date.Sub(previous.date) > bracketMaxGap) {
+			flush()
+		}
+		current = append(current, s.files)
+		previous = s
+	}
+	flush()
+	return sequences
+}
+
+// addBracketNames records the destination names, relative to the day folder,
+// of the frames of bracketed sequences in the given layout
+func addBracketNames(names map[string]string, sequences [][][]string, layout string) {
+	for _, sequence := range sequences {
+		first := filepath.Base(sequence[0][0])
+		first = strings.TrimSuffix(first, filepath.Ext(first))
+		for i, frame := range sequence {
+			for _, path := range frame {
+				name := filepath.Base(path)
+				if layout == BracketsStem {
+					name = fmt.Sprintf("%s-%d%s", first, i+1, filepath.Ext(name))
+				} else {
+					name = filepath.Join(BracketDirPrefix+first, name)
+				}
+				names[path] = name
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createBracketFrame writes a JPEG taken at date with the given exposure mode
+func createBracketFrame(t *testing.T, path, model, date string, mode uint16) {
+	t.Helper()
+	data := wrapJPEG(buildTIFF(
+		[]testTag{asciiTag(TagModel, model), asciiTag(TagDateTime, date)},
+		[]testTag{exposureModeTag(mode)},
+	))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+}
+
+// createBracketSource creates a source with a 3-frame bracket shot as RAW+JPEG,
+// a 2-frame bracket from another camera and single frames
+func createBracketSource(t *testing.T) string {
+	t.Helper()
+	source := t.TempDir()
+	frames := []struct {
+		name  string
+		model string
+		date  string
+		mode  uint16
+	}{
+		{"DSC00009.JPG", "A7", "2024:06:11 15:29:50", 0},
+		{"DSC00010.JPG", "A7", "2024:06:11 15:30:10", ExposureAutoBracket},
+		{"DSC00010.ARW", "A7", "2024:06:11 15:30:10", ExposureAutoBracket},
+		{"DSC00011.JPG", "A7", "2024:06:11 15:30:10", ExposureAutoBracket},
+		{"DSC00012.JPG", "A7", "2024:06:11 15:30:11", ExposureAutoBracket},
+		{"DSC00013.JPG", "A7", "2024:06:11 15:31:00", ExposureAutoBracket}, // Too late for the sequence
+		{"IMG_0001.JPG", "R5", "2024:06:11 15:30:10", ExposureAutoBracket},
+		{"IMG_0002.JPG", "R5", "2024:06:11 15:30:12", ExposureAutoBracket},
+	}
+	for _, f := range frames {
+		createBracketFrame(t, filepath.Join(source, f.name), f.model, f.date, f.mode)
+	}
+	return source
+}
+
+func TestFindBracketSequences(t *testing.T) {
+	source := createBracketSource(t)
+
+	sequences, err := FindBracketSequences(source, nil)
+	if err != nil {
+		t.Fatalf("FindBracketSequences() error = %v", err)
+	}
+	if len(sequences) != 2 {
+		t.Fatalf("Expected 2 sequences, got %v", sequences)
+	}
+
+	want := [][]string{{"DSC00010.ARW", "DSC00010.JPG"}, {"DSC00011.JPG"}, {"DSC00012.JPG"}}
+	if len(sequences[0]) != len(want) {
+		t.Fatalf("Expected %d frames in the first sequence, got %v", len(want), sequences[0])
+	}
+	for i, frame := range want {
+		for j, name := range frame {
+			if filepath.Base(sequences[0][i][j]) != name {
+				t.Errorf("Frame %d file %d = %s, want %s", i, j, sequences[0][i][j], name)
+			}
+		}
+	}
+	if len(sequences[1]) != 2 || filepath.Base(sequences[1][0][0]) != "IMG_0001.JPG" {
+		t.Errorf("Unexpected second sequence %v", sequences[1])
+	}
+}
+
+func TestProcessMediaFilesBrackets(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   []string
+	}{
+		{BracketsFolder, []string{
+			"DSC00009.JPG",
+			filepath.Join("bracket_DSC00010", "DSC00010.ARW"),
+			filepath.Join("bracket_DSC00010", "DSC00010.JPG"),
+			filepath.Join("bracket_DSC00010", "DSC00011.JPG"),
+			filepath.Join("bracket_DSC00010", "DSC00012.JPG"),
+			"DSC00013.JPG",
+			filepath.Join("bracket_IMG_0001", "IMG_0001.JPG"),
+		}},
+		{BracketsStem, []string{
+			"DSC00009.JPG",
+			"DSC00010-1.ARW",
+			"DSC00010-1.JPG",
+			"DSC00010-2.JPG",
+			"DSC00010-3.JPG",
+			"DSC00013.JPG",
+			"IMG_0001-2.JPG",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			source := createBracketSource(t)
+			destination := t.TempDir()
+			params := &models.Params{Source: source, Destination: destination, Compression: -1, Brackets: tt.layout}
+
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if summary.Copied != 8 {
+				t.Errorf("Expected 8 copied files, got %+v", summary)
+			}
+
+			day := filepath.Join(destination, "2024", "06-11")
+			for _, name := range tt.want {
+				if _, err := os.Stat(filepath.Join(day, name)); err != nil {
+					t.Errorf("Expected %s in the day folder: %v", name, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Capacities of pooled read buffers. Files are read into the smallest class
+// holding them, so a batch of 25MB RAW files reuses the same few buffers
+// instead of allocating one per file for the garbage collector to reclaim.
+var bufferClasses = []int{
+	256 << 10,
+	1 << 20,
+	4 << 20,
+	16 << 20,
+	64 << 20,
+	256 << 20,
+}
+
+// One pool per class, of *[]byte so putting a buffer back does not allocate
+var bufferPools = make([]sync.Pool, len(bufferClasses))
+
+// bufferClass returns the index of the smallest class of at least size bytes,
+// or -1 when size is above the largest class
+func bufferClass(size int) int {
+	for i, class := range bufferClasses {
+		if size <= class {
+			return i
+		}
+	}
+	return -1
+}
+
+// getBuffer returns an empty buffer with a capacity of at least size bytes,
+// pooled unless size is above the largest class
+func getBuffer(size int) []byte {
+	i := bufferClass(size)
+	if i < 0 {
+		return make([]byte, 0, size)
+	}
+	if buf, ok := bufferPools[i].Get().(*[]byte); ok {
+		return (*buf)[:0]
+	}
+	return make([]byte, 0, bufferClasses[i])
+}
+
+// putBuffer returns a buffer from getBuffer to its pool. The buffer must not
+// be used afterwards. Buffers whose capacity is not a class, such as buffers
+// grown by append, are left to the garbage collector.
+func putBuffer(buf []byte) {
+	i := bufferClass(cap(buf))
+	if i < 0 || cap(buf) != bufferClasses[i] {
+		return
+	}
+	buf = buf[:0]
+	bufferPools[i].Put(&buf)
+}
+
+// readAllBuffer reads r until EOF like io.ReadAll, into a pooled buffer sized
+// for a file of size bytes. The returned data, also returned on read errors,
+// must be released with putBuffer once no longer used.
+func readAllBuffer(r io.Reader, size int64) ([]byte, error) {
+	// Room to read EOF without growing the buffer of a file read in full
+	buf := getBuffer(int(size) + bytes.MinRead)
+	for {
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return buf, err
+		}
+		if len(buf) == cap(buf) {
+			// The file grew since it was listed
+			buf = append(buf, 0)[:len(buf)]
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadAllBuffer(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 40_000)
+	readErr := errors.New("card removed")
+
+	tests := []struct {
+		name    string
+		reader  io.Reader
+		size    int64 // Size of the file when listed
+		want    []byte
+		wantErr error
+	}{
+		{name: "empty file", reader: bytes.NewReader(nil), size: 0, want: []byte{}},
+		{name: "file read in full", reader: bytes.NewReader(data), size: int64(len(data)), want: data},
+		{name: "small reads", reader: iotest.OneByteReader(bytes.NewReader(data[:5000])), size: 5000, want: data[:5000]},
+		{name: "file grown since listed", reader: bytes.NewReader(data), size: 100, want: data},
+		{name: "file above the largest class", reader: bytes.NewReader(data), size: int64(bufferClasses[len(bufferClasses)-1]) + 1, want: data},
+		{name: "read error keeps the data read", reader: io.MultiReader(bytes.NewReader(data[:1000]), iotest.ErrReader(readErr)), size: int64(len(data)), want: data[:1000], wantErr: readErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readAllBuffer(tt.reader, tt.size)
+			defer putBuffer(got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readAllBuffer() error = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("readAllBuffer() read %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestBufferPool(t *testing.T) {
+	tests := []struct {
+		size    int
+		wantCap int
+	}{
+		{size: 0, wantCap: bufferClasses[0]},
+		{size: 1 << 20, wantCap: 1 << 20},
+		{size: 1<<20 + 1, wantCap: 4 << 20},
+		{size: 300 << 20, wantCap: 300 << 20}, // Above the largest class, not pooled
+	}
+
+	for _, tt := range tests {
+		buf := getBuffer(tt.size)
+		if len(buf) != 0 || cap(buf) != tt.wantCap {
+			t.Errorf("getBuffer(%d) = len %d cap %d, want len 0 cap %d", tt.size, len(buf), cap(buf), tt.wantCap)
+		}
+		putBuffer(buf)
+	}
+
+	// Returned buffers are emptied before reuse
+	buf := append(getBuffer(10), "stale"...)
+	putBuffer(buf)
+	if again := getBuffer(10); len(again) != 0 {
+		t.Errorf("getBuffer() returned %d stale bytes", len(again))
+	}
+
+	// Buffers grown by append are not pooled, they would be put in the wrong class
+	putBuffer(make([]byte, 0, 3<<20))
+}
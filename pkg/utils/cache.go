@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cacheEntry holds the extracted capture date of a file along with the
+// size and modification time it had when the date was extracted
+type cacheEntry struct {
+	Size    int64     `json:"size"`
+	ModTime int64     `json:"mtime"`
+	Date    time.Time `json:"date"`
+}
+
+// MetadataCache stores extracted capture dates keyed by path, size and mtime,
+// so unchanged files can skip EXIF extraction on subsequent runs
+type MetadataCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// LoadMetadataCache reads the cache file at path. A missing file yields an empty cache.
+func LoadMetadataCache(path string) (*MetadataCache, error) {
+	cache := &MetadataCache{
+		path:    path,
+		entries: make(map[string]cacheEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata cache %s: %w", path, err)
+	}
+
+	return cache, nil
+}
+
+// Get returns the cached date for a file if its size and mtime are unchanged
+func (c *MetadataCache) Get(path string, info os.FileInfo) (time.Time, bool) {
+	if c == nil {
+		return time.Time{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[cacheKey(path)]
+	if !ok || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		return time.Time{}, false
+	}
+	return entry.Date, true
+}
+
+// Put records the extracted date for a file
+func (c *MetadataCache) Put(path string, info os.FileInfo, date time.Time) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[cacheKey(path)] = cacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Date:    date,
+	}
+	c.dirty = true
+}
+
+// Save writes the cache back to disk if it was modified
+func (c *MetadataCache) Save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted save never leaves a truncated cache
+	if err := writeFileAtomic(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// cacheKey normalizes a path so the same file is found regardless of how the source was given
+func cacheKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestMetadataCache(t *testing.T) {
+	tempDir := t.TempDir()
+	cachePath := filepath.Join(tempDir, "cache.json")
+
+	mediaFile := filepath.Join(tempDir, "test.jpg")
+	if err := os.WriteFile(mediaFile, []byte("test data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	info, err := os.Stat(mediaFile)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+
+	date := time.Date(2024, time.June, 11, 15, 30, 10, 0, time.UTC)
+
+	t.Run("missing cache file", func(t *testing.T) {
+		cache, err := LoadMetadataCache(cachePath)
+		if err != nil {
+			t.Fatalf("LoadMetadataCache() unexpected error: %v", err)
+		}
+		if _, ok := cache.Get(mediaFile, info); ok {
+			t.Error("Expected cache miss on empty cache")
+		}
+	})
+
+	t.Run("save and reload", func(t *testing.T) {
+		cache, err := LoadMetadataCache(cachePath)
+		if err != nil {
+			t.Fatalf("LoadMetadataCache() unexpected error: %v", err)
+		}
+		cache.Put(mediaFile, info, date)
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save() unexpected error: %v", err)
+		}
+
+		reloaded, err := LoadMetadataCache(cachePath)
+		if err != nil {
+			t.Fatalf("LoadMetadataCache() unexpected error: %v", err)
+		}
+		got, ok := reloaded.Get(mediaFile, info)
+		if !ok {
+			t.Fatal("Expected cache hit after reload")
+		}
+		if !got.Equal(date) {
+			t.Errorf("Get() = %v, want %v", got, date)
+		}
+	})
+
+	t.Run("modified file invalidates entry", func(t *testing.T) {
+		cache, err := LoadMetadataCache(cachePath)
+		if err != nil {
+			t.Fatalf("LoadMetadataCache() unexpected error: %v", err)
+		}
+
+		if err := os.WriteFile(mediaFile, []byte("modified test data"), 0644); err != nil {
+			t.Fatalf("Failed to modify test file: %v", err)
+		}
+		newInfo, err := os.Stat(mediaFile)
+		if err != nil {
+			t.Fatalf("Failed to stat test file: %v", err)
+		}
+
+		if _, ok := cache.Get(mediaFile, newInfo); ok {
+			t.Error("Expected cache miss after file size changed")
+		}
+	})
+
+	t.Run("corrupted cache file", func(t *testing.T) {
+		badPath := filepath.Join(tempDir, "bad.json")
+		if err := os.WriteFile(badPath, []byte("{not json"), 0644); err != nil {
+			t.Fatalf("Failed to create cache file: %v", err)
+		}
+		if _, err := LoadMetadataCache(badPath); err == nil {
+			t.Error("Expected error for corrupted cache file, got nil")
+		}
+	})
+
+	t.Run("nil cache", func(t *testing.T) {
+		var cache *MetadataCache
+		cache.Put(mediaFile, info, date)
+		if _, ok := cache.Get(mediaFile, info); ok {
+			t.Error("Expected nil cache to always miss")
+		}
+		if err := cache.Save(); err != nil {
+			t.Errorf("Save() on nil cache returned error: %v", err)
+		}
+	})
+}
+
+func TestProcessMediaFilesWithCache(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+
+	if err := os.WriteFile(filepath.Join(sourceDir, "cached.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: destDir,
+		Compression: -1,
+		CacheFile:   cachePath,
+	}
+
+	first, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() first run error: %v", err)
+	}
+	if first.CacheHits != 0 {
+		t.Errorf("Expected 0 cache hits on first run, got %d", first.CacheHits)
+	}
+
+	second, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() second run error: %v", err)
+	}
+	if second.CacheHits != 1 {
+		t.Errorf("Expected 1 cache hit on second run, got %d", second.CacheHits)
+	}
+}
@@ -0,0 +1,468 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// CatalogRecord describes one imported file
+type CatalogRecord struct {
+	ID          string            `json:"id,omitempty"` // Unique identifier of the file, kept when it is moved or renamed
+	Hash        string            `json:"hash"`
+	Source      string            `json:"source"`
+	Destination string            `json:"destination"`
+	Size        int64             `json:"size"`
+	ImportedAt  time.Time         `json:"imported_at"`
+	Tags        []string          `json:"tags,omitempty"`     // Detected kind, such as timelapse or pano
+	Faces       []FaceRegion      `json:"faces,omitempty"`    // Face regions of the XMP metadata
+	Albums      []string          `json:"albums,omitempty"`   // Google Takeout albums of the file
+	RunTags     map[string]string `json:"run_tags,omitempty"` // Tags of the import run, such as client=smith
+	RunID       string            `json:"run_id,omitempty"`   // Import run that recorded the file
+}
+
+// catalogLine is a line of the catalog file: the record of a file, or the
+// record of an import run under "run"
+type catalogLine struct {
+	CatalogRecord
+	Run *RunRecord `json:"run,omitempty"`
+}
+
+// Catalog is an append-only record of imported files, keyed by content hash.
+// It is stored as one JSON record per line so new imports only append to the file.
+// Each record is flushed to the disk once written, and a record cut short by a
+// crash is dropped when the catalog is opened again, so the catalog stays
+// readable whenever a run is interrupted.
+//
+// There is no database: the file is its own write-ahead log, and a synced
+// line stands for a committed transaction of one record. The records of
+// AddRecords are synced together but are not atomic, a crash may keep the
+// first ones. The records never describe files that were not written, and
+// RepairCatalog reconciles the catalog with the destination afterwards.
+type Catalog struct {
+	mu      sync.Mutex
+	file    *os.File
+	records map[string]CatalogRecord
+	copies  []CatalogRecord // Earlier records of content imported again under another destination
+	sizes   map[int64]bool  // Sizes of the recorded files, to hash only files that may be recorded
+	runs    []RunRecord
+}
+
+// OpenCatalog loads the catalog at path, creating it if it doesn't exist
+func OpenCatalog(path string) (*Catalog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create catalog directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog: %w", err)
+	}
+
+	catalog := &Catalog{file: file}
+	if err := catalog.load(file, path, true); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// LoadCatalog loads the catalog at path without ever writing to it, for
+// readers such as the gallery. A record cut short by a crash is ignored
+// instead of being dropped from the file. Records cannot be added to it.
+func LoadCatalog(path string) (*Catalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog: %w", err)
+	}
+	defer file.Close()
+
+	catalog := &Catalog{}
+	if err := catalog.load(file, path, false); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// load reads the records and runs of the catalog file. A record cut short by
+// a crash is dropped from the file when repair is set, ignored otherwise.
+func (c *Catalog) load(file *os.File, path string, repair bool) error {
+	c.records = make(map[string]CatalogRecord)
+	c.sizes = make(map[int64]bool)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var offset int64 // Start of the current line
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		next := offset + int64(len(data)) + 1
+		if len(data) == 0 {
+			offset = next
+			continue
+		}
+		var record catalogLine
+		if err := json.Unmarshal(data, &record); err != nil {
+			// A record cut short by a crash is the last line, without its newline
+			if info, statErr := file.Stat(); statErr == nil && next > info.Size() {
+				if !repair {
+					break
+				}
+				if truncErr := file.Truncate(offset); truncErr == nil {
+					output.Status("WARNING", fmt.Sprintf("Dropped incomplete catalog record at %s:%d", path, line))
+					break
+				}
+			}
+			return fmt.Errorf("invalid catalog record at %s:%d: %w", path, line, err)
+		}
+		if record.Run != nil {
+			c.runs = append(c.runs, *record.Run)
+		} else {
+			c.record(record.CatalogRecord)
+		}
+		offset = next
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read catalog: %w", err)
+	}
+	return nil
+}
+
+// Records returns the records of the files imported, sorted by destination
+func (c *Catalog) Records() []CatalogRecord {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records := make([]CatalogRecord, 0, len(c.records))
+	for _, record := range c.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Destination < records[j].Destination })
+	return records
+}
+
+// AllRecords returns the records of every file imported, including copies
+// of content imported again under another destination, sorted by destination
+func (c *Catalog) AllRecords() []CatalogRecord {
+	records := c.Records()
+	if c == nil {
+		return records
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records = append(records, c.copies...)
+	sort.Slice(records, func(i, j int) bool { return records[i].Destination < records[j].Destination })
+	return records
+}
+
+// Lookup returns the record of a previously imported file with the given hash
+func (c *Catalog) Lookup(hash string) (CatalogRecord, bool) {
+	if c == nil {
+		return CatalogRecord{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	record, ok := c.records[hash]
+	return record, ok
+}
+
+// HasSize reports whether a file of the given size was imported. Files of
+// other sizes cannot match a record, their content need not be hashed.
+func (c *Catalog) HasSize(size int64) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.sizes[size]
+}
+
+// Add appends a record to the catalog
+func (c *Catalog) Add(record CatalogRecord) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.append(catalogLine{CatalogRecord: record}); err != nil {
+		return fmt.Errorf("failed to write catalog record: %w", err)
+	}
+	c.record(record)
+	return nil
+}
+
+// AddRecords appends records to the catalog at once, flushing them to the
+// disk together, for records of files already in the destination
+func (c *Catalog) AddRecords(records []CatalogRecord) error {
+	if c == nil || len(records) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return errors.New("catalog loaded read-only")
+	}
+	var data []byte
+	for _, record := range records {
+		line, err := marshalCatalogLine(catalogLine{CatalogRecord: record})
+		if err != nil {
+			return fmt.Errorf("failed to write catalog records: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if _, err := c.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write catalog records: %w", err)
+	}
+	if err := c.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write catalog records: %w", err)
+	}
+	for _, record := range records {
+		c.record(record)
+	}
+	return nil
+}
+
+// record indexes a record, with the lock held. The record of the same
+// content under another destination is kept as a copy.
+func (c *Catalog) record(record CatalogRecord) {
+	if previous, ok := c.records[record.Hash]; ok && previous.Destination != record.Destination {
+		c.copies = append(c.copies, previous)
+	}
+	c.records[record.Hash] = record
+	c.sizes[record.Size] = true
+}
+
+// append writes a line to the catalog file, with the lock held
+func (c *Catalog) append(line catalogLine) error {
+	if c.file == nil {
+		return errors.New("catalog loaded read-only")
+	}
+	data, err := marshalCatalogLine(line)
+	if err != nil {
+		return err
+	}
+	if _, err := c.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	// The record must survive a crash once the file it describes is written
+	return c.file.Sync()
+}
+
+// marshalCatalogLine encodes a line of the catalog file. Run lines only hold
+// the run, without the empty fields of a file record.
+func marshalCatalogLine(line catalogLine) ([]byte, error) {
+	if line.Run != nil {
+		return json.Marshal(struct {
+			Run *RunRecord `json:"run"`
+		}{line.Run})
+	}
+	return json.Marshal(line.CatalogRecord)
+}
+
+// Len returns the number of files recorded in the catalog
+func (c *Catalog) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.records)
+}
+
+// Close closes the underlying catalog file
+func (c *Catalog) Close() error {
+	if c == nil || c.file == nil {
+		return nil
+	}
+	return c.file.Close()
+}
+
+// CatalogRepair summarizes the reconciliation of a catalog with its destination
+type CatalogRepair struct {
+	Kept    int // Records of files found in the destination
+	Removed int // Records of files missing from the destination
+	Added   int // Destination files missing from the catalog, recorded by the repair
+	Moved   int // Records following their file moved or renamed in the destination
+}
+
+// RepairCatalog reconciles the catalog at path with the destination tree it
+// describes: records of files no longer in the destination are dropped, and
+// destination files missing from the catalog are recorded by content hash.
+// A file moved or renamed in the destination keeps its record and identifier
+// when its provenance holds the identifier or its content is that of the
+// record. The catalog is rewritten atomically, nothing is written with dryRun.
+func RepairCatalog(path, destination, algo string, dryRun bool) (CatalogRepair, error) {
+	var repair CatalogRepair
+
+	catalog, err := OpenCatalog(path)
+	if err != nil {
+		return repair, err
+	}
+	catalog.Close()
+
+	catalogAbs, err := filepath.Abs(path)
+	if err != nil {
+		return repair, err
+	}
+
+	var records, missing []CatalogRecord
+	recorded := make(map[string]bool)
+	// Copies of content imported again are files of their own
+	for _, record := range catalog.AllRecords() {
+		if exists, err := fileExists(OSFileSystem{}, record.Destination); err != nil {
+			return repair, err
+		} else if !exists {
+			missing = append(missing, record)
+			continue
+		}
+		repair.Kept++
+		// Records of catalogs older than identifiers get one
+		if record.ID == "" {
+			record.ID = newFileID(record.ImportedAt)
+		}
+		records = append(records, record)
+		if abs, err := filepath.Abs(record.Destination); err == nil {
+			recorded[abs] = true
+		}
+	}
+
+	err = filepath.Walk(destination, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Hidden folders hold the state of the tool, album links are not files of their own
+		if info.IsDir() {
+			if file != destination && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || strings.HasPrefix(info.Name(), ".") || recorded[abs] || abs == catalogAbs || isProvenanceSidecar(file) {
+			return nil
+		}
+
+		hash, err := hashFile(file, algo)
+		if err != nil {
+			return err
+		}
+		id := recordedFileID(file)
+		for i, record := range missing {
+			if id != "" && record.ID == id || id == "" && record.Hash == hash && record.Size == info.Size() {
+				record.Destination = file
+				if record.ID == "" {
+					record.ID = newFileID(record.ImportedAt)
+				}
+				records = append(records, record)
+				missing = append(missing[:i], missing[i+1:]...)
+				repair.Moved++
+				return nil
+			}
+		}
+		if id == "" {
+			id = newFileID(info.ModTime())
+		}
+
+		repair.Added++
+		records = append(records, CatalogRecord{
+			ID:          id,
+			Hash:        hash,
+			Destination: file,
+			Size:        info.Size(),
+			ImportedAt:  info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return repair, fmt.Errorf("failed to walk destination: %w", err)
+	}
+	repair.Removed = len(missing)
+
+	if dryRun {
+		return repair, nil
+	}
+	return repair, writeCatalog(path, records, catalog.runs)
+}
+
+// writeCatalog replaces the catalog at path with the records of runs and
+// files, in import order. The records are written to a temporary file first
+// so an interrupted write never loses the catalog.
+func writeCatalog(path string, records []CatalogRecord, runs []RunRecord) error {
+	sort.SliceStable(records, func(i, j int) bool {
+		if !records[i].ImportedAt.Equal(records[j].ImportedAt) {
+			return records[i].ImportedAt.Before(records[j].ImportedAt)
+		}
+		return records[i].Destination < records[j].Destination
+	})
+
+	file, err := createTemp(path)
+	if err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	tmpPath := file.Name()
+	lines := make([]catalogLine, 0, len(runs)+len(records))
+	for i := range runs {
+		lines = append(lines, catalogLine{Run: &runs[i]})
+	}
+	for _, record := range records {
+		lines = append(lines, catalogLine{CatalogRecord: record})
+	}
+
+	w := bufio.NewWriter(file)
+	for _, line := range lines {
+		data, err := marshalCatalogLine(line)
+		if err == nil {
+			_, err = w.Write(append(data, '\n'))
+		}
+		if err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to write catalog: %w", err)
+		}
+	}
+	err = w.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	return nil
+}
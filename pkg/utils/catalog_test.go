@@ -0,0 +1,330 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestCatalog(t *testing.T) {
+	catalogPath := filepath.Join(t.TempDir(), "catalog", "catalog.jsonl")
+	hash := HashBuffer([]byte("test data"), HashSHA256)
+
+	t.Run("new catalog", func(t *testing.T) {
+		catalog, err := OpenCatalog(catalogPath)
+		if err != nil {
+			t.Fatalf("OpenCatalog() unexpected error: %v", err)
+		}
+		defer catalog.Close()
+
+		if catalog.Len() != 0 {
+			t.Errorf("Expected empty catalog, got %d records", catalog.Len())
+		}
+
+		if err := catalog.Add(CatalogRecord{
+			Hash:        hash,
+			Source:      "/source/test.jpg",
+			Destination: "/dest/2025/01-11/test.jpg",
+			Size:        9,
+			ImportedAt:  time.Now(),
+		}); err != nil {
+			t.Fatalf("Add() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("reopen catalog", func(t *testing.T) {
+		catalog, err := OpenCatalog(catalogPath)
+		if err != nil {
+			t.Fatalf("OpenCatalog() unexpected error: %v", err)
+		}
+		defer catalog.Close()
+
+		record, ok := catalog.Lookup(hash)
+		if !ok {
+			t.Fatal("Expected record to be found after reopening")
+		}
+		if record.Destination != "/dest/2025/01-11/test.jpg" {
+			t.Errorf("Lookup() destination = %s, want /dest/2025/01-11/test.jpg", record.Destination)
+		}
+
+		if _, ok := catalog.Lookup(HashBuffer([]byte("other data"), HashSHA256)); ok {
+			t.Error("Expected unknown hash not to be found")
+		}
+
+		// Only files the size of a record need to be hashed
+		if !catalog.HasSize(9) || catalog.HasSize(10) {
+			t.Errorf("HasSize() = %v for 9 bytes and %v for 10 bytes, want true and false", catalog.HasSize(9), catalog.HasSize(10))
+		}
+	})
+
+	t.Run("invalid record", func(t *testing.T) {
+		badPath := filepath.Join(t.TempDir(), "bad.jsonl")
+		if err := os.WriteFile(badPath, []byte("{not json\n"), 0644); err != nil {
+			t.Fatalf("Failed to create catalog file: %v", err)
+		}
+		if _, err := OpenCatalog(badPath); err == nil {
+			t.Error("Expected error for invalid catalog record, got nil")
+		}
+	})
+
+	t.Run("record cut short by a crash", func(t *testing.T) {
+		crashPath := filepath.Join(t.TempDir(), "crash.jsonl")
+		complete := `{"hash":"aa","source":"/source/a.jpg","destination":"/dest/a.jpg","size":1,"imported_at":"2025-01-11T17:10:39Z"}` + "\n"
+		if err := os.WriteFile(crashPath, []byte(complete+`{"hash":"bb","sou`), 0644); err != nil {
+			t.Fatalf("Failed to create catalog file: %v", err)
+		}
+
+		catalog, err := OpenCatalog(crashPath)
+		if err != nil {
+			t.Fatalf("OpenCatalog() unexpected error: %v", err)
+		}
+		if catalog.Len() != 1 {
+			t.Errorf("Expected the complete record only, got %d records", catalog.Len())
+		}
+		// New records start on a line of their own
+		if err := catalog.Add(CatalogRecord{Hash: "cc", Destination: "/dest/c.jpg"}); err != nil {
+			t.Fatalf("Add() unexpected error: %v", err)
+		}
+		catalog.Close()
+
+		reopened, err := OpenCatalog(crashPath)
+		if err != nil {
+			t.Fatalf("OpenCatalog() after recovery error: %v", err)
+		}
+		defer reopened.Close()
+		if reopened.Len() != 2 {
+			t.Errorf("Expected 2 records after recovery, got %d", reopened.Len())
+		}
+	})
+
+	t.Run("loaded read-only", func(t *testing.T) {
+		crashPath := filepath.Join(t.TempDir(), "crash.jsonl")
+		data := []byte(`{"hash":"aa","source":"/source/a.jpg","destination":"/dest/a.jpg","size":1,"imported_at":"2025-01-11T17:10:39Z"}` + "\n" + `{"hash":"bb","sou`)
+		if err := os.WriteFile(crashPath, data, 0644); err != nil {
+			t.Fatalf("Failed to create catalog file: %v", err)
+		}
+
+		catalog, err := LoadCatalog(crashPath)
+		if err != nil {
+			t.Fatalf("LoadCatalog() unexpected error: %v", err)
+		}
+		if records := catalog.Records(); len(records) != 1 || records[0].Destination != "/dest/a.jpg" {
+			t.Errorf("Records() = %+v, want the complete record only", records)
+		}
+		if err := catalog.Add(CatalogRecord{Hash: "cc"}); err == nil {
+			t.Error("Expected error adding a record to a catalog loaded read-only, got nil")
+		}
+		// The record cut short is left for the next import to drop
+		if got, _ := os.ReadFile(crashPath); string(got) != string(data) {
+			t.Errorf("LoadCatalog() changed the catalog file to %q", got)
+		}
+		if _, err := LoadCatalog(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+			t.Error("Expected error for a missing catalog, got nil")
+		}
+	})
+
+	t.Run("invalid record before the last line", func(t *testing.T) {
+		badPath := filepath.Join(t.TempDir(), "bad.jsonl")
+		if err := os.WriteFile(badPath, []byte("{not json\n{\"hash\":\"aa\"}"), 0644); err != nil {
+			t.Fatalf("Failed to create catalog file: %v", err)
+		}
+		if _, err := OpenCatalog(badPath); err == nil {
+			t.Error("Expected error for invalid catalog record, got nil")
+		}
+	})
+}
+
+func TestRepairCatalog(t *testing.T) {
+	dest := t.TempDir()
+	kept := filepath.Join(dest, "2025", "01-11", "kept.jpg")
+	untracked := filepath.Join(dest, "2025", "01-11", "untracked.jpg")
+	for _, path := range []string{kept, untracked, filepath.Join(dest, ".organize-media", "sources.json")} {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// The catalog lives in the destination, it is not recorded in itself
+	catalogPath := filepath.Join(dest, "catalog.jsonl")
+	catalog, err := OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error: %v", err)
+	}
+	for _, record := range []CatalogRecord{
+		{Hash: "kept", Destination: kept},
+		{Hash: "deleted", Destination: filepath.Join(dest, "2025", "01-11", "deleted.jpg")},
+	} {
+		if err := catalog.Add(record); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+	catalog.Close()
+
+	want := CatalogRepair{Kept: 1, Removed: 1, Added: 1}
+	repair, err := RepairCatalog(catalogPath, dest, HashSHA256, true)
+	if err != nil || repair != want {
+		t.Fatalf("RepairCatalog() dry run = %+v, %v, want %+v", repair, err, want)
+	}
+	if catalog, err = OpenCatalog(catalogPath); err != nil || catalog.Len() != 2 {
+		t.Fatalf("Expected the dry run to leave the catalog untouched, got %d records, %v", catalog.Len(), err)
+	}
+	catalog.Close()
+
+	repair, err = RepairCatalog(catalogPath, dest, HashSHA256, false)
+	if err != nil || repair != want {
+		t.Fatalf("RepairCatalog() = %+v, %v, want %+v", repair, err, want)
+	}
+
+	catalog, err = OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() after repair error: %v", err)
+	}
+	defer catalog.Close()
+	if catalog.Len() != 2 {
+		t.Errorf("Expected 2 records after repair, got %d", catalog.Len())
+	}
+	if _, ok := catalog.Lookup("deleted"); ok {
+		t.Error("Expected the record of the deleted file to be removed")
+	}
+	if record, ok := catalog.Lookup(HashBuffer([]byte("untracked.jpg"), HashSHA256)); !ok || record.Destination != untracked {
+		t.Errorf("Expected the untracked file to be recorded, got %+v", record)
+	}
+
+	// A repaired catalog is consistent
+	if repair, err := RepairCatalog(catalogPath, dest, HashSHA256, true); err != nil || repair != (CatalogRepair{Kept: 2}) {
+		t.Errorf("RepairCatalog() after repair = %+v, %v", repair, err)
+	}
+}
+
+func TestRepairCatalogCopies(t *testing.T) {
+	dest := t.TempDir()
+	first := filepath.Join(dest, "2025", "01-11", "IMG_0001.jpg")
+	second := filepath.Join(dest, "2025", "01-12", "IMG_0001.jpg")
+	untracked := filepath.Join(dest, "2025", "01-13", "IMG_0001.jpg")
+	for _, path := range []string{first, second, untracked} {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("same content"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// Two files of identical content, both recorded
+	catalogPath := filepath.Join(t.TempDir(), "catalog.jsonl")
+	catalog, err := OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error: %v", err)
+	}
+	hash := HashBuffer([]byte("same content"), HashSHA256)
+	for _, path := range []string{first, second} {
+		if err := catalog.Add(CatalogRecord{Hash: hash, Destination: path, Size: 12}); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+	catalog.Close()
+
+	want := CatalogRepair{Kept: 2, Added: 1}
+	if repair, err := RepairCatalog(catalogPath, dest, HashSHA256, false); err != nil || repair != want {
+		t.Fatalf("RepairCatalog() = %+v, %v, want %+v", repair, err, want)
+	}
+
+	catalog, err = OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() after repair error: %v", err)
+	}
+	var got []string
+	for _, record := range catalog.AllRecords() {
+		got = append(got, record.Destination)
+	}
+	catalog.Close()
+	if want := []string{first, second, untracked}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Records after repair = %v, want %v", got, want)
+	}
+
+	// A repaired catalog is consistent
+	if repair, err := RepairCatalog(catalogPath, dest, HashSHA256, true); err != nil || repair != (CatalogRepair{Kept: 3}) {
+		t.Errorf("RepairCatalog() after repair = %+v, %v", repair, err)
+	}
+}
+
+func TestProcessMediaFilesIncremental(t *testing.T) {
+	sourceDir := t.TempDir()
+	catalogPath := filepath.Join(t.TempDir(), "catalog.jsonl")
+
+	if err := os.WriteFile(filepath.Join(sourceDir, "IMG_0001.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: t.TempDir(),
+		Compression: -1,
+		CatalogFile: catalogPath,
+		Incremental: true,
+	}
+
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() first run error: %v", err)
+	}
+	if summary.Processed != 1 {
+		t.Fatalf("Expected 1 processed file on first run, got %d", summary.Processed)
+	}
+
+	// Same content under another name, imported into a fresh destination
+	if err := os.Rename(filepath.Join(sourceDir, "IMG_0001.jpg"), filepath.Join(sourceDir, "renamed.jpg")); err != nil {
+		t.Fatalf("Failed to rename source file: %v", err)
+	}
+	params.Destination = t.TempDir()
+
+	summary, err = ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() second run error: %v", err)
+	}
+	if summary.Processed != 0 || summary.Skipped != 1 {
+		t.Errorf("Expected already imported file to be skipped, got %+v", summary)
+	}
+}
+
+func TestProcessMediaFilesRunTags(t *testing.T) {
+	sourceDir := t.TempDir()
+	catalogPath := filepath.Join(t.TempDir(), "catalog.jsonl")
+
+	data := createFakeExifData()
+	if err := os.WriteFile(filepath.Join(sourceDir, "IMG_0001.jpg"), data, 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: t.TempDir(),
+		Compression: -1,
+		CatalogFile: catalogPath,
+		RunTags:     map[string]string{"client": "smith", "job": "wedding2024"},
+	}
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() error: %v", err)
+	}
+
+	catalog, err := OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error: %v", err)
+	}
+	defer catalog.Close()
+
+	record, ok := catalog.Lookup(HashBuffer(data, ""))
+	if !ok {
+		t.Fatal("Expected the imported file in the catalog")
+	}
+	if record.RunTags["client"] != "smith" || record.RunTags["job"] != "wedding2024" {
+		t.Errorf("Catalog record run tags = %v", record.RunTags)
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"sync"
+)
+
+// ErrSourceChanged is returned for source files modified while the run reads
+// them, such as files a sync client is still writing
+var ErrSourceChanged = errors.New("file changed while it was read")
+
+// checkSourceUnchanged returns ErrSourceChanged when the file at path no longer
+// has the size and modification time of read, its state when it was read. A
+// nil read disables the check.
+func checkSourceUnchanged(fsys FileSystem, path string, read os.FileInfo) error {
+	if read == nil {
+		return nil
+	}
+	current, err := fsys.Stat(path)
+	if err != nil {
+		return err
+	}
+	if current.Size() != read.Size() || !current.ModTime().Equal(read.ModTime()) {
+		return ErrSourceChanged
+	}
+	return nil
+}
+
+// deferredFiles holds the files that changed while they were read, processed
+// again once the other files of the run are done
+type deferredFiles struct {
+	mu   sync.Mutex
+	jobs []fileJob
+}
+
+// add defers a file to the end of the run
+func (d *deferredFiles) add(job fileJob) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job.deferred = true
+	d.jobs = append(d.jobs, job)
+}
+
+// take returns the deferred files, in the order they were deferred
+func (d *deferredFiles) take() []fileJob {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	jobs := d.jobs
+	d.jobs = nil
+	return jobs
+}
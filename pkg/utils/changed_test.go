@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// mockChangingOpen touches the files opened the first changes times by
+// processFile, as a sync client still writing them would
+func mockChangingOpen(changes int) (*int, func()) {
+	original := openFile
+	opens := 0
+	openFile = func(fsys FileSystem, name string) (io.ReadCloser, error) {
+		opens++
+		if opens <= changes {
+			if info, err := os.Stat(name); err == nil {
+				later := info.ModTime().Add(time.Minute)
+				os.Chtimes(name, later, later)
+			}
+		}
+		return os.Open(name)
+	}
+	return &opens, func() { openFile = original }
+}
+
+func TestCheckSourceUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "IMG_0001.JPG")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	read, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+
+	if err := checkSourceUnchanged(OSFileSystem{}, path, read); err != nil {
+		t.Errorf("checkSourceUnchanged() of an unchanged file = %v, want nil", err)
+	}
+	if err := checkSourceUnchanged(OSFileSystem{}, path, nil); err != nil {
+		t.Errorf("checkSourceUnchanged() without state = %v, want nil", err)
+	}
+
+	later := read.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Failed to touch test file: %v", err)
+	}
+	if err := checkSourceUnchanged(OSFileSystem{}, path, read); !errors.Is(err, ErrSourceChanged) {
+		t.Errorf("checkSourceUnchanged() of a touched file = %v, want ErrSourceChanged", err)
+	}
+}
+
+func TestProcessMediaFilesChangedSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		changes     int // Opens during which the file changes
+		wantOpens   int
+		wantCopied  int
+		wantSkipped int
+	}{
+		{name: "settled by the end of the run", changes: 1, wantOpens: 2, wantCopied: 1},
+		{name: "still changing", changes: 2, wantOpens: 2, wantSkipped: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := t.TempDir()
+			destination := t.TempDir()
+			sourceFile := filepath.Join(source, "IMG_0001.JPG")
+			if err := os.WriteFile(sourceFile, createSerialJPEG("2024:06:11 15:30:10", "1"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			opens, restore := mockChangingOpen(tt.changes)
+			defer restore()
+
+			params := &models.Params{
+				Source:       source,
+				Destination:  destination,
+				Compression:  -1,
+				DeleteSource: true,
+				ReportFile:   filepath.Join(t.TempDir(), "report.json"),
+			}
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if *opens != tt.wantOpens || summary.Copied != tt.wantCopied || summary.Skipped != tt.wantSkipped {
+				t.Errorf("opened %d times, summary %+v, want %d opens, %d copied, %d skipped", *opens, summary, tt.wantOpens, tt.wantCopied, tt.wantSkipped)
+			}
+
+			// A file left for the next run is kept on the source
+			_, statErr := os.Stat(sourceFile)
+			if kept := statErr == nil; kept != (tt.wantSkipped > 0) {
+				t.Errorf("source kept = %v, want %v", kept, tt.wantSkipped > 0)
+			}
+			files := readTestReport(t, params.ReportFile).Files
+			if len(files) != 1 {
+				t.Fatalf("report has %d entries, want 1: %+v", len(files), files)
+			}
+			if tt.wantSkipped > 0 && files[0].Reason != ErrSourceChanged.Error() {
+				t.Errorf("report entry = %+v, want reason %q", files[0], ErrSourceChanged)
+			}
+		})
+	}
+}
+
+func TestCopyOrCompressImageKeepsChangedSource(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "IMG_0001.JPG")
+	data := createSerialJPEG("2024:06:11 15:30:10", "1")
+	if err := os.WriteFile(source, data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	read, err := os.Stat(source)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+	// Written again after the run read it
+	later := read.ModTime().Add(time.Second)
+	if err := os.Chtimes(source, later, later); err != nil {
+		t.Fatalf("Failed to touch test file: %v", err)
+	}
+
+	var summary ProcessingSummary
+	destPath := filepath.Join(t.TempDir(), "IMG_0001.JPG")
+	params := &models.Params{Compression: -1, DeleteSource: true}
+	if _, _, err := copyOrCompressImage(OSFileSystem{}, destPath, source, read, data, true, params, nil, nil, nil, nil, &summary); !errors.Is(err, ErrSourceChanged) {
+		t.Errorf("copyOrCompressImage() error = %v, want ErrSourceChanged", err)
+	}
+	if _, err := os.Stat(source); err != nil {
+		t.Errorf("Expected the changed source to be kept: %v", err)
+	}
+	if summary.Deleted != 0 {
+		t.Errorf("Expected no deleted file, got %+v", summary)
+	}
+}
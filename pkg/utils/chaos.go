@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrInjectedFault is the error of the IO failures injected by a ChaosFS
+var ErrInjectedFault = errors.New("injected fault")
+
+// chaosOps are the operations of a ChaosFS that can fail. Listing the source
+// (Lstat and ReadDir) never fails, so every file of the source is attempted.
+var chaosOps = map[string]bool{
+	"open": true, "read": true, "write": true, "sync": true, "close": true,
+	"stat": true, "mkdir": true, "remove": true, "chmod": true, "rename": true,
+}
+
+// ChaosRates are the probabilities, between 0 and 1, that operations of a
+// ChaosFS fail, keyed by operation
+type ChaosRates map[string]float64
+
+// ParseChaosRates parses the fault rates of the -chaos test mode: a single
+// rate applied to every operation, such as 0.05, or comma-separated
+// operation=rate pairs, such as read=0.1,write=0.02
+func ParseChaosRates(spec string) (ChaosRates, error) {
+	rates := make(ChaosRates)
+	parseRate := func(value string) (float64, error) {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return 0, fmt.Errorf("rate must be a number between 0 and 1: %s", value)
+		}
+		return rate, nil
+	}
+
+	if !strings.Contains(spec, "=") {
+		rate, err := parseRate(spec)
+		if err != nil {
+			return nil, err
+		}
+		for op := range chaosOps {
+			rates[op] = rate
+		}
+		return rates, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		op, value, _ := strings.Cut(pair, "=")
+		op = strings.TrimSpace(op)
+		if !chaosOps[op] {
+			ops := make([]string, 0, len(chaosOps))
+			for name := range chaosOps {
+				ops = append(ops, name)
+			}
+			sort.Strings(ops)
+			return nil, fmt.Errorf("unknown operation %q (expected %s)", op, strings.Join(ops, ", "))
+		}
+		rate, err := parseRate(value)
+		if err != nil {
+			return nil, err
+		}
+		rates[op] = rate
+	}
+	return rates, nil
+}
+
+// ChaosFS is a FileSystem failing operations of another one at random, to
+// check that IO failures are handled without losing files. Failed writes
+// write part of their data first, as on a full disk.
+type ChaosFS struct {
+	fsys     FileSystem
+	rates    ChaosRates
+	mu       sync.Mutex
+	rng      *rand.Rand
+	injected int
+}
+
+// NewChaosFS returns a ChaosFS failing operations of fsys at the given rates.
+// The same seed injects the same faults for the same sequence of operations.
+func NewChaosFS(fsys FileSystem, rates ChaosRates, seed int64) *ChaosFS {
+	return &ChaosFS{fsys: fsys, rates: rates, rng: rand.New(rand.NewSource(seed))}
+}
+
+// Injected returns the number of faults injected so far
+func (c *ChaosFS) Injected() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.injected
+}
+
+// fault returns an injected error for an operation on path, or nil
+func (c *ChaosFS) fault(op, path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rng.Float64() >= c.rates[op] {
+		return nil
+	}
+	c.injected++
+	return &fs.PathError{Op: op, Path: path, Err: ErrInjectedFault}
+}
+
+func (c *ChaosFS) Open(name string) (File, error) {
+	if err := c.fault("open", name); err != nil {
+		return nil, err
+	}
+	file, err := c.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosFile{File: file, fs: c, name: name}, nil
+}
+
+func (c *ChaosFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if err := c.fault("open", name); err != nil {
+		return nil, err
+	}
+	file, err := c.fsys.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosFile{File: file, fs: c, name: name}, nil
+}
+
+func (c *ChaosFS) Stat(name string) (fs.FileInfo, error) {
+	if err := c.fault("stat", name); err != nil {
+		return nil, err
+	}
+	return c.fsys.Stat(name)
+}
+
+func (c *ChaosFS) Lstat(name string) (fs.FileInfo, error) {
+	return c.fsys.Lstat(name)
+}
+
+func (c *ChaosFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return c.fsys.ReadDir(name)
+}
+
+func (c *ChaosFS) MkdirAll(path string, perm fs.FileMode) error {
+	if err := c.fault("mkdir", path); err != nil {
+		return err
+	}
+	return c.fsys.MkdirAll(path, perm)
+}
+
+func (c *ChaosFS) Remove(name string) error {
+	if err := c.fault("remove", name); err != nil {
+		return err
+	}
+	return c.fsys.Remove(name)
+}
+
+func (c *ChaosFS) Chmod(name string, mode fs.FileMode) error {
+	if err := c.fault("chmod", name); err != nil {
+		return err
+	}
+	return c.fsys.Chmod(name, mode)
+}
+
+func (c *ChaosFS) Rename(oldpath, newpath string) error {
+	if err := c.fault("rename", newpath); err != nil {
+		return err
+	}
+	return c.fsys.Rename(oldpath, newpath)
+}
+
+func (c *ChaosFS) Link(oldname, newname string) error {
+	if err := c.fault("rename", newname); err != nil {
+		return err
+	}
+	return c.fsys.Link(oldname, newname)
+}
+
+// chaosFile is an open file of a ChaosFS
+type chaosFile struct {
+	File
+	fs   *ChaosFS
+	name string
+}
+
+func (f *chaosFile) Read(p []byte) (int, error) {
+	if err := f.fs.fault("read", f.name); err != nil {
+		return 0, err
+	}
+	return f.File.Read(p)
+}
+
+func (f *chaosFile) Write(p []byte) (int, error) {
+	if err := f.fs.fault("write", f.name); err != nil {
+		n, _ := f.File.Write(p[:len(p)/2])
+		return n, err
+	}
+	return f.File.Write(p)
+}
+
+func (f *chaosFile) Sync() error {
+	if err := f.fs.fault("sync", f.name); err != nil {
+		return err
+	}
+	return f.File.Sync()
+}
+
+func (f *chaosFile) Close() error {
+	err := f.File.Close()
+	if fault := f.fs.fault("close", f.name); err == nil {
+		err = fault
+	}
+	return err
+}
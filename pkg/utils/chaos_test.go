@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestParseChaosRates(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    ChaosRates // Checked operations
+		wantErr bool
+	}{
+		{spec: "0.05", want: ChaosRates{"read": 0.05, "remove": 0.05}},
+		{spec: "read=0.1, write=1", want: ChaosRates{"read": 0.1, "write": 1, "remove": 0}},
+		{spec: "1.5", wantErr: true},
+		{spec: "-0.1", wantErr: true},
+		{spec: "often", wantErr: true},
+		{spec: "rename=0.1", want: ChaosRates{"rename": 0.1}},
+		{spec: "truncate=0.1", wantErr: true},
+		{spec: "read=", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseChaosRates(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseChaosRates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for op, rate := range tt.want {
+				if got[op] != rate {
+					t.Errorf("ParseChaosRates() rate of %s = %v, want %v", op, got[op], rate)
+				}
+			}
+		})
+	}
+}
+
+func TestChaosFS(t *testing.T) {
+	m := NewMemFS()
+	path := filepath.Join(string(filepath.Separator), "photo.jpg")
+	m.WriteFile(path, []byte("photo"), 0644)
+
+	// Faults are only injected in the operations given a rate
+	chaos := NewChaosFS(m, ChaosRates{"stat": 1}, 1)
+	if _, err := chaos.Stat(path); !errors.Is(err, ErrInjectedFault) {
+		t.Errorf("Stat() error = %v, want %v", err, ErrInjectedFault)
+	}
+	if _, err := chaos.Open(path); err != nil {
+		t.Errorf("Open() error = %v", err)
+	}
+	if chaos.Injected() != 1 {
+		t.Errorf("Injected() = %d, want 1", chaos.Injected())
+	}
+
+	// Failed writes leave part of their data, as on a full disk
+	chaos = NewChaosFS(m, ChaosRates{"write": 1}, 1)
+	file, _ := chaos.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if n, err := file.Write([]byte("abcd")); n != 2 || !errors.Is(err, ErrInjectedFault) {
+		t.Errorf("Write() = %d, %v, want 2, %v", n, err, ErrInjectedFault)
+	}
+	file.Close()
+	if data, _ := m.ReadFile(path); string(data) != "ab" {
+		t.Errorf("Content after failed write = %q, want %q", data, "ab")
+	}
+}
+
+// Whatever fails, a source file is only ever deleted once an identical copy is at the destination
+func TestChaosNeverDeletesUnverifiedSources(t *testing.T) {
+	root := string(filepath.Separator)
+	source, dest, mirror := filepath.Join(root, "card"), filepath.Join(root, "nas"), filepath.Join(root, "backup")
+	content := func(i int) []byte {
+		// Files of different sizes, padded before the end of image marker
+		data := createFakeExifData()
+		padded := append(bytes.Clone(data[:len(data)-2]), bytes.Repeat([]byte{byte(i)}, 100*i)...)
+		return append(padded, data[len(data)-2:]...)
+	}
+
+	for seed := int64(1); seed <= 40; seed++ {
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			m := NewMemFS()
+			m.MkdirAll(dest, 0755)
+			m.MkdirAll(mirror, 0755)
+			for i := 1; i <= 10; i++ {
+				m.WriteFile(filepath.Join(source, fmt.Sprintf("IMG_%04d.JPG", i)), content(i), 0644)
+			}
+			chaos := NewChaosFS(m, ChaosRates{"open": 0.05, "read": 0.05, "write": 0.1, "sync": 0.1, "close": 0.1, "stat": 0.05, "mkdir": 0.05, "remove": 0.1, "chmod": 0.1, "rename": 0.1}, seed)
+
+			params := &models.Params{Source: source, Destination: dest, Mirrors: []string{mirror}, Compression: -1, DeleteSource: true, HashAlgo: HashSHA256, Workers: 2}
+			ProcessMediaFilesFS(context.Background(), chaos, params, nil)
+
+			for i := 1; i <= 10; i++ {
+				name := fmt.Sprintf("IMG_%04d.JPG", i)
+				if _, err := m.Stat(filepath.Join(source, name)); err == nil {
+					continue
+				}
+				for _, root := range []string{dest, mirror} {
+					copied, err := m.ReadFile(filepath.Join(root, "2025", "01-11", name))
+					if err != nil || !bytes.Equal(copied, content(i)) {
+						t.Errorf("%s deleted with %d intact bytes of %d in %s (%v), %d faults injected", name, len(copied), len(content(i)), root, err, chaos.Injected())
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// checkPlaceholder is replaced with the path of the checked file in check commands
+const checkPlaceholder = "{}"
+
+// For testing purposes
+var runCheckCommand = func(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// ErrRejected is wrapped by the errors of files rejected by the check command
+var ErrRejected = errors.New("rejected by check command")
+
+// checkFile runs the check command of a run on a source file, such as
+// "clamscan --no-summary {}". The command is split on spaces and run without a
+// shell; {} is replaced with the file path, which is appended when absent.
+// A zero exit status accepts the file, any other status rejects it.
+func checkFile(command, path string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	args, placed := make([]string, 0, len(fields)), false
+	for _, field := range fields[1:] {
+		if strings.Contains(field, checkPlaceholder) {
+			field, placed = strings.ReplaceAll(field, checkPlaceholder, path), true
+		}
+		args = append(args, field)
+	}
+	if !placed {
+		args = append(args, path)
+	}
+
+	out, err := runCheckCommand(fields[0], args...)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// The first line of the output usually names the problem, such as the virus found
+		reason := strings.TrimSpace(strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)[0])
+		if reason == "" {
+			reason = fmt.Sprintf("exit status %d", exitErr.ExitCode())
+		}
+		return fmt.Errorf("%w: %s", ErrRejected, reason)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run check command: %w", err)
+	}
+	return nil
+}
+
+// rejectFile records a file rejected by the check command, copying it to the
+// quarantine folder of the run if there is one
+func (r *mediaRun) rejectFile(entry ReportEntry, data payload, reason error, summary *ProcessingSummary) {
+	summary.Rejected++
+	entry.Status, entry.Reason = ReportRejected, reason.Error()
+
+	if r.p.QuarantineDir == "" {
+		output.Status("REJECTED", fmt.Sprintf("%s: %v", entry.Source, reason))
+		r.finish(entry)
+		return
+	}
+
+	destPath, err := quarantineFile(r.p.QuarantineDir, entry.Source, data)
+	if err != nil {
+		summary.Failed++
+		output.Status("ERROR", fmt.Sprintf("Failed to quarantine %s: %v", entry.Source, err))
+		r.finish(entry)
+		return
+	}
+	entry.Destination = destPath
+	output.Status("REJECTED", fmt.Sprintf("%s: %v, quarantined to: %s", entry.Source, reason, destPath))
+	r.finish(entry)
+}
+
+// quarantineFile copies a rejected file to the quarantine folder, never
+// replacing a file already there
+func quarantineFile(dir, source string, data payload) (string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	destPath := filepath.Join(dir, filepath.Base(source))
+	file, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := data.writeTo(file); err != nil {
+		file.Close()
+		return "", err
+	}
+	return destPath, file.Close()
+}
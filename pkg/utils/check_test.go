@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createCheckScript writes a shell script rejecting files whose name contains "infected"
+func createCheckScript(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("check scripts are shell scripts")
+	}
+	script := filepath.Join(t.TempDir(), "check.sh")
+	content := "#!/bin/sh\ncase \"$1\" in *infected*) echo \"$1: Eicar-Signature FOUND\"; exit 1;; esac\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("Failed to create check script: %v", err)
+	}
+	return script
+}
+
+func TestCheckFileArguments(t *testing.T) {
+	original := runCheckCommand
+	defer func() { runCheckCommand = original }()
+
+	var got []string
+	runCheckCommand = func(name string, args ...string) ([]byte, error) {
+		got = append([]string{name}, args...)
+		return nil, nil
+	}
+
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"clamscan --no-summary {}", []string{"clamscan", "--no-summary", "/card/a.jpg"}},
+		{"validate --file={} -q", []string{"validate", "--file=/card/a.jpg", "-q"}},
+		{"validate", []string{"validate", "/card/a.jpg"}},
+	}
+	for _, tt := range tests {
+		if err := checkFile(tt.command, "/card/a.jpg"); err != nil {
+			t.Errorf("checkFile(%q) error = %v", tt.command, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("checkFile(%q) ran %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	script := createCheckScript(t)
+
+	if err := checkFile(script, "/card/clean.jpg"); err != nil {
+		t.Errorf("checkFile() on a clean file error = %v", err)
+	}
+
+	err := checkFile(script, "/card/infected.jpg")
+	if !errors.Is(err, ErrRejected) || !strings.Contains(err.Error(), "Eicar-Signature FOUND") {
+		t.Errorf("checkFile() on an infected file error = %v", err)
+	}
+
+	if err := checkFile(filepath.Join(t.TempDir(), "missing"), "/card/clean.jpg"); err == nil || errors.Is(err, ErrRejected) {
+		t.Errorf("checkFile() with a missing command error = %v", err)
+	}
+}
+
+func TestProcessMediaFilesCheckCommand(t *testing.T) {
+	script := createCheckScript(t)
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	quarantine := filepath.Join(t.TempDir(), "quarantine")
+	for _, name := range []string{"clean.jpg", "infected.jpg"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	params := &models.Params{Source: sourceDir, Destination: destDir, Compression: -1, CheckCommand: script, QuarantineDir: quarantine, ReportFile: reportFile}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Rejected != 1 {
+		t.Errorf("Expected 1 copied and 1 rejected file, got %+v", summary)
+	}
+
+	if _, err := os.Stat(filepath.Join(destDir, "2025", "01-11", "infected.jpg")); !os.IsNotExist(err) {
+		t.Errorf("Expected the rejected file to stay out of the destination: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(quarantine, "infected.jpg")); err != nil {
+		t.Errorf("Expected the rejected file in quarantine: %v", err)
+	}
+
+	for _, entry := range readTestReport(t, reportFile).Files {
+		if filepath.Base(entry.Source) == "infected.jpg" && entry.Status != ReportRejected {
+			t.Errorf("Report entry %s status = %s, want %s", entry.Source, entry.Status, ReportRejected)
+		}
+	}
+}
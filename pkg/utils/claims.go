@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"strings"
+	"sync"
+)
+
+// destinationClaims reserves destination paths within a run. Workers checking
+// the destination on disk at the same time would all find it free, the first
+// claim decides which source file is written there.
+type destinationClaims struct {
+	mu     sync.Mutex
+	fold   bool              // Paths differing only in case are the same file, as on FAT, exFAT and SMB shares
+	owners map[string]string // Source file of each claimed destination
+}
+
+func newDestinationClaims(fold bool) *destinationClaims {
+	return &destinationClaims{fold: fold, owners: make(map[string]string)}
+}
+
+// claim reserves destPath for source. When another source file of the run
+// claimed it first, it returns that file and false.
+func (c *destinationClaims) claim(destPath, source string) (string, bool) {
+	key := destPath
+	if c.fold {
+		key = strings.ToLower(key)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if owner, ok := c.owners[key]; ok && owner != source {
+		return owner, false
+	}
+	c.owners[key] = source
+	return source, true
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestDestinationClaims(t *testing.T) {
+	testCases := []struct {
+		name      string
+		fold      bool
+		second    string
+		wantOwner string
+		wantOK    bool
+	}{
+		{name: "other destination", second: "/dest/2025/01-11/IMG_0002.JPG", wantOwner: "b.jpg", wantOK: true},
+		{name: "same destination", second: "/dest/2025/01-11/IMG_0001.JPG", wantOwner: "a.jpg"},
+		{name: "case differs", second: "/dest/2025/01-11/img_0001.jpg", wantOwner: "b.jpg", wantOK: true},
+		{name: "case differs on FAT", fold: true, second: "/dest/2025/01-11/img_0001.jpg", wantOwner: "a.jpg"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := newDestinationClaims(tc.fold)
+			if _, ok := claims.claim("/dest/2025/01-11/IMG_0001.JPG", "a.jpg"); !ok {
+				t.Fatal("First claim failed")
+			}
+			owner, ok := claims.claim(tc.second, "b.jpg")
+			if owner != tc.wantOwner || ok != tc.wantOK {
+				t.Errorf("claim() = %q, %v, want %q, %v", owner, ok, tc.wantOwner, tc.wantOK)
+			}
+			// Claiming again for the same file keeps the claim
+			if _, ok := claims.claim("/dest/2025/01-11/IMG_0001.JPG", "a.jpg"); !ok {
+				t.Error("Repeated claim failed")
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesSharedDestination(t *testing.T) {
+	// Cards of several bodies with the same file names, shot on the same day
+	source := t.TempDir()
+	const cards = 16
+	for i := 0; i < cards; i++ {
+		dir := filepath.Join(source, fmt.Sprintf("card%02d", i))
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "IMG_0001.jpg"), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	params := &models.Params{Source: source, Destination: t.TempDir(), Compression: -1, Workers: 8, ReportFile: reportFile}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Skipped != cards-1 {
+		t.Errorf("Expected 1 copied and %d skipped files, got %+v", cards-1, summary)
+	}
+
+	for _, entry := range readTestReport(t, reportFile).Files {
+		if entry.Status == ReportSkipped && !strings.HasPrefix(entry.Reason, "same destination as ") {
+			t.Errorf("Unexpected reason for %s: %s", entry.Source, entry.Reason)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// ClockOffsets maps camera body serial numbers to how far ahead of the real
+// time their clock runs, negative for clocks running late. Dates of pictures
+// taken by these bodies are corrected so multi-body shoots line up.
+type ClockOffsets map[string]time.Duration
+
+// LoadClockOffsets reads a JSON file mapping serial numbers to offsets, such
+// as {"4012345": "3m12s", "8076543": "-45s"}
+func LoadClockOffsets(path string) (ClockOffsets, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clock offsets: %w", err)
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse clock offsets %s: %w", path, err)
+	}
+
+	offsets := make(ClockOffsets, len(raw))
+	for serial, value := range raw {
+		offset, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clock offset of camera %s in %s: %w", serial, path, err)
+		}
+		offsets[serial] = offset
+	}
+	return offsets, nil
+}
+
+// Save writes the offsets to a JSON file read by LoadClockOffsets
+func (o ClockOffsets) Save(path string) error {
+	raw := make(map[string]string, len(o))
+	for serial, offset := range o {
+		raw[serial] = offset.String()
+	}
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write clock offsets: %w", err)
+	}
+	return nil
+}
+
+// Sync sets the offsets of the bodies that took photos of the same clock or
+// slate at the same moment as the reference picture, from the difference of
+// their capture dates. The reference body keeps its offset, unless the real
+// time of the shot is given as at. Sync returns the serial numbers of the
+// bodies, the reference first.
+func (o ClockOffsets) Sync(reference string, photos []string, at time.Time) ([]string, error) {
+	refDate, refSerial, err := shotClock(reference)
+	if err != nil {
+		return nil, err
+	}
+	offset := o[refSerial]
+	if !at.IsZero() {
+		offset = refDate.Sub(at)
+	}
+	o[refSerial] = offset
+
+	serials := []string{refSerial}
+	for _, photo := range photos {
+		date, serial, err := shotClock(photo)
+		if err != nil {
+			return nil, err
+		}
+		if serial == refSerial {
+			return nil, fmt.Errorf("%s was taken by the reference body %s", photo, serial)
+		}
+		o[serial] = offset + date.Sub(refDate)
+		serials = append(serials, serial)
+	}
+	return serials, nil
+}
+
+// shotClock returns the capture date and body serial number of a picture
+func shotClock(path string) (time.Time, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	defer file.Close()
+
+	date, err := GetImageDateTimeFromReader(file, filepath.Ext(path))
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%s: %w", path, err)
+	}
+	meta, err := GetImageMetadata(file, filepath.Ext(path))
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%s: %w", path, err)
+	}
+	if meta.Serial == "" {
+		return time.Time{}, "", fmt.Errorf("no body serial number in %s", path)
+	}
+	return date, meta.Serial, nil
+}
+
+// loadClockOffsets returns the clock offsets of a run, nil when there are none
+func loadClockOffsets(p *models.Params) (ClockOffsets, error) {
+	if p.ClockFile == "" {
+		return nil, nil
+	}
+	return LoadClockOffsets(p.ClockFile)
+}
+
+// correct returns a date recorded by the camera body with the given serial
+// number once its clock offset is removed
+func (o ClockOffsets) correct(date time.Time, serial string) time.Time {
+	return date.Add(-o[serial])
+}
+
+// correctFile is correct for the picture at path
+func (o ClockOffsets) correctFile(date time.Time, path string) time.Time {
+	if len(o) == 0 {
+		return date
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return date
+	}
+	defer file.Close()
+
+	meta, err := GetImageMetadata(file, filepath.Ext(path))
+	if err != nil {
+		return date
+	}
+	return o.correct(date, meta.Serial)
+}
@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createSerialJPEG returns a minimal JPEG file taken at date, as EXIF text,
+// by the camera body with the given serial number
+func createSerialJPEG(date, serial string) []byte {
+	const valuesOffset = 8 + 2 + 2*12 + 4 // Header, entry count, entries and next directory offset
+	dateValue := append([]byte(date), 0)
+	serialValue := append([]byte(serial), 0)
+
+	tiff := []byte("MM\x00*\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	for _, entry := range []struct {
+		tag    uint16
+		value  []byte
+		offset int
+	}{
+		{0x0132, dateValue, valuesOffset},
+		{TagBodySerialNumber, serialValue, valuesOffset + len(dateValue)},
+	} {
+		tiff = binary.BigEndian.AppendUint16(tiff, entry.tag)
+		tiff = binary.BigEndian.AppendUint16(tiff, tiffTypeASCII)
+		tiff = binary.BigEndian.AppendUint32(tiff, uint32(len(entry.value)))
+		tiff = binary.BigEndian.AppendUint32(tiff, uint32(entry.offset))
+	}
+	tiff = binary.BigEndian.AppendUint32(tiff, 0)
+	tiff = append(tiff, dateValue...)
+	tiff = append(tiff, serialValue...)
+	return createTestJPEG(tiff)
+}
+
+func TestLoadClockOffsets(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    ClockOffsets
+		wantErr bool
+	}{
+		{
+			name:    "offsets",
+			content: `{"4012345": "3m12s", "8076543": "-45s"}`,
+			want:    ClockOffsets{"4012345": 3*time.Minute + 12*time.Second, "8076543": -45 * time.Second},
+		},
+		{name: "invalid offset", content: `{"4012345": "3 minutes"}`, wantErr: true},
+		{name: "invalid file", content: `["4012345"]`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "clocks.json")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write offsets: %v", err)
+			}
+
+			got, err := LoadClockOffsets(path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("LoadClockOffsets() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("LoadClockOffsets() = %v, want %v", got, tc.want)
+			}
+			for serial, offset := range tc.want {
+				if got[serial] != offset {
+					t.Errorf("Offset of %s = %v, want %v", serial, got[serial], offset)
+				}
+			}
+		})
+	}
+
+	if _, err := LoadClockOffsets(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing offsets file")
+	}
+}
+
+func TestProcessMediaFilesClockOffsets(t *testing.T) {
+	source := t.TempDir()
+	// Both bodies shot just after midnight, body A runs 3m12s fast
+	files := map[string][]byte{
+		"A_0001.jpg": createSerialJPEG("2025:01:11 00:02:00", "A100"),
+		"B_0001.jpg": createSerialJPEG("2025:01:11 00:02:00", "B200"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(source, name), data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	offsetsFile := filepath.Join(t.TempDir(), "clocks.json")
+	if err := os.WriteFile(offsetsFile, []byte(`{"A100": "3m12s"}`), 0644); err != nil {
+		t.Fatalf("Failed to write offsets: %v", err)
+	}
+
+	dest := t.TempDir()
+	params := &models.Params{Source: source, Destination: dest, Compression: -1, ClockFile: offsetsFile}
+	want := map[string]string{
+		"A_0001.jpg": filepath.Join(dest, "2025", "01-10", "A_0001.jpg"),
+		"B_0001.jpg": filepath.Join(dest, "2025", "01-11", "B_0001.jpg"),
+	}
+
+	plan, err := PlanMediaFiles(params)
+	if err != nil {
+		t.Fatalf("PlanMediaFiles() error = %v", err)
+	}
+	for _, planned := range plan {
+		if expected := want[filepath.Base(planned.Source)]; planned.Destination != expected {
+			t.Errorf("Planned %s to %s, want %s", planned.Source, planned.Destination, expected)
+		}
+	}
+
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != len(want) {
+		t.Errorf("Expected %d copied files, got %+v", len(want), summary)
+	}
+	for _, destPath := range want {
+		if _, err := os.Stat(destPath); err != nil {
+			t.Errorf("Expected %s: %v", destPath, err)
+		}
+	}
+}
+
+func TestClockOffsetsSync(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"reference.jpg": createSerialJPEG("2025:01:11 12:00:00", "A100"),
+		"fast.jpg":      createSerialJPEG("2025:01:11 12:03:12", "B200"),
+		"late.jpg":      createSerialJPEG("2025:01:11 11:59:15", "C300"),
+		"same.jpg":      createSerialJPEG("2025:01:11 12:00:01", "A100"),
+		"noserial.jpg":  createSerialJPEG("2025:01:11 12:00:00", ""),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name    string
+		offsets ClockOffsets
+		photos  []string
+		at      time.Time
+		want    ClockOffsets
+		wantErr bool
+	}{
+		{
+			name:    "reference on time",
+			offsets: ClockOffsets{},
+			photos:  []string{"fast.jpg", "late.jpg"},
+			want:    ClockOffsets{"A100": 0, "B200": 3*time.Minute + 12*time.Second, "C300": -45 * time.Second},
+		},
+		{
+			name:    "reference already offset",
+			offsets: ClockOffsets{"A100": time.Minute},
+			photos:  []string{"fast.jpg"},
+			want:    ClockOffsets{"A100": time.Minute, "B200": 4*time.Minute + 12*time.Second},
+		},
+		{
+			name:    "real time of the shot",
+			offsets: ClockOffsets{"A100": time.Minute},
+			photos:  []string{"late.jpg"},
+			at:      time.Date(2025, time.January, 11, 11, 59, 30, 0, time.UTC),
+			want:    ClockOffsets{"A100": 30 * time.Second, "C300": -15 * time.Second},
+		},
+		{name: "same body", offsets: ClockOffsets{}, photos: []string{"same.jpg"}, wantErr: true},
+		{name: "no serial number", offsets: ClockOffsets{}, photos: []string{"noserial.jpg"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var photos []string
+			for _, name := range tt.photos {
+				photos = append(photos, path(name))
+			}
+			serials, err := tt.offsets.Sync(path("reference.jpg"), photos, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Sync() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(serials) != len(photos)+1 || serials[0] != "A100" {
+				t.Errorf("Sync() serials = %v, want the reference body first", serials)
+			}
+			if !reflect.DeepEqual(tt.offsets, tt.want) {
+				t.Errorf("offsets = %v, want %v", tt.offsets, tt.want)
+			}
+
+			// Saved offsets are read back as they are
+			file := filepath.Join(t.TempDir(), "clocks.json")
+			if err := tt.offsets.Save(file); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			loaded, err := LoadClockOffsets(file)
+			if err != nil {
+				t.Fatalf("LoadClockOffsets() error = %v", err)
+			}
+			if !reflect.DeepEqual(loaded, tt.want) {
+				t.Errorf("saved offsets = %v, want %v", loaded, tt.want)
+			}
+		})
+	}
+}
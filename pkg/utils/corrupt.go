@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// CorruptDir is the destination folder receiving isolated empty and truncated files
+const CorruptDir = "corrupt"
+
+// ErrCorruptFile is wrapped by the errors reporting empty and truncated media files
+var ErrCorruptFile = errors.New("corrupt file")
+
+// jpegTailSize is how much of the end of a JPEG file is searched for its end marker
+const jpegTailSize = 4096
+
+// tiffMagics are the headers of TIFF-based files: standard little and big
+// endian TIFF, Olympus ORF and Panasonic RW2
+var tiffMagics = [][]byte{
+	[]byte("II*\x00"),
+	[]byte("MM\x00*"),
+	[]byte("IIRO"),
+	[]byte("IIU\x00"),
+}
+
+// checkIntegrity detects empty media files and files cut short, as left by
+// card errors and interrupted transfers. Only content recognized as its format
+// is checked for truncation, unknown content is left to date extraction.
+func checkIntegrity(r io.ReadSeeker, size int64, name string) error {
+	if size == 0 {
+		return fmt.Errorf("%w: empty file", ErrCorruptFile)
+	}
+
+	header := make([]byte, 8)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	header = header[:n]
+
+	switch {
+	case isJPEG(name) && bytes.HasPrefix(header, []byte{0xFF, 0xD8}):
+		if n < 4 {
+			return fmt.Errorf("%w: truncated JPEG header", ErrCorruptFile)
+		}
+		return checkJPEGEnd(r, size)
+	case isTIFFHeader(header):
+		return checkTIFFHeader(header, size)
+	case n == 8 && string(header[4:8]) == "ftyp":
+		return checkBoxes(r, size)
+	}
+	return nil
+}
+
+// checkJPEGEnd checks that a JPEG file ends with its end of image marker.
+// Zero padding after the marker, written by some cameras, is ignored.
+func checkJPEGEnd(r io.ReadSeeker, size int64) error {
+	tailSize := min(size, jpegTailSize)
+	if _, err := r.Seek(size-tailSize, io.SeekStart); err != nil {
+		return err
+	}
+	tail := make([]byte, tailSize)
+	if _, err := io.ReadFull(r, tail); err != nil {
+		return err
+	}
+
+	if !bytes.HasSuffix(bytes.TrimRight(tail, "\x00"), []byte{0xFF, 0xD9}) {
+		return fmt.Errorf("%w: JPEG data is truncated, end of image marker missing", ErrCorruptFile)
+	}
+	return nil
+}
+
+// checkTIFFHeader checks that the first image directory of a TIFF-based file,
+// such as most RAW formats, starts within the file
+func checkTIFFHeader(header []byte, size int64) error {
+	if len(header) < 8 {
+		return fmt.Errorf("%w: truncated TIFF header", ErrCorruptFile)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if header[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	// Directories start with a 2-byte entry count
+	if offset := int64(order.Uint32(header[4:8])); offset+2 > size {
+		return fmt.Errorf("%w: first image directory at %d is beyond the end of the file (%d bytes)", ErrCorruptFile, offset, size)
+	}
+	return nil
+}
+
+// checkBoxes checks that the top-level boxes of an ISO base media file, such
+// as CR3 and HEIC, end within the file
+func checkBoxes(r io.ReadSeeker, size int64) error {
+	header := make([]byte, 16)
+	for offset := int64(0); offset < size; {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		if size-offset < 8 {
+			return fmt.Errorf("%w: truncated box header at %d", ErrCorruptFile, offset)
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return err
+		}
+
+		boxSize := int64(binary.BigEndian.Uint32(header[:4]))
+		switch boxSize {
+		case 0:
+			return nil // The last box extends to the end of the file
+		case 1:
+			// 64-bit size following the box type
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return fmt.Errorf("%w: truncated box header at %d", ErrCorruptFile, offset)
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+		}
+		if boxSize < 8 {
+			return fmt.Errorf("%w: invalid box size %d at %d", ErrCorruptFile, boxSize, offset)
+		}
+		// Compared without adding to the offset, which 64-bit sizes would overflow
+		if boxSize > size-offset {
+			return fmt.Errorf("%w: %s box at %d ends beyond the end of the file (%d bytes)", ErrCorruptFile, header[4:8], offset, size)
+		}
+		offset += boxSize
+	}
+	return nil
+}
+
+// isTIFFHeader reports whether header starts like a TIFF-based file. Files
+// shorter than a magic number but matching its start are reported as well.
+func isTIFFHeader(header []byte) bool {
+	for _, magic := range tiffMagics {
+		if len(header) >= 3 && bytes.HasPrefix(header, magic[:min(len(header), len(magic))]) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFileIntegrity opens a media file of fsys and checks it is neither empty nor truncated
+func checkFileIntegrity(fsys FileSystem, path string, info os.FileInfo) error {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return checkIntegrity(file, info.Size(), path)
+}
+
+// isolateCorrupt records an empty or truncated file apart from generic skips,
+// copying it to the corrupt folder of the destination when requested. The
+// source is always kept.
+func (r *mediaRun) isolateCorrupt(path string, info os.FileInfo, data payload, reason error, summary *ProcessingSummary) {
+	summary.Corrupt++
+	entry := ReportEntry{Source: path, Status: ReportCorrupt, Reason: reason.Error(), Size: info.Size()}
+
+	if !r.p.IsolateCorrupt {
+		output.Status("CORRUPT", fmt.Sprintf("%s: %v", path, reason))
+		r.finish(entry)
+		return
+	}
+
+	destPath, err := isolateFile(r.fs, r.p, CorruptDir, path, data)
+	if err != nil {
+		summary.Failed++
+		output.Status("ERROR", fmt.Sprintf("Failed to isolate corrupt file %s: %v", path, err))
+		r.finish(entry)
+		return
+	}
+	entry.Destination = destPath
+	output.Status("CORRUPT", fmt.Sprintf("%s: %v, copied to: %s", path, reason, destPath))
+	r.finish(entry)
+}
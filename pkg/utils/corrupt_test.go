@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// buildBoxes returns ISO base media boxes of the given declared sizes, with
+// contentSize bytes of content in total
+func buildBoxes(contentSize int, boxes ...uint32) []byte {
+	var data []byte
+	for i, size := range boxes {
+		header := make([]byte, 8)
+		binary.BigEndian.PutUint32(header, size)
+		if i == 0 {
+			copy(header[4:], "ftyp")
+		} else {
+			copy(header[4:], "mdat")
+		}
+		data = append(data, header...)
+		data = append(data, make([]byte, int(size)-8)...)
+	}
+	return data[:contentSize]
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	jpeg := createFakeExifData()
+	tiff := buildTIFF(nil, nil)
+	bigTIFF := append([]byte("MM\x00*"), 0, 0, 0x10, 0)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		file        string
+		wantCorrupt bool
+	}{
+		{"Empty file", nil, "a.jpg", true},
+		{"Valid JPEG", jpeg, "a.jpg", false},
+		{"JPEG with padding", append(append([]byte{}, jpeg...), 0, 0, 0), "a.jpg", false},
+		{"Truncated JPEG", jpeg[:len(jpeg)-10], "a.JPG", true},
+		{"Truncated JPEG header", []byte{0xFF, 0xD8, 0xFF}, "a.jpg", true},
+		{"Valid TIFF", tiff, "a.nef", false},
+		{"Truncated TIFF", bigTIFF, "a.nef", true},
+		{"Truncated TIFF header", []byte("II*\x00\x08"), "a.dng", true},
+		{"Valid boxes", buildBoxes(40, 16, 24), "a.cr3", false},
+		{"Truncated boxes", buildBoxes(30, 16, 24), "a.heic", true},
+		{"Unknown content", []byte("test data"), "a.jpg", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkIntegrity(bytes.NewReader(tt.data), int64(len(tt.data)), tt.file)
+			if errors.Is(err, ErrCorruptFile) != tt.wantCorrupt {
+				t.Errorf("checkIntegrity() error = %v, wantCorrupt %v", err, tt.wantCorrupt)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesCorrupt(t *testing.T) {
+	tests := []struct {
+		name    string
+		isolate bool
+	}{
+		{"Report only", false},
+		{"Isolate", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir := t.TempDir()
+			destDir := t.TempDir()
+			jpeg := createFakeExifData()
+			files := map[string][]byte{
+				"valid.jpg":     jpeg,
+				"empty.jpg":     nil,
+				"truncated.jpg": jpeg[:len(jpeg)-10],
+			}
+			for name, data := range files {
+				if err := os.WriteFile(filepath.Join(sourceDir, name), data, 0644); err != nil {
+					t.Fatalf("Failed to create test file: %v", err)
+				}
+			}
+
+			params := &models.Params{
+				Source:         sourceDir,
+				Destination:    destDir,
+				Compression:    -1,
+				DeleteSource:   true,
+				IsolateCorrupt: tt.isolate,
+				ReportFile:     filepath.Join(t.TempDir(), "report.json"),
+			}
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if summary.Corrupt != 2 || summary.Skipped != 0 || summary.Copied != 1 {
+				t.Errorf("Expected 2 corrupt files apart from skips, got %+v", summary)
+			}
+
+			for _, name := range []string{"empty.jpg", "truncated.jpg"} {
+				if _, err := os.Stat(filepath.Join(sourceDir, name)); err != nil {
+					t.Errorf("Source of corrupt file %s was not kept: %v", name, err)
+				}
+				_, err := os.Stat(filepath.Join(destDir, CorruptDir, name))
+				if (err == nil) != tt.isolate {
+					t.Errorf("Corrupt file %s isolated = %t, want %t", name, err == nil, tt.isolate)
+				}
+			}
+
+			var corrupt int
+			for _, entry := range readTestReport(t, params.ReportFile).Files {
+				if entry.Status == ReportCorrupt {
+					corrupt++
+				}
+			}
+			if corrupt != 2 {
+				t.Errorf("Expected 2 corrupt report entries, got %d", corrupt)
+			}
+		})
+	}
+}
+
+func TestPlanMediaFilesCorrupt(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "empty.nef"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	entries, err := ScanMediaFiles(&models.Params{Source: sourceDir, Compression: -1})
+	if err != nil {
+		t.Fatalf("ScanMediaFiles() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != ActionCorrupt {
+		t.Errorf("Expected the empty file to be scanned as corrupt, got %+v", entries)
+	}
+}
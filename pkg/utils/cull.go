@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// Formats reviewed in cull mode
+const (
+	CullJPEG = "jpeg"
+	CullRAW  = "raw"
+)
+
+// previewExtensions are the formats cameras write next to RAW files, used to review shots
+var previewExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".heic": true,
+	".heif": true,
+}
+
+// FindCullOrphans returns the media files of the source whose companion in the
+// reviewed format was deleted during culling. Companions share a directory and
+// a name, such as DSC00001.JPG and DSC00001.ARW. Directories holding no file of
+// the reviewed format are left alone, so RAW-only folders are never culled.
+func FindCullOrphans(source, reviewed string) ([]string, error) {
+	type shot struct {
+		reviewed bool
+		files    []string
+	}
+	dirs := make(map[string]map[string]*shot)
+	reviewedDirs := make(map[string]bool)
+
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+
+		ext := filepath.Ext(info.Name())
+		if info.IsDir() || !isAllowedExtension(ext) {
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		if dirs[dir] == nil {
+			dirs[dir] = make(map[string]*shot)
+		}
+		name := strings.ToLower(strings.TrimSuffix(info.Name(), ext))
+		s := dirs[dir][name]
+		if s == nil {
+			s = &shot{}
+			dirs[dir][name] = s
+		}
+
+		if previewExtensions[strings.ToLower(ext)] == (reviewed == CullJPEG) {
+			s.reviewed = true
+			reviewedDirs[dir] = true
+		} else {
+			s.files = append(s.files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	var orphans []string
+	for dir, shots := range dirs {
+		if !reviewedDirs[dir] {
+			continue
+		}
+		for _, s := range shots {
+			if !s.reviewed {
+				orphans = append(orphans, s.files...)
+			}
+		}
+	}
+	sort.Strings(orphans)
+	return orphans, nil
+}
+
+// loadCullOrphans returns the set of files left out of the import in cull mode
+func loadCullOrphans(p *models.Params) (map[string]bool, error) {
+	if p.Cull == "" {
+		return nil, nil
+	}
+	orphans, err := FindCullOrphans(p.Source, p.Cull)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(orphans))
+	for _, path := range orphans {
+		set[path] = true
+	}
+	return set, nil
+}
+
+// cullFile leaves an orphaned file out of the import, deleting it from the
+// source when requested
+func (r *mediaRun) cullFile(path string, info os.FileInfo, summary *ProcessingSummary) {
+	reason := fmt.Sprintf("no matching %s file", strings.ToUpper(r.p.Cull))
+	entry := ReportEntry{Source: path, Status: ReportCulled, Reason: reason, Size: info.Size()}
+	summary.Culled++
+
+	if r.p.CullDelete {
+		if err := r.fs.Remove(path); err != nil {
+			summary.Failed++
+			output.Status("ERROR", fmt.Sprintf("Failed to delete culled file %s: %v", path, err))
+			r.finish(ReportEntry{Source: path, Status: ReportFailed, Reason: err.Error(), Size: info.Size()})
+			return
+		}
+		summary.Deleted++
+		output.Status("DELETED", fmt.Sprintf("%s (%s)", path, reason))
+	} else {
+		output.Status("CULLED", fmt.Sprintf("%s (%s)", path, reason))
+	}
+	r.finish(entry)
+}
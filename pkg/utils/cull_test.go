@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createCullSource creates empty media files below source
+func createCullSource(t *testing.T, source string, files []string) {
+	t.Helper()
+	for _, name := range files {
+		path := filepath.Join(source, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+}
+
+func TestFindCullOrphans(t *testing.T) {
+	files := []string{
+		"100CANON/IMG_0001.JPG",
+		"100CANON/IMG_0001.CR3",
+		"100CANON/IMG_0002.CR3", // JPEG deleted during review
+		"100CANON/img_0003.jpg",
+		"100CANON/IMG_0003.CR3",
+		"100CANON/IMG_0004.JPG", // RAW deleted during review
+		"100CANON/notes.txt",
+		"101CANON/IMG_0100.CR3", // RAW-only folder
+		"101CANON/IMG_0101.CR3",
+	}
+
+	tests := []struct {
+		reviewed string
+		want     []string
+	}{
+		{CullJPEG, []string{"100CANON/IMG_0002.CR3"}},
+		{CullRAW, []string{"100CANON/IMG_0004.JPG"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reviewed, func(t *testing.T) {
+			source := t.TempDir()
+			createCullSource(t, source, files)
+
+			orphans, err := FindCullOrphans(source, tt.reviewed)
+			if err != nil {
+				t.Fatalf("FindCullOrphans() error = %v", err)
+			}
+
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, filepath.Join(source, name))
+			}
+			if fmt.Sprint(orphans) != fmt.Sprint(want) {
+				t.Errorf("FindCullOrphans() = %v, want %v", orphans, want)
+			}
+		})
+	}
+
+	if _, err := FindCullOrphans(filepath.Join(t.TempDir(), "missing"), CullJPEG); err == nil {
+		t.Error("FindCullOrphans() expected error for a missing source")
+	}
+}
+
+func TestProcessMediaFilesCull(t *testing.T) {
+	tests := []struct {
+		name       string
+		cullDelete bool
+	}{
+		{"Skip orphans", false},
+		{"Delete orphans", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := t.TempDir()
+			destination := t.TempDir()
+			createCullSource(t, source, []string{"IMG_0001.CR3"})
+			if err := os.WriteFile(filepath.Join(source, "IMG_0002.JPG"), createFakeExifData(), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			orphan := filepath.Join(source, "IMG_0001.CR3")
+
+			params := &models.Params{
+				Source:        source,
+				Destination:   destination,
+				Compression:   -1,
+				SkipUserInput: true,
+				Cull:          CullJPEG,
+				CullDelete:    tt.cullDelete,
+				ReportFile:    filepath.Join(destination, "report.json"),
+			}
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+
+			if summary.Culled != 1 || summary.Copied != 1 {
+				t.Errorf("Expected 1 culled and 1 copied file, got %+v", summary)
+			}
+			if _, err := os.Stat(orphan); os.IsNotExist(err) != tt.cullDelete {
+				t.Errorf("Orphan deleted = %t, want %t", os.IsNotExist(err), tt.cullDelete)
+			}
+
+			report := readTestReport(t, params.ReportFile)
+			var culled int
+			for _, entry := range report.Files {
+				if entry.Status == ReportCulled && entry.Source == orphan {
+					culled++
+				}
+			}
+			if culled != 1 {
+				t.Errorf("Expected the orphan to be reported as culled, got %+v", report.Files)
+			}
+		})
+	}
+}
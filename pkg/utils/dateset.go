@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Layouts accepted for manually assigned dates
+var manualDateLayouts = []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05"}
+
+// ParseManualDate parses a capture date given by the user, such as
+// 2023-08-14 or 2023-08-14 18:30. Like EXIF dates, it has no time zone.
+func ParseManualDate(s string) (time.Time, error) {
+	for _, layout := range manualDateLayouts {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s (expected YYYY-MM-DD or YYYY-MM-DD HH:MM)", s)
+}
+
+// fileSet is a selection of source files, nil selecting every file
+type fileSet map[string]bool
+
+// newFileSet returns the selection of the given files, nil when there are none
+func newFileSet(files []string) fileSet {
+	if len(files) == 0 {
+		return nil
+	}
+	set := make(fileSet, len(files))
+	for _, f := range files {
+		set[filepath.Clean(f)] = true
+	}
+	return set
+}
+
+// has reports whether a file is selected
+func (s fileSet) has(path string) bool {
+	return s == nil || s[filepath.Clean(path)]
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseManualDate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2023-08-14", time.Date(2023, 8, 14, 0, 0, 0, 0, time.UTC), false},
+		{"2023-08-14 18:30", time.Date(2023, 8, 14, 18, 30, 0, 0, time.UTC), false},
+		{"2023-08-14 18:30:05", time.Date(2023, 8, 14, 18, 30, 5, 0, time.UTC), false},
+		{"14/08/2023", time.Time{}, true},
+		{"2023-13-01", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseManualDate(tt.input)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("ParseManualDate(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// ExifNamespace is the XMP namespace of EXIF properties
+const ExifNamespace = "http://ns.adobe.com/exif/1.0/"
+
+// Format of dates in XMP packets, local time without zone like EXIF dates
+const xmpTimeLayout = "2006-01-02T15:04:05"
+
+// exifDateTags are the EXIF tags cameras set to the capture date: the date
+// of the shot, of its digitization, and of the file in the main directory
+var exifDateTags = map[uint16]bool{TagDateTimeOriginal: true, TagDateTimeDigitized: true, TagDateTime: true}
+
+// xmpDateOriginal matches the DateTimeOriginal property of an XMP packet,
+// written as an attribute or as an element
+var xmpDateOriginal = regexp.MustCompile(`(exif:DateTimeOriginal=")[^"]*(")|(<exif:DateTimeOriginal>)[^<]*(</exif:DateTimeOriginal>)`)
+
+// correctedDate reports whether the date of a file differs from the one it
+// records: a date assigned manually, a camera clock corrected, or the UTC time
+// of a video converted to the local time of its GPS location. recorded is the
+// date read from the file, before clock offsets.
+func (r *mediaRun) correctedDate(path string, reader io.ReadSeeker, recorded, date time.Time, overridden, captured bool) bool {
+	if overridden {
+		return true
+	}
+	if !captured {
+		return false
+	}
+	if !date.Equal(recorded) {
+		return true
+	}
+	// Videos dated from their GPS location record UTC
+	if r.p.NoGPS || !videoExtensions[strings.ToLower(filepath.Ext(path))] {
+		return false
+	}
+	utc, err := mediaDate(r.fs, path, reader, true)
+	return err == nil && !utc.Equal(recorded)
+}
+
+// withCaptureDate returns a copy of a JPEG file carrying date as its capture
+// date: in the date tags of its EXIF metadata, and in its XMP packet when it
+// has no such tag or is recompressed, which drops the EXIF metadata.
+func withCaptureDate(data []byte, date time.Time, compressed bool) []byte {
+	out := append([]byte(nil), data...)
+	if patchExifDates(out, date) && !compressed {
+		return out
+	}
+	if updated, ok := updateJPEGXMP(out, func(xmp []byte) ([]byte, bool) {
+		return setXMPDate(xmp, date)
+	}, xmpPacket(dateDescription(date))); ok {
+		return updated
+	}
+	return out
+}
+
+// patchExifDates overwrites in place the date tags of the EXIF metadata of a
+// JPEG file with date, reporting whether the file has any. Dates keep their
+// fixed length, so nothing else in the file moves.
+func patchExifDates(data []byte, date time.Time) bool {
+	value := []byte(date.Format(ExifTimeLayout))
+	for _, segment := range jpegHeaderSegments(data) {
+		if segment.marker != 0xE1 || !bytes.HasPrefix(segment.data[4:], []byte(ExifIdentifier)) {
+			continue
+		}
+		tiff := segment.data[4+len(ExifIdentifier):]
+		if len(tiff) < TiffHeaderLength {
+			return false
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case BigEndianMarker:
+			order = binary.BigEndian
+		case LittleEndianMarker:
+			order = binary.LittleEndian
+		default:
+			return false
+		}
+
+		patched := false
+		offsets := []uint32{order.Uint32(tiff[4:8])}
+		for i := 0; i < len(offsets) && i < 2; i++ {
+			ifd := int(offsets[i])
+			if ifd+2 > len(tiff) {
+				continue
+			}
+			count := int(order.Uint16(tiff[ifd:]))
+			for e := ifd + 2; e+12 <= len(tiff) && e < ifd+2+12*count; e += 12 {
+				tag, fieldType, n := order.Uint16(tiff[e:]), order.Uint16(tiff[e+2:]), order.Uint32(tiff[e+4:])
+				at := int(order.Uint32(tiff[e+8:]))
+				switch {
+				case tag == TagExifIFDPointer:
+					offsets = append(offsets, order.Uint32(tiff[e+8:]))
+				case exifDateTags[tag] && fieldType == tiffTypeASCII && n >= uint32(len(value)) && at+len(value) <= len(tiff):
+					copy(tiff[at:], value)
+					patched = true
+				}
+			}
+		}
+		return patched
+	}
+	return false
+}
+
+// dateDescription returns the rdf:Description element of a capture date
+func dateDescription(date time.Time) []byte {
+	return []byte(fmt.Sprintf(`<rdf:Description rdf:about="" xmlns:exif="%s" exif:DateTimeOriginal="%s"/>`, ExifNamespace, date.Format(xmpTimeLayout)))
+}
+
+// setXMPDate returns an XMP packet with date as its DateTimeOriginal,
+// replacing the one it holds or adding it, and false when it is not valid
+func setXMPDate(xmp []byte, date time.Time) ([]byte, bool) {
+	value := date.Format(xmpTimeLayout)
+	if xmpDateOriginal.Match(xmp) {
+		return xmpDateOriginal.ReplaceAll(xmp, []byte("${1}${3}"+value+"${2}${4}")), true
+	}
+	return insertXMPDescription(xmp, dateDescription(date))
+}
+
+// writeDateSidecar writes date as the capture date of the file written to
+// destPath of fsys in the XMP sidecar next to it, the one provenance is
+// recorded in, keeping what the sidecar already holds
+func writeDateSidecar(fsys FileSystem, destPath string, date time.Time, enc *Encryptor) error {
+	packet := xmpPacket(dateDescription(date))
+	if data, err := readFile(fsys, enc.Path(destPath+ProvenanceSidecarExt)); err == nil {
+		if enc != nil {
+			_, data, err = enc.Open(data)
+		}
+		if err == nil {
+			if updated, ok := setXMPDate(data, date); ok {
+				packet = updated
+			}
+		}
+	}
+	return writeXMPSidecar(fsys, destPath, packet, enc)
+}
+
+// recordCaptureDate writes the corrected capture date of a file written to
+// destPath in its sidecar, warning when it cannot be written as the file
+// itself is imported
+func recordCaptureDate(fsys FileSystem, destPath string, date time.Time, enc *Encryptor) {
+	if err := writeDateSidecar(fsys, destPath, date, enc); err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to write the corrected date of %s: %v", enc.Path(destPath), err))
+	}
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestWithCaptureDate(t *testing.T) {
+	const recorded = "2024:06:11 15:30:10"
+	date := time.Date(2023, time.August, 14, 18, 30, 0, 0, time.UTC)
+	dated := createTestJPEG(buildTIFF(
+		[]testTag{asciiTag(TagDateTime, recorded)},
+		[]testTag{asciiTag(TagDateTimeOriginal, recorded), asciiTag(TagDateTimeDigitized, recorded)},
+	))
+
+	tests := []struct {
+		name       string
+		data       []byte
+		compressed bool
+		exifDates  int  // EXIF date tags holding the date
+		xmpDate    bool // Date in the XMP packet
+		faces      int  // Face regions of the original packet still found
+	}{
+		{name: "exif dates", data: dated, exifDates: 3},
+		{name: "recompressed", data: dated, compressed: true, exifDates: 3, xmpDate: true},
+		{name: "no exif date", data: createTestJPEG(buildTIFF([]testTag{asciiTag(TagModel, "ILCE-7M3")}, nil)), xmpDate: true},
+		{name: "merged into XMP", data: createXMPJPEG(t, testXMP), xmpDate: true, faces: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := append([]byte(nil), tt.data...)
+			got := withCaptureDate(tt.data, date, tt.compressed)
+			if !bytes.Equal(tt.data, original) {
+				t.Errorf("withCaptureDate() changed the original data")
+			}
+
+			if n := bytes.Count(got, []byte("2023:08:14 18:30:00")); n != tt.exifDates {
+				t.Errorf("EXIF date tags holding the date = %d, want %d", n, tt.exifDates)
+			}
+			if tt.exifDates > 0 && bytes.Contains(got, []byte(recorded)) {
+				t.Errorf("Expected no EXIF date tag left with the recorded date")
+			}
+			xmp := ExtractXMP(got, "photo.jpg")
+			if found := bytes.Contains(xmp, []byte(`exif:DateTimeOriginal="2023-08-14T18:30:00"`)); found != tt.xmpDate {
+				t.Errorf("XMP date found = %v, want %v in %q", found, tt.xmpDate, xmp)
+			}
+			if faces := FaceRegions(xmp); len(faces) != tt.faces {
+				t.Errorf("FaceRegions() = %+v, want %d regions", faces, tt.faces)
+			}
+		})
+	}
+}
+
+func TestSetXMPDate(t *testing.T) {
+	date := time.Date(2023, time.August, 14, 18, 30, 0, 0, time.UTC)
+	const packet = `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">%s</rdf:RDF></x:xmpmeta>`
+
+	tests := []struct {
+		name   string
+		xmp    string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "attribute replaced",
+			xmp:    strings.Replace(packet, "%s", `<rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/" exif:DateTimeOriginal="2024-06-11T15:30:10"/>`, 1),
+			want:   `exif:DateTimeOriginal="2023-08-14T18:30:00"`,
+			wantOK: true,
+		},
+		{
+			name:   "element replaced",
+			xmp:    strings.Replace(packet, "%s", `<rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/"><exif:DateTimeOriginal>2024-06-11T15:30:10</exif:DateTimeOriginal></rdf:Description>`, 1),
+			want:   `<exif:DateTimeOriginal>2023-08-14T18:30:00</exif:DateTimeOriginal>`,
+			wantOK: true,
+		},
+		{
+			name:   "added",
+			xmp:    strings.Replace(packet, "%s", `<rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/"/>`, 1),
+			want:   `exif:DateTimeOriginal="2023-08-14T18:30:00"`,
+			wantOK: true,
+		},
+		{name: "not a packet", xmp: "garbage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := setXMPDate([]byte(tt.xmp), date)
+			if ok != tt.wantOK {
+				t.Fatalf("setXMPDate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (!bytes.Contains(got, []byte(tt.want)) || bytes.Contains(got, []byte("2024-06-11"))) {
+				t.Errorf("setXMPDate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesWriteDates(t *testing.T) {
+	for _, writeDates := range []bool{false, true} {
+		t.Run(map[bool]string{false: "off", true: "on"}[writeDates], func(t *testing.T) {
+			source := t.TempDir()
+			destination := t.TempDir()
+			jpg := filepath.Join(source, "DSC00001.JPG")
+			raw := filepath.Join(source, "DSC00001.ARW")
+			jpgData := createDatedJPEG(t, "2024:06:11 15:30:10")
+			rawData := buildTIFF([]testTag{asciiTag(TagDateTime, "2024:06:11 15:30:10")}, nil)
+			if err := os.WriteFile(jpg, jpgData, 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			if err := os.WriteFile(raw, rawData, 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			date := time.Date(2023, time.August, 14, 18, 30, 0, 0, time.Local)
+			params := &models.Params{
+				Source:        source,
+				Destination:   destination,
+				Compression:   -1,
+				DateOverrides: map[string]time.Time{jpg: date, raw: date},
+				WriteDates:    writeDates,
+			}
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if summary.Copied != 2 {
+				t.Fatalf("Copied = %d, want 2", summary.Copied)
+			}
+
+			dir := filepath.Join(destination, "2023", "08-14")
+			written, err := os.ReadFile(filepath.Join(dir, "DSC00001.JPG"))
+			if err != nil {
+				t.Fatalf("Expected organized file: %v", err)
+			}
+			if writeDates {
+				if got, err := GetImageDateTime(written, ".jpg"); err != nil || got.Format(ExifTimeLayout) != "2023:08:14 18:30:00" {
+					t.Errorf("date of the organized file = %v, %v, want the manual date", got, err)
+				}
+			} else if !bytes.Equal(written, jpgData) {
+				t.Errorf("Expected the file copied as is without -write-dates")
+			}
+
+			rawPath := filepath.Join(dir, "DSC00001.ARW")
+			if written, err := os.ReadFile(rawPath); err != nil || !bytes.Equal(written, rawData) {
+				t.Errorf("Expected the RAW file copied as is: %v", err)
+			}
+			sidecar, err := os.ReadFile(rawPath + ProvenanceSidecarExt)
+			if (err == nil) != writeDates {
+				t.Fatalf("sidecar read error = %v, want a sidecar %v", err, writeDates)
+			}
+			if writeDates && !bytes.Contains(sidecar, []byte(`exif:DateTimeOriginal="2023-08-14T18:30:00"`)) {
+				t.Errorf("sidecar = %q, want the manual date", sidecar)
+			}
+
+			// The files written by a first run are found identical by the next one
+			summary, err = ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if summary.Skips.ExistsIdentical != 2 {
+				t.Errorf("Skips = %+v, want 2 identical", summary.Skips)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rules choosing the copy kept of a group of duplicates
+const (
+	KeepFirst    = "first"    // The first path in alphabetical order, the earliest folder of date layouts
+	KeepShortest = "shortest" // The shortest path, leaving out copies such as DSC00001 (1).JPG
+	KeepOldest   = "oldest"   // The file modified first
+)
+
+// KeepRules lists the rules choosing the copy kept of a group of duplicates
+var KeepRules = map[string]bool{
+	KeepFirst:    true,
+	KeepShortest: true,
+	KeepOldest:   true,
+}
+
+// DedupeJournalName is the journal of removed duplicates, kept in the
+// destination by default. Hidden, it is never taken for a duplicate.
+const DedupeJournalName = ".organize-media-dedupe.jsonl"
+
+// DuplicateGroup is a set of files of the destination with the same content
+type DuplicateGroup struct {
+	Hash  string
+	Size  int64    // Size of each copy
+	Files []string // Copies, in alphabetical order
+}
+
+// Wasted returns the space taken by the copies beyond the first one
+func (g DuplicateGroup) Wasted() int64 {
+	return g.Size * int64(len(g.Files)-1)
+}
+
+// FindDuplicates returns the groups of files of the destination with the
+// same content, wasting the most space first. Files are grouped by the
+// content hash of their catalog records when catalogFile is set. Otherwise
+// the destination is walked and files of the same size are hashed with algo.
+// Hidden files and folders, which hold the state of the tool, are left out.
+func FindDuplicates(destination, catalogFile, algo string) ([]DuplicateGroup, error) {
+	var groups map[string]*DuplicateGroup
+	var err error
+	if catalogFile != "" {
+		groups, err = catalogDuplicates(catalogFile)
+	} else {
+		groups, err = walkDuplicates(destination, algo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicates []DuplicateGroup
+	for _, group := range groups {
+		if len(group.Files) > 1 {
+			sort.Strings(group.Files)
+			duplicates = append(duplicates, *group)
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		if duplicates[i].Wasted() != duplicates[j].Wasted() {
+			return duplicates[i].Wasted() > duplicates[j].Wasted()
+		}
+		return duplicates[i].Files[0] < duplicates[j].Files[0]
+	})
+	return duplicates, nil
+}
+
+// catalogDuplicates groups the files recorded in a catalog by content hash,
+// leaving out those no longer in the destination
+func catalogDuplicates(catalogFile string) (map[string]*DuplicateGroup, error) {
+	catalog, err := LoadCatalog(catalogFile)
+	if err != nil {
+		return nil, err
+	}
+	defer catalog.Close()
+
+	groups := make(map[string]*DuplicateGroup)
+	seen := make(map[string]bool)
+	for _, record := range catalog.AllRecords() {
+		if seen[record.Destination] {
+			continue
+		}
+		seen[record.Destination] = true
+
+		info, err := os.Stat(record.Destination)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		group, ok := groups[record.Hash]
+		if !ok {
+			group = &DuplicateGroup{Hash: record.Hash, Size: info.Size()}
+			groups[record.Hash] = group
+		}
+		group.Files = append(group.Files, record.Destination)
+	}
+	return groups, nil
+}
+
+// walkDuplicates groups the files of the destination by content hash. Only
+// files sharing their size with another one can be duplicates, they alone
+// are hashed.
+func walkDuplicates(destination, algo string) (map[string]*DuplicateGroup, error) {
+	bySize := make(map[int64][]string)
+	err := filepath.Walk(destination, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if file != destination && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() && info.Size() > 0 && !strings.HasPrefix(info.Name(), ".") {
+			bySize[info.Size()] = append(bySize[info.Size()], file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk destination: %w", err)
+	}
+
+	groups := make(map[string]*DuplicateGroup)
+	for size, files := range bySize {
+		if len(files) < 2 {
+			continue
+		}
+		for _, file := range files {
+			hash, err := hashFile(file, algo)
+			if err != nil {
+				return nil, err
+			}
+			group, ok := groups[hash]
+			if !ok {
+				group = &DuplicateGroup{Hash: hash, Size: size}
+				groups[hash] = group
+			}
+			group.Files = append(group.Files, file)
+		}
+	}
+	return groups, nil
+}
+
+// Keeper returns the index in Files of the copy kept by rule
+func (g DuplicateGroup) Keeper(rule string) (int, error) {
+	keeper := 0
+	switch rule {
+	case KeepFirst:
+	case KeepShortest:
+		for i, file := range g.Files {
+			if len(file) < len(g.Files[keeper]) {
+				keeper = i
+			}
+		}
+	case KeepOldest:
+		var oldest time.Time
+		for i, file := range g.Files {
+			info, err := os.Stat(file)
+			if err != nil {
+				return 0, err
+			}
+			if i == 0 || info.ModTime().Before(oldest) {
+				keeper, oldest = i, info.ModTime()
+			}
+		}
+	default:
+		return 0, fmt.Errorf("unsupported keep rule: %s (expected first, shortest or oldest)", rule)
+	}
+	return keeper, nil
+}
+
+// DedupeJournalEntry records a removed duplicate and the copy kept in its
+// place, from which it can be restored
+type DedupeJournalEntry struct {
+	Removed   string    `json:"removed"`
+	Kept      string    `json:"kept"`
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	RemovedAt time.Time `json:"removed_at"`
+}
+
+// RemoveDuplicate deletes the duplicate removed of the file kept, once their
+// contents are checked again to be the same. The removal is recorded in the
+// journal first, so an interrupted cleanup can always be restored. Paths
+// naming the same file, such as hard links or a path through a symlinked
+// folder, are refused: removing one would leave no copy to restore from.
+func RemoveDuplicate(journal, kept, removed string, group DuplicateGroup, algo string) error {
+	if kept == removed {
+		return fmt.Errorf("cannot remove the kept copy %s", kept)
+	}
+	keptInfo, err := os.Stat(kept)
+	if err != nil {
+		return err
+	}
+	removedInfo, err := os.Stat(removed)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(keptInfo, removedInfo) {
+		return fmt.Errorf("%s is the kept copy %s, not removed", removed, kept)
+	}
+	// Catalog hashes are those of the sources, the copies themselves are compared
+	if same, err := sameContent(kept, removed, algo); err != nil {
+		return err
+	} else if !same {
+		return fmt.Errorf("%s no longer has the content of %s, not removed", removed, kept)
+	}
+
+	line, err := json.Marshal(DedupeJournalEntry{
+		Removed:   removed,
+		Kept:      kept,
+		Hash:      group.Hash,
+		Size:      group.Size,
+		RemovedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(journal, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dedupe journal: %w", err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write dedupe journal: %w", err)
+	}
+
+	return os.Remove(removed)
+}
+
+// RestoreDuplicates copies back the duplicates recorded in the journal from
+// the copies kept in their place, returning how many were restored. Files
+// present again are left alone.
+func RestoreDuplicates(journal string) (int, error) {
+	file, err := os.Open(journal)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	restored := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry DedupeJournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return restored, fmt.Errorf("invalid dedupe journal entry: %w", err)
+		}
+		if exists, err := fileExists(OSFileSystem{}, entry.Removed); err != nil {
+			return restored, err
+		} else if exists {
+			continue
+		}
+
+		info, err := os.Stat(entry.Kept)
+		if err != nil {
+			return restored, fmt.Errorf("cannot restore %s: %w", entry.Removed, err)
+		}
+		if err := copyFileAtomic(entry.Kept, entry.Removed, info.ModTime()); err != nil {
+			return restored, err
+		}
+		restored++
+	}
+	return restored, scanner.Err()
+}
@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// createDedupeDestination creates files of a destination with their content
+func createDedupeDestination(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dest := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dest, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return dest
+}
+
+func TestFindDuplicates(t *testing.T) {
+	dest := createDedupeDestination(t, map[string]string{
+		"2024/06-11/DSC00001.JPG":     "first picture",
+		"2024/06-12/DSC00001 (1).JPG": "first picture",
+		"2025/01-02/IMG_0001.JPG":     "first picture",
+		"2024/06-11/DSC00002.JPG":     "other picture", // Same size, other content
+		"2024/06-11/clip.MP4":         "a longer video clip",
+		"2024/06-12/clip.MP4":         "a longer video clip",
+		".organize-media/state.json":  "first picture", // State of the tool
+	})
+	path := func(name string) string { return filepath.Join(dest, filepath.FromSlash(name)) }
+
+	groups, err := FindDuplicates(dest, "", HashSHA256)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	want := []DuplicateGroup{
+		{Hash: HashBuffer([]byte("first picture"), HashSHA256), Size: 13, Files: []string{path("2024/06-11/DSC00001.JPG"), path("2024/06-12/DSC00001 (1).JPG"), path("2025/01-02/IMG_0001.JPG")}},
+		{Hash: HashBuffer([]byte("a longer video clip"), HashSHA256), Size: 19, Files: []string{path("2024/06-11/clip.MP4"), path("2024/06-12/clip.MP4")}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("FindDuplicates() = %+v, want %+v", groups, want)
+	}
+	if wasted := groups[0].Wasted(); wasted != 26 {
+		t.Errorf("Wasted() = %d, want 26", wasted)
+	}
+}
+
+func TestFindDuplicatesCatalog(t *testing.T) {
+	dest := createDedupeDestination(t, map[string]string{
+		"2024/06-11/DSC00001.JPG":     "compressed once",
+		"2024/06-12/DSC00001 (1).JPG": "compressed twice",
+	})
+	path := func(name string) string { return filepath.Join(dest, filepath.FromSlash(name)) }
+
+	// Records share the hash of their source, whatever the content written
+	catalogFile := filepath.Join(t.TempDir(), "catalog.jsonl")
+	catalog, err := OpenCatalog(catalogFile)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error = %v", err)
+	}
+	for _, record := range []CatalogRecord{
+		{Hash: "abc", Destination: path("2024/06-11/DSC00001.JPG")},
+		{Hash: "abc", Destination: path("2024/06-12/DSC00001 (1).JPG")},
+		{Hash: "abc", Destination: path("2024/06-13/deleted.JPG")},
+		{Hash: "def", Destination: path("2024/06-11/DSC00002.JPG")},
+	} {
+		if err := catalog.Add(record); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	catalog.Close()
+
+	groups, err := FindDuplicates(dest, catalogFile, HashSHA256)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if len(groups) != 1 || groups[0].Hash != "abc" || len(groups[0].Files) != 2 {
+		t.Errorf("FindDuplicates() = %+v, want the 2 files of hash abc", groups)
+	}
+}
+
+func TestDuplicateGroupKeeper(t *testing.T) {
+	dest := createDedupeDestination(t, map[string]string{
+		"2024/06-11/DSC00001 (1).JPG": "picture",
+		"2024/06-12/DSC00001.JPG":     "picture",
+		"2025/DSC00001.JPG":           "picture",
+	})
+	group := DuplicateGroup{Size: 7, Files: []string{
+		filepath.Join(dest, "2024", "06-11", "DSC00001 (1).JPG"),
+		filepath.Join(dest, "2024", "06-12", "DSC00001.JPG"),
+		filepath.Join(dest, "2025", "DSC00001.JPG"),
+	}}
+	old := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(group.Files[1], old, old); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	tests := []struct {
+		rule    string
+		want    int
+		wantErr bool
+	}{
+		{rule: KeepFirst, want: 0},
+		{rule: KeepShortest, want: 2},
+		{rule: KeepOldest, want: 1},
+		{rule: "largest", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := group.Keeper(tt.rule)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Keeper(%s) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("Keeper(%s) = %d, want %d", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveAndRestoreDuplicates(t *testing.T) {
+	dest := createDedupeDestination(t, map[string]string{
+		"2024/06-11/DSC00001.JPG":     "picture",
+		"2024/06-12/DSC00001 (1).JPG": "picture",
+		"2024/06-12/DSC00002.JPG":     "changed",
+	})
+	kept := filepath.Join(dest, "2024", "06-11", "DSC00001.JPG")
+	removed := filepath.Join(dest, "2024", "06-12", "DSC00001 (1).JPG")
+	changed := filepath.Join(dest, "2024", "06-12", "DSC00002.JPG")
+	journal := filepath.Join(dest, DedupeJournalName)
+	group := DuplicateGroup{Hash: "abc", Size: 7, Files: []string{kept, removed, changed}}
+
+	// A copy whose content changed since the report is never removed
+	if err := RemoveDuplicate(journal, kept, changed, group, HashSHA256); err == nil {
+		t.Errorf("RemoveDuplicate() removed a file of another content")
+	}
+	if err := RemoveDuplicate(journal, kept, kept, group, HashSHA256); err == nil {
+		t.Errorf("RemoveDuplicate() removed the kept copy")
+	}
+	// Another path to the kept copy is the same file, not a duplicate
+	link := filepath.Join(dest, "link")
+	if err := os.Symlink(filepath.Dir(kept), link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	aliased := filepath.Join(link, filepath.Base(kept))
+	if err := RemoveDuplicate(journal, kept, aliased, group, HashSHA256); err == nil {
+		t.Errorf("RemoveDuplicate() removed the kept copy through a symlinked folder")
+	}
+	hardLink := filepath.Join(dest, "hardlink.jpg")
+	if err := os.Link(kept, hardLink); err != nil {
+		t.Fatalf("Failed to create hard link: %v", err)
+	}
+	if err := RemoveDuplicate(journal, kept, hardLink, group, HashSHA256); err == nil {
+		t.Errorf("RemoveDuplicate() removed a hard link of the kept copy")
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Fatalf("Expected the kept copy to remain: %v", err)
+	}
+	if err := RemoveDuplicate(journal, kept, removed, group, HashSHA256); err != nil {
+		t.Fatalf("RemoveDuplicate() error = %v", err)
+	}
+	if _, err := os.Stat(removed); !os.IsNotExist(err) {
+		t.Errorf("Expected the duplicate removed, got %v", err)
+	}
+	if _, err := os.Stat(changed); err != nil {
+		t.Errorf("Expected the changed file kept: %v", err)
+	}
+
+	restored, err := RestoreDuplicates(journal)
+	if err != nil {
+		t.Fatalf("RestoreDuplicates() error = %v", err)
+	}
+	if restored != 1 {
+		t.Errorf("RestoreDuplicates() = %d, want 1", restored)
+	}
+	if data, err := os.ReadFile(removed); err != nil || string(data) != "picture" {
+		t.Errorf("restored file = %q, %v, want picture", data, err)
+	}
+
+	// Files present again are not restored twice
+	if restored, err := RestoreDuplicates(journal); err != nil || restored != 0 {
+		t.Errorf("RestoreDuplicates() = %d, %v, want 0", restored, err)
+	}
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// PartDirPrefix names the subfolders receiving the files of a full
+// destination directory: part-2/, part-3/...
+const PartDirPrefix = "part-"
+
+// dirLimiter caps the number of files per destination directory, for file
+// systems and NAS that cannot hold many entries in one directory (FAT32).
+// Files beyond the cap go to part-2/, part-3/... subfolders of the directory.
+type dirLimiter struct {
+	max      int
+	fs       FileSystem
+	enc      *Encryptor
+	mu       sync.Mutex
+	counts   map[string]int  // Files of a directory, on disk or placed by this run
+	reserved map[string]bool // Destinations placed by this run, maybe not written yet
+}
+
+// newDirLimiter returns the directory limiter of a run, nil when there is no cap
+func newDirLimiter(fsys FileSystem, p *models.Params, enc *Encryptor) *dirLimiter {
+	if p.MaxFilesPerDir <= 0 {
+		return nil
+	}
+	return &dirLimiter{max: p.MaxFilesPerDir, fs: fsys, enc: enc, counts: make(map[string]int), reserved: make(map[string]bool)}
+}
+
+// place returns where a file planned at destPath is organized: destPath while
+// its directory has room, or the first part subfolder that has. A file found
+// in the directory or one of its parts keeps that place, so the exists check
+// skips it instead of copying it again. A nil limiter returns destPath.
+func (l *dirLimiter) place(destPath string) string {
+	if l == nil {
+		return destPath
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if candidate, ok := l.find(destPath); ok {
+		return candidate
+	}
+
+	dir, name := filepath.Dir(destPath), filepath.Base(destPath)
+	for part := 1; ; part++ {
+		d := partDir(dir, part)
+		if l.count(d) < l.max {
+			candidate := filepath.Join(d, name)
+			l.counts[d]++
+			l.reserved[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// lookup returns where a file planned at destPath was placed by this run or
+// is found on disk, without placing it. A nil limiter returns destPath.
+func (l *dirLimiter) lookup(destPath string) (string, bool) {
+	if l == nil {
+		return destPath, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.find(destPath)
+}
+
+// find returns the part of the directory of destPath holding its file, with l.mu held
+func (l *dirLimiter) find(destPath string) (string, bool) {
+	dir, name := filepath.Dir(destPath), filepath.Base(destPath)
+	for part := 1; part == 1 || l.partExists(dir, part); part++ {
+		candidate := filepath.Join(partDir(dir, part), name)
+		if l.reserved[candidate] {
+			return candidate, true
+		}
+		if exists, _ := fileExists(l.fs, l.enc.Path(candidate)); exists {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// partExists reports whether a part subfolder exists on disk or was used by this run
+func (l *dirLimiter) partExists(dir string, part int) bool {
+	d := partDir(dir, part)
+	if _, ok := l.counts[d]; ok {
+		return true
+	}
+	info, err := l.fs.Stat(d)
+	return err == nil && info.IsDir()
+}
+
+// count returns the number of files of a directory, reading it the first time
+func (l *dirLimiter) count(dir string) int {
+	if n, ok := l.counts[dir]; ok {
+		return n
+	}
+
+	n := 0
+	entries, _ := l.fs.ReadDir(dir)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			n++
+		}
+	}
+	l.counts[dir] = n
+	return n
+}
+
+// partDir returns the directory of a part, the directory itself being the first part
+func partDir(dir string, part int) string {
+	if part == 1 {
+		return dir
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s%d", PartDirPrefix, part))
+}
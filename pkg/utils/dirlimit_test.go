@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestProcessMediaFilesMaxFilesPerDir(t *testing.T) {
+	source := t.TempDir()
+	for i := 1; i <= 5; i++ {
+		createBracketFrame(t, filepath.Join(source, fmt.Sprintf("IMG_%04d.JPG", i)), "A7", "2024:06:11 15:30:00", 0)
+	}
+	dest := t.TempDir()
+	params := &models.Params{Source: source, Destination: dest, Compression: -1, MaxFilesPerDir: 2}
+
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 5 {
+		t.Errorf("Expected 5 copied files, got %+v", summary)
+	}
+
+	day := filepath.Join(dest, "2024", "06-11")
+	for dir, want := range map[string]int{day: 2, filepath.Join(day, "part-2"): 2, filepath.Join(day, "part-3"): 1} {
+		if got := countTestFiles(t, dir); got != want {
+			t.Errorf("%s holds %d files, want %d", dir, got, want)
+		}
+	}
+
+	// Files already organized in a part are found there again
+	summary, err = ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() second run error = %v", err)
+	}
+	if summary.Skipped != 5 || summary.Copied != 0 {
+		t.Errorf("Expected 5 skipped files on the second run, got %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(day, "part-4")); !os.IsNotExist(err) {
+		t.Error("Second run should not create another part")
+	}
+
+	// The plan places files the same way
+	plan, err := PlanMediaFiles(&models.Params{Source: source, Destination: t.TempDir(), Compression: -1, MaxFilesPerDir: 4})
+	if err != nil {
+		t.Fatalf("PlanMediaFiles() error = %v", err)
+	}
+	parts := 0
+	for _, planned := range plan {
+		if filepath.Base(filepath.Dir(planned.Destination)) == "part-2" {
+			parts++
+		}
+	}
+	if parts != 1 {
+		t.Errorf("Expected 1 planned file in part-2, got %d", parts)
+	}
+}
+
+// countTestFiles returns the number of files of a directory, without subfolders
+func countTestFiles(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", dir, err)
+	}
+	n := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			n++
+		}
+	}
+	return n
+}
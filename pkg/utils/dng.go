@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// DNGExt is the extension of the files written by DNG converters
+const DNGExt = ".dng"
+
+// DNGOriginalsDir is the destination folder holding the original RAW files of
+// converted ones, laid out like the destination, unless another folder is set
+const DNGOriginalsDir = "raw-originals"
+
+// Placeholders of DNG commands, replaced with the RAW file, the DNG file to
+// write, and its folder and name for converters taking them apart
+const (
+	dngOutPlaceholder  = "{out}"
+	dngDirPlaceholder  = "{dir}"
+	dngNamePlaceholder = "{name}"
+)
+
+// For testing purposes
+var runDNGCommand = func(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// dngFormats returns the extensions of the RAW files converted by a run, every
+// RAW format when none is given
+func dngFormats(p *models.Params) map[string]bool {
+	formats := make(map[string]bool)
+	for _, format := range strings.Split(p.DNGFormats, ",") {
+		if format = strings.ToLower(strings.TrimSpace(format)); format != "" {
+			formats["."+strings.TrimPrefix(format, ".")] = true
+		}
+	}
+	if len(formats) == 0 {
+		for format := range models.RawFormats {
+			formats["."+format] = true
+		}
+	}
+	return formats
+}
+
+// convertsToDNG reports whether a file is converted to DNG by the run
+func convertsToDNG(p *models.Params, path string) bool {
+	return p.DNGCommand != "" && dngFormats(p)[strings.ToLower(filepath.Ext(path))]
+}
+
+// dngOriginalDestination returns where the original of a RAW file organized at
+// destPath is kept: the same place in the originals folder of the run, or in
+// the raw-originals folder of its destination root
+func dngOriginalDestination(p *models.Params, destPath string, date time.Time) string {
+	root := destinationRoot(p, date)
+	rel, err := filepath.Rel(root, destPath)
+	if err != nil {
+		rel = filepath.Base(destPath)
+	}
+	if p.DNGOriginals != "" {
+		return filepath.Join(p.DNGOriginals, rel)
+	}
+	return filepath.Join(root, DNGOriginalsDir, rel)
+}
+
+// convertDNG runs the DNG command of a run on a RAW file, such as
+// "dnglab convert {} {out}" or "dngconverter -c -d {dir} -o {name} {}". The
+// command is split on spaces and run without a shell; {} is replaced with the
+// RAW file, appended when absent, and {out}, {dir} and {name} with the DNG
+// file to write, in a temporary folder next to target. It returns the DNG.
+func convertDNG(command, path, target string) ([]byte, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("empty DNG command")
+	}
+
+	// Converters write to a folder of their own, removed once the DNG is read
+	parent := tempDirFor(target)
+	if parent != "" {
+		if err := os.MkdirAll(parent, os.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+	dir, err := os.MkdirTemp(parent, "dng-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + DNGExt
+	out := filepath.Join(dir, name)
+	replacer := strings.NewReplacer(checkPlaceholder, path, dngOutPlaceholder, out, dngDirPlaceholder, dir, dngNamePlaceholder, name)
+
+	args, placed := make([]string, 0, len(fields)), false
+	for _, field := range fields[1:] {
+		placed = placed || strings.Contains(field, checkPlaceholder)
+		args = append(args, replacer.Replace(field))
+	}
+	if !placed {
+		args = append(args, path)
+	}
+
+	printed, err := runDNGCommand(fields[0], args...)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		reason := strings.TrimSpace(strings.SplitN(strings.TrimSpace(string(printed)), "\n", 2)[0])
+		if reason == "" {
+			reason = fmt.Sprintf("exit status %d", exitErr.ExitCode())
+		}
+		return nil, fmt.Errorf("DNG command failed: %s", reason)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to run DNG command: %w", err)
+	}
+
+	dng, err := os.ReadFile(out)
+	if err != nil {
+		return nil, errors.New("DNG command wrote no DNG file")
+	}
+	if len(dng) == 0 {
+		return nil, errors.New("DNG command wrote an empty DNG file")
+	}
+	return dng, nil
+}
+
+// toDNG converts a RAW file organized at destPath, keeping raw, its content,
+// in the originals tree first. It returns where the DNG is organized and its
+// content, raw when the DNG is already there.
+func (r *mediaRun) toDNG(path, destPath string, date time.Time, raw []byte) (string, []byte, error) {
+	dngPath := strings.TrimSuffix(destPath, filepath.Ext(destPath)) + DNGExt
+	if exists, err := fileExists(r.fs, r.enc.Path(dngPath)); err != nil {
+		return "", nil, err
+	} else if exists {
+		return dngPath, raw, nil
+	}
+
+	dng, err := convertDNG(r.p.DNGCommand, path, dngPath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The original is safe before the DNG is written and the source possibly deleted
+	original := dngOriginalDestination(r.p, destPath, date)
+	if exists, err := fileExists(r.fs, r.enc.Path(original)); err != nil {
+		return "", nil, err
+	} else if !exists {
+		sealed, err := r.enc.Seal(filepath.Base(original), raw)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(original), os.ModePerm); err != nil {
+			return "", nil, err
+		}
+		if err := writeFileAtomic(r.enc.Path(original), sealed, 0644); err != nil {
+			return "", nil, fmt.Errorf("failed to keep the original: %w", err)
+		}
+	}
+	return dngPath, dng, nil
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// fakeDNGConverter stands in for a converter writing "DNG" followed by the
+// RAW file to {out}, failing on RAW files named in failing
+func fakeDNGConverter(t *testing.T, failing ...string) func(name string, args ...string) ([]byte, error) {
+	t.Helper()
+	return func(name string, args ...string) ([]byte, error) {
+		raw, err := os.ReadFile(args[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range failing {
+			if filepath.Base(args[0]) == name {
+				return nil, errors.New("unsupported camera")
+			}
+		}
+		return nil, os.WriteFile(args[1], append([]byte("DNG"), raw...), 0644)
+	}
+}
+
+func TestDNGFormats(t *testing.T) {
+	tests := []struct {
+		formats string
+		path    string
+		want    bool
+	}{
+		{"", "DSC00001.ARW", true},
+		{"", "IMG_0001.CR3", true},
+		{"", "IMG_0001.DNG", false},
+		{"", "IMG_0001.JPG", false},
+		{"cr2, .NEF", "DSC_0001.nef", true},
+		{"cr2,nef", "DSC00001.ARW", false},
+	}
+	for _, tt := range tests {
+		p := &models.Params{DNGCommand: "dnglab convert {} {out}", DNGFormats: tt.formats}
+		if got := convertsToDNG(p, tt.path); got != tt.want {
+			t.Errorf("convertsToDNG(%q, %s) = %v, want %v", tt.formats, tt.path, got, tt.want)
+		}
+	}
+	if convertsToDNG(&models.Params{}, "DSC00001.ARW") {
+		t.Error("convertsToDNG() without command = true")
+	}
+}
+
+func TestConvertDNGArguments(t *testing.T) {
+	original := runDNGCommand
+	defer func() { runDNGCommand = original }()
+
+	// The temporary folder of the conversion is shown as <dir>
+	temp := regexp.MustCompile(`[^=]*dng-[0-9]+`)
+	var got []string
+	runDNGCommand = func(name string, args ...string) ([]byte, error) {
+		got = []string{name}
+		for _, arg := range args {
+			got = append(got, filepath.ToSlash(temp.ReplaceAllString(arg, "<dir>")))
+		}
+		return nil, nil
+	}
+
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"dnglab convert {} {out}", []string{"dnglab", "convert", "/card/DSC00001.ARW", "<dir>/DSC00001.dng"}},
+		{"dngconverter -c -d {dir} -o {name} {}", []string{"dngconverter", "-c", "-d", "<dir>", "-o", "DSC00001.dng", "/card/DSC00001.ARW"}},
+		{"convert --to={out}", []string{"convert", "--to=<dir>/DSC00001.dng", "/card/DSC00001.ARW"}},
+	}
+	for _, tt := range tests {
+		_, err := convertDNG(tt.command, "/card/DSC00001.ARW", filepath.Join(t.TempDir(), "DSC00001.dng"))
+		if err == nil || !strings.Contains(err.Error(), "wrote no DNG file") {
+			t.Errorf("convertDNG(%q) error = %v, want no DNG file written", tt.command, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("convertDNG(%q) ran %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestProcessMediaFilesDNG(t *testing.T) {
+	original := runDNGCommand
+	defer func() { runDNGCommand = original }()
+	runDNGCommand = fakeDNGConverter(t, "DSC00002.ARW")
+
+	source := t.TempDir()
+	files := map[string][]byte{
+		"DSC00001.ARW": createSerialJPEG("2025:01:11 10:00:00", "1"),
+		"DSC00002.ARW": createSerialJPEG("2025:01:11 11:00:00", "1"), // Conversion fails
+		"DSC00003.JPG": createSerialJPEG("2025:01:11 12:00:00", "1"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(source, name), data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	dest := t.TempDir()
+	// The catalog proves converted files already organized match their source
+	params := &models.Params{Source: source, Destination: dest, Compression: -1, DNGCommand: "fake {} {out}", ReportFile: filepath.Join(t.TempDir(), "report.json"), CatalogFile: filepath.Join(t.TempDir(), "catalog.jsonl")}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join("2025", "01-11", "DSC00001.dng"),
+		filepath.Join("2025", "01-11", "DSC00002.ARW"),
+		filepath.Join("2025", "01-11", "DSC00003.JPG"),
+		filepath.Join(DNGOriginalsDir, "2025", "01-11", "DSC00001.ARW"),
+	}
+	if got := organizedFiles(t, dest); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Organized files = %v, want %v", got, want)
+	}
+	dng, _ := os.ReadFile(filepath.Join(dest, want[0]))
+	if !bytes.Equal(dng, append([]byte("DNG"), files["DSC00001.ARW"]...)) {
+		t.Errorf("DNG file holds %d bytes, not the converted RAW file", len(dng))
+	}
+	if kept, _ := os.ReadFile(filepath.Join(dest, want[3])); !bytes.Equal(kept, files["DSC00001.ARW"]) {
+		t.Errorf("Original holds %d bytes, want the RAW file", len(kept))
+	}
+	if summary.Copied != 3 {
+		t.Errorf("Copied %d files, want 3", summary.Copied)
+	}
+	for _, entry := range readTestReport(t, params.ReportFile).Files {
+		wantOriginal := ""
+		if filepath.Base(entry.Source) == "DSC00001.ARW" {
+			wantOriginal = filepath.Join(dest, want[3])
+		}
+		if entry.Original != wantOriginal {
+			t.Errorf("Report entry of %s has original %q, want %q", entry.Source, entry.Original, wantOriginal)
+		}
+	}
+
+	// DNG files already organized are not converted again
+	runDNGCommand = func(name string, args ...string) ([]byte, error) {
+		if filepath.Base(args[0]) != "DSC00002.ARW" {
+			t.Errorf("DNG command run again on %v", args)
+		}
+		return nil, errors.New("unsupported camera")
+	}
+	summary, err = ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 0 || summary.Skips.ExistsConflict != 0 {
+		t.Errorf("Second import copied %d files with %d conflicts, want none", summary.Copied, summary.Skips.ExistsConflict)
+	}
+
+	// Without a record of the conversion, nothing proves the DNG file holds the RAW file
+	params.CatalogFile = ""
+	summary, err = ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Skips.ExistsConflict != 1 || summary.Skips.ExistsIdentical != 2 {
+		t.Errorf("Import without catalog found %d conflicts and %d identical files, want 1 and 2", summary.Skips.ExistsConflict, summary.Skips.ExistsIdentical)
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EjectVolume unmounts the removable volume holding path and, where the
+// platform supports it, ejects or powers it off so it can be removed safely
+func EjectVolume(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	return ejectVolume(abs)
+}
+
+// mountOf returns the mount point and device of the volume holding path,
+// from a mounts file in the /proc/mounts format
+func mountOf(mounts, path string) (string, string, error) {
+	mountPoint, device, _, err := mountEntryOf(mounts, path)
+	return mountPoint, device, err
+}
+
+// mountEntryOf returns the mount point, device and file system type of the
+// volume holding path, from a mounts file in the /proc/mounts format
+func mountEntryOf(mounts, path string) (string, string, string, error) {
+	file, err := os.Open(mounts)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer file.Close()
+
+	var mountPoint, device, fsType string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		point := unescapeMountPoint(fields[1])
+		if len(point) > len(mountPoint) && (path == point || isBelow(point, path)) {
+			mountPoint, device, fsType = point, unescapeMountPoint(fields[0]), ""
+			if len(fields) > 2 {
+				fsType = fields[2]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", "", err
+	}
+	if mountPoint == "" {
+		return "", "", "", fmt.Errorf("no mounted volume found for %s", path)
+	}
+	return mountPoint, device, fsType, nil
+}
+
+// isBelow reports whether path is located below dir
+func isBelow(dir, path string) bool {
+	prefix := strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+	return strings.HasPrefix(path, prefix)
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// ejectVolume ejects the volume with diskutil, which also ejects the other
+// partitions of the card, falling back to the unmount system call
+func ejectVolume(path string) error {
+	if !isBelow(volumesRoot, path) {
+		return fmt.Errorf("%s is not on a removable volume", path)
+	}
+	rel, err := filepath.Rel(volumesRoot, path)
+	if err != nil {
+		return err
+	}
+	mountPoint := filepath.Join(volumesRoot, strings.Split(rel, string(filepath.Separator))[0])
+
+	if out, err := exec.Command("diskutil", "eject", mountPoint).CombinedOutput(); err == nil {
+		return nil
+	} else if err := syscall.Unmount(mountPoint, 0); err != nil {
+		return fmt.Errorf("failed to eject %s: %s", mountPoint, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// ejectVolume unmounts the volume through udisks, which desktop users are
+// allowed to do, then powers the device off. Without udisks it falls back to
+// the unmount system call, which requires privileges.
+func ejectVolume(path string) error {
+	mountPoint, device, err := mountOf(procMounts, path)
+	if err != nil {
+		return err
+	}
+	if mountPoint == "/" {
+		return fmt.Errorf("%s is not on a removable volume", path)
+	}
+
+	if udisks, err := exec.LookPath("udisksctl"); err == nil && os.Geteuid() != 0 {
+		if out, err := exec.Command(udisks, "unmount", "--block-device", device, "--no-user-interaction").CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to unmount %s: %v: %s", mountPoint, err, out)
+		}
+		// Powering off is not supported by every device, the volume is safe to remove anyway
+		_ = exec.Command(udisks, "power-off", "--block-device", device, "--no-user-interaction").Run()
+		return nil
+	}
+
+	if err := syscall.Unmount(mountPoint, 0); err != nil {
+		return fmt.Errorf("failed to unmount %s: %w", mountPoint, err)
+	}
+	return nil
+}
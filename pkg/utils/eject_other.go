@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+
+package utils
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// ejectVolume is not supported on this platform
+func ejectVolume(path string) error {
+	return fmt.Errorf("ejecting volumes is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMountOf(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Mounts files only exist on Unix systems")
+	}
+
+	mounts := filepath.Join(t.TempDir(), "mounts")
+	content := `/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+/dev/mmcblk0p1 /media/user/NO\040NAME vfat rw,nosuid 0 0
+/dev/sdb1 /media/user/CARD exfat rw 0 0
+/dev/sdc1 /media/user/CARD2 vfat rw 0 0
+`
+	if err := os.WriteFile(mounts, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create mounts file: %v", err)
+	}
+
+	tests := []struct {
+		path       string
+		mountPoint string
+		device     string
+	}{
+		{"/media/user/NO NAME/DCIM", "/media/user/NO NAME", "/dev/mmcblk0p1"},
+		{"/media/user/CARD", "/media/user/CARD", "/dev/sdb1"},
+		{"/media/user/CARD2/DCIM/100CANON", "/media/user/CARD2", "/dev/sdc1"},
+		{"/home/user/Pictures", "/", "/dev/nvme0n1p2"},
+	}
+	for _, tt := range tests {
+		mountPoint, device, err := mountOf(mounts, tt.path)
+		if err != nil {
+			t.Errorf("mountOf(%q) error = %v", tt.path, err)
+			continue
+		}
+		if mountPoint != tt.mountPoint || device != tt.device {
+			t.Errorf("mountOf(%q) = %q, %q, want %q, %q", tt.path, mountPoint, device, tt.mountPoint, tt.device)
+		}
+	}
+
+	if _, _, err := mountOf(filepath.Join(t.TempDir(), "missing"), "/media/user/CARD"); err == nil {
+		t.Error("mountOf() expected error for a missing mounts file")
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// Device control codes of winioctl.h
+const (
+	fsctlLockVolume          = 0x00090018
+	fsctlDismountVolume      = 0x00090020
+	ioctlStorageMediaRemoval = 0x002D4804
+	ioctlStorageEjectMedia   = 0x002D4808
+)
+
+// ejectVolume locks and dismounts the volume so pending writes are flushed,
+// then ejects its media, as the "Safely remove" action does
+func ejectVolume(path string) error {
+	volume := filepath.VolumeName(path)
+	if len(volume) != 2 || volume[1] != ':' {
+		return fmt.Errorf("%s is not on a drive letter volume", path)
+	}
+
+	name, err := syscall.UTF16PtrFromString(`\\.\` + volume)
+	if err != nil {
+		return err
+	}
+	handle, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE, nil, syscall.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open volume %s: %w", volume, err)
+	}
+	defer syscall.CloseHandle(handle)
+
+	var returned uint32
+	control := func(code uint32, in *byte, inSize uint32) error {
+		return syscall.DeviceIoControl(handle, code, in, inSize, nil, 0, &returned, nil)
+	}
+
+	if err := control(fsctlLockVolume, nil, 0); err != nil {
+		return fmt.Errorf("volume %s is in use: %w", volume, err)
+	}
+	if err := control(fsctlDismountVolume, nil, 0); err != nil {
+		return fmt.Errorf("failed to dismount volume %s: %w", volume, err)
+	}
+
+	// Allow removal, then eject the media
+	preventRemoval := byte(0)
+	if err := control(ioctlStorageMediaRemoval, &preventRemoval, uint32(unsafe.Sizeof(preventRemoval))); err != nil {
+		return fmt.Errorf("failed to allow removal of volume %s: %w", volume, err)
+	}
+	if err := control(ioctlStorageEjectMedia, nil, 0); err != nil {
+		return fmt.Errorf("failed to eject volume %s: %w", volume, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Formats of transfer plans handed over to other copy tools
+const (
+	EmitRsync  = "rsync"  // POSIX shell script of rsync commands
+	EmitRclone = "rclone" // POSIX shell script of rclone copyto commands
+	EmitTSV    = "tsv"    // Tab-separated source and destination paths
+)
+
+// EmitFormats lists the supported transfer plan formats
+var EmitFormats = map[string]bool{
+	EmitRsync:  true,
+	EmitRclone: true,
+	EmitTSV:    true,
+}
+
+// WriteTransferPlan writes the planned files as commands for another copy
+// tool, so existing rsync or rclone pipelines can do the copying while this
+// tool only decides where files go. Files a run would skip are left out,
+// listed as comments in scripts.
+func WriteTransferPlan(w io.Writer, plan []PlannedFile, format string) error {
+	if !EmitFormats[format] {
+		return fmt.Errorf("unsupported emit format: %s (expected rsync, rclone or tsv)", format)
+	}
+
+	conflicts := PlanConflicts(plan)
+	skipped := make(map[string]bool, len(conflicts))
+	for _, f := range conflicts {
+		skipped[f.Source] = true
+	}
+
+	var files []PlannedFile
+	for _, f := range plan {
+		if !skipped[f.Source] {
+			files = append(files, f)
+		}
+	}
+
+	if format == EmitTSV {
+		for _, f := range files {
+			if _, err := fmt.Fprintf(w, "%s\t%s\n", f.Source, f.Destination); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# Generated by organize-media: %d files to copy, %d skipped\n", len(files), len(conflicts))
+	b.WriteString("set -e\n")
+	for _, f := range conflicts {
+		fmt.Fprintf(&b, "# skipped %s: %s\n", strings.ReplaceAll(f.Source, "\n", " "), strings.ReplaceAll(f.Err.Error(), "\n", " "))
+	}
+
+	if format == EmitRsync {
+		// rsync does not create missing parent folders of its target
+		dirs := make(map[string]bool)
+		for _, f := range files {
+			dirs[filepath.Dir(f.Destination)] = true
+		}
+		sorted := make([]string, 0, len(dirs))
+		for dir := range dirs {
+			sorted = append(sorted, dir)
+		}
+		sort.Strings(sorted)
+		for _, dir := range sorted {
+			fmt.Fprintf(&b, "mkdir -p -- %s\n", shellQuote(dir))
+		}
+	}
+
+	for _, f := range files {
+		switch format {
+		case EmitRsync:
+			fmt.Fprintf(&b, "rsync -t --ignore-existing -- %s %s\n", shellQuote(f.Source), shellQuote(f.Destination))
+		case EmitRclone:
+			fmt.Fprintf(&b, "rclone copyto --ignore-existing -- %s %s\n", shellQuote(f.Source), shellQuote(f.Destination))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
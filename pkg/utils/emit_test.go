@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTransferPlan(t *testing.T) {
+	dest := t.TempDir()
+	day := filepath.Join(dest, "2025", "01-11")
+	plan := []PlannedFile{
+		{Source: "/card/IMG_0001.JPG", Destination: filepath.Join(day, "IMG_0001.JPG"), Date: time.Now()},
+		{Source: "/card/it's.JPG", Destination: filepath.Join(day, "it's.JPG"), Date: time.Now()},
+		{Source: "/card/nodate.JPG", Err: errors.New("no date")},
+	}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{EmitRsync, []string{
+			"# skipped /card/nodate.JPG: no date",
+			"mkdir -p -- '" + day + "'",
+			"rsync -t --ignore-existing -- '/card/IMG_0001.JPG' '" + filepath.Join(day, "IMG_0001.JPG") + "'",
+			`rsync -t --ignore-existing -- '/card/it'\''s.JPG'`,
+		}},
+		{EmitRclone, []string{
+			"rclone copyto --ignore-existing -- '/card/IMG_0001.JPG' '" + filepath.Join(day, "IMG_0001.JPG") + "'",
+		}},
+		{EmitTSV, []string{
+			"/card/IMG_0001.JPG\t" + filepath.Join(day, "IMG_0001.JPG") + "\n",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteTransferPlan(&buf, plan, tt.format); err != nil {
+				t.Fatalf("WriteTransferPlan() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("WriteTransferPlan() output missing %q:\n%s", want, buf.String())
+				}
+			}
+			if tt.format == EmitTSV && strings.Contains(buf.String(), "nodate") {
+				t.Errorf("WriteTransferPlan() listed a skipped file:\n%s", buf.String())
+			}
+		})
+	}
+
+	if err := WriteTransferPlan(&bytes.Buffer{}, plan, "robocopy"); err == nil {
+		t.Error("WriteTransferPlan() expected error for unsupported format")
+	}
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// EncryptedExt is the extension of encrypted destination files
+const EncryptedExt = ".enc"
+
+// encryptionKeySize is the size of AES-256 keys
+const encryptionKeySize = 32
+
+// encryptedMagic starts every encrypted file, and identifies the format version
+var encryptedMagic = []byte("OMENC001")
+
+// ErrNotEncrypted is returned when decrypting a file that was not written by Encryptor
+var ErrNotEncrypted = errors.New("not an encrypted file")
+
+// Encryptor encrypts destination files with AES-256-GCM, for archives kept on
+// untrusted storage such as cloud buckets. The original file name is stored
+// in the encrypted content, so file names can be hashed as well.
+// A nil Encryptor leaves files and names untouched.
+type Encryptor struct {
+	aead      cipher.AEAD
+	nameKey   []byte
+	hashNames bool
+}
+
+// GenerateKeyFile writes a new random encryption key to path, hex-encoded.
+// An existing file is never replaced, losing a key loses the files.
+func GenerateKeyFile(path string) error {
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadEncryptor reads a key file written by GenerateKeyFile
+func LoadEncryptor(keyFile string, hashNames bool) (*Encryptor, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption key: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("invalid encryption key in %s: expected %d hex-encoded bytes", keyFile, encryptionKeySize)
+	}
+	return NewEncryptor(key, hashNames)
+}
+
+// loadEncryptor returns the Encryptor of a run, nil when files are not encrypted
+func loadEncryptor(p *models.Params) (*Encryptor, error) {
+	if p.EncryptKeyFile == "" {
+		return nil, nil
+	}
+	return LoadEncryptor(p.EncryptKeyFile, p.HashNames)
+}
+
+// NewEncryptor returns an Encryptor using a 32-byte key
+func NewEncryptor(key []byte, hashNames bool) (*Encryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// File names are hashed with a key of their own, derived from the encryption key
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("organize-media file names"))
+	return &Encryptor{aead: aead, nameKey: mac.Sum(nil), hashNames: hashNames}, nil
+}
+
+// Path returns the path of the encrypted version of destPath. Hashed names
+// are the same for the same name and key, so existing files are still detected.
+func (e *Encryptor) Path(destPath string) string {
+	if e == nil {
+		return destPath
+	}
+	if !e.hashNames {
+		return destPath + EncryptedExt
+	}
+
+	mac := hmac.New(sha256.New, e.nameKey)
+	mac.Write([]byte(filepath.Base(destPath)))
+	return filepath.Join(filepath.Dir(destPath), hex.EncodeToString(mac.Sum(nil)[:16])+EncryptedExt)
+}
+
+// Seal encrypts the content of a file named name
+func (e *Encryptor) Seal(name string, data []byte) ([]byte, error) {
+	if e == nil {
+		return data, nil
+	}
+	if len(name) > 0xFFFF {
+		return nil, fmt.Errorf("file name too long: %s", name)
+	}
+
+	plain := make([]byte, 0, 2+len(name)+len(data))
+	plain = binary.BigEndian.AppendUint16(plain, uint16(len(name)))
+	plain = append(append(plain, name...), data...)
+
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := append(append([]byte{}, encryptedMagic...), nonce...)
+	return e.aead.Seal(sealed, nonce, plain, encryptedMagic), nil
+}
+
+// Open decrypts the content of an encrypted file and returns its original name
+func (e *Encryptor) Open(sealed []byte) (string, []byte, error) {
+	if !bytes.HasPrefix(sealed, encryptedMagic) {
+		return "", nil, ErrNotEncrypted
+	}
+	sealed = sealed[len(encryptedMagic):]
+	if len(sealed) < e.aead.NonceSize() {
+		return "", nil, fmt.Errorf("%w: truncated header", ErrCorruptFile)
+	}
+
+	nonce, ciphertext := sealed[:e.aead.NonceSize()], sealed[e.aead.NonceSize():]
+	plain, err := e.aead.Open(nil, nonce, ciphertext, encryptedMagic)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decrypt, wrong key or damaged file: %w", err)
+	}
+
+	if len(plain) < 2 || len(plain) < 2+int(binary.BigEndian.Uint16(plain)) {
+		return "", nil, fmt.Errorf("%w: invalid name header", ErrCorruptFile)
+	}
+	nameLen := int(binary.BigEndian.Uint16(plain))
+	return string(plain[2 : 2+nameLen]), plain[2+nameLen:], nil
+}
+
+// DecryptTree decrypts the encrypted files below source into destination,
+// restoring their original names in the same folders. Files already in the
+// destination are left alone. It returns the number of decrypted files.
+func DecryptTree(e *Encryptor, source, destination string) (int, error) {
+	var count int
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), EncryptedExt) {
+			return nil
+		}
+
+		sealed, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, data, err := e.Open(sealed)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		// Names come from the file content, never let them leave their folder
+		if name != filepath.Base(name) || name == "." || name == ".." {
+			return fmt.Errorf("%s: invalid file name %q", path, name)
+		}
+
+		rel, err := filepath.Rel(source, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, rel, name)
+		if exists, err := fileExists(OSFileSystem{}, target); err != nil || exists {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
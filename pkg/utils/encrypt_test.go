@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// newTestEncryptor returns an Encryptor with a fixed key
+func newTestEncryptor(t *testing.T, hashNames bool) *Encryptor {
+	t.Helper()
+	enc, err := NewEncryptor(bytes.Repeat([]byte{7}, encryptionKeySize), hashNames)
+	if err != nil {
+		t.Fatalf("NewEncryptor() error = %v", err)
+	}
+	return enc
+}
+
+func TestEncryptorRoundTrip(t *testing.T) {
+	enc := newTestEncryptor(t, false)
+	data := []byte("picture content")
+
+	sealed, err := enc.Seal("IMG_0001.JPG", data)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if bytes.Contains(sealed, data) || bytes.Contains(sealed, []byte("IMG_0001")) {
+		t.Error("Seal() output contains the plain content or name")
+	}
+
+	name, plain, err := enc.Open(sealed)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if name != "IMG_0001.JPG" || !bytes.Equal(plain, data) {
+		t.Errorf("Open() = %q, %q", name, plain)
+	}
+
+	other, _ := NewEncryptor(bytes.Repeat([]byte{8}, encryptionKeySize), false)
+	if _, _, err := other.Open(sealed); err == nil {
+		t.Error("Open() with another key expected an error")
+	}
+	if _, _, err := enc.Open(data); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("Open() of plain data error = %v, want ErrNotEncrypted", err)
+	}
+}
+
+func TestEncryptorPath(t *testing.T) {
+	path := filepath.Join("dest", "2025", "01-11", "IMG_0001.JPG")
+
+	var none *Encryptor
+	if got := none.Path(path); got != path {
+		t.Errorf("nil Path() = %s, want %s", got, path)
+	}
+	if got := newTestEncryptor(t, false).Path(path); got != path+EncryptedExt {
+		t.Errorf("Path() = %s, want %s", got, path+EncryptedExt)
+	}
+
+	hashed := newTestEncryptor(t, true).Path(path)
+	if filepath.Dir(hashed) != filepath.Dir(path) || strings.Contains(hashed, "IMG_0001") || !strings.HasSuffix(hashed, EncryptedExt) {
+		t.Errorf("hashed Path() = %s", hashed)
+	}
+	if again := newTestEncryptor(t, true).Path(path); again != hashed {
+		t.Errorf("hashed Path() is not stable: %s then %s", hashed, again)
+	}
+}
+
+func TestProcessMediaFilesEncrypted(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := GenerateKeyFile(keyFile); err != nil {
+		t.Fatalf("GenerateKeyFile() error = %v", err)
+	}
+	if err := GenerateKeyFile(keyFile); err == nil {
+		t.Error("GenerateKeyFile() replaced an existing key")
+	}
+
+	data := createFakeExifData()
+	if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	params := &models.Params{Source: sourceDir, Destination: destDir, Compression: -1, EncryptKeyFile: keyFile, HashNames: true}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 {
+		t.Fatalf("Expected 1 copied file, got %+v", summary)
+	}
+
+	// A second run finds the encrypted file under its hashed name
+	if summary, err = ProcessMediaFiles(params); err != nil || summary.Skipped != 1 {
+		t.Errorf("Expected the encrypted file to be skipped, got %+v (%v)", summary, err)
+	}
+
+	enc, err := LoadEncryptor(keyFile, false)
+	if err != nil {
+		t.Fatalf("LoadEncryptor() error = %v", err)
+	}
+	restored := t.TempDir()
+	count, err := DecryptTree(enc, destDir, restored)
+	if err != nil || count != 1 {
+		t.Fatalf("DecryptTree() = %d, %v", count, err)
+	}
+	got, err := os.ReadFile(filepath.Join(restored, "2025", "01-11", "photo.jpg"))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Decrypted file differs from the source: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors of files that cannot be organized, to be matched with errors.Is
+var (
+	// ErrUnsupportedFormat is returned for files whose format has no known date location
+	ErrUnsupportedFormat = errors.New("unsupported file format")
+	// ErrNoDate is returned when a file holds no capture date
+	ErrNoDate = errors.New("no date/time information found")
+)
+
+// DestinationConflictError reports a file that cannot be organized because
+// its destination is taken, on disk or by another file of the run. Match it
+// with errors.As.
+type DestinationConflictError struct {
+	Path   string // Destination of the file
+	Source string // Other source file of the run organized at Path, empty when Path already exists
+}
+
+func (e *DestinationConflictError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("same destination as %s: %s", e.Source, e.Path)
+	}
+	return fmt.Sprintf("destination file already exists: %s", e.Path)
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// EventKind identifies what an Event reports
+type EventKind string
+
+const (
+	EventFilePlanned  EventKind = "file_planned"  // PlanMediaFiles resolved the destination of a file, File holds it
+	EventFileStarted  EventKind = "file_started"  // A file is being processed
+	EventFileFinished EventKind = "file_finished" // A file was processed, File holds its outcome
+	EventRunFinished  EventKind = "run_finished"  // A run is over, Summary holds its counts
+)
+
+// Event reports the progress of a run to programs embedding the processing
+// engine and to the extensions subscribed to the event bus
+type Event struct {
+	Kind    EventKind
+	Time    time.Time
+	File    ReportEntry
+	Summary *ProcessingSummary // Summary of the run, for EventRunFinished
+}
+
+// subscriber is an extension registered on the event bus
+type subscriber struct {
+	id int
+	fn func(Event)
+}
+
+// bus holds the extensions receiving the events of every run
+var bus struct {
+	mu          sync.RWMutex
+	next        int
+	subscribers []subscriber
+}
+
+// Subscribe registers fn to receive the events of every run from now on, so
+// optional features such as uploaders, notifiers or galleries can live
+// outside the processing engine. Events of files are delivered from the
+// goroutines processing them: fn must be safe for concurrent use and return
+// quickly, queueing slow work such as uploads. Subscribers receive events in
+// the order they registered. The returned function unregisters fn.
+func Subscribe(fn func(Event)) (unsubscribe func()) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.next++
+	id := bus.next
+	bus.subscribers = append(bus.subscribers, subscriber{id: id, fn: fn})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			bus.mu.Lock()
+			defer bus.mu.Unlock()
+			for i, s := range bus.subscribers {
+				if s.id == id {
+					bus.subscribers = append(bus.subscribers[:i:i], bus.subscribers[i+1:]...)
+					break
+				}
+			}
+		})
+	}
+}
+
+// publish delivers an event to the subscribers of the event bus
+func publish(event Event) {
+	bus.mu.RLock()
+	subscribers := bus.subscribers
+	bus.mu.RUnlock()
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, s := range subscribers {
+		s.fn(event)
+	}
+}
+
+// emit publishes an event on the bus, and sends it if the caller asked for
+// them, unless the run is cancelled
+func (r *mediaRun) emit(event Event) {
+	event.Time = time.Now()
+	publish(event)
+	if r.events == nil {
+		return
+	}
+	select {
+	case r.events <- event:
+	case <-r.ctx.Done():
+	}
+}
+
+// finish records the outcome of a file in the report and sends it as an event
+func (r *mediaRun) finish(entry ReportEntry) {
+	r.report.Add(entry)
+	r.emit(Event{Kind: EventFileFinished, File: entry})
+}
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createEventTestSource creates count dated JPEG files
+func createEventTestSource(t *testing.T, count int) string {
+	t.Helper()
+	sourceDir := t.TempDir()
+	for i := 0; i < count; i++ {
+		if err := os.WriteFile(filepath.Join(sourceDir, fmt.Sprintf("photo%d.jpg", i)), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return sourceDir
+}
+
+func TestProcessMediaFilesCtxEvents(t *testing.T) {
+	sourceDir := createEventTestSource(t, 3)
+	if err := os.WriteFile(filepath.Join(sourceDir, "nodate.nef"), []byte("raw data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	events := make(chan Event)
+	done := make(chan []Event)
+	go func() {
+		var received []Event
+		for event := range events {
+			received = append(received, event)
+		}
+		done <- received
+	}()
+
+	params := &models.Params{Source: sourceDir, Destination: t.TempDir(), Compression: -1}
+	summary, err := ProcessMediaFilesCtx(context.Background(), params, events)
+	close(events)
+	received := <-done
+
+	if err != nil {
+		t.Fatalf("ProcessMediaFilesCtx() unexpected error: %v", err)
+	}
+	if summary.Processed != 3 || summary.Skipped != 1 {
+		t.Errorf("summary = %+v, want 3 processed and 1 skipped", summary)
+	}
+
+	started := make(map[string]bool)
+	statuses := make(map[string]string)
+	for _, event := range received {
+		name := filepath.Base(event.File.Source)
+		switch event.Kind {
+		case EventFileStarted:
+			started[name] = true
+		case EventFileFinished:
+			if !started[name] {
+				t.Errorf("%s finished before being started", name)
+			}
+			statuses[name] = event.File.Status
+		}
+		if event.Time.IsZero() {
+			t.Errorf("event %s of %s has no time", event.Kind, name)
+		}
+	}
+
+	want := map[string]string{
+		"photo0.jpg": ReportCopied,
+		"photo1.jpg": ReportCopied,
+		"photo2.jpg": ReportCopied,
+		"nodate.nef": ReportSkipped,
+	}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("finished statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestProcessMediaFilesCtxCancel(t *testing.T) {
+	t.Run("cancelled before start", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		destDir := t.TempDir()
+		params := &models.Params{Source: createEventTestSource(t, 3), Destination: destDir, Compression: -1}
+		summary, err := ProcessMediaFilesCtx(ctx, params, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ProcessMediaFilesCtx() error = %v, want context.Canceled", err)
+		}
+		if summary.Processed != 0 {
+			t.Errorf("Processed = %d, want 0", summary.Processed)
+		}
+		if entries, _ := os.ReadDir(destDir); len(entries) != 0 {
+			t.Errorf("destination should be untouched, found %d entries", len(entries))
+		}
+	})
+
+	t.Run("cancelled after the first file", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := make(chan Event)
+		go func() {
+			for event := range events {
+				if event.Kind == EventFileFinished {
+					cancel()
+				}
+			}
+		}()
+		defer close(events)
+
+		params := &models.Params{Source: createEventTestSource(t, 10), Destination: t.TempDir(), Compression: -1}
+		summary, err := ProcessMediaFilesCtx(ctx, params, events)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ProcessMediaFilesCtx() error = %v, want context.Canceled", err)
+		}
+		if summary.Processed < 1 || summary.Processed >= 10 {
+			t.Errorf("Processed = %d, want between 1 and 9", summary.Processed)
+		}
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	sourceDir := createEventTestSource(t, 3)
+	if err := os.WriteFile(filepath.Join(sourceDir, "nodate.nef"), []byte("raw data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var mu sync.Mutex
+	counts := make(map[EventKind]int)
+	var finished *ProcessingSummary
+	unsubscribe := Subscribe(func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		counts[event.Kind]++
+		if event.Kind == EventRunFinished {
+			finished = event.Summary
+		}
+	})
+	// Subscribers registered later receive the events too
+	var second atomic.Int32
+	defer Subscribe(func(Event) { second.Add(1) })()
+
+	params := &models.Params{Source: sourceDir, Destination: t.TempDir(), Compression: -1, Workers: 2}
+	if _, err := PlanMediaFiles(params); err != nil {
+		t.Fatalf("PlanMediaFiles() error = %v", err)
+	}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+
+	want := map[EventKind]int{EventFilePlanned: 4, EventFileStarted: 4, EventFileFinished: 4, EventRunFinished: 1}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("received events = %v, want %v", counts, want)
+	}
+	if finished == nil || finished.Processed != summary.Processed {
+		t.Errorf("run finished with summary %+v, want %+v", finished, summary)
+	}
+	if second.Load() != 13 {
+		t.Errorf("second subscriber received %d events, want 13", second.Load())
+	}
+
+	// Unsubscribed extensions receive nothing more
+	unsubscribe()
+	unsubscribe()
+	if _, err := PlanMediaFiles(params); err != nil {
+		t.Fatalf("PlanMediaFiles() error = %v", err)
+	}
+	if counts[EventFilePlanned] != 4 {
+		t.Errorf("received %d planned events after unsubscribing, want 4", counts[EventFilePlanned])
+	}
+}
@@ -46,16 +46,41 @@ var SupportedExtensions = map[string]bool{
 	".rw2":  true, // Panasonic RAW
 	".dng":  true, // Adobe DNG
 	".raw":  true, // Generic RAW
+	".png":  true, // Screenshots and exported pictures
+	".mpo":  true, // 3D pictures, JPEG images one after the other
+	".mts":  true, // AVCHD camcorder video
+	".m2ts": true, // AVCHD camcorder video
+	".insv": true, // Insta360 video, one file per lens
+	".insp": true, // Insta360 picture, one file per lens
 	// Add more formats here as needed
 }
 
 // GetImageDateTime extracts the date and time from an image buffer
 func GetImageDateTime(buffer []byte, fileExt string) (time.Time, error) {
 	// Create a reader from the buffer
-	reader := bytes.NewReader(buffer)
+	return GetImageDateTimeFromReader(bytes.NewReader(buffer), fileExt)
+}
+
+// GetImageDateTimeFromReader extracts the date and time from a seekable reader,
+// such as an open file, reading only the parts needed to find the date
+func GetImageDateTimeFromReader(reader io.ReadSeeker, fileExt string) (time.Time, error) {
+	return imageDateTime(reader, fileExt, false)
+}
 
+// imageDateTime is GetImageDateTimeFromReader, never reading the GPS location
+// of 360 videos when noGPS is set
+func imageDateTime(reader io.ReadSeeker, fileExt string, noGPS bool) (time.Time, error) {
 	ext := strings.ToLower(fileExt)
 
+	// Camcorder videos carry their date in the video stream, not in EXIF
+	if avchdExtensions[ext] {
+		return avchdCreationTime(reader)
+	}
+	// 360 videos are MP4 files dated by their movie header
+	if ext == insta360Video {
+		return movieCreationTime(reader, noGPS)
+	}
+
 	// Try different extraction strategies based on file format
 	strategies := []func(io.ReadSeeker, string) (time.Time, error){
 		ExtractExifFromJPEG,    // JPEG-specific structure
@@ -65,7 +90,7 @@ func GetImageDateTime(buffer []byte, fileExt string) (time.Time, error) {
 	}
 
 	// For non-JPEG files, we can skip the JPEG-specific strategy
-	if ext != ".jpg" && ext != ".jpeg" {
+	if !jpegContainer(ext) {
 		strategies = strategies[1:]
 	}
 
@@ -83,7 +108,12 @@ func GetImageDateTime(buffer []byte, fileExt string) (time.Time, error) {
 		// If this strategy failed, continue with the next one
 	}
 
-	return time.Time{}, fmt.Errorf("no date/time information found")
+	// Other formats are still searched like RAW files, and only rejected
+	// when that finds no date
+	if !SupportedExtensions[ext] {
+		return time.Time{}, fmt.Errorf("%w: %s: %w", ErrUnsupportedFormat, fileExt, ErrNoDate)
+	}
+	return time.Time{}, ErrNoDate
 }
 
 // ExtractExifFromJPEG extracts date/time from JPEG data in a buffer
@@ -256,7 +286,7 @@ func ScanForDateTimeString(reader io.ReadSeeker, _ string) (time.Time, error) {
 		}
 	}
 
-	return time.Time{}, fmt.Errorf("no date/time information found")
+	return time.Time{}, ErrNoDate
 }
 
 // ParseTIFFHeader parses TIFF header and IFD entries to find date/time
@@ -392,5 +422,5 @@ func ParseTIFFHeader(r io.Reader) (time.Time, error) {
 		}
 	}
 
-	return time.Time{}, fmt.Errorf("no date/time information found")
+	return time.Time{}, ErrNoDate
 }
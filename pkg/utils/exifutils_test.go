@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
@@ -603,6 +604,19 @@ func TestMissingTags(t *testing.T) {
 	}
 }
 
+// TestUnsupportedFormat tests that formats without a known date location are only rejected when no date is found
+func TestUnsupportedFormat(t *testing.T) {
+	// Other formats are still dated when their content holds a date
+	if _, err := GetImageDateTime(createFakeExifData(), ".tif"); err != nil {
+		t.Errorf("GetImageDateTime(.tif) error = %v", err)
+	}
+
+	_, err := GetImageDateTime([]byte("GIF89a"), ".gif")
+	if !errors.Is(err, ErrUnsupportedFormat) || !errors.Is(err, ErrNoDate) {
+		t.Errorf("Expected ErrUnsupportedFormat and ErrNoDate, got %v", err)
+	}
+}
+
 // TestParseTIFFHeader tests the TIFF header parsing function with various inputs
 func TestParseTIFFHeader(t *testing.T) {
 	// We'll use the successful ExtractExifWithOffsetsBuffer as a reference
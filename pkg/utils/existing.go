@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// skipExisting skips a file whose destination already exists before its
+// content is read, finding its destination from its header only. It reports
+// whether the file was skipped; files it cannot date from their header, and
+// files whose content decides their outcome, are left to the full read.
+func (r *mediaRun) skipExisting(entry ReportEntry, path string, info os.FileInfo, proxy, unknown bool, summary *ProcessingSummary) bool {
+	// JPEG files whose date is written back may differ from their destination
+	if r.p.WriteDates && isJPEG(path) {
+		return false
+	}
+	header, ok := r.readHeader(path, info, proxy, unknown)
+	if !ok || (header.screenshot && r.screenshots == ScreenshotsSkip) {
+		return false
+	}
+
+	destPath, ok := r.limiter.lookup(r.destination(path, info, header.date, proxy, header.screenshot, unknown))
+	if !ok {
+		return false
+	}
+	if exists, _ := fileExists(r.fs, r.enc.Path(destPath)); !exists {
+		return false
+	}
+
+	// Another source file of the run writing this destination is reported as a conflict
+	if _, ok := r.claims.claim(destPath, path); !ok {
+		return false
+	}
+
+	destPath = r.enc.Path(destPath)
+	output.Status("SKIPPED", fmt.Sprintf("Destination file already exists: %s", destPath))
+	isJPG := isJPEG(path)
+	transformed := isJPG && r.p.Compression >= 0 || embedsProvenance(r.p, isJPG) || r.enc != nil
+	skip := existingSkip(r.fs, destPath, filePayload(r.fs, path, info.Size()), transformed, r.p.HashAlgo, r.catalog)
+	summary.skip(skip)
+	if header.cached {
+		summary.CacheHits++
+	}
+	if header.screenshot {
+		entry.Tags = append(entry.Tags, KindScreenshot)
+	}
+	entry.Status, entry.Destination, entry.Reason, entry.SkipReason = ReportSkipped, destPath, existingReason(r.fs, destPath), skip
+	entry.Mirrors = mirrorExisting(r.fs, destPath, r.p, r.power, summary)
+	r.finish(entry)
+	r.linkAlbums(path, destPath)
+	r.state.Mark(path, info)
+	return true
+}
+
+// fileHeader is what the header of a file tells about its destination
+type fileHeader struct {
+	date       time.Time
+	screenshot bool
+	cached     bool // Date found in the metadata cache
+}
+
+// readHeader returns the date of a file as the full read would find it, and
+// whether it is a screenshot, reading only the header of the file. The date
+// is not cached, the full read of files not skipped caches it.
+func (r *mediaRun) readHeader(path string, info os.FileInfo, proxy, unknown bool) (h fileHeader, ok bool) {
+	if unknown {
+		return fileHeader{date: info.ModTime()}, true
+	}
+
+	file, err := r.fs.Open(path)
+	if err != nil {
+		return h, false
+	}
+	defer file.Close()
+	h.screenshot = !proxy && isScreenshot(path, file, r.device)
+
+	if manual, overridden := r.p.DateOverrides[path]; overridden {
+		h.date = manual
+		return h, true
+	}
+	if folder, trusted := trustedFolderDate(r.p, path); trusted {
+		h.date = folder
+		return h, true
+	}
+
+	h.date, h.cached = r.cache.Get(path, info)
+	if !h.cached {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return h, false
+		}
+		h.date, err = mediaDate(r.fs, path, file, r.p.NoGPS)
+		if err != nil && h.screenshot {
+			// Not dated by the camera clock, no clock offset applies
+			h.date = screenshotDate(path, info)
+			return h, true
+		}
+		if err != nil {
+			return h, false
+		}
+	}
+
+	// Camera clocks known to be off are corrected
+	if len(r.offsets) > 0 && !proxy {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return h, false
+		}
+		meta, _ := GetImageMetadata(file, filepath.Ext(path))
+		h.date = r.offsets.correct(h.date, meta.Serial)
+	}
+	h.date = r.edits.date(path, h.date)
+	return h, true
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// mockCountingOpen records the files read in full by processFile
+func mockCountingOpen() (map[string]bool, func()) {
+	original := openFile
+	var mu sync.Mutex
+	opened := make(map[string]bool)
+	openFile = func(fsys FileSystem, name string) (io.ReadCloser, error) {
+		mu.Lock()
+		opened[filepath.Base(name)] = true
+		mu.Unlock()
+		return os.Open(name)
+	}
+	return opened, func() { openFile = original }
+}
+
+func TestSkipExistingWithoutReading(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     models.Params
+		wantRead   []string // Files read in full on the second run
+		wantReason string
+	}{
+		{
+			name:       "already imported",
+			params:     models.Params{Compression: -1},
+			wantReason: "destination file already exists",
+		},
+		{
+			name:       "directory parts",
+			params:     models.Params{Compression: -1, MaxFilesPerDir: 1},
+			wantReason: "destination file already exists",
+		},
+		{
+			name:       "write-protected destination",
+			params:     models.Params{Compression: -1, ReadOnly: true},
+			wantReason: "destination file already exists and is write-protected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir := t.TempDir()
+			for _, name := range []string{"a.jpg", "b.jpg"} {
+				if err := os.WriteFile(filepath.Join(sourceDir, name), createFakeExifData(), 0644); err != nil {
+					t.Fatalf("Failed to create source file: %v", err)
+				}
+			}
+			// Files without date are always read, their outcome depends on their content
+			if err := os.WriteFile(filepath.Join(sourceDir, "undated.jpg"), []byte{0xFF, 0xD8, 0xFF, 0xD9}, 0644); err != nil {
+				t.Fatalf("Failed to create source file: %v", err)
+			}
+
+			params := tt.params
+			params.Source, params.Destination = sourceDir, t.TempDir()
+			params.ReportFile = filepath.Join(t.TempDir(), "report.json")
+			if first, err := ProcessMediaFiles(&params); err != nil || first.Copied != 2 {
+				t.Fatalf("ProcessMediaFiles() first run = %d copied, %v", first.Copied, err)
+			}
+
+			opened, restore := mockCountingOpen()
+			defer restore()
+			second, err := ProcessMediaFiles(&params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() second run error: %v", err)
+			}
+			if second.Skipped != 3 || second.Copied != 0 {
+				t.Errorf("Second run = %d skipped, %d copied, want 3 skipped", second.Skipped, second.Copied)
+			}
+			if opened["a.jpg"] || opened["b.jpg"] || !opened["undated.jpg"] {
+				t.Errorf("Files read in full = %v, want only undated.jpg", opened)
+			}
+
+			for _, entry := range readTestReport(t, params.ReportFile).Files {
+				if filepath.Base(entry.Source) == "undated.jpg" {
+					continue
+				}
+				if entry.Status != ReportSkipped || entry.Reason != tt.wantReason || entry.Destination == "" {
+					t.Errorf("Report entry of %s = %s %q to %q, want skipped %q", entry.Source, entry.Status, entry.Reason, entry.Destination, tt.wantReason)
+				}
+			}
+		})
+	}
+}
+
+func TestSkipExistingLeavesNewFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "new.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+	params := &models.Params{Source: sourceDir, Destination: t.TempDir(), Compression: -1}
+
+	opened, restore := mockCountingOpen()
+	defer restore()
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error: %v", err)
+	}
+	if summary.Copied != 1 || !opened["new.jpg"] {
+		t.Errorf("ProcessMediaFiles() = %d copied, read %v, want new.jpg read and copied", summary.Copied, opened)
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// Destination file systems
+const (
+	DestFSFAT    = "fat"    // FAT32 or exFAT, such as SD cards and some NAS shares
+	DestFSNative = "native" // Any other file system, never sanitize names
+)
+
+// fatTimeResolution is the granularity of modification times on FAT file systems
+const fatTimeResolution = 2 * time.Second
+
+// fatInvalidChars are the characters FAT and exFAT do not allow in names
+const fatInvalidChars = `/\:*?"<>|`
+
+// For testing purposes
+var detectFATFileSystem = isFATFileSystem
+
+// DestinationIsFAT reports whether names written to the destination must be
+// valid on FAT32 and exFAT, as set by -dest-fs or detected from the volume
+func DestinationIsFAT(p *models.Params) bool {
+	switch p.DestFS {
+	case DestFSFAT:
+		return true
+	case DestFSNative:
+		return false
+	}
+	return IsFATFileSystem(p.Destination)
+}
+
+// IsFATFileSystem reports whether path is on a FAT32 or exFAT volume. It
+// reports false when the file system cannot be determined.
+func IsFATFileSystem(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	return detectFATFileSystem(abs)
+}
+
+// isFATType reports whether a file system type name designates FAT or exFAT
+func isFATType(fsType string) bool {
+	switch strings.ToLower(fsType) {
+	case "vfat", "msdos", "fat", "fat12", "fat16", "fat32", "exfat":
+		return true
+	}
+	return false
+}
+
+// fatSafeName replaces the characters FAT and exFAT do not allow in a name
+// and drops the trailing dots and spaces they ignore
+func fatSafeName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(fatInvalidChars, r) || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+	safe = strings.TrimRight(safe, ". ")
+	if safe == "" {
+		return "_"
+	}
+	return safe
+}
+
+// fatSafePath makes every name of destPath below the destination directory
+// valid on FAT and exFAT. Paths outside the destination are left unchanged.
+func fatSafePath(destination, destPath string) string {
+	rel, err := filepath.Rel(destination, destPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return destPath
+	}
+
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		parts[i] = fatSafeName(part)
+	}
+	return filepath.Join(append([]string{destination}, parts...)...)
+}
+
+// sameModTime reports whether two modification times are equal, within the
+// resolution of FAT file systems when fat is set
+func sameModTime(a, b time.Time, fat bool) bool {
+	if !fat {
+		return a.Equal(b)
+	}
+	d := a.Sub(b)
+	return -fatTimeResolution < d && d < fatTimeResolution
+}
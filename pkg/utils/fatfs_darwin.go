@@ -0,0 +1,21 @@
+package utils
+
+import "syscall"
+
+// isFATFileSystem reports whether the volume holding path is FAT or exFAT,
+// which macOS names msdos and exfat
+func isFATFileSystem(path string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return false
+	}
+
+	name := make([]byte, 0, len(stat.Fstypename))
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return isFATType(string(name))
+}
@@ -0,0 +1,8 @@
+package utils
+
+// isFATFileSystem reports whether the volume holding path is FAT or exFAT,
+// from the file system type of its mount point
+func isFATFileSystem(path string) bool {
+	_, _, fsType, err := mountEntryOf(procMounts, path)
+	return err == nil && isFATType(fsType)
+}
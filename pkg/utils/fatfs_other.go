@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package utils
+
+// isFATFileSystem cannot tell the file system on this platform, -dest-fs fat
+// forces FAT-safe names
+func isFATFileSystem(path string) bool {
+	return false
+}
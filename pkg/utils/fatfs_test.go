@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestFatSafePath(t *testing.T) {
+	dest := filepath.Join("archive", "photos")
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"valid", filepath.Join(dest, "2024", "06-11", "IMG_0001.JPG"), filepath.Join(dest, "2024", "06-11", "IMG_0001.JPG")},
+		{"invalid characters", filepath.Join(dest, "2024", "06-11", `12:30 "best"?.jpg`), filepath.Join(dest, "2024", "06-11", "12_30 _best__.jpg")},
+		{"folder names", filepath.Join(dest, "2024", "06-11", "Trip <1>.", "IMG.JPG"), filepath.Join(dest, "2024", "06-11", "Trip _1_", "IMG.JPG")},
+		{"trailing dots and spaces", filepath.Join(dest, "2024", "06-11", "photo. . "), filepath.Join(dest, "2024", "06-11", "photo")},
+		{"outside the destination", filepath.Join("other", "a:b.jpg"), filepath.Join("other", "a:b.jpg")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fatSafePath(dest, tt.path); got != tt.want {
+				t.Errorf("fatSafePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsFATFileSystemFromMounts(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("File systems are read from /proc/mounts on Linux only")
+	}
+
+	mounts := filepath.Join(t.TempDir(), "mounts")
+	content := `/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+/dev/mmcblk0p1 /media/user/NO\040NAME vfat rw,nosuid 0 0
+/dev/sdb1 /media/user/CARD exfat rw 0 0
+`
+	if err := os.WriteFile(mounts, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create mounts file: %v", err)
+	}
+	original := procMounts
+	procMounts = mounts
+	defer func() { procMounts = original }()
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/media/user/NO NAME/DCIM", true},
+		{"/media/user/CARD/backup", true},
+		{"/home/user/Pictures", false},
+	}
+	for _, tt := range tests {
+		if got := isFATFileSystem(tt.path); got != tt.want {
+			t.Errorf("isFATFileSystem(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDestinationIsFAT(t *testing.T) {
+	original := detectFATFileSystem
+	defer func() { detectFATFileSystem = original }()
+	detectFATFileSystem = func(string) bool { return true }
+
+	dest := t.TempDir()
+	tests := []struct {
+		destFS string
+		want   bool
+	}{
+		{"", true},
+		{DestFSFAT, true},
+		{DestFSNative, false},
+	}
+	for _, tt := range tests {
+		if got := DestinationIsFAT(&models.Params{Destination: dest, DestFS: tt.destFS}); got != tt.want {
+			t.Errorf("DestinationIsFAT(%q) = %v, want %v", tt.destFS, got, tt.want)
+		}
+	}
+}
+
+func TestProcessMediaFilesDestFSFAT(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows does not allow these characters in source names")
+	}
+
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "party: 10pm?.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	params := &models.Params{Source: sourceDir, Destination: destDir, Compression: -1, DestFS: DestFSFAT}
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "2025", "01-11", "party_ 10pm_.jpg")); err != nil {
+		t.Errorf("Expected a FAT-safe name: %v", err)
+	}
+}
+
+func TestSyncArchiveFATTimes(t *testing.T) {
+	original := detectFATFileSystem
+	defer func() { detectFATFileSystem = original }()
+
+	archive := t.TempDir()
+	backup := t.TempDir()
+	modTime := time.Date(2025, 1, 11, 10, 0, 1, 0, time.UTC)
+	for _, dir := range []string{archive, backup} {
+		path := filepath.Join(dir, "photo.jpg")
+		if err := os.WriteFile(path, []byte("photo"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		// FAT rounds times to an even number of seconds
+		if dir == backup {
+			modTime = modTime.Add(-time.Second)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set time: %v", err)
+		}
+	}
+
+	for _, fat := range []bool{false, true} {
+		detectFATFileSystem = func(string) bool { return fat }
+		summary, err := SyncArchive(archive, backup, SyncOptions{DryRun: true}, nil)
+		if err != nil {
+			t.Fatalf("SyncArchive() error = %v", err)
+		}
+		if unchanged := summary.Unchanged == 1; unchanged != fat {
+			t.Errorf("SyncArchive() on FAT %v = %+v", fat, summary)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var procGetVolumeInformation = syscall.NewLazyDLL("kernel32.dll").NewProc("GetVolumeInformationW")
+
+// isFATFileSystem reports whether the volume holding path is FAT or exFAT,
+// from the file system name Windows reports for its root
+func isFATFileSystem(path string) bool {
+	volume := filepath.VolumeName(path)
+	if volume == "" {
+		return false
+	}
+	root, err := syscall.UTF16PtrFromString(volume + `\`)
+	if err != nil {
+		return false
+	}
+
+	var fsName [syscall.MAX_PATH + 1]uint16
+	ret, _, _ := procGetVolumeInformation.Call(uintptr(unsafe.Pointer(root)), 0, 0, 0, 0, 0,
+		uintptr(unsafe.Pointer(&fsName[0])), uintptr(len(fsName)))
+	return ret != 0 && isFATType(syscall.UTF16ToString(fsName[:]))
+}
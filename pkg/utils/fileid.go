@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/rand"
+	"io"
+	"os"
+	"time"
+)
+
+// ulidAlphabet is the Crockford base 32 alphabet of ULIDs, without I, L, O and U
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newFileID returns the identifier of a file imported at t: a ULID, 26
+// characters encoding the milliseconds of t then 80 random bits, so
+// identifiers are unique across runs and machines and sort by import time
+func newFileID(t time.Time) string {
+	var b [16]byte
+	ms := uint64(t.UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	rand.Read(b[6:])
+
+	// 26 characters of 5 bits hold the 128 bits, the first one only 3
+	var id [26]byte
+	for i := range id {
+		v := 0
+		for bit := 5*i - 2; bit < 5*i+3; bit++ {
+			v <<= 1
+			if bit >= 0 && b[bit/8]&(0x80>>(bit%8)) != 0 {
+				v |= 1
+			}
+		}
+		id[i] = ulidAlphabet[v]
+	}
+	return string(id[:])
+}
+
+// recordedFileID returns the identifier recorded in the provenance of an
+// imported file, embedded in it or in its sidecar, empty when there is none
+func recordedFileID(path string) string {
+	if file, err := os.Open(path); err == nil {
+		head := make([]byte, xmpScanSize)
+		n, _ := io.ReadFull(file, head)
+		file.Close()
+		if pv, ok := ReadProvenance(ExtractXMP(head[:n], path)); ok && pv.ID != "" {
+			return pv.ID
+		}
+	}
+	if sidecar, err := os.ReadFile(path + ProvenanceSidecarExt); err == nil {
+		if pv, ok := ReadProvenance(sidecar); ok {
+			return pv.ID
+		}
+	}
+	return ""
+}
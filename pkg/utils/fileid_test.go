@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewFileID(t *testing.T) {
+	// Example of the ULID specification
+	at := time.UnixMilli(1469918176385)
+	id := newFileID(at)
+	if len(id) != 26 || !strings.HasPrefix(id, "01ARYZ6S41") {
+		t.Errorf("newFileID() = %q, want 26 characters starting with the time 01ARYZ6S41", id)
+	}
+	if strings.Trim(id, ulidAlphabet) != "" {
+		t.Errorf("newFileID() = %q, want Crockford base 32 characters", id)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := newFileID(at)
+		if seen[id] {
+			t.Fatalf("newFileID() returned %q twice", id)
+		}
+		seen[id] = true
+	}
+
+	if earlier, later := newFileID(at), newFileID(at.Add(time.Millisecond)); earlier >= later {
+		t.Errorf("newFileID() = %q, then %q a millisecond later, want them sorted by time", earlier, later)
+	}
+}
+
+func TestRepairCatalogFileIDs(t *testing.T) {
+	dest := t.TempDir()
+	day := filepath.Join(dest, "2025", "01-11")
+	if err := os.MkdirAll(day, os.ModePerm); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	moved := filepath.Join(day, "moved.jpg")
+	tagged := filepath.Join(day, "tagged.jpg")
+	old := filepath.Join(day, "old.jpg")
+	pv := Provenance{ID: "01ARYZ6S41TSV4RRFFQ69G5FAV", Tool: "organize-media", ImportedAt: time.Now()}
+	files := map[string][]byte{moved: []byte("moved"), tagged: []byte("tagged"), old: []byte("old")}
+	for path, data := range files {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	// A sidecar holds the ID of a file whose content changed since its import
+	if err := os.WriteFile(tagged+ProvenanceSidecarExt, pv.packet(), 0644); err != nil {
+		t.Fatalf("Failed to create sidecar: %v", err)
+	}
+
+	catalogPath := filepath.Join(t.TempDir(), "catalog.jsonl")
+	catalog, err := OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error: %v", err)
+	}
+	for _, record := range []CatalogRecord{
+		{ID: "01ARYZ6S41AAAAAAAAAAAAAAAA", Hash: HashBuffer([]byte("moved"), HashSHA256), Size: 5, Source: "/card/moved.jpg", Destination: filepath.Join(dest, "moved.jpg")},
+		{ID: pv.ID, Hash: "source", Size: 1, Source: "/card/tagged.jpg", Destination: filepath.Join(dest, "tagged.jpg")},
+		{Hash: HashBuffer([]byte("old"), HashSHA256), Size: 3, Destination: old},
+	} {
+		if err := catalog.Add(record); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+	catalog.Close()
+
+	repair, err := RepairCatalog(catalogPath, dest, HashSHA256, false)
+	if want := (CatalogRepair{Kept: 1, Moved: 2}); err != nil || repair != want {
+		t.Fatalf("RepairCatalog() = %+v, %v, want %+v", repair, err, want)
+	}
+
+	catalog, err = OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() after repair error: %v", err)
+	}
+	defer catalog.Close()
+	byDestination := make(map[string]CatalogRecord)
+	for _, record := range catalog.AllRecords() {
+		byDestination[record.Destination] = record
+	}
+	if record := byDestination[moved]; record.ID != "01ARYZ6S41AAAAAAAAAAAAAAAA" || record.Source != "/card/moved.jpg" {
+		t.Errorf("Record of the moved file = %+v, want its record kept", record)
+	}
+	if record := byDestination[tagged]; record.ID != pv.ID || record.Source != "/card/tagged.jpg" {
+		t.Errorf("Record of the renamed file = %+v, want the record of its sidecar ID", record)
+	}
+	if record := byDestination[old]; len(record.ID) != 26 {
+		t.Errorf("Record without ID = %+v, want an ID assigned", record)
+	}
+	if len(byDestination) != 3 {
+		t.Errorf("Catalog records %v, want the sidecar left out", byDestination)
+	}
+}
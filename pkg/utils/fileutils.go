@@ -2,17 +2,20 @@ package utils
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"image"
-	"image/jpeg"
 	"io"
-	"log"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
 )
 
 type ImageFile struct {
@@ -21,140 +24,878 @@ type ImageFile struct {
 }
 
 type ProcessingSummary struct {
-	Processed  int
-	Compressed int
-	Copied     int
-	Skipped    int
-	Deleted    int
-	Duration   time.Duration
+	Processed    int
+	Compressed   int
+	Copied       int
+	Skipped      int
+	Skips        SkipCounts // Skipped files by reason
+	Failed       int        // Files that could not be processed or recorded because of an error
+	Deleted      int
+	Verified     int // Destination files read back and checked before their source was deleted
+	Salvaged     int
+	Corrupt      int // Empty or truncated files, reported apart from skipped files
+	Culled       int // Files left out because their reviewed companion was deleted
+	Rejected     int // Files vetoed by the check command
+	Protected    int // Destination files made read-only after writing
+	EditsKept    int // Edited JPEG files copied as is instead of being recompressed
+	Mirrored     int // Files replicated to a mirror destination
+	MirrorFailed int // Files that could not be replicated to a mirror destination
+	Unchanged    int // Files left alone because a previous import handled them
+	Lightroom    int // Files already managed in the Lightroom catalog, skipped or flagged
+	CacheHits    int
+	Duration     time.Duration
+	Stats        IOStats
 }
 
-// copyOrCompressImage processes the buffer, compressing if it's a JPG, and writes to disk.
-func copyOrCompressImage(destPath string, sourceFile string, buffer []byte, isJPG bool, p *models.Params, summary *ProcessingSummary) error {
+// For testing purposes
+var openFile = func(fsys FileSystem, name string) (io.ReadCloser, error) { return fsys.Open(name) }
+
+// copyOrCompressImage processes the buffer, compressing if it's a JPG, recording its provenance pv unless nil, encrypting it if enc
+// is not nil, and writes to fsys and to the mirror destinations. It returns the outcome of
+// the file as a report status, with the outcome for each mirror.
+func copyOrCompressImage(fsys FileSystem, destPath string, sourceFile string, sourceInfo os.FileInfo, buffer []byte, isJPG bool, p *models.Params, power *powerMonitor, catalog *Catalog, enc *Encryptor, pv *Provenance, summary *ProcessingSummary) (string, []MirrorResult, error) {
+	name, plainPath := filepath.Base(destPath), destPath
+	destPath = enc.Path(destPath)
+	// RAW files converted to DNG never match their destination in size
+	converted := strings.EqualFold(filepath.Ext(plainPath), DNGExt) && !strings.EqualFold(filepath.Ext(sourceFile), DNGExt)
+	transformed := isJPG && p.Compression >= 0 || embedsProvenance(p, isJPG) || enc != nil || converted
 
 	// Check if file already exists
-	if exists, err := fileExists(destPath); err != nil {
-		return fmt.Errorf("failed to check destination file: %w", err)
+	if exists, err := fileExists(fsys, destPath); err != nil {
+		return ReportFailed, nil, fmt.Errorf("failed to check destination file: %w", err)
 	} else if exists {
-		log.Printf("[SKIPPED] Destination file already exists: %s", destPath)
-		summary.Skipped++
-		return nil
+		output.Status("SKIPPED", fmt.Sprintf("Destination file already exists: %s", destPath))
+		summary.skip(existingSkip(fsys, destPath, bufferPayload(buffer), transformed, p.HashAlgo, catalog))
+		return ReportSkipped, mirrorExisting(fsys, destPath, p, power, summary), nil
 	}
 
 	// Ensure the destination directory exists
-	if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
-		return err
+	if err := fsys.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+		return ReportFailed, nil, err
 	}
 
+	// Finished edits are never degraded by recompression
+	compress := isJPG && p.Compression >= 0
+	keptEdit := compress && p.KeepEdits && IsEdited(ExtractXMP(buffer, sourceFile))
+
 	var outputBuffer []byte
-	var msg string
-	if isJPG && p.Compression >= 0 {
+	var tag, status string
+	if compress && !keptEdit {
 		// Decode and re-encode with compression
+		compressStart := time.Now()
 		img, _, err := image.Decode(bytes.NewReader(buffer))
 		if err != nil {
-			return err
+			return ReportFailed, nil, err
 		}
 
-		var compressedBuffer bytes.Buffer
-		err = jpeg.Encode(&compressedBuffer, img, &jpeg.Options{Quality: p.Compression})
+		compressed, err := encodeJPEG(img, p.Compression)
 		if err != nil {
-			return err
+			return ReportFailed, nil, err
 		}
-		outputBuffer = compressedBuffer.Bytes()
-		summary.Compressed++
-		msg = "[COMPRESSED]"
+		outputBuffer = preserveXMP(buffer, compressed)
+		summary.Stats.Compress += time.Since(compressStart)
+		tag, status = "COMPRESSED", ReportCompressed
 	} else {
-		// Use the original buffer if not JPG or compression is disabled
+		// Use the original buffer if not JPG, compression is disabled or the file is an edit
 		outputBuffer = buffer
-		summary.Copied++
-		msg = "[COPIED]"
+		tag, status = "COPIED", ReportCopied
 	}
 
-	// Create the destination file
-	destFile, err := os.Create(destPath)
+	// JPEG files carry their provenance, others get a sidecar once written
+	embedded := false
+	if pv != nil {
+		if status == ReportCompressed {
+			pv.Compression = p.Compression
+		}
+		if embedsProvenance(p, isJPG) {
+			outputBuffer, embedded = pv.embed(outputBuffer)
+		}
+	}
+
+	outputBuffer, err := enc.Seal(name, outputBuffer)
+	if err != nil {
+		return ReportFailed, nil, fmt.Errorf("failed to encrypt file: %w", err)
+	}
+
+	// Write the processed buffer, unless another worker wrote the destination
+	// file in the meantime. The data is on the disk once the file appears.
+	writeStart := time.Now()
+	n, err := writeMediaFile(fsys, destPath, func(file File) (int64, error) {
+		n, err := file.Write(outputBuffer)
+		return int64(n), err
+	})
+	summary.Stats.addWrite(n, time.Since(writeStart))
+	if errors.Is(err, fs.ErrExist) {
+		output.Status("SKIPPED", fmt.Sprintf("Destination file already exists: %s", destPath))
+		summary.skip(existingSkip(fsys, destPath, bufferPayload(buffer), transformed, p.HashAlgo, catalog))
+		return ReportSkipped, mirrorExisting(fsys, destPath, p, power, summary), nil
+	}
 	if err != nil {
-		return err
+		return ReportFailed, nil, fmt.Errorf("failed to write destination file: %w", err)
 	}
-	defer destFile.Close()
 
-	// Write the processed buffer
-	_, err = destFile.Write(outputBuffer)
-	log.Printf("%s Processed file to: %s", msg, destPath)
+	if status == ReportCompressed {
+		summary.Compressed++
+	} else {
+		summary.Copied++
+		if keptEdit {
+			summary.EditsKept++
+		}
+	}
+	output.Status(tag, fmt.Sprintf("Processed file to: %s", destPath))
 	summary.Processed++
+	if pv != nil && !embedded {
+		recordProvenance(fsys, plainPath, pv, enc)
+	}
+	protectFile(fsys, destPath, p, summary)
+
+	mirrors, mirrorErr := writeMirrors(fsys, destPath, bufferPayload(outputBuffer), p, power, summary)
 
+	// The source is only deleted once its copies are known to be intact
 	if p.DeleteSource {
-		if err := os.Remove(sourceFile); err != nil {
-			return fmt.Errorf("failed to delete source file: %w", err)
+		if err := verifyCopy(fsys, destPath, bufferPayload(outputBuffer), p, power); err != nil {
+			return status, mirrors, fmt.Errorf("source file kept: %w", err)
 		}
-		log.Printf("[DELETED] Deleted source file: %s", sourceFile)
+		summary.Verified++
+
+		if mirrorErr != nil {
+			return status, mirrors, fmt.Errorf("source file kept: %w", mirrorErr)
+		}
+
+		// A source changed since it was read holds data the copies lack
+		if err := checkSourceUnchanged(fsys, sourceFile, sourceInfo); err != nil {
+			return status, mirrors, fmt.Errorf("source file kept: %w", err)
+		}
+
+		if err := fsys.Remove(sourceFile); err != nil {
+			return status, mirrors, fmt.Errorf("failed to delete source file: %w", err)
+		}
+		output.Status("DELETED", fmt.Sprintf("Deleted source file: %s", sourceFile))
 		summary.Deleted++
 	}
 
-	return err
+	return status, mirrors, nil
+}
+
+// verifyWrittenFile reads back a destination file and checks that its content
+// hash matches the data that was written. The file is read in pieces, so
+// files larger than the memory can be verified.
+func verifyWrittenFile(fsys FileSystem, path string, written payload, algo string) error {
+	// A file of another size cannot match, it is not read back
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify destination file: %w", err)
+	}
+	if info.Size() != written.size {
+		return fmt.Errorf("destination file %s does not match the written data", path)
+	}
+
+	want, err := written.hash(algo)
+	if err != nil {
+		return fmt.Errorf("failed to verify destination file: %w", err)
+	}
+	got, err := filePayload(fsys, path, info.Size()).hash(algo)
+	if err != nil {
+		return fmt.Errorf("failed to verify destination file: %w", err)
+	}
+	if got != want {
+		return fmt.Errorf("destination file %s does not match the written data", path)
+	}
+	return nil
 }
 
+// ProcessMediaFiles organizes the media files of the source into the destination
 func ProcessMediaFiles(p *models.Params) (ProcessingSummary, error) {
+	return ProcessMediaFilesCtx(context.Background(), p, nil)
+}
+
+// ProcessMediaFilesCtx organizes the media files of the source into the
+// destination until ctx is cancelled, in which case files not yet started are
+// left untouched and ctx.Err() is returned with the summary of processed files.
+// If events is not nil, the progress of every file is sent to it; sends block
+// until the event is received or ctx is done. The channel is not closed.
+func ProcessMediaFilesCtx(ctx context.Context, p *models.Params, events chan<- Event) (ProcessingSummary, error) {
+	return ProcessMediaFilesFS(ctx, OSFileSystem{}, p, events)
+}
+
+// ProcessMediaFilesFS is ProcessMediaFilesCtx with the source, destination
+// and mirror files on fsys, such as a MemFS or a file system injecting faults
+func ProcessMediaFilesFS(ctx context.Context, fsys FileSystem, p *models.Params, events chan<- Event) (ProcessingSummary, error) {
 	start := time.Now()
 	var summary ProcessingSummary
 
-	log.Printf("Starting processing files...")
+	output.Info("Starting processing files...")
 
-	err := filepath.Walk(p.Source, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("failed to access path %q: %w", path, err)
-		}
+	// Temporary files of interrupted runs are never completed, they only take space
+	if removed, err := CleanOrphanedTemp(p.Destination, p.CacheFile, p.CatalogFile, p.ReportFile, p.TimelineFile); err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to remove orphaned temporary files: %v", err))
+	} else if removed > 0 {
+		output.Info(fmt.Sprintf("Removed %d orphaned temporary files of interrupted runs", removed))
+	}
+	releaseTemp, err := openRunTempDir(p.Destination, newRunID(start))
+	if err != nil {
+		return summary, err
+	}
+	defer releaseTemp()
 
-		if !info.IsDir() && isAllowedExtension(filepath.Ext(info.Name())) {
-			fmt.Printf("Processing file: %s\n", path)
+	var cache *MetadataCache
+	if p.CacheFile != "" {
+		var err error
+		if cache, err = LoadMetadataCache(p.CacheFile); err != nil {
+			return summary, err
+		}
+	}
 
-			// Open the file
-			file, err := os.Open(path)
+	var catalog *Catalog
+	var runID string
+	if p.CatalogFile != "" {
+		if !IsSupportedHashAlgo(p.HashAlgo) {
+			return summary, fmt.Errorf("unsupported hash algorithm: %s", p.HashAlgo)
+		}
+		var err error
+		if catalog, err = OpenCatalog(p.CatalogFile); err != nil {
+			return summary, err
+		}
+		defer catalog.Close()
+		// A new catalog of an existing archive starts from the checksums it already holds
+		if _, err := os.Stat(p.Destination); err == nil && catalog.Len() == 0 {
+			// The catalog is only a head start, the run goes on without it
+			imported, err := ImportManifests(catalog, p.Destination, p.HashAlgo, false)
 			if err != nil {
-				summary.Skipped++
-				log.Printf("[SKIPPED] Could not open file %s: %v", path, err)
-				return nil // Continue to next file
+				output.Status("WARNING", fmt.Sprintf("Failed to import checksum manifests: %v", err))
+			} else if imported.Imported > 0 {
+				output.Info(fmt.Sprintf("Recorded %d destination files from %d checksum manifests", imported.Imported, imported.Manifests))
 			}
-			defer file.Close()
+		}
+		runID = newRunID(start)
+	}
 
-			// Read the entire file into memory
-			buffer, err := io.ReadAll(file)
-			if err != nil {
-				summary.Skipped++
-				log.Printf("[SKIPPED] Could not read file %s: %v", path, err)
-				return nil // Continue to next file
-			}
+	var state *SourceState
+	if p.SinceLast {
+		var err error
+		if state, err = LoadSourceState(p.Destination, p.Source); err != nil {
+			return summary, err
+		}
+	}
 
-			// Check if it's a JPG
-			isJPG := strings.HasSuffix(strings.ToLower(path), ".jpg") || strings.HasSuffix(strings.ToLower(path), ".jpeg")
+	var report *Report
+	if p.ReportFile != "" {
+		report = NewReport(p)
+	}
 
-			// Extract date from EXIF metadata
-			date, err := GetImageDateTime(buffer, filepath.Ext(info.Name()))
-			if err != nil {
-				summary.Skipped++
-				log.Printf("[SKIPPED] Could not get date from EXIF data for %s: %v", path, err)
-				return nil // Continue to next file
-			}
+	culled, err := loadCullOrphans(p)
+	if err != nil {
+		return summary, err
+	}
+
+	edits, err := loadPhoneEdits(fsys, p)
+	if err != nil {
+		return summary, err
+	}
+
+	names, kinds, seqs, err := loadShotNames(fsys, p, cache)
+	if err != nil {
+		return summary, err
+	}
+	names = edits.placeNames(names)
+	// Numbers are kept before anything is written, so a resumed run gives the same
+	if err := seqs.Save(); err != nil {
+		return summary, err
+	}
+
+	albums, err := loadAlbums(p)
+	if err != nil {
+		return summary, err
+	}
 
-			// Format destination folder structure
-			destDir := filepath.Join(p.Destination, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d-%02d", date.Month(), date.Day()))
-			destPath := filepath.Join(destDir, filepath.Base(path))
+	lightroom, err := loadLightroom(p)
+	if err != nil {
+		return summary, err
+	}
+
+	enc, err := loadEncryptor(p)
+	if err != nil {
+		return summary, err
+	}
+
+	offsets, err := loadClockOffsets(p)
+	if err != nil {
+		return summary, err
+	}
+
+	if _, err := layoutOf(p); err != nil {
+		return summary, err
+	}
+	if _, err := yearRootsOf(p); err != nil {
+		return summary, err
+	}
+
+	var timeline *Timeline
+	if p.TimelineFile != "" {
+		timeline = NewTimeline()
+	}
+
+	fat := DestinationIsFAT(p)
+	fold := DestinationFoldsCase(fsys, p, fat)
+	run := &mediaRun{
+		ctx:         ctx,
+		p:           p,
+		fs:          fsys,
+		cache:       cache,
+		catalog:     catalog,
+		state:       state,
+		report:      report,
+		events:      events,
+		culled:      culled,
+		edits:       edits,
+		device:      isDeviceExport(fsys, p),
+		screenshots: screenshotsPolicy(fsys, p),
+		lightroom:   lightroom,
+		names:       names,
+		kinds:       kinds,
+		albums:      albums,
+		enc:         enc,
+		limiter:     newDirLimiter(fsys, p, enc),
+		offsets:     offsets,
+		ordered:     timeline,
+		claims:      newDestinationClaims(fold),
+		folded:      newFoldedNames(fsys, fold, enc),
+		runID:       runID,
+		fat:         fat,
+		power:       newPowerMonitor(p),
+	}
+	pool := newWorkerPool(runWorkers(p), run.processFile)
+	selected := newFileSet(p.Files)
 
-			// Copy or compress before writing
-			if err := copyOrCompressImage(destPath, path, buffer, isJPG, p, &summary); err != nil {
-				log.Printf("Failed to process file %s: %v", path, err)
-				return nil // Continue to next file
+	// Time spent waiting for workers, which is not part of the scan phase
+	var waited time.Duration
+	var unchanged int
+	var leftOut ProcessingSummary // Files left out while walking the source
+
+	err = walkSource(fsys, p, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+
+		if !info.IsDir() && isImportable(p, path) && selected.has(path) {
+			if state.Unchanged(path, info) {
+				unchanged++
+				return nil
 			}
+			if run.leaveOut(path, info, &leftOut) {
+				return nil
+			}
+			submitStart := time.Now()
+			pool.submit(fileJob{path: path, info: info})
+			waited += time.Since(submitStart)
 		}
 		return nil
 	})
 
-	if err != nil {
-		return summary, fmt.Errorf("failed to walk directory: %w", err)
+	closeStart := time.Now()
+	summary = pool.close()
+	waited += time.Since(closeStart)
+
+	// Files that changed while they were read get a second chance once the
+	// others are done, from their current state
+	for _, job := range run.deferred.take() {
+		if info, err := fsys.Stat(job.path); err == nil {
+			job.info = info
+		}
+		var deferred ProcessingSummary
+		run.processFile(job, &deferred)
+		summary.add(deferred)
+	}
+	summary.Unchanged = unchanged
+	summary.add(leftOut)
+
+	// Files imported before the walk failed are saved, reported and recorded
+	// like those of a complete run, and the walk error is returned afterwards
+	walkErr := err
+
+	if err := cache.Save(); err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to save metadata cache: %v", err))
+	}
+
+	if err := state.Save(); err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to save source state: %v", err))
+	}
+
+	if err := report.Write(p.ReportFile); err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to write report: %v", err))
+	}
+
+	if err := timeline.Write(p.TimelineFile); err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to write timeline: %v", err))
 	}
 
 	summary.Duration = time.Since(start)
+	summary.Stats.Scan = summary.Duration - waited
+
+	// Runs are recorded next to the files they imported, for the history command
+	if err := catalog.AddRun(newRunRecord(runID, p, start, summary, ctx.Err() != nil)); err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to record run in catalog: %v", err))
+	}
+
+	run.emit(Event{Kind: EventRunFinished, Summary: &summary})
+	if walkErr != nil {
+		return summary, fmt.Errorf("failed to walk directory: %w", walkErr)
+	}
+	return summary, ctx.Err()
+}
 
-	return summary, nil
+// mediaRun holds the state shared by the workers of a run
+type mediaRun struct {
+	ctx         context.Context
+	p           *models.Params
+	fs          FileSystem // File system of the source, destination and mirror files
+	cache       *MetadataCache
+	catalog     *Catalog
+	state       *SourceState
+	report      *Report
+	events      chan<- Event
+	culled      map[string]bool     // Orphaned files in cull mode
+	edits       *phoneEdits         // Edited copies exported by phones next to their originals, nil without policy
+	device      bool                // Source is a phone or tablet export
+	screenshots string              // Policy for screenshots, as ScreenshotsPolicy returns it
+	lightroom   *LightroomCatalog   // Files managed in Lightroom, nil without catalog
+	names       map[string]string   // Destination names of files placed apart or renamed, relative to the day folder
+	kinds       map[string]string   // Detected timelapse frames and panoramas
+	albums      map[string][]string // Google Takeout albums of the source files
+	enc         *Encryptor          // Encryption of destination files, nil when disabled
+	limiter     *dirLimiter         // Cap on the number of files per destination directory, nil when disabled
+	offsets     ClockOffsets        // Clock offsets of camera bodies, nil when none are known
+	ordered     *Timeline           // Imported files ordered by capture time, nil when not requested
+	claims      *destinationClaims  // Destinations reserved by the files of the run
+	folded      *foldedNames        // Destination names in another case, nil when case tells names apart
+	deferred    deferredFiles       // Files that changed while they were read
+	runID       string              // Identifier of the run in the catalog, empty without catalog
+	fat         bool                // Destination names must be valid on FAT and exFAT
+	power       *powerMonitor       // Pauses between files on battery, nil unless in low-power mode
+}
+
+// leaveOut applies the policies leaving out files before they are read, cull
+// mode and phone edits, reporting whether the file at path was left out
+func (r *mediaRun) leaveOut(path string, info os.FileInfo, summary *ProcessingSummary) bool {
+	if r.culled[path] {
+		r.cullFile(path, info, summary)
+		return true
+	}
+	if reason, skip := r.edits.skip(path); skip {
+		r.skipPhoneEdit(path, info, reason, summary)
+		return true
+	}
+	return false
+}
+
+// processFile imports one source file, recording its outcome in summary
+func (r *mediaRun) processFile(job fileJob, summary *ProcessingSummary) {
+	if r.ctx.Err() != nil {
+		return // Cancelled, leave remaining files untouched
+	}
+	// Pauses are not part of the time spent on the file
+	defer r.power.yield(r.ctx)
+
+	path, info := job.path, job.info
+	output.Debug(fmt.Sprintf("Processing file: %s", path))
+	r.emit(Event{Kind: EventFileStarted, File: ReportEntry{Source: path, Size: info.Size()}})
+	fileStart := time.Now()
+	defer func() { summary.Stats.Busy += time.Since(fileStart) }()
+
+	entry := ReportEntry{Source: path, Size: info.Size(), Tags: kindTags(r.kinds, path), Albums: r.albums[path]}
+
+	proxy := isProxyFile(path)
+	unknown := isUnknownFile(r.p, path)
+	if r.skipProxy(entry, proxy, summary) {
+		return
+	}
+
+	// Files of tethered-capture hot folders may still be arriving, leave them for the next run
+	if err := checkWriteComplete(r.fs, path, info, r.p.Settle); err != nil {
+		summary.skip(SkipFiltered)
+		output.Status("SKIPPED", fmt.Sprintf("Left for the next run, %v: %s", err, path))
+		entry.Status, entry.Reason, entry.SkipReason = ReportSkipped, err.Error(), SkipFiltered
+		r.finish(entry)
+		return
+	}
+
+	// Files already at their destination are skipped from their header alone,
+	// so re-runs over mostly imported sources do not read every file in full
+	if r.skipExisting(entry, path, info, proxy, unknown, summary) {
+		return
+	}
+
+	// Open the file
+	file, err := openFile(r.fs, path)
+	if err != nil {
+		summary.skip(SkipUnreadable)
+		output.Status("SKIPPED", fmt.Sprintf("Could not open file %s: %v", path, err))
+		entry.Status, entry.Reason, entry.SkipReason = ReportSkipped, err.Error(), SkipUnreadable
+		r.finish(entry)
+		return
+	}
+	defer file.Close()
+
+	// Files too large to be held in memory, such as long videos, are only
+	// read where their metadata is, then streamed to the destination
+	isJPG := isJPEG(path)
+	seeker, seekable := file.(io.ReadSeeker)
+	streamed := seekable && isStreamed(r.p, info, isJPG, r.enc)
+
+	// Read the entire file into memory otherwise, in a buffer reused by the next files
+	var buffer []byte
+	if !streamed {
+		buffer, err = readAllBuffer(file, info.Size())
+		defer putBuffer(buffer)
+		summary.Stats.addRead(int64(len(buffer)), time.Since(fileStart))
+	}
+	content := bufferPayload(buffer)
+	if streamed {
+		content = filePayload(r.fs, path, info.Size())
+	}
+	// reader returns the content from its start, for each reading of its metadata
+	reader := func() io.ReadSeeker {
+		if streamed {
+			seeker.Seek(0, io.SeekStart)
+			return seeker
+		}
+		return bytes.NewReader(buffer)
+	}
+	if err != nil {
+		entry.Reason = err.Error()
+
+		// Keep the readable part of files from a degrading card rather than nothing
+		if r.p.SalvageDamaged && len(buffer) > 0 {
+			salvaged, salvageErr := salvagePartialFile(r.fs, r.p, path, buffer)
+			if salvageErr == nil {
+				summary.Salvaged++
+				output.Status("SALVAGED", fmt.Sprintf("Read error after %d of %d bytes, kept readable part of %s to: %s", len(buffer), info.Size(), path, salvaged))
+				entry.Status, entry.Destination, entry.RecoveredBytes = ReportSalvaged, salvaged, int64(len(buffer))
+				r.finish(entry)
+				return
+			}
+			output.Status("ERROR", fmt.Sprintf("Failed to salvage file %s: %v", path, salvageErr))
+		}
+
+		summary.skip(SkipUnreadable)
+		output.Status("SKIPPED", fmt.Sprintf("Could not read file %s: %v", path, err))
+		entry.Status, entry.SkipReason = ReportSkipped, SkipUnreadable
+		r.finish(entry)
+		return
+	}
+
+	// Files still being written, such as by a sync client, are read again at
+	// the end of the run, and left for the next run if they keep changing
+	if !streamed && int64(len(buffer)) != info.Size() {
+		err = ErrSourceChanged
+	} else {
+		err = checkSourceUnchanged(r.fs, path, info)
+	}
+	if errors.Is(err, ErrSourceChanged) && !job.deferred {
+		output.Debug(fmt.Sprintf("File changed while it was read, processing it again at the end of the run: %s", path))
+		r.deferred.add(job)
+		return
+	}
+	if err != nil {
+		reason := SkipUnreadable
+		if errors.Is(err, ErrSourceChanged) {
+			reason = SkipFiltered
+		}
+		summary.skip(reason)
+		output.Status("SKIPPED", fmt.Sprintf("Left for the next run, %v: %s", err, path))
+		entry.Status, entry.Reason, entry.SkipReason = ReportSkipped, err.Error(), reason
+		r.finish(entry)
+		return
+	}
+
+	// Empty and truncated files are reported apart, they are not worth a retry.
+	// Files of unsupported formats are copied as they are.
+	if err := checkIntegrity(reader(), content.size, path); err != nil && !unknown {
+		r.isolateCorrupt(path, info, content, err, summary)
+		return
+	}
+
+	screenshot, skipped := r.applyScreenshots(&entry, reader(), proxy, unknown, summary)
+	if skipped {
+		return
+	}
+
+	// Surface face regions for gallery software reading the report or catalog.
+	// Streamed files are videos, which carry no face regions.
+	var faces []FaceRegion
+	if (r.report != nil || r.catalog != nil) && !streamed {
+		faces = FaceRegions(ExtractXMP(buffer, path))
+		entry.Faces = faces
+	}
+
+	// Skip files already recorded as imported, whatever their destination name.
+	// Only files the size of a recorded file can match one, others are hashed
+	// when recorded.
+	var hash string
+	if r.p.Incremental && r.catalog.HasSize(content.size) {
+		if hash, err = content.hash(r.p.HashAlgo); err != nil {
+			summary.skip(SkipUnreadable)
+			output.Status("SKIPPED", fmt.Sprintf("Could not read file %s: %v", path, err))
+			entry.Status, entry.Reason, entry.SkipReason = ReportSkipped, err.Error(), SkipUnreadable
+			r.finish(entry)
+			return
+		}
+		if record, ok := r.catalog.Lookup(hash); ok {
+			summary.skip(SkipExistsIdentical)
+			output.Status("SKIPPED", fmt.Sprintf("Already imported as %s: %s", record.Destination, path))
+			entry.Status, entry.Reason, entry.SkipReason = ReportSkipped, "already imported as "+record.Destination, SkipExistsIdentical
+			r.finish(entry)
+			return
+		}
+	}
+
+	// Extract date from EXIF metadata, unless the cache already knows this file
+	date, ok := r.cache.Get(path, info)
+	captured := false // Dated by the camera clock
+	manual, overridden := r.p.DateOverrides[path]
+	if unknown {
+		// Files of unsupported formats have no capture date
+		date = info.ModTime()
+	} else if overridden {
+		date, entry.ManualDate = manual, true
+	} else if folder, trusted := trustedFolderDate(r.p, path); trusted {
+		// Files of an already organized source keep their folder, their metadata is not read
+		date = folder
+	} else if ok {
+		summary.CacheHits++
+		captured = true
+	} else {
+		extractStart := time.Now()
+		date, err = mediaDate(r.fs, path, reader(), r.p.NoGPS)
+		captured = err == nil
+		if err != nil && !proxy && datedAsScreenshot(path, reader()) {
+			// Screenshots rarely carry EXIF dates, their name or modification time does
+			date, err = screenshotDate(path, info), nil
+		}
+		summary.Stats.Extract += time.Since(extractStart)
+		if err != nil {
+			summary.skip(dateSkip(err))
+			output.Status("SKIPPED", fmt.Sprintf("Could not get date from EXIF data for %s: %v", path, err))
+			entry.Status, entry.Reason, entry.SkipReason = ReportSkipped, err.Error(), dateSkip(err)
+			r.finish(entry)
+			return
+		}
+		r.cache.Put(path, info, date)
+	}
+
+	if r.skipLightroom(&entry, date, summary) {
+		return
+	}
+
+	// Camera clocks known to be off are corrected, the cache keeps the recorded date
+	recorded := date
+	var meta Metadata
+	if len(r.offsets) > 0 || r.ordered != nil {
+		meta, _ = GetImageMetadata(reader(), filepath.Ext(path))
+	}
+	if captured && !proxy {
+		date = r.offsets.correct(date, meta.Serial)
+	}
+	if captured {
+		date = r.edits.date(path, date)
+	}
+
+	// Let external validators, such as virus scanners, veto the file before it is written
+	if r.p.CheckCommand != "" {
+		if err := checkFile(r.p.CheckCommand, path); errors.Is(err, ErrRejected) {
+			r.rejectFile(entry, content, err, summary)
+			return
+		} else if err != nil {
+			summary.Failed++
+			output.Status("ERROR", fmt.Sprintf("Failed to check file %s: %v", path, err))
+			entry.Status, entry.Reason = ReportFailed, err.Error()
+			r.finish(entry)
+			return
+		}
+	}
+
+	// Format destination folder structure
+	destPath := r.limiter.place(r.destination(path, info, date, proxy, screenshot, unknown))
+
+	// Another source file of the run may share this destination, only the first one is written
+	if first, ok := r.claims.claim(destPath, path); !ok {
+		conflict := &DestinationConflictError{Path: r.enc.Path(destPath), Source: first}
+		summary.skip(SkipExistsConflict)
+		output.Status("SKIPPED", fmt.Sprintf("Not writing %s, %v", path, conflict))
+		entry.Status, entry.Destination, entry.Reason, entry.SkipReason = ReportSkipped, conflict.Path, conflict.Error(), SkipExistsConflict
+		r.finish(entry)
+		r.linkAlbums(path, conflict.Path)
+		return
+	}
+
+	// RAW files of the converted formats are written as DNG, their original to
+	// the originals tree, and copied as is when the conversion fails
+	if convertsToDNG(r.p, path) && !streamed {
+		// Catalog records and provenance keep the hash of the source
+		if hash == "" && (r.catalog != nil || r.p.Provenance != "") {
+			hash = HashBuffer(buffer, r.p.HashAlgo)
+		}
+		if dngPath, dng, err := r.toDNG(path, destPath, date, buffer); err != nil {
+			output.Status("WARNING", fmt.Sprintf("DNG conversion failed for %s, copied as is: %v", path, err))
+		} else {
+			entry.Original = r.enc.Path(dngOriginalDestination(r.p, destPath, date))
+			destPath, buffer = dngPath, dng
+		}
+	}
+
+	// Corrected dates are written back into JPEG files, in a sidecar next to other files
+	writeDate := r.p.WriteDates && !proxy && !unknown && r.correctedDate(path, reader(), recorded, date, overridden, captured)
+	embedDate := writeDate && isJPG && !streamed
+	if embedDate {
+		// Catalog records and provenance keep the hash of the source
+		if hash == "" && (r.catalog != nil || r.p.Provenance != "") {
+			hash = HashBuffer(buffer, r.p.HashAlgo)
+		}
+		buffer = withCaptureDate(buffer, date, r.p.Compression >= 0)
+	}
+
+	// The catalog record and the provenance of the file share its identifier
+	var id string
+	if r.catalog != nil || r.p.Provenance != "" {
+		id = newFileID(time.Now())
+	}
+
+	// Copy or compress before writing
+	var status string
+	var mirrors []MirrorResult
+	if streamed {
+		var sum string
+		status, mirrors, sum, err = copyLargeFile(r.fs, destPath, path, info, r.p, r.power, summary)
+		if hash == "" {
+			hash = sum
+		}
+		if pv := newProvenance(r.p, path, hash, id); pv != nil && status == ReportCopied {
+			recordProvenance(r.fs, destPath, pv, r.enc)
+		}
+	} else {
+		// The provenance records the hash of the source, as the catalog does
+		if r.p.Provenance != "" && hash == "" {
+			hash = HashBuffer(buffer, r.p.HashAlgo)
+		}
+		status, mirrors, err = copyOrCompressImage(r.fs, destPath, path, info, buffer, isJPG, r.p, r.power, r.catalog, r.enc, newProvenance(r.p, path, hash, id), summary)
+	}
+	if writeDate && !embedDate && err == nil && (status == ReportCopied || status == ReportCompressed) {
+		recordCaptureDate(r.fs, destPath, date, r.enc)
+	}
+	destPath = r.enc.Path(destPath)
+	entry.Status, entry.Destination, entry.Mirrors = status, destPath, mirrors
+	// summary only holds the counts of this file
+	entry.Verified, entry.SourceDeleted = summary.Verified > 0, summary.Deleted > 0
+	if status == ReportSkipped {
+		entry.Reason, entry.SkipReason = existingReason(r.fs, destPath), summary.skipReason()
+	}
+	if err != nil {
+		summary.Failed++
+		output.Status("ERROR", fmt.Sprintf("Failed to process file %s: %v", path, err))
+		entry.Reason = err.Error()
+		r.finish(entry)
+		return
+	}
+	r.finish(entry)
+
+	if status == ReportCopied || status == ReportCompressed {
+		r.ordered.Add(TimelineEntry{Date: date, Camera: meta.Camera(), Serial: meta.Serial, Source: path, Destination: destPath})
+	}
+
+	// Album copies of Takeout exports land on the picture already organized
+	// from the year folder, link it in any case
+	r.linkAlbums(path, destPath)
+
+	// Files now at the destination are left alone by the next -since-last import
+	r.state.Mark(path, info)
+
+	// Record newly written files in the catalog
+	if r.catalog != nil && (status == ReportCopied || status == ReportCompressed) {
+		if hash == "" {
+			hash = HashBuffer(buffer, r.p.HashAlgo)
+		}
+		if err := r.catalog.Add(CatalogRecord{
+			ID:          id,
+			Hash:        hash,
+			Source:      path,
+			Destination: destPath,
+			Size:        info.Size(),
+			ImportedAt:  time.Now(),
+			Tags:        entry.Tags,
+			Faces:       faces,
+			Albums:      r.albums[path],
+			RunTags:     r.p.RunTags,
+			RunID:       r.runID,
+		}); err != nil {
+			summary.Failed++
+			output.Status("ERROR", fmt.Sprintf("Failed to record %s in catalog: %v", path, err))
+		}
+	}
+}
+
+// destination returns where a file taken at date is organized, before the
+// directory limiter places it
+func (r *mediaRun) destination(path string, info os.FileInfo, date time.Time, proxy, screenshot, unknown bool) string {
+	destPath := sequenceDestination(r.p, path, date, r.names)
+	if proxy {
+		destPath = proxyDestination(r.p, path, date)
+	}
+	if screenshot && r.screenshots == ScreenshotsRoute {
+		destPath = screenshotDestination(r.p, path, date)
+	}
+	if unknown {
+		destPath = unknownDestination(r.p, path, info)
+	}
+	if r.fat {
+		destPath = fatSafePath(rootOf(r.p, destPath), destPath)
+	}
+	return r.folded.resolve(rootOf(r.p, destPath), destPath)
+}
+
+// existingReason returns the report reason of a file skipped because its
+// destination already exists
+func existingReason(fsys FileSystem, destPath string) string {
+	if isWriteProtected(fsys, destPath) {
+		return "destination file already exists and is write-protected"
+	}
+	return "destination file already exists"
+}
+
+// FormatSize formats the size in bytes to a human-readable string in GB, MB, or KB.
+func FormatSize(size int64) string {
+	const (
+		KB = 1 << 10
+		MB = 1 << 20
+		GB = 1 << 30
+	)
+
+	switch {
+	case size >= GB:
+		return fmt.Sprintf("%.2f GB", float64(size)/GB)
+	case size >= MB:
+		return fmt.Sprintf("%.2f MB", float64(size)/MB)
+	case size >= KB:
+		return fmt.Sprintf("%.2f KB", float64(size)/KB)
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
+
+// isJPEG checks if the file is a JPEG, the only format that can be compressed
+func isJPEG(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".jpg" || ext == ".jpeg"
+}
+
+// jpegContainer reports whether files of the extension store their metadata
+// like JPEG files: JPEG pictures, the 3D MPO pictures made of JPEG images and
+// Insta360 pictures. Only JPEG pictures are recompressed, which would drop the
+// other images of MPO files and the stitching data of Insta360 pictures.
+func jpegContainer(ext string) bool {
+	return ext == ".jpg" || ext == ".jpeg" || ext == ".mpo" || ext == insta360Photo
 }
 
 // isAllowedExtension checks if the file extension is in the list of allowed extensions.
@@ -165,33 +906,91 @@ func isAllowedExtension(ext string) bool {
 
 // CountFiles counts the number of files with allowed extensions in a directory.
 func CountFiles(dir string) (int, int64, error) {
+	walk := func(fn filepath.WalkFunc) error { return walkFiles(OSFileSystem{}, dir, fn) }
+	return countFiles(dir, walk, func(path string) bool {
+		return isAllowedExtension(filepath.Ext(path))
+	})
+}
+
+// CountImportableFiles counts the number of source files a run handles,
+// including proxies and files of unsupported formats when the run copies them
+func CountImportableFiles(p *models.Params) (int, int64, error) {
+	return countFiles(p.Source, func(fn filepath.WalkFunc) error { return walkSource(OSFileSystem{}, p, fn) }, func(path string) bool {
+		return isImportable(p, path)
+	})
+}
+
+// countFiles counts the number and size of the files of a directory matching
+// include, listed with walk
+func countFiles(dir string, walk func(fn filepath.WalkFunc) error, include func(path string) bool) (int, int64, error) {
 	var count int
 	var totalSize int64
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	err := walk(func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
 		// Increment count for files with allowed extensions
-		if !info.IsDir() && isAllowedExtension(filepath.Ext(info.Name())) {
+		if !info.IsDir() && include(path) {
 			count++
 			totalSize += info.Size()
 		}
 		return nil
 	})
 
-	log.Printf("CountFiles: %d files found in %s\n", count, dir)
+	output.Debug(fmt.Sprintf("CountFiles: %d files found in %s", count, dir))
 
 	return count, totalSize, err
 }
 
-func fileExists(path string) (bool, error) {
-	_, err := os.Stat(path)
+// walkSource calls fn for the files of the source of a run on fsys like
+// walkFiles, or only for the selected files when the run has some, without
+// scanning the source. Selected files that no longer exist are left out.
+func walkSource(fsys FileSystem, p *models.Params, fn filepath.WalkFunc) error {
+	// The tool never imports its own output, wherever it was written
+	self := selfPaths(p)
+	walkFn := fn
+	fn = func(path string, info os.FileInfo, err error) error {
+		if err == nil && isSelfPath(self, path) {
+			output.Debug(fmt.Sprintf("Ignoring file written by the tool: %s", path))
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return walkFn(path, info, err)
+	}
+
+	if len(p.Files) == 0 {
+		return walkFiles(fsys, p.Source, fn)
+	}
+
+	files := make([]string, 0, len(p.Files))
+	for f := range newFileSet(p.Files) {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, path := range files {
+		info, err := fsys.Lstat(path)
+		if isNotExist(err) {
+			continue
+		}
+		if err := fn(path, info, err); err == filepath.SkipAll {
+			return nil
+		} else if err != nil && err != filepath.SkipDir {
+			return err
+		}
+	}
+	return nil
+}
+
+func fileExists(fsys FileSystem, path string) (bool, error) {
+	_, err := fsys.Stat(path)
 	if err == nil {
 		return true, nil
 	}
-	if os.IsNotExist(err) {
+	if isNotExist(err) {
 		return false, nil
 	}
 	return false, err
@@ -75,6 +75,49 @@ func TestIsAllowedExtension(t *testing.T) {
 	}
 }
 
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int64
+		expected string
+	}{
+		{
+			name:     "Bytes",
+			size:     500,
+			expected: "500 bytes",
+		},
+		{
+			name:     "Kilobytes",
+			size:     1500,
+			expected: "1.46 KB",
+		},
+		{
+			name:     "Megabytes",
+			size:     1500000,
+			expected: "1.43 MB",
+		},
+		{
+			name:     "Gigabytes",
+			size:     1500000000,
+			expected: "1.40 GB",
+		},
+		{
+			name:     "Zero",
+			size:     0,
+			expected: "0 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FormatSize(tt.size)
+			if result != tt.expected {
+				t.Errorf("FormatSize(%d) = %s; want %s", tt.size, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestCountFiles(t *testing.T) {
 	tempDir := t.TempDir()
 	allowedFile := filepath.Join(tempDir, "test.jpg")
@@ -105,7 +148,7 @@ func TestFileExists(t *testing.T) {
 	}
 
 	t.Run("existing file", func(t *testing.T) {
-		exists, err := fileExists(testFile)
+		exists, err := fileExists(OSFileSystem{}, testFile)
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -115,7 +158,7 @@ func TestFileExists(t *testing.T) {
 	})
 
 	t.Run("non-existent file", func(t *testing.T) {
-		exists, err := fileExists(filepath.Join(tempDir, "nonexistent.txt"))
+		exists, err := fileExists(OSFileSystem{}, filepath.Join(tempDir, "nonexistent.txt"))
 		if err != nil {
 			t.Errorf("Unexpected error: %v", err)
 		}
@@ -136,7 +179,7 @@ func TestFileExists(t *testing.T) {
 		}
 		defer os.Chmod(noPermDir, 0700) // Restore permissions for cleanup
 
-		exists, err := fileExists(filepath.Join(noPermDir, "test.txt"))
+		exists, err := fileExists(OSFileSystem{}, filepath.Join(noPermDir, "test.txt"))
 		if err == nil {
 			t.Error("Expected permission error, got nil")
 		}
@@ -205,6 +248,15 @@ func TestCopyOrCompressImage(t *testing.T) {
 			wantSkipped:  false,
 			wantError:    false,
 		},
+		{
+			name:         "Keep source of skipped file",
+			sourceFile:   filepath.Join(srcDir, "existing_delete.jpg"),
+			isJPG:        true,
+			compression:  50,
+			deleteSource: true,
+			wantSkipped:  true,
+			wantError:    false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -232,7 +284,7 @@ func TestCopyOrCompressImage(t *testing.T) {
 			}
 
 			var summary ProcessingSummary
-			err := copyOrCompressImage(destPath, tt.sourceFile, imageData, tt.isJPG, params, &summary)
+			_, _, err := copyOrCompressImage(OSFileSystem{}, destPath, tt.sourceFile, nil, imageData, tt.isJPG, params, nil, nil, nil, nil, &summary)
 
 			if (err != nil) != tt.wantError {
 				t.Errorf("copyOrCompressImage() error = %v, wantError %v", err, tt.wantError)
@@ -243,6 +295,10 @@ func TestCopyOrCompressImage(t *testing.T) {
 				if summary.Skipped != 1 {
 					t.Errorf("Expected file to be skipped")
 				}
+				// A skipped file has no verified copy, its source must stay
+				if _, err := os.Stat(tt.sourceFile); err != nil {
+					t.Errorf("Source of skipped file was deleted: %v", err)
+				}
 				return
 			}
 
@@ -259,6 +315,9 @@ func TestCopyOrCompressImage(t *testing.T) {
 				if summary.Deleted != 1 {
 					t.Error("Deleted count not incremented")
 				}
+				if summary.Verified != 1 {
+					t.Error("Verified count not incremented")
+				}
 			}
 
 			// Verify compression/copy counters
@@ -772,3 +831,32 @@ func copyTestFile(src, dst string) error {
 	// Write to destination
 	return os.WriteFile(dst, data, 0644)
 }
+
+func TestVerifyWrittenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("written data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		written []byte
+		algo    string
+		wantErr bool
+	}{
+		{"Matching content", path, []byte("written data"), HashSHA256, false},
+		{"Matching content with BLAKE3", path, []byte("written data"), HashBLAKE3, false},
+		{"Corrupted content", path, []byte("other data"), HashSHA256, true},
+		{"Truncated content", path, []byte("written data and more"), HashSHA256, true},
+		{"Missing file", filepath.Join(t.TempDir(), "missing.jpg"), []byte("written data"), HashSHA256, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyWrittenFile(OSFileSystem{}, tt.path, bufferPayload(tt.written), tt.algo); (err != nil) != tt.wantErr {
+				t.Errorf("verifyWrittenFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
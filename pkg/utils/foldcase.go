@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// For testing purposes
+var detectCaseInsensitive = isCaseInsensitive
+
+// DestinationFoldsCase reports whether destination names differing only in
+// case are the same file, as set by -fold-case, on FAT and exFAT or detected
+// from the volume. Destinations shared with macOS or Windows clients over SMB
+// fold case for them even when the volume itself does not.
+func DestinationFoldsCase(fsys FileSystem, p *models.Params, fat bool) bool {
+	return p.FoldCase || fat || detectCaseInsensitive(fsys, p.Destination)
+}
+
+// isCaseInsensitive reports whether the volume holding dir finds a file under
+// its name in another case, writing a probe file to dir. It reports false
+// when dir cannot be written.
+func isCaseInsensitive(fsys FileSystem, dir string) bool {
+	probe := filepath.Join(dir, fmt.Sprintf(".organize-media-case-%d", os.Getpid()))
+	file, err := fsys.OpenFile(probe, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return false
+	}
+	file.Close()
+	defer fsys.Remove(probe)
+
+	_, err = fsys.Stat(filepath.Join(dir, strings.ToUpper(filepath.Base(probe))))
+	return err == nil
+}
+
+// foldedNames finds the folders and files of the destination named like a
+// planned path in another case, so a file is skipped or written next to them
+// instead of creating a second name clients cannot tell apart. Directories are
+// listed once per run, the files of the run itself are reserved through
+// destinationClaims.
+type foldedNames struct {
+	fs   FileSystem
+	enc  *Encryptor
+	mu   sync.Mutex
+	dirs map[string]map[string][]string // Names of a directory, on disk or planned by the run, keyed by lower case name
+}
+
+// newFoldedNames returns the case folding of a run, nil when the destination
+// tells names differing only in case apart
+func newFoldedNames(fsys FileSystem, fold bool, enc *Encryptor) *foldedNames {
+	if !fold {
+		return nil
+	}
+	return &foldedNames{fs: fsys, enc: enc, dirs: make(map[string]map[string][]string)}
+}
+
+// resolve returns destPath spelled as the folders and file found below root,
+// or planned by an earlier file of the run, under the same names in another
+// case. A nil foldedNames returns destPath.
+func (f *foldedNames) resolve(root, destPath string) string {
+	if f == nil {
+		return destPath
+	}
+	rel, err := filepath.Rel(root, destPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return destPath
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(rel, string(filepath.Separator))
+	dir := root
+	for i, part := range parts[:len(parts)-1] {
+		parts[i] = f.find(dir, part)
+		dir = filepath.Join(dir, parts[i])
+	}
+
+	// Encrypted files are found under their encrypted name
+	last := len(parts) - 1
+	name := filepath.Base(f.enc.Path(parts[last]))
+	if found := f.find(dir, name); found != name {
+		switch {
+		case f.enc == nil:
+			parts[last] = found
+		case !f.enc.hashNames && len(found) > len(EncryptedExt):
+			parts[last] = found[:len(found)-len(EncryptedExt)]
+		}
+	}
+	return filepath.Join(append([]string{root}, parts...)...)
+}
+
+// find returns the name of the entry of dir named name in any case, name
+// itself when there is none, reading dir the first time, with f.mu held. The
+// name returned is planned, so the next files of the run use it too.
+func (f *foldedNames) find(dir, name string) string {
+	names, ok := f.dirs[dir]
+	if !ok {
+		names = make(map[string][]string)
+		entries, _ := f.fs.ReadDir(dir)
+		for _, entry := range entries {
+			key := strings.ToLower(entry.Name())
+			names[key] = append(names[key], entry.Name())
+		}
+		f.dirs[dir] = names
+	}
+
+	key := strings.ToLower(name)
+	spellings := names[key]
+	if len(spellings) == 0 {
+		names[key] = []string{name}
+		return name
+	}
+	// A case-sensitive volume may hold several spellings, the exact one wins
+	for _, spelling := range spellings {
+		if spelling == name {
+			return name
+		}
+	}
+	return spellings[0]
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestFoldedNamesResolve(t *testing.T) {
+	dest := t.TempDir()
+	for _, file := range []string{"Canon/2024/06-11/DSC00001.JPG", "2024/06-11/IMG_0001.jpg", "2024/06-11/img_0002.JPG", "2024/06-11/IMG_0002.JPG", "2024/06-11/DSC00003.JPG.enc"} {
+		path := filepath.Join(dest, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	enc := &Encryptor{}
+
+	tests := []struct {
+		name string
+		fold bool
+		enc  *Encryptor
+		path string
+		want string
+	}{
+		{name: "exact name", fold: true, path: "2024/06-11/IMG_0001.jpg", want: "2024/06-11/IMG_0001.jpg"},
+		{name: "file in another case", fold: true, path: "2024/06-11/img_0001.JPG", want: "2024/06-11/IMG_0001.jpg"},
+		{name: "folder in another case", fold: true, path: "CANON/2024/06-11/DSC00002.JPG", want: "Canon/2024/06-11/DSC00002.JPG"},
+		{name: "both spellings on disk", fold: true, path: "2024/06-11/IMG_0002.JPG", want: "2024/06-11/IMG_0002.JPG"},
+		{name: "new file", fold: true, path: "2024/06-12/IMG_0003.JPG", want: "2024/06-12/IMG_0003.JPG"},
+		{name: "encrypted file in another case", fold: true, enc: enc, path: "2024/06-11/dsc00003.jpg", want: "2024/06-11/DSC00003.JPG"},
+		{name: "case tells names apart", path: "2024/06-11/img_0001.JPG", want: "2024/06-11/img_0001.JPG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := newFoldedNames(OSFileSystem{}, tt.fold, tt.enc)
+			got := folded.resolve(dest, filepath.Join(dest, filepath.FromSlash(tt.path)))
+			if want := filepath.Join(dest, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("resolve(%q) = %q, want %q", tt.path, got, want)
+			}
+		})
+	}
+}
+
+func TestFoldedNamesPlanned(t *testing.T) {
+	dest := t.TempDir()
+	folded := newFoldedNames(OSFileSystem{}, true, nil)
+
+	// Folders planned by an earlier file of the run are reused in another case
+	first := folded.resolve(dest, filepath.Join(dest, "CANON", "IMG_0001.JPG"))
+	second := folded.resolve(dest, filepath.Join(dest, "Canon", "IMG_0002.JPG"))
+	if want := filepath.Join(dest, "CANON", "IMG_0002.JPG"); second != want {
+		t.Errorf("resolve() after %q = %q, want %q", first, second, want)
+	}
+}
+
+func TestIsCaseInsensitive(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "probe"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	_, err := os.Stat(filepath.Join(dir, "PROBE"))
+	want := err == nil
+
+	if got := isCaseInsensitive(OSFileSystem{}, dir); got != want {
+		t.Errorf("isCaseInsensitive() = %v, want %v", got, want)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Probe file left in %s: %v", dir, entries)
+	}
+	if isCaseInsensitive(OSFileSystem{}, filepath.Join(dir, "missing")) {
+		t.Error("isCaseInsensitive() of a missing folder = true")
+	}
+}
+
+func TestProcessMediaFilesFoldCase(t *testing.T) {
+	source := t.TempDir()
+	for _, name := range []string{"img_0001.jpg", "IMG_0002.JPG", "img_0002.jpg"} {
+		dir := filepath.Join(source, name+".d")
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	for _, fold := range []bool{false, true} {
+		dest := t.TempDir()
+		dayDir := filepath.Join(dest, "2025", "01-11")
+		if err := os.MkdirAll(dayDir, os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dayDir, "IMG_0001.JPG"), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		params := &models.Params{Source: source, Destination: dest, Compression: -1, Workers: 1, FoldCase: fold}
+		summary, err := ProcessMediaFiles(params)
+		if err != nil {
+			t.Fatalf("ProcessMediaFiles() error = %v", err)
+		}
+
+		// Folding, the existing file and one of the two spellings of the run are skipped
+		wantCopied, wantSkipped := 3, 0
+		if fold {
+			wantCopied, wantSkipped = 1, 2
+		}
+		if summary.Copied != wantCopied || summary.Skipped != wantSkipped {
+			t.Errorf("ProcessMediaFiles() with fold %v copied %d and skipped %d files, want %d and %d", fold, summary.Copied, summary.Skipped, wantCopied, wantSkipped)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package utils
+
+// FreeSpace cannot read the free space of volumes on this platform, runs
+// never stop for lack of space before copying
+func FreeSpace(path string) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFreeSpace(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		t.Skip("free space is not read on this platform")
+	}
+	if free, ok := FreeSpace(t.TempDir()); !ok || free <= 0 {
+		t.Errorf("FreeSpace() = %d, %v, want free bytes", free, ok)
+	}
+	if _, ok := FreeSpace(filepath.Join(t.TempDir(), "missing")); ok {
+		t.Error("FreeSpace() of a missing folder = true, want false")
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package utils
+
+import "syscall"
+
+// FreeSpace returns the bytes available to the user on the volume holding
+// path, and false when they cannot be read
+func FreeSpace(path string) (int64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, false
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), true
+}
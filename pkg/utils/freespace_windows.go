@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// FreeSpace returns the bytes available to the user on the volume holding
+// path, and false when they cannot be read
+func FreeSpace(path string) (int64, bool) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, false
+	}
+	var available uint64
+	if ok, _, _ := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&available)), 0, 0); ok == 0 {
+		return 0, false
+	}
+	return int64(available), true
+}
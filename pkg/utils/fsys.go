@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// File is an open file of a FileSystem. *os.File is a File.
+type File interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Stat() (fs.FileInfo, error)
+	Sync() error
+}
+
+// FileSystem is the file system holding the source, destination and mirror
+// files of runs, the disk unless runs are given another one, such as a MemFS
+// or a file system injecting faults. Files of the run itself, such as the
+// catalog, cache, report and settings files, are always on disk, as are the
+// files handed to external commands and album links. Paths are native paths,
+// like those of the os package, and errors are *fs.PathError wrapping
+// fs.ErrNotExist, fs.ErrExist, fs.ErrPermission or the error of the
+// underlying system.
+type FileSystem interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error) // Sorted by name
+	MkdirAll(path string, perm fs.FileMode) error
+	Remove(name string) error
+	Chmod(name string, mode fs.FileMode) error
+	Rename(oldpath, newpath string) error // Replaces newpath when it exists
+	Link(oldname, newname string) error   // Fails with fs.ErrExist when newname exists
+}
+
+// OSFileSystem is the FileSystem of the os package
+type OSFileSystem struct{}
+
+func (OSFileSystem) Open(name string) (File, error) { return os.Open(name) }
+func (OSFileSystem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (OSFileSystem) Lstat(name string) (fs.FileInfo, error)       { return os.Lstat(name) }
+func (OSFileSystem) ReadDir(name string) ([]fs.DirEntry, error)   { return os.ReadDir(name) }
+func (OSFileSystem) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFileSystem) Remove(name string) error                     { return os.Remove(name) }
+func (OSFileSystem) Chmod(name string, mode fs.FileMode) error    { return os.Chmod(name, mode) }
+func (OSFileSystem) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+func (OSFileSystem) Link(oldname, newname string) error           { return os.Link(oldname, newname) }
+
+// readFile reads the whole file name of fsys
+func readFile(fsys FileSystem, name string) ([]byte, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// walkFiles walks the tree of fsys rooted at root like filepath.Walk: in
+// lexical order, without following symbolic links, calling fn for every file
+// and directory. fn may return filepath.SkipDir or filepath.SkipAll.
+func walkFiles(fsys FileSystem, root string, fn filepath.WalkFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, info, fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDir walks the tree below path for walkFiles
+func walkDir(fsys FileSystem, path string, info fs.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	entries, err := fsys.ReadDir(path)
+	err1 := fn(path, info, err)
+	// A directory that cannot be read is reported once, and skipped if fn allows it
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child := filepath.Join(path, name)
+		childInfo, err := fsys.Lstat(child)
+		if err != nil {
+			if err := fn(child, childInfo, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		if err := walkDir(fsys, child, childInfo, fn); err != nil {
+			if !childInfo.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isNotExist reports whether err tells that a file does not exist
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
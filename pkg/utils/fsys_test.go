@@ -0,0 +1,351 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// faultFS is a MemFS failing some operations, as a full or failing disk would
+type faultFS struct {
+	*MemFS
+	mkdirErr error // Returned by MkdirAll
+	writeErr error // Returned by writes of files open for writing
+	readErr  error // Returned by reads of files open for reading
+}
+
+func (f *faultFS) MkdirAll(path string, perm fs.FileMode) error {
+	if f.mkdirErr != nil {
+		return &fs.PathError{Op: "mkdir", Path: path, Err: f.mkdirErr}
+	}
+	return f.MemFS.MkdirAll(path, perm)
+}
+
+func (f *faultFS) Open(name string) (File, error) {
+	return f.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (f *faultFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	file, err := f.MemFS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultFile{File: file, fs: f}, nil
+}
+
+// faultFile is an open file of a faultFS
+type faultFile struct {
+	File
+	fs *faultFS
+}
+
+func (f *faultFile) Read(p []byte) (int, error) {
+	if f.fs.readErr != nil {
+		return 0, f.fs.readErr
+	}
+	return f.File.Read(p)
+}
+
+func (f *faultFile) Write(p []byte) (int, error) {
+	if f.fs.writeErr != nil {
+		return 0, f.fs.writeErr
+	}
+	return f.File.Write(p)
+}
+
+func TestWalkFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"b/2.jpg", "a/1.jpg", "a/skipped/3.jpg", "c.jpg"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// Same paths, in the same order, as filepath.Walk
+	walk := func(walker func(string, filepath.WalkFunc) error) []string {
+		var paths []string
+		err := walker(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Name() == "skipped" {
+				return filepath.SkipDir
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("walk error = %v", err)
+		}
+		return paths
+	}
+	if got, want := walk(func(root string, fn filepath.WalkFunc) error { return walkFiles(OSFileSystem{}, root, fn) }), walk(filepath.Walk); !reflect.DeepEqual(got, want) {
+		t.Errorf("walkFiles() = %v, want %v", got, want)
+	}
+
+	if err := walkFiles(OSFileSystem{}, filepath.Join(root, "missing"), func(path string, info os.FileInfo, err error) error { return err }); !os.IsNotExist(err) {
+		t.Errorf("walkFiles() of a missing root error = %v, want not exist", err)
+	}
+}
+
+func TestWalkSource(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		if err := os.WriteFile(filepath.Join(root, name), nil, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	walk := func(p *models.Params) []string {
+		var paths []string
+		err := walkSource(OSFileSystem{}, p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				paths = append(paths, filepath.Base(path))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("walkSource() error = %v", err)
+		}
+		return paths
+	}
+
+	if got, want := walk(&models.Params{Source: root}), []string{"a.jpg", "b.jpg", "c.jpg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walkSource() of the source = %v, want %v", got, want)
+	}
+
+	// Selected files only, sorted, without the files no longer in the source
+	files := []string{filepath.Join(root, "c.jpg"), filepath.Join(root, "missing.jpg"), filepath.Join(root, "a.jpg")}
+	if got, want := walk(&models.Params{Source: root, Files: files}), []string{"a.jpg", "c.jpg"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("walkSource() of selected files = %v, want %v", got, want)
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	m := NewMemFS()
+	dir := filepath.Join(string(filepath.Separator), "dest", "2025")
+	file := filepath.Join(dir, "photo.jpg")
+	if err := m.WriteFile(file, []byte("photo"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := m.WriteFile(filepath.Join(dir, "a.jpg"), nil, 0444); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		op      func() error
+		wantErr error
+	}{
+		{name: "create new file", op: func() error {
+			_, err := m.OpenFile(filepath.Join(dir, "new.jpg"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+			return err
+		}},
+		{name: "create existing file", op: func() error {
+			_, err := m.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+			return err
+		}, wantErr: fs.ErrExist},
+		{name: "create in missing directory", op: func() error {
+			_, err := m.OpenFile(filepath.Join(dir, "missing", "new.jpg"), os.O_WRONLY|os.O_CREATE, 0666)
+			return err
+		}, wantErr: fs.ErrNotExist},
+		{name: "open missing file", op: func() error {
+			_, err := m.Open(filepath.Join(dir, "missing.jpg"))
+			return err
+		}, wantErr: fs.ErrNotExist},
+		{name: "write read-only file", op: func() error {
+			_, err := m.OpenFile(filepath.Join(dir, "a.jpg"), os.O_WRONLY, 0)
+			return err
+		}, wantErr: fs.ErrPermission},
+		{name: "remove directory with files", op: func() error { return m.Remove(dir) }, wantErr: fs.ErrExist},
+		{name: "make directory over a file", op: func() error { return m.MkdirAll(filepath.Join(file, "sub"), 0755) }, wantErr: fs.ErrExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.op(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Files read, seek and append like files on disk
+	f, err := m.OpenFile(file, os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	f.Write([]byte(" album"))
+	f.Seek(6, io.SeekStart)
+	if data, _ := io.ReadAll(f); string(data) != "album" {
+		t.Errorf("Read after seek = %q, want %q", data, "album")
+	}
+	f.Close()
+	if _, err := f.Read(nil); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Read() of closed file error = %v, want %v", err, fs.ErrClosed)
+	}
+
+	entries, err := m.ReadDir(dir)
+	if err != nil || len(entries) != 3 || entries[0].Name() != "a.jpg" || entries[2].Name() != "photo.jpg" {
+		t.Errorf("ReadDir() = %v, %v, want a.jpg, new.jpg and photo.jpg", entries, err)
+	}
+}
+
+func TestProcessMediaFilesInMemory(t *testing.T) {
+	root := string(filepath.Separator)
+	source, dest := filepath.Join(root, "card"), filepath.Join(root, "nas")
+	photo := filepath.Join(source, "DCIM", "photo.jpg")
+
+	tests := []struct {
+		name     string
+		faults   faultFS
+		want     func(summary ProcessingSummary) bool
+		wantDest bool
+	}{
+		{
+			name:     "no fault",
+			want:     func(s ProcessingSummary) bool { return s.Copied == 1 && s.Deleted == 1 },
+			wantDest: true,
+		},
+		{
+			name:   "full disk",
+			faults: faultFS{writeErr: syscall.ENOSPC},
+			want:   func(s ProcessingSummary) bool { return s.Failed == 1 && s.Deleted == 0 },
+		},
+		{
+			name:   "read error",
+			faults: faultFS{readErr: syscall.EIO},
+			want:   func(s ProcessingSummary) bool { return s.Skipped == 1 && s.Deleted == 0 },
+		},
+		{
+			name:   "permission denied",
+			faults: faultFS{mkdirErr: fs.ErrPermission},
+			want:   func(s ProcessingSummary) bool { return s.Failed == 1 && s.Deleted == 0 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemFS()
+			if err := m.WriteFile(photo, createFakeExifData(), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			if err := m.MkdirAll(dest, 0755); err != nil {
+				t.Fatalf("MkdirAll() error = %v", err)
+			}
+			faults := tt.faults
+			faults.MemFS = m
+
+			params := &models.Params{Source: source, Destination: dest, Compression: -1, DeleteSource: true, HashAlgo: HashSHA256}
+			summary, err := ProcessMediaFilesFS(context.Background(), &faults, params, nil)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if !tt.want(summary) {
+				t.Errorf("ProcessMediaFiles() unexpected summary: %+v", summary)
+			}
+
+			written, err := m.ReadFile(filepath.Join(dest, "2025", "01-11", "photo.jpg"))
+			if tt.wantDest && (err != nil || string(written) != string(createFakeExifData())) {
+				t.Errorf("Destination file = %d bytes, %v, want the source content", len(written), err)
+			}
+			// Failed writes leave no partial file, and the source is kept
+			if !tt.wantDest {
+				if err == nil {
+					t.Error("Expected no destination file")
+				}
+				if _, err := m.Stat(photo); err != nil {
+					t.Errorf("Expected the source file to be kept: %v", err)
+				}
+			}
+		})
+	}
+
+	// Nothing was written to the disk
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("Expected no destination on disk, got %v", err)
+	}
+}
+
+// detachedDirFS is the disk with one directory that can only be listed once,
+// as a card pulled out during a run after its files were scanned
+type detachedDirFS struct {
+	OSFileSystem
+	dir      string
+	listings int
+}
+
+func (d *detachedDirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == d.dir {
+		if d.listings++; d.listings > 1 {
+			return nil, &fs.PathError{Op: "readdirent", Path: name, Err: syscall.EIO}
+		}
+	}
+	return d.OSFileSystem.ReadDir(name)
+}
+
+func TestProcessMediaFilesWalkError(t *testing.T) {
+	source := t.TempDir()
+	dest := t.TempDir()
+	state := t.TempDir()
+	photo := filepath.Join(source, "a", "photo.jpg")
+	for _, dir := range []string{filepath.Dir(photo), filepath.Join(source, "b")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(photo, createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:       source,
+		Destination:  dest,
+		Compression:  -1,
+		HashAlgo:     HashSHA256,
+		CatalogFile:  filepath.Join(state, "catalog.jsonl"),
+		ReportFile:   filepath.Join(state, "report.json"),
+		TimelineFile: filepath.Join(state, "timeline.json"),
+		CacheFile:    filepath.Join(state, "cache.json"),
+	}
+	fsys := &detachedDirFS{dir: filepath.Join(source, "b")}
+	summary, err := ProcessMediaFilesFS(context.Background(), fsys, params, nil)
+	if !errors.Is(err, syscall.EIO) {
+		t.Fatalf("ProcessMediaFiles() error = %v, want the walk error", err)
+	}
+	if summary.Copied != 1 {
+		t.Errorf("ProcessMediaFiles() summary = %+v, want the file before the error copied", summary)
+	}
+
+	// The files imported before the error are saved like those of a complete run
+	report, err := LoadReport(params.ReportFile)
+	if err != nil || len(report.Files) != 1 {
+		t.Errorf("Report = %+v, %v, want the copied file", report, err)
+	}
+	for _, file := range []string{params.TimelineFile, params.CacheFile} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Expected %s written: %v", filepath.Base(file), err)
+		}
+	}
+	catalog, err := LoadCatalog(params.CatalogFile)
+	if err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+	if runs := catalog.Runs(); len(runs) != 1 || len(catalog.Records()) != 1 {
+		t.Errorf("Catalog runs = %+v, records = %+v, want the run and its file", runs, catalog.Records())
+	}
+}
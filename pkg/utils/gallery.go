@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// galleryItem is a file of the archive shown by the gallery
+type galleryItem struct {
+	Path   string // Relative to the destination, slash-separated
+	Name   string
+	Size   int64
+	Tags   []string
+	Albums []string
+}
+
+// galleryFolder holds the files of a destination folder, a day with the default layout
+type galleryFolder struct {
+	Path  string
+	Items []galleryItem
+}
+
+// Gallery is a read-only web gallery of an organized archive, to check an
+// import visually: its folders newest first, and a page of thumbnails for
+// each. It only serves the files it listed when created, never writes
+// anything, and only answers GET and HEAD requests.
+type Gallery struct {
+	root    string
+	folders []galleryFolder // Newest first
+	files   map[string]bool // Files listed, the only ones served
+	mux     *http.ServeMux
+}
+
+// NewGallery lists the files of the archive at destination: the files
+// recorded in the catalog when catalogFile is set, otherwise the media files
+// found in the destination. Files outside the destination, such as those of
+// other tiers, and hidden folders are left out.
+func NewGallery(destination, catalogFile string) (*Gallery, error) {
+	root, err := filepath.Abs(destination)
+	if err != nil {
+		return nil, err
+	}
+	g := &Gallery{root: root, files: make(map[string]bool)}
+
+	var items []galleryItem
+	if catalogFile != "" {
+		catalog, err := LoadCatalog(catalogFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range catalog.Records() {
+			rel, ok := g.relative(record.Destination)
+			if !ok {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel))); err != nil {
+				continue // Removed since it was imported
+			}
+			items = append(items, galleryItem{Path: rel, Size: record.Size, Tags: record.Tags, Albums: record.Albums})
+		}
+	} else {
+		err := walkFiles(OSFileSystem{}, root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("failed to access path %q: %w", p, err)
+			}
+			if strings.HasPrefix(info.Name(), ".") && p != root {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			ext := strings.ToLower(filepath.Ext(p))
+			if info.IsDir() || !(isAllowedExtension(ext) || videoExtensions[ext]) {
+				return nil
+			}
+			if rel, ok := g.relative(p); ok {
+				items = append(items, galleryItem{Path: rel, Size: info.Size()})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk directory: %w", err)
+		}
+	}
+
+	index := make(map[string]int)
+	for _, item := range items {
+		item.Name = path.Base(item.Path)
+		g.files[item.Path] = true
+		dir := path.Dir(item.Path)
+		i, ok := index[dir]
+		if !ok {
+			i = len(g.folders)
+			index[dir] = i
+			g.folders = append(g.folders, galleryFolder{Path: dir})
+		}
+		g.folders[i].Items = append(g.folders[i].Items, item)
+	}
+	sort.Slice(g.folders, func(i, j int) bool { return g.folders[i].Path > g.folders[j].Path })
+	for i := range g.folders {
+		items := g.folders[i].Items
+		sort.Slice(items, func(a, b int) bool { return items[a].Name < items[b].Name })
+	}
+
+	g.mux = http.NewServeMux()
+	g.mux.HandleFunc("GET /{$}", g.serveIndex)
+	g.mux.HandleFunc("GET /folder/{path...}", g.serveFolder)
+	g.mux.HandleFunc("GET /thumb/{path...}", g.serveThumbnail)
+	g.mux.HandleFunc("GET /file/{path...}", g.serveFile)
+	return g, nil
+}
+
+// relative returns the slash-separated path of a file below the destination
+func (g *Gallery) relative(p string) (string, bool) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(g.root, abs)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Len returns the number of files of the gallery
+func (g *Gallery) Len() int {
+	return len(g.files)
+}
+
+// ServeHTTP serves the pages, thumbnails and files of the gallery
+func (g *Gallery) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'unsafe-inline'")
+	g.mux.ServeHTTP(w, r)
+}
+
+// galleryPage is the layout of the pages of the gallery
+const galleryPage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title>
+<style>
+body{font-family:sans-serif;margin:1em 2em;background:#111;color:#ddd}
+a{color:#9cf;text-decoration:none}
+ul{list-style:none;padding:0}
+.grid{display:flex;flex-wrap:wrap;gap:12px}
+figure{margin:0;width:200px}
+figure img{width:200px;height:150px;object-fit:contain;background:#222}
+figcaption{font-size:12px;overflow-wrap:anywhere}
+.tag{color:#aaa}
+</style></head><body>
+<h1>{{.Title}}</h1>
+{{if .Folder}}<p><a href="/">All folders</a></p>
+<div class="grid">{{range .Folder.Items}}
+<figure><a href="/file/{{.Path}}"><img loading="lazy" src="/thumb/{{.Path}}" alt="{{.Name}}"></a>
+<figcaption>{{.Name}} ({{size .Size}}){{range .Tags}} <span class="tag">#{{.}}</span>{{end}}{{range .Albums}} <span class="tag">[{{.}}]</span>{{end}}</figcaption></figure>{{end}}
+</div>
+{{else}}<p>{{.Files}} files in {{len .Folders}} folders, read-only</p>
+<ul>{{range .Folders}}
+<li><a href="/folder/{{.Path}}">{{.Path}}</a> ({{len .Items}})</li>{{end}}
+</ul>{{end}}
+</body></html>
+`
+
+var galleryTemplate = template.Must(template.New("gallery").Funcs(template.FuncMap{"size": FormatSize}).Parse(galleryPage))
+
+// galleryData is the content of a page of the gallery
+type galleryData struct {
+	Title   string
+	Files   int
+	Folders []galleryFolder
+	Folder  *galleryFolder
+}
+
+// serveIndex lists the folders of the archive, newest first
+func (g *Gallery) serveIndex(w http.ResponseWriter, r *http.Request) {
+	g.render(w, galleryData{Title: g.root, Files: len(g.files), Folders: g.folders})
+}
+
+// serveFolder shows the thumbnails of the files of a folder
+func (g *Gallery) serveFolder(w http.ResponseWriter, r *http.Request) {
+	dir := r.PathValue("path")
+	for i := range g.folders {
+		if g.folders[i].Path == dir {
+			g.render(w, galleryData{Title: dir, Folder: &g.folders[i]})
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// render writes a page of the gallery
+func (g *Gallery) render(w http.ResponseWriter, data galleryData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := galleryTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// open opens a file of the gallery, which must be one it listed
+func (g *Gallery) open(r *http.Request) (*os.File, os.FileInfo, bool) {
+	rel := r.PathValue("path")
+	if !g.files[rel] {
+		return nil, nil, false
+	}
+	file, err := os.Open(filepath.Join(g.root, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, nil, false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, false
+	}
+	return file, info, true
+}
+
+// serveFile serves a file of the archive as it is
+func (g *Gallery) serveFile(w http.ResponseWriter, r *http.Request) {
+	file, info, ok := g.open(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// serveThumbnail serves the thumbnail of a file, or a placeholder naming its
+// format when it has none, such as videos
+func (g *Gallery) serveThumbnail(w http.ResponseWriter, r *http.Request) {
+	file, info, ok := g.open(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Cache-Control", "max-age=86400")
+	if thumb, err := Thumbnail(file, filepath.Ext(info.Name())); err == nil {
+		w.Header().Set("Content-Type", "image/jpeg")
+		http.ServeContent(w, r, "", info.ModTime(), bytes.NewReader(thumb))
+		return
+	}
+	ext := strings.ToUpper(strings.TrimPrefix(filepath.Ext(info.Name()), "."))
+	w.Header().Set("Content-Type", "image/svg+xml")
+	http.ServeContent(w, r, "", info.ModTime(), strings.NewReader(fmt.Sprintf(thumbnailPlaceholder, template.HTMLEscapeString(ext))))
+}
+
+// thumbnailPlaceholder is shown for files without thumbnail, with their format
+const thumbnailPlaceholder = `<svg xmlns="http://www.w3.org/2000/svg" width="200" height="150"><rect width="200" height="150" fill="#333"/><text x="100" y="82" font-family="sans-serif" font-size="24" fill="#aaa" text-anchor="middle">%s</text></svg>`
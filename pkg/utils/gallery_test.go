@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createGalleryArchive returns an organized archive of two days, with a
+// hidden folder of the tool and a catalog next to it
+func createGalleryArchive(t *testing.T) (string, string) {
+	t.Helper()
+	dest := t.TempDir()
+	files := map[string][]byte{
+		"2024/06-11/IMG_0001.JPG":         createPicture(t, 640, 480),
+		"2024/06-11/GX010001.MP4":         createTestMovie(movieEpoch),
+		"2024/06-12/IMG_0002.JPG":         createPicture(t, 640, 480),
+		"2024/06-12/notes.txt":            []byte("notes"),
+		".organize-media/state.json":      []byte("{}"),
+		"2024/06-12/.hidden/IMG_0003.JPG": createPicture(t, 64, 48),
+	}
+	for name, data := range files {
+		path := filepath.Join(dest, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test folder: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	catalogFile := filepath.Join(t.TempDir(), "catalog.jsonl")
+	catalog, err := OpenCatalog(catalogFile)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error = %v", err)
+	}
+	defer catalog.Close()
+	for i, rel := range []string{"2024/06-11/IMG_0001.JPG", "2024/06-12/missing.JPG", "../elsewhere/IMG_0009.JPG"} {
+		record := CatalogRecord{
+			Hash:        strings.Repeat("a", i+1),
+			Destination: filepath.Join(dest, filepath.FromSlash(rel)),
+			ImportedAt:  time.Now(),
+			Tags:        []string{"pano"},
+		}
+		if err := catalog.Add(record); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	return dest, catalogFile
+}
+
+// galleryRequest is a request to the gallery and the expected response
+type galleryRequest struct {
+	method      string
+	path        string
+	wantStatus  int
+	contentType string
+	contains    string
+}
+
+func TestGallery(t *testing.T) {
+	dest, catalogFile := createGalleryArchive(t)
+	before, _ := os.ReadFile(catalogFile)
+
+	tests := []struct {
+		name        string
+		catalogFile string
+		wantFiles   int
+	}{
+		{"destination walked", "", 3},
+		{"catalog", catalogFile, 1}, // Removed files and files of other folders are left out
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gallery, err := NewGallery(dest, tt.catalogFile)
+			if err != nil {
+				t.Fatalf("NewGallery() error = %v", err)
+			}
+			if gallery.Len() != tt.wantFiles {
+				t.Errorf("Len() = %d, want %d", gallery.Len(), tt.wantFiles)
+			}
+			server := httptest.NewServer(gallery)
+			defer server.Close()
+
+			requests := []galleryRequest{
+				{http.MethodGet, "/", http.StatusOK, "text/html", "2024/06-11"},
+				{http.MethodGet, "/folder/2024/06-11", http.StatusOK, "text/html", "/thumb/2024/06-11/IMG_0001.JPG"},
+				{http.MethodGet, "/folder/2024/06-13", http.StatusNotFound, "", ""},
+				{http.MethodGet, "/thumb/2024/06-11/IMG_0001.JPG", http.StatusOK, "image/jpeg", ""},
+				{http.MethodGet, "/file/2024/06-11/IMG_0001.JPG", http.StatusOK, "image/jpeg", ""},
+				{http.MethodGet, "/file/.organize-media/state.json", http.StatusNotFound, "", ""},
+				{http.MethodGet, "/file/2024/06-12/notes.txt", http.StatusNotFound, "", ""},
+				{http.MethodGet, "/file/2024/06-11/../../../etc/passwd", http.StatusNotFound, "", ""},
+				{http.MethodPost, "/file/2024/06-11/IMG_0001.JPG", http.StatusMethodNotAllowed, "", ""},
+				{http.MethodDelete, "/file/2024/06-11/IMG_0001.JPG", http.StatusMethodNotAllowed, "", ""},
+			}
+			if tt.catalogFile == "" {
+				// Videos have no thumbnail, a placeholder names their format
+				requests = append(requests, galleryRequest{http.MethodGet, "/thumb/2024/06-11/GX010001.MP4", http.StatusOK, "image/svg+xml", "MP4"})
+			}
+
+			for _, req := range requests {
+				r, err := http.NewRequest(req.method, server.URL+req.path, nil)
+				if err != nil {
+					t.Fatalf("NewRequest() error = %v", err)
+				}
+				resp, err := http.DefaultClient.Do(r)
+				if err != nil {
+					t.Fatalf("%s %s error = %v", req.method, req.path, err)
+				}
+				body := new(strings.Builder)
+				_, _ = io.Copy(body, resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != req.wantStatus {
+					t.Errorf("%s %s status = %d, want %d", req.method, req.path, resp.StatusCode, req.wantStatus)
+					continue
+				}
+				if !strings.HasPrefix(resp.Header.Get("Content-Type"), req.contentType) {
+					t.Errorf("%s %s content type = %s, want %s", req.method, req.path, resp.Header.Get("Content-Type"), req.contentType)
+				}
+				if !strings.Contains(body.String(), req.contains) {
+					t.Errorf("%s %s body does not contain %q", req.method, req.path, req.contains)
+				}
+			}
+		})
+	}
+
+	if after, _ := os.ReadFile(catalogFile); string(after) != string(before) {
+		t.Error("The gallery changed the catalog")
+	}
+}
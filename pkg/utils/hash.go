@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/matdmb/organize-media/pkg/blake3"
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// Supported content hash algorithms
+const (
+	HashSHA256 = "sha256"
+	HashBLAKE3 = "blake3"
+)
+
+// IsSupportedHashAlgo reports whether algo is a known hash algorithm.
+// An empty algorithm selects the default, SHA-256.
+func IsSupportedHashAlgo(algo string) bool {
+	return models.HashAlgorithms[algo]
+}
+
+// HashBuffer returns the hex-encoded digest of the buffer.
+// SHA-256 digests are unprefixed so catalogs written before other algorithms
+// existed keep matching, while other digests are prefixed with their algorithm
+// (such as "blake3:...") and never collide with them.
+func HashBuffer(buffer []byte, algo string) string {
+	if algo == HashBLAKE3 {
+		sum := blake3.Sum256(buffer)
+		return formatHash(sum[:], algo)
+	}
+	sum := sha256.Sum256(buffer)
+	return formatHash(sum[:], algo)
+}
+
+// HashReader returns the digest of the content of r like HashBuffer, reading
+// it in pieces so files larger than the memory can be hashed
+func HashReader(r io.Reader, algo string) (string, error) {
+	h := newHash(algo)
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return formatHash(h.Sum(nil), algo), nil
+}
+
+// hashFile returns the digest of the file at path like HashBuffer, reading it in pieces
+func hashFile(path, algo string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return HashReader(file, algo)
+}
+
+// newHash returns a hash of the algorithm, SHA-256 by default
+func newHash(algo string) hash.Hash {
+	if algo == HashBLAKE3 {
+		return blake3.New()
+	}
+	return sha256.New()
+}
+
+// formatHash hex-encodes a digest, prefixed with its algorithm unless it is SHA-256
+func formatHash(sum []byte, algo string) string {
+	if algo == HashBLAKE3 {
+		return HashBLAKE3 + ":" + hex.EncodeToString(sum)
+	}
+	return hex.EncodeToString(sum)
+}
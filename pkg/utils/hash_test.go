@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestHashBuffer(t *testing.T) {
+	tests := []struct {
+		name string
+		algo string
+		want string
+	}{
+		{"default", "", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"sha256", HashSHA256, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"blake3", HashBLAKE3, "blake3:6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashBuffer([]byte("abc"), tt.algo); got != tt.want {
+				t.Errorf("HashBuffer() = %s, want %s", got, tt.want)
+			}
+			got, err := HashReader(strings.NewReader("abc"), tt.algo)
+			if err != nil || got != tt.want {
+				t.Errorf("HashReader() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSupportedHashAlgo(t *testing.T) {
+	for algo, want := range map[string]bool{"": true, "sha256": true, "blake3": true, "md5": false, "BLAKE3": false} {
+		if got := IsSupportedHashAlgo(algo); got != want {
+			t.Errorf("IsSupportedHashAlgo(%q) = %t, want %t", algo, got, want)
+		}
+	}
+}
+
+func TestProcessMediaFilesHashAlgo(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	catalogFile := filepath.Join(t.TempDir(), "catalog.jsonl")
+
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: t.TempDir(),
+		Compression: -1,
+		CatalogFile: catalogFile,
+		HashAlgo:    HashBLAKE3,
+	}
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() unexpected error: %v", err)
+	}
+
+	catalog, err := OpenCatalog(catalogFile)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error = %v", err)
+	}
+	defer catalog.Close()
+	if _, ok := catalog.Lookup(HashBuffer(createFakeExifData(), HashBLAKE3)); !ok {
+		t.Error("Expected the import to be recorded under its BLAKE3 hash")
+	}
+
+	params.HashAlgo = "md5"
+	if _, err := ProcessMediaFiles(params); err == nil {
+		t.Error("Expected an error for an unsupported hash algorithm")
+	}
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// RunSummary holds the counts of a run recorded in the catalog
+type RunSummary struct {
+	Processed  int `json:"processed"`
+	Copied     int `json:"copied"`
+	Compressed int `json:"compressed"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+	Deleted    int `json:"deleted,omitempty"`
+	Corrupt    int `json:"corrupt,omitempty"`
+	Rejected   int `json:"rejected,omitempty"`
+}
+
+// RunRecord describes one import run, recorded in the catalog next to the
+// records of the files it imported
+type RunRecord struct {
+	ID         string         `json:"id"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Host       string         `json:"host,omitempty"`
+	Version    string         `json:"version,omitempty"`
+	Commit     string         `json:"commit,omitempty"`
+	BuildDate  string         `json:"build_date,omitempty"`
+	Cancelled  bool           `json:"cancelled,omitempty"`
+	Params     *models.Params `json:"params"`
+	Summary    RunSummary     `json:"summary"`
+}
+
+// newRunID returns a new run identifier, sortable by start time, such as
+// 20250111-171039-3fa2
+func newRunID(start time.Time) string {
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return start.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// newRunRecord returns the record of a finished run
+func newRunRecord(id string, p *models.Params, start time.Time, summary ProcessingSummary, cancelled bool) RunRecord {
+	host, _ := os.Hostname()
+	build := CurrentBuild()
+	return RunRecord{
+		ID:         id,
+		StartedAt:  start,
+		FinishedAt: time.Now(),
+		Host:       host,
+		Version:    build.Version,
+		Commit:     build.Commit,
+		BuildDate:  build.BuildDate,
+		Cancelled:  cancelled,
+		Params:     p,
+		Summary: RunSummary{
+			Processed:  summary.Processed,
+			Copied:     summary.Copied,
+			Compressed: summary.Compressed,
+			Skipped:    summary.Skipped,
+			Failed:     summary.Failed,
+			Deleted:    summary.Deleted,
+			Corrupt:    summary.Corrupt,
+			Rejected:   summary.Rejected,
+		},
+	}
+}
+
+// Runs returns the import runs recorded in the catalog, oldest first
+func (c *Catalog) Runs() []RunRecord {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	runs := append([]RunRecord(nil), c.runs...)
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.Before(runs[j].StartedAt) })
+	return runs
+}
+
+// Run returns the recorded run with the given identifier
+func (c *Catalog) Run(id string) (RunRecord, bool) {
+	for _, run := range c.Runs() {
+		if run.ID == id {
+			return run, true
+		}
+	}
+	return RunRecord{}, false
+}
+
+// RunFiles returns the records of the files imported by a run, ordered by destination
+func (c *Catalog) RunFiles(id string) []CatalogRecord {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var files []CatalogRecord
+	for _, record := range c.records {
+		if record.RunID == id {
+			files = append(files, record)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Destination < files[j].Destination })
+	return files
+}
+
+// AddRun appends the record of a run to the catalog
+func (c *Catalog) AddRun(run RunRecord) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.append(catalogLine{Run: &run}); err != nil {
+		return fmt.Errorf("failed to write run record: %w", err)
+	}
+	c.runs = append(c.runs, run)
+	return nil
+}
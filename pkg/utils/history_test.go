@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestNewRunID(t *testing.T) {
+	start := time.Date(2025, 1, 11, 17, 10, 39, 0, time.UTC)
+	id := newRunID(start)
+	if !regexp.MustCompile(`^20250111-171039-[0-9a-f]{4}$`).MatchString(id) {
+		t.Errorf("newRunID() = %s", id)
+	}
+}
+
+func TestProcessMediaFilesRunHistory(t *testing.T) {
+	sourceDir := t.TempDir()
+	catalogPath := filepath.Join(t.TempDir(), "catalog.jsonl")
+	if err := os.WriteFile(filepath.Join(sourceDir, "IMG_0001.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: t.TempDir(),
+		Compression: -1,
+		CatalogFile: catalogPath,
+		Incremental: true,
+		RunTags:     map[string]string{"job": "wedding2024"},
+	}
+	// The second run finds the file already imported
+	for i := 0; i < 2; i++ {
+		if _, err := ProcessMediaFiles(params); err != nil {
+			t.Fatalf("ProcessMediaFiles() run %d error: %v", i+1, err)
+		}
+	}
+
+	catalog, err := OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error: %v", err)
+	}
+	defer catalog.Close()
+
+	if catalog.Len() != 1 {
+		t.Errorf("Expected run records apart from file records, got %d file records", catalog.Len())
+	}
+	runs := catalog.Runs()
+	if len(runs) != 2 {
+		t.Fatalf("Expected 2 recorded runs, got %d", len(runs))
+	}
+
+	first, second := runs[0], runs[1]
+	if first.Summary.Copied != 1 || second.Summary.Skipped != 1 {
+		t.Errorf("Unexpected run summaries %+v and %+v", first.Summary, second.Summary)
+	}
+	if first.Params == nil || first.Params.Source != sourceDir || first.Params.RunTags["job"] != "wedding2024" {
+		t.Errorf("Unexpected run parameters %+v", first.Params)
+	}
+	if first.Version == "" || first.FinishedAt.Before(first.StartedAt) {
+		t.Errorf("Unexpected run record %+v", first)
+	}
+
+	if files := catalog.RunFiles(first.ID); len(files) != 1 || files[0].Source != filepath.Join(sourceDir, "IMG_0001.jpg") {
+		t.Errorf("RunFiles() of the first run = %+v", files)
+	}
+	if files := catalog.RunFiles(second.ID); len(files) != 0 {
+		t.Errorf("RunFiles() of the second run = %+v, want none", files)
+	}
+	if run, ok := catalog.Run(second.ID); !ok || run.ID != second.ID {
+		t.Errorf("Run() = %+v, %v", run, ok)
+	}
+
+	// Repairing the catalog keeps the history
+	if _, err := RepairCatalog(catalogPath, params.Destination, HashSHA256, false); err != nil {
+		t.Fatalf("RepairCatalog() error: %v", err)
+	}
+	repaired, err := OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() after repair error: %v", err)
+	}
+	defer repaired.Close()
+	if len(repaired.Runs()) != 2 || len(repaired.RunFiles(first.ID)) != 1 {
+		t.Errorf("Expected the history to survive a repair, got %d runs", len(repaired.Runs()))
+	}
+}
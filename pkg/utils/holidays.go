@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/i18n"
+)
+
+// holiday is a day of a holiday calendar, fixed (month and day), relative to
+// Easter Sunday (easter days after it), or the nth weekday of a month (last
+// when nth is -1)
+type holiday struct {
+	name    string
+	month   time.Month
+	day     int
+	easter  int
+	weekday time.Weekday
+	nth     int
+	movable bool // Relative to Easter
+}
+
+// fixed, easterDay and nthWeekday build the holidays of calendars
+func fixed(name string, month time.Month, day int) holiday {
+	return holiday{name: name, month: month, day: day}
+}
+
+func easterDay(name string, offset int) holiday {
+	return holiday{name: name, easter: offset, movable: true}
+}
+
+func nthWeekday(name string, month time.Month, weekday time.Weekday, nth int) holiday {
+	return holiday{name: name, month: month, weekday: weekday, nth: nth}
+}
+
+// HolidayCalendar names the days of the week and the holidays of a country,
+// in its language, for the {weekday} and {holiday} layout tokens
+type HolidayCalendar struct {
+	weekdays [7]string // From Sunday
+	holidays []holiday
+}
+
+// holidayCalendars are the calendars of the -holidays flag, keyed by country
+var holidayCalendars = map[string]*HolidayCalendar{
+	"us": {
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		holidays: []holiday{
+			fixed("New Year's Day", time.January, 1),
+			nthWeekday("Martin Luther King Day", time.January, time.Monday, 3),
+			nthWeekday("Presidents' Day", time.February, time.Monday, 3),
+			easterDay("Easter", 0),
+			nthWeekday("Memorial Day", time.May, time.Monday, -1),
+			fixed("Independence Day", time.July, 4),
+			nthWeekday("Labor Day", time.September, time.Monday, 1),
+			fixed("Halloween", time.October, 31),
+			nthWeekday("Thanksgiving", time.November, time.Thursday, 4),
+			fixed("Christmas Eve", time.December, 24),
+			fixed("Christmas", time.December, 25),
+			fixed("New Year's Eve", time.December, 31),
+		},
+	},
+	"gb": {
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		holidays: []holiday{
+			fixed("New Year's Day", time.January, 1),
+			easterDay("Good Friday", -2),
+			easterDay("Easter", 0),
+			easterDay("Easter Monday", 1),
+			nthWeekday("Early May Bank Holiday", time.May, time.Monday, 1),
+			nthWeekday("Spring Bank Holiday", time.May, time.Monday, -1),
+			nthWeekday("Summer Bank Holiday", time.August, time.Monday, -1),
+			fixed("Christmas Eve", time.December, 24),
+			fixed("Christmas", time.December, 25),
+			fixed("Boxing Day", time.December, 26),
+			fixed("New Year's Eve", time.December, 31),
+		},
+	},
+	"fr": {
+		weekdays: [7]string{"Dimanche", "Lundi", "Mardi", "Mercredi", "Jeudi", "Vendredi", "Samedi"},
+		holidays: []holiday{
+			fixed("Jour de l'An", time.January, 1),
+			easterDay("Pâques", 0),
+			easterDay("Lundi de Pâques", 1),
+			fixed("Fête du Travail", time.May, 1),
+			fixed("Victoire 1945", time.May, 8),
+			easterDay("Ascension", 39),
+			easterDay("Pentecôte", 49),
+			easterDay("Lundi de Pentecôte", 50),
+			fixed("Fête nationale", time.July, 14),
+			fixed("Assomption", time.August, 15),
+			fixed("Toussaint", time.November, 1),
+			fixed("Armistice", time.November, 11),
+			fixed("Réveillon de Noël", time.December, 24),
+			fixed("Noël", time.December, 25),
+			fixed("Saint-Sylvestre", time.December, 31),
+		},
+	},
+	"de": {
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		holidays: []holiday{
+			fixed("Neujahr", time.January, 1),
+			easterDay("Karfreitag", -2),
+			easterDay("Ostersonntag", 0),
+			easterDay("Ostermontag", 1),
+			fixed("Tag der Arbeit", time.May, 1),
+			easterDay("Christi Himmelfahrt", 39),
+			easterDay("Pfingstsonntag", 49),
+			easterDay("Pfingstmontag", 50),
+			fixed("Tag der Deutschen Einheit", time.October, 3),
+			fixed("Heiligabend", time.December, 24),
+			fixed("Erster Weihnachtstag", time.December, 25),
+			fixed("Zweiter Weihnachtstag", time.December, 26),
+			fixed("Silvester", time.December, 31),
+		},
+	},
+}
+
+// calendarOfLanguage is the calendar used for each language when the run
+// does not select one
+var calendarOfLanguage = map[string]string{"en": "us", "fr": "fr", "de": "de"}
+
+// holidayCalendarOf returns the calendar of a country code, or the calendar
+// of the language of messages when code is empty or unknown
+func holidayCalendarOf(code string) *HolidayCalendar {
+	if c, ok := holidayCalendars[code]; ok {
+		return c
+	}
+	if c, ok := holidayCalendars[calendarOfLanguage[i18n.Language()]]; ok {
+		return c
+	}
+	return holidayCalendars["us"]
+}
+
+// Weekday returns the name of the day of the week of date
+func (c *HolidayCalendar) Weekday(date time.Time) string {
+	return c.weekdays[date.Weekday()]
+}
+
+// Holiday returns the name of the holiday on date, empty on other days
+func (c *HolidayCalendar) Holiday(date time.Time) string {
+	year, month, day := date.Date()
+	for _, h := range c.holidays {
+		var m time.Month
+		var d int
+		switch {
+		case h.movable:
+			e := easterSunday(year).AddDate(0, 0, h.easter)
+			m, d = e.Month(), e.Day()
+		case h.nth != 0:
+			m, d = h.month, nthWeekdayOf(year, h.month, h.weekday, h.nth)
+		default:
+			m, d = h.month, h.day
+		}
+		if m == month && d == day {
+			return h.name
+		}
+	}
+	return ""
+}
+
+// easterSunday returns the date of Easter Sunday in the Gregorian calendar,
+// by the anonymous Gregorian algorithm
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// nthWeekdayOf returns the day of the month of the nth weekday of a month,
+// the last one when nth is -1
+func nthWeekdayOf(year int, month time.Month, weekday time.Weekday, nth int) int {
+	if nth < 0 {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+		return last.Day() - (int(last.Weekday())-int(weekday)+7)%7
+	}
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return 1 + (int(weekday)-int(first.Weekday())+7)%7 + 7*(nth-1)
+}
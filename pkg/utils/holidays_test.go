@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestEasterSunday(t *testing.T) {
+	for year, want := range map[int]string{
+		2000: "2000-04-23",
+		2019: "2019-04-21",
+		2024: "2024-03-31",
+		2025: "2025-04-20",
+		2038: "2038-04-25",
+	} {
+		if got := easterSunday(year).Format("2006-01-02"); got != want {
+			t.Errorf("easterSunday(%d) = %s, want %s", year, got, want)
+		}
+	}
+}
+
+func TestNthWeekdayOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		month   time.Month
+		weekday time.Weekday
+		nth     int
+		want    int
+	}{
+		{"Thanksgiving 2024", time.November, time.Thursday, 4, 28},
+		{"Labor Day 2024", time.September, time.Monday, 1, 2},
+		{"Memorial Day 2024", time.May, time.Monday, -1, 27},
+		{"last Monday of a month ending on a Monday", time.September, time.Monday, -1, 30},
+	}
+	for _, tt := range tests {
+		if got := nthWeekdayOf(2024, tt.month, tt.weekday, tt.nth); got != tt.want {
+			t.Errorf("%s: nthWeekdayOf() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHolidayCalendar(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 15, 30, 0, 0, time.UTC) }
+	tests := []struct {
+		calendar string
+		date     time.Time
+		weekday  string
+		holiday  string
+	}{
+		{"us", day(time.June, 11), "Tuesday", ""},
+		{"us", day(time.December, 25), "Wednesday", "Christmas"},
+		{"us", day(time.November, 28), "Thursday", "Thanksgiving"},
+		{"us", day(time.July, 4), "Thursday", "Independence Day"},
+		{"gb", day(time.April, 1), "Monday", "Easter Monday"},
+		{"gb", day(time.August, 26), "Monday", "Summer Bank Holiday"},
+		{"fr", day(time.July, 14), "Dimanche", "Fête nationale"},
+		{"fr", day(time.May, 9), "Jeudi", "Ascension"},
+		{"de", day(time.October, 3), "Donnerstag", "Tag der Deutschen Einheit"},
+		{"de", day(time.May, 20), "Montag", "Pfingstmontag"},
+	}
+	for _, tt := range tests {
+		c := holidayCalendarOf(tt.calendar)
+		if got := c.Weekday(tt.date); got != tt.weekday {
+			t.Errorf("%s Weekday(%s) = %s, want %s", tt.calendar, tt.date.Format("2006-01-02"), got, tt.weekday)
+		}
+		if got := c.Holiday(tt.date); got != tt.holiday {
+			t.Errorf("%s Holiday(%s) = %q, want %q", tt.calendar, tt.date.Format("2006-01-02"), got, tt.holiday)
+		}
+	}
+
+	// Without a calendar, that of the language of messages
+	if holidayCalendarOf("") != holidayCalendars["us"] {
+		t.Error("holidayCalendarOf(\"\") expected the calendar of English")
+	}
+}
+
+func TestLayoutHolidays(t *testing.T) {
+	p := &models.Params{Layout: "{year}/{month}-{day}_{holiday}", Holidays: "fr"}
+	l, err := layoutOf(p)
+	if err != nil {
+		t.Fatalf("layoutOf() error = %v", err)
+	}
+	christmas := time.Date(2024, 12, 25, 10, 0, 0, 0, time.UTC)
+	if got, want := l.Resolve("IMG_0001.JPG", christmas), filepath.Join("2024", "12-25_Noël", "IMG_0001.JPG"); got != want {
+		t.Errorf("Resolve() = %s, want %s", got, want)
+	}
+
+	// The same layout with another calendar is another layout
+	p = &models.Params{Layout: p.Layout, Holidays: "de"}
+	if l, _ = layoutOf(p); l.Resolve("IMG_0001.JPG", christmas) != filepath.Join("2024", "12-25_Erster Weihnachtstag", "IMG_0001.JPG") {
+		t.Errorf("Resolve() with the de calendar = %s", l.Resolve("IMG_0001.JPG", christmas))
+	}
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Insta360 cameras write one file per lens for each clip or photo, which
+// their software stitches into 360 footage only when both are kept together
+const (
+	insta360Video = ".insv" // MP4 video
+	insta360Photo = ".insp" // JPEG picture
+)
+
+// insta360Stem matches the names of Insta360 files, without extension: the
+// kind, the date, the lens and whether it is the low-resolution proxy, and the
+// number of the clip. VID_20240611_153000_00_001 and VID_20240611_153000_10_001
+// are both lenses of a clip, LRV_20240611_153000_01_001 its proxy.
+var insta360Stem = regexp.MustCompile(`(?i)^(VID|IMG|LRV)_(\d{8}_\d{6})_(\d)(\d)_(\d+)$`)
+
+// insta360Primary returns the name, without extension, of the file of the
+// first lens of the clip or photo of an Insta360 file, when it is another file
+func insta360Primary(stem string) (string, bool) {
+	m := insta360Stem.FindStringSubmatch(stem)
+	if m == nil || (m[3] == "0" && m[4] == "0") {
+		return "", false
+	}
+	kind := m[1]
+	if strings.EqualFold(kind, "LRV") {
+		kind = "VID"
+	}
+	return fmt.Sprintf("%s_%s_00_%s", kind, m[2], m[5]), true
+}
+
+// isInsta360Proxy reports whether a file is the low-resolution proxy of an
+// Insta360 clip, which the camera names LRV_ whatever its extension
+func isInsta360Proxy(name string) bool {
+	m := insta360Stem.FindStringSubmatch(strings.TrimSuffix(name, filepath.Ext(name)))
+	return m != nil && strings.EqualFold(m[1], "LRV")
+}
+
+// insta360Pair returns the file of the first lens of the file of another
+// lens of an Insta360 clip or photo, if it is next to it
+func insta360Pair(fsys FileSystem, path string) (string, bool) {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	if !strings.EqualFold(ext, insta360Video) && !strings.EqualFold(ext, insta360Photo) {
+		return "", false
+	}
+	primary, ok := insta360Primary(strings.TrimSuffix(name, ext))
+	if !ok {
+		return "", false
+	}
+
+	entries, err := fsys.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(entry.Name(), primary+ext) {
+			return filepath.Join(filepath.Dir(path), entry.Name()), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestInsta360Primary(t *testing.T) {
+	tests := []struct {
+		stem   string
+		want   string
+		wantOK bool
+	}{
+		{"VID_20240611_153000_10_001", "VID_20240611_153000_00_001", true},
+		{"IMG_20240611_153000_10_002", "IMG_20240611_153000_00_002", true},
+		{"LRV_20240611_153000_01_001", "VID_20240611_153000_00_001", true},
+		{"lrv_20240611_153000_11_001", "VID_20240611_153000_00_001", true},
+		{"VID_20240611_153000_00_001", "", false},
+		{"IMG_1234", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := insta360Primary(tt.stem)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("insta360Primary(%s) = %s, %v, want %s, %v", tt.stem, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestProcessMediaFilesInsta360(t *testing.T) {
+	source := t.TempDir()
+	destination := t.TempDir()
+
+	// The back lens of the clip started a second later, after midnight
+	front := time.Date(2024, time.June, 11, 23, 59, 59, 0, time.UTC)
+	files := map[string][]byte{
+		"VID_20240611_235959_00_001.insv": createTestMovie(front),
+		"VID_20240611_235959_10_001.insv": createTestMovie(front.Add(time.Second)),
+		"LRV_20240611_235959_01_001.insv": createTestMovie(front.Add(time.Second)),
+		"IMG_20240612_100000_00_002.insp": createSerialJPEG("2024:06:12 10:00:00", "1"),
+		"IMG_20240612_100000_10_002.insp": createSerialJPEG("2024:06:12 10:00:00", "1"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(source, name), data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	params := &models.Params{
+		Source:      source,
+		Destination: destination,
+		Compression: 50,
+		Proxies:     ProxiesKeep,
+	}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != len(files) {
+		t.Errorf("Expected %d copied files, got %+v", len(files), summary)
+	}
+
+	var got []string
+	for name, data := range files {
+		day := "2024/06-11"
+		if filepath.Ext(name) == insta360Photo {
+			day = "2024/06-12"
+		}
+		rel := day + "/" + name
+		written, err := os.ReadFile(filepath.Join(destination, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		// Pictures are never recompressed, which would lose their stitching data
+		if len(written) != len(data) {
+			t.Errorf("%s was modified on import", name)
+		}
+		got = append(got, rel)
+	}
+	if len(got) != len(files) {
+		sort.Strings(got)
+		t.Errorf("Files found at their expected place = %v, want all of %d", got, len(files))
+	}
+}
+
+func TestIsMediaFileInsta360Proxy(t *testing.T) {
+	tests := []struct {
+		proxies string
+		want    map[string]bool
+	}{
+		{"", map[string]bool{"VID_20240611_153000_00_001.insv": true, "LRV_20240611_153000_01_001.insv": false}},
+		{ProxiesKeep, map[string]bool{"VID_20240611_153000_00_001.insv": true, "LRV_20240611_153000_01_001.insv": true}},
+	}
+	for _, tt := range tests {
+		got := make(map[string]bool)
+		for name := range tt.want {
+			got[name] = isMediaFile(&models.Params{Proxies: tt.proxies}, name)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("isMediaFile() with proxies %q = %v, want %v", tt.proxies, got, tt.want)
+		}
+	}
+}
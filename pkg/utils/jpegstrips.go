@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"runtime"
+	"sync"
+)
+
+// Images above this number of pixels are encoded in strips on every CPU, as
+// re-encoding 100MP files on a single core dominates the time of a run
+const stripEncodePixels = 20_000_000
+
+// JPEG markers used to join strips
+const (
+	markerSOF0 = 0xC0
+	markerDRI  = 0xDD
+	markerSOS  = 0xDA
+	markerEOI  = 0xD9
+	markerRST0 = 0xD0
+)
+
+// For testing purposes
+var encodeCPUs = runtime.NumCPU
+
+// encodeJPEG encodes img at quality. Large images are encoded as strips in
+// parallel, then joined into a single baseline JPEG with restart markers.
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	b := img.Bounds()
+	if cpus := encodeCPUs(); cpus > 1 && b.Dx()*b.Dy() >= stripEncodePixels {
+		return encodeJPEGStrips(img, quality, cpus)
+	}
+	return encodeJPEGImage(img, quality)
+}
+
+// encodeJPEGImage encodes img at quality on the calling goroutine
+func encodeJPEGImage(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeJPEGStrips encodes img as up to n horizontal strips concurrently.
+// Strips are cut on MCU rows and encoded with the same tables, and each strip
+// starts with a fresh DC prediction like after a restart marker, so their
+// entropy-coded data can be joined with RSTn markers under one header with a
+// restart interval of one strip.
+func encodeJPEGStrips(img image.Image, quality, n int) ([]byte, error) {
+	sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return encodeJPEGImage(img, quality)
+	}
+
+	// Gray images have one component in 8x8 blocks, others are 4:2:0 in 16x16 MCUs
+	mcu := 16
+	if _, gray := img.(*image.Gray); gray {
+		mcu = 8
+	}
+	b := img.Bounds()
+	mcusPerRow := (b.Dx() + mcu - 1) / mcu
+	mcuRows := (b.Dy() + mcu - 1) / mcu
+	rowsPerStrip := min((mcuRows+n-1)/n, 0xFFFF/mcusPerRow) // The restart interval is 16 bits
+	if rowsPerStrip < 1 || rowsPerStrip >= mcuRows {
+		return encodeJPEGImage(img, quality)
+	}
+	strips := (mcuRows + rowsPerStrip - 1) / rowsPerStrip
+
+	encoded := make([][]byte, strips)
+	errs := make([]error, strips)
+	var wg sync.WaitGroup
+	for i := range strips {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			top := b.Min.Y + i*rowsPerStrip*mcu
+			rect := image.Rect(b.Min.X, top, b.Max.X, min(b.Max.Y, top+rowsPerStrip*mcu))
+			encoded[i], errs[i] = encodeJPEGImage(sub.SubImage(rect), quality)
+		}()
+	}
+	wg.Wait()
+
+	var out bytes.Buffer
+	for i, data := range encoded {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		tables, sos, scan, err := splitJPEG(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join JPEG strips: %w", err)
+		}
+		if i == 0 {
+			if err := setJPEGHeight(tables, b.Dy()); err != nil {
+				return nil, fmt.Errorf("failed to join JPEG strips: %w", err)
+			}
+			out.Write(tables)
+			out.Write([]byte{0xFF, markerDRI, 0x00, 0x04})
+			binary.Write(&out, binary.BigEndian, uint16(rowsPerStrip*mcusPerRow))
+			out.Write(sos)
+		} else {
+			out.Write([]byte{0xFF, markerRST0 + byte((i-1)%8)})
+		}
+		out.Write(scan)
+	}
+	out.Write([]byte{0xFF, markerEOI})
+	return out.Bytes(), nil
+}
+
+// splitJPEG splits a JPEG written by image/jpeg into its headers up to the
+// start of scan, the start of scan segment and the entropy-coded data
+func splitJPEG(data []byte) (tables, sos, scan []byte, err error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, nil, nil, fmt.Errorf("missing start of image")
+	}
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xFF {
+			return nil, nil, nil, fmt.Errorf("invalid marker at offset %d", offset)
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		end := offset + 2 + length
+		if end > len(data) {
+			return nil, nil, nil, fmt.Errorf("truncated segment at offset %d", offset)
+		}
+		if data[offset+1] == markerSOS {
+			if len(data)-end < 2 || data[len(data)-2] != 0xFF || data[len(data)-1] != markerEOI {
+				return nil, nil, nil, fmt.Errorf("missing end of image")
+			}
+			return data[:offset], data[offset:end], data[end : len(data)-2], nil
+		}
+		offset = end
+	}
+	return nil, nil, nil, fmt.Errorf("missing start of scan")
+}
+
+// setJPEGHeight sets the image height of the baseline frame header in tables
+func setJPEGHeight(tables []byte, height int) error {
+	offset := 2
+	for offset+4 <= len(tables) {
+		length := int(binary.BigEndian.Uint16(tables[offset+2:]))
+		if tables[offset+1] == markerSOF0 && length >= 7 && offset+9 <= len(tables) {
+			binary.BigEndian.PutUint16(tables[offset+5:], uint16(height))
+			return nil
+		}
+		offset += 2 + length
+	}
+	return fmt.Errorf("missing frame header")
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"reflect"
+	"testing"
+)
+
+// createTestImage returns an image of the given kind filled with a gradient
+func createTestImage(kind string, width, height int) image.Image {
+	rect := image.Rect(0, 0, width, height)
+	var img interface {
+		image.Image
+		Set(x, y int, c color.Color)
+	}
+	switch kind {
+	case "gray":
+		img = image.NewGray(rect)
+	default:
+		img = image.NewRGBA(rect)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 7), uint8(y * 5), uint8(x*y + 31), 0xFF})
+		}
+	}
+	if kind != "ycbcr" {
+		return img
+	}
+
+	// Decoded camera JPEGs are YCbCr images
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
+	decoded, _ := jpeg.Decode(&buf)
+	return decoded
+}
+
+func TestEncodeJPEGStrips(t *testing.T) {
+	tests := []struct {
+		kind          string
+		width, height int
+		strips        int
+	}{
+		{"ycbcr", 100, 70, 4},
+		{"rgba", 97, 130, 3},
+		{"rgba", 40, 16, 4}, // A single MCU row is encoded in one piece
+		{"gray", 50, 61, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			img := createTestImage(tt.kind, tt.width, tt.height)
+
+			joined, err := encodeJPEGStrips(img, 85, tt.strips)
+			if err != nil {
+				t.Fatalf("encodeJPEGStrips() error = %v", err)
+			}
+			sequential, err := encodeJPEGImage(img, 85)
+			if err != nil {
+				t.Fatalf("encodeJPEGImage() error = %v", err)
+			}
+
+			got, err := jpeg.Decode(bytes.NewReader(joined))
+			if err != nil {
+				t.Fatalf("Failed to decode joined strips: %v", err)
+			}
+			want, _ := jpeg.Decode(bytes.NewReader(sequential))
+			if got.Bounds() != want.Bounds() {
+				t.Fatalf("Decoded bounds = %v, want %v", got.Bounds(), want.Bounds())
+			}
+			// Strips are cut between blocks, decoded pixels match a sequential encode
+			if !reflect.DeepEqual(got, want) {
+				t.Error("Decoded strips differ from the sequentially encoded image")
+			}
+		})
+	}
+}
+
+func TestEncodeJPEGLargeImage(t *testing.T) {
+	originalCPUs := encodeCPUs
+	defer func() { encodeCPUs = originalCPUs }()
+	encodeCPUs = func() int { return 4 }
+
+	img := image.NewGray(image.Rect(0, 0, 5000, stripEncodePixels/5000))
+	data, err := encodeJPEG(img, 80)
+	if err != nil {
+		t.Fatalf("encodeJPEG() error = %v", err)
+	}
+	if !bytes.Contains(data, []byte{0xFF, markerDRI}) || !bytes.Contains(data, []byte{0xFF, markerRST0}) {
+		t.Error("Expected a large image to be encoded in strips joined by restart markers")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("Failed to decode large image: %v", err)
+	}
+}
+
+func TestSplitJPEG(t *testing.T) {
+	data, _ := encodeJPEGImage(image.NewGray(image.Rect(0, 0, 8, 8)), 75)
+	tables, sos, scan, err := splitJPEG(data)
+	if err != nil {
+		t.Fatalf("splitJPEG() error = %v", err)
+	}
+	if len(tables)+len(sos)+len(scan)+2 != len(data) || sos[1] != markerSOS {
+		t.Errorf("splitJPEG() = %d, %d and %d bytes of %d", len(tables), len(sos), len(scan), len(data))
+	}
+
+	for _, invalid := range [][]byte{nil, []byte("not a jpeg"), data[:len(data)-2], data[:20]} {
+		if _, _, _, err := splitJPEG(invalid); err == nil {
+			t.Errorf("splitJPEG() of %d bytes, expected an error", len(invalid))
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// Kinds of pictures detected in the source, also the names of the day
+// subfolders they are routed to
+const (
+	KindTimelapse = "timelapse"
+	KindPanorama  = "pano"
+)
+
+// Timelapse and panorama detection settings
+const (
+	timelapseMinFrames   = 30               // Shortest sequence considered a timelapse
+	timelapseMaxInterval = 10 * time.Minute // Longest interval between timelapse frames
+	timelapseTolerance   = time.Second      // Resolution of EXIF dates, each frame date may be off by up to this
+	panoramaMinRatio     = 2.0              // Smallest width to height ratio of a panorama
+)
+
+// RoutedKinds parses a comma-separated list of kinds routed to their own
+// subfolder, such as "timelapse,pano"
+func RoutedKinds(route string) map[string]bool {
+	kinds := make(map[string]bool)
+	for _, kind := range strings.Split(route, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds[kind] = true
+		}
+	}
+	return kinds
+}
+
+// detectKindsOf records the kind of the ordered shots of a directory that are
+// panoramas or frames of a timelapse
+func detectKindsOf(kinds map[string]string, shots []*shot) {
+	for _, s := range shots {
+		if s.known && isPanorama(s) {
+			for _, path := range s.files {
+				kinds[path] = KindPanorama
+			}
+		}
+	}
+
+	for _, sequence := range timelapseSequences(shots) {
+		for _, s := range sequence {
+			for _, path := range s.files {
+				kinds[path] = KindTimelapse
+			}
+		}
+	}
+}
+
+// isPanorama reports whether a shot is a panorama stitched by the camera:
+// described as such in XMP, named as such by the phone or much wider than high
+func isPanorama(s *shot) bool {
+	if s.meta.PanoramaXMP || strings.HasPrefix(strings.ToUpper(s.stem), "PANO") {
+		return true
+	}
+	width, height := s.meta.Width, s.meta.Height
+	if width < height {
+		width, height = height, width // Vertical panoramas
+	}
+	return height > 0 && float64(width)/float64(height) >= panoramaMinRatio
+}
+
+// timelapseSequences returns the long runs of evenly spaced shots of one camera
+func timelapseSequences(shots []*shot) [][]*shot {
+	var sequences [][]*shot
+	var current []*shot
+
+	flush := func() {
+		if len(current) >= timelapseMinFrames {
+			sequences = append(sequences, current)
+		}
+		current = nil
+	}
+
+	for _, s := range shots {
+		if !s.known {
+			continue
+		}
+		if len(current) == 0 {
+			current = []*shot{s}
+			continue
+		}
+
+		previous := current[len(current)-1]
+		gap := s.date.Sub(previous.date)
+		validGap := s.meta.Camera() == previous.meta.Camera() && gap >= timelapseTolerance && gap <= timelapseMaxInterval
+
+		// Both dates of a gap may be off by the EXIF resolution, compare it
+		// to the average interval of the run so far
+		interval := previous.date.Sub(current[0].date) / time.Duration(max(1, len(current)-1))
+		if !validGap || (len(current) > 1 && absDuration(gap-interval) >= 2*timelapseTolerance) {
+			flush()
+			// The last shot may start a new run at another interval
+			current = []*shot{s}
+			if validGap {
+				current = []*shot{previous, s}
+			}
+			continue
+		}
+		current = append(current, s)
+	}
+	flush()
+	return sequences
+}
+
+// absDuration returns the absolute value of d
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// kindTags returns the report and catalog tags of a file
+func kindTags(kinds map[string]string, path string) []string {
+	if kind, ok := kinds[path]; ok {
+		return []string{kind}
+	}
+	return nil
+}
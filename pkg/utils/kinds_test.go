@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// testShots builds known shots of one camera taken at the given offsets from a start date
+func testShots(offsets ...time.Duration) []*shot {
+	start := time.Date(2024, 6, 11, 15, 0, 0, 0, time.UTC)
+	shots := make([]*shot, len(offsets))
+	for i, offset := range offsets {
+		name := fmt.Sprintf("DSC%05d", i)
+		shots[i] = &shot{stem: name, date: start.Add(offset), meta: Metadata{Model: "A7"}, known: true, files: []string{name + ".ARW"}}
+	}
+	return shots
+}
+
+// evenOffsets returns n offsets spaced by interval, starting at from
+func evenOffsets(from time.Duration, n int, interval time.Duration) []time.Duration {
+	offsets := make([]time.Duration, n)
+	for i := range offsets {
+		offsets[i] = from + time.Duration(i)*interval
+	}
+	return offsets
+}
+
+func TestTimelapseSequences(t *testing.T) {
+	jittered := evenOffsets(0, 40, 5*time.Second)
+	for i := range jittered {
+		if i%3 == 0 {
+			jittered[i] += time.Second // EXIF dates only have a one second resolution
+		}
+	}
+
+	tests := []struct {
+		name    string
+		offsets []time.Duration
+		want    []int // Number of frames of each detected sequence
+	}{
+		{"Even interval", evenOffsets(0, 40, 10*time.Second), []int{40}},
+		{"Jittered interval", jittered, []int{40}},
+		{"Too short", evenOffsets(0, 20, 10*time.Second), nil},
+		{"Burst", evenOffsets(0, 40, 0), nil},
+		{"Interval too long", evenOffsets(0, 40, time.Hour), nil},
+		{"Irregular shots", []time.Duration{0, 3 * time.Second, 60 * time.Second, 62 * time.Second, 300 * time.Second}, nil},
+		{"Two timelapses", append(evenOffsets(0, 30, 2*time.Second), evenOffsets(time.Hour, 35, 30*time.Second)...), []int{30, 35}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sequences := timelapseSequences(testShots(tt.offsets...))
+			var got []int
+			for _, sequence := range sequences {
+				got = append(got, len(sequence))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("timelapseSequences() lengths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPanorama(t *testing.T) {
+	tests := []struct {
+		name string
+		shot shot
+		want bool
+	}{
+		{"Regular picture", shot{stem: "DSC00001", meta: Metadata{Width: 6000, Height: 4000}}, false},
+		{"Wide picture", shot{stem: "DSC00001", meta: Metadata{Width: 12000, Height: 3000}}, true},
+		{"Vertical panorama", shot{stem: "DSC00001", meta: Metadata{Width: 2000, Height: 9000}}, true},
+		{"Phone name", shot{stem: "PANO_20240611_150000"}, true},
+		{"Photo sphere XMP", shot{stem: "IMG_0001", meta: Metadata{PanoramaXMP: true}}, true},
+		{"Unknown size", shot{stem: "IMG_0001"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPanorama(&tt.shot); got != tt.want {
+				t.Errorf("isPanorama() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoutedKinds(t *testing.T) {
+	kinds := RoutedKinds(" timelapse, pano,,")
+	if len(kinds) != 2 || !kinds[KindTimelapse] || !kinds[KindPanorama] {
+		t.Errorf("RoutedKinds() = %v", kinds)
+	}
+	if len(RoutedKinds("")) != 0 {
+		t.Error("RoutedKinds(\"\") expected no kinds")
+	}
+}
+
+func TestProcessMediaFilesRoute(t *testing.T) {
+	source := t.TempDir()
+	destination := t.TempDir()
+
+	// A phone panorama next to a regular picture
+	createBracketFrame(t, filepath.Join(source, "PANO_0001.JPG"), "Pixel", "2024:06:11 15:30:10", 0)
+	createBracketFrame(t, filepath.Join(source, "IMG_0002.JPG"), "Pixel", "2024:06:11 15:35:10", 0)
+
+	params := &models.Params{
+		Source:      source,
+		Destination: destination,
+		Compression: -1,
+		Route:       KindPanorama,
+		ReportFile:  filepath.Join(t.TempDir(), "report.json"),
+	}
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+
+	day := filepath.Join(destination, "2024", "06-11")
+	for _, path := range []string{filepath.Join(day, KindPanorama, "PANO_0001.JPG"), filepath.Join(day, "IMG_0002.JPG")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s: %v", path, err)
+		}
+	}
+
+	for _, entry := range readTestReport(t, params.ReportFile).Files {
+		wantTags := filepath.Base(entry.Source) == "PANO_0001.JPG"
+		if (fmt.Sprint(entry.Tags) == "[pano]") != wantTags {
+			t.Errorf("Report entry %s tags = %v", entry.Source, entry.Tags)
+		}
+	}
+}
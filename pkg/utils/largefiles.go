@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// LargeFileThreshold is the size from which source files, such as long
+// videos, are streamed to the destination instead of being read in memory
+var LargeFileThreshold int64 = 256 << 20
+
+// isStreamed reports whether a source file is streamed to the destination.
+// JPEG files recompressed on import or carrying their provenance, and
+// encrypted files are transformed as a whole, they are always read in memory.
+func isStreamed(p *models.Params, info os.FileInfo, isJPG bool, enc *Encryptor) bool {
+	return info.Size() >= LargeFileThreshold && !(isJPG && p.Compression >= 0) && !embedsProvenance(p, isJPG) && enc == nil
+}
+
+// payload is the content written to the destination and mirrors: a buffer,
+// or a file of fsys streamed from the disk
+type payload struct {
+	buffer []byte // Content in memory, nil when streamed from path
+	fsys   FileSystem
+	path   string
+	size   int64
+	sum    string // Content hash with the algorithm of the run, when already known
+}
+
+// bufferPayload returns the payload of data in memory
+func bufferPayload(data []byte) payload {
+	return payload{buffer: data, size: int64(len(data))}
+}
+
+// filePayload returns the payload of a file of fsys streamed from the disk
+func filePayload(fsys FileSystem, path string, size int64) payload {
+	return payload{fsys: fsys, path: path, size: size}
+}
+
+// open returns a reader of the content
+func (c payload) open() (io.ReadCloser, error) {
+	if c.buffer != nil || c.path == "" {
+		return io.NopCloser(bytes.NewReader(c.buffer)), nil
+	}
+	return c.fsys.Open(c.path)
+}
+
+// bytes returns the content in memory, reading streamed files in full
+func (c payload) bytes() ([]byte, error) {
+	if c.buffer != nil || c.path == "" {
+		return c.buffer, nil
+	}
+	return readFile(c.fsys, c.path)
+}
+
+// hash returns the digest of the content like HashBuffer
+func (c payload) hash(algo string) (string, error) {
+	if c.sum != "" {
+		return c.sum, nil
+	}
+	if c.buffer != nil || c.path == "" {
+		return HashBuffer(c.buffer, algo), nil
+	}
+	r, err := c.open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	return HashReader(r, algo)
+}
+
+// writeTo copies the content to w
+func (c payload) writeTo(w io.Writer) (int64, error) {
+	r, err := c.open()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return io.Copy(w, r)
+}
+
+// copyLargeFile streams a source file too large to be read in memory to a new
+// destination file and its mirrors, deleting the source once its copies are
+// verified like copyOrCompressImage. It returns the content hash of the file,
+// computed while it is copied, empty when nothing was written.
+func copyLargeFile(fsys FileSystem, destPath string, sourceFile string, sourceInfo os.FileInfo, p *models.Params, power *powerMonitor, summary *ProcessingSummary) (string, []MirrorResult, string, error) {
+	if exists, err := fileExists(fsys, destPath); err != nil {
+		return ReportFailed, nil, "", fmt.Errorf("failed to check destination file: %w", err)
+	} else if exists {
+		output.Status("SKIPPED", fmt.Sprintf("Destination file already exists: %s", destPath))
+		summary.skip(existingSkip(fsys, destPath, filePayload(fsys, sourceFile, sourceInfo.Size()), false, p.HashAlgo, nil))
+		return ReportSkipped, mirrorExisting(fsys, destPath, p, power, summary), "", nil
+	}
+
+	if err := fsys.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+		return ReportFailed, nil, "", err
+	}
+
+	source, err := fsys.Open(sourceFile)
+	if err != nil {
+		return ReportFailed, nil, "", err
+	}
+	defer source.Close()
+
+	// The content is hashed on its way to the destination, for the catalog
+	// and to verify the copies before the source is deleted
+	writeStart := time.Now()
+	h := newHash(p.HashAlgo)
+	n, err := writeMediaFile(fsys, destPath, func(file File) (int64, error) {
+		n, err := io.Copy(file, io.TeeReader(source, h))
+		if err == nil && n != sourceInfo.Size() {
+			err = ErrSourceChanged
+		}
+		return n, err
+	})
+	elapsed := time.Since(writeStart)
+	summary.Stats.addRead(n, elapsed)
+	summary.Stats.addWrite(n, elapsed)
+	if errors.Is(err, fs.ErrExist) {
+		// Another worker wrote the destination file in the meantime
+		output.Status("SKIPPED", fmt.Sprintf("Destination file already exists: %s", destPath))
+		summary.skip(existingSkip(fsys, destPath, filePayload(fsys, sourceFile, sourceInfo.Size()), false, p.HashAlgo, nil))
+		return ReportSkipped, mirrorExisting(fsys, destPath, p, power, summary), "", nil
+	}
+	if err != nil {
+		return ReportFailed, nil, "", fmt.Errorf("failed to write destination file: %w", err)
+	}
+	sum := formatHash(h.Sum(nil), p.HashAlgo)
+
+	summary.Copied++
+	output.Status("COPIED", fmt.Sprintf("Processed file to: %s", destPath))
+	summary.Processed++
+	protectFile(fsys, destPath, p, summary)
+
+	// Mirrors are written from the destination file, the source may be a slower card
+	written := filePayload(fsys, destPath, n)
+	written.sum = sum
+	mirrors, mirrorErr := writeMirrors(fsys, destPath, written, p, power, summary)
+
+	// The source is only deleted once its copies are known to be intact
+	if p.DeleteSource {
+		if err := verifyCopy(fsys, destPath, written, p, power); err != nil {
+			return ReportCopied, mirrors, sum, fmt.Errorf("source file kept: %w", err)
+		}
+		summary.Verified++
+
+		if mirrorErr != nil {
+			return ReportCopied, mirrors, sum, fmt.Errorf("source file kept: %w", mirrorErr)
+		}
+
+		// A source changed since it was read holds data the copies lack
+		if err := checkSourceUnchanged(fsys, sourceFile, sourceInfo); err != nil {
+			return ReportCopied, mirrors, sum, fmt.Errorf("source file kept: %w", err)
+		}
+
+		if err := fsys.Remove(sourceFile); err != nil {
+			return ReportCopied, mirrors, sum, fmt.Errorf("failed to delete source file: %w", err)
+		}
+		output.Status("DELETED", fmt.Sprintf("Deleted source file: %s", sourceFile))
+		summary.Deleted++
+	}
+
+	return ReportCopied, mirrors, sum, nil
+}
@@ -0,0 +1,83 @@
+//go:build largefiles
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// TestProcessMediaFilesOver4GB imports a video larger than 4GB, past the
+// 32-bit sizes of boxes and buffers. It writes a sparse file but reads and
+// writes the whole video, run it with: go test -tags largefiles -run Over4GB -timeout 30m ./pkg/utils
+func TestProcessMediaFilesOver4GB(t *testing.T) {
+	source := t.TempDir()
+	destination := t.TempDir()
+	date := time.Date(2024, time.June, 11, 15, 30, 10, 0, time.UTC)
+
+	const mdatSize = 4<<30 + 512<<20
+	header := createLargeMovieHeader(mdatSize)
+	sourceFile := filepath.Join(source, "GL010001.LRV")
+	file, err := os.Create(sourceFile)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if _, err := file.Write(header); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	// The media data is left as a hole of the sparse file
+	if _, err := file.WriteAt(createLargeMovieTrailer(date), int64(len(header))+mdatSize); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	info, err := os.Stat(sourceFile)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+	sourceHash, err := hashFile(sourceFile, HashSHA256)
+	if err != nil {
+		t.Fatalf("hashFile() error = %v", err)
+	}
+
+	params := &models.Params{
+		Source:       source,
+		Destination:  destination,
+		Compression:  -1,
+		Proxies:      ProxiesKeep,
+		DeleteSource: true,
+		CatalogFile:  filepath.Join(t.TempDir(), "catalog.jsonl"),
+	}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Verified != 1 || summary.Deleted != 1 {
+		t.Fatalf("Expected the video copied, verified and deleted, got %+v", summary)
+	}
+
+	// Dated from the movie box following the 64-bit media data box
+	destPath := filepath.Join(destination, "2024", "06-11", "GL010001.LRV")
+	written, err := os.Stat(destPath)
+	if err != nil {
+		t.Fatalf("Expected imported video: %v", err)
+	}
+	if written.Size() != info.Size() {
+		t.Errorf("imported video has %d bytes, want %d", written.Size(), info.Size())
+	}
+
+	catalog, err := OpenCatalog(params.CatalogFile)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error = %v", err)
+	}
+	defer catalog.Close()
+	record, ok := catalog.Lookup(sourceHash)
+	if !ok || record.Size != info.Size() {
+		t.Errorf("catalog record = %+v, %v, want the video recorded with its content hash", record, ok)
+	}
+}
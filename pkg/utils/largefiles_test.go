@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createLargeMovieHeader builds the start of an MP4 file whose media data box
+// holds mdatSize bytes, with a 64-bit size like cameras write past 4GB. The
+// movie box, created at date, follows the media data.
+func createLargeMovieHeader(mdatSize int64) []byte {
+	header := isoBox("ftyp", []byte("qt  \x00\x00\x00\x00"))
+	header = binary.BigEndian.AppendUint32(header, 1)
+	header = append(header, "mdat"...)
+	return binary.BigEndian.AppendUint64(header, uint64(16+mdatSize))
+}
+
+// createLargeMovieTrailer builds the movie box following the media data
+func createLargeMovieTrailer(date time.Time) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[4:8], uint32(date.Sub(movieEpoch)/time.Second))
+	return isoBox("moov", isoBox("mvhd", mvhd))
+}
+
+// sizedInfo is the information of a file of the given size
+type sizedInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (i sizedInfo) Size() int64 { return i.size }
+
+func TestIsStreamed(t *testing.T) {
+	defer func(threshold int64) { LargeFileThreshold = threshold }(LargeFileThreshold)
+	LargeFileThreshold = 100
+
+	large := sizedInfo{size: 100}
+	small := sizedInfo{size: 99}
+	tests := []struct {
+		name   string
+		info   os.FileInfo
+		isJPG  bool
+		params models.Params
+		enc    *Encryptor
+		want   bool
+	}{
+		{"large video", large, false, models.Params{Compression: -1}, nil, true},
+		{"small video", small, false, models.Params{Compression: -1}, nil, false},
+		{"large JPEG copied", large, true, models.Params{Compression: -1}, nil, true},
+		{"large JPEG recompressed", large, true, models.Params{Compression: 80}, nil, false},
+		{"encrypted", large, false, models.Params{Compression: -1}, &Encryptor{}, false},
+	}
+	for _, tt := range tests {
+		if got := isStreamed(&tt.params, tt.info, tt.isJPG, tt.enc); got != tt.want {
+			t.Errorf("%s: isStreamed() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProcessMediaFilesStreamed(t *testing.T) {
+	defer func(threshold int64) { LargeFileThreshold = threshold }(LargeFileThreshold)
+	LargeFileThreshold = 1024
+
+	source := t.TempDir()
+	destination := t.TempDir()
+	mirror := t.TempDir()
+	date := time.Date(2024, time.June, 11, 15, 30, 10, 0, time.UTC)
+	mdat := bytes.Repeat([]byte("frame"), 1000)
+	data := append(append(createLargeMovieHeader(int64(len(mdat))), mdat...), createLargeMovieTrailer(date)...)
+	sourceFile := filepath.Join(source, "GL010001.LRV")
+	if err := os.WriteFile(sourceFile, data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:       source,
+		Destination:  destination,
+		Mirrors:      []string{mirror},
+		Compression:  -1,
+		Proxies:      ProxiesKeep,
+		DeleteSource: true,
+		CatalogFile:  filepath.Join(t.TempDir(), "catalog.jsonl"),
+		HashAlgo:     HashBLAKE3,
+	}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Verified != 1 || summary.Deleted != 1 || summary.Mirrored != 1 {
+		t.Errorf("Expected the file copied, mirrored, verified and deleted, got %+v", summary)
+	}
+
+	rel := filepath.Join("2024", "06-11", "GL010001.LRV")
+	for _, root := range []string{destination, mirror} {
+		written, err := os.ReadFile(filepath.Join(root, rel))
+		if err != nil {
+			t.Fatalf("Expected streamed file: %v", err)
+		}
+		if !bytes.Equal(written, data) {
+			t.Errorf("%s differs from the source", filepath.Join(root, rel))
+		}
+	}
+
+	catalog, err := OpenCatalog(params.CatalogFile)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error = %v", err)
+	}
+	defer catalog.Close()
+	if _, ok := catalog.Lookup(HashBuffer(data, HashBLAKE3)); !ok {
+		t.Errorf("Expected the streamed file recorded with its content hash")
+	}
+}
+
+func TestCopyLargeFileChangedSource(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "GX010001.MP4")
+	if err := os.WriteFile(source, []byte("video data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	// Listed before a sync client appended to it
+	listed := sizedInfo{size: 5}
+
+	var summary ProcessingSummary
+	destPath := filepath.Join(t.TempDir(), "GX010001.MP4")
+	params := &models.Params{Compression: -1, DeleteSource: true}
+	if _, _, _, err := copyLargeFile(OSFileSystem{}, destPath, source, listed, params, nil, &summary); !errors.Is(err, ErrSourceChanged) {
+		t.Errorf("copyLargeFile() error = %v, want ErrSourceChanged", err)
+	}
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no partial destination file, got %v", err)
+	}
+	if _, err := os.Stat(source); err != nil {
+		t.Errorf("Expected the source to be kept: %v", err)
+	}
+}
+
+func TestFindBoxOverflowingSize(t *testing.T) {
+	// A 64-bit size that overflows the offset of the box when added to it
+	box := binary.BigEndian.AppendUint32(isoBox("ftyp", []byte("qt  ")), 1)
+	box = append(box, "mdat"...)
+	box = binary.BigEndian.AppendUint64(box, 1<<63-1)
+
+	r := bytes.NewReader(box)
+	if _, _, err := findBox(r, 0, int64(len(box)), "moov"); err == nil {
+		t.Error("findBox() expected error for a box beyond the end of the file")
+	}
+	if err := checkBoxes(r, int64(len(box))); !errors.Is(err, ErrCorruptFile) {
+		t.Errorf("checkBoxes() error = %v, want ErrCorruptFile", err)
+	}
+}
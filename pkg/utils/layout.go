@@ -0,0 +1,337 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// DefaultLayout is the folder layout of organized files below the destination
+const DefaultLayout = "{year}/{month}-{day}"
+
+// FlatLayout puts files directly below the destination, their names prefixed
+// with their capture date and time: 2024-06-11_153010_DSC00001.ARW
+const FlatLayout = "flat"
+
+// flatNamePrefix is the format of the date prefixing names in the flat layout
+const flatNamePrefix = "2006-01-02_150405_"
+
+// layoutFile is a file whose destination folder is resolved from a layout,
+// or whose name is resolved from a name template
+type layoutFile struct {
+	path     string
+	date     time.Time
+	calendar *HolidayCalendar
+	stem     string            // Name of the file without extension, for {name}
+	seq      int               // Number of the file in its destination day, for {seq}
+	tags     map[string]string // Tags of the run, for {tag:<key>}
+	key      string            // Key of the keyed token resolved, such as client for {tag:client}
+}
+
+// layoutTokens resolve the tokens of layouts, such as {year}, for a file
+var layoutTokens = map[string]func(f layoutFile) string{
+	"year":       func(f layoutFile) string { return fmt.Sprintf("%d", f.date.Year()) },
+	"month":      func(f layoutFile) string { return fmt.Sprintf("%02d", f.date.Month()) },
+	"month_name": func(f layoutFile) string { return f.date.Month().String() },
+	"day":        func(f layoutFile) string { return fmt.Sprintf("%02d", f.date.Day()) },
+	"hour":       func(f layoutFile) string { return fmt.Sprintf("%02d", f.date.Hour()) },
+	"week": func(f layoutFile) string {
+		_, week := f.date.ISOWeek()
+		return fmt.Sprintf("%02d", week)
+	},
+	"weekday": func(f layoutFile) string { return f.calendar.Weekday(f.date) },
+	"holiday": func(f layoutFile) string { return f.calendar.Holiday(f.date) },
+	"camera":  func(f layoutFile) string { return readCamera(f.path) },
+	"ext":     func(f layoutFile) string { return strings.ToLower(strings.TrimPrefix(filepath.Ext(f.path), ".")) },
+	"tag":     func(f layoutFile) string { return f.tags[f.key] },
+}
+
+// keyedTokens are the tokens taking a key after a colon, such as
+// {tag:client} for the value of the run tag client=smith
+var keyedTokens = map[string]bool{"tag": true}
+
+// optionalTokens are the tokens only set on some days, such as {holiday}.
+// When they are empty, they are dropped with the separators before them, so
+// {month}-{day}_{holiday} gives 12-25_Christmas and 06-11.
+var optionalTokens = map[string]bool{"holiday": true, "tag": true}
+
+// Characters separating an optional token from the text before it
+const layoutSeparators = " _-."
+
+// Value of tokens left empty once sanitized, such as the camera of a file without metadata
+const unknownLayoutValue = "Unknown"
+
+// Longest value of a token in bytes, so odd metadata cannot exceed name length limits
+const maxLayoutValue = 64
+
+// windowsReservedNames are device names Windows does not allow as file names,
+// with or without extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// sanitizeLayoutValue makes a token value, possibly read from the metadata of
+// a file, a single folder name valid everywhere: path separators, characters
+// invalid on Windows and FAT and control characters are replaced, leading and
+// trailing dots and spaces dropped so the value can never be . or .., long
+// values truncated and Windows device names suffixed
+func sanitizeLayoutValue(value string) string {
+	safe := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(fatInvalidChars, r) || r < 0x20 || r == 0x7F || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, value)
+	safe = strings.Trim(safe, ". ")
+
+	if len(safe) > maxLayoutValue {
+		cut := maxLayoutValue
+		for cut > 0 && !utf8.RuneStart(safe[cut]) {
+			cut--
+		}
+		safe = strings.TrimRight(safe[:cut], ". ")
+	}
+	if safe == "" {
+		return unknownLayoutValue
+	}
+
+	stem, _, _ := strings.Cut(safe, ".")
+	if windowsReservedNames[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		safe += "_"
+	}
+	return safe
+}
+
+// LayoutTokens returns the names of the tokens layouts accept, sorted
+func LayoutTokens() []string {
+	return tokenNames(layoutTokens)
+}
+
+// tokenNames returns the names of tokens, sorted
+func tokenNames(tokens map[string]func(f layoutFile) string) []string {
+	names := make([]string, 0, len(tokens))
+	for name := range tokens {
+		if keyedTokens[name] {
+			name += ":<key>"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// layoutPart is a literal text or a token of a layout
+type layoutPart struct {
+	literal string
+	token   string
+	key     string // Key of a keyed token, such as client for {tag:client}
+}
+
+// Layout is a parsed folder layout, such as {year}/{month_name}/{day}: folders
+// are separated by /, and tokens between braces are replaced with values of
+// the file organized
+type Layout struct {
+	text     string
+	parts    []layoutPart
+	calendar *HolidayCalendar  // Calendar of {weekday} and {holiday}, that of the language when nil
+	command  string            // Command choosing the folder of every file, none when empty
+	flat     bool              // No date folders, names prefixed with the capture date instead
+	tags     map[string]string // Tags of the run, for {tag:<key>}
+}
+
+// ParseLayout parses a folder layout, rejecting unknown tokens and layouts
+// that would place files outside the destination
+func ParseLayout(text string) (*Layout, error) {
+	if text == "" {
+		return nil, fmt.Errorf("empty layout")
+	}
+	if text == FlatLayout {
+		return &Layout{text: text, flat: true}, nil
+	}
+	if strings.Contains(text, `\`) {
+		return nil, fmt.Errorf("use / to separate the folders of layout %s", text)
+	}
+	if strings.HasPrefix(text, "/") || filepath.IsAbs(text) || filepath.VolumeName(text) != "" {
+		return nil, fmt.Errorf("layout must be relative to the destination: %s", text)
+	}
+	for _, folder := range strings.Split(text, "/") {
+		switch folder {
+		case "":
+			return nil, fmt.Errorf("empty folder name in layout %s", text)
+		case ".", "..":
+			return nil, fmt.Errorf("layout must not leave the destination: %s", text)
+		}
+	}
+
+	parts, err := parseTemplate(text, "layout", layoutTokens)
+	if err != nil {
+		return nil, err
+	}
+	return &Layout{text: text, parts: parts}, nil
+}
+
+// parseTemplate splits a layout or name template, what, into literal texts
+// and tokens, rejecting tokens not in tokens
+func parseTemplate(text, what string, tokens map[string]func(f layoutFile) string) ([]layoutPart, error) {
+	var parts []layoutPart
+	rest := text
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			parts = append(parts, layoutPart{literal: rest})
+			break
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("unexpected } in %s %s", what, text)
+		}
+		if open > 0 {
+			parts = append(parts, layoutPart{literal: rest[:open]})
+		}
+		end := strings.IndexAny(rest[open+1:], "{}/")
+		if end < 0 || rest[open+1+end] != '}' {
+			return nil, fmt.Errorf("unclosed { in %s %s", what, text)
+		}
+		token := rest[open+1 : open+1+end]
+		name, key, keyed := strings.Cut(token, ":")
+		if _, ok := tokens[name]; !ok || keyed && !keyedTokens[name] {
+			return nil, fmt.Errorf("unknown token {%s} in %s %s (expected {%s})", token, what, text, strings.Join(tokenNames(tokens), "}, {"))
+		}
+		if keyedTokens[name] && (strings.TrimSpace(key) == "" || strings.Contains(key, "=")) {
+			return nil, fmt.Errorf("token {%s} in %s %s needs a key, such as {%s:client}", token, what, text, name)
+		}
+		parts = append(parts, layoutPart{token: name, key: key})
+		rest = rest[open+1+end+1:]
+	}
+	return parts, nil
+}
+
+// String returns the text of the layout
+func (l *Layout) String() string {
+	return l.text
+}
+
+// dir returns the folder of a file taken at date, relative to the destination
+func (l *Layout) dir(path string, date time.Time) string {
+	f := layoutFile{path: path, date: date, calendar: l.calendar, tags: l.tags}
+	return filepath.FromSlash(renderTemplate(l.parts, layoutTokens, f))
+}
+
+// checkTemplateTags rejects a layout or name template, what, using tags the
+// run was not given, which would always be empty
+func checkTemplateTags(parts []layoutPart, tags map[string]string, what, text string) error {
+	for _, part := range parts {
+		if _, ok := tags[part.key]; part.token == "tag" && !ok {
+			return fmt.Errorf("%s %s uses the tag %s, which the run was not given with -tag %s=<value>", what, text, part.key, part.key)
+		}
+	}
+	return nil
+}
+
+// renderTemplate replaces the tokens of a layout or name template with the
+// sanitized values of a file
+func renderTemplate(parts []layoutPart, tokens map[string]func(f layoutFile) string, f layoutFile) string {
+	if f.calendar == nil {
+		f.calendar = holidayCalendarOf("")
+	}
+	var text string
+	for _, part := range parts {
+		if part.token == "" {
+			text += part.literal
+			continue
+		}
+		f.key = part.key
+		value := tokens[part.token](f)
+		if value == "" && optionalTokens[part.token] {
+			text = strings.TrimRight(text, layoutSeparators)
+			continue
+		}
+		text += sanitizeLayoutValue(value)
+	}
+	return text
+}
+
+// Resolve returns where a file taken at date is organized, relative to the
+// destination. Token values are sanitized, and a path that would still leave
+// the destination falls back to the default layout.
+func (l *Layout) Resolve(path string, date time.Time) string {
+	dir := l.dir(path, date)
+	if l.command != "" {
+		dir = l.commandFolder(path, date, dir)
+	}
+	rel := filepath.Join(dir, l.fileName(filepath.Base(path), date))
+	if !filepath.IsLocal(rel) && l != defaultLayout {
+		return defaultLayout.Resolve(path, date)
+	}
+	return rel
+}
+
+// fileName returns the name of a file taken at date, name relative to its
+// folder: name itself, prefixed with the date in the flat layout unless a
+// previous flat import already did
+func (l *Layout) fileName(name string, date time.Time) string {
+	if !l.flat {
+		return name
+	}
+	dir, base := filepath.Split(name)
+	prefix := date.Format(flatNamePrefix)
+	if strings.HasPrefix(base, prefix) {
+		return name
+	}
+	return filepath.Join(dir, prefix+base)
+}
+
+// Preview returns where the file at path would be organized, relative to the
+// destination, and its capture date read from its metadata
+func (l *Layout) Preview(path string) (string, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	date, err := readMediaDate(OSFileSystem{}, path, info, nil, false)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return l.Resolve(path, date), date, nil
+}
+
+var (
+	defaultLayout, _ = ParseLayout(DefaultLayout)
+	parsedLayouts    sync.Map // Layouts of runs, keyed by text, holiday calendar and command
+)
+
+// layoutOf returns the layout of a run, the default layout when it has none.
+// An invalid layout is reported along with the default layout.
+func layoutOf(p *models.Params) (*Layout, error) {
+	if p.Layout == "" && p.LayoutCommand == "" {
+		return defaultLayout, nil
+	}
+	text := p.Layout
+	if text == "" {
+		text = DefaultLayout
+	}
+	key := text + "\x00" + p.Holidays + "\x00" + p.LayoutCommand + "\x00" + fmt.Sprint(p.RunTags)
+	if l, ok := parsedLayouts.Load(key); ok {
+		return l.(*Layout), nil
+	}
+	l, err := ParseLayout(text)
+	if err != nil {
+		return defaultLayout, err
+	}
+	if err := checkTemplateTags(l.parts, p.RunTags, "layout", text); err != nil {
+		return defaultLayout, err
+	}
+	l.calendar = holidayCalendarOf(p.Holidays)
+	l.tags = p.RunTags
+	l = l.WithCommand(p.LayoutCommand)
+	parsedLayouts.Store(key, l)
+	return l, nil
+}
@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		layout  string
+		wantErr string // Part of the expected error, none for valid layouts
+	}{
+		{layout: DefaultLayout},
+		{layout: "{year}/{month_name}/{day}"},
+		{layout: "Photos {year}/week {week}/{camera}/{ext}"},
+		{layout: "{year}-{month}-{day}_{hour}h"},
+		{layout: FlatLayout},
+		{layout: "{tag:client}/{year}/{month}-{day}"},
+		{layout: "", wantErr: "empty layout"},
+		{layout: "{tag}/{year}", wantErr: "needs a key"},
+		{layout: "{tag:}/{year}", wantErr: "needs a key"},
+		{layout: "{tag:a=b}/{year}", wantErr: "needs a key"},
+		{layout: "{year:2024}", wantErr: "unknown token {year:2024}"},
+		{layout: "{year}/{month_nam}", wantErr: "unknown token {month_nam}"},
+		{layout: "{year/{month}", wantErr: "unclosed {"},
+		{layout: "{year}/{month", wantErr: "unclosed {"},
+		{layout: "{year}}/{month}", wantErr: "unexpected }"},
+		{layout: "/{year}", wantErr: "relative to the destination"},
+		{layout: "../{year}", wantErr: "must not leave the destination"},
+		{layout: "{year}/../../{day}", wantErr: "must not leave the destination"},
+		{layout: "{year}//{day}", wantErr: "empty folder name"},
+		{layout: "{year}/", wantErr: "empty folder name"},
+		{layout: `{year}\{day}`, wantErr: "use / to separate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			_, err := ParseLayout(tt.layout)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ParseLayout(%q) unexpected error: %v", tt.layout, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseLayout(%q) error = %v, want it to contain %q", tt.layout, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLayoutResolve(t *testing.T) {
+	date := time.Date(2024, 6, 11, 15, 30, 10, 0, time.UTC)
+	camera := filepath.Join(t.TempDir(), "DSC00001.JPG")
+	if err := os.WriteFile(camera, createTestJPEG(createTestTIFF(TagModel, tiffTypeASCII, []byte("ILCE-7M3\x00"))), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		layout string
+		path   string
+		want   string
+	}{
+		{DefaultLayout, "DSC00001.ARW", filepath.Join("2024", "06-11", "DSC00001.ARW")},
+		{"{year}/{month_name}/{day}", "DSC00001.ARW", filepath.Join("2024", "June", "11", "DSC00001.ARW")},
+		{"{year}/W{week}/{hour}h", "DSC00001.ARW", filepath.Join("2024", "W24", "15h", "DSC00001.ARW")},
+		{"{ext}/{year}", "DSC00001.ARW", filepath.Join("arw", "2024", "DSC00001.ARW")},
+		{"{year}/{camera}", camera, filepath.Join("2024", "ILCE-7M3", "DSC00001.JPG")},
+		{"{year}/{camera}", "missing.jpg", filepath.Join("2024", "Unknown", "missing.jpg")},
+		{"{year}/{month}-{day}_{weekday}", "DSC00001.ARW", filepath.Join("2024", "06-11_Tuesday", "DSC00001.ARW")},
+		{"{year}/{month}-{day} - {holiday}", "DSC00001.ARW", filepath.Join("2024", "06-11", "DSC00001.ARW")},
+		{"{year}/{holiday}", "DSC00001.ARW", filepath.Join("2024", "DSC00001.ARW")},
+		{FlatLayout, "DSC00001.ARW", "2024-06-11_153010_DSC00001.ARW"},
+		{FlatLayout, "2024-06-11_153010_DSC00001.ARW", "2024-06-11_153010_DSC00001.ARW"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			layout, err := ParseLayout(tt.layout)
+			if err != nil {
+				t.Fatalf("ParseLayout() error = %v", err)
+			}
+			if got := layout.Resolve(tt.path, date); got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayoutTags(t *testing.T) {
+	date := time.Date(2024, 6, 11, 15, 30, 10, 0, time.UTC)
+	tests := []struct {
+		name    string
+		layout  string
+		tags    map[string]string
+		want    string
+		wantErr string
+	}{
+		{"tag folder", "{tag:client}/{year}", map[string]string{"client": "smith"}, filepath.Join("smith", "2024", "DSC00001.ARW"), ""},
+		{"sanitized value", "{tag:job}/{year}", map[string]string{"job": "wedding/2024"}, filepath.Join("wedding_2024", "2024", "DSC00001.ARW"), ""},
+		{"empty value", "{year}/{month}-{day}_{tag:job}", map[string]string{"job": ""}, filepath.Join("2024", "06-11", "DSC00001.ARW"), ""},
+		{"missing tag", "{tag:client}/{year}", map[string]string{"job": "wedding"}, "", "uses the tag client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := layoutOf(&models.Params{Layout: tt.layout, RunTags: tt.tags})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("layoutOf() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("layoutOf() error = %v", err)
+			}
+			if got := layout.Resolve("DSC00001.ARW", date); got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeLayoutValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"ILCE-7M3", "ILCE-7M3"},
+		{"Canon EOS R5", "Canon EOS R5"},
+		{"", unknownLayoutValue},
+		{"..", unknownLayoutValue},
+		{" . ", unknownLayoutValue},
+		{"../../etc", "_.._etc"},
+		{`..\Windows`, "_Windows"},
+		{"/etc/passwd", "_etc_passwd"},
+		{".hidden", "hidden"},
+		{"model.", "model"},
+		{`a:b*c?d"e<f>g|h`, "a_b_c_d_e_f_g_h"},
+		{"line\nbreak\x00\x7f", "line_break__"},
+		{"bad\xffutf8", "bad_utf8"},
+		{"CON", "CON_"},
+		{"nul.txt", "nul.txt_"},
+		{"COM10", "COM10"},
+		{strings.Repeat("a", 100), strings.Repeat("a", maxLayoutValue)},
+		{strings.Repeat("é", 40), strings.Repeat("é", maxLayoutValue/2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := sanitizeLayoutValue(tt.value); got != tt.want {
+				t.Errorf("sanitizeLayoutValue(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayoutResolveMaliciousMetadata(t *testing.T) {
+	date := time.Date(2024, 6, 11, 15, 30, 10, 0, time.UTC)
+	layout, err := ParseLayout("{camera}/{year}")
+	if err != nil {
+		t.Fatalf("ParseLayout() error = %v", err)
+	}
+
+	for _, model := range []string{"..", "../../../../tmp/evil", `..\..\evil`, "/abs", "a/../../b"} {
+		t.Run(model, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "IMG_0001.JPG")
+			data := createTestJPEG(createTestTIFF(TagModel, tiffTypeASCII, append([]byte(model), 0)))
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			got := layout.Resolve(path, date)
+			if !filepath.IsLocal(got) {
+				t.Fatalf("Resolve() = %s, leaves the destination", got)
+			}
+			if parts := strings.Split(got, string(filepath.Separator)); len(parts) != 3 {
+				t.Errorf("Resolve() = %s, want the camera as a single folder", got)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesLayout(t *testing.T) {
+	source := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "photo.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	dest := t.TempDir()
+	params := &models.Params{Source: source, Destination: dest, Compression: -1, Layout: "{year}/{month_name}/{day}"}
+	want := filepath.Join(dest, "2025", "January", "11", "photo.jpg")
+
+	plan, err := PlanMediaFiles(params)
+	if err != nil {
+		t.Fatalf("PlanMediaFiles() error = %v", err)
+	}
+	if len(plan) != 1 || plan[0].Destination != want {
+		t.Errorf("PlanMediaFiles() = %+v, want destination %s", plan, want)
+	}
+
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("Expected %s: %v", want, err)
+	}
+
+	params.Layout = "{year}/{nope}"
+	if _, err := ProcessMediaFiles(params); err == nil {
+		t.Error("Expected an error for an invalid layout")
+	}
+}
+
+func TestProcessMediaFilesFlatLayout(t *testing.T) {
+	// Cards of two bodies with the same file name, shot at the same time
+	source := t.TempDir()
+	for _, card := range []string{"a", "b"} {
+		dir := filepath.Join(source, card)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "IMG_0001.jpg"), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	dest := t.TempDir()
+	params := &models.Params{Source: source, Destination: dest, Compression: -1, Layout: FlatLayout}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Skips.ExistsConflict != 1 {
+		t.Errorf("ProcessMediaFiles() = %+v, want 1 copied and 1 conflict", summary)
+	}
+
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatalf("Failed to read destination: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) != 1 || !strings.HasPrefix(names[0], "2025-01-11_") || !strings.HasSuffix(names[0], "_IMG_0001.jpg") {
+		t.Errorf("Destination holds %v, want a single dated IMG_0001.jpg", names)
+	}
+}
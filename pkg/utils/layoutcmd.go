@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// For testing purposes
+var runLayoutCommand = func(name string, args []string, input []byte) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	return cmd.Output()
+}
+
+// layoutRequest describes a file to the layout command, as one JSON object
+// on its standard input
+type layoutRequest struct {
+	Source string `json:"source"`
+	Name   string `json:"name"`
+	Date   string `json:"date"`             // Capture date, as the wall clock time of the camera
+	Make   string `json:"make,omitempty"`   // Camera make
+	Model  string `json:"model,omitempty"`  // Camera model
+	Serial string `json:"serial,omitempty"` // Camera body serial number
+	Folder string `json:"folder"`           // Folder the layout gives the file, relative to the destination
+}
+
+// layoutCommandFolders are the folders chosen by layout commands, keyed by
+// command, file and date, as every file is placed more than once in a run
+var layoutCommandFolders sync.Map
+
+// WithCommand returns the layout leaving the choice of the folder of every
+// file to a command, for rules too specific for layouts such as school years
+// or client codes in file names. The command is split on spaces and run
+// without a shell for every file, {} being replaced with the file path. It
+// reads a JSON description of the file on its standard input, including the
+// folder the layout gives, and prints the folder of the file relative to the
+// destination, using / as separator. Folder names are sanitized like token
+// values. The layout folder is kept when the command prints nothing or fails.
+func (l *Layout) WithCommand(command string) *Layout {
+	if strings.TrimSpace(command) == "" {
+		return l
+	}
+	c := *l
+	c.command = command
+	return &c
+}
+
+// commandFolder returns the folder the layout command chooses for a file
+// taken at date, folder being the one given by the layout
+func (l *Layout) commandFolder(path string, date time.Time, folder string) string {
+	key := l.command + "\x00" + path + "\x00" + date.String() + "\x00" + folder
+	if chosen, ok := layoutCommandFolders.Load(key); ok {
+		return chosen.(string)
+	}
+
+	chosen, err := runLayoutScript(l.command, path, date, folder)
+	if err != nil {
+		output.Status("WARNING", fmt.Sprintf("Layout command failed for %s, organized by the layout: %v", path, err))
+		chosen = folder
+	}
+	layoutCommandFolders.Store(key, chosen)
+	return chosen
+}
+
+// runLayoutScript runs the layout command on a file, returning the folder it
+// prints, sanitized, or folder when it prints nothing
+func runLayoutScript(command, path string, date time.Time, folder string) (string, error) {
+	fields := strings.Fields(command)
+	args := make([]string, 0, len(fields))
+	for _, field := range fields[1:] {
+		args = append(args, strings.ReplaceAll(field, checkPlaceholder, path))
+	}
+
+	request := layoutRequest{Source: path, Name: filepath.Base(path), Date: date.Format("2006-01-02T15:04:05"), Folder: filepath.ToSlash(folder)}
+	if file, err := os.Open(path); err == nil {
+		meta, _ := GetImageMetadata(file, filepath.Ext(path))
+		file.Close()
+		request.Make, request.Model, request.Serial = meta.Make, meta.Model, meta.Serial
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := runLayoutCommand(fields[0], args, append(input, '\n'))
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", fmt.Errorf("exit status %d", exitErr.ExitCode())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	var parts []string
+	for _, part := range strings.FieldsFunc(strings.TrimSpace(line), func(r rune) bool { return r == '/' || r == '\\' }) {
+		// Folders never climb out of the destination
+		if part == "." || part == ".." {
+			continue
+		}
+		parts = append(parts, sanitizeLayoutValue(part))
+	}
+	if len(parts) == 0 {
+		return folder, nil
+	}
+	return filepath.Join(parts...), nil
+}
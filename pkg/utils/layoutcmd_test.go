@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestLayoutWithCommand(t *testing.T) {
+	original := runLayoutCommand
+	defer func() { runLayoutCommand = original }()
+
+	date := time.Date(2024, time.June, 11, 15, 30, 10, 0, time.UTC)
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   string
+	}{
+		{"folder", "2023-2024/June\n", nil, filepath.Join("2023-2024", "June", "DSC00001.ARW")},
+		{"first line", "School/\nignored\n", nil, filepath.Join("School", "DSC00001.ARW")},
+		{"sanitized", "../Clients/A:B\n", nil, filepath.Join("Clients", "A_B", "DSC00001.ARW")},
+		{"nothing printed", "\n", nil, filepath.Join("2024", "06-11", "DSC00001.ARW")},
+		{"failure", "", errors.New("exit status 1"), filepath.Join("2024", "06-11", "DSC00001.ARW")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request layoutRequest
+			var gotArgs []string
+			runLayoutCommand = func(name string, args []string, input []byte) ([]byte, error) {
+				gotArgs = args
+				if err := json.Unmarshal(input, &request); err != nil {
+					t.Errorf("layout command input %q: %v", input, err)
+				}
+				return []byte(tt.output), tt.err
+			}
+
+			// Every layout is given its own command so folders are not cached across cases
+			layout := defaultLayout.WithCommand("route --case " + tt.name + " {}")
+			if got := layout.Resolve("DSC00001.ARW", date); got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+			if len(gotArgs) == 0 || gotArgs[len(gotArgs)-1] != "DSC00001.ARW" {
+				t.Errorf("layout command arguments = %q, want the file path last", gotArgs)
+			}
+			want := layoutRequest{Source: "DSC00001.ARW", Name: "DSC00001.ARW", Date: "2024-06-11T15:30:10", Folder: "2024/06-11"}
+			if request != want {
+				t.Errorf("layout command input = %+v, want %+v", request, want)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesLayoutCommand(t *testing.T) {
+	original := runLayoutCommand
+	defer func() { runLayoutCommand = original }()
+	runLayoutCommand = func(name string, args []string, input []byte) ([]byte, error) {
+		var request layoutRequest
+		if err := json.Unmarshal(input, &request); err != nil {
+			return nil, err
+		}
+		if request.Serial != "4012345" {
+			return nil, nil
+		}
+		return []byte("Clients/ACME\n"), nil
+	}
+
+	source := t.TempDir()
+	destination := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "DSC00001.JPG"), createSerialJPEG("2024:06:11 15:30:10", "4012345"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "DSC00002.JPG"), createSerialJPEG("2024:06:11 15:31:10", "1"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:        source,
+		Destination:   destination,
+		Compression:   -1,
+		LayoutCommand: "route-by-client",
+	}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 2 {
+		t.Errorf("Expected 2 copied files, got %+v", summary)
+	}
+	for _, rel := range []string{"Clients/ACME/DSC00001.JPG", "2024/06-11/DSC00002.JPG"} {
+		if _, err := os.Stat(filepath.Join(destination, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("Expected organized file: %v", err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+	"github.com/matdmb/organize-media/pkg/sqlite"
+)
+
+// lightroomCaptureTime is the layout of the capture times of Lightroom
+// catalogs, which may carry fractions of a second and a time zone after it
+const lightroomCaptureTime = "2006-01-02T15:04:05"
+
+// LightroomCatalog holds the files managed in a Lightroom Classic catalog
+type LightroomCatalog struct {
+	paths map[string]bool // Lower case absolute paths of the files of the catalog
+	shots map[string]bool // Lower case names given on the card and capture times, see lightroomShotKey
+}
+
+// lightroomShotKey returns the key of a picture named name on the card and taken at date
+func lightroomShotKey(name string, date time.Time) string {
+	return strings.ToLower(name) + " " + date.Format(lightroomCaptureTime)
+}
+
+// lightroomCatalogPath returns the catalog of path: path itself, or the
+// catalog next to a previews folder, Photos.lrcat for Photos Previews.lrdata,
+// Photos Smart Previews.lrdata or the previews.db file inside them
+func lightroomCatalogPath(path string) string {
+	dir := path
+	if strings.EqualFold(filepath.Base(path), "previews.db") {
+		dir = filepath.Dir(path)
+	}
+	if !strings.EqualFold(filepath.Ext(dir), ".lrdata") {
+		return path
+	}
+
+	stem := strings.TrimSuffix(filepath.Base(dir), filepath.Ext(dir))
+	for _, suffix := range []string{" Smart Previews", " Previews"} {
+		if len(stem) > len(suffix) && strings.EqualFold(stem[len(stem)-len(suffix):], suffix) {
+			stem = stem[:len(stem)-len(suffix)]
+			break
+		}
+	}
+	return filepath.Join(filepath.Dir(dir), stem+".lrcat")
+}
+
+// ReadLightroomCatalog reads the files managed in a Lightroom catalog, given
+// as its .lrcat file or its previews. Lightroom should be closed, changes it
+// has not written to the catalog yet are not seen.
+func ReadLightroomCatalog(path string) (*LightroomCatalog, error) {
+	path = lightroomCatalogPath(path)
+	db, err := sqlite.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Lightroom catalog: %w", err)
+	}
+	defer db.Close()
+
+	roots := make(map[int64]string)
+	err = db.Rows("AgLibraryRootFolder", []string{"id_local", "absolutePath"}, func(values []any) error {
+		id, _ := values[0].(int64)
+		roots[id], _ = values[1].(string)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Lightroom catalog %s: %w", path, err)
+	}
+
+	folders := make(map[int64]string)
+	err = db.Rows("AgLibraryFolder", []string{"id_local", "rootFolder", "pathFromRoot"}, func(values []any) error {
+		id, _ := values[0].(int64)
+		root, _ := values[1].(int64)
+		rel, _ := values[2].(string)
+		folders[id] = roots[root] + rel
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Lightroom catalog %s: %w", path, err)
+	}
+
+	// Names of the files, on the card and in the catalog
+	c := &LightroomCatalog{paths: make(map[string]bool), shots: make(map[string]bool)}
+	names := make(map[int64][]string)
+	err = db.Rows("AgLibraryFile", []string{"id_local", "folder", "idx_filename", "originalFilename"}, func(values []any) error {
+		id, _ := values[0].(int64)
+		folder, _ := values[1].(int64)
+		name, _ := values[2].(string)
+		original, _ := values[3].(string)
+		if name == "" {
+			return nil
+		}
+		if dir, ok := folders[folder]; ok && dir != "" {
+			c.paths[strings.ToLower(filepath.Clean(filepath.FromSlash(dir+name)))] = true
+		}
+		names[id] = append(names[id], name)
+		if original != "" && !strings.EqualFold(original, name) {
+			names[id] = append(names[id], original)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Lightroom catalog %s: %w", path, err)
+	}
+
+	err = db.Rows("Adobe_images", []string{"rootFile", "captureTime"}, func(values []any) error {
+		file, _ := values[0].(int64)
+		captured, _ := values[1].(string)
+		if len(captured) < len(lightroomCaptureTime) {
+			return nil
+		}
+		date, err := time.Parse(lightroomCaptureTime, captured[:len(lightroomCaptureTime)])
+		if err != nil {
+			return nil
+		}
+		for _, name := range names[file] {
+			c.shots[lightroomShotKey(name, date)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Lightroom catalog %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// loadLightroom reads the Lightroom catalog of a run, nil without catalog
+func loadLightroom(p *models.Params) (*LightroomCatalog, error) {
+	if p.LightroomCatalog == "" {
+		return nil, nil
+	}
+	return ReadLightroomCatalog(p.LightroomCatalog)
+}
+
+// Manages reports whether a file taken at date is managed in the catalog:
+// imported in place from where it is, or imported from a card under its name
+// and with the same capture time. A nil catalog manages no file.
+func (c *LightroomCatalog) Manages(path string, date time.Time) bool {
+	if c == nil {
+		return false
+	}
+	if abs, err := filepath.Abs(path); err == nil && c.paths[strings.ToLower(abs)] {
+		return true
+	}
+	return c.shots[lightroomShotKey(filepath.Base(path), date)]
+}
+
+// skipLightroom leaves out a file taken at date already managed in Lightroom,
+// or flags its entry with -lightroom-flag, reporting whether it was left out
+func (r *mediaRun) skipLightroom(entry *ReportEntry, date time.Time, summary *ProcessingSummary) bool {
+	if !r.lightroom.Manages(entry.Source, date) {
+		return false
+	}
+	summary.Lightroom++
+	if r.p.LightroomFlag {
+		output.Status("WARNING", fmt.Sprintf("Already managed in Lightroom, imported anyway: %s", entry.Source))
+		entry.Lightroom = true
+		return false
+	}
+	summary.skip(SkipFiltered)
+	output.Status("SKIPPED", fmt.Sprintf("Already managed in Lightroom: %s", entry.Source))
+	entry.Status, entry.Reason, entry.SkipReason = ReportSkipped, "already managed in the Lightroom catalog", SkipFiltered
+	r.finish(*entry)
+	return true
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createLightroomCatalog writes a catalog with the tables of Lightroom
+// Classic read by the tool, managing the files of folder: IMG_0001.JPG as is,
+// and DSC00002.JPG renamed to 20250111-0002.JPG on import
+func createLightroomCatalog(t *testing.T, path, folder string) {
+	t.Helper()
+	shell, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+
+	root := filepath.ToSlash(folder) + "/"
+	script := `CREATE TABLE AgLibraryRootFolder (id_local INTEGER PRIMARY KEY, id_global UNIQUE NOT NULL, absolutePath UNIQUE NOT NULL DEFAULT '', name NOT NULL DEFAULT '', relativePathFromCatalog);
+CREATE TABLE AgLibraryFolder (id_local INTEGER PRIMARY KEY, id_global UNIQUE NOT NULL, parentId INTEGER, pathFromRoot NOT NULL DEFAULT '', rootFolder INTEGER NOT NULL DEFAULT 0, visibility INTEGER);
+CREATE TABLE AgLibraryFile (id_local INTEGER PRIMARY KEY, id_global UNIQUE NOT NULL, baseName NOT NULL DEFAULT '', errorMessage, errorTime, extension NOT NULL DEFAULT '', externalModTime, folder INTEGER NOT NULL DEFAULT 0, idx_filename NOT NULL DEFAULT '', importHash, lc_idx_filename NOT NULL DEFAULT '', lc_idx_filenameExtension NOT NULL DEFAULT '', md5, modTime, originalFilename NOT NULL DEFAULT '', sidecarExtensions);
+CREATE TABLE Adobe_images (id_local INTEGER PRIMARY KEY, id_global UNIQUE NOT NULL, aspectRatioCache NOT NULL DEFAULT -1, captureTime, rootFile INTEGER NOT NULL DEFAULT 0);
+INSERT INTO AgLibraryRootFolder VALUES (1, 'R1', '` + strings.ReplaceAll(root, "'", "''") + `', 'Pictures', NULL);
+INSERT INTO AgLibraryFolder VALUES (2, 'F1', NULL, '2025/', 1, NULL);
+INSERT INTO AgLibraryFile (id_local, id_global, baseName, extension, folder, idx_filename, originalFilename) VALUES (3, 'A', 'IMG_0001', 'JPG', 2, 'IMG_0001.JPG', 'IMG_0001.JPG');
+INSERT INTO AgLibraryFile (id_local, id_global, baseName, extension, folder, idx_filename, originalFilename) VALUES (4, 'B', '20250111-0002', 'JPG', 2, '20250111-0002.JPG', 'DSC00002.JPG');
+INSERT INTO Adobe_images (id_local, id_global, captureTime, rootFile) VALUES (5, 'I1', '2025-01-11T10:00:00', 3);
+INSERT INTO Adobe_images (id_local, id_global, captureTime, rootFile) VALUES (6, 'I2', '2025-01-11T09:00:00.50+01:00', 4);`
+	cmd := exec.Command(shell, path)
+	cmd.Stdin = strings.NewReader(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3 error = %v: %s", err, out)
+	}
+}
+
+func TestLightroomCatalogPath(t *testing.T) {
+	dir := filepath.Join("Pictures", "Lightroom")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(dir, "Photos.lrcat"), filepath.Join(dir, "Photos.lrcat")},
+		{filepath.Join(dir, "Photos Previews.lrdata"), filepath.Join(dir, "Photos.lrcat")},
+		{filepath.Join(dir, "Photos Smart Previews.lrdata"), filepath.Join(dir, "Photos.lrcat")},
+		{filepath.Join(dir, "Photos Previews.lrdata", "previews.db"), filepath.Join(dir, "Photos.lrcat")},
+		{filepath.Join(dir, "previews.db"), filepath.Join(dir, "previews.db")},
+	}
+	for _, tt := range tests {
+		if got := lightroomCatalogPath(tt.path); got != tt.want {
+			t.Errorf("lightroomCatalogPath(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLightroomCatalogManages(t *testing.T) {
+	folder := t.TempDir()
+	catalog := filepath.Join(t.TempDir(), "Photos.lrcat")
+	createLightroomCatalog(t, catalog, folder)
+
+	// The catalog is also found from its previews
+	c, err := ReadLightroomCatalog(filepath.Join(filepath.Dir(catalog), "Photos Previews.lrdata", "previews.db"))
+	if err != nil {
+		t.Fatalf("ReadLightroomCatalog() error = %v", err)
+	}
+
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02 15:04:05", s)
+		return d
+	}
+	tests := []struct {
+		name string
+		path string
+		date time.Time
+		want bool
+	}{
+		{"imported in place", filepath.Join(folder, "2025", "IMG_0001.JPG"), time.Time{}, true},
+		{"imported in place in another case", filepath.Join(folder, "2025", "img_0001.jpg"), time.Time{}, true},
+		{"same name and time on a card", filepath.Join("card", "IMG_0001.JPG"), date("2025-01-11 10:00:00"), true},
+		{"renamed on import", filepath.Join("card", "DSC00002.JPG"), date("2025-01-11 09:00:00"), true},
+		{"same name at another time", filepath.Join("card", "IMG_0001.JPG"), date("2025-01-11 10:00:01"), false},
+		{"new file", filepath.Join("card", "IMG_0003.JPG"), date("2025-01-11 10:00:00"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Manages(tt.path, tt.date); got != tt.want {
+				t.Errorf("Manages(%s, %v) = %v, want %v", tt.path, tt.date, got, tt.want)
+			}
+		})
+	}
+
+	var none *LightroomCatalog
+	if none.Manages(filepath.Join(folder, "2025", "IMG_0001.JPG"), time.Time{}) {
+		t.Error("Manages() of a nil catalog = true")
+	}
+}
+
+func TestReadLightroomCatalogNotCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Photos.lrcat")
+	if err := os.WriteFile(path, []byte("not a catalog"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if _, err := ReadLightroomCatalog(path); err == nil {
+		t.Error("ReadLightroomCatalog() of a file that is not a catalog, expected an error")
+	}
+}
+
+func TestProcessMediaFilesLightroom(t *testing.T) {
+	catalog := filepath.Join(t.TempDir(), "Photos.lrcat")
+	createLightroomCatalog(t, catalog, t.TempDir())
+
+	for _, flagged := range []bool{false, true} {
+		source := t.TempDir()
+		files := map[string]string{
+			"IMG_0001.JPG": "2025:01:11 10:00:00", // Managed in Lightroom
+			"IMG_0003.JPG": "2025:01:11 11:00:00",
+		}
+		for name, date := range files {
+			if err := os.WriteFile(filepath.Join(source, name), createSerialJPEG(date, "1"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+		}
+
+		dest := t.TempDir()
+		params := &models.Params{Source: source, Destination: dest, Compression: -1, LightroomCatalog: catalog, LightroomFlag: flagged, ReportFile: filepath.Join(t.TempDir(), "report.json")}
+		plan, err := PlanMediaFiles(params)
+		if err != nil {
+			t.Fatalf("PlanMediaFiles() error = %v", err)
+		}
+		summary, err := ProcessMediaFiles(params)
+		if err != nil {
+			t.Fatalf("ProcessMediaFiles() error = %v", err)
+		}
+
+		want := []string{filepath.Join("2025", "01-11", "IMG_0003.JPG")}
+		if flagged {
+			want = []string{filepath.Join("2025", "01-11", "IMG_0001.JPG"), filepath.Join("2025", "01-11", "IMG_0003.JPG")}
+		}
+		if len(plan) != len(want) {
+			t.Errorf("With flag %v, planned %d files, want %d", flagged, len(plan), len(want))
+		}
+		if got := organizedFiles(t, dest); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("With flag %v, organized files = %v, want %v", flagged, got, want)
+		}
+		if summary.Lightroom != 1 {
+			t.Errorf("With flag %v, %d files managed in Lightroom, want 1", flagged, summary.Lightroom)
+		}
+		for _, entry := range readTestReport(t, params.ReportFile).Files {
+			if filepath.Base(entry.Source) != "IMG_0001.JPG" {
+				continue
+			}
+			if flagged && !entry.Lightroom || !flagged && entry.SkipReason != SkipFiltered {
+				t.Errorf("With flag %v, report entry = %+v", flagged, entry)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// Low-power mode settings
+const (
+	lowPowerWorkers      = 2                      // Files processed concurrently at most
+	lowPowerPause        = 250 * time.Millisecond // Pause between files on battery
+	batteryCheckInterval = 30 * time.Second       // The laptop may be plugged in or out during the run
+)
+
+// For testing purposes
+var onBattery = batteryPowered
+
+// runWorkers returns the number of files a run processes concurrently, at
+// most lowPowerWorkers in low-power mode, which never tunes it upward. The
+// workers are started once, so the cap holds on mains power too: unlike the
+// pauses and the size-only checks, it does not follow the power source.
+func runWorkers(p *models.Params) int {
+	if !p.LowPower {
+		return p.Workers
+	}
+	if p.Workers == models.AutoWorkers {
+		return lowPowerWorkers
+	}
+	return min(max(1, p.Workers), lowPowerWorkers)
+}
+
+// powerMonitor pauses between the files of a low-power run while the machine
+// runs on battery, checking the power source every batteryCheckInterval
+type powerMonitor struct {
+	mu        sync.Mutex
+	checked   time.Time
+	onBattery bool
+}
+
+// newPowerMonitor returns the monitor of a run, nil unless in low-power mode
+func newPowerMonitor(p *models.Params) *powerMonitor {
+	if !p.LowPower {
+		return nil
+	}
+	return &powerMonitor{}
+}
+
+// battery reports whether the machine runs on battery, checked again once
+// batteryCheckInterval has passed. A nil monitor reports mains power.
+func (m *powerMonitor) battery() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checked.IsZero() || time.Since(m.checked) >= batteryCheckInterval {
+		m.onBattery, m.checked = onBattery(), time.Now()
+	}
+	return m.onBattery
+}
+
+// yield pauses after a file on battery, so the disks and CPUs idle between
+// files, until ctx is done. A nil monitor never pauses.
+func (m *powerMonitor) yield(ctx context.Context) {
+	if !m.battery() {
+		return
+	}
+	timer := time.NewTimer(lowPowerPause)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// verifyCopy checks a destination file before its source is deleted: its
+// content is read back and hashed, or while a low-power run is on battery
+// only its size is compared, sparing the battery a second read of every file.
+// On mains power, low-power runs verify content like any other run.
+func verifyCopy(fsys FileSystem, path string, written payload, p *models.Params, power *powerMonitor) error {
+	if !power.battery() {
+		return verifyWrittenFile(fsys, path, written, p.HashAlgo)
+	}
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to verify destination file: %w", err)
+	}
+	if info.Size() != written.size {
+		return fmt.Errorf("destination file %s does not match the written data", path)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// batteryPowered reports whether the Mac runs on battery, from the power
+// source pmset reports first
+func batteryPowered() bool {
+	out, err := exec.Command("pmset", "-g", "batt").Output()
+	return err == nil && strings.Contains(string(out), "'Battery Power'")
+}
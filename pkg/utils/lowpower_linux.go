@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// powerSupplyRoot lists the power supplies of the machine
+var powerSupplyRoot = "/sys/class/power_supply"
+
+// batteryPowered reports whether the machine runs on battery: it has a
+// discharging battery and no online mains or USB supply
+func batteryPowered() bool {
+	supplies, err := os.ReadDir(powerSupplyRoot)
+	if err != nil {
+		return false
+	}
+	read := func(supply, name string) string {
+		data, _ := os.ReadFile(filepath.Join(powerSupplyRoot, supply, name))
+		return strings.TrimSpace(string(data))
+	}
+
+	discharging := false
+	for _, supply := range supplies {
+		switch read(supply.Name(), "type") {
+		case "Mains", "USB":
+			if read(supply.Name(), "online") == "1" {
+				return false
+			}
+		case "Battery":
+			// Peripherals such as mice report their battery with scope Device
+			if read(supply.Name(), "scope") != "Device" && read(supply.Name(), "status") == "Discharging" {
+				discharging = true
+			}
+		}
+	}
+	return discharging
+}
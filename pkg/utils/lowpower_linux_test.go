@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBatteryPowered(t *testing.T) {
+	tests := []struct {
+		name     string
+		supplies map[string]map[string]string
+		want     bool
+	}{
+		{"desktop", nil, false},
+		{"discharging laptop", map[string]map[string]string{
+			"AC":   {"type": "Mains", "online": "0"},
+			"BAT0": {"type": "Battery", "scope": "System", "status": "Discharging"},
+		}, true},
+		{"plugged laptop", map[string]map[string]string{
+			"AC":   {"type": "Mains", "online": "1"},
+			"BAT0": {"type": "Battery", "status": "Charging"},
+		}, false},
+		{"USB-C charger", map[string]map[string]string{
+			"ucsi-source-psy-USBC000:001": {"type": "USB", "online": "1"},
+			"BAT0":                        {"type": "Battery", "status": "Discharging"},
+		}, false},
+		{"wireless mouse", map[string]map[string]string{
+			"AC":        {"type": "Mains", "online": "0"},
+			"hid-mouse": {"type": "Battery", "scope": "Device", "status": "Discharging"},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for supply, attributes := range tt.supplies {
+				if err := os.MkdirAll(filepath.Join(root, supply), 0755); err != nil {
+					t.Fatalf("Failed to create folder: %v", err)
+				}
+				for name, value := range attributes {
+					if err := os.WriteFile(filepath.Join(root, supply, name), []byte(value+"\n"), 0644); err != nil {
+						t.Fatalf("Failed to create test file: %v", err)
+					}
+				}
+			}
+
+			original := powerSupplyRoot
+			powerSupplyRoot = root
+			defer func() { powerSupplyRoot = original }()
+			if got := batteryPowered(); got != tt.want {
+				t.Errorf("batteryPowered() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
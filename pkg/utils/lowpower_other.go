@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package utils
+
+// batteryPowered cannot tell the power source on this platform, low-power
+// runs never pause between files
+func batteryPowered() bool {
+	return false
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestRunWorkers(t *testing.T) {
+	tests := []struct {
+		workers  int
+		lowPower bool
+		want     int
+	}{
+		{1, false, 1},
+		{8, false, 8},
+		{models.AutoWorkers, false, models.AutoWorkers},
+		{1, true, 1},
+		{8, true, lowPowerWorkers},
+		{models.AutoWorkers, true, lowPowerWorkers},
+	}
+	for _, tt := range tests {
+		if got := runWorkers(&models.Params{Workers: tt.workers, LowPower: tt.lowPower}); got != tt.want {
+			t.Errorf("runWorkers(%d, low power %v) = %d, want %d", tt.workers, tt.lowPower, got, tt.want)
+		}
+	}
+}
+
+func TestPowerMonitorYield(t *testing.T) {
+	original := onBattery
+	defer func() { onBattery = original }()
+
+	checks := 0
+	battery := true
+	onBattery = func() bool {
+		checks++
+		return battery
+	}
+
+	if newPowerMonitor(&models.Params{}) != nil {
+		t.Error("newPowerMonitor() without low-power mode is not nil")
+	}
+	var none *powerMonitor
+	none.yield(context.Background())
+
+	m := newPowerMonitor(&models.Params{LowPower: true})
+	start := time.Now()
+	m.yield(context.Background())
+	if elapsed := time.Since(start); elapsed < lowPowerPause {
+		t.Errorf("yield() on battery paused %v, want at least %v", elapsed, lowPowerPause)
+	}
+
+	// The power source is only checked again once the interval has passed
+	battery = false
+	m.yield(context.Background())
+	if checks != 1 {
+		t.Errorf("Power source checked %d times, want 1", checks)
+	}
+	m.checked = time.Now().Add(-batteryCheckInterval)
+	start = time.Now()
+	m.yield(context.Background())
+	if elapsed := time.Since(start); checks != 2 || elapsed >= lowPowerPause {
+		t.Errorf("yield() on mains checked %d times and paused %v", checks, elapsed)
+	}
+
+	// Cancelling the run ends the pause
+	battery = true
+	m.checked = time.Time{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	m.yield(ctx)
+	if elapsed := time.Since(start); elapsed >= lowPowerPause {
+		t.Errorf("yield() of a cancelled run paused %v", elapsed)
+	}
+}
+
+func TestVerifyCopyLowPower(t *testing.T) {
+	original := onBattery
+	defer func() { onBattery = original }()
+
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		written  string
+		lowPower bool
+		battery  bool
+		wantErr  bool
+	}{
+		{"corrupted", false, false, false},
+		{"photodata", false, false, true},
+		{"photodata", false, true, true},
+		{"photodata", true, false, true}, // On mains power, the content is read back
+		{"photodata", true, true, false}, // Same size on battery, the content is not read back
+		{"photo", true, true, true},
+	}
+	for _, tt := range tests {
+		onBattery = func() bool { return tt.battery }
+		p := &models.Params{LowPower: tt.lowPower, HashAlgo: HashSHA256}
+		if err := verifyCopy(OSFileSystem{}, path, bufferPayload([]byte(tt.written)), p, newPowerMonitor(p)); (err != nil) != tt.wantErr {
+			t.Errorf("verifyCopy(%q, low power %v, battery %v) error = %v, wantErr %v", tt.written, tt.lowPower, tt.battery, err, tt.wantErr)
+		}
+	}
+}
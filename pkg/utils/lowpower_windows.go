@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// systemPowerStatus is the SYSTEM_POWER_STATUS structure of winbase.h
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+var procGetSystemPowerStatus = syscall.NewLazyDLL("kernel32.dll").NewProc("GetSystemPowerStatus")
+
+// batteryPowered reports whether the machine runs on battery, its AC line
+// being offline
+func batteryPowered() bool {
+	var status systemPowerStatus
+	if ok, _, _ := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&status))); ok == 0 {
+		return false
+	}
+	return status.ACLineStatus == 0
+}
@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// ManifestImport summarizes the import of the checksum manifests of a destination
+type ManifestImport struct {
+	Manifests int // Manifests read
+	Imported  int // Files recorded from their checksum
+	Recorded  int // Files of the manifests already in the catalog
+	Stale     int // Files modified after their manifest was written, or missing, left unrecorded
+	Invalid   int // Manifests that could not be read or parsed, skipped
+}
+
+// checksumManifestAlgo returns the hash algorithm of a sha256sum or b3sum
+// manifest, recognized by its name such as SHA256SUMS, photos.sha256 or
+// B3SUMS, and whether the file is one
+func checksumManifestAlgo(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	stem := strings.TrimSuffix(lower, ".txt")
+	switch {
+	case stem == "sha256sums" || stem == "sha256sum" || strings.HasSuffix(stem, ".sha256") || strings.HasSuffix(stem, ".sha256sum"):
+		return HashSHA256, true
+	case stem == "b3sums" || stem == "blake3sums" || strings.HasSuffix(stem, ".b3") || strings.HasSuffix(stem, ".blake3"):
+		return HashBLAKE3, true
+	}
+	return "", false
+}
+
+// isHexDigest reports whether s is the hex encoding of a 256-bit digest
+func isHexDigest(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// readChecksumManifest reads the digests of a sha256sum or b3sum manifest by
+// file, as written by the tools: "<digest>  <file>", "<digest> *<file>" for
+// binary mode, or "SHA256 (<file>) = <digest>" for the BSD tag format
+func readChecksumManifest(r io.Reader) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if open := strings.Index(line, " ("); open > 0 && strings.Contains(line, ") = ") {
+			end := strings.LastIndex(line, ") = ")
+			if digest := strings.ToLower(line[end+4:]); end > open && isHexDigest(digest) {
+				sums[line[open+2:end]] = digest
+			}
+			continue
+		}
+
+		digest, file, ok := strings.Cut(line, " ")
+		digest = strings.ToLower(strings.TrimPrefix(digest, "\\"))
+		if !ok || !isHexDigest(digest) {
+			continue
+		}
+		if file = strings.TrimPrefix(strings.TrimPrefix(file, " "), "*"); file != "" {
+			sums[file] = digest
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// exifToolDigestColumns are the names, in lower case, of the columns of
+// ExifTool CSV exports holding the SHA-256 digests of the files
+var exifToolDigestColumns = map[string]bool{
+	"sha256":    true,
+	"sha256sum": true,
+	"checksum":  true,
+	"hash":      true,
+}
+
+// readExifToolCSV reads the digests of an ExifTool CSV export, written by
+// "exiftool -csv", by file: the SourceFile column and a column holding the
+// SHA-256 digests of the files, such as SHA256 or Checksum. Other CSV files
+// hold no digests.
+func readExifToolCSV(r io.Reader) (map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	// Spreadsheets and scripts often leave stray quotes in names
+	reader.LazyQuotes = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	source, digest := -1, -1
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "sourcefile" {
+			source = i
+		} else if exifToolDigestColumns[name] && digest < 0 {
+			digest = i
+		}
+	}
+	if source < 0 || digest < 0 {
+		return nil, nil
+	}
+
+	sums := make(map[string]string)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return sums, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) <= max(source, digest) {
+			continue
+		}
+		if sum := strings.ToLower(strings.TrimSpace(record[digest])); isHexDigest(sum) && record[source] != "" {
+			sums[record[source]] = sum
+		}
+	}
+}
+
+// readManifest reads the digests of a checksum manifest by file, returning
+// the algorithm of the digests, or false when the file is not a manifest
+func readManifest(path string) (map[string]string, string, bool, error) {
+	algo, ok := checksumManifestAlgo(filepath.Base(path))
+	isCSV := strings.EqualFold(filepath.Ext(path), ".csv")
+	if !ok && !isCSV {
+		return nil, "", false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer file.Close()
+
+	var sums map[string]string
+	if isCSV {
+		algo = HashSHA256
+		sums, err = readExifToolCSV(file)
+	} else {
+		sums, err = readChecksumManifest(file)
+	}
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to read checksum manifest %s: %w", path, err)
+	}
+	return sums, algo, len(sums) > 0, nil
+}
+
+// manifestFile resolves a file listed in a manifest: relative to the folder
+// of the manifest, where the tools are usually run, or to the destination
+func manifestFile(name, manifestDir, destination string) string {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	path := filepath.Join(manifestDir, name)
+	if _, err := os.Stat(path); err != nil {
+		if other := filepath.Join(destination, name); other != path {
+			if _, err := os.Stat(other); err == nil {
+				return other
+			}
+		}
+	}
+	return path
+}
+
+// ImportManifests records in the catalog the files of the destination listed
+// in its checksum manifests: sha256sum and b3sum files, and ExifTool CSV
+// exports with a SHA-256 column. Their digests are trusted instead of hashing
+// the files again, for the manifests of the algorithm of the catalog, algo.
+// A file modified after its manifest was written is left unrecorded, as its
+// digest may be outdated, and so are files already recorded. Hidden folders
+// are ignored, and manifests that cannot be parsed are skipped with a
+// warning. Nothing is written with dryRun.
+func ImportManifests(catalog *Catalog, destination, algo string, dryRun bool) (ManifestImport, error) {
+	var result ManifestImport
+	if algo == "" {
+		algo = HashSHA256
+	}
+
+	recorded := make(map[string]bool)
+	for _, record := range catalog.AllRecords() {
+		if abs, err := filepath.Abs(record.Destination); err == nil {
+			recorded[abs] = true
+		}
+	}
+
+	var records []CatalogRecord
+	err := filepath.Walk(destination, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != destination && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		sums, manifestAlgo, ok, err := readManifest(path)
+		if err != nil {
+			result.Invalid++
+			output.Status("WARNING", fmt.Sprintf("Skipping manifest that could not be read: %s: %v", path, err))
+			return nil
+		}
+		if !ok || manifestAlgo != algo {
+			return nil
+		}
+		result.Manifests++
+
+		names := make([]string, 0, len(sums))
+		for name := range sums {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			file := manifestFile(name, filepath.Dir(path), destination)
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				return err
+			}
+			if recorded[abs] {
+				result.Recorded++
+				continue
+			}
+			fileInfo, err := os.Stat(file)
+			if err != nil || !fileInfo.Mode().IsRegular() || fileInfo.ModTime().After(info.ModTime()) {
+				result.Stale++
+				continue
+			}
+
+			id := recordedFileID(file)
+			if id == "" {
+				id = newFileID(fileInfo.ModTime())
+			}
+			digest, _ := hex.DecodeString(sums[name])
+			recorded[abs] = true
+			result.Imported++
+			records = append(records, CatalogRecord{
+				ID:          id,
+				Hash:        formatHash(digest, algo),
+				Destination: file,
+				Size:        fileInfo.Size(),
+				ImportedAt:  fileInfo.ModTime(),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to walk destination: %w", err)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	return result, catalog.AddRecords(records)
+}
@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestChecksumManifestAlgo(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"SHA256SUMS", HashSHA256, true},
+		{"sha256sum.txt", HashSHA256, true},
+		{"2025.sha256", HashSHA256, true},
+		{"B3SUMS", HashBLAKE3, true},
+		{"photos.b3", HashBLAKE3, true},
+		{"MD5SUMS", "", false},
+		{"notes.txt", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := checksumManifestAlgo(tt.name); got != tt.want || ok != tt.wantOK {
+			t.Errorf("checksumManifestAlgo(%s) = %s, %v, want %s, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestReadChecksumManifest(t *testing.T) {
+	a := strings.Repeat("a", 64)
+	b := strings.Repeat("B", 64)
+	c := strings.Repeat("c", 64)
+	manifest := "# Written by sha256sum\n" +
+		a + "  01-11/IMG_0001.JPG\n" +
+		b + " *01-11/IMG 0002.JPG\r\n" +
+		"SHA256 (01-12/IMG_0003.JPG) = " + c + "\n" +
+		"abc  short.JPG\n"
+
+	got, err := readChecksumManifest(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("readChecksumManifest() error = %v", err)
+	}
+	want := map[string]string{
+		"01-11/IMG_0001.JPG": a,
+		"01-11/IMG 0002.JPG": strings.ToLower(b),
+		"01-12/IMG_0003.JPG": c,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readChecksumManifest() = %v, want %v", got, want)
+	}
+}
+
+func TestReadExifToolCSV(t *testing.T) {
+	a := strings.Repeat("a", 64)
+	tests := []struct {
+		name string
+		csv  string
+		want map[string]string
+	}{
+		{"SHA256 column", "SourceFile,DateTimeOriginal,SHA256\n./IMG_0001.JPG,2025:01:11 10:00:00," + a + "\n./IMG_0002.JPG,,-\n", map[string]string{"./IMG_0001.JPG": a}},
+		{"byte order mark", "\ufeffSourceFile,Checksum\nIMG_0001.JPG," + strings.ToUpper(a) + "\n", map[string]string{"IMG_0001.JPG": a}},
+		{"stray quotes", "SourceFile,SHA256\nIMG_0001 \"copy\".JPG," + a + "\n", map[string]string{"IMG_0001 \"copy\".JPG": a}},
+		{"no digest column", "SourceFile,DateTimeOriginal\nIMG_0001.JPG,2025:01:11 10:00:00\n", nil},
+		{"not an export", "date,amount\n2025-01-11,10\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readExifToolCSV(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatalf("readExifToolCSV() error = %v", err)
+			}
+			if len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readExifToolCSV() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportManifests(t *testing.T) {
+	dest := t.TempDir()
+	files := map[string]string{
+		filepath.Join("2025", "01-11", "IMG_0001.JPG"): "first",
+		filepath.Join("2025", "01-11", "IMG_0002.JPG"): "second",
+		filepath.Join("2025", "01-12", "IMG_0003.JPG"): "third",
+		filepath.Join("2024", "IMG_0004.JPG"):          "fourth",
+		filepath.Join("2024", "IMG_0005.JPG"):          "fifth, changed after its manifest",
+	}
+	for name, content := range files {
+		path := filepath.Join(dest, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	sum := func(name string) string { return HashBuffer([]byte(files[name]), HashSHA256) }
+
+	// A sha256sum manifest of 2025 and an ExifTool export of 2024, listing a missing file
+	manifests := map[string]string{
+		filepath.Join("2025", "SHA256SUMS"): sum(filepath.Join("2025", "01-11", "IMG_0001.JPG")) + "  01-11/IMG_0001.JPG\n" +
+			sum(filepath.Join("2025", "01-11", "IMG_0002.JPG")) + "  01-11/IMG_0002.JPG\n" +
+			sum(filepath.Join("2025", "01-12", "IMG_0003.JPG")) + "  01-12/IMG_0003.JPG\n",
+		filepath.Join("2024", "export.csv"): "SourceFile,SHA256\n" +
+			"2024/IMG_0004.JPG," + sum(filepath.Join("2024", "IMG_0004.JPG")) + "\n" +
+			"2024/IMG_0005.JPG," + strings.Repeat("0", 64) + "\n" +
+			"2024/IMG_0006.JPG," + strings.Repeat("1", 64) + "\n",
+		filepath.Join("2024", "B3SUMS"): strings.Repeat("2", 64) + "  IMG_0004.JPG\n",
+		// A manifest that cannot be parsed is skipped, the others are imported
+		"SHA256SUMS": strings.Repeat("3", 2*1024*1024) + "\n",
+	}
+	for name, content := range manifests {
+		if err := os.WriteFile(filepath.Join(dest, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create manifest: %v", err)
+		}
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dest, "2024", "IMG_0005.JPG"), later, later); err != nil {
+		t.Fatalf("Failed to change modification time: %v", err)
+	}
+
+	catalogPath := filepath.Join(t.TempDir(), "catalog.jsonl")
+	catalog, err := OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error = %v", err)
+	}
+	// IMG_0003.JPG is already recorded
+	recorded := filepath.Join(dest, "2025", "01-12", "IMG_0003.JPG")
+	if err := catalog.Add(CatalogRecord{Hash: sum(filepath.Join("2025", "01-12", "IMG_0003.JPG")), Destination: recorded, Size: 5}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	want := ManifestImport{Manifests: 2, Imported: 3, Recorded: 1, Stale: 2, Invalid: 1}
+	if got, err := ImportManifests(catalog, dest, HashSHA256, true); err != nil || got != want {
+		t.Fatalf("ImportManifests() dry run = %+v, %v, want %+v", got, err, want)
+	}
+	if catalog.Len() != 1 {
+		t.Errorf("Catalog holds %d records after a dry run, want 1", catalog.Len())
+	}
+	if got, err := ImportManifests(catalog, dest, HashSHA256, false); err != nil || got != want {
+		t.Fatalf("ImportManifests() = %+v, %v, want %+v", got, err, want)
+	}
+	catalog.Close()
+
+	// The records are in the catalog file, found by the hash of their content
+	catalog, err = OpenCatalog(catalogPath)
+	if err != nil {
+		t.Fatalf("OpenCatalog() error = %v", err)
+	}
+	defer catalog.Close()
+	for _, name := range []string{filepath.Join("2025", "01-11", "IMG_0001.JPG"), filepath.Join("2025", "01-11", "IMG_0002.JPG"), filepath.Join("2024", "IMG_0004.JPG")} {
+		record, ok := catalog.Lookup(sum(name))
+		if !ok || record.Destination != filepath.Join(dest, name) || record.Size != int64(len(files[name])) || record.ID == "" {
+			t.Errorf("Record of %s = %+v, %v", name, record, ok)
+		}
+	}
+	if catalog.Len() != 4 {
+		t.Errorf("Catalog holds %d records, want 4", catalog.Len())
+	}
+
+	// Manifests of another algorithm are not imported
+	if got, err := ImportManifests(catalog, dest, HashBLAKE3, true); err != nil || got != (ManifestImport{Manifests: 1, Recorded: 1, Invalid: 1}) {
+		t.Errorf("ImportManifests() of BLAKE3 manifests = %+v, %v", got, err)
+	}
+}
+
+func TestProcessMediaFilesImportsManifests(t *testing.T) {
+	source := t.TempDir()
+	data := createSerialJPEG("2025:01:11 10:00:00", "1")
+	if err := os.WriteFile(filepath.Join(source, "IMG_0001.JPG"), data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// The destination already holds the picture, under another name, with its manifest
+	dest := t.TempDir()
+	archived := filepath.Join(dest, "archive", "IMG_0001-copy.JPG")
+	if err := os.MkdirAll(filepath.Dir(archived), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	if err := os.WriteFile(archived, data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	manifest := HashBuffer(data, HashSHA256) + "  IMG_0001-copy.JPG\n"
+	if err := os.WriteFile(filepath.Join(dest, "archive", "SHA256SUMS"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to create manifest: %v", err)
+	}
+	// A manifest that cannot be parsed does not keep the others from being imported
+	if err := os.WriteFile(filepath.Join(dest, "SHA256SUMS"), []byte(strings.Repeat("3", 2*1024*1024)), 0644); err != nil {
+		t.Fatalf("Failed to create manifest: %v", err)
+	}
+
+	params := &models.Params{Source: source, Destination: dest, Compression: -1, CatalogFile: filepath.Join(t.TempDir(), "catalog.jsonl"), Incremental: true}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 0 || summary.Skips.ExistsIdentical != 1 {
+		t.Errorf("Copied %d files, skipped %d already imported, want 0 and 1", summary.Copied, summary.Skips.ExistsIdentical)
+	}
+}
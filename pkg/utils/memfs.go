@@ -0,0 +1,379 @@
+package utils
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an in-memory FileSystem, for runs and tests that must not touch
+// the disk. Write permissions are enforced on files, so write-protected
+// destinations behave as on disk. The zero value is not usable, call NewMemFS.
+type MemFS struct {
+	mu    sync.Mutex
+	nodes map[string]*memNode // Files and directories, keyed by cleaned path
+}
+
+// memNode is a file or directory of a MemFS
+type memNode struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemFS returns an empty in-memory file system, with its root directories
+// created on first use by MkdirAll or WriteFile
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: make(map[string]*memNode)}
+}
+
+// WriteFile creates name with its parent directories and the given content,
+// replacing an existing file
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := m.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodes[filepath.Clean(name)] = &memNode{data: append([]byte(nil), data...), mode: perm & fs.ModePerm, modTime: time.Now()}
+	return nil
+}
+
+// ReadFile returns a copy of the content of name
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.node("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	return append([]byte(nil), node.data...), nil
+}
+
+// Chtimes sets the modification time of name
+func (m *MemFS) Chtimes(name string, modTime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.node("chtimes", name)
+	if err != nil {
+		return err
+	}
+	node.modTime = modTime
+	return nil
+}
+
+func (m *MemFS) Open(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := filepath.Clean(name)
+	write := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	node, ok := m.nodes[key]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case ok && node.mode.IsDir() && write:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	case ok && write && node.mode.Perm()&0200 == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		if parent, err := m.node("open", filepath.Dir(key)); err != nil {
+			return nil, err
+		} else if !parent.mode.IsDir() {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		node = &memNode{mode: perm & fs.ModePerm, modTime: time.Now()}
+		m.nodes[key] = node
+	}
+
+	if flag&os.O_TRUNC != 0 && write {
+		node.data = nil
+	}
+	f := &memFile{fs: m, name: name, node: node, read: flag&os.O_WRONLY == 0, write: write, append: flag&os.O_APPEND != 0}
+	return f, nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.node("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return node.info(name), nil
+}
+
+// Lstat is Stat, MemFS has no symbolic links
+func (m *MemFS) Lstat(name string) (fs.FileInfo, error) {
+	return m.Stat(name)
+}
+
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, err := m.node("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !dir.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	key := filepath.Clean(name)
+	var entries []fs.DirEntry
+	for path, node := range m.nodes {
+		if path != key && filepath.Dir(path) == key {
+			entries = append(entries, fs.FileInfoToDirEntry(node.info(path)))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (m *MemFS) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := filepath.Clean(path)
+	var missing []string
+	for dir := key; ; dir = filepath.Dir(dir) {
+		node, ok := m.nodes[dir]
+		if ok {
+			if !node.mode.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: dir, Err: fs.ErrExist}
+			}
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break // Root of the file system
+		}
+	}
+	for _, dir := range missing {
+		m.nodes[dir] = &memNode{mode: fs.ModeDir | perm&fs.ModePerm, modTime: time.Now()}
+	}
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := filepath.Clean(name)
+	node, err := m.node("remove", name)
+	if err != nil {
+		return err
+	}
+	if node.mode.IsDir() {
+		prefix := key + string(filepath.Separator)
+		for path := range m.nodes {
+			if strings.HasPrefix(path, prefix) {
+				return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrExist}
+			}
+		}
+	}
+	delete(m.nodes, key)
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.node("chmod", name)
+	if err != nil {
+		return err
+	}
+	node.mode = node.mode&fs.ModeType | mode&fs.ModePerm
+	return nil
+}
+
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.linkable("rename", oldpath, newpath)
+	if err != nil {
+		return err
+	}
+	delete(m.nodes, filepath.Clean(oldpath))
+	m.nodes[filepath.Clean(newpath)] = node
+	return nil
+}
+
+// Link makes newname share the content of oldname, as a hard link does
+func (m *MemFS) Link(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.nodes[filepath.Clean(newname)]; ok {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+	node, err := m.linkable("link", oldname, newname)
+	if err != nil {
+		return err
+	}
+	m.nodes[filepath.Clean(newname)] = node
+	return nil
+}
+
+// linkable returns the file at oldpath, checking that it can be renamed or
+// linked to newpath, with m.mu held
+func (m *MemFS) linkable(op, oldpath, newpath string) (*memNode, error) {
+	node, err := m.node(op, oldpath)
+	if err != nil {
+		return nil, err
+	}
+	if node.mode.IsDir() {
+		return nil, &os.LinkError{Op: op, Old: oldpath, New: newpath, Err: fs.ErrInvalid}
+	}
+	if parent, ok := m.nodes[filepath.Dir(filepath.Clean(newpath))]; !ok || !parent.mode.IsDir() {
+		return nil, &os.LinkError{Op: op, Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if target, ok := m.nodes[filepath.Clean(newpath)]; ok && target.mode.IsDir() {
+		return nil, &os.LinkError{Op: op, Old: oldpath, New: newpath, Err: fs.ErrExist}
+	}
+	return node, nil
+}
+
+// node returns the node at name, with m.mu held
+func (m *MemFS) node(op, name string) (*memNode, error) {
+	node, ok := m.nodes[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return node, nil
+}
+
+// info returns the file info of a node at path
+func (n *memNode) info(path string) fs.FileInfo {
+	return memFileInfo{name: filepath.Base(path), size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+// memFileInfo is the fs.FileInfo of a MemFS node
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memFileInfo) Sys() any           { return nil }
+
+// memFile is an open file of a MemFS
+type memFile struct {
+	fs                  *MemFS
+	name                string
+	node                *memNode
+	offset              int64
+	read, write, append bool
+	closed              bool
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check("read", f.read); err != nil {
+		return 0, err
+	}
+	if f.node.mode.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check("write", f.write); err != nil {
+		return 0, err
+	}
+	if f.append {
+		f.offset = int64(len(f.node.data))
+	}
+	if end := f.offset + int64(len(p)); end > int64(len(f.node.data)) {
+		f.node.data = append(f.node.data, make([]byte, end-int64(len(f.node.data)))...)
+	}
+	n := copy(f.node.data[f.offset:], p)
+	f.offset += int64(n)
+	f.node.modTime = time.Now()
+	return n, nil
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check("seek", true); err != nil {
+		return 0, err
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check("stat", true); err != nil {
+		return nil, err
+	}
+	return f.node.info(f.name), nil
+}
+
+func (f *memFile) Sync() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	return f.check("sync", true)
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	if err := f.check("close", true); err != nil {
+		return err
+	}
+	f.closed = true
+	return nil
+}
+
+// check returns an error if the file is closed or not open for the operation, with f.fs.mu held
+func (f *memFile) check(op string, allowed bool) error {
+	if f.closed {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	}
+	if !allowed {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrPermission}
+	}
+	return nil
+}
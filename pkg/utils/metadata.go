@@ -0,0 +1,342 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Descriptive EXIF tags
+const (
+	TagMake             = 0x010F
+	TagModel            = 0x0110
+	TagExifIFDPointer   = 0x8769
+	TagExposureMode     = 0xA402
+	TagPixelXDimension  = 0xA002
+	TagPixelYDimension  = 0xA003
+	TagBodySerialNumber = 0xA431
+	TagUserComment      = 0x9286
+)
+
+// TIFF field types
+const (
+	tiffTypeByte  = 1
+	tiffTypeASCII = 2
+	tiffTypeShort = 3
+	tiffTypeLong  = 4
+)
+
+// ExposureAutoBracket is the exposure mode of frames shot in an automatic bracket
+const ExposureAutoBracket = 2
+
+// pngSignature starts every PNG file
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// maxTagValueSize bounds the size of a tag value we are willing to read
+const maxTagValueSize = 64 * 1024
+
+// Metadata holds descriptive EXIF fields of a media file
+type Metadata struct {
+	Make   string
+	Model  string
+	Serial string
+
+	ExposureMode uint32 // 0 auto, 1 manual, ExposureAutoBracket for bracketed frames
+	Width        uint32 // Pixel dimensions of the main image, 0 if unknown
+	Height       uint32
+	PanoramaXMP  bool // XMP photo sphere description, written by phone cameras
+	UserComment  string
+}
+
+// Camera returns a readable camera name, such as "SONY ILCE-7M3"
+func (m Metadata) Camera() string {
+	if m.Model == "" {
+		return m.Make
+	}
+	// Many vendors already repeat the make in the model name
+	if m.Make == "" || strings.HasPrefix(strings.ToLower(m.Model), strings.ToLower(m.Make)) {
+		return m.Model
+	}
+	return m.Make + " " + m.Model
+}
+
+// ifdEntry is a decoded entry of a TIFF image file directory
+type ifdEntry struct {
+	Tag   uint16
+	Type  uint16
+	Count uint32
+	Value []byte
+}
+
+// tiffReader reads image file directories relative to a TIFF header
+type tiffReader struct {
+	r     io.ReadSeeker
+	base  int64
+	order binary.ByteOrder
+	ifd0  uint32
+}
+
+// newTIFFReader reads the TIFF header at the current position of r
+func newTIFFReader(r io.ReadSeeker) (*tiffReader, error) {
+	base, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, TiffHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	switch string(header[:2]) {
+	case BigEndianMarker:
+		order = binary.BigEndian
+	case LittleEndianMarker:
+		order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("invalid TIFF byte order marker")
+	}
+
+	if order.Uint16(header[2:4]) != 42 {
+		return nil, fmt.Errorf("invalid TIFF marker")
+	}
+
+	return &tiffReader{r: r, base: base, order: order, ifd0: order.Uint32(header[4:8])}, nil
+}
+
+// readIFD returns the entries of the directory at offset, relative to the TIFF header
+func (t *tiffReader) readIFD(offset uint32) ([]ifdEntry, error) {
+	if _, err := t.r.Seek(t.base+int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	countBytes := make([]byte, 2)
+	if _, err := io.ReadFull(t.r, countBytes); err != nil {
+		return nil, err
+	}
+	count := int(t.order.Uint16(countBytes))
+
+	raw := make([]byte, 12*count)
+	if _, err := io.ReadFull(t.r, raw); err != nil {
+		return nil, err
+	}
+
+	entries := make([]ifdEntry, 0, count)
+	for i := 0; i < count; i++ {
+		b := raw[i*12 : (i+1)*12]
+		entry := ifdEntry{
+			Tag:   t.order.Uint16(b[0:2]),
+			Type:  t.order.Uint16(b[2:4]),
+			Count: t.order.Uint32(b[4:8]),
+		}
+
+		size := int64(tiffTypeSize(entry.Type)) * int64(entry.Count)
+		switch {
+		case size == 0 || size > maxTagValueSize:
+			// Unknown type or oversized value, keep the entry without its value
+		case size <= 4:
+			entry.Value = append([]byte(nil), b[8:8+size]...)
+		default:
+			value := make([]byte, size)
+			if _, err := t.r.Seek(t.base+int64(t.order.Uint32(b[8:12])), io.SeekStart); err == nil {
+				if _, err := io.ReadFull(t.r, value); err == nil {
+					entry.Value = value
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// uint returns the first numeric value of an entry
+func (t *tiffReader) uint(entry ifdEntry) (uint32, bool) {
+	switch {
+	case entry.Type == tiffTypeLong && len(entry.Value) >= 4:
+		return t.order.Uint32(entry.Value), true
+	case entry.Type == tiffTypeShort && len(entry.Value) >= 2:
+		return uint32(t.order.Uint16(entry.Value)), true
+	case entry.Type == tiffTypeByte && len(entry.Value) >= 1:
+		return uint32(entry.Value[0]), true
+	}
+	return 0, false
+}
+
+// tiffTypeSize returns the size in bytes of one value of a TIFF field type
+func tiffTypeSize(fieldType uint16) int {
+	switch fieldType {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return 0
+}
+
+// asciiValue decodes a NUL-terminated ASCII tag value
+func asciiValue(entry ifdEntry) string {
+	if entry.Type != tiffTypeASCII {
+		return ""
+	}
+	value := string(entry.Value)
+	if i := strings.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// userCommentValue decodes a UserComment tag value, whose first 8 bytes name
+// its character code
+func userCommentValue(entry ifdEntry) string {
+	if len(entry.Value) < 8 {
+		return ""
+	}
+	return strings.TrimSpace(strings.Trim(string(entry.Value[8:]), "\x00"))
+}
+
+// readTIFFEntries returns the entries of IFD0 and of the Exif sub-directory
+func readTIFFEntries(r io.ReadSeeker) (*tiffReader, []ifdEntry, error) {
+	t, err := newTIFFReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := t.readIFD(t.ifd0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.Tag != TagExifIFDPointer {
+			continue
+		}
+		if offset, ok := t.uint(entry); ok {
+			if exifEntries, err := t.readIFD(offset); err == nil {
+				entries = append(entries, exifEntries...)
+			}
+		}
+	}
+
+	return t, entries, nil
+}
+
+// seekToTIFFHeader positions reader at the TIFF header embedded in the file
+func seekToTIFFHeader(reader io.ReadSeeker, ext string) error {
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if ext == ".png" {
+		return seekToPNGExif(reader)
+	}
+	if !jpegContainer(ext) {
+		// TIFF-based RAW formats start with the TIFF header
+		return nil
+	}
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(reader, buf[:2]); err != nil {
+		return err
+	}
+	if buf[0] != 0xFF || buf[1] != 0xD8 {
+		return fmt.Errorf("not a valid JPEG file")
+	}
+
+	for {
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return err
+		}
+		if buf[0] != 0xFF || buf[1] == 0xDA {
+			return fmt.Errorf("no EXIF data found in JPEG structure")
+		}
+
+		length := int64(buf[2])<<8 | int64(buf[3])
+		if length < 2 {
+			return fmt.Errorf("invalid JPEG segment length")
+		}
+
+		if buf[1] == 0xE1 {
+			header := make([]byte, 6)
+			if _, err := io.ReadFull(reader, header); err != nil {
+				return err
+			}
+			if string(header) == ExifIdentifier {
+				return nil
+			}
+			length -= 6
+		}
+
+		if _, err := reader.Seek(length-2, io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+}
+
+// seekToPNGExif positions reader at the TIFF header of the eXIf chunk of a PNG file
+func seekToPNGExif(reader io.ReadSeeker) error {
+	signature := make([]byte, 8)
+	if _, err := io.ReadFull(reader, signature); err != nil {
+		return err
+	}
+	if string(signature) != pngSignature {
+		return fmt.Errorf("not a valid PNG file")
+	}
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return err
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		switch string(header[4:8]) {
+		case "eXIf":
+			return nil
+		case "IDAT", "IEND":
+			// Metadata written after the image data is not worth reading the whole file
+			return fmt.Errorf("no EXIF data found in PNG structure")
+		}
+		// Skip the chunk data and its CRC
+		if _, err := reader.Seek(length+4, io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+}
+
+// GetImageMetadata extracts descriptive EXIF fields such as the camera make and model
+func GetImageMetadata(reader io.ReadSeeker, fileExt string) (Metadata, error) {
+	if err := seekToTIFFHeader(reader, strings.ToLower(fileExt)); err != nil {
+		return Metadata{}, err
+	}
+
+	t, entries, err := readTIFFEntries(reader)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var meta Metadata
+	for _, entry := range entries {
+		switch entry.Tag {
+		case TagMake:
+			meta.Make = asciiValue(entry)
+		case TagModel:
+			meta.Model = asciiValue(entry)
+		case TagBodySerialNumber:
+			meta.Serial = asciiValue(entry)
+		case TagExposureMode:
+			meta.ExposureMode, _ = t.uint(entry)
+		case TagPixelXDimension:
+			meta.Width, _ = t.uint(entry)
+		case TagPixelYDimension:
+			meta.Height, _ = t.uint(entry)
+		case TagUserComment:
+			meta.UserComment = userCommentValue(entry)
+		}
+	}
+	return meta, nil
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"testing"
+)
+
+// testTag is a TIFF tag used to build test files
+type testTag struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte // Raw value, already encoded in the file byte order
+}
+
+// asciiTag builds a NUL-terminated ASCII tag
+func asciiTag(tag uint16, value string) testTag {
+	return testTag{tag: tag, typ: tiffTypeASCII, count: uint32(len(value) + 1), value: append([]byte(value), 0)}
+}
+
+// exposureModeTag builds a big-endian ExposureMode tag
+func exposureModeTag(mode uint16) testTag {
+	return testTag{tag: TagExposureMode, typ: tiffTypeShort, count: 1, value: binary.BigEndian.AppendUint16(nil, mode)}
+}
+
+// buildTIFF encodes a big-endian TIFF structure with an IFD0 and, when exif is
+// not empty, an Exif sub-directory referenced from IFD0
+func buildTIFF(ifd0, exif []testTag) []byte {
+	order := binary.BigEndian
+
+	// Layout: header, IFD0, Exif IFD, then all out-of-line values
+	ifdSize := func(tags []testTag) int { return 2 + 12*len(tags) + 4 }
+	if len(exif) > 0 {
+		ifd0 = append(ifd0, testTag{tag: TagExifIFDPointer, typ: tiffTypeLong, count: 1})
+	}
+	sort.Slice(ifd0, func(i, j int) bool { return ifd0[i].tag < ifd0[j].tag })
+
+	exifOffset := TiffHeaderLength + ifdSize(ifd0)
+	dataOffset := exifOffset
+	if len(exif) > 0 {
+		dataOffset += ifdSize(exif)
+	}
+
+	var data bytes.Buffer
+	encodeIFD := func(tags []testTag) []byte {
+		var ifd bytes.Buffer
+		binary.Write(&ifd, order, uint16(len(tags)))
+		for _, tag := range tags {
+			binary.Write(&ifd, order, tag.tag)
+			binary.Write(&ifd, order, tag.typ)
+			binary.Write(&ifd, order, tag.count)
+
+			value := tag.value
+			if tag.tag == TagExifIFDPointer {
+				value = order.AppendUint32(nil, uint32(exifOffset))
+			}
+			if len(value) <= 4 {
+				ifd.Write(append(value, make([]byte, 4-len(value))...))
+			} else {
+				binary.Write(&ifd, order, uint32(dataOffset+data.Len()))
+				data.Write(value)
+			}
+		}
+		binary.Write(&ifd, order, uint32(0)) // No next IFD
+		return ifd.Bytes()
+	}
+
+	var out bytes.Buffer
+	out.WriteString(BigEndianMarker)
+	binary.Write(&out, order, uint16(42))
+	binary.Write(&out, order, uint32(TiffHeaderLength))
+	out.Write(encodeIFD(ifd0))
+	if len(exif) > 0 {
+		out.Write(encodeIFD(exif))
+	}
+	out.Write(data.Bytes())
+	return out.Bytes()
+}
+
+// wrapJPEG embeds a TIFF structure in the EXIF APP1 segment of a minimal JPEG
+func wrapJPEG(tiff []byte) []byte {
+	segment := append([]byte(ExifIdentifier), tiff...)
+	length := len(segment) + 2
+
+	data := []byte{0xFF, 0xD8}
+	// An unrelated APP0 segment before the EXIF segment, as written by most encoders
+	data = append(data, 0xFF, 0xE0, 0x00, 0x04, 'J', 'F')
+	data = append(data, 0xFF, 0xE1, byte(length>>8), byte(length&0xFF))
+	data = append(data, segment...)
+	return append(data, 0xFF, 0xD9)
+}
+
+func TestGetImageMetadata(t *testing.T) {
+	tiff := buildTIFF(
+		[]testTag{asciiTag(TagMake, "SONY"), asciiTag(TagModel, "ILCE-7M3"), asciiTag(TagDateTime, "2024:06:11 15:30:10")},
+		[]testTag{asciiTag(TagBodySerialNumber, "3312345"), exposureModeTag(ExposureAutoBracket)},
+	)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		ext     string
+		want    Metadata
+		wantErr bool
+	}{
+		{
+			name: "JPEG",
+			data: wrapJPEG(tiff),
+			ext:  ".JPG",
+			want: Metadata{Make: "SONY", Model: "ILCE-7M3", Serial: "3312345", ExposureMode: ExposureAutoBracket},
+		},
+		{
+			name: "TIFF-based RAW",
+			data: tiff,
+			ext:  ".arw",
+			want: Metadata{Make: "SONY", Model: "ILCE-7M3", Serial: "3312345", ExposureMode: ExposureAutoBracket},
+		},
+		{
+			name:    "JPEG without EXIF",
+			data:    []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02},
+			ext:     ".jpg",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid data",
+			data:    []byte("not an image"),
+			ext:     ".nef",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetImageMetadata(bytes.NewReader(tt.data), tt.ext)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetImageMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetImageMetadata() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataCamera(t *testing.T) {
+	tests := []struct {
+		meta Metadata
+		want string
+	}{
+		{meta: Metadata{Make: "SONY", Model: "ILCE-7M3"}, want: "SONY ILCE-7M3"},
+		{meta: Metadata{Make: "Canon", Model: "Canon EOS R5"}, want: "Canon EOS R5"},
+		{meta: Metadata{Make: "Apple"}, want: "Apple"},
+		{meta: Metadata{Model: "X-T4"}, want: "X-T4"},
+		{meta: Metadata{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.meta.Camera(); got != tt.want {
+			t.Errorf("%+v.Camera() = %q, want %q", tt.meta, got, tt.want)
+		}
+	}
+}
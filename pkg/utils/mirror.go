@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// MirrorResult is the outcome of replicating an organized file to a mirror destination
+type MirrorResult struct {
+	Destination string `json:"destination"`
+	Status      string `json:"status"` // copied, skipped or failed
+	Reason      string `json:"reason,omitempty"`
+}
+
+// mirrorDestination returns where a file organized at destPath is replicated
+// in mirror, at the same place relative to the primary destination, or to
+// its tier folder
+func mirrorDestination(p *models.Params, mirror, destPath string) string {
+	rel, err := filepath.Rel(rootOf(p, destPath), destPath)
+	if err != nil {
+		rel = filepath.Base(destPath)
+	}
+	return filepath.Join(mirror, rel)
+}
+
+// writeMirrors replicates the data written to destPath to every mirror. Files
+// already in a mirror are left alone, but checked against the data when the
+// source is about to be deleted. The returned error joins the failures.
+func writeMirrors(fsys FileSystem, destPath string, data payload, p *models.Params, power *powerMonitor, summary *ProcessingSummary) ([]MirrorResult, error) {
+	var results []MirrorResult
+	var errs []error
+
+	for _, mirror := range p.Mirrors {
+		result := MirrorResult{Destination: mirrorDestination(p, mirror, destPath), Status: ReportCopied}
+
+		exists, err := fileExists(fsys, result.Destination)
+		switch {
+		case err != nil:
+			err = fmt.Errorf("failed to check mirror file: %w", err)
+		case exists:
+			result.Status, result.Reason = ReportSkipped, "mirror file already exists"
+			if p.DeleteSource {
+				err = verifyCopy(fsys, result.Destination, data, p, power)
+			}
+		default:
+			err = writeMirrorFile(fsys, result.Destination, data, p, power, summary)
+		}
+
+		if err != nil {
+			result.Status, result.Reason = ReportFailed, err.Error()
+			summary.MirrorFailed++
+			output.Status("ERROR", fmt.Sprintf("Failed to mirror %s to %s: %v", destPath, mirror, err))
+			errs = append(errs, fmt.Errorf("mirror %s: %w", mirror, err))
+		} else if result.Status == ReportCopied {
+			summary.Mirrored++
+			output.Status("COPIED", fmt.Sprintf("Mirrored file to: %s", result.Destination))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// mirrorExisting replicates a file already at the destination to the mirrors
+// missing it, so a mirror added later catches up with the primary destination
+func mirrorExisting(fsys FileSystem, destPath string, p *models.Params, power *powerMonitor, summary *ProcessingSummary) []MirrorResult {
+	missing := false
+	for _, mirror := range p.Mirrors {
+		if exists, err := fileExists(fsys, mirrorDestination(p, mirror, destPath)); err != nil || !exists {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	info, err := fsys.Stat(destPath)
+	if err != nil {
+		summary.MirrorFailed++
+		output.Status("ERROR", fmt.Sprintf("Failed to read %s for mirroring: %v", destPath, err))
+		return nil
+	}
+	results, _ := writeMirrors(fsys, destPath, filePayload(fsys, destPath, info.Size()), p, power, summary)
+	return results
+}
+
+// writeMirrorFile writes data to a new mirror file, synced and read back
+// before the source is deleted, like the primary destination
+func writeMirrorFile(fsys FileSystem, path string, data payload, p *models.Params, power *powerMonitor, summary *ProcessingSummary) error {
+	if err := fsys.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	writeStart := time.Now()
+	n, err := writeMediaFile(fsys, path, func(file File) (int64, error) {
+		n, err := data.writeTo(file)
+		if err == nil && n != data.size {
+			err = fmt.Errorf("wrote %d of %d bytes", n, data.size)
+		}
+		return n, err
+	})
+	summary.Stats.addWrite(n, time.Since(writeStart))
+	if err != nil {
+		return fmt.Errorf("failed to write mirror file: %w", err)
+	}
+
+	if p.DeleteSource {
+		if err := verifyCopy(fsys, path, data, p, power); err != nil {
+			return err
+		}
+	}
+	protectFile(fsys, path, p, nil)
+	return nil
+}
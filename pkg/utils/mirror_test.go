@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestProcessMediaFilesMirrors(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	mirror := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	params := &models.Params{Source: sourceDir, Destination: destDir, Mirrors: []string{mirror}, Compression: -1, ReportFile: reportFile}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Mirrored != 1 {
+		t.Errorf("Expected 1 copied and mirrored file, got %+v", summary)
+	}
+
+	rel := filepath.Join("2025", "01-11", "photo.jpg")
+	if _, err := os.Stat(filepath.Join(mirror, rel)); err != nil {
+		t.Errorf("Expected mirrored file: %v", err)
+	}
+	files := readTestReport(t, reportFile).Files
+	if len(files) != 1 || len(files[0].Mirrors) != 1 || files[0].Mirrors[0].Status != ReportCopied {
+		t.Errorf("report files = %+v", files)
+	}
+
+	// A mirror added later receives the files already at the destination
+	late := t.TempDir()
+	params.Mirrors = []string{mirror, late}
+	summary, err = ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() second run error = %v", err)
+	}
+	if summary.Skipped != 1 || summary.Mirrored != 1 {
+		t.Errorf("Expected the skipped file to be mirrored once, got %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(late, rel)); err != nil {
+		t.Errorf("Expected file in the late mirror: %v", err)
+	}
+}
+
+func TestProcessMediaFilesMirrorFailureKeepsSource(t *testing.T) {
+	sourceDir := t.TempDir()
+	mirror := t.TempDir()
+	sourcePath := filepath.Join(sourceDir, "photo.jpg")
+	if err := os.WriteFile(sourcePath, createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	// A file where the year folder should be makes the mirror write fail
+	if err := os.WriteFile(filepath.Join(mirror, "2025"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create blocking file: %v", err)
+	}
+
+	params := &models.Params{Source: sourceDir, Destination: t.TempDir(), Mirrors: []string{mirror}, Compression: -1, DeleteSource: true}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.MirrorFailed != 1 || summary.Deleted != 0 || summary.Failed != 1 {
+		t.Errorf("Expected a failed mirror and no deletion, got %+v", summary)
+	}
+	if _, err := os.Stat(sourcePath); err != nil {
+		t.Errorf("Expected source file to be kept: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// States of the import published to MQTT
+const (
+	StatusRunning = "running"
+	StatusIdle    = "idle"
+	StatusErrored = "errored"
+)
+
+// DefaultMQTTTopic is the topic of the import status when the settings give none
+const DefaultMQTTTopic = "organize-media/status"
+
+// Time allowed to connect to the broker and publish a status
+const mqttTimeout = 10 * time.Second
+
+// MQTTSettings are the settings of the broker receiving the import status
+type MQTTSettings struct {
+	Broker   string `json:"broker"` // host:port, such as homeassistant.local:1883
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Topic    string `json:"topic,omitempty"`
+}
+
+// RunStatus is the retained message describing the current or last import,
+// for home-automation dashboards
+type RunStatus struct {
+	State        string     `json:"state"`
+	Source       string     `json:"source"`
+	Destination  string     `json:"destination"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	Processed    int        `json:"processed"`
+	Copied       int        `json:"copied"`
+	Compressed   int        `json:"compressed"`
+	Skipped      int        `json:"skipped"`
+	Failed       int        `json:"failed"`
+	BytesWritten int64      `json:"bytes_written"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// For testing purposes
+var dialMQTT = func(address string) (net.Conn, error) {
+	return net.DialTimeout("tcp", address, mqttTimeout)
+}
+
+// LoadMQTTSettings reads MQTT settings from a JSON file, such as
+// {"broker": "homeassistant.local:1883", "username": "ingest", "password": "...",
+// "topic": "organize-media/status"}
+func LoadMQTTSettings(path string) (MQTTSettings, error) {
+	var settings MQTTSettings
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return settings, fmt.Errorf("failed to read MQTT settings: %w", err)
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("failed to parse MQTT settings %s: %w", path, err)
+	}
+
+	if settings.Broker == "" {
+		return settings, fmt.Errorf("invalid MQTT settings in %s: broker is required", path)
+	}
+	if _, _, err := net.SplitHostPort(settings.Broker); err != nil {
+		return settings, fmt.Errorf("invalid MQTT broker in %s: %w", path, err)
+	}
+	if settings.Topic == "" {
+		settings.Topic = DefaultMQTTTopic
+	}
+	if settings.ClientID == "" {
+		settings.ClientID = "organize-media"
+	}
+	return settings, nil
+}
+
+// PublishStatus publishes the status of an import as a retained JSON message,
+// so dashboards show the last state as soon as they subscribe. It speaks the
+// MQTT 3.1.1 protocol with QoS 0 and disconnects once the status is sent.
+func PublishStatus(settings MQTTSettings, status RunStatus) error {
+	payload, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialMQTT(settings.Broker)
+	if err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker %s: %w", settings.Broker, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(mqttTimeout))
+
+	if _, err := conn.Write(mqttConnect(settings)); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker %s: %w", settings.Broker, err)
+	}
+	var ack [4]byte
+	if _, err := io.ReadFull(conn, ack[:]); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker %s: %w", settings.Broker, err)
+	}
+	if ack[0] != 0x20 || ack[1] != 0x02 {
+		return fmt.Errorf("unexpected reply from MQTT broker %s", settings.Broker)
+	}
+	if ack[3] != 0 {
+		return fmt.Errorf("MQTT broker %s refused the connection (code %d)", settings.Broker, ack[3])
+	}
+
+	if _, err := conn.Write(mqttPublish(settings.Topic, payload)); err != nil {
+		return fmt.Errorf("failed to publish to MQTT broker %s: %w", settings.Broker, err)
+	}
+	conn.Write([]byte{0xE0, 0x00}) // DISCONNECT
+	return nil
+}
+
+// mqttConnect returns a CONNECT packet with a clean session
+func mqttConnect(settings MQTTSettings) []byte {
+	flags := byte(0x02)
+	body := mqttString(nil, "MQTT")
+	body = append(body, 4) // Protocol level of MQTT 3.1.1
+	flagsAt := len(body)
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, 60) // Keep alive, in seconds
+	body = mqttString(body, settings.ClientID)
+	if settings.Username != "" {
+		flags |= 0x80
+		body = mqttString(body, settings.Username)
+		if settings.Password != "" {
+			flags |= 0x40
+			body = mqttString(body, settings.Password)
+		}
+	}
+	body[flagsAt] = flags
+	return mqttPacket(0x10, body)
+}
+
+// mqttPublish returns a retained PUBLISH packet with QoS 0
+func mqttPublish(topic string, payload []byte) []byte {
+	return mqttPacket(0x31, append(mqttString(nil, topic), payload...))
+}
+
+// mqttPacket prefixes a packet body with its fixed header
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// mqttString appends a length-prefixed UTF-8 string
+func mqttString(data []byte, s string) []byte {
+	data = binary.BigEndian.AppendUint16(data, uint16(len(s)))
+	return append(data, s...)
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readMQTTPacket reads one packet and returns its fixed header and body
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7F) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return header, body, err
+}
+
+// readMQTTString reads a length-prefixed string and returns the rest of the data
+func readMQTTString(data []byte) (string, []byte) {
+	n := int(binary.BigEndian.Uint16(data))
+	return string(data[2 : 2+n]), data[2+n:]
+}
+
+func TestLoadMQTTSettings(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    MQTTSettings
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			content: `{"broker": "homeassistant.local:1883"}`,
+			want:    MQTTSettings{Broker: "homeassistant.local:1883", ClientID: "organize-media", Topic: DefaultMQTTTopic},
+		},
+		{
+			name:    "settings",
+			content: `{"broker": "10.0.0.2:1883", "username": "ingest", "password": "secret", "client_id": "nas", "topic": "home/photos"}`,
+			want:    MQTTSettings{Broker: "10.0.0.2:1883", Username: "ingest", Password: "secret", ClientID: "nas", Topic: "home/photos"},
+		},
+		{name: "missing broker", content: `{"topic": "home/photos"}`, wantErr: true},
+		{name: "missing port", content: `{"broker": "homeassistant.local"}`, wantErr: true},
+		{name: "invalid file", content: `["homeassistant.local:1883"]`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mqtt.json")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write settings: %v", err)
+			}
+
+			got, err := LoadMQTTSettings(path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("LoadMQTTSettings() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && got != tc.want {
+				t.Errorf("LoadMQTTSettings() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPublishStatus(t *testing.T) {
+	originalDial := dialMQTT
+	defer func() { dialMQTT = originalDial }()
+
+	testCases := []struct {
+		name       string
+		returnCode byte
+		wantErr    bool
+	}{
+		{"accepted", 0, false},
+		{"bad credentials", 4, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, broker := net.Pipe()
+			dialMQTT = func(string) (net.Conn, error) { return client, nil }
+
+			type packet struct {
+				header byte
+				body   []byte
+			}
+			received := make(chan []packet, 1)
+			go func() {
+				defer broker.Close()
+				var packets []packet
+				r := bufio.NewReader(broker)
+				for {
+					header, body, err := readMQTTPacket(r)
+					if err != nil {
+						break
+					}
+					packets = append(packets, packet{header, body})
+					if header == 0x10 {
+						broker.Write([]byte{0x20, 0x02, 0x00, tc.returnCode})
+					}
+				}
+				received <- packets
+			}()
+
+			settings := MQTTSettings{Broker: "broker:1883", Username: "ingest", Password: "secret", ClientID: "nas", Topic: "home/photos"}
+			status := RunStatus{State: StatusIdle, Source: "/media/card", Copied: 12, BytesWritten: 4096}
+			err := PublishStatus(settings, status)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("PublishStatus() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			packets := <-received
+
+			if len(packets) == 0 || packets[0].header != 0x10 {
+				t.Fatalf("Expected a CONNECT packet first, got %v", packets)
+			}
+			protocol, rest := readMQTTString(packets[0].body)
+			if protocol != "MQTT" || rest[0] != 4 || rest[1] != 0xC2 {
+				t.Errorf("Unexpected CONNECT header %q %v", protocol, rest[:2])
+			}
+			var fields []string
+			for rest = rest[4:]; len(rest) > 0; {
+				var field string
+				field, rest = readMQTTString(rest)
+				fields = append(fields, field)
+			}
+			if want := []string{"nas", "ingest", "secret"}; !reflect.DeepEqual(fields, want) {
+				t.Errorf("CONNECT payload = %v, want %v", fields, want)
+			}
+
+			if tc.wantErr {
+				if len(packets) != 1 {
+					t.Errorf("Expected nothing published after a refused connection, got %d packets", len(packets))
+				}
+				return
+			}
+			if len(packets) != 3 || packets[1].header != 0x31 || packets[2].header != 0xE0 {
+				t.Fatalf("Expected retained PUBLISH and DISCONNECT packets, got %v", packets)
+			}
+			topic, payload := readMQTTString(packets[1].body)
+			if topic != settings.Topic {
+				t.Errorf("Published to %q, want %q", topic, settings.Topic)
+			}
+			var got RunStatus
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Fatalf("Failed to parse status: %v", err)
+			}
+			if !reflect.DeepEqual(got, status) {
+				t.Errorf("Published %+v, want %+v", got, status)
+			}
+		})
+	}
+}
+
+func TestMQTTPacketLength(t *testing.T) {
+	testCases := []struct {
+		length int
+		want   []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7F}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xFF, 0x7F}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+	}
+
+	for _, tc := range testCases {
+		packet := mqttPacket(0x30, make([]byte, tc.length))
+		if got := packet[1 : 1+len(tc.want)]; !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Length %d encoded as %v, want %v", tc.length, got, tc.want)
+		}
+	}
+}
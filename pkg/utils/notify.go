@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SMTPSettings are the settings of the server sending run summaries by email
+type SMTPSettings struct {
+	Server   string   `json:"server"` // host:port, such as smtp.example.com:587
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// For testing purposes
+var sendMail = smtp.SendMail
+
+// LoadSMTPSettings reads SMTP settings from a JSON file, such as
+// {"server": "smtp.example.com:587", "username": "nas", "password": "...",
+// "from": "nas@example.com", "to": ["me@example.com"]}
+func LoadSMTPSettings(path string) (SMTPSettings, error) {
+	var settings SMTPSettings
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return settings, fmt.Errorf("failed to read SMTP settings: %w", err)
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("failed to parse SMTP settings %s: %w", path, err)
+	}
+
+	switch {
+	case settings.Server == "":
+		return settings, fmt.Errorf("invalid SMTP settings in %s: server is required", path)
+	case settings.From == "" || len(settings.To) == 0:
+		return settings, fmt.Errorf("invalid SMTP settings in %s: from and to addresses are required", path)
+	}
+	if _, _, err := net.SplitHostPort(settings.Server); err != nil {
+		return settings, fmt.Errorf("invalid SMTP server in %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// SendEmail sends a plain text email with attachments, keyed by file name.
+// Servers supporting STARTTLS are switched to TLS before authenticating.
+func SendEmail(settings SMTPSettings, subject, body string, attachments map[string][]byte) error {
+	message, err := formatEmail(settings.From, settings.To, subject, body, attachments)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if settings.Username != "" {
+		host, _, _ := net.SplitHostPort(settings.Server)
+		auth = smtp.PlainAuth("", settings.Username, settings.Password, host)
+	}
+	if err := sendMail(settings.Server, auth, settings.From, settings.To, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// formatEmail returns a MIME message with a text body and attachments
+func formatEmail(from string, to []string, subject, body string, attachments map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", w.Boundary())
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(part, []byte(body)); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(attachments))
+	for name := range attachments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		contentType := mime.TypeByExtension(filepath.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, attachments[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64-encoded in lines of 76 characters, as MIME requires
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:n]); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
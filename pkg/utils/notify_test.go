@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadSMTPSettings(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    SMTPSettings
+		wantErr bool
+	}{
+		{
+			name:    "settings",
+			content: `{"server": "smtp.example.com:587", "username": "nas", "password": "secret", "from": "nas@example.com", "to": ["me@example.com"]}`,
+			want:    SMTPSettings{Server: "smtp.example.com:587", Username: "nas", Password: "secret", From: "nas@example.com", To: []string{"me@example.com"}},
+		},
+		{name: "missing server", content: `{"from": "nas@example.com", "to": ["me@example.com"]}`, wantErr: true},
+		{name: "missing port", content: `{"server": "smtp.example.com", "from": "nas@example.com", "to": ["me@example.com"]}`, wantErr: true},
+		{name: "missing recipients", content: `{"server": "smtp.example.com:587", "from": "nas@example.com"}`, wantErr: true},
+		{name: "invalid file", content: `["smtp.example.com"]`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "smtp.json")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to write settings: %v", err)
+			}
+
+			got, err := LoadSMTPSettings(path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("LoadSMTPSettings() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("LoadSMTPSettings() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSendEmail(t *testing.T) {
+	originalSend := sendMail
+	defer func() { sendMail = originalSend }()
+
+	var server, from string
+	var to []string
+	var auth smtp.Auth
+	var message []byte
+	sendMail = func(addr string, a smtp.Auth, f string, recipients []string, msg []byte) error {
+		server, auth, from, to, message = addr, a, f, recipients, msg
+		return nil
+	}
+
+	settings := SMTPSettings{Server: "smtp.example.com:587", Username: "nas", Password: "secret", From: "nas@example.com", To: []string{"me@example.com", "family@example.com"}}
+	report := []byte(`{"files": []}`)
+	if err := SendEmail(settings, "Import done", "3 files copied", map[string][]byte{"report.json": report}); err != nil {
+		t.Fatalf("SendEmail() error = %v", err)
+	}
+
+	if server != settings.Server || from != settings.From || !reflect.DeepEqual(to, settings.To) {
+		t.Errorf("Sent through %s from %s to %v", server, from, to)
+	}
+	if auth == nil {
+		t.Error("Expected authentication with a username")
+	}
+	text := string(message)
+	for _, want := range []string{
+		"Subject: Import done",
+		"To: me@example.com, family@example.com\r\n",
+		base64.StdEncoding.EncodeToString([]byte("3 files copied")),
+		`filename=report.json`,
+		base64.StdEncoding.EncodeToString(report),
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	sendMail = func(string, smtp.Auth, string, []string, []byte) error { return errors.New("connection refused") }
+	if err := SendEmail(settings, "Import done", "", nil); err == nil {
+		t.Error("Expected an error when the server refuses the email")
+	}
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// organizedShare is the share of media files that must be in YYYY/MM-DD
+// folders for a source to be considered already organized
+const organizedShare = 0.9
+
+// IsOrganizedSource reports whether the media files of a source are already
+// in the YYYY/MM-DD layout this tool creates, such as an archive migrated
+// from another machine. Such a source can be imported with -trust-folders.
+func IsOrganizedSource(source string) (bool, error) {
+	var total, dated int
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+		if info.IsDir() || !isAllowedExtension(filepath.Ext(path)) {
+			return nil
+		}
+		total++
+		if _, ok := folderDate(source, path); ok {
+			dated++
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	return total > 0 && float64(dated) >= organizedShare*float64(total), nil
+}
+
+// folderDate returns the date of the YYYY/MM-DD folder holding path below
+// source, at the hour of an hour subfolder (14h) if there is one
+func folderDate(source, path string) (time.Time, bool) {
+	rel, err := filepath.Rel(source, filepath.Dir(path))
+	if err != nil {
+		return time.Time{}, false
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	if len(parts) < 2 || len(parts[0]) != 4 || len(parts[1]) != 5 {
+		return time.Time{}, false
+	}
+
+	date, err := time.Parse("2006/01-02", parts[0]+"/"+parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// Sequence, kind and part folders may sit between the day and hour folders
+	for _, part := range parts[2:] {
+		if hour, ok := parseHourDir(part); ok {
+			return date.Add(time.Duration(hour) * time.Hour), true
+		}
+	}
+	return date, true
+}
+
+// parseHourDir returns the hour of an hour subfolder name, such as 14h
+func parseHourDir(name string) (int, bool) {
+	if len(name) != 3 || name[2] != 'h' {
+		return 0, false
+	}
+	hour, err := strconv.Atoi(name[:2])
+	return hour, err == nil && hour >= 0 && hour < 24
+}
+
+// trustedFolderDate returns the date of the source folder of a file when the
+// run trusts folder dates
+func trustedFolderDate(p *models.Params, path string) (time.Time, bool) {
+	if !p.TrustFolders {
+		return time.Time{}, false
+	}
+	return folderDate(p.Source, path)
+}
+
+// sourceDate returns the date a file is organized by: the date assigned
+// manually, the date of its source folder when folder dates are trusted, or
+// its capture date, corrected by the clock offset of the camera
+func sourceDate(fsys FileSystem, p *models.Params, path string, info os.FileInfo, cache *MetadataCache, offsets ClockOffsets) (time.Time, error) {
+	if date, ok := p.DateOverrides[path]; ok {
+		return date, nil
+	}
+	if date, ok := trustedFolderDate(p, path); ok {
+		return date, nil
+	}
+	date, err := readMediaDate(fsys, path, info, cache, p.NoGPS)
+	if err != nil || isProxyFile(path) {
+		return date, err
+	}
+	return offsets.correctFile(date, path), nil
+}
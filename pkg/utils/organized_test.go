@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestFolderDate(t *testing.T) {
+	source := filepath.Join("archive")
+	tests := []struct {
+		path string
+		want time.Time
+		ok   bool
+	}{
+		{filepath.Join(source, "2020", "03-04", "a.jpg"), time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC), true},
+		{filepath.Join(source, "2020", "03-04", "14h", "a.jpg"), time.Date(2020, 3, 4, 14, 0, 0, 0, time.UTC), true},
+		{filepath.Join(source, "2020", "03-04", "part-2", "09h", "a.jpg"), time.Date(2020, 3, 4, 9, 0, 0, 0, time.UTC), true},
+		{filepath.Join(source, "2020", "03-04", "timelapse", "a.jpg"), time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC), true},
+		{filepath.Join(source, "2020", "13-04", "a.jpg"), time.Time{}, false},
+		{filepath.Join(source, "2020", "a.jpg"), time.Time{}, false},
+		{filepath.Join(source, "DCIM", "100CANON", "a.jpg"), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := folderDate(source, tt.path)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("folderDate(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsOrganizedSource(t *testing.T) {
+	write := func(root, rel string, data []byte) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	organized := t.TempDir()
+	write(organized, filepath.Join("2020", "03-04", "a.jpg"), createFakeExifData())
+	write(organized, filepath.Join("2021", "12-25", "b.jpg"), createFakeExifData())
+	write(organized, "notes.txt", []byte("not a picture"))
+
+	card := t.TempDir()
+	write(card, filepath.Join("DCIM", "100CANON", "a.jpg"), createFakeExifData())
+	write(card, filepath.Join("2020", "03-04", "b.jpg"), createFakeExifData())
+
+	for _, tt := range []struct {
+		source string
+		want   bool
+	}{{organized, true}, {card, false}, {t.TempDir(), false}} {
+		got, err := IsOrganizedSource(tt.source)
+		if err != nil || got != tt.want {
+			t.Errorf("IsOrganizedSource(%s) = %v, %v, want %v", tt.source, got, err, tt.want)
+		}
+	}
+}
+
+func TestProcessMediaFilesTrustFolders(t *testing.T) {
+	source := t.TempDir()
+	dir := filepath.Join(source, "2020", "03-04")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	// Taken on 2025:01:11 according to its metadata
+	if err := os.WriteFile(filepath.Join(dir, "photo.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	// No metadata at all
+	if err := os.WriteFile(filepath.Join(dir, "scan.jpg"), []byte{0xFF, 0xD8, 0xFF, 0xD9}, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		trust  bool
+		copied int
+		want   string
+	}{
+		{name: "metadata dates", copied: 1, want: filepath.Join("2025", "01-11", "photo.jpg")},
+		{name: "folder dates", trust: true, copied: 2, want: filepath.Join("2020", "03-04", "scan.jpg")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := t.TempDir()
+			params := &models.Params{Source: source, Destination: dest, Compression: -1, TrustFolders: tc.trust}
+
+			plan, err := PlanMediaFiles(params)
+			if err != nil {
+				t.Fatalf("PlanMediaFiles() error = %v", err)
+			}
+			if conflicts := len(PlanConflicts(plan)); conflicts != 2-tc.copied {
+				t.Errorf("PlanConflicts() = %d conflicts, want %d", conflicts, 2-tc.copied)
+			}
+
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if summary.Copied != tc.copied {
+				t.Errorf("Expected %d copied files, got %+v", tc.copied, summary)
+			}
+			if _, err := os.Stat(filepath.Join(dest, tc.want)); err != nil {
+				t.Errorf("Expected %s: %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// NormalizePath returns the clean absolute form of a user-supplied path.
+// On Windows it accepts forward slashes, drive-relative paths (D:photos)
+// and UNC shares (\\nas\photos or //nas/photos).
+func NormalizePath(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+
+	if runtime.GOOS == "windows" {
+		path = cleanWindowsPath(path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %s: %w", path, err)
+	}
+	return abs, nil
+}
+
+// cleanWindowsPath normalizes separators and dot segments of a Windows path
+// without touching the filesystem. The volume (drive letter, UNC share or
+// extended-length prefix) is preserved and ".." never climbs above it.
+func cleanWindowsPath(path string) string {
+	path = strings.ReplaceAll(path, "/", `\`)
+
+	volume, rest := splitWindowsVolume(path)
+	rooted := strings.HasPrefix(rest, `\`) || strings.HasPrefix(volume, `\\`)
+
+	var parts []string
+	for _, part := range strings.Split(rest, `\`) {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(parts) > 0 && parts[len(parts)-1] != ".." {
+				parts = parts[:len(parts)-1]
+			} else if !rooted {
+				// Relative paths may legitimately start above the working directory
+				parts = append(parts, part)
+			}
+		default:
+			parts = append(parts, part)
+		}
+	}
+
+	cleaned := strings.Join(parts, `\`)
+	switch {
+	case rooted && strings.HasPrefix(volume, `\\`) && cleaned == "":
+		return volume
+	case rooted:
+		return volume + `\` + cleaned
+	case volume+cleaned == "":
+		return "."
+	default:
+		return volume + cleaned
+	}
+}
+
+// splitWindowsVolume splits a backslash-separated path into its volume
+// (C:, \\server\share, \\?\C:, \\?\UNC\server\share) and the remainder
+func splitWindowsVolume(path string) (string, string) {
+	// Extended-length paths
+	if strings.HasPrefix(path, `\\?\`) {
+		inner := path[4:]
+		if len(inner) >= 4 && strings.EqualFold(inner[:4], `UNC\`) {
+			volume, rest := splitUNCShare(inner[4:])
+			return `\\?\UNC\` + volume, rest
+		}
+		if len(inner) >= 2 && inner[1] == ':' {
+			return `\\?\` + inner[:2], inner[2:]
+		}
+		return `\\?\`, inner
+	}
+
+	// UNC shares
+	if strings.HasPrefix(path, `\\`) {
+		volume, rest := splitUNCShare(strings.TrimLeft(path, `\`))
+		return `\\` + volume, rest
+	}
+
+	// Drive letters, including drive-relative paths such as D:photos
+	if len(path) >= 2 && path[1] == ':' && isDriveLetter(path[0]) {
+		return strings.ToUpper(path[:1]) + ":", path[2:]
+	}
+
+	return "", path
+}
+
+// splitUNCShare splits "server\share\rest" into "server\share" and "\rest"
+func splitUNCShare(path string) (string, string) {
+	parts := strings.SplitN(path, `\`, 3)
+	switch len(parts) {
+	case 1:
+		return parts[0], ""
+	case 2:
+		return parts[0] + `\` + parts[1], ""
+	default:
+		return parts[0] + `\` + parts[1], `\` + parts[2]
+	}
+}
+
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanWindowsPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "drive path", path: `C:\Photos\2024`, want: `C:\Photos\2024`},
+		{name: "forward slashes", path: `C:/Photos/2024`, want: `C:\Photos\2024`},
+		{name: "mixed separators", path: `C:\Photos/2024\\06-11`, want: `C:\Photos\2024\06-11`},
+		{name: "lowercase drive", path: `d:\photos`, want: `D:\photos`},
+		{name: "drive root", path: `C:\`, want: `C:\`},
+		{name: "drive-relative path", path: `D:photos\import`, want: `D:photos\import`},
+		{name: "dot segments", path: `C:\Photos\.\import\..\2024`, want: `C:\Photos\2024`},
+		{name: "parent above drive root", path: `C:\..\Photos`, want: `C:\Photos`},
+		{name: "UNC share", path: `\\nas\photos`, want: `\\nas\photos`},
+		{name: "UNC share with forward slashes", path: `//nas/photos/2024`, want: `\\nas\photos\2024`},
+		{name: "UNC share with trailing separator", path: `\\nas\photos\`, want: `\\nas\photos`},
+		{name: "UNC parent above share", path: `\\nas\photos\..\..\other`, want: `\\nas\photos\other`},
+		{name: "extended-length drive path", path: `\\?\C:\Photos\.\2024`, want: `\\?\C:\Photos\2024`},
+		{name: "extended-length UNC path", path: `\\?\UNC\nas\photos\2024`, want: `\\?\UNC\nas\photos\2024`},
+		{name: "relative path", path: `photos/../import`, want: `import`},
+		{name: "relative path above working directory", path: `..\photos`, want: `..\photos`},
+		{name: "current directory", path: `.\`, want: `.`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanWindowsPath(tt.path); got != tt.want {
+				t.Errorf("cleanWindowsPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	t.Run("empty path", func(t *testing.T) {
+		if _, err := NormalizePath("  "); err == nil {
+			t.Error("Expected error for empty path, got nil")
+		}
+	})
+
+	t.Run("relative path is made absolute", func(t *testing.T) {
+		got, err := NormalizePath(filepath.Join("photos", "..", "import"))
+		if err != nil {
+			t.Fatalf("NormalizePath() unexpected error: %v", err)
+		}
+		if !filepath.IsAbs(got) {
+			t.Errorf("NormalizePath() = %q, want an absolute path", got)
+		}
+		if filepath.Base(got) != "import" {
+			t.Errorf("NormalizePath() = %q, want it to end with import", got)
+		}
+	})
+}
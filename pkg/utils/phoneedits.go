@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// Policies for the edited copies phones export next to their originals
+const (
+	PhoneEditsKeep     = "keep"
+	PhoneEditsEdited   = "edited"
+	PhoneEditsOriginal = "original"
+)
+
+// appleEditStem matches the names iOS gives to edited copies, IMG_E1234 for IMG_1234
+var appleEditStem = regexp.MustCompile(`(?i)^(IMG_)E(\d+)$`)
+
+// phoneEditOriginalStem returns the name, without extension, of the original
+// of an edited copy exported by a phone: IMG_1234 for IMG_E1234 (iOS) and
+// PXL_20240611_153000123 for PXL_20240611_153000123-edited (Google Photos)
+func phoneEditOriginalStem(stem string) (string, bool) {
+	if m := appleEditStem.FindStringSubmatch(stem); m != nil {
+		return m[1] + m[2], true
+	}
+	if original, ok := strings.CutSuffix(stem, "-edited"); ok && original != "" {
+		return original, true
+	}
+	return "", false
+}
+
+// FindPhoneEdits returns the edited copies of the source whose original is
+// in the same directory, mapped to their original. The original may be of
+// another format, such as IMG_E1234.JPG for IMG_1234.HEIC.
+func FindPhoneEdits(source string) (map[string]string, error) {
+	return findPhoneEdits(OSFileSystem{}, source)
+}
+
+// findPhoneEdits is FindPhoneEdits for a source on fsys
+func findPhoneEdits(fsys FileSystem, source string) (map[string]string, error) {
+	originals := make(map[string]string) // Lower-case path without extension to file
+	var edits []string
+
+	err := walkFiles(fsys, source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+		if info.IsDir() || !isAllowedExtension(filepath.Ext(path)) {
+			return nil
+		}
+		stem := strings.TrimSuffix(path, filepath.Ext(path))
+		if _, ok := phoneEditOriginalStem(filepath.Base(stem)); ok {
+			edits = append(edits, path)
+		} else {
+			originals[strings.ToLower(stem)] = path
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	pairs := make(map[string]string)
+	for _, edit := range edits {
+		stem, _ := phoneEditOriginalStem(strings.TrimSuffix(filepath.Base(edit), filepath.Ext(edit)))
+		if original, ok := originals[strings.ToLower(filepath.Join(filepath.Dir(edit), stem))]; ok {
+			pairs[edit] = original
+		}
+	}
+	return pairs, nil
+}
+
+// phoneEdits are the pairs of edited copies and originals of a run
+type phoneEdits struct {
+	policy    string
+	originals map[string]string    // Edited copies to their original
+	edits     map[string]string    // Originals to their edited copy
+	dates     map[string]time.Time // Dates of the originals of edited copies, which place both files
+	names     map[string]string    // Names of the edited versions of exports, which would take the name of their original
+}
+
+// loadPhoneEdits finds the edited copies of the source on fsys, nil without policy
+func loadPhoneEdits(fsys FileSystem, p *models.Params) (*phoneEdits, error) {
+	if p.PhoneEdits == "" {
+		return nil, nil
+	}
+	pairs, err := findPhoneEdits(fsys, p.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	if p.Profile == ProfileApplePhotos {
+		exported, err := findExportEdits(fsys, p.Source)
+		if err != nil {
+			return nil, err
+		}
+		for edit, original := range exported {
+			pairs[edit] = original
+			names[edit] = exportEditName(filepath.Base(edit))
+		}
+	}
+
+	e := &phoneEdits{policy: p.PhoneEdits, originals: pairs, edits: make(map[string]string, len(pairs)), dates: make(map[string]time.Time), names: names}
+	for edit, original := range pairs {
+		e.edits[original] = edit
+		// Edits exported later may carry the export date, the original keeps both together
+		if date, err := fileDate(fsys, original, p.NoGPS); err == nil {
+			e.dates[edit] = date
+		}
+	}
+	return e, nil
+}
+
+// skip returns why a file is left out by the policy, if it is
+func (e *phoneEdits) skip(path string) (string, bool) {
+	if e == nil {
+		return "", false
+	}
+	if edit, ok := e.edits[path]; ok && e.policy == PhoneEditsEdited {
+		return fmt.Sprintf("original of the edited copy %s", filepath.Base(edit)), true
+	}
+	if original, ok := e.originals[path]; ok && e.policy == PhoneEditsOriginal {
+		return fmt.Sprintf("edited copy of %s", filepath.Base(original)), true
+	}
+	return "", false
+}
+
+// date returns the date placing a file: that of its original for an edited
+// copy, so both land in the same folder, date otherwise
+func (e *phoneEdits) date(path string, date time.Time) time.Time {
+	if e == nil {
+		return date
+	}
+	if original, ok := e.dates[path]; ok {
+		return original
+	}
+	return date
+}
+
+// placeNames adds the names of the edited versions of exports to the names of
+// files placed apart, unless they are already placed, and returns them
+func (e *phoneEdits) placeNames(names map[string]string) map[string]string {
+	if e == nil || len(e.names) == 0 {
+		return names
+	}
+	if names == nil {
+		names = make(map[string]string, len(e.names))
+	}
+	for path, name := range e.names {
+		if _, ok := names[path]; !ok {
+			names[path] = name
+		}
+	}
+	return names
+}
+
+// skipPhoneEdit leaves out the file of a pair the policy does not keep
+func (r *mediaRun) skipPhoneEdit(path string, info os.FileInfo, reason string, summary *ProcessingSummary) {
+	summary.skip(SkipFiltered)
+	output.Status("SKIPPED", fmt.Sprintf("%s (%s)", path, reason))
+	r.finish(ReportEntry{Source: path, Status: ReportSkipped, Reason: reason, SkipReason: SkipFiltered, Size: info.Size()})
+}
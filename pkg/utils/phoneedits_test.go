@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestPhoneEditOriginalStem(t *testing.T) {
+	tests := []struct {
+		stem   string
+		want   string
+		wantOK bool
+	}{
+		{"IMG_E1234", "IMG_1234", true},
+		{"img_e0001", "img_0001", true},
+		{"PXL_20240611_153000123-edited", "PXL_20240611_153000123", true},
+		{"IMG_1234", "", false},
+		{"IMG_EDIT", "", false},
+		{"-edited", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := phoneEditOriginalStem(tt.stem)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("phoneEditOriginalStem(%s) = %s, %v, want %s, %v", tt.stem, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFindPhoneEdits(t *testing.T) {
+	source := t.TempDir()
+	createCullSource(t, source, []string{
+		"DCIM/IMG_1234.HEIC",
+		"DCIM/IMG_E1234.JPG",  // Edited copy in another format
+		"DCIM/IMG_E1235.HEIC", // Original deleted
+		"Camera/PXL_20240611_153000123.jpg",
+		"Camera/PXL_20240611_153000123-edited.jpg",
+		"Other/IMG_1236.HEIC",
+		"IMG_E1236.HEIC", // Original in another folder
+	})
+
+	pairs, err := FindPhoneEdits(source)
+	if err != nil {
+		t.Fatalf("FindPhoneEdits() error = %v", err)
+	}
+	path := func(name string) string { return filepath.Join(source, filepath.FromSlash(name)) }
+	want := map[string]string{
+		path("DCIM/IMG_E1234.JPG"):                       path("DCIM/IMG_1234.HEIC"),
+		path("Camera/PXL_20240611_153000123-edited.jpg"): path("Camera/PXL_20240611_153000123.jpg"),
+	}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("FindPhoneEdits() = %v, want %v", pairs, want)
+	}
+}
+
+func TestProcessMediaFilesPhoneEdits(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    []string // Imported files, relative to the destination
+		skipped string
+	}{
+		{policy: PhoneEditsKeep, want: []string{"2024/06-11/IMG_1234.JPG", "2024/06-11/IMG_E1234.JPG"}},
+		{policy: PhoneEditsEdited, want: []string{"2024/06-11/IMG_E1234.JPG"}, skipped: "IMG_1234.JPG"},
+		{policy: PhoneEditsOriginal, want: []string{"2024/06-11/IMG_1234.JPG"}, skipped: "IMG_E1234.JPG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			source := t.TempDir()
+			destination := t.TempDir()
+			// The edited copy carries the date it was exported on
+			if err := os.WriteFile(filepath.Join(source, "IMG_1234.JPG"), createSerialJPEG("2024:06:11 15:30:10", "1"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(source, "IMG_E1234.JPG"), createSerialJPEG("2024:08:02 09:00:00", "1"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			params := &models.Params{
+				Source:      source,
+				Destination: destination,
+				Compression: -1,
+				PhoneEdits:  tt.policy,
+				ReportFile:  filepath.Join(t.TempDir(), "report.json"),
+			}
+			plan, err := PlanMediaFiles(params)
+			if err != nil {
+				t.Fatalf("PlanMediaFiles() error = %v", err)
+			}
+			if len(plan) != len(tt.want) {
+				t.Errorf("planned %d files, want %d: %+v", len(plan), len(tt.want), plan)
+			}
+
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			for _, rel := range tt.want {
+				if _, err := os.Stat(filepath.Join(destination, filepath.FromSlash(rel))); err != nil {
+					t.Errorf("Expected imported file: %v", err)
+				}
+			}
+			if summary.Copied != len(tt.want) {
+				t.Errorf("Expected %d copied files, got %+v", len(tt.want), summary)
+			}
+
+			if tt.skipped == "" {
+				return
+			}
+			if summary.Skipped != 1 {
+				t.Errorf("Expected 1 skipped file, got %+v", summary)
+			}
+			for _, entry := range readTestReport(t, params.ReportFile).Files {
+				if filepath.Base(entry.Source) == tt.skipped && entry.Status != ReportSkipped {
+					t.Errorf("report entry of %s = %+v, want skipped", tt.skipped, entry)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// PlannedFile describes where a media file will be organized
+type PlannedFile struct {
+	Source      string
+	Destination string // Empty when the file cannot be organized
+	Date        time.Time
+	Size        int64
+	Err         error // Reason the file cannot be organized, if any
+}
+
+// PlanMediaFiles walks the source and resolves the destination of every media
+// file without writing anything. Dates are read straight from the open files,
+// so only the metadata sections are loaded, not whole files.
+func PlanMediaFiles(p *models.Params) ([]PlannedFile, error) {
+	return PlanMediaFilesFS(OSFileSystem{}, p)
+}
+
+// PlanMediaFilesFS is PlanMediaFiles with the source and destination files on
+// fsys, like ProcessMediaFilesFS
+func PlanMediaFilesFS(fsys FileSystem, p *models.Params) ([]PlannedFile, error) {
+	var cache *MetadataCache
+	if p.CacheFile != "" {
+		var err error
+		if cache, err = LoadMetadataCache(p.CacheFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var state *SourceState
+	if p.SinceLast {
+		var err error
+		if state, err = LoadSourceState(p.Destination, p.Source); err != nil {
+			return nil, err
+		}
+	}
+
+	culled, err := loadCullOrphans(p)
+	if err != nil {
+		return nil, err
+	}
+
+	edits, err := loadPhoneEdits(fsys, p)
+	if err != nil {
+		return nil, err
+	}
+
+	names, _, _, err := loadShotNames(fsys, p, cache)
+	if err != nil {
+		return nil, err
+	}
+	names = edits.placeNames(names)
+
+	lightroom, err := loadLightroom(p)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := loadEncryptor(p)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets, err := loadClockOffsets(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := layoutOf(p); err != nil {
+		return nil, err
+	}
+	if _, err := yearRootsOf(p); err != nil {
+		return nil, err
+	}
+
+	limiter := newDirLimiter(fsys, p, enc)
+	fat := DestinationIsFAT(p)
+
+	selected := newFileSet(p.Files)
+	device, shots := isDeviceExport(fsys, p), screenshotsPolicy(fsys, p)
+
+	var plan []PlannedFile
+	err = walkSource(fsys, p, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+
+		if info.IsDir() || !isImportable(p, path) || !selected.has(path) || state.Unchanged(path, info) || culled[path] {
+			return nil
+		}
+		if _, skip := edits.skip(path); skip {
+			return nil
+		}
+
+		// Screenshots are only told apart when the policy places them differently
+		screenshot := (shots == ScreenshotsRoute || shots == ScreenshotsSkip) &&
+			!isProxyFile(path) && !isUnknownFile(p, path) && isScreenshotFile(fsys, path, device)
+		if screenshot && shots == ScreenshotsSkip {
+			return nil
+		}
+
+		planned := PlannedFile{Source: path, Size: info.Size()}
+		if isUnknownFile(p, path) {
+			planned.Date = info.ModTime()
+			planned.Destination = unknownDestination(p, path, info)
+		} else if err := checkFileIntegrity(fsys, path, info); err != nil {
+			planned.Err = err
+		} else if date, err := sourceDate(fsys, p, path, info, cache, offsets); err != nil {
+			planned.Err = err
+		} else if lightroom.Manages(path, date) && !p.LightroomFlag {
+			return nil
+		} else {
+			if _, manual := p.DateOverrides[path]; !manual {
+				date = edits.date(path, date)
+			}
+			planned.Date = date
+			planned.Destination = sequenceDestination(p, path, date, names)
+			if isProxyFile(path) {
+				planned.Destination = proxyDestination(p, path, date)
+			}
+			if screenshot {
+				planned.Destination = screenshotDestination(p, path, date)
+			}
+		}
+		if planned.Destination != "" {
+			if fat {
+				planned.Destination = fatSafePath(rootOf(p, planned.Destination), planned.Destination)
+			}
+			planned.Destination = enc.Path(limiter.place(planned.Destination))
+		}
+		plan = append(plan, planned)
+		publish(plannedEvent(planned))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	if err := cache.Save(); err != nil {
+		return plan, fmt.Errorf("failed to save metadata cache: %w", err)
+	}
+
+	return plan, nil
+}
+
+// readMediaDate returns the capture date of a file, from the cache when
+// possible, never reading GPS locations when noGPS is set
+func readMediaDate(fsys FileSystem, path string, info os.FileInfo, cache *MetadataCache, noGPS bool) (time.Time, error) {
+	if date, ok := cache.Get(path, info); ok {
+		return date, nil
+	}
+
+	file, err := fsys.Open(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not open file: %w", err)
+	}
+	defer file.Close()
+
+	date, err := mediaDate(fsys, path, file, noGPS)
+	if err != nil && !isProxyFile(path) && datedAsScreenshot(path, file) {
+		date, err = screenshotDate(path, info), nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not get date from EXIF data: %w", err)
+	}
+
+	cache.Put(path, info, date)
+	return date, nil
+}
+
+// destinationPath returns where a file taken at date is organized, by the
+// layout of the run: <dest>/YYYY/MM-DD/<name> by default, below the tier of
+// its age when tiering rules apply
+func destinationPath(p *models.Params, source string, date time.Time) string {
+	l, _ := layoutOf(p)
+	return filepath.Join(destinationRoot(p, date), l.Resolve(source, date))
+}
+
+// PlanConflicts returns the planned files a run would skip, with the reason in
+// Err: files without a date, files whose destination already exists and files
+// sharing their destination with another source file
+func PlanConflicts(plan []PlannedFile) []PlannedFile {
+	var conflicts []PlannedFile
+	claimed := make(map[string]string) // Destination to the first source planned there
+
+	for _, f := range plan {
+		switch {
+		case f.Err != nil:
+			conflicts = append(conflicts, f)
+			continue
+		case claimed[f.Destination] != "":
+			f.Err = &DestinationConflictError{Path: f.Destination, Source: claimed[f.Destination]}
+			conflicts = append(conflicts, f)
+			continue
+		}
+		claimed[f.Destination] = f.Source
+
+		if exists, err := fileExists(OSFileSystem{}, f.Destination); err != nil {
+			f.Err = fmt.Errorf("failed to check destination file: %w", err)
+			conflicts = append(conflicts, f)
+		} else if exists {
+			f.Err = &DestinationConflictError{Path: f.Destination}
+			conflicts = append(conflicts, f)
+		}
+	}
+	return conflicts
+}
+
+// plannedEvent returns the event of a planned file, the reason it cannot be
+// organized in the reason of its entry
+func plannedEvent(planned PlannedFile) Event {
+	entry := ReportEntry{Source: planned.Source, Destination: planned.Destination, Size: planned.Size}
+	if planned.Err != nil {
+		entry.Reason = planned.Err.Error()
+	}
+	return Event{Kind: EventFilePlanned, File: entry}
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestPlanMediaFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	files := map[string][]byte{
+		"dated.jpg":   createFakeExifData(),
+		"undated.jpg": []byte("Not a valid JPEG"),
+		"notes.txt":   []byte("not media"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), data, 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: destDir,
+		Compression: -1,
+	}
+
+	plan, err := PlanMediaFiles(params)
+	if err != nil {
+		t.Fatalf("PlanMediaFiles() unexpected error: %v", err)
+	}
+	if len(plan) != 2 {
+		t.Fatalf("Expected 2 planned files, got %d", len(plan))
+	}
+
+	for _, f := range plan {
+		switch filepath.Base(f.Source) {
+		case "dated.jpg":
+			if f.Err != nil {
+				t.Errorf("Unexpected planning error for dated file: %v", f.Err)
+			}
+			want := filepath.Join(destDir, "2025", "01-11", "dated.jpg")
+			if f.Destination != want {
+				t.Errorf("Destination = %s, want %s", f.Destination, want)
+			}
+			if !f.Date.Equal(time.Date(2025, time.January, 11, 17, 10, 39, 0, time.UTC)) {
+				t.Errorf("Date = %v, want 2025-01-11 17:10:39", f.Date)
+			}
+		case "undated.jpg":
+			if f.Err == nil || f.Destination != "" {
+				t.Errorf("Expected undated file to have an error and no destination, got %+v", f)
+			}
+		default:
+			t.Errorf("Unexpected planned file %s", f.Source)
+		}
+	}
+
+	// Planning must not write anything
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		t.Fatalf("Failed to read destination: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected empty destination after planning, got %d entries", len(entries))
+	}
+}
+
+func TestPlanConflicts(t *testing.T) {
+	destDir := t.TempDir()
+	existing := filepath.Join(destDir, "2025", "01-11", "existing.jpg")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(existing, []byte("existing"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	date := time.Date(2025, 1, 11, 17, 10, 39, 0, time.UTC)
+	free := filepath.Join(destDir, "2025", "01-11", "free.jpg")
+
+	tests := []struct {
+		name     string
+		plan     []PlannedFile
+		want     []string // Sources expected as conflicts
+		conflict string   // Source expected in the DestinationConflictError, if any
+	}{
+		{
+			name: "no conflicts",
+			plan: []PlannedFile{{Source: "a/free.jpg", Destination: free, Date: date}},
+		},
+		{
+			name: "no date",
+			plan: []PlannedFile{{Source: "a/undated.jpg", Err: os.ErrInvalid}},
+			want: []string{"a/undated.jpg"},
+		},
+		{
+			name: "destination exists",
+			plan: []PlannedFile{{Source: "a/existing.jpg", Destination: existing, Date: date}},
+			want: []string{"a/existing.jpg"},
+		},
+		{
+			name: "shared destination",
+			plan: []PlannedFile{
+				{Source: "a/free.jpg", Destination: free, Date: date},
+				{Source: "b/free.jpg", Destination: free, Date: date},
+			},
+			want:     []string{"b/free.jpg"},
+			conflict: "a/free.jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := PlanConflicts(tt.plan)
+			if len(conflicts) != len(tt.want) {
+				t.Fatalf("PlanConflicts() = %v, want %v", conflicts, tt.want)
+			}
+			for i, c := range conflicts {
+				if c.Source != tt.want[i] || c.Err == nil {
+					t.Errorf("conflict %d = %s (%v), want %s with a reason", i, c.Source, c.Err, tt.want[i])
+				}
+				var conflictErr *DestinationConflictError
+				if errors.As(c.Err, &conflictErr) != (c.Destination != "") {
+					t.Errorf("conflict %d error = %v, want a DestinationConflictError for taken destinations", i, c.Err)
+				} else if conflictErr != nil && (conflictErr.Path != c.Destination || conflictErr.Source != tt.conflict) {
+					t.Errorf("conflict %d error = %+v, want path %s and source %q", i, conflictErr, c.Destination, tt.conflict)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// UnreadableFile describes a source file that could not be read completely
+type UnreadableFile struct {
+	Path string
+	Err  error
+}
+
+// PrecheckFiles reads every media file of the source to the end, so IO errors
+// from a failing card are found before anything is copied or deleted
+func PrecheckFiles(source string) ([]UnreadableFile, error) {
+	var unreadable []UnreadableFile
+	buffer := make([]byte, 1024*1024)
+
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// An unreadable directory hides its files, report it and keep going
+			unreadable = append(unreadable, UnreadableFile{Path: path, Err: err})
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() || !isAllowedExtension(filepath.Ext(info.Name())) {
+			return nil
+		}
+
+		if err := readFully(path, info.Size(), buffer); err != nil {
+			unreadable = append(unreadable, UnreadableFile{Path: path, Err: err})
+		}
+		return nil
+	})
+	if err != nil {
+		return unreadable, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	return unreadable, nil
+}
+
+// readFully reads a file to the end and checks that its size matches what the directory reported
+func readFully(path string, size int64, buffer []byte) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	n, err := io.CopyBuffer(io.Discard, file, buffer)
+	if err != nil {
+		return fmt.Errorf("read failed after %d bytes: %w", n, err)
+	}
+	if n != size {
+		return fmt.Errorf("read %d bytes, expected %d", n, size)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPrecheckFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(sourceDir, "good.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	t.Run("all files readable", func(t *testing.T) {
+		unreadable, err := PrecheckFiles(sourceDir)
+		if err != nil {
+			t.Fatalf("PrecheckFiles() unexpected error: %v", err)
+		}
+		if len(unreadable) != 0 {
+			t.Errorf("Expected no unreadable files, got %v", unreadable)
+		}
+	})
+
+	t.Run("unreadable file", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("Skipping permission test on Windows")
+		}
+		if os.Geteuid() == 0 {
+			t.Skip("Skipping permission test when running as root")
+		}
+
+		badFile := filepath.Join(sourceDir, "bad.nef")
+		if err := os.WriteFile(badFile, []byte("raw data"), 0200); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		defer os.Remove(badFile)
+
+		unreadable, err := PrecheckFiles(sourceDir)
+		if err != nil {
+			t.Fatalf("PrecheckFiles() unexpected error: %v", err)
+		}
+		if len(unreadable) != 1 || unreadable[0].Path != badFile {
+			t.Errorf("Expected %s to be reported as unreadable, got %v", badFile, unreadable)
+		}
+	})
+
+	t.Run("size mismatch", func(t *testing.T) {
+		path := filepath.Join(sourceDir, "good.jpg")
+		if err := readFully(path, 1<<20, make([]byte, 512)); err == nil {
+			t.Error("Expected error when fewer bytes than the reported size can be read")
+		}
+	})
+}
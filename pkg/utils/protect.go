@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// readOnlyPerm is the mode of write-protected destination files
+const readOnlyPerm = 0444
+
+// protectFile makes a newly written destination file read-only when the run
+// protects the archive, so it cannot be modified or deleted by accident.
+// Runs never replace existing files, protected ones are skipped like others.
+func protectFile(fsys FileSystem, path string, p *models.Params, summary *ProcessingSummary) {
+	if !p.ReadOnly {
+		return
+	}
+	if err := fsys.Chmod(path, readOnlyPerm); err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to write-protect %s: %v", path, err))
+		return
+	}
+	if summary != nil {
+		summary.Protected++
+	}
+}
+
+// isWriteProtected reports whether a file exists and cannot be written by anyone
+func isWriteProtected(fsys FileSystem, path string) bool {
+	info, err := fsys.Stat(path)
+	return err == nil && info.Mode().Perm()&0222 == 0
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestProcessMediaFilesReadOnly(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	mirror := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	reportFile := filepath.Join(t.TempDir(), "report.json")
+	params := &models.Params{Source: sourceDir, Destination: destDir, Mirrors: []string{mirror}, Compression: -1, ReadOnly: true, ReportFile: reportFile}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Protected != 1 {
+		t.Errorf("Expected 1 protected file, got %+v", summary)
+	}
+
+	rel := filepath.Join("2025", "01-11", "photo.jpg")
+	for _, path := range []string{filepath.Join(destDir, rel), filepath.Join(mirror, rel)} {
+		if !isWriteProtected(OSFileSystem{}, path) {
+			t.Errorf("Expected %s to be read-only", path)
+		}
+	}
+
+	// The next run skips the protected file and says why
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() second run error = %v", err)
+	}
+	files := readTestReport(t, reportFile).Files
+	if len(files) != 1 || files[0].Reason != "destination file already exists and is write-protected" {
+		t.Errorf("report files = %+v", files)
+	}
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// Ways of recording the provenance of imported files
+const (
+	ProvenanceEmbed   = "embed"   // In the XMP metadata of JPEG files, in sidecars for other formats
+	ProvenanceSidecar = "sidecar" // In XMP sidecars next to every file
+)
+
+// ProvenanceNamespace is the XMP namespace of provenance properties
+const ProvenanceNamespace = "https://github.com/matdmb/organize-media/ns/provenance/1.0/"
+
+// ProvenanceSidecarExt is appended to the name of files whose provenance is
+// recorded in a sidecar, such as DSC00001.ARW.xmp, so the sidecars of a RAW
+// and a JPEG file of the same shot are apart from those of editors
+const ProvenanceSidecarExt = ".xmp"
+
+// Provenance traces an archived file back to its import
+type Provenance struct {
+	ID          string    // Identifier of the imported file, as in catalog records
+	Tool        string    // Tool and version that imported the file
+	Source      string    // Path of the source file
+	Hash        string    // Content hash of the source file, as in catalog records
+	Compression int       // JPEG quality the file was recompressed at, 0 when copied as is
+	ImportedAt  time.Time // When the file was imported
+}
+
+// newProvenance returns the provenance of a source file whose content hash is
+// hash, imported under the identifier id, nil when the run does not record
+// provenance
+func newProvenance(p *models.Params, source, hash, id string) *Provenance {
+	if p.Provenance == "" {
+		return nil
+	}
+	return &Provenance{
+		ID:         id,
+		Tool:       "organize-media " + CurrentBuild().Version,
+		Source:     source,
+		Hash:       hash,
+		ImportedAt: time.Now(),
+	}
+}
+
+// embedsProvenance reports whether the provenance of a file is embedded in
+// it, which changes its content like recompression does
+func embedsProvenance(p *models.Params, isJPG bool) bool {
+	return p.Provenance == ProvenanceEmbed && isJPG
+}
+
+// description returns the rdf:Description element of the provenance
+func (pv *Provenance) description() []byte {
+	var b bytes.Buffer
+	attr := func(name, value string) {
+		fmt.Fprintf(&b, " omp:%s=\"", name)
+		xml.EscapeText(&b, []byte(value))
+		b.WriteByte('"')
+	}
+
+	fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:omp="%s"`, ProvenanceNamespace)
+	if pv.ID != "" {
+		attr("ID", pv.ID)
+	}
+	attr("Tool", pv.Tool)
+	attr("Source", pv.Source)
+	attr("Hash", pv.Hash)
+	if pv.Compression > 0 {
+		attr("Compression", strconv.Itoa(pv.Compression))
+	}
+	attr("ImportedAt", pv.ImportedAt.Format(time.RFC3339))
+	b.WriteString("/>")
+	return b.Bytes()
+}
+
+// packet returns an XMP packet holding the provenance alone
+func (pv *Provenance) packet() []byte {
+	return xmpPacket(pv.description())
+}
+
+// embed returns a JPEG file with the provenance added to its XMP packet, or
+// to a new one when it has none. It returns false when the file is not a
+// JPEG file or the packet would not fit in its segment.
+func (pv *Provenance) embed(data []byte) ([]byte, bool) {
+	return updateJPEGXMP(data, func(xmp []byte) ([]byte, bool) {
+		return insertXMPDescription(xmp, pv.description())
+	}, pv.packet())
+}
+
+// writeProvenanceSidecar writes the provenance of a file written to destPath
+// of fsys in a sidecar next to it, encrypted like the file with enc
+func writeProvenanceSidecar(fsys FileSystem, destPath string, pv *Provenance, enc *Encryptor) error {
+	return writeXMPSidecar(fsys, destPath, pv.packet(), enc)
+}
+
+// writeXMPSidecar writes the XMP packet of a file written to destPath of fsys
+// in the sidecar next to it, encrypted like the file with enc
+func writeXMPSidecar(fsys FileSystem, destPath string, packet []byte, enc *Encryptor) error {
+	sidecar := destPath + ProvenanceSidecarExt
+	data, err := enc.Seal(filepath.Base(sidecar), packet)
+	if err != nil {
+		return err
+	}
+
+	// The sidecar replaces the one of an earlier run, once complete
+	path := enc.Path(sidecar)
+	file, name, err := openTemp(fsys, path)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fsys.Rename(name, path)
+	}
+	if err != nil {
+		fsys.Remove(name)
+	}
+	return err
+}
+
+// isProvenanceSidecar reports whether path is the provenance sidecar of a
+// file next to it, such as DSC00001.ARW.xmp
+func isProvenanceSidecar(path string) bool {
+	if !strings.HasSuffix(path, ProvenanceSidecarExt) {
+		return false
+	}
+	info, err := os.Stat(strings.TrimSuffix(path, ProvenanceSidecarExt))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// recordProvenance records the provenance of a file written to destPath in a
+// sidecar, warning when it cannot be written as the file itself is imported
+func recordProvenance(fsys FileSystem, destPath string, pv *Provenance, enc *Encryptor) {
+	if err := writeProvenanceSidecar(fsys, destPath, pv, enc); err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to write provenance of %s: %v", enc.Path(destPath), err))
+	}
+}
+
+// destinationProvenance returns the provenance an earlier import recorded for
+// the file at destPath of fsys, in its sidecar or embedded in a JPEG file
+func destinationProvenance(fsys FileSystem, destPath string) (Provenance, bool) {
+	if data, err := readFile(fsys, destPath+ProvenanceSidecarExt); err == nil {
+		if pv, ok := ReadProvenance(data); ok {
+			return pv, true
+		}
+	}
+	if !isJPEG(destPath) {
+		return Provenance{}, false
+	}
+	data, err := readFile(fsys, destPath)
+	if err != nil {
+		return Provenance{}, false
+	}
+	return ReadProvenance(ExtractXMP(data, destPath))
+}
+
+// ReadProvenance returns the provenance recorded in an XMP packet, from an
+// imported JPEG file or a sidecar, and whether there was one
+func ReadProvenance(xmp []byte) (Provenance, bool) {
+	var pv Provenance
+	found := false
+
+	decoder := xml.NewDecoder(bytes.NewReader(xmp))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return pv, found // End of the packet, or trailing padding
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Space != ProvenanceNamespace {
+				continue
+			}
+			found = true
+			switch attr.Name.Local {
+			case "ID":
+				pv.ID = attr.Value
+			case "Tool":
+				pv.Tool = attr.Value
+			case "Source":
+				pv.Source = attr.Value
+			case "Hash":
+				pv.Hash = attr.Value
+			case "Compression":
+				pv.Compression, _ = strconv.Atoi(attr.Value)
+			case "ImportedAt":
+				pv.ImportedAt, _ = time.Parse(time.RFC3339, attr.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createDatedJPEG returns a JPEG file taken at date, as EXIF text, holding
+// an image that decodes, so it can be recompressed
+func createDatedJPEG(t *testing.T, date string) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewGray(image.Rect(0, 0, 16, 16)), nil); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	header := createSerialJPEG(date, "1")
+	return append(header[:len(header)-2], encoded.Bytes()[2:]...)
+}
+
+func TestProvenanceEmbed(t *testing.T) {
+	pv := &Provenance{
+		Tool:        "organize-media v1.2.0",
+		Source:      `/media/card/DCIM/100MSDCF/DSC"<&>.JPG`,
+		Hash:        "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		Compression: 80,
+		ImportedAt:  time.Date(2024, time.June, 11, 15, 30, 10, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		wantOK bool
+		faces  int // Face regions of the original packet still found
+	}{
+		{name: "without XMP", data: createDatedJPEG(t, "2024:06:11 15:30:10"), wantOK: true},
+		{name: "merged into XMP", data: createXMPJPEG(t, testXMP), wantOK: true, faces: 2},
+		{name: "not a JPEG file", data: []byte("RIFF....WEBP")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedded, ok := pv.embed(tt.data)
+			if ok != tt.wantOK {
+				t.Fatalf("embed() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if !bytes.Equal(embedded, tt.data) {
+					t.Errorf("embed() changed a file it could not embed into")
+				}
+				return
+			}
+
+			if _, err := jpeg.Decode(bytes.NewReader(embedded)); err != nil {
+				t.Errorf("embedded file does not decode: %v", err)
+			}
+			xmp := ExtractXMP(embedded, "photo.jpg")
+			got, found := ReadProvenance(xmp)
+			if !found || got != *pv {
+				t.Errorf("ReadProvenance() = %+v, %v, want %+v", got, found, *pv)
+			}
+			if faces := FaceRegions(xmp); len(faces) != tt.faces {
+				t.Errorf("FaceRegions() = %+v, want %d regions", faces, tt.faces)
+			}
+		})
+	}
+}
+
+func TestReadProvenanceNone(t *testing.T) {
+	if pv, found := ReadProvenance([]byte(testXMP)); found {
+		t.Errorf("ReadProvenance() = %+v, want none", pv)
+	}
+}
+
+func TestProcessMediaFilesProvenance(t *testing.T) {
+	tests := []struct {
+		mode        string
+		compression int
+		embedded    bool
+	}{
+		{mode: ProvenanceEmbed, compression: -1, embedded: true},
+		{mode: ProvenanceEmbed, compression: 80, embedded: true},
+		{mode: ProvenanceSidecar, compression: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			source := t.TempDir()
+			destination := t.TempDir()
+			sourcePath := filepath.Join(source, "DSC00001.JPG")
+			data := createDatedJPEG(t, "2024:06:11 15:30:10")
+			if err := os.WriteFile(sourcePath, data, 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			catalogPath := filepath.Join(t.TempDir(), "catalog.jsonl")
+			params := &models.Params{
+				Source:      source,
+				Destination: destination,
+				Compression: tt.compression,
+				Provenance:  tt.mode,
+				CatalogFile: catalogPath,
+			}
+			if _, err := ProcessMediaFiles(params); err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+
+			destPath := filepath.Join(destination, "2024", "06-11", "DSC00001.JPG")
+			written, err := os.ReadFile(destPath)
+			if err != nil {
+				t.Fatalf("Expected organized file: %v", err)
+			}
+			sidecar, sidecarErr := os.ReadFile(destPath + ProvenanceSidecarExt)
+
+			var xmp []byte
+			if tt.embedded {
+				xmp = ExtractXMP(written, destPath)
+				if sidecarErr == nil {
+					t.Errorf("Expected no sidecar when the provenance is embedded")
+				}
+			} else {
+				xmp = sidecar
+				if sidecarErr != nil {
+					t.Errorf("Expected a provenance sidecar: %v", sidecarErr)
+				}
+				if !bytes.Equal(written, data) {
+					t.Errorf("Expected the file copied as is with a sidecar")
+				}
+			}
+
+			pv, found := ReadProvenance(xmp)
+			if !found {
+				t.Fatalf("Expected provenance in %q", xmp)
+			}
+			if pv.Source != sourcePath || pv.Hash != HashBuffer(data, HashSHA256) {
+				t.Errorf("provenance = %+v, want source %s and hash of the source", pv, sourcePath)
+			}
+			if want := max(tt.compression, 0); pv.Compression != want {
+				t.Errorf("provenance compression = %d, want %d", pv.Compression, want)
+			}
+			catalog, err := OpenCatalog(catalogPath)
+			if err != nil {
+				t.Fatalf("OpenCatalog() error = %v", err)
+			}
+			record, _ := catalog.Lookup(pv.Hash)
+			catalog.Close()
+			if len(pv.ID) != 26 || pv.ID != record.ID {
+				t.Errorf("provenance ID = %q, want the ID of the catalog record %q", pv.ID, record.ID)
+			}
+
+			// Embedded provenance changes the file, a second import sees it as identical
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if summary.Skips.ExistsIdentical != 1 {
+				t.Errorf("Expected the file skipped as identical on the second import, got %+v", summary.Skips)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// Policies for the low-resolution companions of action cam and drone videos
+const (
+	ProxiesSkip  = "skip"  // Reported as skipped
+	ProxiesKeep  = "keep"  // Organized in the day folder of their video
+	ProxiesRoute = "route" // Organized in a separate proxies tree
+)
+
+// ProxiesDir is the destination folder holding the proxies tree, laid out like the destination
+const ProxiesDir = "proxies"
+
+// ProxyExtensions are the low-resolution companions written by GoPro and DJI
+// cameras next to their videos: .LRV proxy videos and .THM thumbnails
+var ProxyExtensions = map[string]bool{
+	".lrv": true,
+	".thm": true,
+}
+
+// videoExtensions are the videos proxies are made for
+var videoExtensions = map[string]bool{
+	".mp4": true,
+	".mov": true,
+}
+
+// movieEpoch is the origin of ISO base media file timestamps
+var movieEpoch = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// isProxyFile reports whether path is the low-resolution companion of a video
+func isProxyFile(path string) bool {
+	return ProxyExtensions[strings.ToLower(filepath.Ext(path))] || isInsta360Proxy(filepath.Base(path))
+}
+
+// isImportable reports whether a file is handled by the run: supported media,
+// and any other file with -copy-unknown
+func isImportable(p *models.Params, path string) bool {
+	return p.CopyUnknown || isMediaFile(p, path)
+}
+
+// isMediaFile reports whether a file is organized by its capture date:
+// supported media, proxies unless no proxy policy is set, and the Live Photo
+// videos and AAE sidecars of photos with the apple-photos profile
+func isMediaFile(p *models.Params, path string) bool {
+	if isProxyFile(path) {
+		return p.Proxies != ""
+	}
+	if p.Profile == ProfileApplePhotos {
+		if _, ok := applePhotosCompanion(path); ok {
+			return true
+		}
+	}
+	return isAllowedExtension(filepath.Ext(path))
+}
+
+// proxyParentStems returns the names, without extension, the video of a proxy
+// may have. DJI proxies share the name of their video, GoPro ones replace its
+// GX or GH prefix with GL (GL010001.LRV for GX010001.MP4), and Insta360 ones
+// are named after the first lens of their clip (LRV_20240611_153000_01_001
+// for VID_20240611_153000_00_001).
+func proxyParentStems(name string) []string {
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	stems := []string{stem}
+	if len(stem) > 2 && strings.EqualFold(stem[:2], "GL") {
+		stems = append(stems, "GX"+stem[2:], "GH"+stem[2:])
+	}
+	if primary, ok := insta360Primary(stem); ok {
+		stems = append(stems, primary)
+	}
+	return stems
+}
+
+// proxyParent returns the media file a proxy was made for, if it is next to it
+func proxyParent(fsys FileSystem, path string) (string, bool) {
+	entries, err := fsys.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return "", false
+	}
+
+	for _, stem := range proxyParentStems(filepath.Base(path)) {
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := strings.ToLower(filepath.Ext(name))
+			if entry.IsDir() || isProxyFile(name) || !strings.EqualFold(strings.TrimSuffix(name, filepath.Ext(name)), stem) {
+				continue
+			}
+			if videoExtensions[ext] || isAllowedExtension(ext) {
+				return filepath.Join(filepath.Dir(path), name), true
+			}
+		}
+	}
+	return "", false
+}
+
+// proxyDate returns the date of a proxy: the date of its video when it is
+// still on the card, otherwise the date recorded in the proxy itself
+func proxyDate(fsys FileSystem, path string, r io.ReadSeeker, noGPS bool) (time.Time, error) {
+	if parent, ok := proxyParent(fsys, path); ok {
+		if date, err := fileDate(fsys, parent, noGPS); err == nil {
+			return date, nil
+		}
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".thm") {
+		// Thumbnails are small JPEG files
+		return GetImageDateTimeFromReader(r, ".jpg")
+	}
+	return movieCreationTime(r, noGPS)
+}
+
+// mediaDate returns the capture date of a media file read from r. Proxies, the
+// files of the other lens of 360 cameras, Live Photo videos and AAE sidecars
+// are dated by the file they go with when it is next to them, so they land in
+// the same folder. Privacy mode (noGPS) never reads the GPS locations of
+// videos, dating videos recorded in UTC in UTC.
+func mediaDate(fsys FileSystem, path string, r io.ReadSeeker, noGPS bool) (time.Time, error) {
+	if isProxyFile(path) {
+		return proxyDate(fsys, path, r, noGPS)
+	}
+	if primary, ok := insta360Pair(fsys, path); ok {
+		if date, err := fileDate(fsys, primary, noGPS); err == nil {
+			return date, nil
+		}
+	}
+	if photo, ok := applePhotosCompanion(path); ok {
+		if date, err := fileDate(fsys, photo, noGPS); err == nil {
+			return date, nil
+		}
+		if videoExtensions[strings.ToLower(filepath.Ext(path))] {
+			return movieCreationTime(r, noGPS)
+		}
+	}
+	return imageDateTime(r, filepath.Ext(path), noGPS)
+}
+
+// fileDate returns the capture date of a picture or the creation date of a video
+func fileDate(fsys FileSystem, path string, noGPS bool) (time.Time, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+
+	if videoExtensions[strings.ToLower(filepath.Ext(path))] {
+		return movieCreationTime(file, noGPS)
+	}
+	return imageDateTime(file, filepath.Ext(path), noGPS)
+}
+
+// movieCreationTime returns the creation time of the movie header (moov/mvhd)
+// of an ISO base media file, such as MP4, MOV and LRV files. Cameras record
+// their local time there, it is read as is like EXIF dates. Phones record UTC
+// along with a GPS location, converted to the local time of that location so
+// their videos land in the day folder of the photos taken at the same moment,
+// unless noGPS is set: the location is then never read and UTC is kept.
+func movieCreationTime(r io.ReadSeeker, noGPS bool) (time.Time, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	moovStart, moovEnd, err := findBox(r, 0, size, "moov")
+	if err != nil {
+		return time.Time{}, err
+	}
+	start, end, err := findBox(r, moovStart, moovEnd, "mvhd")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	header := make([]byte, 12)
+	if end-start < int64(len(header)) {
+		return time.Time{}, errors.New("truncated movie header")
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return time.Time{}, err
+	}
+	if _, err := io.ReadFull(r, header); err != nil {
+		return time.Time{}, err
+	}
+
+	// Version 1 headers use 64-bit times
+	var seconds uint64
+	if header[0] == 1 {
+		seconds = binary.BigEndian.Uint64(header[4:12])
+	} else {
+		seconds = uint64(binary.BigEndian.Uint32(header[4:8]))
+	}
+	if seconds == 0 {
+		return time.Time{}, fmt.Errorf("%w in movie header", ErrNoDate)
+	}
+	created := movieEpoch.Add(time.Duration(seconds) * time.Second)
+
+	// Phones record UTC and where the video was taken
+	if noGPS {
+		return created, nil
+	}
+	if lat, lon, ok := movieLocation(r, moovStart, moovEnd); ok {
+		return localTime(created, lat, lon), nil
+	}
+	return created, nil
+}
+
+// findBox returns the content bounds of the first box of type boxType
+// between start and end
+func findBox(r io.ReadSeeker, start, end int64, boxType string) (int64, int64, error) {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return 0, 0, err
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return 0, 0, err
+		}
+
+		boxSize, headerSize := int64(binary.BigEndian.Uint32(header[:4])), int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = end - offset // The box extends to the end
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return 0, 0, err
+			}
+			boxSize, headerSize = int64(binary.BigEndian.Uint64(header[8:16])), 16
+		}
+		// Compared without adding to the offset, which 64-bit sizes would overflow
+		if boxSize < headerSize || boxSize > end-offset {
+			return 0, 0, fmt.Errorf("invalid %s box at %d", header[4:8], offset)
+		}
+
+		if string(header[4:8]) == boxType {
+			return offset + headerSize, offset + boxSize, nil
+		}
+		offset += boxSize
+	}
+	return 0, 0, fmt.Errorf("no %s box found", boxType)
+}
+
+// proxyDestination returns where a proxy taken at date is organized: next to
+// the other files of the day, or below the proxies tree when routed
+func proxyDestination(p *models.Params, source string, date time.Time) string {
+	destPath := destinationPath(p, source, date)
+	if p.Proxies != ProxiesRoute {
+		return destPath
+	}
+	root := destinationRoot(p, date)
+	rel, err := filepath.Rel(root, destPath)
+	if err != nil {
+		return destPath
+	}
+	return filepath.Join(root, ProxiesDir, rel)
+}
+
+// skipProxy leaves out a proxy under the skip policy of -proxies, reporting
+// whether it did
+func (r *mediaRun) skipProxy(entry ReportEntry, proxy bool, summary *ProcessingSummary) bool {
+	if !proxy || r.p.Proxies != ProxiesSkip {
+		return false
+	}
+	summary.skip(SkipFiltered)
+	output.Status("SKIPPED", fmt.Sprintf("Low-resolution proxy: %s", entry.Source))
+	entry.Status, entry.Reason, entry.SkipReason = ReportSkipped, "low-resolution proxy", SkipFiltered
+	r.finish(entry)
+	return true
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// isoBox builds an ISO base media box
+func isoBox(boxType string, content []byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(content)))
+	return append(append(box, boxType...), content...)
+}
+
+// createTestMovie builds a minimal MP4 file created at date
+func createTestMovie(date time.Time) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[4:8], uint32(date.Sub(movieEpoch)/time.Second))
+	movie := isoBox("ftyp", []byte("mp42\x00\x00\x00\x00"))
+	return append(movie, isoBox("moov", isoBox("mvhd", mvhd))...)
+}
+
+func TestMovieCreationTime(t *testing.T) {
+	date := time.Date(2024, time.June, 11, 15, 30, 10, 0, time.UTC)
+
+	got, err := movieCreationTime(bytes.NewReader(createTestMovie(date)), false)
+	if err != nil {
+		t.Fatalf("movieCreationTime() error = %v", err)
+	}
+	if !got.Equal(date) {
+		t.Errorf("movieCreationTime() = %v, want %v", got, date)
+	}
+
+	if _, err := movieCreationTime(bytes.NewReader(isoBox("ftyp", []byte("mp42"))), false); err == nil {
+		t.Error("movieCreationTime() expected an error without movie header")
+	}
+}
+
+func TestProxyParentStems(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"GL010001.LRV", []string{"GL010001", "GX010001", "GH010001"}},
+		{"GX010001.THM", []string{"GX010001"}},
+		{"DJI_0001.LRV", []string{"DJI_0001"}},
+		{"LRV_20240611_153000_01_001.insv", []string{"LRV_20240611_153000_01_001", "VID_20240611_153000_00_001"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := proxyParentStems(tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("proxyParentStems(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesProxies(t *testing.T) {
+	videoDate := time.Date(2024, time.June, 11, 15, 30, 10, 0, time.UTC)
+	proxyDate := time.Date(2024, time.June, 12, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		policy string
+		want   []string // Expected files, relative to the destination
+	}{
+		{"", nil},
+		{ProxiesSkip, nil},
+		{ProxiesKeep, []string{"2024/06-11/GL010001.LRV", "2024/06-12/DJI_0001.LRV"}},
+		{ProxiesRoute, []string{"proxies/2024/06-11/GL010001.LRV", "proxies/2024/06-12/DJI_0001.LRV"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			source := t.TempDir()
+			destination := t.TempDir()
+
+			// A GoPro proxy dated by its video, and a DJI proxy whose video was removed
+			files := map[string][]byte{
+				"GX010001.MP4": createTestMovie(videoDate),
+				"GL010001.LRV": createTestMovie(proxyDate),
+				"DJI_0001.LRV": createTestMovie(proxyDate),
+			}
+			for name, data := range files {
+				if err := os.WriteFile(filepath.Join(source, name), data, 0644); err != nil {
+					t.Fatalf("Failed to create test file: %v", err)
+				}
+			}
+
+			params := &models.Params{
+				Source:      source,
+				Destination: destination,
+				Compression: -1,
+				Proxies:     tt.policy,
+			}
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+
+			var got []string
+			err = filepath.Walk(destination, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					rel, _ := filepath.Rel(destination, path)
+					got = append(got, filepath.ToSlash(rel))
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatalf("Failed to list destination: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Destination files = %v, want %v", got, tt.want)
+			}
+
+			wantSkipped := 0
+			if tt.policy == ProxiesSkip {
+				wantSkipped = 2
+			}
+			if summary.Skipped != wantSkipped {
+				t.Errorf("Skipped = %d, want %d", summary.Skipped, wantSkipped)
+			}
+		})
+	}
+}
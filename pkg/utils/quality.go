@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// AdvisedQualities are the JPEG qualities compared by the compression advisor
+var AdvisedQualities = []int{50, 60, 70, 75, 80, 85, 90, 95}
+
+// DefaultSSIMTarget is the similarity to the original below which a
+// recompressed picture is considered visibly degraded
+const DefaultSSIMTarget = 0.98
+
+// ssimWindow is the side of the square windows SSIM is computed on
+const ssimWindow = 8
+
+// QualityScore measures a picture recompressed at a JPEG quality
+type QualityScore struct {
+	Quality int
+	Size    int64   // Size of the recompressed file
+	Ratio   float64 // Size relative to the original file
+	SSIM    float64 // Structural similarity to the original, 1 when identical
+}
+
+// ScoreQualities recompresses a JPEG file at each quality, the way imports
+// do, and scores the result against the original picture
+func ScoreQualities(data []byte, qualities []int) ([]QualityScore, error) {
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	original := lumaPlane(img)
+
+	scores := make([]QualityScore, 0, len(qualities))
+	for _, quality := range qualities {
+		encoded, err := encodeJPEG(img, quality)
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := jpeg.Decode(bytes.NewReader(encoded))
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, QualityScore{
+			Quality: quality,
+			Size:    int64(len(encoded)),
+			Ratio:   float64(len(encoded)) / float64(len(data)),
+			SSIM:    ssim(original, lumaPlane(decoded)),
+		})
+	}
+	return scores, nil
+}
+
+// RecommendQuality returns the lowest quality keeping every sample at least
+// as similar to its original as target, scores holding the scores of each
+// sample for the same qualities. It returns false when no quality does, or
+// when the quality recommended would not make the samples smaller.
+func RecommendQuality(scores [][]QualityScore, target float64) (int, bool) {
+	if len(scores) == 0 {
+		return 0, false
+	}
+	for i, score := range scores[0] {
+		ratio := 0.0
+		ok := true
+		for _, sample := range scores {
+			if i >= len(sample) || sample[i].Quality != score.Quality || sample[i].SSIM < target {
+				ok = false
+				break
+			}
+			ratio += sample[i].Ratio
+		}
+		if ok {
+			return score.Quality, ratio/float64(len(scores)) < 1
+		}
+	}
+	return 0, false
+}
+
+// plane is the luma of a picture, the channel SSIM is computed on
+type plane struct {
+	pix           []uint8
+	width, height int
+}
+
+// lumaPlane returns the luma of a picture. JPEG pictures decode to YCbCr or
+// gray images, whose luma is read as is.
+func lumaPlane(img image.Image) plane {
+	b := img.Bounds()
+	p := plane{pix: make([]uint8, b.Dx()*b.Dy()), width: b.Dx(), height: b.Dy()}
+	for y := 0; y < p.height; y++ {
+		row := p.pix[y*p.width : (y+1)*p.width]
+		switch img := img.(type) {
+		case *image.YCbCr:
+			offset := img.YOffset(b.Min.X, b.Min.Y+y)
+			copy(row, img.Y[offset:offset+p.width])
+		case *image.Gray:
+			offset := img.PixOffset(b.Min.X, b.Min.Y+y)
+			copy(row, img.Pix[offset:offset+p.width])
+		default:
+			for x := range row {
+				row[x] = color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+			}
+		}
+	}
+	return p
+}
+
+// ssim returns the mean structural similarity of two planes, over square
+// windows of their common area
+func ssim(a, b plane) float64 {
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+	width, height := min(a.width, b.width), min(a.height, b.height)
+
+	var total float64
+	windows := 0
+	for y := 0; y+ssimWindow <= height; y += ssimWindow {
+		for x := 0; x+ssimWindow <= width; x += ssimWindow {
+			var sumA, sumB, sumAA, sumBB, sumAB float64
+			for dy := 0; dy < ssimWindow; dy++ {
+				offsetA, offsetB := (y+dy)*a.width+x, (y+dy)*b.width+x
+				for dx := 0; dx < ssimWindow; dx++ {
+					va, vb := float64(a.pix[offsetA+dx]), float64(b.pix[offsetB+dx])
+					sumA += va
+					sumB += vb
+					sumAA += va * va
+					sumBB += vb * vb
+					sumAB += va * vb
+				}
+			}
+			const n = ssimWindow * ssimWindow
+			meanA, meanB := sumA/n, sumB/n
+			varA, varB := sumAA/n-meanA*meanA, sumBB/n-meanB*meanB
+			covariance := sumAB/n - meanA*meanB
+			total += (2*meanA*meanB + c1) * (2*covariance + c2) / ((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			windows++
+		}
+	}
+	if windows == 0 {
+		return 1 // Pictures smaller than a window are not scored
+	}
+	return total / float64(windows)
+}
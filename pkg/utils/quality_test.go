@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// createDetailedImage returns a picture mixing smooth gradients and fine
+// detail, whose quality visibly drops at low JPEG qualities
+func createDetailedImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 128, 96))
+	for y := 0; y < 96; y++ {
+		for x := 0; x < 128; x++ {
+			detail := uint8((x*7 + y*13) % 32 * 4)
+			img.Set(x, y, color.RGBA{R: uint8(x * 2), G: uint8(y*2) + detail, B: detail, A: 255})
+		}
+	}
+	return img
+}
+
+func TestSSIM(t *testing.T) {
+	img := createDetailedImage()
+	luma := lumaPlane(img)
+	inverted := image.NewGray(img.Bounds())
+	for i := range inverted.Pix {
+		inverted.Pix[i] = 255 - luma.pix[i]
+	}
+
+	tests := []struct {
+		name     string
+		b        image.Image
+		min, max float64
+	}{
+		{"identical", img, 1, 1},
+		{"inverted", inverted, -1, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ssim(luma, lumaPlane(tt.b))
+			if got < tt.min-1e-9 || got > tt.max+1e-9 {
+				t.Errorf("ssim() = %v, want between %v and %v", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestScoreQualities(t *testing.T) {
+	var original bytes.Buffer
+	if err := jpeg.Encode(&original, createDetailedImage(), &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	scores, err := ScoreQualities(original.Bytes(), []int{30, 60, 95})
+	if err != nil {
+		t.Fatalf("ScoreQualities() error = %v", err)
+	}
+	if len(scores) != 3 {
+		t.Fatalf("ScoreQualities() = %+v, want 3 scores", scores)
+	}
+	for i := 1; i < len(scores); i++ {
+		if scores[i].SSIM <= scores[i-1].SSIM || scores[i].Size <= scores[i-1].Size {
+			t.Errorf("quality %d scores %+v, not above quality %d scores %+v", scores[i].Quality, scores[i], scores[i-1].Quality, scores[i-1])
+		}
+	}
+	if last := scores[len(scores)-1]; last.SSIM > 1 || last.Ratio >= 1 {
+		t.Errorf("quality 95 scores %+v, want a smaller file similar to the original", last)
+	}
+
+	if _, err := ScoreQualities([]byte("not a JPEG file"), []int{80}); err == nil {
+		t.Error("ScoreQualities() expected an error for a file that is not a JPEG file")
+	}
+}
+
+func TestRecommendQuality(t *testing.T) {
+	sample := func(ssims ...float64) []QualityScore {
+		scores := make([]QualityScore, len(ssims))
+		for i, value := range ssims {
+			scores[i] = QualityScore{Quality: 70 + 10*i, Ratio: 0.3 + 0.5*float64(i), SSIM: value}
+		}
+		return scores
+	}
+
+	tests := []struct {
+		name        string
+		scores      [][]QualityScore
+		want        int
+		wantSmaller bool
+	}{
+		{"every sample above the target", [][]QualityScore{sample(0.985, 0.99, 0.995)}, 70, true},
+		{"worst sample decides", [][]QualityScore{sample(0.985, 0.99, 0.995), sample(0.95, 0.97, 0.99)}, 90, false},
+		{"no quality reaches the target", [][]QualityScore{sample(0.9, 0.92, 0.95)}, 0, false},
+		{"no samples", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, smaller := RecommendQuality(tt.scores, 0.98)
+			if got != tt.want || smaller != tt.wantSmaller {
+				t.Errorf("RecommendQuality() = %d, %v, want %d, %v", got, smaller, tt.want, tt.wantSmaller)
+			}
+		})
+	}
+}
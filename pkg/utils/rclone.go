@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RclonePrefix marks destinations handled by rclone, such as rclone:gdrive:Photos
+const RclonePrefix = "rclone:"
+
+// rcloneDirNotFound is the exit code of rclone when a remote directory does not exist
+const rcloneDirNotFound = 3
+
+// For testing purposes
+var runRclone = func(args ...string) ([]byte, error) {
+	return exec.Command("rclone", args...).Output()
+}
+
+// IsRcloneDestination reports whether a destination is an rclone remote path
+func IsRcloneDestination(destination string) bool {
+	return strings.HasPrefix(destination, RclonePrefix)
+}
+
+// RcloneRemote returns the rclone remote path of a destination, such as gdrive:Photos
+func RcloneRemote(destination string) string {
+	return strings.TrimPrefix(destination, RclonePrefix)
+}
+
+// ListRemoteFiles returns the paths of the files below an rclone remote path,
+// relative to it with forward slashes. A missing remote directory has no files.
+func ListRemoteFiles(remote string) ([]string, error) {
+	out, err := runRclone("lsf", "-R", "--files-only", remote)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == rcloneDirNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", remote, rcloneError(err))
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// CreatePlaceholders creates an empty file in dir for each remote file, so a
+// run organizing into dir skips the files already uploaded like files already
+// at a local destination. It returns the paths of the placeholders.
+func CreatePlaceholders(dir string, files []string) ([]string, error) {
+	var placeholders []string
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return placeholders, err
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return placeholders, err
+		}
+		placeholders = append(placeholders, path)
+	}
+	return placeholders, nil
+}
+
+// UploadToRemote copies the files organized in dir to an rclone remote path,
+// never replacing files already there
+func UploadToRemote(dir, remote string) error {
+	if _, err := runRclone("copy", "--ignore-existing", dir, remote); err != nil {
+		return fmt.Errorf("failed to upload to %s: %w", remote, rcloneError(err))
+	}
+	return nil
+}
+
+// rcloneError adds the error output of rclone to err
+func rcloneError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRclone replaces rclone for the duration of a test, recording its arguments
+func fakeRclone(t *testing.T, out string, err error) *[][]string {
+	t.Helper()
+	var calls [][]string
+	original := runRclone
+	runRclone = func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return []byte(out), err
+	}
+	t.Cleanup(func() { runRclone = original })
+	return &calls
+}
+
+func TestRcloneRemote(t *testing.T) {
+	if !IsRcloneDestination("rclone:gdrive:Photos") || IsRcloneDestination("/photos") {
+		t.Error("IsRcloneDestination() misdetected destinations")
+	}
+	if got := RcloneRemote("rclone:gdrive:Photos"); got != "gdrive:Photos" {
+		t.Errorf("RcloneRemote() = %q, want gdrive:Photos", got)
+	}
+}
+
+func TestListRemoteFiles(t *testing.T) {
+	calls := fakeRclone(t, "2025/01-11/a.jpg\r\n2025/01-12/b.arw\n", nil)
+
+	files, err := ListRemoteFiles("gdrive:Photos")
+	if err != nil {
+		t.Fatalf("ListRemoteFiles() error = %v", err)
+	}
+	if want := []string{"2025/01-11/a.jpg", "2025/01-12/b.arw"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("ListRemoteFiles() = %v, want %v", files, want)
+	}
+	if want := []string{"lsf", "-R", "--files-only", "gdrive:Photos"}; !reflect.DeepEqual((*calls)[0], want) {
+		t.Errorf("rclone arguments = %v, want %v", (*calls)[0], want)
+	}
+
+	fakeRclone(t, "", errors.New("no remote"))
+	if _, err := ListRemoteFiles("missing:"); err == nil {
+		t.Error("ListRemoteFiles() expected error")
+	}
+}
+
+func TestCreatePlaceholders(t *testing.T) {
+	dir := t.TempDir()
+
+	placeholders, err := CreatePlaceholders(dir, []string{"2025/01-11/a.jpg"})
+	if err != nil {
+		t.Fatalf("CreatePlaceholders() error = %v", err)
+	}
+	want := filepath.Join(dir, "2025", "01-11", "a.jpg")
+	if len(placeholders) != 1 || placeholders[0] != want {
+		t.Errorf("CreatePlaceholders() = %v, want [%s]", placeholders, want)
+	}
+	if info, err := os.Stat(want); err != nil || info.Size() != 0 {
+		t.Errorf("Expected empty placeholder: %v", err)
+	}
+}
+
+func TestUploadToRemote(t *testing.T) {
+	calls := fakeRclone(t, "", nil)
+	if err := UploadToRemote("/tmp/staging", "gdrive:Photos"); err != nil {
+		t.Fatalf("UploadToRemote() error = %v", err)
+	}
+	if got := strings.Join((*calls)[0], " "); got != "copy --ignore-existing /tmp/staging gdrive:Photos" {
+		t.Errorf("rclone arguments = %s", got)
+	}
+}
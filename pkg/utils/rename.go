@@ -0,0 +1,310 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// SequenceStateFile is the path, relative to the destination, of the numbers
+// given by the {seq} token to imported files
+var SequenceStateFile = filepath.Join(".organize-media", "sequences.json")
+
+// seqDigits is the least number of digits of {seq} numbers: 0001, 0002...
+const seqDigits = 4
+
+// nameTokens resolve the tokens of name templates: those of layouts, {name}
+// for the original name and {seq} for the number of the file in its day
+var nameTokens = func() map[string]func(f layoutFile) string {
+	tokens := map[string]func(f layoutFile) string{
+		"name": func(f layoutFile) string { return f.stem },
+		"seq":  func(f layoutFile) string { return fmt.Sprintf("%0*d", seqDigits, f.seq) },
+	}
+	for name, resolve := range layoutTokens {
+		tokens[name] = resolve
+	}
+	return tokens
+}()
+
+// NameTemplate is a parsed template of the names of organized files, such as
+// {year}{month}{day}_{seq}: tokens are replaced like those of layouts, {name}
+// with the original name without extension and {seq} with the number of the
+// file in its destination day. Files keep their extension.
+type NameTemplate struct {
+	text     string
+	parts    []layoutPart
+	calendar *HolidayCalendar  // Calendar of {weekday} and {holiday}, that of the language when nil
+	tags     map[string]string // Tags of the run, for {tag:<key>}
+}
+
+// ParseNameTemplate parses a name template, rejecting unknown tokens and
+// templates naming folders
+func ParseNameTemplate(text string) (*NameTemplate, error) {
+	if text == "" {
+		return nil, fmt.Errorf("empty name template")
+	}
+	if strings.ContainsAny(text, `/\`) {
+		return nil, fmt.Errorf("name template must not contain folders: %s", text)
+	}
+	parts, err := parseTemplate(text, "name template", nameTokens)
+	if err != nil {
+		return nil, err
+	}
+	return &NameTemplate{text: text, parts: parts}, nil
+}
+
+// String returns the text of the template
+func (t *NameTemplate) String() string {
+	return t.text
+}
+
+// numbered reports whether the names given by the template hold {seq}
+func (t *NameTemplate) numbered() bool {
+	for _, part := range t.parts {
+		if part.token == "seq" {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the new name of the file at path taken at date, named name
+// so far, with its number in its destination day
+func (t *NameTemplate) name(path, name string, date time.Time, seq int) string {
+	ext := filepath.Ext(name)
+	f := layoutFile{path: path, date: date, calendar: t.calendar, stem: strings.TrimSuffix(name, ext), seq: seq, tags: t.tags}
+	return renderTemplate(t.parts, nameTokens, f) + ext
+}
+
+// seqPattern returns the expression matching the names given by the template
+// after prefix, capturing their number
+func (t *NameTemplate) seqPattern(prefix string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)^" + prefix)
+	captured := false
+	for _, part := range t.parts {
+		switch {
+		case part.token == "":
+			b.WriteString(regexp.QuoteMeta(part.literal))
+		case part.token == "seq" && !captured:
+			fmt.Fprintf(&b, `(\d{%d,})`, seqDigits)
+			captured = true
+		case part.token == "seq":
+			fmt.Fprintf(&b, `\d{%d,}`, seqDigits)
+		default:
+			b.WriteString(".*?")
+		}
+	}
+	b.WriteString(`(\.[^.]*)?$`)
+	return regexp.MustCompile(b.String())
+}
+
+// nameTemplateOf returns the name template of a run, nil when files keep their name
+func nameTemplateOf(p *models.Params) (*NameTemplate, error) {
+	if p.Rename == "" {
+		return nil, nil
+	}
+	t, err := ParseNameTemplate(p.Rename)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTemplateTags(t.parts, p.RunTags, "name template", p.Rename); err != nil {
+		return nil, err
+	}
+	t.calendar = holidayCalendarOf(p.Holidays)
+	t.tags = p.RunTags
+	return t, nil
+}
+
+// sequenceDay holds the {seq} numbers given to the files of a destination day
+type sequenceDay struct {
+	Last  int            `json:"last"`
+	Files map[string]int `json:"files"` // Keyed by name, size and modification time of the source file
+}
+
+// SequenceState remembers the {seq} numbers given to files, per destination
+// day, so files keep their number when an interrupted import is resumed or a
+// card is imported again. It is stored in the destination.
+type SequenceState struct {
+	path    string
+	days    map[string]*sequenceDay // Keyed by day folder and date
+	scanned map[string]bool         // Days whose folder was searched for numbered names by this run
+	dirty   bool
+}
+
+// loadSequenceState reads the sequence state stored in the destination. A
+// missing state file yields an empty state.
+func loadSequenceState(destination string) (*SequenceState, error) {
+	state := &SequenceState{
+		path:    filepath.Join(destination, SequenceStateFile),
+		days:    make(map[string]*sequenceDay),
+		scanned: make(map[string]bool),
+	}
+
+	data, err := os.ReadFile(state.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read sequence state: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &state.days); err != nil {
+			return nil, fmt.Errorf("failed to parse sequence state %s: %w", state.path, err)
+		}
+	}
+	return state, nil
+}
+
+// Save writes the state back to the destination if numbers were given
+func (s *SequenceState) Save() error {
+	if s == nil || !s.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(s.days)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), os.ModePerm); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sequence state: %w", err)
+	}
+
+	s.dirty = false
+	return nil
+}
+
+// number returns the number of a shot of a day, the one given to one of its
+// files before or the next one of the day, last returning the highest number
+// found in the names of the day folder
+func (s *SequenceState) number(day string, files []string, last func() int) int {
+	d := s.days[day]
+	if d == nil {
+		d = &sequenceDay{Files: make(map[string]int)}
+		s.days[day] = d
+	}
+	// Files written since the last run, by this tool or another one, are not numbered again
+	if !s.scanned[day] {
+		s.scanned[day] = true
+		d.Last = max(d.Last, last())
+	}
+
+	keys := make([]string, 0, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			keys = append(keys, fmt.Sprintf("%s %d %d", filepath.Base(file), info.Size(), info.ModTime().UnixNano()))
+		}
+	}
+
+	seq := 0
+	for _, key := range keys {
+		if n, ok := d.Files[key]; ok {
+			seq = n
+			break
+		}
+	}
+	if seq == 0 {
+		d.Last++
+		seq = d.Last
+	}
+	for _, key := range keys {
+		if d.Files[key] != seq {
+			d.Files[key] = seq
+			s.dirty = true
+		}
+	}
+	return seq
+}
+
+// renameShots gives the dated shots of the source their names from the name
+// template, companions sharing the same number. Shots are numbered per
+// destination day in chronological order, after those already numbered in the
+// state or in the names of the day folder on fsys.
+func renameShots(fsys FileSystem, p *models.Params, template *NameTemplate, shots map[string][]*shot, names map[string]string, state *SequenceState) {
+	layout, _ := layoutOf(p)
+
+	type day struct {
+		dir   string
+		date  time.Time
+		shots []*shot
+	}
+	days := make(map[string]*day)
+	for _, dirShots := range shots {
+		for _, s := range dirShots {
+			if !s.dated {
+				continue
+			}
+			dir := filepath.Dir(destinationPath(p, s.files[0], s.date))
+			key := dir
+			if rel, err := filepath.Rel(p.Destination, dir); err == nil {
+				key = filepath.ToSlash(rel)
+			}
+			key += " " + s.date.Format("2006-01-02")
+			if days[key] == nil {
+				days[key] = &day{dir: dir, date: s.date}
+			}
+			days[key].shots = append(days[key].shots, s)
+		}
+	}
+
+	keys := make([]string, 0, len(days))
+	for key := range days {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		d := days[key]
+		sort.Slice(d.shots, func(i, j int) bool {
+			a, b := d.shots[i], d.shots[j]
+			if !a.date.Equal(b.date) {
+				return a.date.Before(b.date)
+			}
+			return a.files[0] < b.files[0]
+		})
+
+		// Flat layouts prefix names with the date, within the destination itself
+		prefix := ""
+		if layout.flat {
+			prefix = regexp.QuoteMeta(d.date.Format("2006-01-02_")) + `\d{6}_`
+		}
+		pattern := template.seqPattern(prefix)
+		last := func() int { return lastSeq(fsys, d.dir, pattern) }
+
+		for _, s := range d.shots {
+			seq := 0
+			if template.numbered() {
+				seq = state.number(key, s.files, last)
+			}
+			for _, path := range s.files {
+				placed, ok := names[path]
+				if !ok {
+					placed = filepath.Base(path)
+				}
+				names[path] = filepath.Join(filepath.Dir(placed), template.name(path, filepath.Base(placed), s.date, seq))
+			}
+		}
+	}
+}
+
+// lastSeq returns the highest number of the names of dir matching pattern
+func lastSeq(fsys FileSystem, dir string, pattern *regexp.Regexp) int {
+	entries, _ := fsys.ReadDir(dir)
+	last := 0
+	for _, entry := range entries {
+		if match := pattern.FindStringSubmatch(entry.Name()); match != nil {
+			if n, err := strconv.Atoi(match[1]); err == nil {
+				last = max(last, n)
+			}
+		}
+	}
+	return last
+}
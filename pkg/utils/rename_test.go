@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestParseNameTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  string // Part of the expected error, none for valid templates
+	}{
+		{template: "{year}{month}{day}_{seq}"},
+		{template: "{camera}_{name}"},
+		{template: "{seq}"},
+		{template: "", wantErr: "empty name template"},
+		{template: "{year}/{seq}", wantErr: "must not contain folders"},
+		{template: `{year}\{seq}`, wantErr: "must not contain folders"},
+		{template: "{sequence}", wantErr: "unknown token {sequence} in name template"},
+		{template: "{seq", wantErr: "unclosed {"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			_, err := ParseNameTemplate(tt.template)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ParseNameTemplate(%q) unexpected error: %v", tt.template, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseNameTemplate(%q) error = %v, want it to contain %q", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNameTemplateName(t *testing.T) {
+	date := time.Date(2024, 6, 11, 15, 30, 10, 0, time.UTC)
+
+	tests := []struct {
+		template string
+		name     string
+		seq      int
+		want     string
+	}{
+		{"{year}{month}{day}_{seq}", "DSC00001.ARW", 1, "20240611_0001.ARW"},
+		{"{year}{month}{day}_{seq}", "DSC00001.JPG", 12345, "20240611_12345.JPG"},
+		{"{seq}_{name}", "DSC00001.ARW", 7, "0007_DSC00001.ARW"},
+		{"{name}-{hour}h", "README", 0, "README-15h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			template, err := ParseNameTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("ParseNameTemplate() error = %v", err)
+			}
+			if got := template.name(tt.name, tt.name, date, tt.seq); got != tt.want {
+				t.Errorf("name(%q, %d) = %q, want %q", tt.name, tt.seq, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastSeq(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"20240611_0003.ARW", "20240611_0012.JPG", "20240611_0002", "DSC00099.JPG", "20240611_12.JPG", "20240611_0099.JPG.bak"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	template, err := ParseNameTemplate("{year}{month}{day}_{seq}")
+	if err != nil {
+		t.Fatalf("ParseNameTemplate() error = %v", err)
+	}
+	if got := lastSeq(OSFileSystem{}, dir, template.seqPattern("")); got != 12 {
+		t.Errorf("lastSeq() = %d, want 12", got)
+	}
+	if got := lastSeq(OSFileSystem{}, filepath.Join(dir, "missing"), template.seqPattern("")); got != 0 {
+		t.Errorf("lastSeq() of a missing folder = %d, want 0", got)
+	}
+}
+
+func TestProcessMediaFilesRenameSeq(t *testing.T) {
+	// Two shots of the day, the first one with a RAW companion, and one of the next day
+	source := t.TempDir()
+	files := map[string]string{
+		"DSC00002.JPG": "2025:01:11 10:00:00",
+		"DSC00009.JPG": "2025:01:11 09:00:00",
+		"DSC00009.ARW": "2025:01:11 09:00:00",
+		"DSC00010.JPG": "2025:01:12 08:00:00",
+	}
+	for name, date := range files {
+		if err := os.WriteFile(filepath.Join(source, name), createSerialJPEG(date, "1"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// A file numbered by an earlier import of the day
+	dest := t.TempDir()
+	day := filepath.Join(dest, "2025", "01-11")
+	if err := os.MkdirAll(day, os.ModePerm); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(day, "20250111_0004.JPG"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	want := []string{
+		filepath.Join("2025", "01-11", "20250111_0004.JPG"),
+		filepath.Join("2025", "01-11", "20250111_0005.ARW"),
+		filepath.Join("2025", "01-11", "20250111_0005.JPG"),
+		filepath.Join("2025", "01-11", "20250111_0006.JPG"),
+		filepath.Join("2025", "01-12", "20250112_0001.JPG"),
+	}
+	params := &models.Params{Source: source, Destination: dest, Compression: -1, Rename: "{year}{month}{day}_{seq}"}
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if got := organizedFiles(t, dest); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Organized files = %v, want %v", got, want)
+	}
+
+	// An interrupted import resumed gives files the same numbers
+	if err := os.Remove(filepath.Join(day, "20250111_0006.JPG")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if got := organizedFiles(t, dest); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Organized files after resuming = %v, want %v", got, want)
+	}
+	if summary.Copied != 1 || summary.Skipped != 3 {
+		t.Errorf("Resumed import copied %d and skipped %d files, want 1 and 3", summary.Copied, summary.Skipped)
+	}
+}
+
+// organizedFiles returns the files of the destination, relative to it and
+// sorted, leaving out the state of the tool
+func organizedFiles(t *testing.T, dest string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(dest, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() {
+			rel, _ := filepath.Rel(dest, path)
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk destination: %v", err)
+	}
+	sort.Strings(files)
+	return files
+}
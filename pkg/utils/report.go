@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// Outcomes of a file recorded in the run report
+const (
+	ReportCopied     = "copied"
+	ReportCompressed = "compressed"
+	ReportSkipped    = "skipped"
+	ReportFailed     = "failed"
+	ReportSalvaged   = "salvaged"
+	ReportCulled     = "culled"
+	ReportCorrupt    = "corrupt"
+	ReportRejected   = "rejected"
+)
+
+// ReportEntry records the outcome of one source file
+type ReportEntry struct {
+	Source         string         `json:"source"`
+	Destination    string         `json:"destination,omitempty"`
+	Status         string         `json:"status"`
+	Reason         string         `json:"reason,omitempty"`
+	SkipReason     string         `json:"skip_reason,omitempty"` // Why a skipped file was skipped, see SkipReasons
+	Size           int64          `json:"size"`
+	RecoveredBytes int64          `json:"recovered_bytes,omitempty"`
+	Verified       bool           `json:"verified,omitempty"`       // Destination read back and checked before deleting the source
+	SourceDeleted  bool           `json:"source_deleted,omitempty"` // Source removed after a verified copy
+	Tags           []string       `json:"tags,omitempty"`           // Detected kind, such as timelapse or pano
+	Faces          []FaceRegion   `json:"faces,omitempty"`          // Face regions of the XMP metadata
+	Albums         []string       `json:"albums,omitempty"`         // Google Takeout albums of the file
+	Mirrors        []MirrorResult `json:"mirrors,omitempty"`        // Outcome for each mirror destination
+	ManualDate     bool           `json:"manual_date,omitempty"`    // Capture date assigned manually instead of read from the file
+	Lightroom      bool           `json:"lightroom,omitempty"`      // Imported although already managed in the Lightroom catalog
+	Original       string         `json:"original,omitempty"`       // Where the original of a RAW file converted to DNG is kept
+}
+
+// Report is a machine-readable record of a run, written as JSON at the end of processing
+type Report struct {
+	mu          sync.Mutex
+	Source      string            `json:"source"`
+	Destination string            `json:"destination"`
+	Mirrors     []string          `json:"mirrors,omitempty"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  time.Time         `json:"finished_at"`
+	RunTags     map[string]string `json:"run_tags,omitempty"` // Tags given to the run, such as client=smith
+	Build       BuildInfo         `json:"build"`              // Build of the tool that ran the import
+	Skips       map[string]int    `json:"skips,omitempty"`    // Skipped files by reason
+	Files       []ReportEntry     `json:"files"`
+}
+
+// NewReport returns an empty report for a run with the given parameters
+func NewReport(p *models.Params) *Report {
+	return &Report{
+		Source:      p.Source,
+		Destination: p.Destination,
+		Mirrors:     p.Mirrors,
+		StartedAt:   time.Now(),
+		RunTags:     p.RunTags,
+		Build:       CurrentBuild(),
+		Files:       []ReportEntry{},
+	}
+}
+
+// Add records the outcome of a file. A nil report ignores entries.
+func (r *Report) Add(entry ReportEntry) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Files = append(r.Files, entry)
+}
+
+// reportErrorStatuses are the outcomes of files worth importing again once
+// their cause, such as permissions or a full disk, is fixed
+var reportErrorStatuses = map[string]bool{
+	ReportFailed:   true,
+	ReportSkipped:  true,
+	ReportCorrupt:  true,
+	ReportSalvaged: true,
+}
+
+// SourceFiles returns the source files of the report, only those that failed
+// or were skipped when onlyErrors is true
+func (r *Report) SourceFiles(onlyErrors bool) []string {
+	var files []string
+	for _, entry := range r.Files {
+		if !onlyErrors || reportErrorStatuses[entry.Status] {
+			files = append(files, entry.Source)
+		}
+	}
+	return files
+}
+
+// Write saves the report as indented JSON to path
+func (r *Report) Write(path string) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = time.Now()
+	r.Skips = nil
+	for _, entry := range r.Files {
+		if entry.SkipReason == "" {
+			continue
+		}
+		if r.Skips == nil {
+			r.Skips = make(map[string]int)
+		}
+		r.Skips[entry.SkipReason]++
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// LoadReport reads a report written by a run
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// readTestReport decodes a report written by a run
+func readTestReport(t *testing.T, path string) *Report {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	report := &Report{}
+	if err := json.Unmarshal(data, report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	return report
+}
+
+func TestReport(t *testing.T) {
+	t.Run("nil report", func(t *testing.T) {
+		var report *Report
+		report.Add(ReportEntry{Source: "a.jpg"})
+		if err := report.Write(filepath.Join(t.TempDir(), "report.json")); err != nil {
+			t.Errorf("Write() on nil report error = %v", err)
+		}
+	})
+
+	t.Run("write entries", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "reports", "run.json")
+		report := NewReport(&models.Params{Source: "/card", Destination: "/photos", RunTags: map[string]string{"job": "wedding2024"}})
+		report.Add(ReportEntry{Source: "/card/a.jpg", Destination: "/photos/2025/01-11/a.jpg", Status: ReportCopied, Size: 10})
+		report.Add(ReportEntry{Source: "/card/b.jpg", Status: ReportSkipped, Reason: "no date", Size: 20})
+
+		if err := report.Write(path); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+
+		got := readTestReport(t, path)
+		if got.Source != "/card" || got.Destination != "/photos" {
+			t.Errorf("report paths = %s, %s", got.Source, got.Destination)
+		}
+		if got.RunTags["job"] != "wedding2024" {
+			t.Errorf("report run tags = %v", got.RunTags)
+		}
+		if len(got.Files) != 2 || got.Files[1].Reason != "no date" {
+			t.Errorf("report files = %+v", got.Files)
+		}
+		if got.FinishedAt.Before(got.StartedAt) {
+			t.Errorf("finished_at %v before started_at %v", got.FinishedAt, got.StartedAt)
+		}
+	})
+
+	t.Run("source files", func(t *testing.T) {
+		report := &Report{Files: []ReportEntry{
+			{Source: "/card/a.jpg", Status: ReportCopied},
+			{Source: "/card/b.jpg", Status: ReportFailed},
+			{Source: "/card/c.jpg", Status: ReportSkipped},
+			{Source: "/card/d.jpg", Status: ReportCulled},
+			{Source: "/card/e.jpg", Status: ReportCorrupt},
+		}}
+		if got, want := report.SourceFiles(false), []string{"/card/a.jpg", "/card/b.jpg", "/card/c.jpg", "/card/d.jpg", "/card/e.jpg"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("SourceFiles(false) = %v, want %v", got, want)
+		}
+		if got, want := report.SourceFiles(true), []string{"/card/b.jpg", "/card/c.jpg", "/card/e.jpg"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("SourceFiles(true) = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("run outcomes", func(t *testing.T) {
+		sourceDir := t.TempDir()
+		destDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(sourceDir, "nodate.nef"), []byte("raw data"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		reportFile := filepath.Join(t.TempDir(), "report.json")
+		params := &models.Params{Source: sourceDir, Destination: destDir, Compression: -1, ReportFile: reportFile}
+		if _, err := ProcessMediaFiles(params); err != nil {
+			t.Fatalf("ProcessMediaFiles() unexpected error: %v", err)
+		}
+
+		statuses := make(map[string]string)
+		for _, entry := range readTestReport(t, reportFile).Files {
+			statuses[filepath.Base(entry.Source)] = entry.Status
+		}
+		if statuses["photo.jpg"] != ReportCopied || statuses["nodate.nef"] != ReportSkipped {
+			t.Errorf("report statuses = %v", statuses)
+		}
+	})
+
+	t.Run("verified deletion", func(t *testing.T) {
+		sourceDir := t.TempDir()
+		destDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		reportFile := filepath.Join(t.TempDir(), "report.json")
+		params := &models.Params{Source: sourceDir, Destination: destDir, Compression: -1, DeleteSource: true, ReportFile: reportFile}
+		summary, err := ProcessMediaFiles(params)
+		if err != nil {
+			t.Fatalf("ProcessMediaFiles() unexpected error: %v", err)
+		}
+		if summary.Verified != 1 || summary.Deleted != 1 {
+			t.Errorf("Expected 1 verified and deleted file, got %+v", summary)
+		}
+
+		files := readTestReport(t, reportFile).Files
+		if len(files) != 1 || !files[0].Verified || !files[0].SourceDeleted {
+			t.Errorf("report files = %+v", files)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// DamagedDir is the destination folder receiving the readable part of damaged files
+const DamagedDir = "damaged"
+
+// salvagePartialFile writes the readable prefix of a file that failed mid-read
+// to the damaged folder of the destination, and returns the written path.
+// Existing files are never overwritten and the source is always kept.
+func salvagePartialFile(fsys FileSystem, p *models.Params, source string, partial []byte) (string, error) {
+	return isolateFile(fsys, p, DamagedDir, source, bufferPayload(partial))
+}
+
+// isolateFile writes data under the name of source to a folder of the
+// destination kept apart from organized files, and returns the written path.
+// Existing files are never overwritten.
+func isolateFile(fsys FileSystem, p *models.Params, dir string, source string, data payload) (string, error) {
+	// Isolated files are rare, the key is only read when one is found
+	enc, err := loadEncryptor(p)
+	if err != nil {
+		return "", err
+	}
+	destPath := enc.Path(filepath.Join(p.Destination, dir, filepath.Base(source)))
+	if enc != nil {
+		plain, err := data.bytes()
+		if err != nil {
+			return "", err
+		}
+		sealed, err := enc.Seal(filepath.Base(source), plain)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt file: %w", err)
+		}
+		data = bufferPayload(sealed)
+	}
+
+	if exists, err := fileExists(fsys, destPath); err != nil {
+		return "", fmt.Errorf("failed to check destination file: %w", err)
+	} else if exists {
+		return "", fmt.Errorf("destination file already exists: %s", destPath)
+	}
+
+	if err := fsys.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+		return "", err
+	}
+	if _, err := writeMediaFile(fsys, destPath, func(file File) (int64, error) { return data.writeTo(file) }); err != nil {
+		return "", err
+	}
+	return destPath, nil
+}
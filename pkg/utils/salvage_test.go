@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// failingReader returns its data, then a read error as a failing card would
+type failingReader struct {
+	r io.Reader
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("input/output error")
+	}
+	return n, err
+}
+
+func (f *failingReader) Close() error { return nil }
+
+// mockFailingRead makes every opened file fail after its first half
+func mockFailingRead() func() {
+	original := openFile
+	openFile = func(fsys FileSystem, name string) (io.ReadCloser, error) {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		return &failingReader{r: bytes.NewReader(data[:len(data)/2])}, nil
+	}
+	return func() { openFile = original }
+}
+
+func TestSalvagePartialFile(t *testing.T) {
+	destDir := t.TempDir()
+	params := &models.Params{Destination: destDir}
+
+	path, err := salvagePartialFile(OSFileSystem{}, params, filepath.Join("card", "DSC00001.ARW"), []byte("partial"))
+	if err != nil {
+		t.Fatalf("salvagePartialFile() unexpected error: %v", err)
+	}
+	if want := filepath.Join(destDir, DamagedDir, "DSC00001.ARW"); path != want {
+		t.Errorf("salvagePartialFile() path = %s, want %s", path, want)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "partial" {
+		t.Errorf("salvaged content = %q, %v, want %q", data, err, "partial")
+	}
+
+	// An existing salvaged file is never overwritten
+	if _, err := salvagePartialFile(OSFileSystem{}, params, filepath.Join("card", "DSC00001.ARW"), []byte("other")); err == nil {
+		t.Error("salvagePartialFile() expected error for existing file")
+	}
+}
+
+func TestProcessMediaFilesSalvage(t *testing.T) {
+	tests := []struct {
+		name         string
+		salvage      bool
+		wantSalvaged int
+		wantSkipped  int
+		wantStatus   string
+	}{
+		{name: "salvage enabled", salvage: true, wantSalvaged: 1, wantStatus: ReportSalvaged},
+		{name: "salvage disabled", salvage: false, wantSkipped: 1, wantStatus: ReportSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir := t.TempDir()
+			destDir := t.TempDir()
+			source := filepath.Join(sourceDir, "photo.jpg")
+			data := createFakeExifData()
+			if err := os.WriteFile(source, data, 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			defer mockFailingRead()()
+
+			reportFile := filepath.Join(t.TempDir(), "report.json")
+			params := &models.Params{
+				Source:         sourceDir,
+				Destination:    destDir,
+				Compression:    -1,
+				DeleteSource:   true,
+				SalvageDamaged: tt.salvage,
+				ReportFile:     reportFile,
+			}
+
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() unexpected error: %v", err)
+			}
+			if summary.Salvaged != tt.wantSalvaged || summary.Skipped != tt.wantSkipped || summary.Processed != 0 {
+				t.Errorf("summary = %+v, want %d salvaged and %d skipped", summary, tt.wantSalvaged, tt.wantSkipped)
+			}
+
+			// The source of a damaged file is kept even with DeleteSource
+			if _, err := os.Stat(source); err != nil {
+				t.Errorf("source file should be kept: %v", err)
+			}
+
+			_, err = os.Stat(filepath.Join(destDir, DamagedDir, "photo.jpg"))
+			if tt.salvage != (err == nil) {
+				t.Errorf("salvaged file exists = %t, want %t", err == nil, tt.salvage)
+			}
+
+			report := readTestReport(t, reportFile)
+			if len(report.Files) != 1 || report.Files[0].Status != tt.wantStatus {
+				t.Fatalf("report files = %+v, want one %s entry", report.Files, tt.wantStatus)
+			}
+			entry := report.Files[0]
+			if entry.Reason == "" {
+				t.Error("report entry should contain the read error")
+			}
+			if tt.salvage && entry.RecoveredBytes != int64(len(data)/2) {
+				t.Errorf("recovered bytes = %d, want %d", entry.RecoveredBytes, len(data)/2)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// Actions a run would take on a scanned file
+const (
+	ActionCopy       = "copy"
+	ActionCompress   = "compress"
+	ActionSkipExists = "skip: exists"
+	ActionSkipNoDate = "skip: no date"
+	ActionCorrupt    = "corrupt"
+)
+
+// ScanEntry describes a detected media file and what a run would do with it
+type ScanEntry struct {
+	PlannedFile
+	Camera string
+	Action string
+}
+
+// ScanMediaFiles plans every media file of the source and describes its camera
+// and the action a run with the same parameters would take, without writing anything
+func ScanMediaFiles(p *models.Params) ([]ScanEntry, error) {
+	plan, err := PlanMediaFiles(p)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ScanEntry, 0, len(plan))
+	for _, planned := range plan {
+		entry := ScanEntry{PlannedFile: planned, Camera: readCamera(planned.Source)}
+
+		switch {
+		case errors.Is(planned.Err, ErrCorruptFile):
+			entry.Action = ActionCorrupt
+		case planned.Err != nil:
+			entry.Action = ActionSkipNoDate
+		case p.Destination != "" && pathExists(planned.Destination):
+			entry.Action = ActionSkipExists
+		case isJPEG(planned.Source) && p.Compression >= 0:
+			entry.Action = ActionCompress
+		default:
+			entry.Action = ActionCopy
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// readCamera returns the camera name of a file, or an empty string if unknown
+func readCamera(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	meta, err := GetImageMetadata(file, filepath.Ext(path))
+	if err != nil {
+		return ""
+	}
+	return meta.Camera()
+}
+
+// pathExists reports whether path exists, treating errors as absent
+func pathExists(path string) bool {
+	exists, err := fileExists(OSFileSystem{}, path)
+	return err == nil && exists
+}
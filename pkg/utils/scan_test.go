@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestScanMediaFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	jpeg := wrapJPEG(buildTIFF([]testTag{
+		asciiTag(TagMake, "FUJIFILM"),
+		asciiTag(TagModel, "X-T4"),
+		asciiTag(TagDateTime, "2024:06:11 15:30:10"),
+	}, nil))
+
+	files := map[string][]byte{
+		"new.jpg":      jpeg,
+		"existing.jpg": jpeg,
+		"undated.jpg":  []byte("Not a valid JPEG"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), data, 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	existing := filepath.Join(destDir, "2024", "06-11", "existing.jpg")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatalf("Failed to create destination structure: %v", err)
+	}
+	if err := os.WriteFile(existing, jpeg, 0644); err != nil {
+		t.Fatalf("Failed to create existing file: %v", err)
+	}
+
+	entries, err := ScanMediaFiles(&models.Params{
+		Source:      sourceDir,
+		Destination: destDir,
+		Compression: 80,
+	})
+	if err != nil {
+		t.Fatalf("ScanMediaFiles() unexpected error: %v", err)
+	}
+
+	want := map[string]struct{ action, camera string }{
+		"new.jpg":      {ActionCompress, "FUJIFILM X-T4"},
+		"existing.jpg": {ActionSkipExists, "FUJIFILM X-T4"},
+		"undated.jpg":  {ActionSkipNoDate, ""},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d", len(want), len(entries))
+	}
+	for _, e := range entries {
+		w := want[filepath.Base(e.Source)]
+		if e.Action != w.action || e.Camera != w.camera {
+			t.Errorf("%s: action = %q, camera = %q, want %q, %q", filepath.Base(e.Source), e.Action, e.Camera, w.action, w.camera)
+		}
+	}
+}
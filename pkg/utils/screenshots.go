@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// Policies for the screenshots found in phone and tablet exports
+const (
+	ScreenshotsRoute = "route" // Organized in a separate Screenshots/YYYY/MM tree
+	ScreenshotsKeep  = "keep"  // Organized in day folders like other pictures
+	ScreenshotsSkip  = "skip"  // Reported as skipped
+)
+
+// ScreenshotsDir is the destination folder holding the screenshots tree
+const ScreenshotsDir = "Screenshots"
+
+// KindScreenshot is the report and catalog tag of screenshots
+const KindScreenshot = "screenshot"
+
+// screenshotPrefixes start the names devices give to screenshots, lowercased:
+// Screenshot_20240611-153000.png on Android, "Screen Shot 2024-06-11 at
+// 15.30.00.png" on older macOS
+var screenshotPrefixes = []string{"screenshot", "screen shot", "screen_shot"}
+
+// screenshotNameDate matches the date, and optionally the time, in the name of
+// a screenshot
+var screenshotNameDate = regexp.MustCompile(`(\d{4})-?(\d{2})-?(\d{2})(?:\D{1,4}(\d{2})[.\-_:]?(\d{2})[.\-_:]?(\d{2}))?`)
+
+// isDeviceExport reports whether the source of a run on fsys is a phone or
+// tablet export: imported with the apple-photos profile, or laid out like the
+// storage of the device, in or above a DCIM folder
+func isDeviceExport(fsys FileSystem, p *models.Params) bool {
+	if p.Profile == ProfileApplePhotos {
+		return true
+	}
+	for _, part := range strings.Split(filepath.ToSlash(filepath.Clean(p.Source)), "/") {
+		if strings.EqualFold(part, CameraDir) {
+			return true
+		}
+	}
+	info, err := fsys.Stat(filepath.Join(p.Source, CameraDir))
+	return err == nil && info.IsDir()
+}
+
+// ScreenshotsPolicy returns how a run handles screenshots: by the policy of
+// the run, or without one, routed for device exports, which mix them with
+// camera pictures, and kept with other pictures for other sources
+func ScreenshotsPolicy(p *models.Params) string {
+	return screenshotsPolicy(OSFileSystem{}, p)
+}
+
+// screenshotsPolicy is ScreenshotsPolicy for a source on fsys
+func screenshotsPolicy(fsys FileSystem, p *models.Params) string {
+	switch {
+	case p.Screenshots != "":
+		return p.Screenshots
+	case isDeviceExport(fsys, p):
+		return ScreenshotsRoute
+	default:
+		return ScreenshotsKeep
+	}
+}
+
+// screenshotSigns tells whether a picture may be a screenshot: marked when
+// named as such by the device or marked as such in its EXIF user comment, as
+// iOS does, bare when it is a PNG file without camera information
+func screenshotSigns(path string, r io.ReadSeeker) (marked, bare bool) {
+	name := strings.ToLower(filepath.Base(path))
+	for _, prefix := range screenshotPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true, false
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if !isAllowedExtension(ext) {
+		return false, false
+	}
+	// Files without readable metadata carry no camera information
+	meta, _ := GetImageMetadata(r, ext)
+	marked = strings.Contains(strings.ToLower(meta.UserComment), KindScreenshot)
+	return marked, ext == ".png" && meta.Make == "" && meta.Model == ""
+}
+
+// isScreenshot reports whether a picture is a screenshot: marked as one, or
+// a bare PNG file of a device export. Other sources hold PNG files of all
+// kinds, such as exported pictures and scans, which are not taken for
+// screenshots.
+func isScreenshot(path string, r io.ReadSeeker, device bool) bool {
+	marked, bare := screenshotSigns(path, r)
+	return marked || device && bare
+}
+
+// isScreenshotFile reports whether the file at path of fsys is a screenshot
+func isScreenshotFile(fsys FileSystem, path string, device bool) bool {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	return isScreenshot(path, file, device)
+}
+
+// datedAsScreenshot reports whether a picture without EXIF date is dated like
+// a screenshot, as any picture that may be one is
+func datedAsScreenshot(path string, r io.ReadSeeker) bool {
+	marked, bare := screenshotSigns(path, r)
+	return marked || bare
+}
+
+// screenshotDate returns the date of a screenshot without EXIF date: the date
+// in its name when there is one, otherwise its modification time
+func screenshotDate(path string, info os.FileInfo) time.Time {
+	m := screenshotNameDate.FindStringSubmatch(filepath.Base(path))
+	if m != nil {
+		value, layout := m[1]+m[2]+m[3], "20060102"
+		if m[4] != "" {
+			value, layout = value+m[4]+m[5]+m[6], "20060102150405"
+		}
+		if date, err := time.Parse(layout, value); err == nil {
+			return date
+		}
+	}
+	return info.ModTime()
+}
+
+// screenshotDestination returns where a screenshot taken at date is routed:
+// <dest>/Screenshots/YYYY/MM/<name>
+func screenshotDestination(p *models.Params, source string, date time.Time) string {
+	return filepath.Join(destinationRoot(p, date), ScreenshotsDir, fmt.Sprintf("%d", date.Year()), fmt.Sprintf("%02d", date.Month()), filepath.Base(source))
+}
+
+// applyScreenshots tags the entry of a screenshot read from content, and
+// leaves it out under the skip policy. It reports whether the file is a
+// screenshot, and whether it was left out.
+func (r *mediaRun) applyScreenshots(entry *ReportEntry, content io.ReadSeeker, proxy, unknown bool, summary *ProcessingSummary) (screenshot, skipped bool) {
+	if proxy || unknown || !isScreenshot(entry.Source, content, r.device) {
+		return false, false
+	}
+	entry.Tags = append(entry.Tags, KindScreenshot)
+	if r.screenshots != ScreenshotsSkip {
+		return true, false
+	}
+	summary.skip(SkipFiltered)
+	output.Status("SKIPPED", fmt.Sprintf("Screenshot: %s", entry.Source))
+	entry.Status, entry.Reason, entry.SkipReason = ReportSkipped, KindScreenshot, SkipFiltered
+	r.finish(*entry)
+	return true, true
+}
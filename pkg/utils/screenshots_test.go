@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createTestTIFF returns a big-endian TIFF structure holding one tag in IFD0
+func createTestTIFF(tag, fieldType uint16, value []byte) []byte {
+	data := []byte("MM\x00*\x00\x00\x00\x08")
+	data = binary.BigEndian.AppendUint16(data, 1)
+	data = binary.BigEndian.AppendUint16(data, tag)
+	data = binary.BigEndian.AppendUint16(data, fieldType)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(value)))
+	data = binary.BigEndian.AppendUint32(data, 26) // Value right after the directory
+	data = binary.BigEndian.AppendUint32(data, 0)
+	return append(data, value...)
+}
+
+// createTestPNG returns a minimal PNG file, with an eXIf chunk when exif is set
+func createTestPNG(exif []byte) []byte {
+	chunk := func(data []byte, kind string, content []byte) []byte {
+		data = binary.BigEndian.AppendUint32(data, uint32(len(content)))
+		data = append(data, kind...)
+		data = append(data, content...)
+		return append(data, 0, 0, 0, 0) // CRC, not checked
+	}
+
+	data := []byte(pngSignature)
+	data = chunk(data, "IHDR", make([]byte, 13))
+	if exif != nil {
+		data = chunk(data, "eXIf", exif)
+	}
+	return chunk(data, "IEND", nil)
+}
+
+// createTestJPEG returns a minimal JPEG file embedding a TIFF structure
+func createTestJPEG(tiff []byte) []byte {
+	segment := append([]byte(ExifIdentifier), tiff...)
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(segment)+2))
+	data = append(data, segment...)
+	return append(data, 0xFF, 0xD9)
+}
+
+func TestIsScreenshot(t *testing.T) {
+	testCases := []struct {
+		name   string
+		path   string
+		data   []byte
+		device bool
+		want   bool
+	}{
+		{"android name", "Screenshot_20240611-153000.jpg", createFakeExifData(), false, true},
+		{"macos name", "Screen Shot 2024-06-11 at 15.30.00.png", createTestPNG(nil), false, true},
+		{"png without exif", "IMG_0001.png", createTestPNG(nil), true, true},
+		{"png without exif outside device exports", "IMG_0001.png", createTestPNG(nil), false, false},
+		{"png from a camera", "IMG_0001.png", createTestPNG(createTestTIFF(TagMake, tiffTypeASCII, []byte("Canon\x00"))), true, false},
+		{"user comment", "IMG_0001.jpg", createTestJPEG(createTestTIFF(TagUserComment, 7, []byte("ASCII\x00\x00\x00Screenshot"))), false, true},
+		{"camera picture", "IMG_0001.jpg", createFakeExifData(), true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isScreenshot(tc.path, bytes.NewReader(tc.data), tc.device); got != tc.want {
+				t.Errorf("isScreenshot(%q, %v) = %v, want %v", tc.path, tc.device, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScreenshotsPolicy(t *testing.T) {
+	root := t.TempDir()
+	phone := filepath.Join(root, "phone")
+	if err := os.MkdirAll(filepath.Join(phone, CameraDir, "Camera"), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		params models.Params
+		device bool
+		want   string
+	}{
+		{"folder", models.Params{Source: root}, false, ScreenshotsKeep},
+		{"phone storage", models.Params{Source: phone}, true, ScreenshotsRoute},
+		{"camera folder", models.Params{Source: filepath.Join(phone, "dcim", "Camera")}, true, ScreenshotsRoute},
+		{"apple-photos profile", models.Params{Source: root, Profile: ProfileApplePhotos}, true, ScreenshotsRoute},
+		{"policy", models.Params{Source: phone, Screenshots: ScreenshotsSkip}, true, ScreenshotsSkip},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isDeviceExport(OSFileSystem{}, &tc.params); got != tc.device {
+				t.Errorf("isDeviceExport() = %v, want %v", got, tc.device)
+			}
+			if got := ScreenshotsPolicy(&tc.params); got != tc.want {
+				t.Errorf("ScreenshotsPolicy() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScreenshotDate(t *testing.T) {
+	modTime := time.Date(2023, 5, 4, 12, 0, 0, 0, time.Local)
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set time: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		want time.Time
+	}{
+		{"Screenshot_20240611-153005.png", time.Date(2024, 6, 11, 15, 30, 5, 0, time.UTC)},
+		{"Screenshot 2024-06-11 at 15.30.05.png", time.Date(2024, 6, 11, 15, 30, 5, 0, time.UTC)},
+		{"Screenshot_2024-06-11.png", time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC)},
+		{"Screenshot.png", modTime},
+		{"Screenshot_20241399.png", modTime}, // Not a date
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := screenshotDate(tc.name, info); !got.Equal(tc.want) {
+				t.Errorf("screenshotDate(%q) = %v, want %v", tc.name, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesScreenshots(t *testing.T) {
+	source := t.TempDir()
+	files := map[string][]byte{
+		"photo.jpg":                      createFakeExifData(),
+		"Screenshot_20240611-153000.png": createTestPNG(nil),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(source, name), data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		policy  string
+		want    []string
+		skipped int
+	}{
+		{
+			policy: ScreenshotsRoute,
+			want: []string{
+				filepath.Join("2025", "01-11", "photo.jpg"),
+				filepath.Join(ScreenshotsDir, "2024", "06", "Screenshot_20240611-153000.png"),
+			},
+		},
+		{
+			policy: "", // Kept, the source is not a device export
+			want: []string{
+				filepath.Join("2025", "01-11", "photo.jpg"),
+				filepath.Join("2024", "06-11", "Screenshot_20240611-153000.png"),
+			},
+		},
+		{
+			policy: ScreenshotsKeep,
+			want: []string{
+				filepath.Join("2025", "01-11", "photo.jpg"),
+				filepath.Join("2024", "06-11", "Screenshot_20240611-153000.png"),
+			},
+		},
+		{
+			policy:  ScreenshotsSkip,
+			want:    []string{filepath.Join("2025", "01-11", "photo.jpg")},
+			skipped: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.policy, func(t *testing.T) {
+			dest := t.TempDir()
+			params := &models.Params{Source: source, Destination: dest, Compression: -1, Screenshots: tc.policy}
+
+			plan, err := PlanMediaFiles(params)
+			if err != nil {
+				t.Fatalf("PlanMediaFiles() error = %v", err)
+			}
+			if len(plan) != len(tc.want) {
+				t.Errorf("PlanMediaFiles() planned %d files, want %d", len(plan), len(tc.want))
+			}
+			for _, planned := range plan {
+				rel, _ := filepath.Rel(dest, planned.Destination)
+				found := false
+				for _, want := range tc.want {
+					found = found || rel == want
+				}
+				if !found {
+					t.Errorf("Unexpected planned destination %s", rel)
+				}
+			}
+
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if summary.Copied != len(tc.want) || summary.Skipped != tc.skipped {
+				t.Errorf("Expected %d copied and %d skipped files, got %+v", len(tc.want), tc.skipped, summary)
+			}
+			for _, rel := range tc.want {
+				if _, err := os.Stat(filepath.Join(dest, rel)); err != nil {
+					t.Errorf("Expected %s: %v", rel, err)
+				}
+			}
+		})
+	}
+}
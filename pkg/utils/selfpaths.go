@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+var (
+	registeredMu    sync.Mutex
+	registeredPaths []string // Artifacts of the tool that are not run parameters
+)
+
+// RegisterSelfPath records a file or directory the tool writes outside of
+// the run parameters, such as the logs directory, so runs never import it
+// even when it lies inside their source
+func RegisterSelfPath(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		registeredMu.Lock()
+		registeredPaths = append(registeredPaths, abs)
+		registeredMu.Unlock()
+	}
+}
+
+// selfPaths returns the absolute paths of the files and directories the tool
+// writes for a run: its cache, catalog, report, timeline and quarantine, the
+// state kept in the destination and the registered paths. Temporary files
+// are told apart by their extension, except those left by earlier versions.
+func selfPaths(p *models.Params) []string {
+	var paths []string
+	add := func(path string) {
+		if abs, err := filepath.Abs(path); err == nil {
+			paths = append(paths, abs)
+		}
+	}
+	for _, file := range []string{p.CacheFile, p.CatalogFile, p.ReportFile, p.TimelineFile} {
+		if file != "" {
+			add(file)
+			add(file + ".tmp")
+		}
+	}
+	if p.QuarantineDir != "" {
+		add(p.QuarantineDir)
+	}
+	if p.Destination != "" {
+		add(filepath.Join(p.Destination, filepath.Dir(SourceStateFile)))
+	}
+
+	registeredMu.Lock()
+	paths = append(paths, registeredPaths...)
+	registeredMu.Unlock()
+	return paths
+}
+
+// isSelfPath reports whether path is one of the paths of the tool, lies
+// below one of them or is a temporary file of the tool
+func isSelfPath(paths []string, path string) bool {
+	if strings.HasSuffix(path, TempExt) {
+		return true
+	}
+	if len(paths) == 0 {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, self := range paths {
+		if abs == self || strings.HasPrefix(abs, self+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestIsSelfPath(t *testing.T) {
+	root := t.TempDir()
+	paths := []string{filepath.Join(root, "logs"), filepath.Join(root, "catalog.db")}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(root, "logs"), true},
+		{filepath.Join(root, "logs", "2024-06-11.log"), true},
+		{filepath.Join(root, "catalog.db"), true},
+		{filepath.Join(root, "logs-2024"), false},
+		{filepath.Join(root, "catalog.db.bak"), false},
+		{root, false},
+	}
+	for _, tt := range tests {
+		if got := isSelfPath(paths, tt.path); got != tt.want {
+			t.Errorf("isSelfPath(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestProcessMediaFilesSelfPaths(t *testing.T) {
+	source := t.TempDir()
+	destination := t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "IMG_0001.JPG"), createSerialJPEG("2024:06:11 15:30:10", "1"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	// Output of earlier runs written into the source
+	for _, name := range []string{"report.json", "catalog.db", "catalog.db.tmp", "logs/2024-06-11.log"} {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("output"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	RegisterSelfPath(filepath.Join(source, "logs"))
+
+	params := &models.Params{
+		Source:      source,
+		Destination: destination,
+		Compression: -1,
+		CopyUnknown: true,
+		ReportFile:  filepath.Join(source, "report.json"),
+		CatalogFile: filepath.Join(source, "catalog.db"),
+	}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Skipped != 0 {
+		t.Errorf("Expected only the photo to be copied, got %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(destination, "other")); !os.IsNotExist(err) {
+		t.Errorf("Expected no file of the tool in the destination, got %v", err)
+	}
+}
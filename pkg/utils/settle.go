@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// ErrWriteInProgress is returned for files that may still be written, such as
+// a RAW file arriving in a tethered-capture hot folder
+var ErrWriteInProgress = errors.New("file is still being written")
+
+// For testing purposes
+var (
+	isOpenForWriting = openForWriting
+	settleSleep      = time.Sleep
+)
+
+// checkWriteComplete returns ErrWriteInProgress when the file at path may not
+// be fully written: it is waited for until it was last modified settle ago,
+// then its size and modification time must not have changed and no other
+// process may hold it open for writing. A zero settle disables the check.
+func checkWriteComplete(fsys FileSystem, path string, info os.FileInfo, settle time.Duration) error {
+	if settle <= 0 {
+		return nil
+	}
+	if age := time.Since(info.ModTime()); age < settle {
+		settleSleep(settle - age)
+	}
+
+	current, err := fsys.Stat(path)
+	if err != nil {
+		return err
+	}
+	if current.Size() != info.Size() || !current.ModTime().Equal(info.ModTime()) {
+		return ErrWriteInProgress
+	}
+	if isOpenForWriting(path) {
+		return ErrWriteInProgress
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Root of the process file system, for testing purposes
+var procRoot = "/proc"
+
+// openForWriting reports whether a process holds the file at path open for
+// writing, from the file descriptors listed in /proc. Processes of other users
+// cannot be inspected without privileges and are ignored.
+func openForWriting(path string) bool {
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	}
+
+	processes, err := os.ReadDir(procRoot)
+	if err != nil {
+		return false
+	}
+	self := strconv.Itoa(os.Getpid())
+	for _, process := range processes {
+		pid := process.Name()
+		if _, err := strconv.Atoi(pid); err != nil || pid == self {
+			continue
+		}
+		fds, err := os.ReadDir(filepath.Join(procRoot, pid, "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(procRoot, pid, "fd", fd.Name()))
+			if err != nil || link != target {
+				continue
+			}
+			if fdWritable(filepath.Join(procRoot, pid, "fdinfo", fd.Name())) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fdWritable reports whether the flags of an fdinfo file give write access.
+// Unreadable flags count as writable, an open file is not worth the risk.
+func fdWritable(fdinfo string) bool {
+	file, err := os.Open(fdinfo)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "flags:")
+		if !ok {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 64)
+		if err != nil {
+			return true
+		}
+		return flags&(uint64(os.O_WRONLY)|uint64(os.O_RDWR)) != 0
+	}
+	return true
+}
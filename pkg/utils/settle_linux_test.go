@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenForWriting(t *testing.T) {
+	originalRoot := procRoot
+	defer func() { procRoot = originalRoot }()
+
+	target := filepath.Join(t.TempDir(), "IMG_0001.CR3")
+	if err := os.WriteFile(target, nil, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	target, _ = filepath.EvalSymlinks(target)
+
+	tests := []struct {
+		name  string
+		flags string // Octal open flags of the descriptor, none for another file
+		want  bool
+	}{
+		{"written", "0100001", true},
+		{"read and written", "0100002", true},
+		{"read", "0100000", false},
+		{"other file", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			procRoot = t.TempDir()
+			for _, dir := range []string{"4242/fd", "4242/fdinfo", "self"} {
+				if err := os.MkdirAll(filepath.Join(procRoot, dir), 0755); err != nil {
+					t.Fatalf("Failed to create directory: %v", err)
+				}
+			}
+			linked := target
+			if tt.flags == "" {
+				linked = filepath.Join(filepath.Dir(target), "other.CR3")
+			}
+			if err := os.Symlink(linked, filepath.Join(procRoot, "4242", "fd", "3")); err != nil {
+				t.Fatalf("Failed to create descriptor: %v", err)
+			}
+			fdinfo := "pos:\t0\nflags:\t" + tt.flags + "\nmnt_id:\t25\n"
+			if err := os.WriteFile(filepath.Join(procRoot, "4242", "fdinfo", "3"), []byte(fdinfo), 0644); err != nil {
+				t.Fatalf("Failed to create descriptor info: %v", err)
+			}
+
+			if got := openForWriting(target); got != tt.want {
+				t.Errorf("openForWriting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+
+package utils
+
+// openForWriting cannot tell open files on this platform, only the size and
+// modification time of files are checked
+func openForWriting(path string) bool {
+	return false
+}
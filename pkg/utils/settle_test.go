@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestCheckWriteComplete(t *testing.T) {
+	originalSleep, originalOpen := settleSleep, isOpenForWriting
+	defer func() { settleSleep, isOpenForWriting = originalSleep, originalOpen }()
+
+	tests := []struct {
+		name      string
+		age       time.Duration
+		grow      bool // The file grows while waited for
+		open      bool // Another process writes to the file
+		wantSleep bool
+		wantErr   error
+	}{
+		{name: "settled", age: time.Minute},
+		{name: "recent", age: time.Second, wantSleep: true},
+		{name: "growing", age: time.Second, grow: true, wantSleep: true, wantErr: ErrWriteInProgress},
+		{name: "open for writing", age: time.Minute, open: true, wantErr: ErrWriteInProgress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "IMG_0001.CR3")
+			if err := os.WriteFile(path, []byte("first chunk"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			modTime := time.Now().Add(-tt.age)
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatalf("Failed to set time: %v", err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Failed to stat test file: %v", err)
+			}
+
+			slept := false
+			settleSleep = func(d time.Duration) {
+				slept = true
+				if tt.grow {
+					os.WriteFile(path, []byte("first chunk, second chunk"), 0644)
+				}
+			}
+			isOpenForWriting = func(string) bool { return tt.open }
+
+			err = checkWriteComplete(OSFileSystem{}, path, info, 5*time.Second)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkWriteComplete() error = %v, want %v", err, tt.wantErr)
+			}
+			if slept != tt.wantSleep {
+				t.Errorf("checkWriteComplete() waited = %v, want %v", slept, tt.wantSleep)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesSettle(t *testing.T) {
+	originalOpen := isOpenForWriting
+	defer func() { isOpenForWriting = originalOpen }()
+
+	source := t.TempDir()
+	for _, name := range []string{"complete.jpg", "arriving.jpg"} {
+		if err := os.WriteFile(filepath.Join(source, name), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	old := time.Now().Add(-time.Minute)
+	for _, name := range []string{"complete.jpg", "arriving.jpg"} {
+		if err := os.Chtimes(filepath.Join(source, name), old, old); err != nil {
+			t.Fatalf("Failed to set time: %v", err)
+		}
+	}
+	isOpenForWriting = func(path string) bool { return filepath.Base(path) == "arriving.jpg" }
+
+	dest := t.TempDir()
+	params := &models.Params{Source: source, Destination: dest, Compression: -1, Settle: time.Second, ReportFile: filepath.Join(t.TempDir(), "report.json")}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Skipped != 1 {
+		t.Errorf("Expected 1 copied and 1 skipped file, got %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "2025", "01-11", "arriving.jpg")); !os.IsNotExist(err) {
+		t.Errorf("Expected the file being written to be left for the next run, got %v", err)
+	}
+
+	report := readTestReport(t, params.ReportFile)
+	for _, entry := range report.Files {
+		if filepath.Base(entry.Source) == "arriving.jpg" && entry.Reason != ErrWriteInProgress.Error() {
+			t.Errorf("Expected reason %q, got %q", ErrWriteInProgress, entry.Reason)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package utils
+
+import "syscall"
+
+// Windows error returned when a file is open with an incompatible share mode
+const errorSharingViolation syscall.Errno = 32
+
+// openForWriting reports whether another process holds the file at path open
+// for writing: opening it while only sharing read access then fails with a
+// sharing violation
+func openForWriting(path string) bool {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false
+	}
+	handle, err := syscall.CreateFile(name, syscall.GENERIC_READ, syscall.FILE_SHARE_READ, nil,
+		syscall.OPEN_EXISTING, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return err == errorSharingViolation
+	}
+	syscall.CloseHandle(handle)
+	return false
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// addHourShards places the files of the days holding more than threshold
+// files in hour subfolders of their day folder, such as 2024/06-11/14h/, so
+// file browsers stay usable after timelapses and events. Files already placed
+// apart, in a sequence or kind folder, stay there, and so do files whose date
+// cannot be read.
+func addHourShards(names map[string]string, shots map[string][]*shot, threshold int) {
+	perDay := make(map[string]int)
+	for _, dirShots := range shots {
+		for _, s := range dirShots {
+			if s.dated {
+				perDay[dayKey(s.date)] += len(s.files)
+			}
+		}
+	}
+
+	for _, dirShots := range shots {
+		for _, s := range dirShots {
+			if !s.dated || perDay[dayKey(s.date)] <= threshold {
+				continue
+			}
+			for _, path := range s.files {
+				if _, ok := names[path]; !ok {
+					names[path] = filepath.Join(hourDir(s.date), filepath.Base(path))
+				}
+			}
+		}
+	}
+}
+
+// dayKey identifies the day folder of a date
+func dayKey(date time.Time) string {
+	return date.Format("2006-01-02")
+}
+
+// hourDir returns the name of the hour subfolder of a date, such as "14h"
+func hourDir(date time.Time) string {
+	return fmt.Sprintf("%02dh", date.Hour())
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestProcessMediaFilesShardThreshold(t *testing.T) {
+	source := t.TempDir()
+	frames := []struct{ name, date string }{
+		{"IMG_0001.JPG", "2024:06:11 09:15:00"},
+		{"IMG_0002.JPG", "2024:06:11 14:02:00"},
+		{"IMG_0003.JPG", "2024:06:11 14:59:59"},
+		{"IMG_0004.JPG", "2024:06:12 14:00:00"}, // Alone on its day
+	}
+	for _, f := range frames {
+		createBracketFrame(t, filepath.Join(source, f.name), "A7", f.date, 0)
+	}
+
+	testCases := []struct {
+		name      string
+		threshold int
+		want      []string
+	}{
+		{
+			name:      "crowded day split by hour",
+			threshold: 2,
+			want: []string{
+				filepath.Join("2024", "06-11", "09h", "IMG_0001.JPG"),
+				filepath.Join("2024", "06-11", "14h", "IMG_0002.JPG"),
+				filepath.Join("2024", "06-11", "14h", "IMG_0003.JPG"),
+				filepath.Join("2024", "06-12", "IMG_0004.JPG"),
+			},
+		},
+		{
+			name:      "days under the threshold",
+			threshold: 3,
+			want: []string{
+				filepath.Join("2024", "06-11", "IMG_0001.JPG"),
+				filepath.Join("2024", "06-11", "IMG_0002.JPG"),
+				filepath.Join("2024", "06-11", "IMG_0003.JPG"),
+				filepath.Join("2024", "06-12", "IMG_0004.JPG"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := t.TempDir()
+			params := &models.Params{Source: source, Destination: dest, Compression: -1, ShardThreshold: tc.threshold}
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if summary.Copied != len(frames) {
+				t.Errorf("Expected %d copied files, got %+v", len(frames), summary)
+			}
+			for _, rel := range tc.want {
+				if _, err := os.Stat(filepath.Join(dest, rel)); err != nil {
+					t.Errorf("Expected %s: %v", rel, err)
+				}
+			}
+		})
+	}
+}
+
+func TestAddHourShardsKeepsPlacedFiles(t *testing.T) {
+	source := createBracketSource(t)
+	dest := t.TempDir()
+	params := &models.Params{Source: source, Destination: dest, Compression: -1, Brackets: BracketsFolder, ShardThreshold: 1}
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+
+	day := filepath.Join(dest, "2024", "06-11")
+	if _, err := os.Stat(filepath.Join(day, "15h", "DSC00009.JPG")); err != nil {
+		t.Errorf("Expected a single frame in the hour folder: %v", err)
+	}
+	entries, err := os.ReadDir(day)
+	if err != nil {
+		t.Fatalf("Failed to read day folder: %v", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			t.Errorf("Expected only subfolders in a sharded day, found %s", entry.Name())
+		}
+	}
+	if len(entries) < 2 {
+		t.Errorf("Expected bracket folders next to the hour folder, got %d entries", len(entries))
+	}
+}
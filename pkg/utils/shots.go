@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// shot is one picture with its companion files, such as RAW+JPEG sharing a name
+type shot struct {
+	stem  string
+	date  time.Time
+	meta  Metadata
+	known bool // Date and metadata read from one of the companions
+	dated bool // Date read from one of the companions, even without metadata
+	files []string
+}
+
+// readShots groups the media files of the source by directory and shot, with
+// the date and metadata of the first readable companion of each shot. Shots
+// of a directory are sorted by camera, date and name, which keeps the shooting
+// order despite the one second resolution of EXIF dates. Privacy mode (noGPS)
+// never reads GPS locations.
+func readShots(fsys FileSystem, source string, cache *MetadataCache, noGPS bool) (map[string][]*shot, error) {
+	dirs := make(map[string]map[string]*shot)
+
+	err := walkFiles(fsys, source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+
+		ext := filepath.Ext(info.Name())
+		if info.IsDir() || !isAllowedExtension(ext) {
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		if dirs[dir] == nil {
+			dirs[dir] = make(map[string]*shot)
+		}
+		stem := strings.TrimSuffix(info.Name(), ext)
+		s := dirs[dir][strings.ToLower(stem)]
+		if s == nil {
+			s = &shot{stem: stem}
+			dirs[dir][strings.ToLower(stem)] = s
+		}
+		s.files = append(s.files, path)
+
+		// Companions share their metadata, the first readable one is enough
+		if s.known {
+			return nil
+		}
+		date, err := readMediaDate(fsys, path, info, cache, noGPS)
+		if err != nil {
+			return nil
+		}
+		s.date, s.dated = date, true
+		if meta, ok := readShotMetadata(path); ok {
+			s.date, s.meta, s.known = date, meta, true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	shots := make(map[string][]*shot, len(dirs))
+	for dir, byStem := range dirs {
+		ordered := make([]*shot, 0, len(byStem))
+		for _, s := range byStem {
+			sort.Strings(s.files)
+			ordered = append(ordered, s)
+		}
+		sort.Slice(ordered, func(i, j int) bool {
+			a, b := ordered[i], ordered[j]
+			if a.meta.Camera() != b.meta.Camera() {
+				return a.meta.Camera() < b.meta.Camera()
+			}
+			if !a.date.Equal(b.date) {
+				return a.date.Before(b.date)
+			}
+			return a.stem < b.stem
+		})
+		shots[dir] = ordered
+	}
+	return shots, nil
+}
+
+// xmpScanSize is how much of the start of a file is searched for XMP markers
+const xmpScanSize = 128 * 1024
+
+// readShotMetadata returns the EXIF metadata of a file and whether it could be
+// read, noting whether its XMP packet describes a panorama
+func readShotMetadata(path string) (Metadata, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, false
+	}
+	defer file.Close()
+
+	meta, err := GetImageMetadata(file, filepath.Ext(path))
+	if err != nil {
+		return Metadata{}, false
+	}
+
+	// Phone cameras describe their panoramas with the Google photo sphere XMP namespace
+	if _, err := file.Seek(0, io.SeekStart); err == nil {
+		head := make([]byte, xmpScanSize)
+		n, _ := io.ReadFull(file, head)
+		meta.PanoramaXMP = bytes.Contains(head[:n], []byte("GPano:"))
+	}
+	return meta, true
+}
+
+// loadShotNames detects bracketed sequences, timelapses and panoramas, and
+// crowded days, and renames files, when the run needs them. It returns the
+// destination names of the files placed apart or renamed, relative to their
+// day folder, the detected kind of files, and the sequence state holding the
+// numbers given to files, nil when names are not numbered. The state is
+// saved by the caller once the names are final.
+func loadShotNames(fsys FileSystem, p *models.Params, cache *MetadataCache) (map[string]string, map[string]string, *SequenceState, error) {
+	detectKinds := p.ReportFile != "" || p.CatalogFile != "" || p.Route != ""
+	if p.Brackets == "" && !detectKinds && p.ShardThreshold == 0 && p.Rename == "" {
+		return nil, nil, nil, nil
+	}
+
+	template, err := nameTemplateOf(p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var seqs *SequenceState
+	if template != nil && template.numbered() {
+		if seqs, err = loadSequenceState(p.Destination); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	shots, err := readShots(fsys, p.Source, cache, p.NoGPS)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	names := make(map[string]string)
+	if p.Brackets != "" {
+		for _, dirShots := range shots {
+			addBracketNames(names, bracketSequences(dirShots), p.Brackets)
+		}
+	}
+
+	var kinds map[string]string
+	if detectKinds {
+		kinds = make(map[string]string)
+		for _, dirShots := range shots {
+			detectKindsOf(kinds, dirShots)
+		}
+
+		// Frames already placed in a bracketed sequence stay there
+		routed := RoutedKinds(p.Route)
+		for path, kind := range kinds {
+			if _, ok := names[path]; !ok && routed[kind] {
+				names[path] = filepath.Join(kind, filepath.Base(path))
+			}
+		}
+	}
+
+	if p.ShardThreshold > 0 {
+		addHourShards(names, shots, p.ShardThreshold)
+	}
+	if template != nil {
+		renameShots(fsys, p, template, shots, names, seqs)
+	}
+	return names, kinds, seqs, nil
+}
+
+// sequenceDestination returns where a file is organized, inside its sequence
+// or kind folder when it is placed apart, under its new name when renamed
+func sequenceDestination(p *models.Params, source string, date time.Time, names map[string]string) string {
+	destPath := destinationPath(p, source, date)
+	if name, ok := names[source]; ok {
+		l, _ := layoutOf(p)
+		return filepath.Join(filepath.Dir(destPath), l.fileName(name, date))
+	}
+	return destPath
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// Reasons a file is skipped, recorded in the summary and the report. Files
+// already imported or left out on purpose are benign; conflicts, files
+// without date and unreadable files may hold pictures missing from the archive.
+const (
+	SkipExistsIdentical = "exists-identical" // The destination already holds the file
+	SkipExistsConflict  = "exists-conflict"  // The destination holds another file, or another source file writes it
+	SkipNoDate          = "no-date"
+	SkipUnreadable      = "unreadable"
+	SkipUnsupported     = "unsupported"
+	SkipFiltered        = "filtered" // Left out by a policy, such as proxies, screenshots or phone edits
+)
+
+// SkipReasons are the reasons files are skipped, benign ones first
+var SkipReasons = []string{SkipExistsIdentical, SkipFiltered, SkipExistsConflict, SkipNoDate, SkipUnreadable, SkipUnsupported}
+
+// benignSkips are the reasons of files safely left behind
+var benignSkips = map[string]bool{
+	SkipExistsIdentical: true,
+	SkipFiltered:        true,
+}
+
+// IsBenignSkip reports whether files skipped for reason are safely left
+// behind, others deserving a look before the source is wiped
+func IsBenignSkip(reason string) bool {
+	return benignSkips[reason]
+}
+
+// SkipCounts counts skipped files by reason
+type SkipCounts struct {
+	ExistsIdentical int
+	ExistsConflict  int
+	NoDate          int
+	Unreadable      int
+	Unsupported     int
+	Filtered        int
+}
+
+// counter returns the count of a reason, unsupported files for unknown reasons
+func (c *SkipCounts) counter(reason string) *int {
+	switch reason {
+	case SkipExistsIdentical:
+		return &c.ExistsIdentical
+	case SkipExistsConflict:
+		return &c.ExistsConflict
+	case SkipNoDate:
+		return &c.NoDate
+	case SkipUnreadable:
+		return &c.Unreadable
+	case SkipFiltered:
+		return &c.Filtered
+	default:
+		return &c.Unsupported
+	}
+}
+
+// Count returns the number of files skipped for reason
+func (c SkipCounts) Count(reason string) int {
+	return *c.counter(reason)
+}
+
+// skip counts a file skipped for reason
+func (s *ProcessingSummary) skip(reason string) {
+	s.Skipped++
+	*s.Skips.counter(reason)++
+}
+
+// skipReason returns the reason of the skipped file of a summary holding the
+// counts of one file, empty when it was not skipped
+func (s *ProcessingSummary) skipReason() string {
+	for _, reason := range SkipReasons {
+		if s.Skips.Count(reason) > 0 {
+			return reason
+		}
+	}
+	return ""
+}
+
+// dateSkip returns the skip reason of a file whose date could not be read
+func dateSkip(err error) string {
+	if errors.Is(err, ErrUnsupportedFormat) {
+		return SkipUnsupported
+	}
+	return SkipNoDate
+}
+
+// existingSkip returns the skip reason of a file whose destination exists:
+// identical only when the destination is proven to hold the source, a
+// conflict otherwise. Copies are compared by size, then by content hash.
+// Files transformed on import, such as compressed, encrypted, converted to
+// DNG or carrying their provenance, never match their source: the catalog,
+// or the provenance recorded in the destination, must hold the hash of the
+// source for that destination.
+func existingSkip(fsys FileSystem, destPath string, source payload, transformed bool, algo string, catalog *Catalog) string {
+	info, err := fsys.Stat(destPath)
+	if err != nil || !transformed && info.Size() != source.size {
+		return SkipExistsConflict
+	}
+	sum, err := source.hash(algo)
+	if err != nil {
+		return SkipExistsConflict
+	}
+
+	if !transformed {
+		written, err := filePayload(fsys, destPath, info.Size()).hash(algo)
+		if err != nil || written != sum {
+			return SkipExistsConflict
+		}
+		return SkipExistsIdentical
+	}
+	if record, ok := catalog.Lookup(sum); ok && filepath.Clean(record.Destination) == filepath.Clean(destPath) {
+		return SkipExistsIdentical
+	}
+	if pv, ok := destinationProvenance(fsys, destPath); ok && pv.Hash == sum {
+		return SkipExistsIdentical
+	}
+	return SkipExistsConflict
+}
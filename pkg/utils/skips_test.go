@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestDateSkip(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{ErrNoDate, SkipNoDate},
+		{fmt.Errorf("%w: .xyz", ErrUnsupportedFormat), SkipUnsupported},
+		{fmt.Errorf("%w in movie header", ErrNoDate), SkipNoDate},
+	}
+	for _, tt := range tests {
+		if got := dateSkip(tt.err); got != tt.want {
+			t.Errorf("dateSkip(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExistingSkip(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		return path
+	}
+	same := write("same.jpg", "photodata")
+	other := write("other.jpg", "photoDATA") // Same size, another picture
+	shorter := write("shorter.jpg", "photo")
+	recorded := write("recorded.jpg", "compressed")
+	sidecar := write("sidecar.arw", "encrypted")
+	source := bufferPayload([]byte("photodata"))
+	sum := HashBuffer([]byte("photodata"), HashSHA256)
+	pv := &Provenance{ID: "1", Source: "DSC00001.ARW", Hash: sum}
+	write("sidecar.arw"+ProvenanceSidecarExt, string(pv.packet()))
+
+	catalog, err := OpenCatalog(filepath.Join(t.TempDir(), "catalog.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenCatalog() error = %v", err)
+	}
+	defer catalog.Close()
+	if err := catalog.Add(CatalogRecord{Hash: sum, Destination: recorded, Size: 9}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		dest        string
+		transformed bool
+		catalog     *Catalog
+		want        string
+	}{
+		{"same content", same, false, nil, SkipExistsIdentical},
+		{"same size, other content", other, false, nil, SkipExistsConflict},
+		{"other size", shorter, false, nil, SkipExistsConflict},
+		{"missing", filepath.Join(dir, "missing.jpg"), false, nil, SkipExistsConflict},
+		{"transformed, nothing recorded", same, true, nil, SkipExistsConflict},
+		{"transformed, recorded in the catalog", recorded, true, catalog, SkipExistsIdentical},
+		{"transformed, catalog records another destination", other, true, catalog, SkipExistsConflict},
+		{"transformed, provenance sidecar", sidecar, true, nil, SkipExistsIdentical},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := existingSkip(OSFileSystem{}, tt.dest, source, tt.transformed, HashSHA256, tt.catalog); got != tt.want {
+				t.Errorf("existingSkip(%s) = %s, want %s", filepath.Base(tt.dest), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesSkipReasons(t *testing.T) {
+	source := t.TempDir()
+	dest := t.TempDir()
+	photo := createFakeExifData()
+	files := map[string][]byte{
+		"same.jpg":     photo, // Already imported
+		"other.jpg":    photo, // Destination holds another file
+		"samesize.jpg": photo, // Destination holds another file of the same size
+		"undated.jpg":  {0xFF, 0xD8, 0xFF, 0xD9},
+		"GL010001.LRV": createTestMovie(movieEpoch.AddDate(120, 0, 0)),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(source, name), data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	day := filepath.Join(dest, "2025", "01-11")
+	if err := os.MkdirAll(day, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(day, "same.jpg"), photo, 0644); err != nil {
+		t.Fatalf("Failed to create destination file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(day, "other.jpg"), []byte("another picture"), 0644); err != nil {
+		t.Fatalf("Failed to create destination file: %v", err)
+	}
+	altered := append([]byte(nil), photo...)
+	altered[len(altered)-3] ^= 0xFF
+	if err := os.WriteFile(filepath.Join(day, "samesize.jpg"), altered, 0644); err != nil {
+		t.Fatalf("Failed to create destination file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:      source,
+		Destination: dest,
+		Compression: -1,
+		Proxies:     ProxiesSkip,
+		ReportFile:  filepath.Join(t.TempDir(), "report.json"),
+	}
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+
+	want := SkipCounts{ExistsIdentical: 1, ExistsConflict: 2, NoDate: 1, Filtered: 1}
+	if summary.Skipped != 5 || summary.Skips != want {
+		t.Errorf("Skipped = %d, Skips = %+v, want 5, %+v", summary.Skipped, summary.Skips, want)
+	}
+
+	report := readTestReport(t, params.ReportFile)
+	wantReasons := map[string]string{
+		"same.jpg":     SkipExistsIdentical,
+		"other.jpg":    SkipExistsConflict,
+		"samesize.jpg": SkipExistsConflict,
+		"undated.jpg":  SkipNoDate,
+		"GL010001.LRV": SkipFiltered,
+	}
+	for _, entry := range report.Files {
+		if got := entry.SkipReason; got != wantReasons[filepath.Base(entry.Source)] {
+			t.Errorf("skip reason of %s = %q, want %q", entry.Source, got, wantReasons[filepath.Base(entry.Source)])
+		}
+	}
+	for reason, n := range map[string]int{SkipExistsIdentical: 1, SkipExistsConflict: 2, SkipNoDate: 1, SkipFiltered: 1} {
+		if report.Skips[reason] != n {
+			t.Errorf("report skips = %v, want %d %s", report.Skips, n, reason)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SourceStateFile is the path, relative to the destination, of the state of
+// previously imported sources
+var SourceStateFile = filepath.Join(".organize-media", "sources.json")
+
+// fileStamp identifies a version of a source file
+type fileStamp struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime"`
+}
+
+// sourceSnapshot lists the files of a source directory handled by previous imports
+type sourceSnapshot struct {
+	LastImport time.Time            `json:"last_import"`
+	Files      map[string]fileStamp `json:"files"` // Keyed by path relative to the source
+}
+
+// SourceState remembers which files of a source directory were already
+// imported, so repeated imports from the same folder only touch new files.
+// It is stored in the destination, one snapshot per source directory.
+type SourceState struct {
+	path     string
+	source   string
+	mu       sync.Mutex
+	sources  map[string]*sourceSnapshot
+	snapshot *sourceSnapshot
+	dirty    bool
+}
+
+// LoadSourceState reads the state of source stored in the destination.
+// A missing state file yields an empty state.
+func LoadSourceState(destination, source string) (*SourceState, error) {
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &SourceState{
+		path:    filepath.Join(destination, SourceStateFile),
+		source:  abs,
+		sources: make(map[string]*sourceSnapshot),
+	}
+
+	data, err := os.ReadFile(state.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read source state: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &state.sources); err != nil {
+			return nil, fmt.Errorf("failed to parse source state %s: %w", state.path, err)
+		}
+	}
+
+	state.snapshot = state.sources[abs]
+	if state.snapshot == nil || state.snapshot.Files == nil {
+		state.snapshot = &sourceSnapshot{Files: make(map[string]fileStamp)}
+		state.sources[abs] = state.snapshot
+	}
+	return state, nil
+}
+
+// Unchanged reports whether a file was handled by a previous import and has
+// not been modified since. A nil state reports every file as new.
+func (s *SourceState) Unchanged(path string, info os.FileInfo) bool {
+	if s == nil {
+		return false
+	}
+
+	key, ok := s.key(path)
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stamp, ok := s.snapshot.Files[key]
+	return ok && stamp.Size == info.Size() && stamp.ModTime == info.ModTime().UnixNano()
+}
+
+// Mark records a file as handled
+func (s *SourceState) Mark(path string, info os.FileInfo) {
+	if s == nil {
+		return
+	}
+
+	key, ok := s.key(path)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshot.Files[key] = fileStamp{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+	s.dirty = true
+}
+
+// LastImport returns when files were last imported from the source
+func (s *SourceState) LastImport() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot.LastImport
+}
+
+// Save writes the state back to the destination if files were marked
+func (s *SourceState) Save() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	s.snapshot.LastImport = time.Now()
+
+	data, err := json.Marshal(s.sources)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted save never loses the state
+	if err := writeFileAtomic(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write source state: %w", err)
+	}
+
+	s.dirty = false
+	return nil
+}
+
+// key returns the path of a file relative to the source, with forward slashes
+// so the state stays valid when a card is read on another system
+func (s *SourceState) key(path string) (string, bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(s.source, abs)
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestSourceState(t *testing.T) {
+	sourceDir := t.TempDir()
+	otherSource := t.TempDir()
+	destDir := t.TempDir()
+
+	path := filepath.Join(sourceDir, "photo.jpg")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat test file: %v", err)
+	}
+
+	state, err := LoadSourceState(destDir, sourceDir)
+	if err != nil {
+		t.Fatalf("LoadSourceState() error = %v", err)
+	}
+	if state.Unchanged(path, info) {
+		t.Error("Unchanged() on an empty state should be false")
+	}
+
+	state.Mark(path, info)
+	if !state.Unchanged(path, info) {
+		t.Error("Unchanged() should be true after Mark()")
+	}
+	if err := state.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	t.Run("reloaded", func(t *testing.T) {
+		reloaded, err := LoadSourceState(destDir, sourceDir)
+		if err != nil {
+			t.Fatalf("LoadSourceState() error = %v", err)
+		}
+		if !reloaded.Unchanged(path, info) {
+			t.Error("Unchanged() should be true after reloading the state")
+		}
+		if reloaded.LastImport().IsZero() {
+			t.Error("LastImport() should be set after saving")
+		}
+	})
+
+	t.Run("modified file", func(t *testing.T) {
+		if err := os.Chtimes(path, time.Now(), info.ModTime().Add(time.Hour)); err != nil {
+			t.Fatalf("Failed to change mtime: %v", err)
+		}
+		modified, _ := os.Stat(path)
+		if state.Unchanged(path, modified) {
+			t.Error("Unchanged() should be false for a modified file")
+		}
+	})
+
+	t.Run("other source", func(t *testing.T) {
+		other, err := LoadSourceState(destDir, otherSource)
+		if err != nil {
+			t.Fatalf("LoadSourceState() error = %v", err)
+		}
+		if other.Unchanged(filepath.Join(otherSource, "photo.jpg"), info) {
+			t.Error("Unchanged() should not use the state of another source")
+		}
+	})
+
+	t.Run("nil state", func(t *testing.T) {
+		var nilState *SourceState
+		nilState.Mark(path, info)
+		if nilState.Unchanged(path, info) || nilState.Save() != nil {
+			t.Error("nil state should report every file as new")
+		}
+	})
+}
+
+func TestProcessMediaFilesSinceLast(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	params := &models.Params{Source: sourceDir, Destination: destDir, Compression: -1, SinceLast: true}
+
+	first, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if first.Processed != 2 || first.Unchanged != 0 {
+		t.Errorf("first run = %+v, want 2 processed", first)
+	}
+
+	// A new file appears in the synced folder
+	if err := os.WriteFile(filepath.Join(sourceDir, "c.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	plan, err := PlanMediaFiles(params)
+	if err != nil {
+		t.Fatalf("PlanMediaFiles() error = %v", err)
+	}
+	if len(plan) != 1 || filepath.Base(plan[0].Source) != "c.jpg" {
+		t.Errorf("plan = %v, want only c.jpg", plan)
+	}
+
+	second, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if second.Processed != 1 || second.Unchanged != 2 || second.Skipped != 0 {
+		t.Errorf("second run = %+v, want 1 processed and 2 unchanged", second)
+	}
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// IOStats records the data volume, throughput and time spent in each phase
+// of a run, to tell whether the CPU (compression) or the disks are the bottleneck
+type IOStats struct {
+	BytesRead     int64
+	BytesWritten  int64
+	PeakReadRate  float64 // Bytes per second of the fastest file read
+	PeakWriteRate float64 // Bytes per second of the fastest file write
+
+	// Time spent per phase
+	Scan     time.Duration // Walking the source, loading and saving the cache and catalog, while not waiting for workers
+	Read     time.Duration
+	Extract  time.Duration // EXIF date extraction
+	Compress time.Duration
+	Write    time.Duration
+
+	Workers int           // Highest number of workers processing files concurrently
+	Busy    time.Duration // Total time workers spent processing files
+}
+
+// addRead records a file read of n bytes
+func (s *IOStats) addRead(n int64, d time.Duration) {
+	s.BytesRead += n
+	s.Read += d
+	s.PeakReadRate = max(s.PeakReadRate, rate(n, d))
+}
+
+// addWrite records a file write of n bytes
+func (s *IOStats) addWrite(n int64, d time.Duration) {
+	s.BytesWritten += n
+	s.Write += d
+	s.PeakWriteRate = max(s.PeakWriteRate, rate(n, d))
+}
+
+// ReadRate returns the average read throughput in bytes per second
+func (s IOStats) ReadRate() float64 {
+	return rate(s.BytesRead, s.Read)
+}
+
+// WriteRate returns the average write throughput in bytes per second
+func (s IOStats) WriteRate() float64 {
+	return rate(s.BytesWritten, s.Write)
+}
+
+// Utilization returns the fraction of the run duration workers spent processing files
+func (s IOStats) Utilization(duration time.Duration) float64 {
+	if s.Workers == 0 || duration <= 0 {
+		return 0
+	}
+	return min(1, s.Busy.Seconds()/(duration.Seconds()*float64(s.Workers)))
+}
+
+// FormatRate formats a throughput in bytes per second, such as "42.00 MB/s"
+func FormatRate(bytesPerSecond float64) string {
+	return fmt.Sprintf("%s/s", FormatSize(int64(bytesPerSecond)))
+}
+
+func rate(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestIOStats(t *testing.T) {
+	var stats IOStats
+	stats.addRead(10<<20, time.Second)
+	stats.addRead(10<<20, 4*time.Second)
+	stats.addWrite(4<<20, 2*time.Second)
+	stats.addWrite(0, 0)
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"average read rate", stats.ReadRate(), 4 << 20},
+		{"peak read rate", stats.PeakReadRate, 10 << 20},
+		{"average write rate", stats.WriteRate(), 2 << 20},
+		{"peak write rate", stats.PeakWriteRate, 2 << 20},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if stats.BytesRead != 20<<20 || stats.BytesWritten != 4<<20 {
+		t.Errorf("bytes read/written = %d/%d", stats.BytesRead, stats.BytesWritten)
+	}
+	if got := FormatRate(stats.ReadRate()); got != "4.00 MB/s" {
+		t.Errorf("FormatRate() = %s, want 4.00 MB/s", got)
+	}
+}
+
+func TestIOStatsUtilization(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    IOStats
+		duration time.Duration
+		want     float64
+	}{
+		{"no workers", IOStats{Busy: time.Second}, time.Second, 0},
+		{"no duration", IOStats{Workers: 1, Busy: time.Second}, 0, 0},
+		{"single worker", IOStats{Workers: 1, Busy: 3 * time.Second}, 4 * time.Second, 0.75},
+		{"several workers", IOStats{Workers: 4, Busy: 4 * time.Second}, 2 * time.Second, 0.5},
+		{"capped", IOStats{Workers: 1, Busy: 2 * time.Second}, time.Second, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.Utilization(tt.duration); got != tt.want {
+				t.Errorf("Utilization() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesStats(t *testing.T) {
+	sourceDir := t.TempDir()
+	data := createFakeExifData()
+	if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	summary, err := ProcessMediaFiles(&models.Params{Source: sourceDir, Destination: t.TempDir(), Compression: -1})
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() unexpected error: %v", err)
+	}
+
+	stats := summary.Stats
+	if stats.BytesRead != int64(len(data)) || stats.BytesWritten != int64(len(data)) {
+		t.Errorf("bytes read/written = %d/%d, want %d", stats.BytesRead, stats.BytesWritten, len(data))
+	}
+	if stats.Workers != 1 {
+		t.Errorf("Workers = %d, want 1", stats.Workers)
+	}
+	if stats.Busy <= 0 || stats.Busy > summary.Duration {
+		t.Errorf("Busy = %v, want within (0, %v]", stats.Busy, summary.Duration)
+	}
+	if stats.Scan <= 0 || stats.Scan > summary.Duration {
+		t.Errorf("Scan = %v, want within (0, %v]", stats.Scan, summary.Duration)
+	}
+}
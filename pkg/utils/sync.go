@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// Actions of an archive sync on a file
+const (
+	SyncCopy      = "copy"      // Missing from the backup
+	SyncUpdate    = "update"    // Different in the backup
+	SyncUnchanged = "unchanged" // Same size and modification time, or same content when verified
+	SyncProtected = "protected" // Different in the backup, which is write-protected
+	SyncFailed    = "failed"
+)
+
+// SyncOptions configures an archive sync
+type SyncOptions struct {
+	Verify   bool   // Compare the content of files with the same size and modification time
+	DryRun   bool   // Only report what would be copied
+	HashAlgo string // Hash algorithm used to compare content
+	// Catalog of the archive, if any, telling which side holds the recorded
+	// content when verifying finds a file changed without its size or date
+	Catalog *Catalog
+}
+
+// SyncResult is the outcome of syncing one file of the archive
+type SyncResult struct {
+	Path   string // Relative to the archive
+	Action string
+	Err    error
+}
+
+// SyncSummary counts the outcomes of an archive sync
+type SyncSummary struct {
+	Copied    int
+	Updated   int
+	Unchanged int
+	Protected int
+	Failed    int
+	Extra     int // Files only in the backup, which are never deleted
+	Orphans   int // Temporary files of interrupted syncs removed from the backup
+	Bytes     int64
+}
+
+// SyncArchive copies the files of an organized archive missing or changed in
+// a backup, such as a second drive. Files are compared by size and
+// modification time, and by content hash when verifying, so a sync can also
+// check that a backup is intact. Nothing is deleted from the backup.
+// A verified file whose content differs with the same size and modification
+// time is damaged on one side: it is only replaced when the hash recorded in
+// the catalog shows the archive holds the right content, and reported as
+// failed otherwise.
+// onResult, if not nil, is called for every file that is not unchanged.
+func SyncArchive(from, to string, opts SyncOptions, onResult func(SyncResult)) (SyncSummary, error) {
+	var summary SyncSummary
+	seen := make(map[string]bool)
+	// FAT drives only keep modification times to 2 seconds
+	fat := IsFATFileSystem(to)
+
+	// Copies cut short by an interrupted sync are never completed, they only take space
+	orphans, err := CleanOrphanedTemp(to)
+	summary.Orphans = orphans
+	if err != nil {
+		output.Status("WARNING", fmt.Sprintf("Failed to remove orphaned temporary files: %v", err))
+	}
+	release, err := openRunTempDir(to, newRunID(time.Now()))
+	if err != nil {
+		return summary, err
+	}
+	defer release()
+	recorded := recordedHashes(opts.Catalog)
+
+	err = filepath.Walk(from, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+		// Temporary files of runs writing to the archive are not part of it
+		if info.IsDir() || !info.Mode().IsRegular() || strings.HasSuffix(info.Name(), TempExt) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		seen[rel] = true
+
+		result := SyncResult{Path: rel}
+		result.Action, result.Err = syncFile(path, filepath.Join(to, rel), info, opts, fat, recorded)
+		switch result.Action {
+		case SyncCopy:
+			summary.Copied++
+			summary.Bytes += info.Size()
+		case SyncUpdate:
+			summary.Updated++
+			summary.Bytes += info.Size()
+		case SyncUnchanged:
+			summary.Unchanged++
+			return nil
+		case SyncProtected:
+			summary.Protected++
+		case SyncFailed:
+			summary.Failed++
+		}
+		if onResult != nil {
+			onResult(result)
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	// Files only in the backup may come from another archive, they are only counted
+	err = filepath.Walk(to, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasSuffix(info.Name(), TempExt) {
+			return nil
+		}
+		if rel, err := filepath.Rel(to, path); err == nil && !seen[rel] {
+			summary.Extra++
+		}
+		return nil
+	})
+	return summary, err
+}
+
+// recordedHashes returns the content hashes recorded in a catalog by
+// absolute destination path
+func recordedHashes(catalog *Catalog) map[string]string {
+	recorded := make(map[string]string)
+	for _, record := range catalog.AllRecords() {
+		if abs, err := filepath.Abs(record.Destination); err == nil {
+			recorded[abs] = record.Hash
+		}
+	}
+	return recorded
+}
+
+// syncFile brings one backup file up to date and returns the action taken
+func syncFile(source, target string, info os.FileInfo, opts SyncOptions, fat bool, recorded map[string]string) (string, error) {
+	action := SyncCopy
+	if targetInfo, err := os.Stat(target); err == nil {
+		same := targetInfo.Size() == info.Size() && sameModTime(targetInfo.ModTime(), info.ModTime(), fat)
+		if same && opts.Verify {
+			if same, err = verifyContent(source, target, opts.HashAlgo, recorded); err != nil {
+				return SyncFailed, err
+			}
+		}
+		if same {
+			return SyncUnchanged, nil
+		}
+		if isWriteProtected(OSFileSystem{}, target) {
+			return SyncProtected, fmt.Errorf("backup file differs and is write-protected: %s", target)
+		}
+		action = SyncUpdate
+	} else if !os.IsNotExist(err) {
+		return SyncFailed, err
+	}
+
+	if opts.DryRun {
+		return action, nil
+	}
+	if err := copyFileAtomic(source, target, info.ModTime()); err != nil {
+		return SyncFailed, err
+	}
+	return action, nil
+}
+
+// verifyContent reports whether a backup file of the same size and
+// modification time as the archive file has its content. A file changed
+// without its size or date is damaged on one side: it may only be replaced
+// when the catalog shows the archive holds the recorded content, and is an
+// error otherwise.
+func verifyContent(source, target, algo string, recorded map[string]string) (bool, error) {
+	sourceHash, err := hashFile(source, algo)
+	if err != nil {
+		return false, err
+	}
+	targetHash, err := hashFile(target, algo)
+	if err != nil {
+		return false, err
+	}
+	if sourceHash == targetHash {
+		return true, nil
+	}
+
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return false, err
+	}
+	switch hash, ok := recorded[abs]; {
+	case ok && hash == sourceHash:
+		return false, nil
+	case ok && hash == targetHash:
+		return false, fmt.Errorf("archive file differs from the catalog, the backup holds the recorded content: %s", source)
+	default:
+		return false, fmt.Errorf("content differs with the same size and modification time: %s", target)
+	}
+}
+
+// sameContent reports whether two files have the same content hash. Files of
+// different sizes are not hashed.
+func sameContent(a, b, algo string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	hashA, err := hashFile(a, algo)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := hashFile(b, algo)
+	if err != nil {
+		return false, err
+	}
+	return hashA == hashB, nil
+}
+
+// copyFileAtomic copies source to target through a temporary file, so an
+// interrupted copy never leaves a partial file that looks complete. The
+// modification time is kept so the next sync sees the files as identical.
+func copyFileAtomic(source, target string, modTime time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := createTemp(target)
+	if err != nil {
+		return err
+	}
+	tmpPath := out.Name()
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err == nil {
+		err = os.Chtimes(tmpPath, modTime, modTime)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, target)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to copy %s: %w", source, err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncArchive(t *testing.T) {
+	archive := t.TempDir()
+	backup := t.TempDir()
+	modTime := time.Date(2025, 1, 11, 10, 0, 0, 0, time.UTC)
+
+	write := func(root, rel, content string) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set time: %v", err)
+		}
+	}
+
+	write(archive, "2025/01-11/new.jpg", "new")
+	write(archive, "2025/01-11/same.jpg", "same")
+	write(backup, "2025/01-11/same.jpg", "same")
+	write(archive, "2025/01-11/changed.jpg", "archive version")
+	write(backup, "2025/01-11/changed.jpg", "old")
+	// Same size and date, only found by verifying the content, and damaged on
+	// one side with no catalog to tell which
+	write(archive, "2025/01-11/bitrot.jpg", "abcd")
+	write(backup, "2025/01-11/bitrot.jpg", "abce")
+	write(backup, "2024/extra.jpg", "extra")
+
+	// A dry run reports without copying
+	summary, err := SyncArchive(archive, backup, SyncOptions{DryRun: true}, nil)
+	if err != nil {
+		t.Fatalf("SyncArchive() dry run error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Updated != 1 || summary.Unchanged != 2 || summary.Extra != 1 {
+		t.Errorf("Unexpected dry run summary: %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(backup, "2025", "01-11", "new.jpg")); !os.IsNotExist(err) {
+		t.Error("Dry run should not copy files")
+	}
+
+	var results []SyncResult
+	summary, err = SyncArchive(archive, backup, SyncOptions{Verify: true}, func(r SyncResult) {
+		results = append(results, r)
+	})
+	if err != nil {
+		t.Fatalf("SyncArchive() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Updated != 1 || summary.Unchanged != 1 || summary.Failed != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected 3 results, got %+v", results)
+	}
+	if got, _ := os.ReadFile(filepath.Join(backup, "2025", "01-11", "bitrot.jpg")); string(got) != "abce" {
+		t.Errorf("Backup file differing with the same size and date was replaced: %q", got)
+	}
+
+	for _, name := range []string{"new.jpg", "changed.jpg"} {
+		want, _ := os.ReadFile(filepath.Join(archive, "2025", "01-11", name))
+		got, err := os.ReadFile(filepath.Join(backup, "2025", "01-11", name))
+		if err != nil || string(got) != string(want) {
+			t.Errorf("Backup of %s = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(backup, "2025", "01-11", "new.jpg")); err != nil || !info.ModTime().Equal(modTime) {
+		t.Errorf("Expected the modification time to be kept: %v", err)
+	}
+
+	// Everything else is now up to date
+	summary, err = SyncArchive(archive, backup, SyncOptions{Verify: true}, nil)
+	if err != nil {
+		t.Fatalf("SyncArchive() second run error = %v", err)
+	}
+	if summary.Copied != 0 || summary.Updated != 0 || summary.Unchanged != 3 || summary.Failed != 1 {
+		t.Errorf("Unexpected second run summary: %+v", summary)
+	}
+}
+
+func TestSyncArchiveVerifyCatalog(t *testing.T) {
+	archive := t.TempDir()
+	backup := t.TempDir()
+	modTime := time.Date(2025, 1, 11, 10, 0, 0, 0, time.UTC)
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set time: %v", err)
+		}
+	}
+
+	catalog, err := OpenCatalog(filepath.Join(t.TempDir(), "catalog.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenCatalog() error = %v", err)
+	}
+	defer catalog.Close()
+	// The archive holds the recorded content of good.jpg, the backup that of damaged.jpg
+	for name, recorded := range map[string]string{"good.jpg": "abcd", "damaged.jpg": "wxyz"} {
+		source := filepath.Join(archive, name)
+		if err := catalog.Add(CatalogRecord{Hash: HashBuffer([]byte(recorded), HashSHA256), Destination: source, Size: 4}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	write(filepath.Join(archive, "good.jpg"), "abcd")
+	write(filepath.Join(backup, "good.jpg"), "abce")
+	write(filepath.Join(archive, "damaged.jpg"), "wxya")
+	write(filepath.Join(backup, "damaged.jpg"), "wxyz")
+
+	results := make(map[string]SyncResult)
+	opts := SyncOptions{Verify: true, HashAlgo: HashSHA256, Catalog: catalog}
+	summary, err := SyncArchive(archive, backup, opts, func(r SyncResult) {
+		results[r.Path] = r
+	})
+	if err != nil {
+		t.Fatalf("SyncArchive() error = %v", err)
+	}
+	if summary.Updated != 1 || summary.Failed != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if r := results["good.jpg"]; r.Action != SyncUpdate {
+		t.Errorf("good.jpg = %+v, want the backup repaired", r)
+	}
+	if r := results["damaged.jpg"]; r.Action != SyncFailed || r.Err == nil {
+		t.Errorf("damaged.jpg = %+v, want a failure", r)
+	}
+	for name, want := range map[string]string{"good.jpg": "abcd", "damaged.jpg": "wxyz"} {
+		if got, _ := os.ReadFile(filepath.Join(backup, name)); string(got) != want {
+			t.Errorf("Backup of %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSyncArchiveProtected(t *testing.T) {
+	archive := t.TempDir()
+	backup := t.TempDir()
+	if err := os.WriteFile(filepath.Join(archive, "photo.jpg"), []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	target := filepath.Join(backup, "photo.jpg")
+	if err := os.WriteFile(target, []byte("older"), 0444); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	summary, err := SyncArchive(archive, backup, SyncOptions{}, nil)
+	if err != nil {
+		t.Fatalf("SyncArchive() error = %v", err)
+	}
+	if summary.Protected != 1 {
+		t.Errorf("Expected 1 protected file, got %+v", summary)
+	}
+	if got, _ := os.ReadFile(target); string(got) != "older" {
+		t.Errorf("Write-protected backup file was replaced: %q", got)
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a.jpg": "abcd", "copy.jpg": "abcd", "bitrot.jpg": "abce", "longer.jpg": "abcde"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		b       string
+		want    bool
+		wantErr bool
+	}{
+		{name: "same content", b: "copy.jpg", want: true},
+		{name: "same size, other content", b: "bitrot.jpg", want: false},
+		{name: "other size", b: "longer.jpg", want: false},
+		{name: "missing file", b: "missing.jpg", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sameContent(filepath.Join(dir, "a.jpg"), filepath.Join(dir, tt.b), HashSHA256)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("sameContent() = %v, %v, want %v, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// Ways of mirroring Google Takeout albums
+const (
+	AlbumsLinks = "links" // Symlink tree of albums in the destination
+	AlbumsTags  = "tags"  // Album names recorded in the catalog and report
+)
+
+// AlbumsDir is the destination folder holding the symlink trees of albums
+const AlbumsDir = "Albums"
+
+// takeoutAlbumFile describes an album folder of a Google Takeout export
+const takeoutAlbumFile = "metadata.json"
+
+// LoadTakeoutAlbums returns the albums of the media files of a Google Takeout
+// export. Takeout stores each album as a folder holding a copy of its pictures
+// and a metadata.json file with the album title, next to the "Photos from
+// YYYY" folders holding every picture.
+func LoadTakeoutAlbums(source string) (map[string][]string, error) {
+	albums := make(map[string][]string)
+
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to access path %q: %w", path, err)
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		title, err := readAlbumTitle(filepath.Join(path, takeoutAlbumFile))
+		if err != nil || title == "" {
+			return nil // Not an album folder
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("failed to read album %q: %w", path, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && isAllowedExtension(filepath.Ext(entry.Name())) {
+				file := filepath.Join(path, entry.Name())
+				albums[file] = append(albums[file], title)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	return albums, nil
+}
+
+// readAlbumTitle returns the title of a Takeout album metadata file
+func readAlbumTitle(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var metadata struct {
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(metadata.Title), nil
+}
+
+// loadAlbums returns the Takeout albums of the source files when the run mirrors them
+func loadAlbums(p *models.Params) (map[string][]string, error) {
+	if p.Albums == "" {
+		return nil, nil
+	}
+	return LoadTakeoutAlbums(p.Source)
+}
+
+// linkAlbums adds a link to an organized file in the folder of each of its
+// albums. Links are relative, so the destination can be moved as a whole.
+func (r *mediaRun) linkAlbums(source, destPath string) {
+	if r.p.Albums != AlbumsLinks {
+		return
+	}
+
+	albums := append([]string(nil), r.albums[source]...)
+	sort.Strings(albums)
+	for _, album := range albums {
+		linkPath := filepath.Join(r.p.Destination, AlbumsDir, albumDirName(album), filepath.Base(destPath))
+		if err := createRelativeLink(destPath, linkPath); err != nil {
+			output.Status("WARNING", fmt.Sprintf("Failed to link %s in album %s: %v", destPath, album, err))
+		}
+	}
+}
+
+// createRelativeLink creates a symbolic link at linkPath pointing to target,
+// leaving an existing link untouched
+func createRelativeLink(target, linkPath string) error {
+	if _, err := os.Lstat(linkPath); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(linkPath), os.ModePerm); err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(filepath.Dir(linkPath), target)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(rel, linkPath)
+}
+
+// albumDirName turns an album title into a folder name valid on every system
+func albumDirName(title string) string {
+	// FAT rules are the strictest of the common file systems
+	return fatSafeName(title)
+}
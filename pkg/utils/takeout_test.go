@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// createTakeoutSource creates a Google Takeout export holding a picture in its
+// year folder and, as Takeout does, a copy in the folder of an album
+func createTakeoutSource(t *testing.T) string {
+	t.Helper()
+	source := t.TempDir()
+	year := filepath.Join(source, "Photos from 2025")
+	album := filepath.Join(source, "Summer 2024")
+	for _, dir := range []string{year, album} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "IMG_0001.JPG"), createFakeExifData(), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "IMG_0001.JPG.json"), []byte(`{"title": "IMG_0001.JPG"}`), 0644); err != nil {
+			t.Fatalf("Failed to create sidecar: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(album, "metadata.json"), []byte(`{"title": "Summer: 2024", "description": ""}`), 0644); err != nil {
+		t.Fatalf("Failed to create album metadata: %v", err)
+	}
+	return source
+}
+
+func TestLoadTakeoutAlbums(t *testing.T) {
+	source := createTakeoutSource(t)
+
+	albums, err := LoadTakeoutAlbums(source)
+	if err != nil {
+		t.Fatalf("LoadTakeoutAlbums() error = %v", err)
+	}
+	want := map[string][]string{
+		filepath.Join(source, "Summer 2024", "IMG_0001.JPG"): {"Summer: 2024"},
+	}
+	if !reflect.DeepEqual(albums, want) {
+		t.Errorf("LoadTakeoutAlbums() = %v, want %v", albums, want)
+	}
+}
+
+func TestAlbumDirName(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Summer 2024", "Summer 2024"},
+		{"Paris / London: 2024", "Paris _ London_ 2024"},
+		{"Trip...", "Trip"},
+		{"..", "_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := albumDirName(tt.title); got != tt.want {
+				t.Errorf("albumDirName(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesAlbums(t *testing.T) {
+	tests := []struct {
+		mode      string
+		wantLink  bool
+		wantNames string
+	}{
+		{AlbumsLinks, true, "[Summer: 2024]"},
+		{AlbumsTags, false, "[Summer: 2024]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			source := createTakeoutSource(t)
+			destination := t.TempDir()
+			params := &models.Params{
+				Source:      source,
+				Destination: destination,
+				Compression: -1,
+				Albums:      tt.mode,
+				ReportFile:  filepath.Join(t.TempDir(), "report.json"),
+			}
+			if _, err := ProcessMediaFiles(params); err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+
+			organized := filepath.Join(destination, "2025", "01-11", "IMG_0001.JPG")
+			link := filepath.Join(destination, AlbumsDir, "Summer_ 2024", "IMG_0001.JPG")
+			target, err := os.Readlink(link)
+			if (err == nil) != tt.wantLink {
+				t.Fatalf("Album link error = %v, want link %v", err, tt.wantLink)
+			}
+			if tt.wantLink {
+				if resolved := filepath.Join(filepath.Dir(link), target); resolved != organized {
+					t.Errorf("Album link points to %s, want %s", resolved, organized)
+				}
+			}
+
+			for _, entry := range readTestReport(t, params.ReportFile).Files {
+				inAlbum := filepath.Base(filepath.Dir(entry.Source)) == "Summer 2024"
+				if (fmt.Sprint(entry.Albums) == tt.wantNames) != inAlbum {
+					t.Errorf("Report entry %s albums = %v", entry.Source, entry.Albums)
+				}
+			}
+		})
+	}
+}
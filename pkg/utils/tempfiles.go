@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TempExt ends the temporary files written before being renamed onto their
+// target, so files left by an interrupted run are recognized
+const TempExt = ".organize-tmp"
+
+// TempDirName is the folder of a destination holding the temporary files of
+// the runs writing to it, one subfolder per run. It lies on the volume of the
+// destination, so temporary files are renamed onto their target atomically.
+var TempDirName = filepath.Join(".organize-media", "tmp")
+
+// TempOrphanAge is the time after which a temporary file no longer written
+// to is left by an interrupted run. Younger files may belong to another run
+// writing to the same destination at the same time.
+var TempOrphanAge = time.Hour
+
+// runTempDir is the temporary folder of a run under a root
+type runTempDir struct {
+	dir  string
+	refs int // Runs of the process sharing the root
+}
+
+var (
+	runTempMu   sync.Mutex
+	runTempDirs = make(map[string]*runTempDir) // Keyed by absolute root
+)
+
+// openRunTempDir reserves a temporary folder for a run writing below root,
+// such as the destination of an import, and returns the function releasing
+// it, which removes the folder once the last run sharing it is done
+func openRunTempDir(root, run string) (func(), error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	runTempMu.Lock()
+	defer runTempMu.Unlock()
+
+	temp, ok := runTempDirs[abs]
+	if !ok {
+		dir := filepath.Join(abs, TempDirName, fmt.Sprintf("%s-%d", run, os.Getpid()))
+		temp = &runTempDir{dir: dir}
+		runTempDirs[abs] = temp
+	}
+	temp.refs++
+
+	return func() {
+		runTempMu.Lock()
+		defer runTempMu.Unlock()
+		if temp.refs--; temp.refs == 0 {
+			delete(runTempDirs, abs)
+			os.RemoveAll(temp.dir)
+		}
+	}, nil
+}
+
+// tempDirFor returns the temporary folder of the run writing below a root
+// holding target, empty when there is none
+func tempDirFor(target string) string {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return ""
+	}
+
+	runTempMu.Lock()
+	defer runTempMu.Unlock()
+
+	for root, temp := range runTempDirs {
+		if strings.HasPrefix(abs, root+string(filepath.Separator)) {
+			return temp.dir
+		}
+	}
+	return ""
+}
+
+// createTemp creates a temporary file to be renamed onto target once
+// written: in the temporary folder of the run writing below a root holding
+// target, next to target otherwise, so both lie on the same volume. Names
+// are unique, so workers and runs writing the same target never share one.
+func createTemp(target string) (*os.File, error) {
+	dir := tempDirFor(target)
+	if dir == "" {
+		dir = filepath.Dir(target)
+	}
+	pattern := filepath.Base(target) + ".*" + TempExt
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(dir, pattern)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Another run cleaning orphans dropped the folder while it was empty
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return nil, err
+		}
+		file, err = os.CreateTemp(dir, pattern)
+	}
+	return file, err
+}
+
+// writeFileAtomic writes data to path through a temporary file, so an
+// interrupted write never leaves a truncated file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	file, err := createTemp(path)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// openTemp creates a temporary file of fsys to be renamed onto target, like
+// createTemp, returning it with its name
+func openTemp(fsys FileSystem, target string) (File, string, error) {
+	dir := tempDirFor(target)
+	if dir == "" {
+		dir = filepath.Dir(target)
+	}
+	if err := fsys.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, "", err
+	}
+	for {
+		var suffix [8]byte
+		rand.Read(suffix[:])
+		name := filepath.Join(dir, filepath.Base(target)+"."+hex.EncodeToString(suffix[:])+TempExt)
+		file, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Another run cleaning orphans dropped the folder while it was empty
+			if err := fsys.MkdirAll(dir, os.ModePerm); err != nil {
+				return nil, "", err
+			}
+			file, err = fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		}
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return file, name, err
+	}
+}
+
+// writeMediaFile writes a new media file of fsys at path through a temporary
+// file, filled by write then synced and moved to path, so a crash or a failed
+// write never leaves a partial file at path that later runs would take for
+// the file already imported. Like a file created with O_EXCL, it fails with
+// fs.ErrExist when path exists, such as a file written by another worker in
+// the meantime. It returns what write returns.
+func writeMediaFile(fsys FileSystem, path string, write func(File) (int64, error)) (int64, error) {
+	file, name, err := openTemp(fsys, path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := write(file)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = commitTemp(fsys, name, path)
+	}
+	if err != nil {
+		fsys.Remove(name)
+	}
+	return n, err
+}
+
+// commitTemp moves a complete temporary file onto path unless path exists.
+// Hard linking it there fails atomically when path exists; on file systems
+// without hard links, such as FAT and exFAT, path is checked before the
+// temporary file is renamed onto it.
+func commitTemp(fsys FileSystem, name, path string) error {
+	err := fsys.Link(name, path)
+	if err == nil || errors.Is(err, fs.ErrExist) {
+		if err == nil {
+			fsys.Remove(name)
+		}
+		return err
+	}
+	if _, err := fsys.Lstat(path); err == nil {
+		return &fs.PathError{Op: "rename", Path: path, Err: fs.ErrExist}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return fsys.Rename(name, path)
+}
+
+// CleanOrphanedTemp removes the temporary files left by interrupted runs in
+// the temporary folder of root and next to files, such as the catalog, and
+// returns how many were removed. Files written to within TempOrphanAge, which
+// may belong to a run still going, are kept.
+func CleanOrphanedTemp(root string, files ...string) (int, error) {
+	removed := 0
+	var errs []error
+	remove := func(path string, info fs.FileInfo) {
+		if !info.Mode().IsRegular() || !strings.HasSuffix(info.Name(), TempExt) || time.Since(info.ModTime()) < TempOrphanAge {
+			return
+		}
+		if err := os.Remove(path); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		removed++
+	}
+
+	if root != "" {
+		tempDir := filepath.Join(root, TempDirName)
+		var runDirs []string
+		err := filepath.Walk(tempDir, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && path != tempDir {
+				runDirs = append(runDirs, path)
+			}
+			remove(path, info)
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		// Folders of interrupted runs are dropped once empty, deepest first
+		for i := len(runDirs) - 1; i >= 0; i-- {
+			os.Remove(runDirs[i])
+		}
+	}
+
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		if file != "" {
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+	for dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*"+TempExt))
+		for _, match := range matches {
+			if info, err := os.Lstat(match); err == nil {
+				remove(match, info)
+			}
+		}
+	}
+	return removed, errors.Join(errs...)
+}
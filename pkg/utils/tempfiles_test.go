@@ -0,0 +1,259 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCreateTemp(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	release, err := openRunTempDir(root, "run")
+	if err != nil {
+		t.Fatalf("openRunTempDir() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		target  string
+		wantDir string
+	}{
+		{"below the root", filepath.Join(root, "2024", "catalog.jsonl"), filepath.Join(root, TempDirName)},
+		{"outside the root", filepath.Join(outside, "cache.json"), outside},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := createTemp(tt.target)
+			if err != nil {
+				t.Fatalf("createTemp() error = %v", err)
+			}
+			first.Close()
+			second, err := createTemp(tt.target)
+			if err != nil {
+				t.Fatalf("createTemp() error = %v", err)
+			}
+			second.Close()
+
+			if first.Name() == second.Name() {
+				t.Errorf("createTemp() returned %s twice", first.Name())
+			}
+			for _, name := range []string{first.Name(), second.Name()} {
+				if !strings.HasPrefix(name, tt.wantDir+string(filepath.Separator)) || !strings.HasSuffix(name, TempExt) {
+					t.Errorf("createTemp() = %s, want a %s file in %s", name, TempExt, tt.wantDir)
+				}
+			}
+		})
+	}
+
+	// The folder of the run goes away with it
+	release()
+	entries, _ := os.ReadDir(filepath.Join(root, TempDirName))
+	if len(entries) != 0 {
+		t.Errorf("Expected the temporary folder of the run removed, found %v", entries)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	for _, content := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != content {
+			t.Errorf("file = %q, %v, want %q", data, err, content)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected no temporary file left, found %v", entries)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0444 != 0444 {
+		t.Errorf("file mode = %v, want readable by all", info.Mode())
+	}
+}
+
+// noLinkFS is a file system without hard links, such as FAT and exFAT
+type noLinkFS struct{ *MemFS }
+
+func (noLinkFS) Link(oldname, newname string) error {
+	return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EPERM}
+}
+
+func TestWriteMediaFile(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("complete media file")
+	write := func(file File) (int64, error) {
+		n, err := file.Write(content)
+		return int64(n), err
+	}
+
+	tests := []struct {
+		name string
+		fsys FileSystem
+	}{
+		{"disk", OSFileSystem{}},
+		{"memory", NewMemFS()},
+		{"no hard links", noLinkFS{NewMemFS()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name, "2025", "photo.jpg")
+			read := func() string {
+				file, err := tt.fsys.Open(path)
+				if err != nil {
+					return err.Error()
+				}
+				defer file.Close()
+				data, _ := io.ReadAll(file)
+				return string(data)
+			}
+			if err := tt.fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("MkdirAll() error = %v", err)
+			}
+
+			// A write killed partway leaves nothing at the destination, even
+			// while it is going on
+			killed := errors.New("killed")
+			_, err := writeMediaFile(tt.fsys, path, func(file File) (int64, error) {
+				n, _ := file.Write(content[:len(content)/2])
+				if _, err := tt.fsys.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Destination during the write: %v, want not exist", err)
+				}
+				return int64(n), killed
+			})
+			if !errors.Is(err, killed) {
+				t.Fatalf("writeMediaFile() error = %v, want %v", err, killed)
+			}
+			if _, err := tt.fsys.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("Destination after a killed write: %v, want not exist", err)
+			}
+			if entries, _ := tt.fsys.ReadDir(filepath.Dir(path)); len(entries) != 0 {
+				t.Errorf("Expected no temporary file left, found %v", entries)
+			}
+
+			// The next write completes the file
+			if n, err := writeMediaFile(tt.fsys, path, write); err != nil || n != int64(len(content)) {
+				t.Fatalf("writeMediaFile() = %d, %v", n, err)
+			}
+			if got := read(); got != string(content) {
+				t.Errorf("Destination = %q, want %q", got, content)
+			}
+
+			// An existing file is never replaced
+			_, err = writeMediaFile(tt.fsys, path, func(file File) (int64, error) {
+				n, err := file.Write([]byte("another file"))
+				return int64(n), err
+			})
+			if !errors.Is(err, fs.ErrExist) {
+				t.Errorf("writeMediaFile() over an existing file error = %v, want exist", err)
+			}
+			if got := read(); got != string(content) {
+				t.Errorf("Destination = %q, want %q kept", got, content)
+			}
+			if entries, _ := tt.fsys.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+				t.Errorf("Expected no temporary file left, found %v", entries)
+			}
+		})
+	}
+}
+
+func TestCleanOrphanedTemp(t *testing.T) {
+	dest := t.TempDir()
+	state := t.TempDir()
+	old := time.Now().Add(-2 * TempOrphanAge)
+	create := func(path string, modTime time.Time) string {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+		return path
+	}
+
+	orphans := []string{
+		create(filepath.Join(dest, TempDirName, "20240611-153010-3fa2-100", "sources.json.123"+TempExt), old),
+		create(filepath.Join(state, "catalog.jsonl.456"+TempExt), old),
+	}
+	kept := []string{
+		create(filepath.Join(dest, TempDirName, "20240611-160000-9bc1-200", "DSC00001.ARW.789"+TempExt), time.Now()), // Another run still writing
+		create(filepath.Join(dest, "2024", "06-11", "notes.organize-tmp.txt"), old),
+		create(filepath.Join(state, "catalog.jsonl"), old),
+	}
+
+	removed, err := CleanOrphanedTemp(dest, filepath.Join(state, "catalog.jsonl"))
+	if err != nil {
+		t.Fatalf("CleanOrphanedTemp() error = %v", err)
+	}
+	if removed != len(orphans) {
+		t.Errorf("CleanOrphanedTemp() = %d, want %d", removed, len(orphans))
+	}
+	for _, path := range orphans {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s removed, got %v", path, err)
+		}
+	}
+	for _, path := range kept {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s kept: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Dir(orphans[0])); !os.IsNotExist(err) {
+		t.Errorf("Expected the emptied folder of the interrupted run removed, got %v", err)
+	}
+
+	// Nothing to clean in a destination never written to
+	if removed, err := CleanOrphanedTemp(t.TempDir()); err != nil || removed != 0 {
+		t.Errorf("CleanOrphanedTemp() = %d, %v, want 0", removed, err)
+	}
+}
+
+func TestSyncArchiveTempFiles(t *testing.T) {
+	from := t.TempDir()
+	to := t.TempDir()
+	writeFile := func(path, content string, modTime time.Time) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create test folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+	writeFile(filepath.Join(from, "2024", "06-11", "DSC00001.JPG"), "photo", time.Now())
+	// An import writing to the archive during the sync
+	writeFile(filepath.Join(from, TempDirName, "run-1", "DSC00002.JPG.1"+TempExt), "partial", time.Now())
+	// A copy cut short by an interrupted sync
+	orphan := filepath.Join(to, TempDirName, "run-2", "DSC00001.JPG.2"+TempExt)
+	writeFile(orphan, "partial", time.Now().Add(-2*TempOrphanAge))
+
+	summary, err := SyncArchive(from, to, SyncOptions{}, nil)
+	if err != nil {
+		t.Fatalf("SyncArchive() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Orphans != 1 || summary.Extra != 0 {
+		t.Errorf("SyncArchive() = %+v, want 1 copied file and 1 orphan removed", summary)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("Expected the orphaned copy removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(to, TempDirName, "run-1")); !os.IsNotExist(err) {
+		t.Errorf("Expected temporary files of the archive not synced, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/png" // Decodes screenshots without embedded thumbnail
+	"io"
+	"strings"
+)
+
+// Tags of the JPEG previews cameras embed in TIFF directories
+const (
+	TagSubIFDs                     = 0x014A
+	TagJPEGInterchangeFormat       = 0x0201
+	TagJPEGInterchangeFormatLength = 0x0202
+)
+
+// ThumbnailSize is the longest side, in pixels, of the thumbnails made from
+// pictures without embedded preview
+const ThumbnailSize = 320
+
+// Bounds of the work done for a thumbnail: directories searched for an
+// embedded preview, size of that preview, and pixels of a decoded picture
+const (
+	maxThumbnailDirs   = 16
+	maxEmbeddedPreview = 8 << 20
+	maxDecodedPixels   = 120_000_000
+)
+
+// Thumbnail returns a JPEG thumbnail of a picture: the preview its camera
+// embedded in the EXIF metadata, otherwise the picture scaled down when it is
+// a JPEG or PNG file
+func Thumbnail(r io.ReadSeeker, ext string) ([]byte, error) {
+	ext = strings.ToLower(ext)
+	if preview, err := embeddedPreview(r, ext); err == nil {
+		return preview, nil
+	}
+	if !jpegContainer(ext) && ext != ".png" {
+		return nil, fmt.Errorf("%w: no embedded preview in %s file", ErrUnsupportedFormat, ext)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxDecodedPixels {
+		return nil, fmt.Errorf("picture of %dx%d pixels too large for a thumbnail", config.Width, config.Height)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return encodeJPEGImage(scaleDown(img, ThumbnailSize), 80)
+}
+
+// embeddedPreview returns the first JPEG preview found in the TIFF
+// directories of a file: the chain of IFD0, IFD1... then their sub-directories,
+// where RAW formats store larger previews
+func embeddedPreview(r io.ReadSeeker, ext string) ([]byte, error) {
+	if err := seekToTIFFHeader(r, ext); err != nil {
+		return nil, err
+	}
+	t, err := newTIFFReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := []uint32{t.ifd0}
+	seen := make(map[uint32]bool)
+	for len(queue) > 0 && len(seen) < maxThumbnailDirs {
+		offset := queue[0]
+		queue = queue[1:]
+		if offset == 0 || seen[offset] {
+			continue
+		}
+		seen[offset] = true
+
+		entries, err := t.readIFD(offset)
+		if err != nil {
+			continue
+		}
+		var start, length uint32
+		for _, entry := range entries {
+			switch entry.Tag {
+			case TagJPEGInterchangeFormat:
+				start, _ = t.uint(entry)
+			case TagJPEGInterchangeFormatLength:
+				length, _ = t.uint(entry)
+			case TagSubIFDs:
+				for i := 0; i+4 <= len(entry.Value) && entry.Type == tiffTypeLong; i += 4 {
+					queue = append(queue, t.order.Uint32(entry.Value[i:]))
+				}
+			}
+		}
+		if preview, ok := t.readPreview(start, length); ok {
+			return preview, nil
+		}
+		if next, err := t.nextIFD(offset, len(entries)); err == nil {
+			queue = append(queue, next)
+		}
+	}
+	return nil, fmt.Errorf("no embedded preview")
+}
+
+// readPreview reads the JPEG preview at offset, relative to the TIFF header
+func (t *tiffReader) readPreview(offset, length uint32) ([]byte, bool) {
+	if offset == 0 || length < 4 || length > maxEmbeddedPreview {
+		return nil, false
+	}
+	if _, err := t.r.Seek(t.base+int64(offset), io.SeekStart); err != nil {
+		return nil, false
+	}
+	preview := make([]byte, length)
+	if _, err := io.ReadFull(t.r, preview); err != nil {
+		return nil, false
+	}
+	return preview, bytes.HasPrefix(preview, []byte{0xFF, 0xD8})
+}
+
+// nextIFD returns the offset of the directory following the directory of
+// count entries at offset, 0 for the last one
+func (t *tiffReader) nextIFD(offset uint32, count int) (uint32, error) {
+	if _, err := t.r.Seek(t.base+int64(offset)+2+12*int64(count), io.SeekStart); err != nil {
+		return 0, err
+	}
+	next := make([]byte, 4)
+	if _, err := io.ReadFull(t.r, next); err != nil {
+		return 0, err
+	}
+	return t.order.Uint32(next), nil
+}
+
+// scaleDown returns img scaled so its longest side is at most size pixels,
+// sampling the nearest pixel, which is enough for thumbnails
+func scaleDown(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= size && b.Dy() <= size {
+		return img
+	}
+	w, h := size, max(1, b.Dy()*size/b.Dx())
+	if b.Dy() > b.Dx() {
+		w, h = max(1, b.Dx()*size/b.Dy()), size
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy := b.Min.Y + y*b.Dy()/h
+		for x := 0; x < w; x++ {
+			out.Set(x, y, img.At(b.Min.X+x*b.Dx()/w, sy))
+		}
+	}
+	return out
+}
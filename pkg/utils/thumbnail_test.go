@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// createPreviewTIFF returns a TIFF structure whose directory holds a JPEG
+// preview: IFD1 like JPEG files, or a sub-directory of IFD0 like RAW files
+func createPreviewTIFF(preview []byte, subIFD bool) []byte {
+	entry := func(tiff []byte, tag, fieldType uint16, value uint32) []byte {
+		tiff = binary.BigEndian.AppendUint16(tiff, tag)
+		tiff = binary.BigEndian.AppendUint16(tiff, fieldType)
+		tiff = binary.BigEndian.AppendUint32(tiff, 1)
+		return binary.BigEndian.AppendUint32(tiff, value)
+	}
+
+	// IFD0 at 8, the directory of the preview at 26, the preview at 56
+	const dir, data = 8 + 2 + 12 + 4, 8 + 2 + 12 + 4 + 2 + 2*12 + 4
+	tiff := []byte("MM\x00*\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	if subIFD {
+		tiff = entry(tiff, TagSubIFDs, tiffTypeLong, dir)
+		tiff = binary.BigEndian.AppendUint32(tiff, 0)
+	} else {
+		tiff = entry(tiff, TagMake, tiffTypeASCII, 0)
+		tiff = binary.BigEndian.AppendUint32(tiff, dir)
+	}
+	tiff = binary.BigEndian.AppendUint16(tiff, 2)
+	tiff = entry(tiff, TagJPEGInterchangeFormat, tiffTypeLong, data)
+	tiff = entry(tiff, TagJPEGInterchangeFormatLength, tiffTypeLong, uint32(len(preview)))
+	tiff = binary.BigEndian.AppendUint32(tiff, 0)
+	return append(tiff, preview...)
+}
+
+// createPicture returns a JPEG picture of the given size
+func createPicture(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatalf("Failed to encode picture: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestThumbnail(t *testing.T) {
+	preview := createPicture(t, 16, 12)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		ext        string
+		wantWidth  int
+		wantHeight int
+		wantErr    bool
+	}{
+		{"JPEG thumbnail in IFD1", createTestJPEG(createPreviewTIFF(preview, false)), ".jpg", 16, 12, false},
+		{"RAW preview in a sub-directory", createPreviewTIFF(preview, true), ".NEF", 16, 12, false},
+		{"JPEG without thumbnail", createPicture(t, 640, 480), ".jpg", 320, 240, false},
+		{"portrait JPEG without thumbnail", createPicture(t, 300, 600), ".jpeg", 160, 320, false},
+		{"small JPEG", createPicture(t, 100, 50), ".jpg", 100, 50, false},
+		{"RAW without preview", createPreviewTIFF(nil, true), ".nef", 0, 0, true},
+		{"video", createTestMovie(movieEpoch), ".mp4", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumb, err := Thumbnail(bytes.NewReader(tt.data), tt.ext)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Thumbnail() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			config, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatalf("Thumbnail is not a JPEG image: %v", err)
+			}
+			if config.Width != tt.wantWidth || config.Height != tt.wantHeight {
+				t.Errorf("Thumbnail() = %dx%d, want %dx%d", config.Width, config.Height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
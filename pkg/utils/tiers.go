@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// Tier is a storage tier receiving the files older than an age instead of
+// the destination, such as a cold archive for files older than 2 years
+type Tier struct {
+	Years, Months, Days int
+	Dir                 string
+}
+
+// Cutoff returns the capture time before which files belong to the tier
+func (t Tier) Cutoff(now time.Time) time.Time {
+	return now.AddDate(-t.Years, -t.Months, -t.Days)
+}
+
+// Age returns the age of the tier as written in tiering rules, such as 2y
+func (t Tier) Age() string {
+	switch {
+	case t.Years > 0:
+		return fmt.Sprintf("%dy", t.Years)
+	case t.Months > 0:
+		return fmt.Sprintf("%dm", t.Months)
+	case t.Days%7 == 0:
+		return fmt.Sprintf("%dw", t.Days/7)
+	default:
+		return fmt.Sprintf("%dd", t.Days)
+	}
+}
+
+// ParseTiers parses tiering rules of the form <age>=<folder>, such as
+// 2y=/cold-archive, where the age is a number of years (y), months (m),
+// weeks (w) or days (d). Tiers are returned oldest first.
+func ParseTiers(rules []string) ([]Tier, error) {
+	tiers := make([]Tier, 0, len(rules))
+	for _, rule := range rules {
+		age, dir, ok := strings.Cut(rule, "=")
+		age, dir = strings.TrimSpace(age), strings.TrimSpace(dir)
+		if !ok || dir == "" {
+			return nil, fmt.Errorf("expected <age>=<folder>, such as 2y=/cold-archive: %s", rule)
+		}
+
+		tier := Tier{Dir: filepath.Clean(dir)}
+		var n int
+		var err error
+		if age != "" {
+			n, err = strconv.Atoi(age[:len(age)-1])
+		}
+		if age == "" || err != nil || n <= 0 || !strings.Contains("ymwd", age[len(age)-1:]) {
+			return nil, fmt.Errorf("age must be a positive number of years (y), months (m), weeks (w) or days (d): %s", age)
+		}
+		switch age[len(age)-1] {
+		case 'y':
+			tier.Years = n
+		case 'm':
+			tier.Months = n
+		case 'w':
+			tier.Days = 7 * n
+		case 'd':
+			tier.Days = n
+		}
+		tiers = append(tiers, tier)
+	}
+
+	// Oldest first, so a file goes to the tier of the oldest age it exceeds
+	now := time.Now()
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].Cutoff(now).Before(tiers[j].Cutoff(now)) })
+	for i := 1; i < len(tiers); i++ {
+		if tiers[i].Cutoff(now).Equal(tiers[i-1].Cutoff(now)) {
+			return nil, fmt.Errorf("several tiers for the age %s", tiers[i].Age())
+		}
+	}
+	return tiers, nil
+}
+
+var parsedTiers sync.Map // Tiers of runs, keyed by rules
+
+// tiersOf returns the tiers of a run, none when its rules are invalid
+func tiersOf(p *models.Params) []Tier {
+	if len(p.Tiers) == 0 {
+		return nil
+	}
+	key := strings.Join(p.Tiers, "\n")
+	if tiers, ok := parsedTiers.Load(key); ok {
+		return tiers.([]Tier)
+	}
+	tiers, _ := ParseTiers(p.Tiers)
+	parsedTiers.Store(key, tiers)
+	return tiers
+}
+
+// destinationRoot returns the folder organizing the files taken at date: the
+// tier of the oldest age the file exceeds, or else the root of its year, or
+// else the destination
+func destinationRoot(p *models.Params, date time.Time) string {
+	now := time.Now()
+	for _, tier := range tiersOf(p) {
+		if date.Before(tier.Cutoff(now)) {
+			return tier.Dir
+		}
+	}
+	roots, _ := yearRootsOf(p)
+	if root, ok := roots.root(date); ok {
+		return root
+	}
+	return p.Destination
+}
+
+// rootOf returns the destination, tier or year root folder holding destPath
+func rootOf(p *models.Params, destPath string) string {
+	var dirs []string
+	for _, tier := range tiersOf(p) {
+		dirs = append(dirs, tier.Dir)
+	}
+	roots, _ := yearRootsOf(p)
+	for _, root := range roots {
+		dirs = append(dirs, root.Dir)
+	}
+	for _, dir := range dirs {
+		if rel, err := filepath.Rel(dir, destPath); err == nil && !strings.HasPrefix(rel, "..") {
+			return dir
+		}
+	}
+	return p.Destination
+}
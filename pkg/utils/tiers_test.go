@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestParseTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []string
+		want    []Tier
+		wantErr string // Part of the expected error, none for valid rules
+	}{
+		{name: "no rules", want: []Tier{}},
+		{
+			name:  "every unit, oldest first",
+			rules: []string{"6w=/b", "2y = /a/ ", "18m=/c", "10d=/d"},
+			want:  []Tier{{Years: 2, Dir: "/a"}, {Months: 18, Dir: "/c"}, {Days: 42, Dir: "/b"}, {Days: 10, Dir: "/d"}},
+		},
+		{name: "missing folder", rules: []string{"2y"}, wantErr: "expected <age>=<folder>"},
+		{name: "empty folder", rules: []string{"2y="}, wantErr: "expected <age>=<folder>"},
+		{name: "missing unit", rules: []string{"2=/a"}, wantErr: "age must be"},
+		{name: "unknown unit", rules: []string{"2h=/a"}, wantErr: "age must be"},
+		{name: "several units", rules: []string{"1ym=/a"}, wantErr: "age must be"},
+		{name: "zero age", rules: []string{"0d=/a"}, wantErr: "age must be"},
+		{name: "empty age", rules: []string{"=/a"}, wantErr: "age must be"},
+		{name: "same age twice", rules: []string{"1w=/a", "7d=/b"}, wantErr: "several tiers for the age 1w"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTiers(tt.rules)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseTiers(%q) error = %v, want it to contain %q", tt.rules, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTiers(%q) unexpected error: %v", tt.rules, err)
+			}
+			for i := range tt.want {
+				tt.want[i].Dir = filepath.Clean(filepath.FromSlash(tt.want[i].Dir))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTiers(%q) = %+v, want %+v", tt.rules, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDestinationRoot(t *testing.T) {
+	p := &models.Params{Destination: "/ssd-archive", Tiers: []string{"1y=/nas", "5y=/cold-archive"}}
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		date time.Time
+		want string
+	}{
+		{name: "recent file", date: now.AddDate(0, -6, 0), want: "/ssd-archive"},
+		{name: "older than the first tier", date: now.AddDate(-3, 0, 0), want: "/nas"},
+		{name: "older than every tier", date: now.AddDate(-10, 0, 0), want: "/cold-archive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := filepath.FromSlash(tt.want)
+			if got := destinationRoot(p, tt.date); got != want {
+				t.Errorf("destinationRoot() = %s, want %s", got, want)
+			}
+			path := destinationPath(p, "/card/a.jpg", tt.date)
+			if got := rootOf(p, path); got != want {
+				t.Errorf("rootOf(%s) = %s, want %s", path, got, want)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesTiers(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	cold := t.TempDir()
+	mirror := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Taken on 2025-01-11, older than a day and younger than a thousand years
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: destDir,
+		Mirrors:     []string{mirror},
+		Tiers:       []string{"1000y=" + t.TempDir(), "1d=" + cold},
+		Compression: -1,
+	}
+	plan, err := PlanMediaFiles(params)
+	if err != nil {
+		t.Fatalf("PlanMediaFiles() error = %v", err)
+	}
+	rel := filepath.Join("2025", "01-11", "photo.jpg")
+	if len(plan) != 1 || plan[0].Destination != filepath.Join(cold, rel) {
+		t.Errorf("planned files = %+v, want destination in %s", plan, cold)
+	}
+
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Mirrored != 1 {
+		t.Errorf("Expected 1 copied and mirrored file, got %+v", summary)
+	}
+	for _, path := range []string{filepath.Join(cold, rel), filepath.Join(mirror, rel)} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected tiered file: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(destDir, rel)); !os.IsNotExist(err) {
+		t.Errorf("Expected no file at the destination, stat error = %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimelineEntry is an imported file placed on the timeline
+type TimelineEntry struct {
+	Date        time.Time `json:"date"` // Capture time, corrected by the clock offset of the camera
+	Camera      string    `json:"camera,omitempty"`
+	Serial      string    `json:"serial,omitempty"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+}
+
+// Timeline interleaves the files imported from every camera of a shoot by
+// capture time, so editors can sync multi-camera projects. It is written as
+// JSON at the end of processing.
+type Timeline struct {
+	mu      sync.Mutex
+	Cameras []string        `json:"cameras"` // Cameras of the imported files, sorted
+	Files   []TimelineEntry `json:"files"`
+}
+
+// NewTimeline returns an empty timeline
+func NewTimeline() *Timeline {
+	return &Timeline{Cameras: []string{}, Files: []TimelineEntry{}}
+}
+
+// Add places an imported file on the timeline. A nil timeline ignores entries.
+func (t *Timeline) Add(entry TimelineEntry) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Files = append(t.Files, entry)
+}
+
+// sort orders files by capture time, files taken at the same time by camera
+// then source path, and lists the cameras
+func (t *Timeline) sort() {
+	sort.SliceStable(t.Files, func(i, j int) bool {
+		a, b := t.Files[i], t.Files[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Camera != b.Camera {
+			return a.Camera < b.Camera
+		}
+		return a.Source < b.Source
+	})
+
+	seen := make(map[string]bool)
+	t.Cameras = []string{}
+	for _, entry := range t.Files {
+		if entry.Camera != "" && !seen[entry.Camera] {
+			seen[entry.Camera] = true
+			t.Cameras = append(t.Cameras, entry.Camera)
+		}
+	}
+	sort.Strings(t.Cameras)
+}
+
+// Write saves the ordered timeline as indented JSON to path
+func (t *Timeline) Write(path string) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sort()
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create timeline directory: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write timeline: %w", err)
+	}
+	return nil
+}
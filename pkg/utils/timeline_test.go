@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestProcessMediaFilesTimeline(t *testing.T) {
+	source := t.TempDir()
+	// Body A runs 10 minutes fast, its picture was taken between those of body B
+	files := map[string][]byte{
+		"A_0001.jpg": createSerialJPEG("2025:01:11 10:05:00", "A100"),
+		"B_0001.jpg": createSerialJPEG("2025:01:11 10:00:00", "B200"),
+		"B_0002.jpg": createSerialJPEG("2025:01:11 09:50:00", "B200"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(source, name), data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	work := t.TempDir()
+	offsetsFile := filepath.Join(work, "clocks.json")
+	if err := os.WriteFile(offsetsFile, []byte(`{"A100": "10m"}`), 0644); err != nil {
+		t.Fatalf("Failed to write offsets: %v", err)
+	}
+
+	params := &models.Params{
+		Source:       source,
+		Destination:  t.TempDir(),
+		Compression:  -1,
+		ClockFile:    offsetsFile,
+		TimelineFile: filepath.Join(work, "timeline.json"),
+	}
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+
+	data, err := os.ReadFile(params.TimelineFile)
+	if err != nil {
+		t.Fatalf("Failed to read timeline: %v", err)
+	}
+	var timeline Timeline
+	if err := json.Unmarshal(data, &timeline); err != nil {
+		t.Fatalf("Failed to parse timeline: %v", err)
+	}
+
+	want := []struct {
+		name   string
+		serial string
+		date   time.Time
+	}{
+		{"B_0002.jpg", "B200", time.Date(2025, 1, 11, 9, 50, 0, 0, time.UTC)},
+		{"A_0001.jpg", "A100", time.Date(2025, 1, 11, 9, 55, 0, 0, time.UTC)},
+		{"B_0001.jpg", "B200", time.Date(2025, 1, 11, 10, 0, 0, 0, time.UTC)},
+	}
+	if len(timeline.Files) != len(want) {
+		t.Fatalf("Timeline has %d files, want %d", len(timeline.Files), len(want))
+	}
+	for i, w := range want {
+		got := timeline.Files[i]
+		if filepath.Base(got.Source) != w.name || got.Serial != w.serial || !got.Date.Equal(w.date) {
+			t.Errorf("Timeline entry %d = %s %s %v, want %s %s %v", i, filepath.Base(got.Source), got.Serial, got.Date, w.name, w.serial, w.date)
+		}
+		if got.Destination == "" {
+			t.Errorf("Timeline entry %d has no destination", i)
+		}
+	}
+}
+
+func TestTimelineSort(t *testing.T) {
+	date := time.Date(2024, 6, 11, 15, 30, 0, 0, time.UTC)
+	timeline := NewTimeline()
+	timeline.Add(TimelineEntry{Date: date, Camera: "SONY ILCE-7M3", Source: "b.jpg"})
+	timeline.Add(TimelineEntry{Date: date, Camera: "Canon EOS R5", Source: "c.jpg"})
+	timeline.Add(TimelineEntry{Date: date.Add(-time.Second), Camera: "SONY ILCE-7M3", Source: "a.jpg"})
+	timeline.Add(TimelineEntry{Date: date, Source: "d.png"})
+
+	timeline.sort()
+
+	var order []string
+	for _, entry := range timeline.Files {
+		order = append(order, entry.Source)
+	}
+	if want := []string{"a.jpg", "d.png", "c.jpg", "b.jpg"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Timeline order = %v, want %v", order, want)
+	}
+	if want := []string{"Canon EOS R5", "SONY ILCE-7M3"}; !reflect.DeepEqual(timeline.Cameras, want) {
+		t.Errorf("Timeline cameras = %v, want %v", timeline.Cameras, want)
+	}
+}
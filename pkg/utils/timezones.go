@@ -0,0 +1,238 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+	_ "time/tzdata" // Zones of GPS locations, on systems without a time zone database
+)
+
+// zoneBound is a rectangle of latitudes and longitudes, in degrees, lying in
+// a time zone
+type zoneBound struct {
+	south, north, west, east float64
+	zone                     string
+}
+
+// zoneBounds approximate the time zones of the populated areas of the world
+// with rectangles, smaller areas first as the first matching one wins. They
+// are right within countries and may be off by an hour near their borders;
+// elsewhere zoneAt falls back to the nautical zone of the longitude.
+var zoneBounds = []zoneBound{
+	// Europe
+	{27.6, 29.5, -18.2, -13.4, "Atlantic/Canary"},
+	{63.2, 66.6, -24.6, -13.4, "Atlantic/Reykjavik"},
+	{36.9, 42.2, -9.6, -6.2, "Europe/Lisbon"},
+	{49.8, 61.0, -11.0, 1.8, "Europe/London"},
+	{36.0, 43.8, -9.4, 3.4, "Europe/Madrid"},
+	{42.3, 51.1, -5.2, 8.2, "Europe/Paris"},
+	{59.7, 70.1, 20.5, 28.5, "Europe/Helsinki"},
+	{61.0, 70.1, 28.5, 31.6, "Europe/Helsinki"},
+	{54.0, 59.7, 21.0, 28.2, "Europe/Riga"},
+	{51.3, 56.2, 26.0, 32.8, "Europe/Minsk"},
+	{54.5, 71.2, 4.5, 20.5, "Europe/Stockholm"},
+	{50.7, 53.6, 2.5, 7.2, "Europe/Amsterdam"},
+	{45.8, 55.1, 5.8, 15.1, "Europe/Berlin"},
+	{36.6, 47.1, 6.6, 18.6, "Europe/Rome"},
+	{40.8, 46.6, 13.3, 23.0, "Europe/Belgrade"},
+	{45.7, 54.9, 12.0, 24.2, "Europe/Warsaw"},
+	{36.0, 42.1, 27.0, 44.8, "Europe/Istanbul"},
+	{29.4, 33.4, 34.2, 35.9, "Asia/Jerusalem"},
+	{29.2, 37.3, 35.9, 42.4, "Asia/Amman"},
+	{34.8, 52.4, 20.0, 40.2, "Europe/Athens"},
+	{51.0, 54.5, 47.5, 53.5, "Europe/Samara"},
+	{41.0, 70.0, 27.0, 50.0, "Europe/Moscow"},
+
+	// Africa
+	{22.0, 31.7, 24.7, 36.9, "Africa/Cairo"},
+	{27.6, 35.9, -13.2, -1.0, "Africa/Casablanca"},
+	{19.0, 37.1, -8.7, 9.3, "Africa/Algiers"},
+	{19.5, 33.2, 9.3, 24.7, "Africa/Tripoli"},
+	{8.7, 22.0, 21.8, 36.5, "Africa/Khartoum"},
+	{-2.9, -1.0, 28.8, 30.9, "Africa/Kigali"},
+	{-25.6, -11.9, 43.2, 50.5, "Indian/Antananarivo"},
+	{-35.0, -18.0, 11.7, 41.0, "Africa/Johannesburg"},
+	{-18.0, -4.4, 11.7, 24.0, "Africa/Luanda"},
+	{-18.0, -8.0, 24.0, 41.0, "Africa/Maputo"},
+	{-12.0, 18.0, 29.0, 52.0, "Africa/Nairobi"},
+	{-18.0, 37.5, 2.0, 16.0, "Africa/Lagos"},
+	{4.0, 27.0, -17.6, 2.0, "Africa/Abidjan"},
+
+	// Asia
+	{24.4, 26.4, 50.3, 51.5, "Asia/Qatar"},
+	{16.6, 26.4, 51.5, 60.0, "Asia/Dubai"},
+	{25.0, 39.8, 48.0, 63.3, "Asia/Tehran"},
+	{12.0, 37.5, 34.5, 55.0, "Asia/Riyadh"},
+	{31.0, 38.5, 60.5, 70.0, "Asia/Kabul"},
+	{23.6, 37.1, 60.9, 74.6, "Asia/Karachi"},
+	{26.3, 30.5, 80.0, 88.2, "Asia/Kathmandu"},
+	{20.6, 26.6, 88.6, 92.7, "Asia/Dhaka"},
+	{15.5, 21.5, 92.2, 98.0, "Asia/Yangon"},
+	{9.5, 15.5, 97.5, 99.0, "Asia/Yangon"},
+	{21.5, 28.6, 94.5, 101.2, "Asia/Yangon"},
+	{6.5, 28.5, 68.0, 97.4, "Asia/Kolkata"},
+	{28.5, 35.7, 68.0, 80.5, "Asia/Kolkata"},
+	{4.5, 21.2, 116.9, 126.6, "Asia/Manila"},
+	{0.8, 6.5, 99.6, 119.3, "Asia/Kuala_Lumpur"},
+	{5.6, 20.5, 97.3, 109.5, "Asia/Bangkok"},
+	{20.5, 23.4, 100.0, 106.8, "Asia/Bangkok"},
+	{-11.0, 6.0, 95.0, 114.5, "Asia/Jakarta"},
+	{-11.0, 6.0, 114.5, 125.0, "Asia/Makassar"},
+	{-11.0, 1.0, 125.0, 141.0, "Asia/Jayapura"},
+	{33.0, 42.0, 124.6, 131.0, "Asia/Seoul"},
+	{24.0, 41.4, 122.9, 146.0, "Asia/Tokyo"},
+	{41.4, 45.6, 139.3, 146.0, "Asia/Tokyo"},
+	{41.5, 52.2, 87.7, 119.9, "Asia/Ulaanbaatar"},
+	{39.2, 43.0, 69.3, 80.3, "Asia/Bishkek"},
+	{35.0, 45.6, 52.0, 75.0, "Asia/Tashkent"},
+	{40.5, 51.0, 46.5, 61.0, "Asia/Almaty"},
+	{40.5, 55.5, 61.0, 70.0, "Asia/Almaty"},
+	{40.5, 54.0, 70.0, 76.0, "Asia/Almaty"},
+	{40.5, 51.5, 76.0, 87.4, "Asia/Almaty"},
+	{42.3, 50.0, 130.7, 141.0, "Asia/Vladivostok"},
+	{18.0, 49.2, 73.5, 135.1, "Asia/Shanghai"},
+	{49.2, 53.6, 115.5, 135.1, "Asia/Shanghai"},
+	{50.0, 78.0, 50.0, 66.0, "Asia/Yekaterinburg"},
+	{50.0, 78.0, 66.0, 82.0, "Asia/Omsk"},
+	{50.0, 78.0, 82.0, 103.0, "Asia/Krasnoyarsk"},
+	{50.0, 78.0, 103.0, 120.0, "Asia/Irkutsk"},
+	{50.0, 78.0, 120.0, 130.7, "Asia/Yakutsk"},
+	{42.3, 78.0, 130.7, 141.0, "Asia/Vladivostok"},
+	{42.3, 78.0, 141.0, 156.0, "Asia/Magadan"},
+	{50.0, 78.0, 156.0, 180.0, "Asia/Kamchatka"},
+
+	// North and Central America
+	{18.9, 22.3, -160.3, -154.8, "Pacific/Honolulu"},
+	{54.6, 60.0, -141.0, -129.9, "America/Juneau"},
+	{51.0, 71.5, -170.0, -141.0, "America/Anchorage"},
+	{60.0, 69.7, -141.0, -123.8, "America/Whitehorse"},
+	{31.3, 37.0, -114.8, -109.05, "America/Phoenix"},
+	{28.0, 32.7, -117.2, -114.7, "America/Tijuana"},
+	{26.3, 31.3, -115.0, -108.4, "America/Hermosillo"},
+	{32.5, 42.0, -124.5, -114.0, "America/Los_Angeles"},
+	{42.0, 60.0, -139.0, -116.5, "America/Los_Angeles"},
+	{49.0, 60.0, -110.0, -101.4, "America/Regina"},
+	{31.3, 37.0, -109.05, -103.0, "America/Denver"},
+	{37.0, 60.0, -116.5, -101.5, "America/Denver"},
+	{24.5, 37.0, -85.3, -75.0, "America/New_York"},
+	{37.0, 49.0, -87.5, -66.9, "America/New_York"},
+	{49.0, 62.0, -90.0, -66.9, "America/Toronto"},
+	{43.4, 60.0, -66.9, -59.7, "America/Halifax"},
+	{46.6, 52.0, -59.5, -52.6, "America/St_Johns"},
+	{19.8, 23.3, -85.0, -74.1, "America/Havana"},
+	{20.9, 27.3, -79.3, -72.7, "America/Nassau"},
+	{25.8, 60.0, -106.7, -82.0, "America/Chicago"},
+	{17.8, 21.7, -89.2, -86.7, "America/Cancun"},
+	{7.0, 9.7, -83.0, -77.1, "America/Panama"},
+	{7.0, 18.5, -92.3, -82.5, "America/Guatemala"},
+	{14.5, 32.7, -118.0, -86.7, "America/Mexico_City"},
+	{17.7, 18.6, -78.4, -76.2, "America/Jamaica"},
+	{18.0, 20.1, -74.5, -71.6, "America/Port-au-Prince"},
+	{17.5, 20.0, -71.6, -68.3, "America/Santo_Domingo"},
+	{10.0, 18.7, -68.0, -59.4, "America/Puerto_Rico"},
+
+	// South America
+	{1.1, 8.6, -61.4, -57.0, "America/Guyana"},
+	{1.8, 6.1, -57.0, -54.0, "America/Paramaribo"},
+	{2.1, 5.8, -54.0, -51.6, "America/Cayenne"},
+	{7.5, 12.3, -72.0, -59.8, "America/Caracas"},
+	{0.6, 7.5, -67.9, -59.8, "America/Caracas"},
+	{-4.3, 12.5, -79.1, -66.9, "America/Bogota"},
+	{-5.0, 1.5, -81.1, -75.2, "America/Guayaquil"},
+	{-18.4, -0.03, -81.4, -68.7, "America/Lima"},
+	{-22.9, -9.7, -69.6, -57.5, "America/La_Paz"},
+	{-11.1, -7.1, -74.0, -66.6, "America/Rio_Branco"},
+	{-13.7, 5.3, -74.0, -56.5, "America/Manaus"},
+	{-18.1, -7.3, -61.6, -50.2, "America/Cuiaba"},
+	{-27.6, -22.0, -62.7, -54.3, "America/Asuncion"},
+	{-22.0, -19.3, -62.7, -57.9, "America/Asuncion"},
+	{-24.1, -17.2, -58.2, -50.9, "America/Campo_Grande"},
+	{-28.0, -17.5, -76.0, -68.4, "America/Santiago"},
+	{-44.0, -28.0, -76.0, -70.0, "America/Santiago"},
+	{-56.0, -44.0, -76.0, -71.5, "America/Santiago"},
+	{-55.1, -21.8, -73.6, -53.6, "America/Argentina/Buenos_Aires"},
+	{-33.8, 5.3, -74.0, -34.7, "America/Sao_Paulo"},
+
+	// Oceania
+	{-35.2, -13.7, 112.9, 129.0, "Australia/Perth"},
+	{-26.0, -10.9, 129.0, 138.0, "Australia/Darwin"},
+	{-38.1, -26.0, 129.0, 141.0, "Australia/Adelaide"},
+	{-29.2, -9.0, 138.0, 154.0, "Australia/Brisbane"},
+	{-44.0, -29.0, 141.0, 154.0, "Australia/Sydney"},
+	{-11.7, -1.0, 141.0, 156.0, "Pacific/Port_Moresby"},
+	{-47.4, -34.3, 166.3, 178.7, "Pacific/Auckland"},
+}
+
+// zoneAt returns the time zone of a location, or the nautical zone of its
+// longitude (UTC+2 for 30°E) outside the known areas
+func zoneAt(lat, lon float64) *time.Location {
+	for _, b := range zoneBounds {
+		if lat >= b.south && lat <= b.north && lon >= b.west && lon <= b.east {
+			if loc, err := time.LoadLocation(b.zone); err == nil {
+				return loc
+			}
+			break
+		}
+	}
+	offset := int(math.Round(lon / 15))
+	return time.FixedZone(fmt.Sprintf("UTC%+d", offset), offset*3600)
+}
+
+// iso6709Location matches the decimal degrees of ISO 6709 locations, such as
+// +48.8584+002.2945+035.000/ written by phones in their videos
+var iso6709Location = regexp.MustCompile(`^([+-]\d{1,2}(?:\.\d+)?)([+-]\d{1,3}(?:\.\d+)?)`)
+
+// parseISO6709 returns the latitude and longitude of an ISO 6709 location
+func parseISO6709(s string) (lat, lon float64, ok bool) {
+	m := iso6709Location.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(m[1], 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, false
+	}
+	lon, err = strconv.ParseFloat(m[2], 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// movieLocation returns the GPS location of the user data (udta/©xyz) of
+// the movie box between start and end, written by phones recording videos
+func movieLocation(r io.ReadSeeker, start, end int64) (lat, lon float64, ok bool) {
+	start, end, err := findBox(r, start, end, "udta")
+	if err != nil {
+		return 0, 0, false
+	}
+	start, end, err = findBox(r, start, end, "\xa9xyz")
+	if err != nil || end-start < 4 || end-start > 256 {
+		return 0, 0, false
+	}
+
+	// A 16-bit length and language precede the text
+	data := make([]byte, end-start)
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return 0, 0, false
+	}
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, 0, false
+	}
+	length := int(binary.BigEndian.Uint16(data[:2]))
+	if length > len(data)-4 {
+		return 0, 0, false
+	}
+	return parseISO6709(string(data[4 : 4+length]))
+}
+
+// localTime returns the wall clock time at a location of a UTC time, as a
+// time in UTC like the dates read from EXIF
+func localTime(t time.Time, lat, lon float64) time.Time {
+	local := t.In(zoneAt(lat, lon))
+	return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC)
+}
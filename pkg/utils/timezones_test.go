@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// createLocatedMovie builds a minimal MP4 file created at date, recorded at
+// an ISO 6709 location like phones do
+func createLocatedMovie(date time.Time, location string) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[4:8], uint32(date.Sub(movieEpoch)/time.Second))
+	xyz := binary.BigEndian.AppendUint16(nil, uint16(len(location)))
+	xyz = append(binary.BigEndian.AppendUint16(xyz, 0x15c7), location...)
+	moov := append(isoBox("mvhd", mvhd), isoBox("udta", isoBox("\xa9xyz", xyz))...)
+	return append(isoBox("ftyp", []byte("qt  \x00\x00\x00\x00")), isoBox("moov", moov)...)
+}
+
+func TestZoneAt(t *testing.T) {
+	tests := []struct {
+		place    string
+		lat, lon float64
+		want     string
+	}{
+		{"Paris", 48.8566, 2.3522, "Europe/Paris"},
+		{"London", 51.5074, -0.1278, "Europe/London"},
+		{"Berlin", 52.52, 13.405, "Europe/Berlin"},
+		{"Vienna", 48.2082, 16.3738, "Europe/Warsaw"},
+		{"Athens", 37.9838, 23.7275, "Europe/Athens"},
+		{"Saint Petersburg", 59.9311, 30.3609, "Europe/Moscow"},
+		{"New York", 40.7128, -74.006, "America/New_York"},
+		{"Chicago", 41.8781, -87.6298, "America/Chicago"},
+		{"Denver", 39.7392, -104.9903, "America/Denver"},
+		{"Phoenix", 33.4484, -112.074, "America/Phoenix"},
+		{"San Francisco", 37.7749, -122.4194, "America/Los_Angeles"},
+		{"Mexico City", 19.4326, -99.1332, "America/Mexico_City"},
+		{"São Paulo", -23.5505, -46.6333, "America/Sao_Paulo"},
+		{"Buenos Aires", -34.6037, -58.3816, "America/Argentina/Buenos_Aires"},
+		{"Cape Town", -33.9249, 18.4241, "Africa/Johannesburg"},
+		{"Nairobi", -1.2921, 36.8219, "Africa/Nairobi"},
+		{"Mumbai", 19.076, 72.8777, "Asia/Kolkata"},
+		{"Beijing", 39.9042, 116.4074, "Asia/Shanghai"},
+		{"Tokyo", 35.6762, 139.6503, "Asia/Tokyo"},
+		{"Sydney", -33.8688, 151.2093, "Australia/Sydney"},
+		{"Pacific Ocean", 0, -140, "UTC-9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.place, func(t *testing.T) {
+			if got := zoneAt(tt.lat, tt.lon).String(); got != tt.want {
+				t.Errorf("zoneAt(%v, %v) = %s, want %s", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseISO6709(t *testing.T) {
+	tests := []struct {
+		location string
+		lat, lon float64
+		ok       bool
+	}{
+		{"+48.8584+002.2945+035.000/", 48.8584, 2.2945, true},
+		{"-33.8688+151.2093/", -33.8688, 151.2093, true},
+		{"+40.7128-074.0060/", 40.7128, -74.006, true},
+		{"+95.0000+002.0000/", 0, 0, false},
+		{"48.8584,2.2945", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		lat, lon, ok := parseISO6709(tt.location)
+		if lat != tt.lat || lon != tt.lon || ok != tt.ok {
+			t.Errorf("parseISO6709(%q) = %v, %v, %v, want %v, %v, %v", tt.location, lat, lon, ok, tt.lat, tt.lon, tt.ok)
+		}
+	}
+}
+
+func TestMovieCreationTimeLocation(t *testing.T) {
+	// Recorded at 23:30 UTC in New York on a summer evening, 19:30 local time
+	utc := time.Date(2024, time.June, 11, 23, 30, 10, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		location string
+		want     time.Time
+	}{
+		{"New York", "+40.7128-074.0060/", time.Date(2024, time.June, 11, 19, 30, 10, 0, time.UTC)},
+		{"Tokyo", "+35.6762+139.6503/", time.Date(2024, time.June, 12, 8, 30, 10, 0, time.UTC)},
+		{"invalid location", "nowhere", utc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := movieCreationTime(bytes.NewReader(createLocatedMovie(utc, tt.location)), false)
+			if err != nil {
+				t.Fatalf("movieCreationTime() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("movieCreationTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMovieCreationTimeNoGPS(t *testing.T) {
+	// Privacy mode never reads the location, the recorded UTC time is kept
+	utc := time.Date(2024, time.June, 11, 23, 30, 10, 0, time.UTC)
+	got, err := movieCreationTime(bytes.NewReader(createLocatedMovie(utc, "+40.7128-074.0060/")), true)
+	if err != nil {
+		t.Fatalf("movieCreationTime() error = %v", err)
+	}
+	if !got.Equal(utc) {
+		t.Errorf("movieCreationTime() = %v, want %v", got, utc)
+	}
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// OtherDir is the destination subtree of the files of unsupported formats
+// copied with -copy-unknown
+const OtherDir = "other"
+
+// isUnknownFile reports whether a file is only imported because of
+// -copy-unknown, its format having no capture date the tool can read
+func isUnknownFile(p *models.Params, path string) bool {
+	return p.CopyUnknown && !isMediaFile(p, path)
+}
+
+// unknownDestination returns where a file of an unsupported format is copied,
+// dated by its modification time: <dest>/other/YYYY/MM-DD/<name>
+func unknownDestination(p *models.Params, source string, info os.FileInfo) string {
+	destPath := destinationPath(p, source, info.ModTime())
+	root := destinationRoot(p, info.ModTime())
+	rel, err := filepath.Rel(root, destPath)
+	if err != nil {
+		return destPath
+	}
+	return filepath.Join(root, OtherDir, rel)
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestProcessMediaFilesCopyUnknown(t *testing.T) {
+	source := t.TempDir()
+	modTime := time.Date(2023, 5, 4, 12, 0, 0, 0, time.Local)
+	files := map[string][]byte{
+		"photo.jpg":                       createFakeExifData(),
+		"notes.txt":                       []byte("notes"),
+		filepath.Join("MISC", ".nomedia"): nil, // Empty files are copied as they are
+	}
+	for name, data := range files {
+		path := filepath.Join(source, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set time: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		copyUnknown bool
+		want        []string
+		absent      []string
+	}{
+		{
+			name:   "unknown files ignored",
+			want:   []string{filepath.Join("2025", "01-11", "photo.jpg")},
+			absent: []string{OtherDir},
+		},
+		{
+			name:        "unknown files copied",
+			copyUnknown: true,
+			want: []string{
+				filepath.Join("2025", "01-11", "photo.jpg"),
+				filepath.Join(OtherDir, "2023", "05-04", "notes.txt"),
+				filepath.Join(OtherDir, "2023", "05-04", ".nomedia"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dest := t.TempDir()
+			params := &models.Params{Source: source, Destination: dest, Compression: -1, CopyUnknown: tc.copyUnknown}
+
+			count, _, err := CountImportableFiles(params)
+			if err != nil || count != len(tc.want) {
+				t.Errorf("CountImportableFiles() = %d, %v, want %d", count, err, len(tc.want))
+			}
+
+			plan, err := PlanMediaFiles(params)
+			if err != nil {
+				t.Fatalf("PlanMediaFiles() error = %v", err)
+			}
+			if conflicts := PlanConflicts(plan); len(conflicts) != 0 {
+				t.Errorf("PlanConflicts() = %v, want none", conflicts)
+			}
+
+			summary, err := ProcessMediaFiles(params)
+			if err != nil {
+				t.Fatalf("ProcessMediaFiles() error = %v", err)
+			}
+			if summary.Copied != len(tc.want) {
+				t.Errorf("Expected %d copied files, got %+v", len(tc.want), summary)
+			}
+			for _, rel := range tc.want {
+				if _, err := os.Stat(filepath.Join(dest, rel)); err != nil {
+					t.Errorf("Expected %s: %v", rel, err)
+				}
+			}
+			for _, rel := range tc.absent {
+				if _, err := os.Stat(filepath.Join(dest, rel)); !os.IsNotExist(err) {
+					t.Errorf("Expected no %s", rel)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Version, commit and build date of the tool, recorded with each run. They are
+// set at build time with -ldflags "-X github.com/matdmb/organize-media/pkg/utils.ToolVersion=v1.2.0",
+// the build information of the Go toolchain is used otherwise.
+var (
+	ToolVersion   = ""
+	ToolCommit    = ""
+	ToolBuildDate = ""
+)
+
+// BuildInfo identifies the build of the tool that ran an import
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version,omitempty"`
+}
+
+// CurrentBuild returns the build information of the running tool
+func CurrentBuild() BuildInfo {
+	build := BuildInfo{
+		Version:   ToolVersion,
+		Commit:    ToolCommit,
+		BuildDate: ToolBuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	// Fill in what the build flags left out from the module and VCS information
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if build.Version == "" && info.Main.Version != "" {
+			build.Version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if build.Commit == "" {
+					build.Commit = setting.Value
+				}
+			case "vcs.time":
+				if build.BuildDate == "" {
+					build.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				if setting.Value == "true" && build.Commit != "" && !strings.HasSuffix(build.Commit, "-dirty") {
+					build.Commit += "-dirty"
+				}
+			}
+		}
+	}
+	if build.Version == "" {
+		build.Version = "(devel)"
+	}
+	return build
+}
+
+// String formats the build information, such as
+// v1.2.0 (commit 3fa2c1d, built 2025-01-11T17:10:39Z, go1.23.0)
+func (b BuildInfo) String() string {
+	var details []string
+	if b.Commit != "" {
+		details = append(details, "commit "+shortCommit(b.Commit))
+	}
+	if b.BuildDate != "" {
+		details = append(details, "built "+b.BuildDate)
+	}
+	if b.GoVersion != "" {
+		details = append(details, b.GoVersion)
+	}
+	if len(details) == 0 {
+		return b.Version
+	}
+	return b.Version + " (" + strings.Join(details, ", ") + ")"
+}
+
+// shortCommit abbreviates a commit hash to 7 characters, keeping the -dirty
+// marker of builds from a modified tree
+func shortCommit(commit string) string {
+	hash, dirty := strings.CutSuffix(commit, "-dirty")
+	if len(hash) > 7 {
+		hash = hash[:7]
+	}
+	if dirty {
+		return hash + "-dirty"
+	}
+	return hash
+}
@@ -0,0 +1,39 @@
+package utils
+
+import "testing"
+
+func TestBuildInfoString(t *testing.T) {
+	tests := []struct {
+		name  string
+		build BuildInfo
+		want  string
+	}{
+		{"full", BuildInfo{Version: "v1.2.0", Commit: "3fa2c1d9e8b7a6", BuildDate: "2025-01-11T17:10:39Z", GoVersion: "go1.23.0"}, "v1.2.0 (commit 3fa2c1d, built 2025-01-11T17:10:39Z, go1.23.0)"},
+		{"modified tree", BuildInfo{Version: "v1.2.0", Commit: "3fa2c1d9e8b7a6-dirty"}, "v1.2.0 (commit 3fa2c1d-dirty)"},
+		{"short commit", BuildInfo{Version: "v1.2.0", Commit: "3fa2"}, "v1.2.0 (commit 3fa2)"},
+		{"version only", BuildInfo{Version: "(devel)"}, "(devel)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.build.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrentBuildLdflags(t *testing.T) {
+	defer func(version, commit, date string) {
+		ToolVersion, ToolCommit, ToolBuildDate = version, commit, date
+	}(ToolVersion, ToolCommit, ToolBuildDate)
+	ToolVersion, ToolCommit, ToolBuildDate = "v1.2.0", "3fa2c1d9e8b7a6", "2025-01-11T17:10:39Z"
+
+	build := CurrentBuild()
+	if build.Version != "v1.2.0" || build.Commit != "3fa2c1d9e8b7a6" || build.BuildDate != "2025-01-11T17:10:39Z" {
+		t.Errorf("CurrentBuild() = %+v, want the values set at build time", build)
+	}
+	if build.GoVersion == "" {
+		t.Errorf("CurrentBuild() = %+v, want the Go version", build)
+	}
+}
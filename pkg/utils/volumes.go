@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CameraDir is the directory holding pictures on camera memory cards (DCF standard)
+const CameraDir = "DCIM"
+
+// For testing purposes
+var (
+	procMounts  = "/proc/mounts"
+	volumesRoot = "/Volumes"
+)
+
+// mountPrefixes are the Linux mount locations of removable volumes
+var mountPrefixes = []string{"/media/", "/run/media/", "/mnt/"}
+
+// FindCardVolumes returns the DCIM directories of mounted removable volumes,
+// such as memory cards and cameras connected over USB
+func FindCardVolumes() ([]string, error) {
+	roots, err := mountRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []string
+	for _, root := range roots {
+		dir := filepath.Join(root, CameraDir)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			cards = append(cards, dir)
+		}
+	}
+	sort.Strings(cards)
+	return cards, nil
+}
+
+// mountRoots returns the root directories of mounted volumes that may be removable
+func mountRoots() ([]string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		var roots []string
+		for letter := 'A'; letter <= 'Z'; letter++ {
+			roots = append(roots, string(letter)+`:\`)
+		}
+		return roots, nil
+	case "darwin":
+		entries, err := os.ReadDir(volumesRoot)
+		if err != nil {
+			return nil, err
+		}
+		var roots []string
+		for _, entry := range entries {
+			roots = append(roots, filepath.Join(volumesRoot, entry.Name()))
+		}
+		return roots, nil
+	default:
+		return linuxMountRoots(procMounts)
+	}
+}
+
+// linuxMountRoots returns the mount points listed in a mounts file that are
+// located where desktops and users mount removable volumes
+func linuxMountRoots(mounts string) ([]string, error) {
+	file, err := os.Open(mounts)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var roots []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		mountPoint := unescapeMountPoint(fields[1])
+		for _, prefix := range mountPrefixes {
+			if strings.HasPrefix(mountPoint, prefix) {
+				roots = append(roots, mountPoint)
+				break
+			}
+		}
+	}
+	return roots, scanner.Err()
+}
+
+// unescapeMountPoint decodes the octal escapes (\040 for spaces) of /proc/mounts
+func unescapeMountPoint(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
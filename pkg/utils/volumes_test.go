@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestUnescapeMountPoint(t *testing.T) {
+	tests := map[string]string{
+		"/media/user/CARD":           "/media/user/CARD",
+		`/media/user/NO\040NAME`:     "/media/user/NO NAME",
+		`/media/user/tab\011name`:    "/media/user/tab\tname",
+		`/media/user/trailing\04`:    `/media/user/trailing\04`,
+		`/media/user/not\999octal`:   `/media/user/not\999octal`,
+		`/media/user/two\040spa\040`: "/media/user/two spa ",
+	}
+	for input, want := range tests {
+		if got := unescapeMountPoint(input); got != want {
+			t.Errorf("unescapeMountPoint(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestLinuxMountRoots(t *testing.T) {
+	mounts := filepath.Join(t.TempDir(), "mounts")
+	content := `/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+proc /proc proc rw,nosuid 0 0
+/dev/mmcblk0p1 /media/user/NO\040NAME vfat rw,nosuid 0 0
+/dev/sdb1 /run/media/user/EOS_DIGITAL exfat rw 0 0
+/dev/sdc1 /mnt/card vfat rw 0 0
+`
+	if err := os.WriteFile(mounts, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create mounts file: %v", err)
+	}
+
+	roots, err := linuxMountRoots(mounts)
+	if err != nil {
+		t.Fatalf("linuxMountRoots() error = %v", err)
+	}
+	want := []string{"/media/user/NO NAME", "/run/media/user/EOS_DIGITAL", "/mnt/card"}
+	if fmt.Sprint(roots) != fmt.Sprint(want) {
+		t.Errorf("linuxMountRoots() = %q, want %q", roots, want)
+	}
+
+	if _, err := linuxMountRoots(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("linuxMountRoots() expected error for a missing mounts file")
+	}
+}
+
+func TestFindCardVolumes(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Mount detection is only simulated on Linux")
+	}
+
+	root := t.TempDir()
+	card := filepath.Join(root, "card")
+	usbDrive := filepath.Join(root, "usb")
+	for _, dir := range []string{filepath.Join(card, CameraDir), usbDrive} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	mounts := filepath.Join(root, "mounts")
+	content := fmt.Sprintf("/dev/sdb1 %s vfat rw 0 0\n/dev/sdc1 %s ext4 rw 0 0\n", card, usbDrive)
+	if err := os.WriteFile(mounts, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create mounts file: %v", err)
+	}
+
+	originalMounts, originalPrefixes := procMounts, mountPrefixes
+	procMounts, mountPrefixes = mounts, []string{root + string(filepath.Separator)}
+	defer func() { procMounts, mountPrefixes = originalMounts, originalPrefixes }()
+
+	cards, err := FindCardVolumes()
+	if err != nil {
+		t.Fatalf("FindCardVolumes() error = %v", err)
+	}
+	if want := []string{filepath.Join(card, CameraDir)}; fmt.Sprint(cards) != fmt.Sprint(want) {
+		t.Errorf("FindCardVolumes() = %v, want %v", cards, want)
+	}
+}
@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/output"
+)
+
+// Automatic worker tuning settings
+const (
+	tuneInterval    = time.Second
+	maxAutoWorkers  = 64
+	cpuBoundIOShare = 0.3  // Below this share of time spent on IO, the run is CPU bound
+	rateTolerance   = 0.05 // Throughput drop tolerated before reverting a change
+)
+
+// fileJob is a source file waiting to be processed
+type fileJob struct {
+	path     string
+	info     os.FileInfo
+	deferred bool // Processed again after changing while it was read
+}
+
+// workerPool processes files concurrently. The number of active workers can
+// change during the run when the worker count is tuned automatically.
+type workerPool struct {
+	jobs    chan fileJob
+	wg      sync.WaitGroup
+	process func(fileJob, *ProcessingSummary)
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	active  int
+	limit   int
+	peak    int
+	summary ProcessingSummary
+
+	stopTuner chan struct{}
+	tunerDone chan struct{}
+}
+
+// newWorkerPool starts the workers of a run. workers is the number of files
+// processed concurrently, or models.AutoWorkers to tune it during the run.
+func newWorkerPool(workers int, process func(fileJob, *ProcessingSummary)) *workerPool {
+	auto := workers == models.AutoWorkers
+	size, limit := max(1, workers), max(1, workers)
+	if auto {
+		size, limit = autoWorkerBounds()
+	}
+
+	pool := &workerPool{
+		jobs:    make(chan fileJob, size),
+		process: process,
+		limit:   limit,
+		peak:    limit,
+	}
+	pool.cond = sync.NewCond(&pool.mu)
+
+	for i := 0; i < size; i++ {
+		pool.wg.Add(1)
+		go pool.work()
+	}
+
+	if auto {
+		pool.stopTuner = make(chan struct{})
+		pool.tunerDone = make(chan struct{})
+		go pool.tune(newWorkerTuner(limit, 1, size, runtime.NumCPU()))
+	}
+	return pool
+}
+
+// autoWorkerBounds returns the maximum and initial number of workers in auto mode
+func autoWorkerBounds() (int, int) {
+	cpus := runtime.NumCPU()
+	return min(maxAutoWorkers, max(4, cpus*4)), cpus
+}
+
+// submit queues a file for processing
+func (w *workerPool) submit(job fileJob) {
+	w.jobs <- job
+}
+
+// close waits for queued files to be processed and returns the merged summary
+func (w *workerPool) close() ProcessingSummary {
+	close(w.jobs)
+	w.wg.Wait()
+	if w.stopTuner != nil {
+		close(w.stopTuner)
+		<-w.tunerDone
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.summary.Stats.Workers = w.peak
+	return w.summary
+}
+
+// work processes files while only limit workers are active at a time
+func (w *workerPool) work() {
+	defer w.wg.Done()
+	for job := range w.jobs {
+		w.mu.Lock()
+		for w.active >= w.limit {
+			w.cond.Wait()
+		}
+		w.active++
+		w.mu.Unlock()
+
+		var fileSummary ProcessingSummary
+		w.process(job, &fileSummary)
+
+		w.mu.Lock()
+		w.active--
+		w.summary.add(fileSummary)
+		w.mu.Unlock()
+		w.cond.Signal()
+	}
+}
+
+// setLimit changes the number of active workers
+func (w *workerPool) setLimit(limit int) {
+	w.mu.Lock()
+	w.limit = limit
+	w.peak = max(w.peak, limit)
+	w.mu.Unlock()
+	w.cond.Broadcast()
+}
+
+// snapshot returns the summary of the files processed so far
+func (w *workerPool) snapshot() ProcessingSummary {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.summary
+}
+
+// tune periodically adapts the number of active workers to the observed workload
+func (w *workerPool) tune(tuner *workerTuner) {
+	defer close(w.tunerDone)
+
+	ticker := time.NewTicker(tuneInterval)
+	defer ticker.Stop()
+
+	var previous IOStats
+	for {
+		select {
+		case <-w.stopTuner:
+			return
+		case <-ticker.C:
+			stats := w.snapshot().Stats
+			io := (stats.Read - previous.Read) + (stats.Write - previous.Write)
+			cpu := (stats.Extract - previous.Extract) + (stats.Compress - previous.Compress)
+			bytes := (stats.BytesRead - previous.BytesRead) + (stats.BytesWritten - previous.BytesWritten)
+			previous = stats
+
+			if io+cpu == 0 {
+				continue // Nothing finished during this interval
+			}
+			ioShare := io.Seconds() / (io + cpu).Seconds()
+			limit := tuner.adjust(ioShare, float64(bytes)/tuneInterval.Seconds())
+			output.Debug(fmt.Sprintf("Workers: %d (IO share %.0f%%)", limit, ioShare*100))
+			w.setLimit(limit)
+		}
+	}
+}
+
+// workerTuner searches the number of workers giving the best throughput.
+// IO-bound runs (network shares, fast SSDs) benefit from more concurrent
+// requests than CPUs, while CPU-bound runs (compression) never go beyond the
+// number of CPUs. A change that lowers throughput is reverted.
+type workerTuner struct {
+	limit     int
+	min, max  int
+	cpus      int
+	direction int
+	lastRate  float64
+}
+
+func newWorkerTuner(limit, minWorkers, maxWorkers, cpus int) *workerTuner {
+	return &workerTuner{limit: limit, min: minWorkers, max: maxWorkers, cpus: cpus}
+}
+
+// adjust returns the number of workers to use given the share of time spent
+// on IO and the throughput in bytes per second of the last interval
+func (t *workerTuner) adjust(ioShare, rate float64) int {
+	switch {
+	case ioShare < cpuBoundIOShare:
+		// Workers beyond the number of CPUs only compete for them
+		t.direction = 0
+		if t.limit > t.cpus {
+			t.direction = -1
+		}
+	case t.direction != 0 && rate < t.lastRate*(1-rateTolerance):
+		// The last change made things worse, go back
+		t.direction = -t.direction
+	case t.direction == 0:
+		t.direction = 1
+	}
+
+	t.limit = min(t.max, max(t.min, t.limit+t.direction))
+	t.lastRate = rate
+	return t.limit
+}
+
+// add merges the summary of a processed file
+func (s *ProcessingSummary) add(o ProcessingSummary) {
+	s.Processed += o.Processed
+	s.Compressed += o.Compressed
+	s.Copied += o.Copied
+	s.Skipped += o.Skipped
+	for _, reason := range SkipReasons {
+		*s.Skips.counter(reason) += o.Skips.Count(reason)
+	}
+	s.Failed += o.Failed
+	s.Deleted += o.Deleted
+	s.Verified += o.Verified
+	s.Salvaged += o.Salvaged
+	s.Corrupt += o.Corrupt
+	s.Culled += o.Culled
+	s.Rejected += o.Rejected
+	s.Protected += o.Protected
+	s.Mirrored += o.Mirrored
+	s.MirrorFailed += o.MirrorFailed
+	s.EditsKept += o.EditsKept
+	s.Lightroom += o.Lightroom
+	s.CacheHits += o.CacheHits
+
+	s.Stats.BytesRead += o.Stats.BytesRead
+	s.Stats.BytesWritten += o.Stats.BytesWritten
+	s.Stats.PeakReadRate = max(s.Stats.PeakReadRate, o.Stats.PeakReadRate)
+	s.Stats.PeakWriteRate = max(s.Stats.PeakWriteRate, o.Stats.PeakWriteRate)
+	s.Stats.Read += o.Stats.Read
+	s.Stats.Extract += o.Stats.Extract
+	s.Stats.Compress += o.Stats.Compress
+	s.Stats.Write += o.Stats.Write
+	s.Stats.Busy += o.Stats.Busy
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestWorkerTuner(t *testing.T) {
+	type step struct {
+		ioShare float64
+		rate    float64
+		want    int
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		steps []step
+	}{
+		{
+			name:  "IO bound grows while throughput improves",
+			limit: 4,
+			steps: []step{{0.9, 100, 5}, {0.9, 120, 6}, {0.9, 130, 7}},
+		},
+		{
+			name:  "IO bound reverts a change lowering throughput",
+			limit: 4,
+			steps: []step{{0.9, 100, 5}, {0.9, 80, 4}, {0.9, 90, 3}},
+		},
+		{
+			name:  "CPU bound shrinks back to the number of CPUs",
+			limit: 6,
+			steps: []step{{0.1, 100, 5}, {0.1, 100, 4}, {0.1, 100, 4}},
+		},
+		{
+			name:  "bounded",
+			limit: 8,
+			steps: []step{{0.9, 100, 8}, {0.9, 100, 8}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tuner := newWorkerTuner(tt.limit, 1, 8, 4)
+			for i, s := range tt.steps {
+				if got := tuner.adjust(s.ioShare, s.rate); got != s.want {
+					t.Fatalf("step %d: adjust(%v, %v) = %d, want %d", i, s.ioShare, s.rate, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+	}{
+		{"default", 0},
+		{"single", 1},
+		{"several", 4},
+		{"auto", models.AutoWorkers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, peak atomic.Int32
+			pool := newWorkerPool(tt.workers, func(job fileJob, summary *ProcessingSummary) {
+				n := running.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+
+				summary.Processed++
+				summary.Stats.addRead(10, time.Millisecond)
+			})
+
+			for i := 0; i < 50; i++ {
+				pool.submit(fileJob{path: fmt.Sprintf("file%d.jpg", i)})
+			}
+			summary := pool.close()
+
+			if summary.Processed != 50 || summary.Stats.BytesRead != 500 {
+				t.Errorf("summary = %+v, want 50 files and 500 bytes", summary)
+			}
+			if tt.workers >= 1 && int(peak.Load()) > tt.workers {
+				t.Errorf("%d files processed concurrently, want at most %d", peak.Load(), tt.workers)
+			}
+			if summary.Stats.Workers < 1 {
+				t.Errorf("Stats.Workers = %d, want at least 1", summary.Stats.Workers)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesWorkers(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	data := createFakeExifData()
+
+	// Files with the same name and date in different folders share a destination
+	for i := 0; i < 20; i++ {
+		dir := filepath.Join(sourceDir, fmt.Sprintf("dir%d", i%2))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("photo%d.jpg", i/2)), data, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	summary, err := ProcessMediaFiles(&models.Params{
+		Source:      sourceDir,
+		Destination: destDir,
+		Compression: -1,
+		Workers:     4,
+		CatalogFile: filepath.Join(t.TempDir(), "catalog.jsonl"),
+		ReportFile:  filepath.Join(t.TempDir(), "report.json"),
+	})
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() unexpected error: %v", err)
+	}
+
+	if summary.Processed != 10 || summary.Copied != 10 || summary.Skipped != 10 {
+		t.Errorf("summary = %+v, want 10 copied and 10 skipped", summary)
+	}
+	if summary.Stats.Workers != 4 {
+		t.Errorf("Stats.Workers = %d, want 4", summary.Stats.Workers)
+	}
+
+	written, err := filepath.Glob(filepath.Join(destDir, "2025", "01-11", "*.jpg"))
+	if err != nil || len(written) != 10 {
+		t.Errorf("destination files = %v, %v, want 10", written, err)
+	}
+}
@@ -0,0 +1,293 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// XMPIdentifier starts the APP1 segments of JPEG files holding an XMP packet
+const XMPIdentifier = "http://ns.adobe.com/xap/1.0/\x00"
+
+// FaceRegion is a region of a picture described in its XMP metadata, such as
+// a face detected or tagged by the camera, phone or gallery software. The
+// area is relative to the picture size, with x and y at the region center
+// (Metadata Working Group regions).
+type FaceRegion struct {
+	Name string  `json:"name,omitempty"`
+	Type string  `json:"type,omitempty"`
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+	W    float64 `json:"w"`
+	H    float64 `json:"h"`
+}
+
+// jpegSegment is a marker segment of the header of a JPEG file
+type jpegSegment struct {
+	marker byte
+	data   []byte // Segment content, including its marker and length
+}
+
+// jpegHeaderSegments returns the marker segments of a JPEG file preceding its image data
+func jpegHeaderSegments(data []byte) []jpegSegment {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	var segments []jpegSegment
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF || data[offset+1] == 0xDA {
+			break // Start of scan, the image data follows
+		}
+		length := int(data[offset+2])<<8 | int(data[offset+3])
+		if length < 2 || offset+2+length > len(data) {
+			break
+		}
+		segments = append(segments, jpegSegment{marker: data[offset+1], data: data[offset : offset+2+length]})
+		offset += 2 + length
+	}
+	return segments
+}
+
+// ExtractXMP returns the XMP packet of a media file, or nil if it has none.
+// JPEG files hold it in an APP1 segment, while RAW files embed it as is.
+func ExtractXMP(data []byte, path string) []byte {
+	if isJPEG(path) {
+		for _, segment := range jpegHeaderSegments(data) {
+			if segment.marker == 0xE1 && bytes.HasPrefix(segment.data[4:], []byte(XMPIdentifier)) {
+				return segment.data[4+len(XMPIdentifier):]
+			}
+		}
+		return nil
+	}
+
+	start := bytes.Index(data, []byte("<x:xmpmeta"))
+	if start < 0 {
+		return nil
+	}
+	end := bytes.Index(data[start:], []byte("</x:xmpmeta>"))
+	if end < 0 {
+		return nil
+	}
+	return data[start : start+end+len("</x:xmpmeta>")]
+}
+
+// FaceRegions returns the face regions described in an XMP packet. Region
+// fields may be written as attributes or as child elements, depending on the
+// software that wrote them.
+func FaceRegions(xmp []byte) []FaceRegion {
+	if len(xmp) == 0 {
+		return nil
+	}
+
+	var regions []FaceRegion
+	var current *FaceRegion
+	var inList bool
+	var depth, itemDepth int
+	var field string
+
+	set := func(name, value string) {
+		if current == nil {
+			return
+		}
+		number, _ := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		switch name {
+		case "Name":
+			current.Name = strings.TrimSpace(value)
+		case "Type":
+			current.Type = strings.TrimSpace(value)
+		case "x":
+			current.X = number
+		case "y":
+			current.Y = number
+		case "w":
+			current.W = number
+		case "h":
+			current.H = number
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(xmp))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break // End of the packet, or trailing padding
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case t.Name.Local == "RegionList":
+				inList = true
+			case inList && t.Name.Local == "li" && current == nil:
+				current, itemDepth = &FaceRegion{}, depth
+			}
+			for _, attr := range t.Attr {
+				set(attr.Name.Local, attr.Value)
+			}
+			field = t.Name.Local
+		case xml.CharData:
+			set(field, string(t))
+		case xml.EndElement:
+			if current != nil && depth == itemDepth {
+				// Keep faces, and regions of software that does not type them
+				if current.Type == "" || strings.EqualFold(current.Type, "Face") {
+					regions = append(regions, *current)
+				}
+				current = nil
+			}
+			if t.Name.Local == "RegionList" {
+				inList = false
+			}
+			depth--
+			field = ""
+		}
+	}
+	return regions
+}
+
+// editorTools are the editing applications recognized in the CreatorTool and
+// history software agents of XMP packets, lower case
+var editorTools = []string{"photoshop", "lightroom", "camera raw", "gimp", "capture one", "affinity photo", "darktable", "luminar"}
+
+// IsEdited reports whether an XMP packet marks a file edited by editing
+// software: a Photoshop history, a saved or derived step in the XMP history,
+// Lightroom or Camera Raw develop settings, or an editor as creator tool.
+func IsEdited(xmp []byte) bool {
+	if len(xmp) == 0 {
+		return false
+	}
+
+	edited := func(name, value string) bool {
+		value = strings.ToLower(strings.TrimSpace(value))
+		switch name {
+		case "action":
+			return value == "saved" || value == "derived"
+		case "HasSettings":
+			return value == "true"
+		case "CreatorTool", "softwareAgent":
+			for _, tool := range editorTools {
+				if strings.Contains(value, tool) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var field string
+	decoder := xml.NewDecoder(bytes.NewReader(xmp))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false // End of the packet, or trailing padding
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "History" && t.Name.Space == "http://ns.adobe.com/photoshop/1.0/" {
+				return true
+			}
+			for _, attr := range t.Attr {
+				if edited(attr.Name.Local, attr.Value) {
+					return true
+				}
+			}
+			field = t.Name.Local
+		case xml.CharData:
+			if edited(field, string(t)) {
+				return true
+			}
+		case xml.EndElement:
+			field = ""
+		}
+	}
+}
+
+// preserveXMP copies the XMP segments of the original JPEG file into a
+// re-encoded one, right after its start of image marker, so descriptive
+// metadata such as face regions survives compression
+func preserveXMP(original, encoded []byte) []byte {
+	var xmp [][]byte
+	for _, segment := range jpegHeaderSegments(original) {
+		if segment.marker == 0xE1 && bytes.HasPrefix(segment.data[4:], []byte(XMPIdentifier)) {
+			xmp = append(xmp, segment.data)
+		}
+	}
+	if len(xmp) == 0 || len(encoded) < 2 {
+		return encoded
+	}
+
+	out := make([]byte, 0, len(encoded)+len(bytes.Join(xmp, nil)))
+	out = append(out, encoded[:2]...)
+	for _, segment := range xmp {
+		out = append(out, segment...)
+	}
+	return append(out, encoded[2:]...)
+}
+
+// xmpPacket returns an XMP packet holding the rdf:Description elements
+func xmpPacket(descriptions ...[]byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">`)
+	for _, description := range descriptions {
+		b.Write(description)
+	}
+	b.WriteString("</rdf:RDF></x:xmpmeta>\n<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// insertXMPDescription returns an XMP packet with an rdf:Description element
+// added, and false when the packet has no rdf:RDF element to add it to
+func insertXMPDescription(xmp, description []byte) ([]byte, bool) {
+	end := bytes.LastIndex(xmp, []byte("</rdf:RDF>"))
+	if end < 0 {
+		return xmp, false
+	}
+	return append(append(append([]byte{}, xmp[:end]...), description...), xmp[end:]...), true
+}
+
+// updateJPEGXMP returns a JPEG file with its XMP packet changed by update, or
+// with packet added when it has none. It returns false when the file is not
+// a JPEG file, update fails, or the packet would not fit in its segment.
+func updateJPEGXMP(data []byte, update func(xmp []byte) ([]byte, bool), packet []byte) ([]byte, bool) {
+	segments := jpegHeaderSegments(data)
+	if segments == nil {
+		return data, false
+	}
+
+	// Segments are listed in file order, their offsets follow from their sizes
+	offset, insertAt := 2, 2
+	for _, segment := range segments {
+		if segment.marker == 0xE1 && bytes.HasPrefix(segment.data[4:], []byte(XMPIdentifier)) {
+			updated, ok := update(segment.data[4+len(XMPIdentifier):])
+			if !ok {
+				return data, false
+			}
+			return spliceSegment(data, offset, offset+len(segment.data), updated)
+		}
+		// The packet follows the APP0 and Exif segments at the start of the file
+		if (segment.marker == 0xE0 || segment.marker == 0xE1) && insertAt == offset {
+			insertAt = offset + len(segment.data)
+		}
+		offset += len(segment.data)
+	}
+	return spliceSegment(data, insertAt, insertAt, packet)
+}
+
+// spliceSegment replaces data[start:end] with an APP1 segment holding xmp
+func spliceSegment(data []byte, start, end int, xmp []byte) ([]byte, bool) {
+	length := 2 + len(XMPIdentifier) + len(xmp)
+	if length > 0xFFFF {
+		return data, false
+	}
+
+	out := make([]byte, 0, len(data)-(end-start)+2+length)
+	out = append(out, data[:start]...)
+	out = append(out, 0xFF, 0xE1, byte(length>>8), byte(length))
+	out = append(append(out, XMPIdentifier...), xmp...)
+	return append(out, data[end:]...), true
+}
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// testXMP describes two faces, one with attributes and one with child elements,
+// and a focus region that is not a face
+const testXMP = `<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description xmlns:mwg-rs="http://www.metadataworkinggroup.com/schemas/regions/"
+    xmlns:stArea="http://ns.adobe.com/xmp/sType/Area#" xmlns:stDim="http://ns.adobe.com/xap/1.0/sType/Dimensions#">
+   <mwg-rs:Regions rdf:parseType="Resource">
+    <mwg-rs:AppliedToDimensions stDim:w="4000" stDim:h="3000" stDim:unit="pixel"/>
+    <mwg-rs:RegionList>
+     <rdf:Bag>
+      <rdf:li>
+       <rdf:Description mwg-rs:Name="Alice" mwg-rs:Type="Face">
+        <mwg-rs:Area stArea:x="0.25" stArea:y="0.4" stArea:w="0.1" stArea:h="0.15" stArea:unit="normalized"/>
+       </rdf:Description>
+      </rdf:li>
+      <rdf:li rdf:parseType="Resource">
+       <mwg-rs:Name>Bob</mwg-rs:Name>
+       <mwg-rs:Type>Face</mwg-rs:Type>
+       <mwg-rs:Area rdf:parseType="Resource">
+        <stArea:x>0.7</stArea:x>
+        <stArea:y>0.5</stArea:y>
+        <stArea:w>0.2</stArea:w>
+        <stArea:h>0.25</stArea:h>
+       </mwg-rs:Area>
+      </rdf:li>
+      <rdf:li>
+       <rdf:Description mwg-rs:Type="Focus">
+        <mwg-rs:Area stArea:x="0.5" stArea:y="0.5" stArea:w="0.05" stArea:h="0.05"/>
+       </rdf:Description>
+      </rdf:li>
+     </rdf:Bag>
+    </mwg-rs:RegionList>
+   </mwg-rs:Regions>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>`
+
+// xmpSegment builds a JPEG APP1 segment holding an XMP packet
+func xmpSegment(packet string) []byte {
+	content := append([]byte(XMPIdentifier), packet...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(content)+2))
+	return append(segment, content...)
+}
+
+// createXMPJPEG encodes a small picture with an XMP segment after its start of image marker
+func createXMPJPEG(t *testing.T, packet string) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewGray(image.Rect(0, 0, 16, 16)), nil); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	data := append([]byte{0xFF, 0xD8}, xmpSegment(packet)...)
+	return append(data, encoded.Bytes()[2:]...)
+}
+
+func TestFaceRegions(t *testing.T) {
+	regions := FaceRegions([]byte(testXMP))
+	want := []FaceRegion{
+		{Name: "Alice", Type: "Face", X: 0.25, Y: 0.4, W: 0.1, H: 0.15},
+		{Name: "Bob", Type: "Face", X: 0.7, Y: 0.5, W: 0.2, H: 0.25},
+	}
+	if len(regions) != len(want) {
+		t.Fatalf("FaceRegions() = %+v, want %+v", regions, want)
+	}
+	for i := range want {
+		if regions[i] != want[i] {
+			t.Errorf("FaceRegions()[%d] = %+v, want %+v", i, regions[i], want[i])
+		}
+	}
+
+	if regions := FaceRegions(nil); regions != nil {
+		t.Errorf("FaceRegions(nil) = %+v, want none", regions)
+	}
+	if regions := FaceRegions([]byte("<x:xmpmeta>not closed")); regions != nil {
+		t.Errorf("FaceRegions() on invalid XML = %+v, want none", regions)
+	}
+}
+
+func TestExtractXMP(t *testing.T) {
+	jpegData := createXMPJPEG(t, testXMP)
+	raw := append(append(buildTIFF(nil, nil), testXMP...), 0, 0)
+
+	tests := []struct {
+		name string
+		data []byte
+		path string
+		want string
+	}{
+		{"JPEG", jpegData, "a.jpg", testXMP},
+		{"RAW", raw, "a.nef", testXMP},
+		{"JPEG without XMP", createFakeExifData(), "a.jpg", ""},
+		{"RAW without XMP", buildTIFF(nil, nil), "a.arw", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(ExtractXMP(tt.data, tt.path)); got != tt.want {
+				t.Errorf("ExtractXMP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressionPreservesXMP(t *testing.T) {
+	destPath := filepath.Join(t.TempDir(), "photo.jpg")
+	var summary ProcessingSummary
+	if _, _, err := copyOrCompressImage(OSFileSystem{}, destPath, "photo.jpg", nil, createXMPJPEG(t, testXMP), true, &models.Params{Compression: 50}, nil, nil, nil, nil, &summary); err != nil {
+		t.Fatalf("copyOrCompressImage() error = %v", err)
+	}
+	if summary.Compressed != 1 {
+		t.Fatalf("Expected the picture to be compressed, got %+v", summary)
+	}
+
+	compressed, err := os.ReadFile(destPath)
+	if err != nil {
+		t.Fatalf("Failed to read compressed file: %v", err)
+	}
+	if string(ExtractXMP(compressed, destPath)) != testXMP {
+		t.Error("XMP packet was not preserved by compression")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(compressed)); err != nil {
+		t.Errorf("Compressed file with XMP is not a valid JPEG: %v", err)
+	}
+}
+
+func TestIsEdited(t *testing.T) {
+	packet := func(description string) []byte {
+		return []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + description + `</rdf:RDF></x:xmpmeta>`)
+	}
+	tests := []struct {
+		name string
+		xmp  []byte
+		want bool
+	}{
+		{name: "no XMP", xmp: nil, want: false},
+		{name: "faces only", xmp: []byte(testXMP), want: false},
+		{
+			name: "camera creator tool",
+			xmp:  packet(`<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:CreatorTool="ILCE-7M3 v3.01"/>`),
+			want: false,
+		},
+		{
+			name: "Photoshop history",
+			xmp:  packet(`<rdf:Description xmlns:photoshop="http://ns.adobe.com/photoshop/1.0/"><photoshop:History>Levels</photoshop:History></rdf:Description>`),
+			want: true,
+		},
+		{
+			name: "saved step in the XMP history",
+			xmp: packet(`<rdf:Description xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/" xmlns:stEvt="http://ns.adobe.com/xap/1.0/sType/ResourceEvent#">
+			 <xmpMM:History><rdf:Seq><rdf:li stEvt:action="saved" stEvt:when="2024-06-12T10:00:00"/></rdf:Seq></xmpMM:History></rdf:Description>`),
+			want: true,
+		},
+		{
+			name: "created step only",
+			xmp: packet(`<rdf:Description xmlns:xmpMM="http://ns.adobe.com/xap/1.0/mm/" xmlns:stEvt="http://ns.adobe.com/xap/1.0/sType/ResourceEvent#">
+			 <xmpMM:History><rdf:Seq><rdf:li><stEvt:action>created</stEvt:action></rdf:li></rdf:Seq></xmpMM:History></rdf:Description>`),
+			want: false,
+		},
+		{
+			name: "Lightroom develop settings",
+			xmp:  packet(`<rdf:Description xmlns:crs="http://ns.adobe.com/camera-raw-settings/1.0/"><crs:HasSettings>True</crs:HasSettings></rdf:Description>`),
+			want: true,
+		},
+		{
+			name: "editor creator tool",
+			xmp:  packet(`<rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:CreatorTool="Adobe Photoshop Lightroom Classic 13.3 (Windows)"/>`),
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEdited(tt.xmp); got != tt.want {
+				t.Errorf("IsEdited() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressionKeepsEdits(t *testing.T) {
+	edit := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+ <rdf:Description xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:CreatorTool="GIMP 2.10"/></rdf:RDF></x:xmpmeta>`
+	data := createXMPJPEG(t, edit)
+
+	tests := []struct {
+		name      string
+		keepEdits bool
+		want      ProcessingSummary
+	}{
+		{name: "edits recompressed", keepEdits: false, want: ProcessingSummary{Compressed: 1}},
+		{name: "edits kept", keepEdits: true, want: ProcessingSummary{Copied: 1, EditsKept: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destPath := filepath.Join(t.TempDir(), "edit.jpg")
+			var summary ProcessingSummary
+			params := &models.Params{Compression: 50, KeepEdits: tt.keepEdits}
+			if _, _, err := copyOrCompressImage(OSFileSystem{}, destPath, "edit.jpg", nil, data, true, params, nil, nil, nil, nil, &summary); err != nil {
+				t.Fatalf("copyOrCompressImage() error = %v", err)
+			}
+			if summary.Compressed != tt.want.Compressed || summary.Copied != tt.want.Copied || summary.EditsKept != tt.want.EditsKept {
+				t.Errorf("summary = %+v, want %+v", summary, tt.want)
+			}
+
+			written, err := os.ReadFile(destPath)
+			if err != nil {
+				t.Fatalf("Failed to read written file: %v", err)
+			}
+			if kept := bytes.Equal(written, data); kept != tt.keepEdits {
+				t.Errorf("written file identical to the edit = %v, want %v", kept, tt.keepEdits)
+			}
+		})
+	}
+}
+
+func TestProcessMediaFilesFaces(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	// Dated JPEG with the XMP segment inserted after its start of image marker
+	dated := createFakeExifData()
+	data := append(append([]byte{0xFF, 0xD8}, xmpSegment(testXMP)...), dated[2:]...)
+	if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), data, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	params := &models.Params{
+		Source:      sourceDir,
+		Destination: destDir,
+		Compression: -1,
+		CatalogFile: filepath.Join(t.TempDir(), "catalog.jsonl"),
+		ReportFile:  filepath.Join(t.TempDir(), "report.json"),
+	}
+	if _, err := ProcessMediaFiles(params); err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+
+	files := readTestReport(t, params.ReportFile).Files
+	if len(files) != 1 || len(files[0].Faces) != 2 || files[0].Faces[0].Name != "Alice" {
+		t.Errorf("Expected 2 faces in the report, got %+v", files)
+	}
+
+	catalog, err := os.ReadFile(params.CatalogFile)
+	if err != nil {
+		t.Fatalf("Failed to read catalog: %v", err)
+	}
+	if !bytes.Contains(catalog, []byte(`"name":"Bob"`)) {
+		t.Errorf("Expected faces in the catalog record, got %s", catalog)
+	}
+}
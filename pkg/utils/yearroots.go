@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+// YearRoot is a destination root receiving the files taken in a range of
+// years, so archives too large for one drive are spread over several
+type YearRoot struct {
+	From, To int // Inclusive years, math.MinInt or math.MaxInt when open
+	Dir      string
+}
+
+// YearRoots are the destination roots of a run, by ascending years
+type YearRoots []YearRoot
+
+// LoadYearRoots reads a JSON file mapping years or ranges of years to
+// destination roots, such as {"2010-2019": "/mnt/a", "2020-": "/mnt/b"}.
+// Ranges may be open on either side ("-2009", "2020-") and must not overlap.
+func LoadYearRoots(path string) (YearRoots, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read year roots: %w", err)
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse year roots %s: %w", path, err)
+	}
+
+	roots := make(YearRoots, 0, len(raw))
+	for years, dir := range raw {
+		from, to, err := parseYearRange(years)
+		if err != nil {
+			return nil, fmt.Errorf("invalid years %q in %s: %w", years, path, err)
+		}
+		if strings.TrimSpace(dir) == "" {
+			return nil, fmt.Errorf("missing destination root of years %q in %s", years, path)
+		}
+		roots = append(roots, YearRoot{From: from, To: to, Dir: filepath.Clean(dir)})
+	}
+
+	sort.Slice(roots, func(i, j int) bool { return roots[i].From < roots[j].From })
+	for i := 1; i < len(roots); i++ {
+		if roots[i].From <= roots[i-1].To {
+			return nil, fmt.Errorf("years of %s and %s overlap in %s", roots[i-1].Dir, roots[i].Dir, path)
+		}
+	}
+	return roots, nil
+}
+
+// parseYearRange parses a year (2015) or a range of years (2010-2019),
+// possibly open on one side (-2009, 2020-)
+func parseYearRange(years string) (from, to int, err error) {
+	parseYear := func(s string, open int) (int, error) {
+		if s = strings.TrimSpace(s); s == "" {
+			return open, nil
+		}
+		year, err := strconv.Atoi(s)
+		if err != nil || year < 1 {
+			return 0, fmt.Errorf("expected a year such as 2015 or a range such as 2010-2019")
+		}
+		return year, nil
+	}
+
+	first, last, isRange := strings.Cut(years, "-")
+	if !isRange {
+		last = first
+	}
+	if strings.TrimSpace(first) == "" && strings.TrimSpace(last) == "" {
+		return 0, 0, fmt.Errorf("expected a year such as 2015 or a range such as 2010-2019")
+	}
+	if from, err = parseYear(first, math.MinInt); err != nil {
+		return 0, 0, err
+	}
+	if to, err = parseYear(last, math.MaxInt); err != nil {
+		return 0, 0, err
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("range ends before it starts")
+	}
+	return from, to, nil
+}
+
+// Years returns the years of the root as written in year roots files
+func (r YearRoot) Years() string {
+	switch {
+	case r.From == r.To:
+		return strconv.Itoa(r.From)
+	case r.From == math.MinInt:
+		return fmt.Sprintf("-%d", r.To)
+	case r.To == math.MaxInt:
+		return fmt.Sprintf("%d-", r.From)
+	default:
+		return fmt.Sprintf("%d-%d", r.From, r.To)
+	}
+}
+
+// root returns the destination root of the files taken at date, if any
+func (r YearRoots) root(date time.Time) (string, bool) {
+	for _, root := range r {
+		if date.Year() >= root.From && date.Year() <= root.To {
+			return root.Dir, true
+		}
+	}
+	return "", false
+}
+
+var loadedYearRoots sync.Map // Year roots of runs, keyed by file
+
+// yearRootsOf returns the year roots of a run, nil when it has none. The
+// file is read once, by the first run using it.
+func yearRootsOf(p *models.Params) (YearRoots, error) {
+	if p.YearRootsFile == "" {
+		return nil, nil
+	}
+	if roots, ok := loadedYearRoots.Load(p.YearRootsFile); ok {
+		return roots.(YearRoots), nil
+	}
+	roots, err := LoadYearRoots(p.YearRootsFile)
+	if err != nil {
+		return nil, err
+	}
+	loadedYearRoots.Store(p.YearRootsFile, roots)
+	return roots, nil
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/models"
+)
+
+func TestLoadYearRoots(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    YearRoots
+		wantErr string // Part of the expected error, none for valid files
+	}{
+		{name: "not a year", content: `{"2015x": "/a"}`, wantErr: `invalid years "2015x"`},
+		{
+			name:    "sorted by year",
+			content: `{"2020-": "/b", "-2009": "/old", "2010-2019": "/a/"}`,
+			want: YearRoots{
+				{From: math.MinInt, To: 2009, Dir: "/old"},
+				{From: 2010, To: 2019, Dir: "/a"},
+				{From: 2020, To: math.MaxInt, Dir: "/b"},
+			},
+		},
+		{name: "single year", content: `{"2015": "/a"}`, want: YearRoots{{From: 2015, To: 2015, Dir: "/a"}}},
+		{name: "overlapping ranges", content: `{"2010-2019": "/a", "2019-": "/b"}`, wantErr: "overlap"},
+		{name: "reversed range", content: `{"2019-2010": "/a"}`, wantErr: "range ends before it starts"},
+		{name: "open range", content: `{"-": "/a"}`, wantErr: "expected a year"},
+		{name: "missing root", content: `{"2015": " "}`, wantErr: "missing destination root"},
+		{name: "invalid JSON", content: `["2015"]`, wantErr: "failed to parse year roots"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "roots.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write year roots: %v", err)
+			}
+			got, err := LoadYearRoots(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadYearRoots() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadYearRoots() unexpected error: %v", err)
+			}
+			for i := range tt.want {
+				tt.want[i].Dir = filepath.Clean(filepath.FromSlash(tt.want[i].Dir))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadYearRoots() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadYearRoots(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadYearRoots() of a missing file expected an error")
+	}
+}
+
+func TestYearRootYears(t *testing.T) {
+	for want, root := range map[string]YearRoot{
+		"2015":      {From: 2015, To: 2015},
+		"2010-2019": {From: 2010, To: 2019},
+		"-2009":     {From: math.MinInt, To: 2009},
+		"2020-":     {From: 2020, To: math.MaxInt},
+	} {
+		if got := root.Years(); got != want {
+			t.Errorf("Years() = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestProcessMediaFilesYearRoots(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	driveA := t.TempDir()
+	driveB := t.TempDir()
+	mirror := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "photo.jpg"), createFakeExifData(), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	rootsFile := filepath.Join(t.TempDir(), "roots.json")
+	roots := `{"2010-2019": "` + filepath.ToSlash(driveA) + `", "2020-": "` + filepath.ToSlash(driveB) + `"}`
+	if err := os.WriteFile(rootsFile, []byte(roots), 0644); err != nil {
+		t.Fatalf("Failed to write year roots: %v", err)
+	}
+
+	// Taken in 2025, organized below the root of 2020 onwards
+	params := &models.Params{Source: sourceDir, Destination: destDir, Mirrors: []string{mirror}, YearRootsFile: rootsFile, Compression: -1}
+	if root := destinationRoot(params, time.Date(2012, 5, 1, 0, 0, 0, 0, time.UTC)); root != driveA {
+		t.Errorf("destinationRoot() of 2012 = %s, want %s", root, driveA)
+	}
+	if root := destinationRoot(params, time.Date(2005, 5, 1, 0, 0, 0, 0, time.UTC)); root != destDir {
+		t.Errorf("destinationRoot() of 2005 = %s, want %s", root, destDir)
+	}
+
+	summary, err := ProcessMediaFiles(params)
+	if err != nil {
+		t.Fatalf("ProcessMediaFiles() error = %v", err)
+	}
+	if summary.Copied != 1 || summary.Mirrored != 1 {
+		t.Errorf("Expected 1 copied and mirrored file, got %+v", summary)
+	}
+	rel := filepath.Join("2025", "01-11", "photo.jpg")
+	for _, path := range []string{filepath.Join(driveB, rel), filepath.Join(mirror, rel)} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected file below its year root: %v", err)
+		}
+	}
+
+	invalid := filepath.Join(t.TempDir(), "roots.json")
+	if err := os.WriteFile(invalid, []byte(`{"2019-2010": "/a"}`), 0644); err != nil {
+		t.Fatalf("Failed to write year roots: %v", err)
+	}
+	params.YearRootsFile = invalid
+	if _, err := ProcessMediaFiles(params); err == nil {
+		t.Error("ProcessMediaFiles() with invalid year roots expected an error")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runScan implements the scan subcommand, which inspects a source without writing anything
+func runScan(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	source := fs.String("source", "", "Path to the source directory to inspect")
+	dest := fs.String("dest", "", "Destination directory used to resolve target paths and existing files (optional)")
+	compression := fs.Int("compression", -1, "Compression level a run would apply to JPG files (0-100, optional)")
+	long := fs.Bool("l", false, "Print a table of every detected file")
+	sortBy := fs.String("sort", "date", "Sort order of the long listing: date, size or name")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *source == "" {
+		return fmt.Errorf("source directory is required")
+	}
+	if *sortBy != "date" && *sortBy != "size" && *sortBy != "name" {
+		return fmt.Errorf("invalid sort order: %s (expected date, size or name)", *sortBy)
+	}
+
+	entries, err := utils.ScanMediaFiles(&models.Params{
+		Source:      *source,
+		Destination: *dest,
+		Compression: *compression,
+	})
+	if err != nil {
+		return err
+	}
+
+	sortScanEntries(entries, *sortBy)
+
+	if *long {
+		printScanTable(os.Stdout, entries, *dest)
+	} else {
+		printScanSummary(os.Stdout, entries)
+	}
+	return nil
+}
+
+// sortScanEntries orders entries by date (undated last), size (largest first) or name
+func sortScanEntries(entries []utils.ScanEntry, by string) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch by {
+		case "size":
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+		case "date":
+			if a.Date.IsZero() != b.Date.IsZero() {
+				return b.Date.IsZero()
+			}
+			if !a.Date.Equal(b.Date) {
+				return a.Date.Before(b.Date)
+			}
+		}
+		return a.Source < b.Source
+	})
+}
+
+// printScanTable prints one row per file with its date, camera, size, target path and action
+func printScanTable(w io.Writer, entries []utils.ScanEntry, dest string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tCAMERA\tSIZE\tTARGET\tACTION\tFILE")
+
+	for _, e := range entries {
+		date, target := "-", "-"
+		if e.Err == nil {
+			date = e.Date.Format("2006-01-02 15:04:05")
+			target = e.Destination
+			if dest != "" {
+				if rel, err := filepath.Rel(dest, e.Destination); err == nil {
+					target = rel
+				}
+			}
+		}
+
+		camera := e.Camera
+		if camera == "" {
+			camera = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", date, camera, utils.FormatSize(e.Size), target, e.Action, e.Source)
+	}
+	tw.Flush()
+}
+
+// printScanSummary prints the number of files, their total size and the covered dates
+func printScanSummary(w io.Writer, entries []utils.ScanEntry) {
+	var total int64
+	var undated, corrupt int
+	actions := make(map[string]int)
+	for _, e := range entries {
+		total += e.Size
+		actions[e.Action]++
+		switch {
+		case errors.Is(e.Err, utils.ErrCorruptFile):
+			corrupt++
+		case e.Err != nil:
+			undated++
+		}
+	}
+
+	fmt.Fprintf(w, "Files: %d [%s]\n", len(entries), utils.FormatSize(total))
+
+	// Entries are sorted by date by default, but the summary must not depend on it
+	var first, last utils.ScanEntry
+	for _, e := range entries {
+		if e.Err != nil {
+			continue
+		}
+		if first.Date.IsZero() || e.Date.Before(first.Date) {
+			first = e
+		}
+		if e.Date.After(last.Date) {
+			last = e
+		}
+	}
+	if !first.Date.IsZero() {
+		fmt.Fprintf(w, "Dates: %s to %s\n", first.Date.Format("2006-01-02"), last.Date.Format("2006-01-02"))
+	}
+	if undated > 0 {
+		fmt.Fprintf(w, "Without date: %d\n", undated)
+	}
+	if corrupt > 0 {
+		fmt.Fprintf(w, "Empty or truncated: %d\n", corrupt)
+	}
+
+	names := make([]string, 0, len(actions))
+	for action := range actions {
+		names = append(names, action)
+	}
+	sort.Strings(names)
+	for _, action := range names {
+		fmt.Fprintf(w, "  %s: %d\n", action, actions[action])
+	}
+}
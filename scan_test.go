@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+func TestRunScanErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "missing source", args: []string{}},
+		{name: "invalid sort order", args: []string{"-source", t.TempDir(), "-sort", "camera"}},
+		{name: "non-existent source", args: []string{"-source", filepath.Join(t.TempDir(), "missing")}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runScan(tc.args); err == nil {
+				t.Errorf("runScan(%v) expected error, got nil", tc.args)
+			}
+		})
+	}
+}
+
+func TestSortScanEntries(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, time.June, d, 0, 0, 0, 0, time.UTC) }
+	entries := []utils.ScanEntry{
+		{PlannedFile: utils.PlannedFile{Source: "b.jpg", Date: day(12), Size: 10}},
+		{PlannedFile: utils.PlannedFile{Source: "c.jpg", Size: 30}},
+		{PlannedFile: utils.PlannedFile{Source: "a.jpg", Date: day(11), Size: 20}},
+	}
+
+	testCases := []struct {
+		by   string
+		want []string
+	}{
+		{by: "date", want: []string{"a.jpg", "b.jpg", "c.jpg"}},
+		{by: "size", want: []string{"c.jpg", "a.jpg", "b.jpg"}},
+		{by: "name", want: []string{"a.jpg", "b.jpg", "c.jpg"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.by, func(t *testing.T) {
+			sortScanEntries(entries, tc.by)
+			for i, e := range entries {
+				if e.Source != tc.want[i] {
+					t.Errorf("position %d = %s, want %s", i, e.Source, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPrintScanTable(t *testing.T) {
+	dest := filepath.Join(string(os.PathSeparator), "archive")
+	entries := []utils.ScanEntry{
+		{
+			PlannedFile: utils.PlannedFile{
+				Source:      "/card/DSC00001.ARW",
+				Destination: filepath.Join(dest, "2024", "06-11", "DSC00001.ARW"),
+				Date:        time.Date(2024, time.June, 11, 15, 30, 10, 0, time.UTC),
+				Size:        2048,
+			},
+			Camera: "SONY ILCE-7M3",
+			Action: utils.ActionCopy,
+		},
+		{
+			PlannedFile: utils.PlannedFile{Source: "/card/broken.jpg", Size: 10, Err: os.ErrInvalid},
+			Action:      utils.ActionSkipNoDate,
+		},
+	}
+
+	var buf bytes.Buffer
+	printScanTable(&buf, entries, dest)
+	got := buf.String()
+
+	expected := []string{
+		"DATE", "CAMERA", "TARGET", "ACTION",
+		"2024-06-11 15:30:10",
+		"SONY ILCE-7M3",
+		"2.00 KB",
+		filepath.Join("2024", "06-11", "DSC00001.ARW"),
+		utils.ActionSkipNoDate,
+	}
+	for _, want := range expected {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected table to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	buf.Reset()
+	printScanSummary(&buf, entries)
+	for _, want := range []string{"Files: 2", "Dates: 2024-06-11 to 2024-06-11", "Without date: 1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
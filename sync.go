@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/matdmb/organize-media/pkg/models"
+	"github.com/matdmb/organize-media/pkg/utils"
+)
+
+// runSync implements the sync subcommand, which copies the files of an
+// organized archive missing or changed in a backup
+func runSync(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	from := fs.String("from", "", "Organized archive to back up")
+	to := fs.String("to", "", "Backup directory, such as a second drive")
+	verify := fs.Bool("verify", false, "Also compare the content of files with the same size and date")
+	dryRun := fs.Bool("dry-run", false, "Only list the files that would be copied")
+	hashAlgo := fs.String("hash", "sha256", "Hash algorithm used by -verify: sha256 or blake3")
+	catalogFile := fs.String("catalog", "", "Catalog of the archive, telling which copy is right when -verify finds a damaged file (optional)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("archive and backup directories are required")
+	}
+	if !models.HashAlgorithms[*hashAlgo] {
+		return fmt.Errorf("unsupported hash algorithm: %s (expected sha256 or blake3)", *hashAlgo)
+	}
+
+	// The backup is checked like a run destination, it must not overlap the archive
+	params := &models.Params{Source: *from, Destination: *to, Compression: -1}
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	opts := utils.SyncOptions{Verify: *verify, DryRun: *dryRun, HashAlgo: *hashAlgo}
+	if *catalogFile != "" {
+		catalog, err := utils.LoadCatalog(*catalogFile)
+		if err != nil {
+			return err
+		}
+		opts.Catalog = catalog
+	}
+	summary, err := utils.SyncArchive(*from, *to, opts, func(r utils.SyncResult) {
+		if r.Err != nil {
+			fmt.Fprintf(stdout, "%-9s %s: %v\n", r.Action, r.Path, r.Err)
+		} else {
+			fmt.Fprintf(stdout, "%-9s %s\n", r.Action, r.Path)
+		}
+	})
+
+	fmt.Fprintf(stdout, "Copied: %d, updated: %d [%s], unchanged: %d\n", summary.Copied, summary.Updated, utils.FormatSize(summary.Bytes), summary.Unchanged)
+	if summary.Protected > 0 {
+		fmt.Fprintf(stdout, "Different but write-protected in the backup: %d\n", summary.Protected)
+	}
+	if summary.Failed > 0 {
+		fmt.Fprintf(stdout, "Failed: %d\n", summary.Failed)
+	}
+	if summary.Orphans > 0 {
+		fmt.Fprintf(stdout, "Temporary files of interrupted syncs removed: %d\n", summary.Orphans)
+	}
+	if summary.Extra > 0 {
+		fmt.Fprintf(stdout, "Only in the backup (kept): %d\n", summary.Extra)
+	}
+	if err != nil {
+		return err
+	}
+	if summary.Failed > 0 || summary.Protected > 0 {
+		return fmt.Errorf("%d files could not be synced", summary.Failed+summary.Protected)
+	}
+	return nil
+}
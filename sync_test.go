@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSync(t *testing.T) {
+	archive := t.TempDir()
+	backup := t.TempDir()
+	if err := os.WriteFile(filepath.Join(archive, "photo.jpg"), []byte("photo"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runSync([]string{"-from", archive, "-to", backup, "-verify"}, &out); err != nil {
+		t.Fatalf("runSync() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "copy      photo.jpg") || !strings.Contains(out.String(), "Copied: 1") {
+		t.Errorf("runSync() output = %q", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(backup, "photo.jpg")); err != nil {
+		t.Errorf("Expected the file in the backup: %v", err)
+	}
+}
+
+func TestRunSyncErrors(t *testing.T) {
+	archive := t.TempDir()
+	inside := filepath.Join(archive, "backup")
+	if err := os.Mkdir(inside, os.ModePerm); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "missing backup", args: []string{"-from", archive}},
+		{name: "same directory", args: []string{"-from", archive, "-to", archive}},
+		{name: "backup inside archive", args: []string{"-from", archive, "-to", inside}},
+		{name: "unsupported hash", args: []string{"-from", archive, "-to", t.TempDir(), "-hash", "md5"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := runSync(tc.args, &bytes.Buffer{}); err == nil {
+				t.Errorf("runSync(%v) expected error, got nil", tc.args)
+			}
+		})
+	}
+}